@@ -0,0 +1,157 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// UserScan is the outcome of scanning one user's home directory
+type UserScan struct {
+	// User is the name of the home directory, normally the account name
+	User    string
+	HomeDir string
+	// Result is nil when Err is set
+	Result *ScanResult
+	// Err wraps ErrPermissionDenied when the home directory could not be
+	// read, which usually means the caller is not privileged
+	Err error
+}
+
+// nonUserProfiles are directories under the profile roots that belong to no
+// user
+var nonUserProfiles = map[string]bool{
+	"lost+found":   true,
+	"Shared":       true,
+	"Guest":        true,
+	"Public":       true,
+	"Default":      true,
+	"Default User": true,
+	"All Users":    true,
+	"defaultuser0": true,
+}
+
+// userProfileRoots returns the directories holding user home directories
+// on this platform, and any home directories kept elsewhere
+func userProfileRoots() (roots, homes []string) {
+	switch runtime.GOOS {
+	case "windows":
+		drive := os.Getenv("SystemDrive")
+		if drive == "" {
+			drive = "C:"
+		}
+		return []string{drive + `\Users`}, nil
+	case "darwin":
+		return []string{"/Users"}, []string{"/var/root"}
+	default:
+		return []string{"/home"}, []string{"/root"}
+	}
+}
+
+// ScanAllUsers scans every user's home directory on the machine: /home/*
+// and /root on Linux, /Users/* on macOS and the profiles under
+// %SystemDrive%\Users on Windows. It is meant for endpoint agents running
+// with administrator rights. options.HomeDir is ignored; everything else
+// applies to each user.
+//
+// A home directory that cannot be read does not stop the scan; its UserScan
+// carries the error. ScanAllUsers itself fails only when no profile root can
+// be listed at all.
+func ScanAllUsers(options ScanOptions) ([]UserScan, error) {
+	roots, homes := userProfileRoots()
+	return scanUserHomes(roots, homes, options)
+}
+
+func scanUserHomes(roots, extraHomes []string, options ScanOptions) ([]UserScan, error) {
+	homes, err := listUserHomes(roots, extraHomes)
+	if err != nil {
+		return nil, err
+	}
+
+	scans := make([]UserScan, 0, len(homes))
+	for _, home := range homes {
+		scan := UserScan{User: filepath.Base(home), HomeDir: home}
+		if scan.Err = checkReadable(home); scan.Err == nil {
+			userOptions := options
+			userOptions.HomeDir = home
+			scan.Result, scan.Err = Scan(userOptions)
+		}
+		if scan.Err != nil {
+			logger().Warn("user scan failed", slog.String("home_dir", home), slog.String("error", scan.Err.Error()))
+		}
+		scans = append(scans, scan)
+	}
+	return scans, nil
+}
+
+// listUserHomes lists the home directories under roots plus any extra homes
+// that exist, sorted by path
+func listUserHomes(roots, extraHomes []string) ([]string, error) {
+	var (
+		homes  []string
+		listed int
+		errs   []error
+	)
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger().Warn("cannot list user homes", slog.String("root", root), slog.String("error", err.Error()))
+				errs = append(errs, err)
+			}
+			continue
+		}
+		listed++
+		for _, e := range entries {
+			name := e.Name()
+			if strings.HasPrefix(name, ".") || nonUserProfiles[name] {
+				continue
+			}
+			p := filepath.Join(root, name)
+			// Homes may be symlinks to another volume
+			if info, err := os.Stat(p); err == nil && info.IsDir() {
+				homes = append(homes, p)
+			}
+		}
+	}
+	for _, p := range extraHomes {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			homes = append(homes, p)
+			listed++
+		}
+	}
+	if listed == 0 && len(errs) > 0 {
+		err := errors.Join(errs...)
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, fmt.Errorf("listing user homes: %w: %v", ErrPermissionDenied, err)
+		}
+		return nil, fmt.Errorf("listing user homes: %w: %v", ErrIO, err)
+	}
+	sort.Strings(homes)
+	return homes, nil
+}
+
+// checkReadable reports whether home can be listed. Scanning a home the
+// caller cannot read would otherwise succeed with nothing found.
+func checkReadable(home string) error {
+	f, err := os.Open(home)
+	if err == nil {
+		_, err = f.Readdirnames(1)
+		f.Close()
+	}
+	switch {
+	case err == nil || errors.Is(err, io.EOF):
+		return nil
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%s: %w", home, ErrPermissionDenied)
+	default:
+		return fmt.Errorf("%s: %w: %v", home, ErrIO, err)
+	}
+}
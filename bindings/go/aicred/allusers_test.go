@@ -0,0 +1,68 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanUserHomes(t *testing.T) {
+	root, extra := t.TempDir(), filepath.Join(t.TempDir(), "root")
+	for _, name := range []string{"alice", "bob", "Public", ".hidden", "lost+found"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(root, "notes.txt"), nil)
+	if err := os.Mkdir(extra, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	scans, err := scanUserHomes([]string{root, filepath.Join(root, "missing")}, []string{extra, "/nonexistent-home"}, ScanOptions{HomeDir: "/ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []string
+	for _, s := range scans {
+		users = append(users, s.User)
+		if s.Err != nil {
+			t.Errorf("%s: %v", s.User, s.Err)
+			continue
+		}
+		if s.Result.HomeDir != s.HomeDir {
+			t.Errorf("%s: scanned %s, want %s", s.User, s.Result.HomeDir, s.HomeDir)
+		}
+	}
+	if len(users) != 3 || users[0] != "alice" || users[1] != "bob" || users[2] != "root" {
+		t.Errorf("users = %v, want alice, bob and root", users)
+	}
+}
+
+func TestScanUserHomesPermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := t.TempDir()
+	locked := filepath.Join(root, "carol")
+	if err := os.Mkdir(locked, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0o700)
+
+	scans, err := scanUserHomes([]string{root}, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 1 || !errors.Is(scans[0].Err, ErrPermissionDenied) || scans[0].Result != nil {
+		t.Errorf("got %+v, want carol with ErrPermissionDenied", scans)
+	}
+
+	if err := os.Chmod(root, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(root, 0o700)
+	if _, err := scanUserHomes([]string{root}, nil, ScanOptions{}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("unlistable root: got %v, want ErrPermissionDenied", err)
+	}
+}
@@ -0,0 +1,227 @@
+package aicred
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// defaultMaxArchiveDepth allows one archive nested inside another
+	defaultMaxArchiveDepth = 2
+	// defaultMaxArchiveBytes bounds the uncompressed bytes read per archive
+	defaultMaxArchiveBytes = 64 << 20
+	// defaultMaxFileSize matches the core library's default
+	defaultMaxFileSize = 1 << 20
+)
+
+// errArchiveBudget stops reading an archive whose uncompressed size exceeds
+// MaxArchiveBytes, which also guards against decompression bombs
+var errArchiveBudget = errors.New("archive exceeds MaxArchiveBytes")
+
+type archiveKind int
+
+const (
+	notArchive archiveKind = iota
+	zipArchive
+	tarArchive
+	tarGzArchive
+	gzFile
+)
+
+// archiveKindOf classifies a file or entry name by extension
+func archiveKindOf(name string) archiveKind {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return zipArchive
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return tarGzArchive
+	case strings.HasSuffix(lower, ".tar"):
+		return tarArchive
+	case strings.HasSuffix(lower, ".gz"):
+		return gzFile
+	}
+	return notArchive
+}
+
+// archiveScanner searches archive entries for keys with the Go detector
+type archiveScanner struct {
+	options  ScanOptions
+	maxDepth int
+	maxBytes int64
+	maxFile  int64
+	// budget is what remains of maxBytes for the current top-level archive
+	budget int64
+	keys   []DiscoveredKey
+}
+
+func newArchiveScanner(options ScanOptions) *archiveScanner {
+	s := &archiveScanner{
+		options:  options,
+		maxDepth: options.MaxArchiveDepth,
+		maxBytes: options.MaxArchiveBytes,
+		maxFile:  int64(options.MaxFileSize),
+	}
+	if s.maxDepth <= 0 {
+		s.maxDepth = defaultMaxArchiveDepth
+	}
+	if s.maxBytes <= 0 {
+		s.maxBytes = defaultMaxArchiveBytes
+	}
+	if s.maxFile <= 0 {
+		s.maxFile = defaultMaxFileSize
+	}
+	return s
+}
+
+// scanArchives walks home, and the OneDrive and AppData folders of its
+// profile that lie outside it, for archives and returns the keys inside them.
+// Entry sources are reported as "archive!entry". Damaged archives are
+// logged and skipped.
+func scanArchives(home string, options ScanOptions, run *scanRun) []DiscoveredKey {
+	var paths []string
+	w := newWalker(passArchives, home, options, run)
+	for _, root := range scanRoots(home) {
+		w.walk(root, 0, func(p string, d fs.DirEntry) {
+			if archiveKindOf(d.Name()) != notArchive {
+				paths = append(paths, p)
+			}
+		})
+	}
+	// Each archive gets its own scanner, which keeps byte budgets per worker
+	return run.scanFiles(passArchives, paths, options, func(p string) []DiscoveredKey {
+		s := newArchiveScanner(options)
+		if err := s.scanFile(p); err != nil {
+			logger().Warn("archive scan incomplete", slog.String("path", p), slog.String("error", err.Error()))
+			kind := warningKindOf(err)
+			if errors.Is(err, errArchiveBudget) {
+				kind = WarningTooLarge
+			}
+			run.warn(passArchives, p, kind, err)
+		}
+		return s.keys
+	})
+}
+
+func (s *archiveScanner) scanFile(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	s.budget = s.maxBytes
+	return s.scan(p, archiveKindOf(p), f, info.Size(), 1)
+}
+
+// scan reads the entries of one archive at the given nesting depth
+func (s *archiveScanner) scan(name string, kind archiveKind, r io.Reader, size int64, depth int) error {
+	switch kind {
+	case zipArchive:
+		ra, ok := r.(io.ReaderAt)
+		if !ok {
+			return errors.New("zip archive is not seekable")
+		}
+		zr, err := zip.NewReader(ra, size)
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = s.entry(name, f.Name, rc, depth)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	case tarArchive, tarGzArchive:
+		if kind == tarGzArchive {
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return err
+			}
+			defer gr.Close()
+			r = gr
+		}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := s.entry(name, hdr.Name, tr, depth); err != nil {
+				return err
+			}
+		}
+	case gzFile:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		inner := strings.TrimSuffix(path.Base(filepath.ToSlash(name)), path.Ext(name))
+		return s.entry(name, inner, gr, depth)
+	}
+	return nil
+}
+
+// entry scans one archive member, descending into it if it is itself an
+// archive and the depth limit allows
+func (s *archiveScanner) entry(archive, member string, r io.Reader, depth int) error {
+	name := archive + "!" + member
+	kind := archiveKindOf(member)
+	if kind != notArchive && depth >= s.maxDepth {
+		return nil
+	}
+
+	// Nested archives may use the whole remaining budget; other members
+	// are bounded by MaxFileSize like files on disk
+	limit := min(s.maxFile, s.budget)
+	if kind != notArchive {
+		limit = s.budget
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	defer zero(data)
+	s.budget -= int64(len(data))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		if s.budget < 0 {
+			return errArchiveBudget
+		}
+		logger().Debug("skipping large archive member", slog.String("name", name))
+		return nil
+	}
+
+	if kind != notArchive {
+		return s.scan(name, kind, bytes.NewReader(data), int64(len(data)), depth+1)
+	}
+	s.keys = append(s.keys, detectKeys(name, data, s.options, true)...)
+	return nil
+}
@@ -0,0 +1,174 @@
+package aicred
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+const archiveKey = "OPENAI_API_KEY=sk-proj-abcdefghijklmnopqrstuvwx\n"
+
+func zipBytes(t testing.TB, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzBytes(t testing.TB, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gw.Close()
+	return buf.Bytes()
+}
+
+func writeFile(t testing.TB, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanArchives(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, "backup.zip"), zipBytes(t, map[string][]byte{"app/.env": []byte(archiveKey)}))
+	// A zip inside a tarball is within the default depth of 2
+	writeFile(t, filepath.Join(home, "exports", "bundle.tar.gz"), tarGzBytes(t, map[string][]byte{
+		"inner.jar": zipBytes(t, map[string][]byte{"config.properties": []byte(archiveKey)}),
+	}))
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(archiveKey))
+	gw.Close()
+	writeFile(t, filepath.Join(home, "env.gz"), gz.Bytes())
+	writeFile(t, filepath.Join(home, "plain.env"), []byte(archiveKey))
+
+	keys := scanArchives(home, ScanOptions{}, nil)
+	var sources []string
+	for _, key := range keys {
+		sources = append(sources, key.Source)
+	}
+	sort.Strings(sources)
+	want := []string{
+		filepath.Join(home, "backup.zip") + "!app/.env",
+		filepath.Join(home, "env.gz") + "!env",
+		filepath.Join(home, "exports", "bundle.tar.gz") + "!inner.jar!config.properties",
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("sources = %v, want %v", sources, want)
+	}
+	for i := range want {
+		if sources[i] != want[i] {
+			t.Errorf("source %d = %q, want %q", i, sources[i], want[i])
+		}
+	}
+
+	if keys := scanArchives(home, ScanOptions{MaxArchiveDepth: 1}, nil); len(keys) != 2 {
+		t.Errorf("depth 1 found %d keys, want 2 without the nested jar", len(keys))
+	}
+}
+
+func TestScanArchivesByteLimit(t *testing.T) {
+	home := t.TempDir()
+	padding := bytes.Repeat([]byte("x"), 4096)
+	writeFile(t, filepath.Join(home, "big.tar.gz"), tarGzBytes(t, map[string][]byte{
+		"padded.env": append(padding, archiveKey...),
+	}))
+	if keys := scanArchives(home, ScanOptions{MaxArchiveBytes: 1024}, nil); len(keys) != 0 {
+		t.Errorf("found %d keys past the byte limit", len(keys))
+	}
+}
+
+func TestScanWithArchivesRedacts(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, "backup.zip"), zipBytes(t, map[string][]byte{".env": []byte(archiveKey)}))
+
+	result, err := Scan(ScanOptions{HomeDir: home, ScanArchives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, key := range result.Keys {
+		if key.Source == filepath.Join(home, "backup.zip")+"!.env" {
+			found = true
+			if !key.Value.IsZero() {
+				t.Error("archive key carries its value at the default redaction")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("archive key missing from %+v", result.Keys)
+	}
+}
+
+// archiveTree writes n zip archives, each holding a key, under home
+func archiveTree(tb testing.TB, home string, n int) {
+	tb.Helper()
+	data := zipBytes(tb, map[string][]byte{"app/.env": []byte(archiveKey)})
+	for i := range n {
+		writeFile(tb, filepath.Join(home, fmt.Sprintf("d%02d", i%10), fmt.Sprintf("backup%03d.zip", i)), data)
+	}
+}
+
+func TestScanArchivesConcurrency(t *testing.T) {
+	home := t.TempDir()
+	archiveTree(t, home, 40)
+
+	serial := scanArchives(home, ScanOptions{Concurrency: 1}, nil)
+	if len(serial) != 40 {
+		t.Fatalf("got %d keys, want 40", len(serial))
+	}
+	parallel := scanArchives(home, ScanOptions{Concurrency: 8}, nil)
+	if len(parallel) != len(serial) {
+		t.Fatalf("got %d keys with 8 workers, want %d", len(parallel), len(serial))
+	}
+	for i := range serial {
+		if parallel[i].Source != serial[i].Source {
+			t.Errorf("key %d: source %q with 8 workers, want %q", i, parallel[i].Source, serial[i].Source)
+		}
+	}
+}
+
+func benchmarkScanArchives(b *testing.B, concurrency int) {
+	home := b.TempDir()
+	archiveTree(b, home, 200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if keys := scanArchives(home, ScanOptions{Concurrency: concurrency}, nil); len(keys) != 200 {
+			b.Fatalf("got %d keys, want 200", len(keys))
+		}
+	}
+}
+
+func BenchmarkScanArchivesSerial(b *testing.B)   { benchmarkScanArchives(b, 1) }
+func BenchmarkScanArchivesParallel(b *testing.B) { benchmarkScanArchives(b, 0) }
@@ -0,0 +1,275 @@
+package aicred
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Attribution is best-effort ownership metadata for a key, so fleet reports
+// can route a finding to the team responsible for it. Fields that could not
+// be determined are empty.
+type Attribution struct {
+	// UID and User identify the owner of the file the key was found in
+	UID  string `json:"uid,omitempty"`
+	User string `json:"user,omitempty"`
+	// GitRepo is the working tree containing the file, and GitRemote the
+	// URL of its origin remote, or of its first remote, with any
+	// credentials removed
+	GitRepo   string `json:"git_repo,omitempty"`
+	GitRemote string `json:"git_remote,omitempty"`
+	// Application is the application whose configuration the file is,
+	// such as "claude-desktop" or "chrome"
+	Application string `json:"application,omitempty"`
+}
+
+// applicationPaths map path fragments to the application that owns them,
+// for files no config instance claims. Fragments use forward slashes and
+// the first match wins.
+var applicationPaths = []struct{ fragment, app string }{
+	{"/.claude.json", "claude-desktop"},
+	{"/.claude/", "claude-desktop"},
+	{"/Application Support/Claude/", "claude-desktop"},
+	{"/AppData/Roaming/Claude/", "claude-desktop"},
+	{"/.gshrc", "gsh"},
+	{"/.ragit/", "ragit"},
+	{"/.langchain", "langchain"},
+	{"/rooveterinaryinc.roo-cline/", "roo-code"},
+	{"/Code/User/", "vscode"},
+	{"/Raycast/", "raycast"},
+	{"/BraveSoftware/Brave-Browser/", "brave"},
+	{"/Microsoft Edge/", "edge"},
+	{"/Microsoft/Edge/", "edge"},
+	{"/Google/Chrome/", "chrome"},
+	{"/google-chrome/", "chrome"},
+	{"/Chromium/", "chromium"},
+	{"/chromium/", "chromium"},
+	{"/Firefox/", "firefox"},
+	{"/.mozilla/firefox/", "firefox"},
+}
+
+// Attribute sets Attribution on every key in the result, including those
+// of its config instances. It reads file metadata and git configuration
+// under the result's sources; anything it cannot read is left empty.
+func (r *ScanResult) Attribute() {
+	a := newAttributor(r.ConfigInstances)
+	for i := range r.Keys {
+		r.Keys[i].Attribution = a.attribute(r.Keys[i])
+	}
+	for i := range r.ConfigInstances {
+		inst := &r.ConfigInstances[i]
+		for j := range inst.Keys {
+			attribution := a.attribute(inst.Keys[j])
+			if attribution == nil {
+				attribution = &Attribution{}
+			}
+			if inst.AppName != "" {
+				attribution.Application = inst.AppName
+			}
+			inst.Keys[j].Attribution = attribution
+		}
+	}
+}
+
+// attributor caches lookups shared by keys found near each other
+type attributor struct {
+	apps  map[string]string
+	users map[string]string
+	// repos maps a directory to the working tree containing it, "" if none
+	repos   map[string]string
+	remotes map[string]string
+}
+
+func newAttributor(instances []ConfigInstance) *attributor {
+	a := &attributor{
+		apps:    make(map[string]string),
+		users:   make(map[string]string),
+		repos:   make(map[string]string),
+		remotes: make(map[string]string),
+	}
+	for _, inst := range instances {
+		if inst.ConfigPath != "" && inst.AppName != "" {
+			a.apps[filepath.Clean(inst.ConfigPath)] = inst.AppName
+		}
+	}
+	return a
+}
+
+func (a *attributor) attribute(key DiscoveredKey) *Attribution {
+	path := key.Source
+	if key.Location != nil && key.Location.Path != "" {
+		path = key.Location.Path
+	}
+	// Nested sources such as archive!entry belong to the outer file
+	path, _, _ = strings.Cut(path, "!")
+	if path == "" {
+		return nil
+	}
+	path = filepath.Clean(path)
+
+	var at Attribution
+	if info, err := os.Stat(path); err == nil {
+		if uid, ok := fileOwner(info); ok {
+			at.UID = uid
+			at.User = a.userName(uid)
+		}
+		dir := path
+		if !info.IsDir() {
+			dir = filepath.Dir(path)
+		}
+		if repo := a.gitRepo(dir); repo != "" {
+			at.GitRepo = repo
+			at.GitRemote = a.gitRemote(repo)
+		}
+	}
+	at.Application = a.application(path)
+	if at == (Attribution{}) {
+		return nil
+	}
+	return &at
+}
+
+func (a *attributor) userName(uid string) string {
+	name, ok := a.users[uid]
+	if !ok {
+		if u, err := user.LookupId(uid); err == nil {
+			name = u.Username
+		}
+		a.users[uid] = name
+	}
+	return name
+}
+
+func (a *attributor) application(path string) string {
+	if app, ok := a.apps[path]; ok {
+		return app
+	}
+	slashed := filepath.ToSlash(path)
+	for _, p := range applicationPaths {
+		if strings.Contains(slashed, p.fragment) || strings.HasSuffix(slashed, strings.TrimSuffix(p.fragment, "/")) {
+			return p.app
+		}
+	}
+	return ""
+}
+
+// gitRepo returns the working tree containing dir, looking for a .git
+// directory or file in dir and its parents
+func (a *attributor) gitRepo(dir string) string {
+	var visited []string
+	repo := ""
+	for {
+		if cached, ok := a.repos[dir]; ok {
+			repo = cached
+			break
+		}
+		visited = append(visited, dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			repo = dir
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for _, d := range visited {
+		a.repos[d] = repo
+	}
+	return repo
+}
+
+func (a *attributor) gitRemote(repo string) string {
+	remote, ok := a.remotes[repo]
+	if !ok {
+		remote = readGitRemote(gitDir(repo))
+		a.remotes[repo] = remote
+	}
+	return remote
+}
+
+// gitDir returns the directory holding repo's configuration, following the
+// "gitdir:" file that worktrees and submodules use in place of .git
+func gitDir(repo string) string {
+	dir := filepath.Join(repo, ".git")
+	info, err := os.Stat(dir)
+	if err != nil || info.IsDir() {
+		return dir
+	}
+	data, err := os.ReadFile(dir)
+	if err != nil {
+		return dir
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return dir
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(repo, target)
+	}
+	// Linked worktrees keep the shared config in the common directory
+	if common, err := os.ReadFile(filepath.Join(target, "commondir")); err == nil {
+		c := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(c) {
+			c = filepath.Join(target, c)
+		}
+		return c
+	}
+	return target
+}
+
+// readGitRemote returns the URL of the origin remote in gitDir's config, or
+// of the first remote when there is no origin
+func readGitRemote(gitDir string) string {
+	f, err := os.Open(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var section, first, origin string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		if !strings.HasPrefix(section, "[remote ") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) != "url" {
+			continue
+		}
+		value = stripURLCredentials(strings.Trim(strings.TrimSpace(value), `"`))
+		if first == "" {
+			first = value
+		}
+		if section == `[remote "origin"]` && origin == "" {
+			origin = value
+		}
+	}
+	if origin != "" {
+		return origin
+	}
+	return first
+}
+
+// stripURLCredentials removes a user's password or token from a remote URL,
+// keeping scp-style addresses such as git@host:path as they are
+func stripURLCredentials(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || u.Host == "" {
+		return remote
+	}
+	if _, hasPassword := u.User.Password(); hasPassword || u.Scheme == "https" || u.Scheme == "http" {
+		u.User = nil
+	}
+	return u.String()
+}
@@ -0,0 +1,218 @@
+package aicred
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// auditFile is the audit log in the data directory
+const auditFile = "audit.jsonl"
+
+// AuditEntry is one line of the audit log: a write a Session made to its
+// instances, label assignments or tags
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Actor is who made the change: SessionOptions.Actor, or the user
+	// running the program
+	Actor string `json:"actor"`
+	// Profile is the profile that was changed
+	Profile string `json:"profile"`
+	// Operation is the Session method that made the change, such as
+	// SaveInstance or RollbackTo
+	Operation string `json:"operation"`
+	// Changes is what changed, as Plan describes it. API keys and
+	// metadata that may hold a secret show as (sensitive).
+	Changes []Change `json:"changes"`
+}
+
+// AuditLogPath returns the audit log of home: audit.jsonl in DataDir
+func AuditLogPath(home string) string {
+	return filepath.Join(DataDir(home), auditFile)
+}
+
+// ReadAuditLog returns the entries of home's audit log made at or after
+// since, oldest first; a zero since returns them all. A home without a log
+// has no entries. A line that does not parse gives an error wrapping
+// ErrParse that names it.
+func ReadAuditLog(home string, since time.Time) ([]AuditEntry, error) {
+	f, err := os.Open(AuditLogPath(home))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w: %v", ErrIO, err)
+	}
+	defer f.Close()
+	var entries []AuditEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w: %v", f.Name(), line, ErrParse, err)
+		}
+		if !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w: %v", ErrIO, err)
+	}
+	return entries, nil
+}
+
+// ReadAuditLog returns the entries of the session's audit log made at or
+// after since, as the package-level ReadAuditLog does. The session appends
+// an entry after every SaveInstance, DeleteInstance, SaveLabels, SaveTags,
+// SaveTagAssignments, Restore and RollbackTo that changed something, so
+// Apply, the repositories and the tag operations are logged too.
+func (s *Session) ReadAuditLog(since time.Time) ([]AuditEntry, error) {
+	return ReadAuditLog(s.homeDir, since)
+}
+
+// audit appends an entry for changes to the audit log. The log is only
+// appended to, one line per write, so concurrent sessions do not
+// interleave entries.
+func (s *Session) audit(operation string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	entry := AuditEntry{
+		Time:      time.Now().UTC(),
+		Actor:     s.actor,
+		Profile:   ActiveProfile(s.homeDir),
+		Operation: operation,
+		Changes:   changes,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("audit %s: %v", operation, err)
+	}
+	p := AuditLogPath(s.homeDir)
+	if err := hardenConfigDir(filepath.Dir(p)); err != nil {
+		return fmt.Errorf("audit %s: %w: %v", operation, ErrIO, err)
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit %s: %w: %v", operation, ErrIO, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("audit %s: %w: %v", operation, ErrIO, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("audit %s: %w: %v", operation, ErrIO, err)
+	}
+	return nil
+}
+
+// auditBefore returns the configuration before an operation that
+// replaces it wholesale, for auditReplaced. One that cannot be read, as
+// Restore and RollbackTo are there to repair, counts as empty.
+func (s *Session) auditBefore() *Config {
+	before, err := CurrentConfig(s)
+	if err != nil {
+		return &Config{Instances: []ProviderInstance{}, Tags: []Tag{}, Labels: []LabelAssignment{}, TagAssignments: []LabelAssignment{}}
+	}
+	return before
+}
+
+// auditReplaced appends an entry for an operation that replaced the
+// configuration files wholesale, comparing before with what the session
+// now loads
+func (s *Session) auditReplaced(operation string, before *Config) error {
+	after, err := CurrentConfig(s)
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", operation, err)
+	}
+	return s.audit(operation, configAudit(after, before))
+}
+
+// instanceAudit describes saving instance over old, which is nil for a
+// new instance
+func instanceAudit(old *ProviderInstance, instance ProviderInstance) []Change {
+	if old == nil {
+		fields := createFields(instanceFields(ProviderInstance{}, auditRedacted(instance)))
+		return []Change{{Action: ActionCreate, Kind: KindInstance, Name: instance.ID, Fields: fields}}
+	}
+	fields := instanceFields(auditRedacted(*old), auditRedacted(instance))
+	if len(fields) == 0 {
+		return nil
+	}
+	return []Change{{Action: ActionUpdate, Kind: KindInstance, Name: instance.ID, Fields: fields}}
+}
+
+// configAudit describes replacing before with after, as Plan does, with
+// secrets left out
+func configAudit(after, before *Config) []Change {
+	redact := func(c *Config) *Config {
+		redacted := *c
+		redacted.Instances = make([]ProviderInstance, len(c.Instances))
+		for i, instance := range c.Instances {
+			redacted.Instances[i] = auditRedacted(instance)
+		}
+		redacted.Tags = auditTags(c.Tags)
+		return &redacted
+	}
+	return Plan(redact(after), redact(before)).Changes
+}
+
+// auditRedacted returns instance with its metadata redacted for the audit
+// log. The key is kept, as instanceFields shows only that it changed.
+func auditRedacted(instance ProviderInstance) ProviderInstance {
+	instance.Metadata = auditMetadata(instance.Metadata)
+	return instance
+}
+
+// auditTags returns copies of tags with their metadata redacted for the
+// audit log
+func auditTags(tags []Tag) []Tag {
+	redacted := make([]Tag, len(tags))
+	for i, tag := range tags {
+		tag.Metadata = auditMetadata(tag.Metadata)
+		redacted[i] = tag
+	}
+	return redacted
+}
+
+// auditMetadata replaces the values of metadata keys that may hold a
+// secret, such as header.X-Api-Key, with a placeholder
+func auditMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	redacted := maps.Clone(metadata)
+	for k := range redacted {
+		name := strings.ToLower(k)
+		if strings.HasPrefix(name, "header.") || strings.Contains(name, "key") || strings.Contains(name, "token") ||
+			strings.Contains(name, "secret") || strings.Contains(name, "password") || strings.Contains(name, "auth") {
+			redacted[k] = sensitive
+		}
+	}
+	return redacted
+}
+
+// defaultActor names the user running the program, for audit entries
+func defaultActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return "unknown"
+}
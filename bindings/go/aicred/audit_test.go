@@ -0,0 +1,125 @@
+package aicred
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	saved := backupIterations
+	backupIterations = 1000
+	t.Cleanup(func() { backupIterations = saved })
+
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSessionWith(home, SessionOptions{Actor: "auditor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	start := time.Now().UTC()
+
+	instance := ProviderInstance{ID: "anthropic-x", ProviderType: "anthropic", BaseURL: "https://api.anthropic.com",
+		APIKey: NewSecretString("sk-ant-audited"), Metadata: map[string]string{"header.X-Api-Key": "hdr-secret", "team": "core"}}
+	if err := s.SaveInstance(instance); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTags([]Tag{{Name: "prod", Metadata: map[string]string{"color": "red"}}}); err != nil {
+		t.Fatal(err)
+	}
+	// Saving the same tags again changes nothing and logs nothing
+	if err := s.SaveTags([]Tag{{Name: "prod", Metadata: map[string]string{"color": "red"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveLabels([]LabelAssignment{{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "openai-main"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteInstance("anthropic-x"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.ReadAuditLog(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, e := range entries {
+		ops = append(ops, e.Operation)
+		if e.Actor != "auditor" || e.Profile != DefaultProfile || e.Time.Before(start) {
+			t.Errorf("entry %+v", e)
+		}
+	}
+	if strings.Join(ops, ",") != "SaveInstance,SaveTags,SaveLabels,DeleteInstance" {
+		t.Fatalf("operations = %v", ops)
+	}
+	create := entries[0].Changes[0]
+	if create.Action != ActionCreate || create.Kind != KindInstance || create.Name != "anthropic-x" {
+		t.Errorf("SaveInstance change = %+v", create)
+	}
+	fields := map[string]string{}
+	for _, f := range create.Fields {
+		fields[f.Field] = f.New
+	}
+	if fields["api_key"] != sensitive || fields["metadata"] != "{header.X-Api-Key=(sensitive), team=core}" {
+		t.Errorf("SaveInstance fields = %+v", create.Fields)
+	}
+	if label := entries[2].Changes[0]; label.Action != ActionCreate || label.Name != "prod on openai-main" {
+		t.Errorf("SaveLabels change = %+v", label)
+	}
+	if del := entries[3].Changes[0]; del.Action != ActionDelete || del.Name != "anthropic-x" {
+		t.Errorf("DeleteInstance change = %+v", del)
+	}
+
+	raw, err := os.ReadFile(AuditLogPath(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("sk-ant-audited")) || bytes.Contains(raw, []byte("hdr-secret")) {
+		t.Error("the audit log holds a secret")
+	}
+	if info, err := os.Stat(AuditLogPath(home)); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log mode = %v, %v", info.Mode(), err)
+	}
+	if later, err := s.ReadAuditLog(time.Now().Add(time.Hour)); err != nil || len(later) != 0 {
+		t.Errorf("entries from the future = %+v, %v", later, err)
+	}
+
+	// A restore neither backs up nor rewinds the log, and is logged itself
+	var backup bytes.Buffer
+	if err := s.Backup(&backup, []byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTags(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(&backup, []byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = ReadAuditLog(home, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 || entries[4].Operation != "SaveTags" || entries[5].Operation != "Restore" {
+		t.Fatalf("entries after restore = %+v", entries)
+	}
+	if tag := entries[5].Changes[0]; tag.Action != ActionCreate || tag.Kind != KindTag || tag.Name != "prod" {
+		t.Errorf("Restore change = %+v", tag)
+	}
+
+	f, err := os.OpenFile(AuditLogPath(home), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{not json\n")
+	f.Close()
+	if _, err := ReadAuditLog(home, time.Time{}); !errors.Is(err, ErrParse) || !strings.Contains(err.Error(), "line 7") {
+		t.Errorf("malformed line: err = %v", err)
+	}
+	if entries, err := ReadAuditLog(t.TempDir(), time.Time{}); err != nil || entries != nil {
+		t.Errorf("missing log = %+v, %v", entries, err)
+	}
+}
@@ -0,0 +1,107 @@
+package aicred
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ProviderAzureOpenAI is the provider type of Azure OpenAI resources. Each
+// resource has its own host, and requests go to a named deployment of a
+// model rather than naming the model, so an instance maps the model IDs in
+// its Models to deployments with MetadataDeploymentPrefix keys and sets
+// MetadataAPIVersion. The key is sent in the api-key header.
+const ProviderAzureOpenAI = "azure"
+
+// MetadataDeploymentPrefix starts the instance metadata keys mapping a
+// model to an Azure OpenAI deployment: "deployment.gpt-4o" holds the name
+// of the deployment serving gpt-4o
+const MetadataDeploymentPrefix = "deployment."
+
+func init() {
+	providerTypes[ProviderAzureOpenAI] = ProviderType{
+		Name:       ProviderAzureOpenAI,
+		Hosts:      []string{"*.openai.azure.com", "*.cognitiveservices.azure.com", "*.services.ai.azure.com"},
+		KeyPattern: regexp.MustCompile(`^[A-Za-z0-9]{32,}$`),
+	}
+}
+
+// AzureDeployments returns instance's model ID to deployment name mapping
+func AzureDeployments(instance ProviderInstance) map[string]string {
+	deployments := map[string]string{}
+	for key, value := range instance.Metadata {
+		if model, ok := strings.CutPrefix(key, MetadataDeploymentPrefix); ok {
+			deployments[model] = value
+		}
+	}
+	return deployments
+}
+
+// SetAzureDeployment maps model to deployment on instance and adds model
+// to its Models. An empty deployment removes the mapping.
+func SetAzureDeployment(instance *ProviderInstance, model, deployment string) {
+	if instance.Metadata == nil {
+		instance.Metadata = map[string]string{}
+	}
+	if deployment == "" {
+		delete(instance.Metadata, MetadataDeploymentPrefix+model)
+		return
+	}
+	instance.Metadata[MetadataDeploymentPrefix+model] = deployment
+	if !slices.Contains(instance.Models, model) {
+		instance.Models = append(instance.Models, model)
+	}
+}
+
+// Resolve returns the URL requests for model are sent to, with the path of
+// the operation, such as "chat/completions", still to be joined on with
+// JoinPath. For most providers that is the BaseURL and the model goes in
+// the request body. For Azure OpenAI it is the model's deployment URL,
+// BaseURL/openai/deployments/<deployment>, with the api-version query
+// parameter set. A model with no deployment gives an error wrapping
+// ErrNotFound, and an Azure instance without an api_version one wrapping
+// ErrInvalidInstance.
+func (p ProviderInstance) Resolve(model string) (*url.URL, error) {
+	base, err := url.Parse(p.BaseURL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("instance %q: base URL %q is not a URL: %w", p.ID, p.BaseURL, ErrInvalidInstance)
+	}
+	if p.ProviderType != ProviderAzureOpenAI {
+		return base, nil
+	}
+	deployment, ok := p.Metadata[MetadataDeploymentPrefix+model]
+	if !ok {
+		return nil, fmt.Errorf("instance %q: no deployment for model %q: %w", p.ID, model, ErrNotFound)
+	}
+	version := p.Metadata[MetadataAPIVersion]
+	if version == "" {
+		return nil, fmt.Errorf("instance %q: Azure OpenAI needs %s: %w", p.ID, MetadataAPIVersion, ErrInvalidInstance)
+	}
+	u := base.JoinPath("openai", "deployments", deployment)
+	query := u.Query()
+	query.Set("api-version", version)
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+// URL returns the URL requests for the selected model are sent to, as
+// ProviderInstance.Resolve gives it
+func (s Selection) URL() (*url.URL, error) {
+	return s.Instance.Resolve(s.Model)
+}
+
+// validateAzure reports an Azure OpenAI instance with no api_version or
+// with models that have no deployment
+func validateAzure(instance ProviderInstance, report func(format string, args ...any)) {
+	if instance.Metadata[MetadataAPIVersion] == "" {
+		report("Azure OpenAI instance has no %s", MetadataAPIVersion)
+	}
+	deployments := AzureDeployments(instance)
+	for _, model := range instance.Models {
+		if deployments[model] == "" {
+			report("model %q has no Azure OpenAI deployment", model)
+		}
+	}
+}
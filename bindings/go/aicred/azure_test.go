@@ -0,0 +1,75 @@
+package aicred
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func azureInstance() ProviderInstance {
+	instance := ProviderInstance{
+		ID:           "azure-acme",
+		ProviderType: ProviderAzureOpenAI,
+		BaseURL:      "https://acme.openai.azure.com",
+		APIKey:       NewSecretString(strings.Repeat("a1", 16)),
+		Metadata:     map[string]string{MetadataAPIVersion: "2024-10-21"},
+	}
+	SetAzureDeployment(&instance, "gpt-4o", "prod-gpt4o")
+	return instance
+}
+
+func TestAzureResolve(t *testing.T) {
+	instance := azureInstance()
+	if len(instance.Models) != 1 || AzureDeployments(instance)["gpt-4o"] != "prod-gpt4o" {
+		t.Fatalf("instance = %+v", instance)
+	}
+
+	u, err := instance.Resolve("gpt-4o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://acme.openai.azure.com/openai/deployments/prod-gpt4o/chat/completions?api-version=2024-10-21"
+	if got := u.JoinPath("chat", "completions").String(); got != want {
+		t.Errorf("Resolve = %s, want %s", got, want)
+	}
+	if _, err := instance.Resolve("gpt-4o-mini"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve of an undeployed model = %v, want ErrNotFound", err)
+	}
+
+	openai := Selection{Instance: ProviderInstance{ID: "openai", ProviderType: "openai", BaseURL: "https://api.openai.com/v1"}, Model: "gpt-4o"}
+	if u, err := openai.URL(); err != nil || u.String() != "https://api.openai.com/v1" {
+		t.Errorf("URL for openai = %v, %v, want the base URL", u, err)
+	}
+
+	delete(instance.Metadata, MetadataAPIVersion)
+	if _, err := instance.Resolve("gpt-4o"); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("Resolve without api_version = %v, want ErrInvalidInstance", err)
+	}
+}
+
+func TestAzureValidateAndAuthorize(t *testing.T) {
+	instance := azureInstance()
+	if err := ValidateProviderInstance(instance, ValidationOptions{Strict: true}); err != nil {
+		t.Errorf("valid Azure instance: %v", err)
+	}
+	instance.Models = append(instance.Models, "o1")
+	delete(instance.Metadata, MetadataAPIVersion)
+	err := ValidateProviderInstance(instance, ValidationOptions{Strict: true})
+	if !errors.Is(err, ErrInvalidInstance) || !strings.Contains(err.Error(), "api_version") || !strings.Contains(err.Error(), `"o1"`) {
+		t.Errorf("ValidateProviderInstance = %v, want the api_version and o1 problems", err)
+	}
+
+	source, err := TokenSource(azureInstance())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://acme.openai.azure.com", nil)
+	if err := source.Authorize(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("api-key") == "" || req.Header.Get("Authorization") != "" {
+		t.Errorf("headers = %v, want only api-key", req.Header)
+	}
+}
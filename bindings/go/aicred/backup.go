@@ -0,0 +1,367 @@
+package aicred
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// backupMagic starts every backup, followed by a format version byte
+const backupMagic = "AICREDBK"
+
+// maxBackupSize bounds what Restore reads, compressed and expanded, and so
+// what Backup writes. Tests lower it.
+var maxBackupSize = 64 << 20
+
+const (
+	backupVersion = 1
+	// backupDataPrefix holds DataDir files in the archive when DataDir is
+	// not the configuration directory
+	backupDataPrefix = "data/"
+	saltSize         = 16
+	// maxBackupIterations bounds the work factor Restore accepts, so a
+	// forged header cannot stall it
+	maxBackupIterations = 10_000_000
+)
+
+// backupIterations is the PBKDF2-HMAC-SHA256 work factor for new backups;
+// Restore reads it from the backup. Tests lower it.
+var backupIterations uint32 = 600_000
+
+// backupSkipped are files in the configuration directory that a backup
+// leaves out because they are rebuilt on demand
+var backupSkipped = map[string]bool{
+	"scan-cache.json": true,
+}
+
+// Backup writes the session's configuration to w as one gzipped tarball
+// encrypted with AES-256-GCM under a key derived from passphrase. It covers
+// everything in the configuration directory, such as instances, labels,
+// tags and organizations, and the data directory when that is separate,
+// such as history and baselines; the incremental scan cache and the audit
+// log are left out.
+// Keep the passphrase: without it the backup cannot be read. Files too
+// large for Restore to accept give an error wrapping ErrInvalidOption
+// before anything is written to w.
+func (s *Session) Backup(w io.Writer, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return fmt.Errorf("backup: empty passphrase: %w", ErrInvalidOption)
+	}
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	// Restore refuses a tarball that expands past the limit, so stop
+	// writing one as soon as it does
+	tw := tar.NewWriter(&backupLimitWriter{w: gz})
+	config := ConfigDir(s.homeDir)
+	if err := addBackupDir(tw, config, ""); err != nil {
+		return backupError(err)
+	}
+	if data := DataDir(s.homeDir); data != config {
+		if err := addBackupDir(tw, data, backupDataPrefix); err != nil {
+			return backupError(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return backupError(err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("backup: %w: %v", ErrIO, err)
+	}
+	defer zero(archive.Bytes())
+
+	header := make([]byte, 0, len(backupMagic)+1+4+saltSize)
+	header = append(header, backupMagic...)
+	header = append(header, backupVersion)
+	header = binary.BigEndian.AppendUint32(header, backupIterations)
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	header = append(header, salt...)
+
+	gcm, err := backupCipher(passphrase, salt, backupIterations)
+	if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	sealed := gcm.Seal(nil, nonce, archive.Bytes(), header)
+	if size := len(header) + len(nonce) + len(sealed); size > maxBackupSize {
+		return backupError(fmt.Errorf("%w: %d bytes", errBackupTooLarge, size))
+	}
+	for _, part := range [][]byte{header, nonce, sealed} {
+		if _, err := w.Write(part); err != nil {
+			return fmt.Errorf("backup: %w: %v", ErrIO, err)
+		}
+	}
+	return nil
+}
+
+// errBackupTooLarge reports a backup Restore would refuse
+var errBackupTooLarge = errors.New("over the size limit")
+
+// backupError wraps an error met while writing a backup
+func backupError(err error) error {
+	if errors.Is(err, errBackupTooLarge) {
+		return fmt.Errorf("backup: %v; Restore accepts at most %d bytes: %w", err, maxBackupSize, ErrInvalidOption)
+	}
+	return fmt.Errorf("backup: %w: %v", ErrIO, err)
+}
+
+// backupLimitWriter fails with errBackupTooLarge once more than
+// maxBackupSize bytes have been written
+type backupLimitWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *backupLimitWriter) Write(p []byte) (int, error) {
+	if l.n += len(p); l.n > maxBackupSize {
+		return 0, errBackupTooLarge
+	}
+	return l.w.Write(p)
+}
+
+// addBackupDir adds the regular files under dir to tw, named relative to
+// dir with prefix. A missing dir adds nothing.
+func addBackupDir(tw *tar.Writer, dir, prefix string) error {
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || !d.Type().IsRegular() || (prefix == "" && backupSkipped[rel]) || rel == auditFile {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > int64(maxBackupSize) {
+			return fmt.Errorf("%w: %s is %d bytes", errBackupTooLarge, rel, info.Size())
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		defer zero(data)
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     prefix + filepath.ToSlash(rel),
+			Size:     int64(len(data)),
+			Mode:     int64(configFileMode),
+			ModTime:  info.ModTime(),
+		}); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Restore reads a backup written by Backup and writes its files into the
+// session's configuration and data directories, replacing files of the
+// same name and leaving others alone, then reloads the session. It returns
+// an error wrapping ErrBadPassphrase if the passphrase is wrong or the
+// backup was altered, and ErrParse if r does not hold a backup. Nothing is
+// written unless the whole backup decrypts.
+func (s *Session) Restore(r io.Reader, passphrase []byte) error {
+	sealed, err := io.ReadAll(io.LimitReader(r, int64(maxBackupSize)+1))
+	if err != nil {
+		return fmt.Errorf("restore: %w: %v", ErrIO, err)
+	}
+	archive, err := openBackup(sealed, passphrase)
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	defer zero(archive)
+
+	files, err := readBackupArchive(archive)
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	before := s.auditBefore()
+	if err := s.snapshot(); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	config, data := ConfigDir(s.homeDir), DataDir(s.homeDir)
+	for _, f := range files {
+		dir, name := config, f.name
+		if rest, ok := strings.CutPrefix(name, backupDataPrefix); ok {
+			dir, name = data, rest
+		}
+		if name == auditFile {
+			continue // the audit log is only appended to
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := hardenConfigDir(filepath.Dir(p)); err != nil {
+			return fmt.Errorf("restore: %w: %v", ErrIO, err)
+		}
+		if err := writeBytesAtomic(p, f.data); err != nil {
+			return fmt.Errorf("restore %s: %w: %v", name, ErrIO, err)
+		}
+		zero(f.data)
+	}
+
+	if err := s.Reload(); err != nil {
+		return err
+	}
+	s.storesChanged(time.Now().UTC())
+	return s.auditReplaced("Restore", before)
+}
+
+// storesChanged publishes a StoreChanged event for the instances, labels
+// and organizations, after their files were replaced wholesale
+func (s *Session) storesChanged(now time.Time) {
+	if instances, err := s.LoadInstances(); err == nil {
+		s.events.Publish(events.StoreChanged{Time: now, Store: "instances", Count: len(instances)})
+	}
+	if labels, err := s.LoadLabels(); err == nil {
+		s.events.Publish(events.StoreChanged{Time: now, Store: "labels", Count: len(labels)})
+	}
+	if organizations, err := s.LoadOrganizations(); err == nil {
+		s.events.Publish(events.StoreChanged{Time: now, Store: "organizations", Count: len(organizations)})
+	}
+}
+
+// openBackup checks the header of a backup and decrypts the archive in it
+func openBackup(sealed, passphrase []byte) ([]byte, error) {
+	headerSize := len(backupMagic) + 1 + 4 + saltSize
+	if len(sealed) > maxBackupSize {
+		return nil, fmt.Errorf("backup exceeds %d bytes: %w", maxBackupSize, ErrParse)
+	}
+	if len(sealed) < headerSize || string(sealed[:len(backupMagic)]) != backupMagic {
+		return nil, fmt.Errorf("not an aicred backup: %w", ErrParse)
+	}
+	if v := sealed[len(backupMagic)]; v != backupVersion {
+		return nil, fmt.Errorf("backup format version %d is not supported: %w", v, ErrParse)
+	}
+	header := sealed[:headerSize]
+	iterations := binary.BigEndian.Uint32(header[len(backupMagic)+1:])
+	if iterations == 0 || iterations > maxBackupIterations {
+		return nil, fmt.Errorf("backup work factor %d is out of range: %w", iterations, ErrParse)
+	}
+	salt := header[headerSize-saltSize:]
+
+	gcm, err := backupCipher(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	rest := sealed[headerSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("backup is truncated: %w", ErrParse)
+	}
+	archive, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return archive, nil
+}
+
+type backupFile struct {
+	name string
+	data []byte
+}
+
+// readBackupArchive unpacks the decrypted tarball, rejecting entries that
+// would land outside the directories being restored
+func readBackupArchive(archive []byte) ([]backupFile, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	tr := tar.NewReader(io.LimitReader(gz, int64(maxBackupSize)))
+	var files []backupFile
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrParse, err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(h.Name)
+		if !fs.ValidPath(name) || name == "." {
+			return nil, fmt.Errorf("entry %q escapes the configuration directory: %w", h.Name, ErrParse)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrParse, err)
+		}
+		files = append(files, backupFile{name: name, data: data})
+	}
+}
+
+// backupCipher derives the AES-256-GCM key for a backup from passphrase
+func backupCipher(passphrase, salt []byte, iterations uint32) (cipher.AEAD, error) {
+	key := pbkdf2SHA256(passphrase, salt, int(iterations), 32)
+	defer zero(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 is PBKDF2 (RFC 8018) with HMAC-SHA256, which the standard
+// library only gained in Go 1.24
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	u := make([]byte, 0, sha256.Size)
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u = prf.Sum(u[:0])
+		t := bytes.Clone(u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// Backup writes the Client's configuration to w, encrypted; see
+// Session.Backup
+func (c *Client) Backup(w io.Writer, passphrase []byte) error {
+	return c.session.Backup(w, passphrase)
+}
+
+// Restore restores a backup into the Client's configuration; see
+// Session.Restore
+func (c *Client) Restore(r io.Reader, passphrase []byte) error {
+	return c.session.Restore(r, passphrase)
+}
@@ -0,0 +1,142 @@
+package aicred
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+func TestBackupRestore(t *testing.T) {
+	saved := backupIterations
+	backupIterations = 1000
+	t.Cleanup(func() { backupIterations = saved })
+
+	home := t.TempDir()
+	writeInstance(t, home)
+	config := filepath.Join(home, ".config", "aicred")
+	writeFile(t, filepath.Join(config, "tags.yaml"), []byte("- name: prod\n"))
+	writeFile(t, filepath.Join(config, "scan-cache.json"), []byte("{}"))
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Organizations().Save(Organization{ID: "platform"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var backup bytes.Buffer
+	passphrase := []byte("correct horse battery staple")
+	if err := s.Backup(&backup, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(backup.Bytes(), []byte("sk-test")) || bytes.Contains(backup.Bytes(), []byte("openai-main")) {
+		t.Fatal("backup holds plaintext")
+	}
+	if err := s.Backup(&bytes.Buffer{}, nil); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Backup without passphrase: err = %v", err)
+	}
+
+	target := t.TempDir()
+	restored, err := OpenSession(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if err := restored.Restore(bytes.NewReader(backup.Bytes()), []byte("wrong")); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("Restore with the wrong passphrase: err = %v", err)
+	}
+	tampered := bytes.Clone(backup.Bytes())
+	tampered[len(tampered)-1] ^= 1
+	if err := restored.Restore(bytes.NewReader(tampered), passphrase); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("Restore of an altered backup: err = %v", err)
+	}
+	if err := restored.Restore(bytes.NewReader([]byte("PK\x03\x04")), passphrase); !errors.Is(err, ErrParse) {
+		t.Errorf("Restore of a zip: err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, ".config", "aicred")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("a failed restore wrote files")
+	}
+
+	bus := events.New()
+	var changed []string
+	defer events.Subscribe(bus, func(e events.StoreChanged) { changed = append(changed, e.Store) })()
+	restored.events = bus
+	if err := restored.Restore(&backup, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if instances, err := restored.LoadInstances(); err != nil || len(instances) != 1 || instances[0].ID != "openai-main" {
+		t.Errorf("restored instances = %+v, %v", instances, err)
+	}
+	if orgs, err := restored.Organizations().List(); err != nil || len(orgs) != 1 {
+		t.Errorf("restored organizations = %+v, %v", orgs, err)
+	}
+	restoredConfig := filepath.Join(target, ".config", "aicred")
+	if data, err := os.ReadFile(filepath.Join(restoredConfig, "tags.yaml")); err != nil || string(data) != "- name: prod\n" {
+		t.Errorf("tags.yaml = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(restoredConfig, "scan-cache.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("the scan cache was backed up")
+	}
+	if len(changed) != 3 {
+		t.Errorf("StoreChanged for %v", changed)
+	}
+	if issues := CheckPermissions(target, nil); len(issues) != 0 {
+		t.Errorf("restored files: %+v", issues)
+	}
+}
+
+func TestBackupTooLarge(t *testing.T) {
+	savedIterations, savedSize := backupIterations, maxBackupSize
+	backupIterations, maxBackupSize = 1000, 8192
+	t.Cleanup(func() { backupIterations, maxBackupSize = savedIterations, savedSize })
+
+	home := t.TempDir()
+	config := filepath.Join(home, ".config", "aicred")
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	noise := make([]byte, 3500)
+	rand.Read(noise)
+	writeFile(t, filepath.Join(config, "a.bin"), noise)
+	var backup bytes.Buffer
+	if err := s.Backup(&backup, []byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(&backup, []byte("passphrase")); err != nil {
+		t.Errorf("restore of a backup under the limit: %v", err)
+	}
+
+	// Together the files pass the limit, and alone one does
+	writeFile(t, filepath.Join(config, "b.bin"), noise)
+	writeFile(t, filepath.Join(config, "c.bin"), make([]byte, 9000))
+	backup.Reset()
+	if err := s.Backup(&backup, []byte("passphrase")); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("oversized backup: err = %v", err)
+	}
+	if backup.Len() != 0 {
+		t.Errorf("an oversized backup wrote %d bytes", backup.Len())
+	}
+	os.Remove(filepath.Join(config, "c.bin"))
+	if err := s.Backup(&backup, []byte("passphrase")); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("backup of incompressible files over the limit: err = %v", err)
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914 section 11
+	got := pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64)
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("PBKDF2 = %x", got)
+	}
+}
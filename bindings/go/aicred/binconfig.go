@@ -0,0 +1,102 @@
+package aicred
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// defaultMaxBinaryConfigSize bounds plist and database files read when
+	// MaxFileSize is unset; app databases are often larger than text configs
+	defaultMaxBinaryConfigSize = 32 << 20
+	// binaryConfigDepth bounds how far below each root the search descends
+	binaryConfigDepth = 4
+)
+
+// binaryConfigRoots are the directories, relative to the home directory,
+// where applications keep binary plists and SQLite settings. The AppData
+// roots follow %APPDATA% and %LOCALAPPDATA%; see homePath.
+var binaryConfigRoots = []string{
+	"Library/Preferences",
+	"Library/Application Support",
+	".config",
+	".local/share",
+	"AppData/Roaming",
+	"AppData/Local",
+}
+
+// binaryConfigExts are the file extensions examined; contents are checked
+// against the format's magic bytes before parsing
+var binaryConfigExts = map[string]bool{
+	".plist":   true,
+	".db":      true,
+	".sqlite":  true,
+	".sqlite3": true,
+}
+
+// scanBinaryConfigs searches binary plists and SQLite databases under the
+// application data directories of home for keys in their string values
+func scanBinaryConfigs(home string, options ScanOptions, run *scanRun) []DiscoveredKey {
+	limit := int64(options.MaxFileSize)
+	if limit <= 0 {
+		limit = defaultMaxBinaryConfigSize
+	}
+
+	w := newWalker(passBinaryConfigs, home, options, run)
+	var paths []string
+	for _, rel := range binaryConfigRoots {
+		w.walk(homePath(home, rel), binaryConfigDepth, func(p string, d fs.DirEntry) {
+			if !binaryConfigExts[strings.ToLower(filepath.Ext(p))] {
+				return
+			}
+			info, err := d.Info()
+			if err != nil {
+				return
+			}
+			if info.Size() > limit {
+				run.warn(passBinaryConfigs, p, WarningTooLarge, fmt.Errorf("%d bytes exceeds the limit of %d", info.Size(), limit))
+				return
+			}
+			paths = append(paths, p)
+		})
+	}
+	return run.scanFiles(passBinaryConfigs, paths, options, func(p string) []DiscoveredKey {
+		keys, err := scanBinaryConfig(p, options)
+		if err != nil {
+			run.warn(passBinaryConfigs, p, warningKindOf(err), err)
+		}
+		return keys
+	})
+}
+
+// scanBinaryConfig parses one plist or database file and runs the detector
+// over its strings. Files in neither format yield nothing and no error.
+func scanBinaryConfig(p string, options ScanOptions) ([]DiscoveredKey, error) {
+	data, err := os.ReadFile(p)
+	defer zero(data)
+	if err != nil {
+		logger().Debug("skipping unreadable file", slog.String("path", p), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var lines []string
+	switch {
+	case bytes.HasPrefix(data, []byte(bplistMagic)):
+		lines = bplistStrings(data)
+	case bytes.HasPrefix(data, []byte(sqliteMagic)):
+		lines = sqliteStrings(data)
+	default:
+		return nil, nil
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	text := []byte(strings.Join(lines, "\n"))
+	defer zero(text)
+	return pathOnly(detectKeys(p, text, options, true)), nil
+}
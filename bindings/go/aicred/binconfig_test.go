@@ -0,0 +1,117 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func keyProviders(keys []DiscoveredKey) []string {
+	var out []string
+	for _, key := range keys {
+		out = append(out, key.Provider)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestBplistStrings(t *testing.T) {
+	data, err := os.ReadFile("testdata/settings.plist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := keyProviders(scanBinaryConfigFromBytes(t, "settings.plist", data))
+	if len(got) != 2 || got[0] != "anthropic" || got[1] != "openai" {
+		t.Errorf("providers = %v, want [anthropic openai]", got)
+	}
+}
+
+func TestBplistSharedOffsets(t *testing.T) {
+	// Every offset table entry names the same 60 KB string
+	const n = 1 << 16
+	data := append([]byte(bplistMagic), 0x5f, 0x11, 0xea, 0x60)
+	data = append(data, bytes.Repeat([]byte("a"), 60000)...)
+	table := len(data)
+	data = append(data, bytes.Repeat([]byte{8}, n)...)
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = 1, 1
+	binary.BigEndian.PutUint64(trailer[8:], n)
+	binary.BigEndian.PutUint64(trailer[24:], uint64(table))
+	data = append(data, trailer...)
+
+	if lines := bplistStrings(data); len(lines) != 1 || len(lines[0]) != 60000 {
+		t.Errorf("got %d lines, want the string once", len(lines))
+	}
+}
+
+func TestSqliteStrings(t *testing.T) {
+	data, err := os.ReadFile("testdata/settings.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := keyProviders(scanBinaryConfigFromBytes(t, "settings.sqlite", data))
+	if len(got) != 2 || got[0] != "groq" || got[1] != "groq" {
+		t.Errorf("providers = %v, want [groq groq]", got)
+	}
+}
+
+func TestBinaryParsersRejectDamage(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte(bplistMagic),
+		append([]byte(bplistMagic), make([]byte, 40)...),
+		[]byte(sqliteMagic),
+		append([]byte(sqliteMagic), make([]byte, 600)...),
+	} {
+		bplistStrings(data)
+		sqliteStrings(data)
+	}
+}
+
+func TestScanBinaryConfigs(t *testing.T) {
+	home := t.TempDir()
+	for src, dst := range map[string]string{
+		"testdata/settings.plist":  "Library/Preferences/com.example.app.plist",
+		"testdata/settings.sqlite": "Library/Application Support/Example/store.db",
+	} {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(home, filepath.FromSlash(dst)), data)
+	}
+
+	if got := scanBinaryConfigs(home, ScanOptions{}, nil); len(got) != 4 {
+		t.Errorf("found %d keys, want 4", len(got))
+	}
+	if got := scanBinaryConfigs(home, ScanOptions{OnlyProviders: []string{"groq"}}, nil); len(got) != 2 {
+		t.Errorf("found %d groq keys, want 2", len(got))
+	}
+}
+
+// scanBinaryConfigFromBytes writes data to a temporary file and scans it
+func scanBinaryConfigFromBytes(t *testing.T, name string, data []byte) []DiscoveredKey {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	writeFile(t, p, data)
+	keys, err := scanBinaryConfig(p, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func FuzzBinaryConfigs(f *testing.F) {
+	for _, name := range []string{"testdata/settings.plist", "testdata/settings.sqlite", "testdata/firefox-ls.sqlite"} {
+		if data, err := os.ReadFile(name); err == nil {
+			f.Add(data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bplistStrings(data)
+		sqliteStrings(data)
+	})
+}
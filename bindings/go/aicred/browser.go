@@ -0,0 +1,316 @@
+package aicred
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// chromiumRoots are Chromium-family user data directories relative to the
+// home directory, for Linux, macOS and Windows; see homePath
+var chromiumRoots = []string{
+	".config/google-chrome",
+	".config/chromium",
+	".config/microsoft-edge",
+	".config/BraveSoftware/Brave-Browser",
+	"Library/Application Support/Google/Chrome",
+	"Library/Application Support/Chromium",
+	"Library/Application Support/Microsoft Edge",
+	"Library/Application Support/BraveSoftware/Brave-Browser",
+	"AppData/Local/Google/Chrome/User Data",
+	"AppData/Local/Chromium/User Data",
+	"AppData/Local/Microsoft/Edge/User Data",
+	"AppData/Local/BraveSoftware/Brave-Browser/User Data",
+}
+
+// firefoxRoots are Firefox profile directories relative to the home directory
+var firefoxRoots = []string{
+	".mozilla/firefox",
+	"Library/Application Support/Firefox/Profiles",
+	"AppData/Roaming/Mozilla/Firefox/Profiles",
+}
+
+// minPrintableRun is the shortest string pulled out of opaque values
+const minPrintableRun = 8
+
+// scanBrowserStorage searches Chromium and Firefox profiles under home for
+// keys that web apps keep in localStorage or IndexedDB. localStorage keys
+// report their source as "<leveldb dir>!<origin>". Each storage directory or
+// database counts as one file for the ignore rules and PerFileTimeout.
+func scanBrowserStorage(home string, options ScanOptions, run *scanRun) []DiscoveredKey {
+	limit := int64(options.MaxFileSize)
+	if limit <= 0 {
+		limit = defaultMaxBinaryConfigSize
+	}
+
+	type store struct {
+		path string
+		dir  bool
+		scan func(p string) []DiscoveredKey
+	}
+	var stores []store
+	for _, rel := range chromiumRoots {
+		for _, profile := range subdirs(homePath(home, rel)) {
+			name := filepath.Base(profile)
+			if name != "Default" && !strings.HasPrefix(name, "Profile ") {
+				continue
+			}
+
+			stores = append(stores, store{filepath.Join(profile, "Local Storage", "leveldb"), true, func(dir string) []DiscoveredKey {
+				origins := make(map[string][]string)
+				leveldbRecords(dir, limit, passBrowserStorage, run, func(k, v []byte) {
+					origin, item, ok := chromeLocalStorageKey(k)
+					if ok {
+						origins[origin] = append(origins[origin], item+" = "+chromeString(v))
+					}
+				})
+				var keys []DiscoveredKey
+				for origin, lines := range origins {
+					keys = append(keys, browserKeys(dir+"!"+origin, lines, options)...)
+				}
+				return keys
+			}})
+
+			idb, _ := filepath.Glob(filepath.Join(profile, "IndexedDB", "*.indexeddb.leveldb"))
+			for _, dir := range idb {
+				stores = append(stores, store{dir, true, func(dir string) []DiscoveredKey {
+					var lines []string
+					leveldbRecords(dir, limit, passBrowserStorage, run, func(_, v []byte) {
+						lines = append(lines, v8Strings(v)...)
+						lines = append(lines, printableRuns(v)...)
+					})
+					return browserKeys(dir, lines, options)
+				}})
+			}
+		}
+	}
+
+	for _, rel := range firefoxRoots {
+		for _, profile := range subdirs(homePath(home, rel)) {
+			// Storage used before Firefox 68
+			if p := filepath.Join(profile, "webappsstore.sqlite"); fileExists(p) {
+				stores = append(stores, store{p, false, func(p string) []DiscoveredKey {
+					var lines []string
+					readBrowserFile(p, limit, run, func(data []byte) { lines = sqliteStrings(data) })
+					return browserKeys(p, lines, options)
+				}})
+			}
+
+			ls, _ := filepath.Glob(filepath.Join(profile, "storage", "default", "*", "ls", "data.sqlite"))
+			for _, p := range ls {
+				stores = append(stores, store{p, false, func(p string) []DiscoveredKey {
+					var lines []string
+					readBrowserFile(p, limit, run, func(data []byte) {
+						sqliteRows(data, func(row []sqliteColumn) {
+							lines = append(lines, firefoxRow(row))
+						})
+					})
+					return browserKeys(p, lines, options)
+				}})
+			}
+		}
+	}
+
+	w := newWalker(passBrowserStorage, home, options, run)
+	var keys []DiscoveredKey
+	for _, s := range stores {
+		if run.expired() {
+			break
+		}
+		if w.ignoredPath(s.path, s.dir) {
+			continue
+		}
+		keys = append(keys, run.timeLimited(passBrowserStorage, s.scan, options.PerFileTimeout)(s.path)...)
+	}
+	return keys
+}
+
+// browserKeys runs the detector over the strings recovered from one store.
+// Tables and the log often hold the same record, so each key is reported
+// once per source.
+func browserKeys(source string, lines []string, options ScanOptions) []DiscoveredKey {
+	if len(lines) == 0 {
+		return nil
+	}
+	text := []byte(strings.Join(lines, "\n"))
+	defer zero(text)
+	var keys []DiscoveredKey
+	seen := make(map[string]bool)
+	for _, key := range pathOnly(detectKeys(source, text, options, true)) {
+		if !seen[key.Hash] {
+			seen[key.Hash] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// readBrowserFile reads the database p for fn, recording in run what was
+// read or why it was not
+func readBrowserFile(p string, limit int64, run *scanRun, fn func(data []byte)) {
+	info, err := os.Stat(p)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+	if info.Size() > limit {
+		run.warn(passBrowserStorage, p, WarningTooLarge, fmt.Errorf("%d bytes exceeds the limit of %d", info.Size(), limit))
+		return
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		run.warn(passBrowserStorage, p, warningKindOf(err), err)
+		return
+	}
+	run.read(int64(len(data)))
+	fn(data)
+	zero(data)
+}
+
+// chromeLocalStorageKey splits a Chromium localStorage record key of the form
+// "_" origin NUL encoded-key
+func chromeLocalStorageKey(k []byte) (origin, item string, ok bool) {
+	if len(k) < 2 || k[0] != '_' {
+		return "", "", false
+	}
+	origin, rest, ok := strings.Cut(string(k[1:]), "\x00")
+	if !ok {
+		return "", "", false
+	}
+	return origin, chromeString([]byte(rest)), true
+}
+
+// chromeString decodes a Chromium localStorage string, whose first byte
+// selects UTF-16LE (0) or Latin-1 (1)
+func chromeString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	switch b[0] {
+	case 0:
+		units := make([]uint16, (len(b)-1)/2)
+		for i := range units {
+			units[i] = uint16(b[1+2*i]) | uint16(b[2+2*i])<<8
+		}
+		return string(utf16.Decode(units))
+	case 1:
+		runes := make([]rune, len(b)-1)
+		for i, c := range b[1:] {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	return string(b)
+}
+
+// firefoxRow renders a Firefox local storage row. Values are blobs that may
+// be Snappy-compressed.
+func firefoxRow(row []sqliteColumn) string {
+	parts := make([]string, 0, len(row))
+	for _, col := range row {
+		if col.text {
+			parts = append(parts, string(col.data))
+			continue
+		}
+		data := col.data
+		if decoded, err := snappyDecode(data); err == nil {
+			data = decoded
+		}
+		parts = append(parts, strings.Join(printableRuns(data), " "))
+	}
+	return strings.Join(parts, " = ")
+}
+
+// printableRuns returns the runs of printable ASCII in b, whether stored as
+// single bytes or as UTF-16LE, that are at least minPrintableRun long
+func printableRuns(b []byte) []string {
+	printable := func(c byte) bool { return c >= 0x20 && c < 0x7f }
+
+	var out []string
+	start := -1
+	for i := 0; i <= len(b); i++ {
+		if i < len(b) && printable(b[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minPrintableRun {
+			out = append(out, string(b[start:i]))
+		}
+		start = -1
+	}
+
+	for align := 0; align < 2; align++ {
+		var run []byte
+		for i := align; i+1 <= len(b); i += 2 {
+			if i+1 < len(b) && printable(b[i]) && b[i+1] == 0 {
+				run = append(run, b[i])
+				continue
+			}
+			if len(run) >= minPrintableRun {
+				out = append(out, string(run))
+			}
+			run = run[:0]
+		}
+		if len(run) >= minPrintableRun {
+			out = append(out, string(run))
+		}
+	}
+	return out
+}
+
+// v8Strings returns the strings in a V8-serialized IndexedDB value. One-byte
+// strings are tagged '"' and two-byte strings 'c', each followed by a varint
+// byte length. Reading them by length keeps a printable length byte from
+// running into the string, which printableRuns cannot avoid.
+func v8Strings(b []byte) []string {
+	var out []string
+	for i := 0; i < len(b); i++ {
+		if b[i] != '"' && b[i] != 'c' {
+			continue
+		}
+		n, m := binary.Uvarint(b[i+1:])
+		if m <= 0 || n < minPrintableRun || n > uint64(len(b)-i-1-m) {
+			continue
+		}
+		data := b[i+1+m : i+1+m+int(n)]
+		if b[i] == 'c' {
+			if n%2 != 0 {
+				continue
+			}
+			units := make([]uint16, n/2)
+			for j := range units {
+				units[j] = binary.LittleEndian.Uint16(data[2*j:])
+			}
+			out = append(out, string(utf16.Decode(units)))
+		} else {
+			out = append(out, string(data))
+		}
+		i += m + int(n)
+	}
+	return out
+}
+
+// subdirs lists the directories directly inside dir
+func subdirs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		if e.IsDir() {
+			out = append(out, filepath.Join(dir, e.Name()))
+		}
+	}
+	return out
+}
+
+// fileExists reports whether p names something, whatever its type
+func fileExists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
@@ -0,0 +1,211 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// snappyLiteral encodes data as an uncompressed Snappy stream
+func snappyLiteral(data []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(data)))
+	for len(data) > 0 {
+		n := min(len(data), 60)
+		out = append(out, byte(n-1)<<2)
+		out = append(out, data[:n]...)
+		data = data[n:]
+	}
+	return out
+}
+
+// leveldbTestLog builds a log file holding one write batch of puts
+func leveldbTestLog(puts [][2][]byte) []byte {
+	batch := make([]byte, 12)
+	binary.LittleEndian.PutUint32(batch[8:], uint32(len(puts)))
+	for _, kv := range puts {
+		batch = append(batch, 1)
+		batch = binary.AppendUvarint(batch, uint64(len(kv[0])))
+		batch = append(batch, kv[0]...)
+		batch = binary.AppendUvarint(batch, uint64(len(kv[1])))
+		batch = append(batch, kv[1]...)
+	}
+	header := make([]byte, 7)
+	binary.LittleEndian.PutUint16(header[4:], uint16(len(batch)))
+	header[6] = 1
+	return append(header, batch...)
+}
+
+// leveldbTestBlock builds a block with no prefix compression
+func leveldbTestBlock(entries [][2][]byte) []byte {
+	var b []byte
+	for _, kv := range entries {
+		b = binary.AppendUvarint(b, 0)
+		b = binary.AppendUvarint(b, uint64(len(kv[0])))
+		b = binary.AppendUvarint(b, uint64(len(kv[1])))
+		b = append(b, kv[0]...)
+		b = append(b, kv[1]...)
+	}
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	return binary.LittleEndian.AppendUint32(b, 1)
+}
+
+// leveldbTestTable builds a table with one Snappy-compressed data block
+func leveldbTestTable(puts [][2][]byte) []byte {
+	return leveldbTestTableIndexed(puts, 1)
+}
+
+// leveldbTestTableIndexed builds a table whose index names its one data
+// block n times, as a damaged index might
+func leveldbTestTableIndexed(puts [][2][]byte, n int) []byte {
+	var entries [][2][]byte
+	for _, kv := range puts {
+		ikey := append(append([]byte(nil), kv[0]...), 1, 0, 0, 0, 0, 0, 0, 0)
+		entries = append(entries, [2][]byte{ikey, kv[1]})
+	}
+	data := snappyLiteral(leveldbTestBlock(entries))
+	table := append(data, 1, 0, 0, 0, 0)
+
+	handle := binary.AppendUvarint(nil, 0)
+	handle = binary.AppendUvarint(handle, uint64(len(data)))
+	var indexEntries [][2][]byte
+	for i := 0; i < n; i++ {
+		indexEntries = append(indexEntries, [2][]byte{[]byte("z"), handle})
+	}
+	index := leveldbTestBlock(indexEntries)
+	indexOffset := len(table)
+	table = append(table, index...)
+	table = append(table, 0, 0, 0, 0, 0)
+
+	footer := binary.AppendUvarint(nil, 0)
+	footer = binary.AppendUvarint(footer, 0)
+	footer = binary.AppendUvarint(footer, uint64(indexOffset))
+	footer = binary.AppendUvarint(footer, uint64(len(index)))
+	footer = append(footer, make([]byte, 40-len(footer))...)
+	footer = binary.LittleEndian.AppendUint64(footer, leveldbTableMagic)
+	return append(table, footer...)
+}
+
+func TestLevelDBTableRepeatedBlock(t *testing.T) {
+	table := leveldbTestTableIndexed([][2][]byte{{[]byte("b"), []byte("from-table")}}, 1000)
+	var got int
+	if err := leveldbTable(table, func(k, v []byte) { got++ }); err != nil || got != 1 {
+		t.Errorf("read %d records, %v; want the block once", got, err)
+	}
+}
+
+func TestSnappyDecode(t *testing.T) {
+	// "abcd" as a literal, then a copy of 8 bytes from 4 back
+	src := []byte{12, 3 << 2, 'a', 'b', 'c', 'd', 1 | 4<<2, 4}
+	got, err := snappyDecode(src)
+	if err != nil || string(got) != "abcdabcdabcd" {
+		t.Fatalf("snappyDecode = %q, %v", got, err)
+	}
+	if _, err := snappyDecode([]byte{5, 1 | 4<<2, 9}); err == nil {
+		t.Error("expected an error for a copy before the start")
+	}
+}
+
+func TestLevelDBRecords(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "000003.log"), leveldbTestLog([][2][]byte{{[]byte("a"), []byte("from-log")}}))
+	writeFile(t, filepath.Join(dir, "000005.ldb"), leveldbTestTable([][2][]byte{{[]byte("b"), []byte("from-table")}}))
+
+	var got []string
+	leveldbRecords(dir, 1<<20, passBrowserStorage, nil, func(k, v []byte) {
+		got = append(got, string(k)+"="+string(v))
+	})
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "a=from-log" || got[1] != "b=from-table" {
+		t.Errorf("records = %v", got)
+	}
+}
+
+func TestScanBrowserStorage(t *testing.T) {
+	home := t.TempDir()
+
+	// Chrome localStorage: Latin-1 keys and values in the log, a UTF-16
+	// value in a table
+	chromeDir := filepath.Join(home, ".config", "google-chrome", "Default", "Local Storage", "leveldb")
+	writeFile(t, filepath.Join(chromeDir, "000003.log"), leveldbTestLog([][2][]byte{
+		{[]byte("_http://localhost:3000\x00\x01OPENAI_API_KEY"), []byte("\x01plainopenaivalue1234")},
+		{[]byte("VERSION"), []byte("1")},
+	}))
+	utf16 := []byte{0}
+	for _, c := range `{"key":"gsk_abcdefghijklmnopqrstuvwxyz"}` {
+		utf16 = append(utf16, byte(c), 0)
+	}
+	writeFile(t, filepath.Join(chromeDir, "000005.ldb"), leveldbTestTable([][2][]byte{
+		{[]byte("_https://chat.example.com\x00\x01settings"), utf16},
+	}))
+
+	// Chrome IndexedDB with an opaque serialized value
+	idb := filepath.Join(home, ".config", "google-chrome", "Profile 1", "IndexedDB", "https_app.example.com_0.indexeddb.leveldb")
+	writeFile(t, filepath.Join(idb, "000003.log"), leveldbTestLog([][2][]byte{
+		// A one-byte string whose length, 0x33, prints as '3'
+		{[]byte("\x00\x01\x02"), append([]byte{0xff, 0x0f, 0x22, 0x33}, "hf_abcdefghijklmnopqrstuvwxyz0123456789abcdefghijkl"...)},
+	}))
+
+	// Firefox local storage
+	fixture, err := os.ReadFile("testdata/firefox-ls.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(home, ".mozilla", "firefox", "abcd.default", "storage", "default", "https+++chat.example.com", "ls", "data.sqlite"), fixture)
+
+	keys := scanBrowserStorage(home, ScanOptions{}, nil)
+	firefox := filepath.Join(home, ".mozilla", "firefox", "abcd.default", "storage", "default", "https+++chat.example.com", "ls", "data.sqlite")
+	var got []string
+	for _, key := range keys {
+		got = append(got, key.Provider+" "+key.Source)
+	}
+	sort.Strings(got)
+	want := []string{
+		"anthropic " + firefox,
+		"groq " + chromeDir + "!https://chat.example.com",
+		"huggingface " + idb,
+		"openai " + chromeDir + "!http://localhost:3000",
+		"openai " + firefox,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("key %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// The pass follows the ignore rules and reports what it read
+	run := newScanRun(ScanOptions{}, time.Now())
+	keys = scanBrowserStorage(home, ScanOptions{IgnoreGlobs: []string{".config/google-chrome/Profile 1/", ".mozilla/"}}, run)
+	if len(keys) != 2 {
+		t.Errorf("keys with ignore rules = %+v", keys)
+	}
+	if run.stats.FilesExamined != 2 || run.stats.BytesRead == 0 {
+		t.Errorf("stats = %+v", run.stats)
+	}
+}
+
+func TestPrintableRuns(t *testing.T) {
+	b := append([]byte("\x00\x01short\x00longenough!\x02"), bytes.Repeat([]byte{'w', 0}, 9)...)
+	got := printableRuns(b)
+	if len(got) != 2 || got[0] != "longenough!" || got[1] != "wwwwwwwww" {
+		t.Errorf("printableRuns = %q", got)
+	}
+}
+
+func FuzzLevelDB(f *testing.F) {
+	f.Add(snappyLiteral([]byte("_https://chat.example\x00\x01openai_key sk-proj-abcdefghijklmnopqrstuvwx")))
+	f.Add([]byte{0x05, 0x10, 'a', 'b', 'c', 'd', 'e'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		snappyDecode(data)
+		noop := func(key, value []byte) {}
+		leveldbTable(data, noop)
+		leveldbLog(data, noop)
+		leveldbBatch(data, noop)
+	})
+}
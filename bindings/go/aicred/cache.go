@@ -0,0 +1,270 @@
+package aicred
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+const (
+	// ScanCacheFileName is the incremental scan cache kept in CacheDir
+	ScanCacheFileName = "scan-cache.json"
+	// scanCacheVersion changes whenever the cache layout does
+	scanCacheVersion = 1
+)
+
+// cachedKey is a DiscoveredKey without its value; values never reach disk
+type cachedKey struct {
+	Provider   string    `json:"provider"`
+	Source     string    `json:"source"`
+	ValueType  string    `json:"value_type"`
+	Confidence string    `json:"confidence"`
+	Hash       string    `json:"hash"`
+	Redacted   string    `json:"redacted"`
+	Location   *Location `json:"location,omitempty"`
+}
+
+// cacheEntry records a file as it was when last examined
+type cacheEntry struct {
+	Size    int64       `json:"size"`
+	ModTime int64       `json:"mtime"`
+	SHA256  string      `json:"sha256"`
+	Keys    []cachedKey `json:"keys,omitempty"`
+}
+
+// scanCache remembers the keys found in each file the Go-side passes
+// examine, so unchanged files are not parsed again. A nil *scanCache
+// disables caching.
+type scanCache struct {
+	path string
+
+	mu          sync.Mutex
+	Version     int                   `json:"version"`
+	Fingerprint string                `json:"fingerprint"`
+	Files       map[string]cacheEntry `json:"files"`
+	seen        map[string]bool
+}
+
+// scanCacheDir resolves CacheDir, defaulting to the StateDir of the default
+// home directory, which is ~/.config/aicred like the CLI unless overridden
+func scanCacheDir(options ScanOptions) (string, error) {
+	if options.CacheDir != "" {
+		return options.CacheDir, nil
+	}
+	home, err := DefaultHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return StateDir(home), nil
+}
+
+// cacheFingerprint covers the options that change what a file yields, so a
+// cache written under different options is discarded rather than trusted
+func cacheFingerprint(options ScanOptions) string {
+	data, _ := json.Marshal(struct {
+		Only, Exclude, Providers []string
+		MaxFileSize              int
+		MaxArchiveDepth          int
+		MaxArchiveBytes          int64
+	}{
+		options.OnlyProviders, options.ExcludeProviders, detect.Providers(),
+		options.MaxFileSize, options.MaxArchiveDepth, options.MaxArchiveBytes,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadScanCache reads the cache for an incremental scan. A missing, damaged
+// or stale cache starts empty.
+func loadScanCache(options ScanOptions) *scanCache {
+	dir, err := scanCacheDir(options)
+	if err != nil {
+		logger().Warn("scan cache disabled", slog.String("error", err.Error()))
+		return nil
+	}
+	c := &scanCache{path: filepath.Join(dir, ScanCacheFileName)}
+	data, err := os.ReadFile(c.path)
+	if err == nil {
+		if err := json.Unmarshal(data, c); err != nil {
+			logger().Warn("ignoring damaged scan cache", slog.String("path", c.path), slog.String("error", err.Error()))
+		}
+	}
+	fingerprint := cacheFingerprint(options)
+	if c.Version != scanCacheVersion || c.Fingerprint != fingerprint || c.Files == nil {
+		c.Version, c.Fingerprint, c.Files = scanCacheVersion, fingerprint, map[string]cacheEntry{}
+	}
+	c.seen = map[string]bool{}
+	return c
+}
+
+// wrap returns scan with cached results substituted for unchanged files.
+// A file is unchanged when its size and mtime match, or failing that its
+// SHA-256 does. Files that held keys are rescanned when values are wanted,
+// since the cache does not keep them.
+func (c *scanCache) wrap(scan func(p string) []DiscoveredKey, withValues bool) func(p string) []DiscoveredKey {
+	if c == nil {
+		return scan
+	}
+	return func(p string) []DiscoveredKey {
+		info, err := os.Stat(p)
+		if err != nil {
+			return scan(p)
+		}
+		c.mu.Lock()
+		entry, ok := c.Files[p]
+		c.seen[p] = true
+		c.mu.Unlock()
+
+		usable := ok && !(withValues && len(entry.Keys) > 0)
+		if usable && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+			return entry.keys()
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return scan(p)
+		}
+		var keys []DiscoveredKey
+		if usable && entry.SHA256 == sum {
+			keys = entry.keys()
+		} else {
+			keys = scan(p)
+			entry = cacheEntry{SHA256: sum, Keys: make([]cachedKey, 0, len(keys))}
+			for _, k := range keys {
+				entry.Keys = append(entry.Keys, cachedKey{k.Provider, k.Source, k.ValueType, k.Confidence, k.Hash, k.Redacted, k.Location})
+			}
+		}
+		entry.Size, entry.ModTime = info.Size(), info.ModTime().UnixNano()
+		c.mu.Lock()
+		c.Files[p] = entry
+		c.mu.Unlock()
+		return keys
+	}
+}
+
+func (e cacheEntry) keys() []DiscoveredKey {
+	if len(e.Keys) == 0 {
+		return nil
+	}
+	keys := make([]DiscoveredKey, len(e.Keys))
+	for i, k := range e.Keys {
+		keys[i] = DiscoveredKey{Provider: k.Provider, Source: k.Source, ValueType: k.ValueType, Confidence: k.Confidence, Hash: k.Hash, Redacted: k.Redacted, Location: k.Location}
+	}
+	return keys
+}
+
+// save drops entries for files this scan did not reach and writes the cache
+// atomically, readable only by the owner
+func (c *scanCache) save() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := range c.Files {
+		if !c.seen[p] {
+			delete(c.Files, p)
+		}
+	}
+	if err := writeFileAtomic(c.path, c); err != nil {
+		logger().Warn("failed to save scan cache", slog.String("path", c.path), slog.String("error", err.Error()))
+	}
+}
+
+func writeFileAtomic(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writeBytesAtomic(path, data)
+}
+
+// writeBytesAtomic writes data to p with mode 0600 through a temporary file
+// and rename, so readers never see a partial file
+func writeBytesAtomic(p string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(configFileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// InvalidateCache discards the incremental scan cache in options.CacheDir
+// so the next incremental scan examines every file. Given paths, it
+// forgets only those files and anything beneath them.
+func InvalidateCache(options ScanOptions, paths ...string) error {
+	dir, err := scanCacheDir(options)
+	if err != nil {
+		return fmt.Errorf("locating scan cache: %w", err)
+	}
+	cachePath := filepath.Join(dir, ScanCacheFileName)
+	if len(paths) == 0 {
+		if err := os.Remove(cachePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("removing scan cache: %w", err)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading scan cache: %w", err)
+	}
+	c := &scanCache{path: cachePath}
+	if err := json.Unmarshal(data, c); err != nil {
+		// A damaged cache would be discarded anyway
+		return os.Remove(cachePath)
+	}
+	for p := range c.Files {
+		for _, prefix := range paths {
+			prefix = filepath.Clean(prefix)
+			if p == prefix || strings.HasPrefix(p, prefix+string(filepath.Separator)) {
+				delete(c.Files, p)
+			}
+		}
+	}
+	if err := writeFileAtomic(cachePath, c); err != nil {
+		return fmt.Errorf("writing scan cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package aicred
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanCacheReusesUnchangedFiles(t *testing.T) {
+	home, cacheDir := t.TempDir(), t.TempDir()
+	archive := filepath.Join(home, "backup.zip")
+	writeFile(t, archive, zipBytes(t, map[string][]byte{".env": []byte(archiveKey)}))
+	options := ScanOptions{Incremental: true, CacheDir: cacheDir}
+
+	scans := 0
+	counting := func(p string) []DiscoveredKey {
+		scans++
+		return scanArchives(filepath.Dir(p), ScanOptions{}, nil)
+	}
+	run := func() []DiscoveredKey {
+		cache := loadScanCache(options)
+		keys := cache.wrap(counting, false)(archive)
+		cache.save()
+		return keys
+	}
+
+	if keys := run(); len(keys) != 1 || scans != 1 {
+		t.Fatalf("first scan: %d keys after %d scans", len(keys), scans)
+	}
+	keys := run()
+	if len(keys) != 1 || scans != 1 {
+		t.Fatalf("unchanged file: %d keys after %d scans, want 1 after 1", len(keys), scans)
+	}
+	if !keys[0].Value.IsZero() || keys[0].Hash == "" {
+		t.Errorf("cached key should carry its hash but no value: %+v", keys[0])
+	}
+
+	// A touched but identical file is recognised by its hash
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(archive, later, later)
+	if run(); scans != 1 {
+		t.Errorf("touched file was rescanned")
+	}
+
+	writeFile(t, archive, zipBytes(t, map[string][]byte{".env": []byte("nothing here")}))
+	if keys := run(); len(keys) != 0 || scans != 2 {
+		t.Errorf("changed file: %d keys after %d scans, want 0 after 2", len(keys), scans)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cacheDir, ScanCacheFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-proj-") {
+		t.Error("cache file holds a key value")
+	}
+}
+
+func TestScanCacheRereadsKeysForFullValues(t *testing.T) {
+	home, cacheDir := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(home, "backup.zip"), zipBytes(t, map[string][]byte{".env": []byte(archiveKey)}))
+	options := ScanOptions{Incremental: true, CacheDir: cacheDir, Redaction: RedactionNone}
+
+	for i := 0; i < 2; i++ {
+		cache := loadScanCache(options)
+		keys := scanArchives(home, options, &scanRun{cache: cache})
+		cache.save()
+		if len(keys) != 1 || keys[0].Value.IsZero() {
+			t.Fatalf("scan %d: want one key with its value, got %+v", i, keys)
+		}
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	home, cacheDir := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(home, "a", "one.zip"), zipBytes(t, map[string][]byte{".env": []byte(archiveKey)}))
+	writeFile(t, filepath.Join(home, "b", "two.zip"), zipBytes(t, map[string][]byte{".env": []byte(archiveKey)}))
+	options := ScanOptions{Incremental: true, CacheDir: cacheDir}
+	cache := loadScanCache(options)
+	scanArchives(home, options, &scanRun{cache: cache})
+	cache.save()
+
+	if err := InvalidateCache(options, filepath.Join(home, "a")); err != nil {
+		t.Fatal(err)
+	}
+	cache = loadScanCache(options)
+	if _, ok := cache.Files[filepath.Join(home, "a", "one.zip")]; ok {
+		t.Error("invalidated path is still cached")
+	}
+	if _, ok := cache.Files[filepath.Join(home, "b", "two.zip")]; !ok {
+		t.Error("other path was dropped")
+	}
+
+	if err := InvalidateCache(options); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, ScanCacheFileName)); !os.IsNotExist(err) {
+		t.Errorf("cache file survived invalidation: %v", err)
+	}
+	if err := InvalidateCache(options); err != nil {
+		t.Errorf("invalidating a missing cache: %v", err)
+	}
+}
+
+func TestScanCacheDiscardedWhenOptionsChange(t *testing.T) {
+	cacheDir := t.TempDir()
+	cache := loadScanCache(ScanOptions{CacheDir: cacheDir})
+	cache.Files["/x"] = cacheEntry{Size: 1}
+	cache.seen["/x"] = true
+	cache.save()
+
+	if cache := loadScanCache(ScanOptions{CacheDir: cacheDir}); len(cache.Files) != 1 {
+		t.Fatalf("cache not reloaded: %v", cache.Files)
+	}
+	if cache := loadScanCache(ScanOptions{CacheDir: cacheDir, OnlyProviders: []string{"groq"}}); len(cache.Files) != 0 {
+		t.Errorf("cache kept across a provider filter change: %v", cache.Files)
+	}
+}
@@ -0,0 +1,330 @@
+// Package canary plants decoy provider keys and reports when they turn up
+// where they were not planted, a sign that files were copied off the
+// machine or into places they should not be.
+//
+// Generate makes a random key in a provider's real format, so scanners and
+// attackers treat it as genuine. A Ledger records each canary by hash, with
+// the files it was planted in; it never holds the key itself. Check and
+// Scan compare scan findings with the ledger and return an Alert for each
+// canary found anywhere else.
+package canary
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+// LedgerFile is the name of the ledger in the data directory
+const LedgerFile = "canaries.json"
+
+const (
+	alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	urlSafe      = alphanumeric + "-_"
+	hexDigits    = "0123456789abcdef"
+)
+
+// format is how a provider's keys look, and the variable they are usually
+// assigned to
+type format struct {
+	prefix, suffix string
+	alphabet       string
+	length         int
+	envVar         string
+}
+
+// formats are the providers Generate supports, matching the key shapes the
+// providers issue today
+var formats = map[string]format{
+	"openai":      {prefix: "sk-proj-", alphabet: urlSafe, length: 156, envVar: "OPENAI_API_KEY"},
+	"anthropic":   {prefix: "sk-ant-api03-", alphabet: urlSafe, length: 93, suffix: "AA", envVar: "ANTHROPIC_API_KEY"},
+	"groq":        {prefix: "gsk_", alphabet: alphanumeric, length: 52, envVar: "GROQ_API_KEY"},
+	"huggingface": {prefix: "hf_", alphabet: alphanumeric, length: 34, envVar: "HF_TOKEN"},
+	"openrouter":  {prefix: "sk-or-v1-", alphabet: hexDigits, length: 64, envVar: "OPENROUTER_API_KEY"},
+}
+
+// Providers returns the providers Generate supports, sorted
+func Providers() []string {
+	providers := make([]string, 0, len(formats))
+	for p := range formats {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Generate returns a random decoy key in provider's format. It returns an
+// error wrapping aicred.ErrInvalidOption for providers Providers does not
+// list.
+func Generate(provider string) (string, error) {
+	f, ok := formats[provider]
+	if !ok {
+		return "", fmt.Errorf("canary: no key format for provider %q: %w", provider, aicred.ErrInvalidOption)
+	}
+	body := make([]byte, f.length)
+	// Rejection sampling keeps every character equally likely
+	limit := 256 - 256%len(f.alphabet)
+	var buf [64]byte
+	for i := 0; i < len(body); {
+		// crypto/rand.Read never returns an error
+		_, _ = rand.Read(buf[:])
+		for _, b := range buf {
+			if int(b) < limit && i < len(body) {
+				body[i] = f.alphabet[int(b)%len(f.alphabet)]
+				i++
+			}
+		}
+	}
+	return f.prefix + string(body) + f.suffix, nil
+}
+
+// Canary is a decoy key recorded in a ledger
+type Canary struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	// Hash is the SHA-256 of the key, as scans report it
+	Hash     string `json:"hash"`
+	Redacted string `json:"redacted"`
+	// Locations are the files the canary was planted in, where finding it
+	// is expected
+	Locations []string  `json:"locations"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Alert is a canary found outside the files it was planted in
+type Alert struct {
+	Canary Canary
+	// Key is the finding, and Path the file it was found in
+	Key  aicred.DiscoveredKey
+	Path string
+}
+
+// Ledger is a file of canaries. It is safe for concurrent use, but not for
+// several processes writing the same file.
+type Ledger struct {
+	path string
+
+	mu       sync.Mutex
+	canaries []Canary
+}
+
+// DefaultLedgerPath is the ledger in home's data directory; see
+// aicred.DataDir
+func DefaultLedgerPath(home string) string {
+	return filepath.Join(aicred.DataDir(home), LedgerFile)
+}
+
+// OpenLedger reads the ledger at path. A missing file is an empty ledger,
+// created on the first change.
+func OpenLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("canary ledger: %w: %v", aicred.ErrIO, err)
+	}
+	if err := json.Unmarshal(data, &l.canaries); err != nil {
+		return nil, fmt.Errorf("canary ledger %s: %w: %v", path, aicred.ErrParse, err)
+	}
+	return l, nil
+}
+
+// Canaries returns the canaries in the ledger, oldest first
+func (l *Ledger) Canaries() []Canary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]Canary, len(l.canaries))
+	for i, c := range l.canaries {
+		c.Locations = slices.Clone(c.Locations)
+		out[i] = c
+	}
+	return out
+}
+
+// Register records value as a canary of provider planted in locations,
+// for decoys planted by hand or by other tools. Registering a known value
+// again adds the locations to its canary.
+func (l *Ledger) Register(value, provider, note string, locations ...string) (Canary, error) {
+	if value == "" {
+		return Canary{}, fmt.Errorf("canary: empty value: %w", aicred.ErrInvalidOption)
+	}
+	locations = slices.Clone(locations)
+	for i, loc := range locations {
+		abs, err := filepath.Abs(loc)
+		if err != nil {
+			return Canary{}, fmt.Errorf("canary: %w: %v", aicred.ErrInvalidOption, err)
+		}
+		locations[i] = abs
+	}
+	sum := sha256.Sum256([]byte(value))
+	hash := hex.EncodeToString(sum[:])
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := slices.IndexFunc(l.canaries, func(c Canary) bool { return c.Hash == hash }); i >= 0 {
+		c := &l.canaries[i]
+		for _, loc := range locations {
+			if !slices.Contains(c.Locations, loc) {
+				c.Locations = append(c.Locations, loc)
+			}
+		}
+		return *c, l.save()
+	}
+	c := Canary{
+		ID:        aicred.NewUUID(),
+		Provider:  provider,
+		Hash:      hash,
+		Redacted:  detect.Redact(value),
+		Locations: locations,
+		Note:      note,
+		CreatedAt: time.Now().UTC(),
+	}
+	l.canaries = append(l.canaries, c)
+	return c, l.save()
+}
+
+// Plant generates a canary for provider, appends it to the file at path
+// as an assignment to the provider's usual variable, such as
+// OPENAI_API_KEY=..., and registers it. The file is created with mode 0600
+// if missing. Files where such a line does not belong, such as JSON, need
+// Generate and Register instead.
+func (l *Ledger) Plant(path, provider, note string) (Canary, error) {
+	value, err := Generate(provider)
+	if err != nil {
+		return Canary{}, err
+	}
+	line := formats[provider].envVar + "=" + value + "\n"
+	if existing, err := os.ReadFile(path); err == nil && len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		line = "\n" + line
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return Canary{}, fmt.Errorf("canary: plant in %s: %w: %v", path, aicred.ErrIO, err)
+	}
+	if _, err := f.WriteString(line); err != nil {
+		f.Close()
+		return Canary{}, fmt.Errorf("canary: plant in %s: %w: %v", path, aicred.ErrIO, err)
+	}
+	if err := f.Close(); err != nil {
+		return Canary{}, fmt.Errorf("canary: plant in %s: %w: %v", path, aicred.ErrIO, err)
+	}
+	return l.Register(value, provider, note, path)
+}
+
+// Remove drops the canary with the given ID from the ledger; the planted
+// keys stay where they are. It returns an error wrapping aicred.ErrNotFound
+// for an unknown ID.
+func (l *Ledger) Remove(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := slices.IndexFunc(l.canaries, func(c Canary) bool { return c.ID == id })
+	if i < 0 {
+		return fmt.Errorf("canary %q: %w", id, aicred.ErrNotFound)
+	}
+	l.canaries = slices.Delete(l.canaries, i, i+1)
+	return l.save()
+}
+
+// save writes the ledger through a temporary file and rename. The caller
+// holds l.mu.
+func (l *Ledger) save() error {
+	data, err := json.MarshalIndent(l.canaries, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(l.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("canary ledger: %w: %v", aicred.ErrIO, err)
+	}
+	tmp, err := os.CreateTemp(dir, LedgerFile+".*")
+	if err != nil {
+		return fmt.Errorf("canary ledger: %w: %v", aicred.ErrIO, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.path)
+	}
+	if err != nil {
+		return fmt.Errorf("canary ledger: %w: %v", aicred.ErrIO, err)
+	}
+	return nil
+}
+
+// Check returns an alert for every key in result that is a canary found
+// outside the files it was planted in, including copies in archives. The
+// result must carry hashes, so it cannot be redacted with
+// aicred.RedactionFull.
+func (l *Ledger) Check(result *aicred.ScanResult) []Alert {
+	l.mu.Lock()
+	byHash := make(map[string]Canary, len(l.canaries))
+	for _, c := range l.canaries {
+		byHash[c.Hash] = c
+	}
+	l.mu.Unlock()
+
+	var alerts []Alert
+	check := func(key aicred.DiscoveredKey) {
+		c, ok := byHash[key.Hash]
+		if !ok || key.Hash == "" {
+			return
+		}
+		// Keys in archives and browser storage have sources such as
+		// "archive.zip!.env", which are never planted locations
+		path := key.Source
+		if !strings.Contains(path, "!") {
+			if key.Location != nil && key.Location.Path != "" {
+				path = key.Location.Path
+			}
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+		}
+		if !slices.Contains(c.Locations, path) {
+			alerts = append(alerts, Alert{Canary: c, Key: key, Path: path})
+		}
+	}
+	for _, key := range result.Keys {
+		check(key)
+	}
+	for _, instance := range result.ConfigInstances {
+		for _, key := range instance.Keys {
+			check(key)
+		}
+	}
+	return alerts
+}
+
+// Scan scans with options and checks the findings against the ledger. A
+// options.Redaction of aicred.RedactionFull is lowered to
+// aicred.RedactionHashOnly, which Check needs.
+func Scan(l *Ledger, options aicred.ScanOptions) ([]Alert, error) {
+	if options.Redaction == aicred.RedactionFull {
+		options.Redaction = aicred.RedactionHashOnly
+	}
+	result, err := aicred.Scan(options)
+	if err != nil {
+		return nil, err
+	}
+	return l.Check(result), nil
+}
@@ -0,0 +1,131 @@
+package canary
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, provider := range Providers() {
+		a, err := Generate(provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := Generate(provider); a == b {
+			t.Errorf("%s: two canaries are equal", provider)
+		}
+		if pt, ok := aicred.LookupProviderType(provider); !ok || !pt.KeyPattern.MatchString(a) {
+			t.Errorf("%s: %q does not match the provider's key pattern", provider, a)
+		}
+		if matches := detect.Find([]byte(a)); len(matches) != 1 || matches[0].Provider != provider {
+			t.Errorf("%s: detector found %+v", provider, matches)
+		}
+	}
+	if _, err := Generate("ollama"); !errors.Is(err, aicred.ErrInvalidOption) {
+		t.Errorf("Generate(ollama): err = %v", err)
+	}
+}
+
+// scanFiles scans paths with the Go detector, as Scan's passes do
+func scanFiles(t *testing.T, paths ...string) *aicred.ScanResult {
+	t.Helper()
+	result := &aicred.ScanResult{}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, err := aicred.ScanContent(p, f, aicred.ScanOptions{})
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result.Keys = append(result.Keys, keys...)
+	}
+	return result
+}
+
+func TestLedger(t *testing.T) {
+	dir := t.TempDir()
+	ledgerPath := filepath.Join(dir, "data", LedgerFile)
+	ledger, err := OpenLedger(ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := filepath.Join(dir, ".env")
+	if err := os.WriteFile(env, []byte("DEBUG=1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	planted, err := ledger.Plant(env, "openai", "decoy in the project .env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(env)
+	if lines := len(scanFiles(t, env).Keys); lines != 1 || string(data[:8]) != "DEBUG=1\n" {
+		t.Errorf("planted file:\n%s", data)
+	}
+	if info, err := os.Stat(ledgerPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("ledger: %v, %v", info, err)
+	}
+	if raw, _ := os.ReadFile(ledgerPath); len(raw) == 0 || string(raw) == string(data) {
+		t.Error("ledger is empty or holds the key")
+	}
+
+	// Found where it was planted: no alert
+	if alerts := ledger.Check(scanFiles(t, env)); len(alerts) != 0 {
+		t.Errorf("alerts for the planted file: %+v", alerts)
+	}
+
+	// Found in a copy: an alert
+	copied := filepath.Join(dir, "exfil", "backup.txt")
+	os.MkdirAll(filepath.Dir(copied), 0o755)
+	os.WriteFile(copied, data, 0o644)
+	alerts := ledger.Check(scanFiles(t, env, copied))
+	if len(alerts) != 1 || alerts[0].Canary.ID != planted.ID || alerts[0].Path != copied {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+
+	// A second copy can be registered as expected
+	if _, err := ledger.Register(scanValue(t, copied), "openai", "", copied); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenLedger(ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canaries := reopened.Canaries()
+	if len(canaries) != 1 || len(canaries[0].Locations) != 2 || canaries[0].Note != "decoy in the project .env" {
+		t.Errorf("reopened ledger = %+v", canaries)
+	}
+	if alerts := reopened.Check(scanFiles(t, env, copied)); len(alerts) != 0 {
+		t.Errorf("alerts after registering the copy: %+v", alerts)
+	}
+
+	if err := reopened.Remove(planted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Remove(planted.ID); !errors.Is(err, aicred.ErrNotFound) {
+		t.Errorf("removing twice: err = %v", err)
+	}
+}
+
+// scanValue returns the value of the one key in p
+func scanValue(t *testing.T, p string) string {
+	t.Helper()
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	keys, err := aicred.ScanContent(p, f, aicred.ScanOptions{Redaction: aicred.RedactionNone})
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("keys = %+v, %v", keys, err)
+	}
+	return keys[0].Value.Reveal()
+}
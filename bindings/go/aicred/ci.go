@@ -0,0 +1,151 @@
+package aicred
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteGitHubAnnotations writes one GitHub Actions workflow command per key,
+// such as "::error file=app/.env,line=3,col=16,title=...::...", so findings
+// show inline on pull requests when printed from a workflow step. Paths
+// under $GITHUB_WORKSPACE are made relative to it. Low confidence keys are
+// notices, Medium ones warnings and the rest errors; Hugging Face tokens that
+// can write are always errors and those the Hub rejected notices.
+func WriteGitHubAnnotations(w io.Writer, result *ScanResult) error {
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	for _, key := range result.Keys {
+		props := []string{"file=" + escapeAnnotationProperty(ciPath(key, workspace))}
+		if l := key.Location; l != nil && l.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", l.Line))
+			if l.Column > 0 {
+				props = append(props, fmt.Sprintf("col=%d", l.Column))
+			}
+		}
+		props = append(props, "title="+escapeAnnotationProperty(fmt.Sprintf("Exposed %s credential", key.Provider)))
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", annotationLevel(key), strings.Join(props, ","), escapeAnnotationData(ciMessage(key))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitlabIssue is one entry of a GitLab Code Quality report
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+}
+
+// WriteGitLabCodeQuality writes the keys as a GitLab Code Quality report,
+// to be uploaded as an artifacts:reports:codequality artifact so findings
+// show in merge request widgets and diffs. Paths under $CI_PROJECT_DIR are
+// made relative to it. Each issue's fingerprint is stable across pipelines
+// for the same key in the same place.
+func WriteGitLabCodeQuality(w io.Writer, result *ScanResult) error {
+	workspace := os.Getenv("CI_PROJECT_DIR")
+	issues := make([]gitlabIssue, 0, len(result.Keys))
+	for _, key := range result.Keys {
+		path := ciPath(key, workspace)
+		line := 1
+		if key.Location != nil && key.Location.Line > 0 {
+			line = key.Location.Line
+		}
+		sum := sha256.Sum256([]byte(strings.Join([]string{key.Provider, path, fmt.Sprint(line), key.Hash}, "\x00")))
+		issues = append(issues, gitlabIssue{
+			Description: ciMessage(key),
+			CheckName:   "aicred/" + key.Provider,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    gitlabSeverity(key),
+			Location:    gitlabLocation{Path: path, Lines: gitlabLines{Begin: line}},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
+
+// ciPath returns the file a key was found in, relative to workspace when it
+// lies beneath it, with forward slashes
+func ciPath(key DiscoveredKey, workspace string) string {
+	path := key.Source
+	if key.Location != nil && key.Location.Path != "" {
+		path = key.Location.Path
+	}
+	// CI systems can only point at the outer file of archive!entry sources
+	path, _, _ = strings.Cut(path, "!")
+	if workspace != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+func ciMessage(key DiscoveredKey) string {
+	msg := fmt.Sprintf("%s credential %s found", key.Provider, key.Redacted)
+	if key.Location != nil && key.Location.EnvVar != "" {
+		msg += " in " + key.Location.EnvVar
+	}
+	return msg
+}
+
+func annotationLevel(key DiscoveredKey) string {
+	switch {
+	case key.HFToken.CanWrite():
+		return "error"
+	case key.HFToken != nil && key.HFToken.Rejected:
+		return "notice"
+	}
+	switch key.Confidence {
+	case "Low":
+		return "notice"
+	case "Medium":
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+func gitlabSeverity(key DiscoveredKey) string {
+	switch {
+	case key.HFToken.CanWrite():
+		return "critical"
+	case key.HFToken != nil && key.HFToken.Rejected:
+		return "info"
+	}
+	switch key.Confidence {
+	case "Low":
+		return "info"
+	case "Medium":
+		return "minor"
+	case "VeryHigh":
+		return "critical"
+	default:
+		return "major"
+	}
+}
+
+var (
+	annotationData     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	annotationProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeAnnotationData(s string) string     { return annotationData.Replace(s) }
+func escapeAnnotationProperty(s string) string { return annotationProperty.Replace(s) }
@@ -0,0 +1,61 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func ciResult(workspace string) *ScanResult {
+	return &ScanResult{Keys: []DiscoveredKey{
+		{
+			Provider:   "openai",
+			Source:     workspace + "/app/.env",
+			Confidence: "VeryHigh",
+			Hash:       "abc",
+			Redacted:   "****wxyz",
+			Location:   &Location{Path: workspace + "/app/.env", Line: 3, Column: 16, EnvVar: "OPENAI_API_KEY"},
+		},
+		{Provider: "groq", Source: "/elsewhere/a,b.zip!x/.env", Confidence: "Medium", Redacted: "****1234"},
+	}}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "/work")
+	var out bytes.Buffer
+	if err := WriteGitHubAnnotations(&out, ciResult("/work")); err != nil {
+		t.Fatal(err)
+	}
+	want := "::error file=app/.env,line=3,col=16,title=Exposed openai credential::openai credential ****wxyz found in OPENAI_API_KEY\n" +
+		"::warning file=/elsewhere/a%2Cb.zip,title=Exposed groq credential::groq credential ****1234 found\n"
+	if out.String() != want {
+		t.Errorf("annotations =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteGitLabCodeQuality(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", "/builds/acme/app")
+	var out bytes.Buffer
+	if err := WriteGitLabCodeQuality(&out, ciResult("/builds/acme/app")); err != nil {
+		t.Fatal(err)
+	}
+	var issues []gitlabIssue
+	if err := json.Unmarshal(out.Bytes(), &issues); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("got %d issues", len(issues))
+	}
+	first := issues[0]
+	if first.CheckName != "aicred/openai" || first.Severity != "critical" || first.Location.Path != "app/.env" || first.Location.Lines.Begin != 3 || len(first.Fingerprint) != 64 {
+		t.Errorf("first issue = %+v", first)
+	}
+	if second := issues[1]; second.Severity != "minor" || second.Location.Lines.Begin != 1 || second.Fingerprint == first.Fingerprint {
+		t.Errorf("second issue = %+v", second)
+	}
+
+	out.Reset()
+	if err := WriteGitLabCodeQuality(&out, &ScanResult{}); err != nil || out.String() != "[]\n" {
+		t.Errorf("empty report = %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,250 @@
+package aicred
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// Client is the entry point to the library for one home directory. It
+// bundles what the package-level functions otherwise take piecemeal or from
+// globals: the FFI session, the Store instances and labels are kept in, the
+// Vault secrets are pushed to, a logger and an event bus. Programs and tests
+// can swap each through an Option.
+//
+// The package-level functions remain for callers that need only one of
+// them. A Client is safe for concurrent use.
+type Client struct {
+	homeDir string
+	session *Session
+	store   Store
+	vault   VaultMapping
+	logger  *slog.Logger
+	events  *events.Bus
+	leaser  *Leaser
+}
+
+// Option configures a Client created by New
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	homeDir  string
+	encoding Encoding
+	store    Store
+	vault    VaultMapping
+	logger   *slog.Logger
+	events   *events.Bus
+}
+
+// WithHomeDir sets the home directory scanned and whose configuration is
+// loaded; the default is the current user's
+func WithHomeDir(dir string) Option {
+	return func(c *clientConfig) { c.homeDir = dir }
+}
+
+// WithEncoding sets how scan results cross the FFI; see SessionOptions
+func WithEncoding(encoding Encoding) Option {
+	return func(c *clientConfig) { c.encoding = encoding }
+}
+
+// WithStore keeps instances and labels in store instead of the home
+// directory's aicred configuration
+func WithStore(store Store) Option {
+	return func(c *clientConfig) { c.store = store }
+}
+
+// WithSecretStore sets the Vault PushSecrets stores key values in. Unset
+// fields take PushToVault's defaults, such as $VAULT_ADDR.
+func WithSecretStore(mapping VaultMapping) Option {
+	return func(c *clientConfig) { c.vault = mapping }
+}
+
+// WithLogger sets the logger the Client reports its operations to, with
+// secret-looking attributes redacted as SetLogger does. Package-level
+// functions called by the Client still log to the SetLogger logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *clientConfig) { c.logger = logger }
+}
+
+// WithEvents publishes the Client's StoreChanged, ScanCompleted and
+// ValidationFailed events on bus
+func WithEvents(bus *events.Bus) Option {
+	return func(c *clientConfig) { c.events = bus }
+}
+
+// New opens a Client. It opens an FFI session over the home directory,
+// so configuration errors surface here, and returns an error wrapping
+// ErrNotFound if no home directory is given and the current user's cannot
+// be determined. Close the Client when done.
+func New(opts ...Option) (*Client, error) {
+	var config clientConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := validateHomeDir(config.homeDir); err != nil {
+		return nil, err
+	}
+
+	session, err := OpenSessionWith(config.homeDir, SessionOptions{Encoding: config.encoding, Events: config.events})
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		homeDir: session.homeDir,
+		session: session,
+		store:   config.store,
+		vault:   config.vault,
+		logger:  slog.New(discardHandler{}),
+		events:  config.events,
+	}
+	if c.store == nil {
+		c.store = session
+	}
+	c.leaser = NewLeaser(c.store)
+	if config.logger != nil {
+		c.logger = slog.New(&redactingHandler{next: config.logger.Handler()})
+	}
+	c.logger.Debug("client opened", slog.String("home_dir", c.homeDir))
+	return c, nil
+}
+
+// HomeDir returns the home directory the Client works on
+func (c *Client) HomeDir() string {
+	return c.homeDir
+}
+
+// Session returns the Client's FFI session
+func (c *Client) Session() *Session {
+	return c.session
+}
+
+// Scan scans the Client's home directory through its session.
+// options.HomeDir is ignored.
+func (c *Client) Scan(options ScanOptions) (*ScanResult, error) {
+	result, err := c.session.Scan(options)
+	if err != nil {
+		c.logger.Error("scan failed", slog.String("home_dir", c.homeDir), slog.String("error", err.Error()))
+		return nil, err
+	}
+	c.logger.Info("scan complete",
+		slog.String("home_dir", c.homeDir),
+		slog.Int("keys", len(result.Keys)),
+		slog.Int("config_instances", len(result.ConfigInstances)))
+	return result, nil
+}
+
+// Instances returns a repository over the provider instances in the
+// Client's store
+func (c *Client) Instances() *InstanceRepository {
+	return newInstanceRepository(c.store)
+}
+
+// Labels returns a repository over the label assignments in the Client's
+// store. Saved changes are published as StoreChanged events.
+func (c *Client) Labels() *LabelRepository {
+	return newLabelRepository(publishingStore{Store: c.store, client: c})
+}
+
+// Organizations returns a repository over the organizations in the
+// Client's store, or in its session when the store keeps none, with
+// queries over the instances in the Client's store
+func (c *Client) Organizations() *OrganizationRepository {
+	orgs, ok := c.store.(OrganizationStore)
+	if !ok {
+		orgs = c.session
+	}
+	return newOrganizationRepository(orgs, c.store)
+}
+
+// CheckIntegrity checks the label assignments in the Client's store against
+// its instances, publishing any issues as a ValidationFailed event
+func (c *Client) CheckIntegrity() ([]IntegrityIssue, error) {
+	instances, err := c.store.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := c.store.LoadLabels()
+	if err != nil {
+		return nil, err
+	}
+	issues := CheckIntegrity(instances, assignments)
+	if len(issues) > 0 {
+		problems := make([]string, len(issues))
+		for i, issue := range issues {
+			problems[i] = issue.Message
+		}
+		c.events.Publish(events.ValidationFailed{Time: time.Now().UTC(), Subject: "labels", Problems: problems})
+	}
+	return issues, nil
+}
+
+// PushSecrets stores the full value of every key in a RedactionNone result
+// in the Client's secret store; see PushToVault
+func (c *Client) PushSecrets(ctx context.Context, result *ScanResult) ([]VaultPush, error) {
+	pushes, err := PushToVault(ctx, result, c.vault)
+	if err != nil {
+		c.logger.Warn("pushing secrets failed", slog.String("error", err.Error()))
+	}
+	return pushes, err
+}
+
+// Registry describes the provider plugins and application scanners the
+// library was built with
+func (c *Client) Registry() Registry {
+	return Registry{}
+}
+
+// Close ends the Client's leases and releases its FFI session. It is safe
+// to call more than once.
+func (c *Client) Close() error {
+	c.leaser.Close()
+	return c.session.Close()
+}
+
+// Lease leases the API key of an instance in the Client's store, by
+// instance ID or label; see Leaser.Lease. Close ends every lease.
+func (c *Client) Lease(ctx context.Context, labelOrInstance string, ttl time.Duration) (*Credential, error) {
+	return c.leaser.Lease(ctx, labelOrInstance, ttl)
+}
+
+// LeaseUsage returns the per-instance lease counts of the Client's leases
+func (c *Client) LeaseUsage() []LeaseUsage {
+	return c.leaser.Usage()
+}
+
+// Registry lists what the library can recognize
+type Registry struct{}
+
+// Providers returns the names of the provider plugins
+func (Registry) Providers() []string {
+	return ListProviders()
+}
+
+// Scanners returns the names of the application scanners
+func (Registry) Scanners() []string {
+	return ListScanners()
+}
+
+// Version returns the library version
+func (Registry) Version() string {
+	return Version()
+}
+
+// publishingStore publishes StoreChanged when labels are saved to a Store
+// other than the Client's session, which publishes its own
+type publishingStore struct {
+	Store
+	client *Client
+}
+
+func (s publishingStore) SaveLabels(labels []LabelAssignment) error {
+	if err := s.Store.SaveLabels(labels); err != nil {
+		return err
+	}
+	if _, isSession := s.Store.(*Session); !isSession {
+		s.client.events.Publish(events.StoreChanged{Time: time.Now().UTC(), Store: "labels", Count: len(labels)})
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package aicred
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+func TestClient(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	c, err := New(WithHomeDir(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.HomeDir() != home {
+		t.Errorf("HomeDir = %q, want %q", c.HomeDir(), home)
+	}
+	if inst, err := c.Instances().Get("openai-main"); err != nil || inst.ID != "openai-main" {
+		t.Fatalf("Instances().Get = %+v, %v", inst, err)
+	}
+	if len(c.Registry().Providers()) == 0 {
+		t.Error("Registry().Providers() is empty")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestClientWithStore(t *testing.T) {
+	bus := events.New()
+	var changed, failed int
+	bus.SubscribeAll(func(e events.Event) {
+		switch e.(type) {
+		case events.StoreChanged:
+			changed++
+		case events.ValidationFailed:
+			failed++
+		}
+	})
+
+	store := NewMemoryStore([]ProviderInstance{{ID: "b"}, {ID: "a"}}, nil)
+	c, err := New(WithHomeDir(t.TempDir()), WithStore(store), WithEvents(bus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var ids []string
+	for instance, err := range c.Instances().All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, instance.ID)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("Instances().All yielded %v", ids)
+	}
+	if _, err := c.Instances().Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing): err = %v, want ErrNotFound", err)
+	}
+
+	dangling := LabelAssignment{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "gone"}}
+	if err := c.Labels().Assign(dangling); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := store.LoadLabels(); len(saved) != 1 {
+		t.Fatalf("store holds %+v", saved)
+	}
+	issues, err := c.CheckIntegrity()
+	if err != nil || len(issues) == 0 {
+		t.Fatalf("CheckIntegrity = %+v, %v", issues, err)
+	}
+	if changed != 1 || failed != 1 {
+		t.Errorf("events: %d StoreChanged, %d ValidationFailed; want 1 each", changed, failed)
+	}
+}
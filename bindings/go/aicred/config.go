@@ -0,0 +1,539 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// Tag uniqueness scopes: how widely a tag may be assigned
+const (
+	TagUniqueGlobal        = "global"
+	TagUniquePerTargetType = "per_target_type"
+	TagUniquePerInstance   = "per_instance"
+)
+
+// Tag is a tag definition from tags.yaml, with a description, a color in
+// Metadata["color"] and a place in the tag hierarchy. The CLI assigns tags
+// in tag_assignments.yaml; the label assignments in labels.yaml refer to
+// the label definitions in labels_metadata.yaml instead.
+type Tag struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Parent is the tag this one sits under, such as "prod" for "prod-us"
+	Parent string `json:"parent,omitempty"`
+	// Uniqueness is one of the TagUnique scopes; "" means TagUniqueGlobal
+	Uniqueness string `json:"uniqueness,omitempty"`
+}
+
+// Config is the aicred configuration of a machine as one document: its
+// provider instances, tags, label assignments and tag assignments. Tag
+// assignments name a tag in LabelName, as tag_assignments.yaml does, and
+// are kept apart from the label assignments. Plan compares two and
+// Apply makes a store match. A nil section is not managed: Plan leaves
+// that part of the configuration alone, while an empty one means none.
+type Config struct {
+	Instances []ProviderInstance `json:"instances"`
+	Tags      []Tag              `json:"tags"`
+	Labels    []LabelAssignment  `json:"labels"`
+	// TagAssignments assign the tags, which InstancesByTag selects by
+	TagAssignments []LabelAssignment `json:"tag_assignments"`
+}
+
+// ConfigStore is a Store whose instances and tags can be written too, as
+// Apply needs. A Session writes the files of its configuration directory;
+// a MemoryStore keeps everything in memory.
+type ConfigStore interface {
+	Store
+	// SaveInstance creates or replaces the instance with instance.ID
+	SaveInstance(instance ProviderInstance) error
+	// DeleteInstance removes the instance with the given ID, or returns an
+	// error wrapping ErrNotFound
+	DeleteInstance(id string) error
+	// LoadTags returns every tag, sorted by name
+	LoadTags() ([]Tag, error)
+	// SaveTags replaces all tags
+	SaveTags(tags []Tag) error
+	// LoadTagAssignments returns every tag assignment. LabelName holds the
+	// tag's name.
+	LoadTagAssignments() ([]LabelAssignment, error)
+	// SaveTagAssignments replaces all tag assignments
+	SaveTagAssignments(assignments []LabelAssignment) error
+}
+
+var (
+	_ ConfigStore = (*Session)(nil)
+	_ ConfigStore = (*MemoryStore)(nil)
+)
+
+// envReference matches an API key of the form ${VAR}
+var envReference = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// ParseConfig reads a Config from YAML, or JSON, with the instances, tags,
+// labels and tag_assignments sections in the format of the aicred
+// configuration files:
+//
+//	instances:
+//	  - id: openai-prod
+//	    provider_type: openai
+//	    base_url: https://api.openai.com/v1
+//	    api_key: ${OPENAI_API_KEY}
+//	    models: [gpt-4o, gpt-4o-mini]
+//	tags:
+//	  - name: prod
+//	labels:
+//	  - label_name: fast
+//	    target: {type: provider_instance, instance_id: openai-prod}
+//	tag_assignments:
+//	  - label_name: prod
+//	    target: {type: provider_instance, instance_id: openai-prod}
+//
+// Keys do not belong in a repository: an api_key of ${VAR} is read from the
+// environment, and an instance without api_key keeps the key it has. It
+// returns an error wrapping ErrParse for malformed documents and
+// ErrInvalidOption for instances without an ID, IDs or tag names given
+// twice, and unset variables.
+func ParseConfig(data []byte) (*Config, error) {
+	var c Config
+	if err := decodeYAML(data, &c); err != nil {
+		return nil, fmt.Errorf("config: %w: %v", ErrParse, err)
+	}
+	return checkConfig(&c)
+}
+
+// checkConfig checks a decoded Config as ParseConfig describes and reads
+// its ${VAR} API keys from the environment
+func checkConfig(c *Config) (*Config, error) {
+	seen := map[string]bool{}
+	for i := range c.Instances {
+		instance := &c.Instances[i]
+		if instance.ID == "" {
+			return nil, fmt.Errorf("config: instance %d has no id: %w", i+1, ErrInvalidOption)
+		}
+		if seen[instance.ID] {
+			return nil, fmt.Errorf("config: instance %q is given twice: %w", instance.ID, ErrInvalidOption)
+		}
+		seen[instance.ID] = true
+		if m := envReference.FindStringSubmatch(instance.APIKey.Reveal()); m != nil {
+			value := getenv(m[1])
+			if value == "" {
+				return nil, fmt.Errorf("config: instance %q: $%s is not set: %w", instance.ID, m[1], ErrInvalidOption)
+			}
+			instance.APIKey = NewSecretString(value)
+		}
+	}
+	clear(seen)
+	for _, tag := range c.Tags {
+		if tag.Name == "" || seen[tag.Name] {
+			return nil, fmt.Errorf("config: tag %q is unnamed or given twice: %w", tag.Name, ErrInvalidOption)
+		}
+		seen[tag.Name] = true
+	}
+	return c, nil
+}
+
+// MarshalConfig encodes c as YAML that ParseConfig reads, such as to start
+// a repository from a machine's CurrentConfig. API keys are left out.
+func MarshalConfig(c *Config) ([]byte, error) {
+	return encodeYAML(c)
+}
+
+// CurrentConfig returns everything store holds, as the current side of a
+// Plan
+func CurrentConfig(store ConfigStore) (*Config, error) {
+	instances, err := store.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := store.LoadTags()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := store.LoadLabels()
+	if err != nil {
+		return nil, err
+	}
+	tagAssignments, err := store.LoadTagAssignments()
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{Instances: instances, Tags: tags, Labels: labels, TagAssignments: tagAssignments}
+	// Nothing stored is still managed: empty, not nil
+	if c.Instances == nil {
+		c.Instances = []ProviderInstance{}
+	}
+	if c.Tags == nil {
+		c.Tags = []Tag{}
+	}
+	if c.Labels == nil {
+		c.Labels = []LabelAssignment{}
+	}
+	if c.TagAssignments == nil {
+		c.TagAssignments = []LabelAssignment{}
+	}
+	return c, nil
+}
+
+// decodeYAML decodes a YAML document into v through its JSON form, so v's
+// json tags and UnmarshalJSON methods apply as they do to the core
+// library's JSON
+func decodeYAML(data []byte, v any) error {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	js, err := json.Marshal(jsonValue(doc))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, v)
+}
+
+// jsonValue converts the mappings yaml.v3 decodes with non-string keys,
+// which encoding/json cannot marshal
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = jsonValue(item)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = jsonValue(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+	}
+	return v
+}
+
+// encodeYAML encodes v as block YAML through its JSON form, keeping the
+// order of struct fields. Secrets encode as null, as in JSON.
+func encodeYAML(v any) ([]byte, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML in flow style; parse it as a node tree and restyle it
+	var doc yaml.Node
+	if err := yaml.Unmarshal(js, &doc); err != nil {
+		return nil, err
+	}
+	blockStyle(&doc)
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// blockStyle clears the flow and quoting styles of n and its children, so
+// the encoder writes block collections and quotes only where needed
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+func sortTags(tags []Tag) {
+	slices.SortStableFunc(tags, func(a, b Tag) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// instancesDir is the directory the core library reads instances from
+func (s *Session) instancesDir() string {
+	return filepath.Join(ConfigDir(s.homeDir), "inference_services")
+}
+
+// instanceFile returns the file holding the instance with the given ID, or
+// "" if there is none. A file that cannot be read or parsed might hold it,
+// so that is an error rather than a reason to write a second file.
+func (s *Session) instanceFile(id string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.instancesDir(), "*.yaml"))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrIO, err)
+	}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrIO, err)
+		}
+		var head struct {
+			ID string `yaml:"id"`
+		}
+		if err := yaml.Unmarshal(data, &head); err != nil {
+			return "", fmt.Errorf("%s: %w: %v", p, ErrParse, err)
+		}
+		if head.ID == id {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// instanceDocument is an instance as its YAML file holds it, key included
+type instanceDocument struct {
+	ProviderInstance
+	APIKey string `json:"api_key"`
+}
+
+// SaveInstance writes instance to its file in inference_services, or to a
+// new file named like the CLI's, <provider_type>-<id prefix>.yaml, and
+// reloads the session
+func (s *Session) SaveInstance(instance ProviderInstance) error {
+	if instance.ID == "" {
+		return fmt.Errorf("save instance: no id: %w", ErrInvalidOption)
+	}
+	old, err := s.GetInstance(instance.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("save instance %q: %w", instance.ID, err)
+	}
+	if err := s.snapshot(); err != nil {
+		return fmt.Errorf("save instance %q: %w", instance.ID, err)
+	}
+	dir := s.instancesDir()
+	if err := hardenConfigDir(dir); err != nil {
+		return fmt.Errorf("save instance %q: %w: %v", instance.ID, ErrIO, err)
+	}
+	p, err := s.instanceFile(instance.ID)
+	if err != nil {
+		return fmt.Errorf("save instance %q: %w", instance.ID, err)
+	}
+	if p == "" {
+		p = filepath.Join(dir, Slugify(instance.ProviderType)+"-"+Slugify(instance.ID[:min(4, len(instance.ID))])+".yaml")
+		if _, err := os.Stat(p); err == nil {
+			p = filepath.Join(dir, Slugify(instance.ProviderType)+"-"+Slugify(instance.ID)+".yaml")
+		}
+	}
+	// The core library requires every field
+	if instance.Models == nil {
+		instance.Models = []string{}
+	}
+	if instance.Metadata == nil {
+		instance.Metadata = map[string]string{}
+	}
+	data, err := encodeYAML(instanceDocument{ProviderInstance: instance, APIKey: instance.APIKey.Reveal()})
+	if err != nil {
+		return fmt.Errorf("save instance %q: %v", instance.ID, err)
+	}
+	if err := writeBytesAtomic(p, data); err != nil {
+		return fmt.Errorf("save instance %q: %w: %v", instance.ID, ErrIO, err)
+	}
+	if err := s.instancesChanged(); err != nil {
+		return err
+	}
+	return s.audit("SaveInstance", instanceAudit(old, instance))
+}
+
+// DeleteInstance removes the file of the instance with the given ID and
+// reloads the session
+func (s *Session) DeleteInstance(id string) error {
+	p, err := s.instanceFile(id)
+	if err != nil {
+		return fmt.Errorf("delete instance %q: %w", id, err)
+	}
+	if p == "" {
+		return fmt.Errorf("delete instance %q: %w", id, ErrNotFound)
+	}
+	if err := s.snapshot(); err != nil {
+		return fmt.Errorf("delete instance %q: %w", id, err)
+	}
+	if err := os.Remove(p); err != nil {
+		return fmt.Errorf("delete instance %q: %w: %v", id, ErrIO, err)
+	}
+	if err := s.instancesChanged(); err != nil {
+		return err
+	}
+	return s.audit("DeleteInstance", []Change{{Action: ActionDelete, Kind: KindInstance, Name: id}})
+}
+
+func (s *Session) instancesChanged() error {
+	if err := s.Reload(); err != nil {
+		return err
+	}
+	instances, err := s.LoadInstances()
+	if err != nil {
+		return err
+	}
+	s.events.Publish(events.StoreChanged{Time: time.Now().UTC(), Store: "instances", Count: len(instances)})
+	return nil
+}
+
+// tagsPath is the file the core library keeps tags in
+func (s *Session) tagsPath() string {
+	return filepath.Join(ConfigDir(s.homeDir), "tags.yaml")
+}
+
+// LoadTags returns every tag in tags.yaml, sorted by name
+func (s *Session) LoadTags() ([]Tag, error) {
+	data, err := os.ReadFile(s.tagsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read tags: %w: %v", ErrIO, err)
+	}
+	var tags []Tag
+	if err := decodeYAML(data, &tags); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", s.tagsPath(), ErrParse, err)
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+// SaveTags replaces all tags and writes them to tags.yaml
+func (s *Session) SaveTags(tags []Tag) error {
+	if tags == nil {
+		tags = []Tag{}
+	}
+	before, err := s.LoadTags()
+	if err != nil {
+		return err
+	}
+	if err := s.snapshot(); err != nil {
+		return fmt.Errorf("save tags: %w", err)
+	}
+	if err := hardenConfigDir(ConfigDir(s.homeDir)); err != nil {
+		return fmt.Errorf("save tags: %w: %v", ErrIO, err)
+	}
+	data, err := encodeYAML(tags)
+	if err != nil {
+		return fmt.Errorf("save tags: %v", err)
+	}
+	if err := writeBytesAtomic(s.tagsPath(), data); err != nil {
+		return fmt.Errorf("save tags: %w: %v", ErrIO, err)
+	}
+	s.events.Publish(events.StoreChanged{Time: time.Now().UTC(), Store: "tags", Count: len(tags)})
+	return s.audit("SaveTags", planTags(auditTags(tags), auditTags(before)))
+}
+
+// tagAssignmentsPath is the file the CLI keeps tag assignments in
+func (s *Session) tagAssignmentsPath() string {
+	return filepath.Join(ConfigDir(s.homeDir), "tag_assignments.yaml")
+}
+
+// LoadTagAssignments returns every tag assignment in tag_assignments.yaml,
+// the file the CLI's tag commands read and write
+func (s *Session) LoadTagAssignments() ([]LabelAssignment, error) {
+	data, err := os.ReadFile(s.tagAssignmentsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read tag assignments: %w: %v", ErrIO, err)
+	}
+	var assignments []LabelAssignment
+	if err := decodeYAML(data, &assignments); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", s.tagAssignmentsPath(), ErrParse, err)
+	}
+	return assignments, nil
+}
+
+// SaveTagAssignments replaces all tag assignments and writes them to
+// tag_assignments.yaml
+func (s *Session) SaveTagAssignments(assignments []LabelAssignment) error {
+	if assignments == nil {
+		assignments = []LabelAssignment{}
+	}
+	before, err := s.LoadTagAssignments()
+	if err != nil {
+		return err
+	}
+	if err := s.snapshot(); err != nil {
+		return fmt.Errorf("save tag assignments: %w", err)
+	}
+	if err := hardenConfigDir(ConfigDir(s.homeDir)); err != nil {
+		return fmt.Errorf("save tag assignments: %w: %v", ErrIO, err)
+	}
+	data, err := encodeYAML(assignments)
+	if err != nil {
+		return fmt.Errorf("save tag assignments: %v", err)
+	}
+	if err := writeBytesAtomic(s.tagAssignmentsPath(), data); err != nil {
+		return fmt.Errorf("save tag assignments: %w: %v", ErrIO, err)
+	}
+	s.events.Publish(events.StoreChanged{Time: time.Now().UTC(), Store: "tag_assignments", Count: len(assignments)})
+	return s.audit("SaveTagAssignments", planTagAssignments(assignments, before))
+}
+
+// SaveInstance creates or replaces the instance with instance.ID
+func (m *MemoryStore) SaveInstance(instance ProviderInstance) error {
+	if instance.ID == "" {
+		return fmt.Errorf("save instance: no id: %w", ErrInvalidOption)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := slices.IndexFunc(m.instances, func(p ProviderInstance) bool { return p.ID == instance.ID }); i >= 0 {
+		m.instances[i] = instance
+		return nil
+	}
+	m.instances = append(m.instances, instance)
+	slices.SortStableFunc(m.instances, func(a, b ProviderInstance) int { return strings.Compare(a.ID, b.ID) })
+	return nil
+}
+
+// DeleteInstance removes the instance with the given ID
+func (m *MemoryStore) DeleteInstance(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.instances, func(p ProviderInstance) bool { return p.ID == id })
+	if i < 0 {
+		return fmt.Errorf("instance %q: %w", id, ErrNotFound)
+	}
+	m.instances = slices.Delete(m.instances, i, i+1)
+	return nil
+}
+
+// LoadTags returns every tag, sorted by name
+func (m *MemoryStore) LoadTags() ([]Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.tags), nil
+}
+
+// SaveTags replaces all tags
+func (m *MemoryStore) SaveTags(tags []Tag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tags = slices.Clone(tags)
+	sortTags(m.tags)
+	return nil
+}
+
+// LoadTagAssignments returns every tag assignment
+func (m *MemoryStore) LoadTagAssignments() ([]LabelAssignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.tagAssignments), nil
+}
+
+// SaveTagAssignments replaces all tag assignments
+func (m *MemoryStore) SaveTagAssignments(assignments []LabelAssignment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tagAssignments = slices.Clone(assignments)
+	return nil
+}
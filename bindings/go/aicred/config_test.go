@@ -0,0 +1,209 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	fakeEnv(t, false, nil)
+	for doc, want := range map[string]error{
+		"instances: [": ErrParse,
+		"instances:\n  - provider_type: openai\n":        ErrInvalidOption,
+		"instances:\n  - id: a\n  - id: a\n":             ErrInvalidOption,
+		"instances:\n  - id: a\n    api_key: ${UNSET}\n": ErrInvalidOption,
+		"tags:\n  - name: prod\n  - name: prod\n":        ErrInvalidOption,
+	} {
+		if _, err := ParseConfig([]byte(doc)); !errors.Is(err, want) {
+			t.Errorf("ParseConfig(%q) = %v, want %v", doc, err, want)
+		}
+	}
+
+	c, err := ParseConfig([]byte("tags: []\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Instances != nil || c.Tags == nil {
+		t.Errorf("sections = %+v", c)
+	}
+}
+
+func FuzzParseConfig(f *testing.F) {
+	for _, doc := range []string{
+		"instances: [",
+		"instances:\n  - id: a\n  - id: a\n",
+		"instances:\n  - id: a\n    api_key: ${UNSET}\n",
+		"tags:\n  - name: prod\n  - name: prod\n",
+	} {
+		f.Add([]byte(doc))
+	}
+	for _, format := range []ConfigFormat{ConfigYAML, ConfigJSON} {
+		if data, err := MarshalConfigAs(configFixture(), format); err == nil {
+			f.Add(data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := ParseConfig(data)
+		if err != nil {
+			if !errors.Is(err, ErrParse) && !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("error wraps neither ErrParse nor ErrInvalidOption: %v", err)
+			}
+			return
+		}
+		out, err := MarshalConfig(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseConfig(out); err != nil {
+			t.Fatalf("written config does not parse: %v\n%s", err, out)
+		}
+	})
+}
+
+func TestYAMLCodec(t *testing.T) {
+	type entry struct {
+		Name    string            `json:"name"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]string `json:"labels,omitempty"`
+		Key     Secret            `json:"key"`
+		Created time.Time         `json:"created"`
+	}
+	var in []entry
+	doc := `# serde_yaml output
+- name: openai
+  tags: [prod, "yes"]
+  labels: {color: '#ff0000'}
+  key: sk-secret
+  created: 2024-01-01T00:00:00Z
+- name: 'a: b'
+  tags: []
+  key: null
+  created: "2024-02-01T00:00:00Z"
+`
+	if err := decodeYAML([]byte(doc), &in); err != nil {
+		t.Fatal(err)
+	}
+	if len(in) != 2 || in[0].Key.Reveal() != "sk-secret" || in[0].Labels["color"] != "#ff0000" || in[0].Created.Month() != 1 || in[1].Name != "a: b" {
+		t.Fatalf("decoded %+v", in)
+	}
+
+	data, err := encodeYAML(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- name: openai
+  tags:
+    - prod
+    - yes
+  labels:
+    color: '#ff0000'
+  key: null
+  created: "2024-01-01T00:00:00Z"
+- name: 'a: b'
+  tags: []
+  key: null
+  created: "2024-02-01T00:00:00Z"
+`
+	if string(data) != want {
+		t.Errorf("encoded\n%s\nwant\n%s", data, want)
+	}
+	if err := decodeYAML([]byte("a: [b"), &in); err == nil {
+		t.Error("decoded malformed YAML")
+	}
+}
+
+func TestMarshalConfig(t *testing.T) {
+	c := &Config{
+		Instances: []ProviderInstance{{ID: "openai", ProviderType: "openai", APIKey: NewSecretString("sk-secret"), Models: []string{"gpt-4o"}}},
+		Tags:      []Tag{{Name: "prod", Metadata: map[string]string{"color": "#00ff00"}}},
+		Labels:    []LabelAssignment{},
+	}
+	data, err := MarshalConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-secret") {
+		t.Errorf("config holds a key:\n%s", data)
+	}
+	back, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, data)
+	}
+	if back.Instances[0].Models[0] != "gpt-4o" || back.Tags[0].Metadata["color"] != "#00ff00" || back.Labels == nil {
+		t.Errorf("round trip = %+v", back)
+	}
+}
+
+func TestSessionConfigStore(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	instance := ProviderInstance{ID: "groq-fast", ProviderType: "groq", BaseURL: "https://api.groq.com/openai/v1", APIKey: NewSecretString("gsk_test")}
+	if err := s.SaveInstance(instance); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(home, ".config", "aicred", "inference_services")
+	readFile := func(name string) map[string]any {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]any
+		if err := decodeYAML(data, &doc); err != nil {
+			t.Fatalf("%v:\n%s", err, data)
+		}
+		return doc
+	}
+	if doc := readFile("groq-groq.yaml"); doc["id"] != "groq-fast" || doc["api_key"] != "gsk_test" || doc["models"] == nil || doc["metadata"] == nil {
+		t.Errorf("instance file = %v", doc)
+	}
+
+	instance.Models = []string{"llama-3.1-8b"}
+	if err := s.SaveInstance(instance); err != nil {
+		t.Fatal(err)
+	}
+	if paths, _ := filepath.Glob(filepath.Join(dir, "*.yaml")); len(paths) != 2 {
+		t.Errorf("files = %v", paths)
+	}
+	if doc := readFile("groq-groq.yaml"); len(doc["models"].([]any)) != 1 {
+		t.Errorf("updated instance file = %v", doc)
+	}
+
+	if err := s.DeleteInstance("openai-main"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "openai-main.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("instance file was not removed: %v", err)
+	}
+	if err := s.DeleteInstance("openai-main"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete = %v", err)
+	}
+
+	// A file that does not parse might hold the instance
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("id: [groq-fast\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveInstance(instance); !errors.Is(err, ErrParse) {
+		t.Errorf("save beside a broken file = %v", err)
+	}
+	os.Remove(filepath.Join(dir, "broken.yaml"))
+
+	tags := []Tag{{Name: "prod", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Metadata: map[string]string{"color": "#ff0000"}}}
+	if err := s.SaveTags(tags); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.LoadTags()
+	if err != nil || len(loaded) != 1 || loaded[0].Metadata["color"] != "#ff0000" || !loaded[0].CreatedAt.Equal(tags[0].CreatedAt) {
+		t.Errorf("tags = %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,108 @@
+package aicred
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFormat is an encoding of a Config document
+type ConfigFormat string
+
+// The encodings LoadConfigFile and SaveConfigFile choose between
+const (
+	// ConfigYAML is YAML, as the core library writes its configuration
+	ConfigYAML ConfigFormat = "yaml"
+	// ConfigJSON is JSON
+	ConfigJSON ConfigFormat = "json"
+	// ConfigTOML is TOML, with instances, tags and labels as arrays of
+	// tables
+	ConfigTOML ConfigFormat = "toml"
+)
+
+// ConfigFormatOf returns the encoding of a configuration file by its
+// extension: .yaml or .yml, .json, or .toml. Other extensions give an error
+// wrapping ErrInvalidOption.
+func ConfigFormatOf(path string) (ConfigFormat, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return ConfigYAML, nil
+	case ".json":
+		return ConfigJSON, nil
+	case ".toml":
+		return ConfigTOML, nil
+	default:
+		return "", fmt.Errorf("config %s: unknown extension %q: %w", path, ext, ErrInvalidOption)
+	}
+}
+
+// ParseConfigAs is ParseConfig for a document in format
+func ParseConfigAs(data []byte, format ConfigFormat) (*Config, error) {
+	switch format {
+	case ConfigYAML, ConfigJSON:
+		// JSON is YAML
+		return ParseConfig(data)
+	case ConfigTOML:
+		var c Config
+		if err := decodeTOML(data, &c); err != nil {
+			return nil, fmt.Errorf("config: %w: %v", ErrParse, err)
+		}
+		return checkConfig(&c)
+	}
+	return nil, fmt.Errorf("config: unknown format %q: %w", format, ErrInvalidOption)
+}
+
+// MarshalConfigAs is MarshalConfig for format. API keys are left out.
+func MarshalConfigAs(c *Config, format ConfigFormat) ([]byte, error) {
+	switch format {
+	case ConfigYAML:
+		return MarshalConfig(c)
+	case ConfigJSON:
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case ConfigTOML:
+		return encodeTOML(c)
+	}
+	return nil, fmt.Errorf("config: unknown format %q: %w", format, ErrInvalidOption)
+}
+
+// LoadConfigFile reads the Config in the file at path, in the format its
+// extension names, as ParseConfig does. A file that cannot be read gives
+// an error wrapping ErrIO.
+func LoadConfigFile(path string) (*Config, error) {
+	format, err := ConfigFormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w: %v", path, ErrIO, err)
+	}
+	c, err := ParseConfigAs(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// SaveConfigFile writes c to the file at path, in the format its extension
+// names, without API keys. The file is replaced atomically.
+func SaveConfigFile(path string, c *Config) error {
+	format, err := ConfigFormatOf(path)
+	if err != nil {
+		return err
+	}
+	data, err := MarshalConfigAs(c, format)
+	if err != nil {
+		return fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := writeBytesAtomic(path, data); err != nil {
+		return fmt.Errorf("config %s: %w: %v", path, ErrIO, err)
+	}
+	return nil
+}
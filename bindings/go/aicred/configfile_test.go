@@ -0,0 +1,122 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configFixture is a Config with every section set
+func configFixture() *Config {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &Config{
+		Instances: []ProviderInstance{{
+			ID:           "openai-prod",
+			ProviderType: "openai",
+			BaseURL:      "https://api.openai.com/v1",
+			APIKey:       NewSecretString("sk-secret"),
+			Models:       []string{"gpt-4o", "gpt-4o-mini"},
+			Capabilities: Capabilities{Chat: true, Streaming: true},
+			Active:       true,
+			Metadata:     map[string]string{MetadataTemperature: "0.2", "header.X-Team": "research"},
+		}},
+		Tags:   []Tag{{Name: "prod", CreatedAt: created, Metadata: map[string]string{"color": "#00ff00"}}},
+		Labels: []LabelAssignment{{LabelName: "fast", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "openai-prod", ModelID: "gpt-4o"}, AssignedAt: created}},
+	}
+}
+
+func TestConfigFileFormats(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := configFixture()
+	dir := t.TempDir()
+	for _, name := range []string{"config.yaml", "config.yml", "config.json", "config.toml"} {
+		p := filepath.Join(dir, name)
+		if err := SaveConfigFile(p, c); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, _ := os.ReadFile(p)
+		if strings.Contains(string(data), "sk-secret") {
+			t.Errorf("%s holds the API key:\n%s", name, data)
+		}
+		back, err := LoadConfigFile(p)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", name, err, data)
+		}
+		instance := back.Instances[0]
+		if instance.Models[1] != "gpt-4o-mini" || !instance.Capabilities.Streaming || !instance.Active || instance.Metadata["header.X-Team"] != "research" {
+			t.Errorf("%s: instance = %+v", name, instance)
+		}
+		if !back.Tags[0].CreatedAt.Equal(created) || back.Tags[0].Metadata["color"] != "#00ff00" || back.Labels[0].Target.ModelID != "gpt-4o" {
+			t.Errorf("%s: tags %+v, labels %+v", name, back.Tags, back.Labels)
+		}
+	}
+
+	if err := SaveConfigFile(filepath.Join(dir, "config.ini"), c); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("SaveConfigFile with .ini = %v, want ErrInvalidOption", err)
+	}
+	if _, err := LoadConfigFile(filepath.Join(dir, "missing.toml")); !errors.Is(err, ErrIO) {
+		t.Errorf("LoadConfigFile of a missing file = %v, want ErrIO", err)
+	}
+	bad := filepath.Join(dir, "bad.toml")
+	if err := os.WriteFile(bad, []byte("[[instances]]\nid = \"a\"\n[[instances]]\nid = \"a\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFile(bad); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("duplicate instances in TOML = %v, want ErrInvalidOption", err)
+	}
+	if err := os.WriteFile(bad, []byte("instances = ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFile(bad); !errors.Is(err, ErrParse) {
+		t.Errorf("malformed TOML = %v, want ErrParse", err)
+	}
+	if _, err := ParseConfigAs([]byte("[[instances]]\nid = \"a\x7f\"\n"), ConfigTOML); !errors.Is(err, ErrParse) {
+		t.Errorf("TOML string with a control character = %v, want ErrParse", err)
+	}
+
+	// Escaped control characters survive a trip through TOML
+	escaped, err := ParseConfigAs([]byte(`{"instances": [{"id": "a\u007f\u0085", "provider_type": "x\u0000"}]}`), ConfigJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalConfigAs(escaped, ConfigTOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := ParseConfigAs(data, ConfigTOML)
+	if err != nil || back.Instances[0].ID != "a\x7f\u0085" || back.Instances[0].ProviderType != "x\x00" {
+		t.Errorf("TOML round trip = %+v, %v\n%s", back, err, data)
+	}
+}
+
+func FuzzParseConfigAs(f *testing.F) {
+	formats := []ConfigFormat{ConfigYAML, ConfigJSON, ConfigTOML}
+	for i, format := range formats {
+		if data, err := MarshalConfigAs(configFixture(), format); err == nil {
+			f.Add(data, uint8(i))
+		}
+	}
+	f.Add([]byte("[[instances]]\nid = \"a\"\n[[instances]]\nid = \"a\"\n"), uint8(2))
+	f.Add([]byte("instances = ["), uint8(2))
+	f.Fuzz(func(t *testing.T, data []byte, i uint8) {
+		format := formats[int(i)%len(formats)]
+		c, err := ParseConfigAs(data, format)
+		if err != nil {
+			if !errors.Is(err, ErrParse) && !errors.Is(err, ErrInvalidOption) {
+				t.Fatalf("%s: error wraps neither ErrParse nor ErrInvalidOption: %v", format, err)
+			}
+			return
+		}
+		// What parses writes back out in a form that parses again
+		out, err := MarshalConfigAs(c, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if _, err := ParseConfigAs(out, format); err != nil {
+			t.Fatalf("%s: written config does not parse: %v\n%s", format, err, out)
+		}
+	})
+}
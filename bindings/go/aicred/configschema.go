@@ -0,0 +1,256 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSchemaRequired lists the properties ParseConfig cannot do without,
+// by struct
+var configSchemaRequired = map[reflect.Type][]string{
+	reflect.TypeFor[ProviderInstance](): {"id"},
+	reflect.TypeFor[Tag]():              {"name"},
+	reflect.TypeFor[LabelAssignment]():  {"label_name", "target"},
+	reflect.TypeFor[LabelTarget]():      {"type", "instance_id"},
+}
+
+// configSchemaEnums lists the values a property may take, by struct and
+// property
+var configSchemaEnums = map[reflect.Type]map[string][]any{
+	reflect.TypeFor[LabelTarget](): {"type": {LabelTargetInstance, LabelTargetModel}},
+	reflect.TypeFor[Tag]():         {"uniqueness": {"", TagUniqueGlobal, TagUniquePerTargetType, TagUniquePerInstance}},
+}
+
+// ConfigJSONSchema returns a JSON Schema (draft 2020-12) for the documents
+// ParseConfig reads, generated from Config and the types it holds, so
+// editors, CI checks and other languages can validate configuration
+// repositories. Unknown properties are rejected, which catches misspelled
+// fields that ParseConfig would ignore. The caller may modify the slice.
+func ConfigJSONSchema() []byte {
+	return bytes.Clone(configSchema())
+}
+
+var configSchema = sync.OnceValue(func() []byte {
+	defs := map[string]any{}
+	root := schemaFor(reflect.TypeFor[Config](), defs).(map[string]any)
+	name := strings.TrimPrefix(root["$ref"].(string), "#/$defs/")
+	schema := defs[name].(map[string]any)
+	delete(defs, name)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "aicred configuration"
+	schema["$defs"] = defs
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return data
+})
+
+// schemaFor returns the schema of values of t as encoding/json writes
+// them, adding the structs it refers to to defs
+func schemaFor(t reflect.Type, defs map[string]any) any {
+	switch t {
+	case reflect.TypeFor[Secret]():
+		return map[string]any{"type": []string{"string", "null"}}
+	case reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": []string{"array", "null"}, "items": schemaFor(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": schemaFor(t.Elem(), defs)}
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), defs)
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // placeholder against recursion
+			properties := map[string]any{}
+			for _, f := range reflect.VisibleFields(t) {
+				name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+				if !f.IsExported() || f.Anonymous || name == "-" {
+					continue
+				}
+				if name == "" {
+					name = f.Name
+				}
+				property := schemaFor(f.Type, defs).(map[string]any)
+				if values, ok := configSchemaEnums[t][name]; ok {
+					property["enum"] = values
+				}
+				properties[name] = property
+			}
+			def := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+			if required := configSchemaRequired[t]; required != nil {
+				def["required"] = required
+			}
+			defs[t.Name()] = def
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	panic("aicred: no JSON Schema for " + t.String())
+}
+
+// ValidateAgainstSchema checks a configuration document, in YAML or JSON,
+// against ConfigJSONSchema. A document that does not parse gives an error
+// wrapping ErrParse. Each schema violation gives an error wrapping
+// ErrInvalidOption that names where it is, such as instances[0].base_url,
+// and all of them are returned joined.
+func ValidateAgainstSchema(data []byte) error {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("config: %w: %v", ErrParse, err)
+	}
+	// Go through JSON, as decodeYAML does, so timestamps and numbers look
+	// as they will to ParseConfig
+	js, err := json.Marshal(jsonValue(raw))
+	if err != nil {
+		return fmt.Errorf("config: %w: %v", ErrParse, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("config: %w: %v", ErrParse, err)
+	}
+	if doc == nil {
+		doc = map[string]any{} // an empty document manages nothing
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(configSchema(), &schema); err != nil {
+		return fmt.Errorf("config schema: %v", err)
+	}
+	v := schemaValidator{defs: schema["$defs"].(map[string]any)}
+	v.validate("", doc, schema)
+	return errors.Join(v.problems...)
+}
+
+// schemaValidator checks a document against the subset of JSON Schema that
+// schemaFor writes
+type schemaValidator struct {
+	defs     map[string]any
+	problems []error
+}
+
+func (v *schemaValidator) report(path, format string, args ...any) {
+	if path == "" {
+		path = "(document)"
+	}
+	v.problems = append(v.problems, fmt.Errorf("config: %s: %s: %w", path, fmt.Sprintf(format, args...), ErrInvalidOption))
+}
+
+func (v *schemaValidator) validate(path string, value any, schema map[string]any) {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+	if want, ok := schema["type"]; ok && !matchesType(value, want) {
+		v.report(path, "is %s, want %s", jsonType(value), typeList(want))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		v.report(path, "%v is not one of %v", value, enum)
+	}
+	if schema["format"] == "date-time" {
+		if s, ok := value.(string); ok {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				v.report(path, "%q is not an RFC 3339 date-time", s)
+			}
+		}
+	}
+	switch value := value.(type) {
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.validate(fmt.Sprintf("%s[%d]", path, i), item, items)
+			}
+		}
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := value[name.(string)]; !ok {
+				v.report(path, "%s is required", name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			child := strings.TrimPrefix(path+"."+k, ".")
+			switch extra := schema["additionalProperties"].(type) {
+			case nil, bool:
+				if property, ok := properties[k].(map[string]any); ok {
+					v.validate(child, value[k], property)
+				} else if extra == false {
+					v.report(child, "unknown property")
+				}
+			case map[string]any:
+				v.validate(child, value[k], extra)
+			}
+		}
+	}
+}
+
+// matchesType reports whether value is of the JSON type, or one of the
+// types, in want
+func matchesType(value any, want any) bool {
+	if types, ok := want.([]any); ok {
+		return slices.ContainsFunc(types, func(t any) bool { return matchesType(value, t) })
+	}
+	got := jsonType(value)
+	return got == want || got == "integer" && want == "number"
+}
+
+// jsonType returns the JSON Schema type of a value decoded with UseNumber
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// typeList formats a schema type for a message: "string" or "array or null"
+func typeList(want any) string {
+	types, ok := want.([]any)
+	if !ok {
+		return fmt.Sprint(want)
+	}
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprint(t)
+	}
+	return strings.Join(parts, " or ")
+}
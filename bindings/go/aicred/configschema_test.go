@@ -0,0 +1,100 @@
+package aicred
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfigJSONSchema(t *testing.T) {
+	var schema struct {
+		Schema     string                    `json:"$schema"`
+		Properties map[string]map[string]any `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+			Required   []string                  `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(ConfigJSONSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Schema == "" || len(schema.Properties) != 4 || schema.Properties["tag_assignments"]["items"] == nil {
+		t.Errorf("root = %+v", schema)
+	}
+	instance := schema.Defs["ProviderInstance"]
+	if instance.Properties["api_key"]["type"] == nil || instance.Properties["capabilities"]["$ref"] != "#/$defs/Capabilities" || len(instance.Required) != 1 {
+		t.Errorf("ProviderInstance = %+v", instance)
+	}
+	if enum := schema.Defs["LabelTarget"].Properties["type"]["enum"]; enum == nil {
+		t.Error("LabelTarget.type has no enum")
+	}
+
+	b := ConfigJSONSchema()
+	b[0] = 'x'
+	if ConfigJSONSchema()[0] != '{' {
+		t.Error("ConfigJSONSchema shares its slice")
+	}
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	valid := `
+instances:
+  - id: openai-prod
+    provider_type: openai
+    base_url: https://api.openai.com/v1
+    api_key: ${OPENAI_API_KEY}
+    models: [gpt-4o]
+    active: true
+    capabilities: {chat: true}
+    metadata: {temperature: "0.2"}
+tags:
+  - name: prod
+    created_at: 2024-01-01T00:00:00Z
+    uniqueness: global
+labels:
+  - label_name: fast
+    target: {type: provider_model, instance_id: openai-prod, model_id: gpt-4o}
+`
+	if err := ValidateAgainstSchema([]byte(valid)); err != nil {
+		t.Errorf("valid document: %v", err)
+	}
+	if err := ValidateAgainstSchema(nil); err != nil {
+		t.Errorf("empty document: %v", err)
+	}
+	if err := ValidateAgainstSchema([]byte("instances: [")); !errors.Is(err, ErrParse) {
+		t.Errorf("malformed document = %v, want ErrParse", err)
+	}
+
+	invalid := `
+instances:
+  - provider_type: openai
+    base_ur: https://api.openai.com/v1
+    active: "yes"
+tags:
+  - name: prod
+    uniqueness: everywhere
+labels:
+  - label_name: fast
+    target: {type: model, instance_id: openai-prod}
+    assigned_at: last week
+extra: true
+`
+	err := ValidateAgainstSchema([]byte(invalid))
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("invalid document = %v, want ErrInvalidOption", err)
+	}
+	for _, want := range []string{
+		"instances[0]: id is required",
+		"instances[0].base_ur: unknown property",
+		"instances[0].active: is string, want boolean",
+		"tags[0].uniqueness: everywhere is not one of",
+		"labels[0].target.type: model is not one of",
+		`labels[0].assigned_at: "last week" is not an RFC 3339 date-time`,
+		"extra: unknown property",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("errors do not include %q:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package aicred
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+// ScanContent scans the content read from r for provider keys without
+// writing it to disk. name is reported as each key's Source.
+//
+// Detection runs in Go (see package detect) and looks for known key
+// prefixes and environment variable assignments. The application scanners
+// used by Scan do not apply, so HomeDir is ignored. MaxFileSize, when set,
+// bounds how much is read, OnlyProviders and ExcludeProviders filter the
+// results, and Redaction applies as for Scan.
+func ScanContent(name string, r io.Reader, options ScanOptions) ([]DiscoveredKey, error) {
+	if options.MaxFileSize > 0 {
+		r = io.LimitReader(r, int64(options.MaxFileSize)+1)
+	}
+	content, err := io.ReadAll(r)
+	defer zero(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %v", ErrIO, name, err)
+	}
+	if options.MaxFileSize > 0 && len(content) > options.MaxFileSize {
+		return nil, fmt.Errorf("%s exceeds MaxFileSize of %d bytes", name, options.MaxFileSize)
+	}
+
+	level := options.redactionLevel()
+	keys := detectKeys(name, content, options, level == RedactionNone)
+
+	logger().Debug("content scan complete",
+		slog.String("name", name),
+		slog.Int("bytes", len(content)),
+		slog.Int("keys", len(keys)))
+	return redactKeys(keys, level), nil
+}
+
+// detectKeys runs the Go detector over content and applies the provider
+// filters in options. Values are kept only when withValues is set.
+func detectKeys(name string, content []byte, options ScanOptions, withValues bool) []DiscoveredKey {
+	var keys []DiscoveredKey
+	for _, m := range detect.Find(content) {
+		if len(options.OnlyProviders) > 0 && !slices.Contains(options.OnlyProviders, m.Provider) {
+			continue
+		}
+		if slices.Contains(options.ExcludeProviders, m.Provider) {
+			continue
+		}
+		key := DiscoveredKey{
+			Provider:   m.Provider,
+			Source:     name,
+			ValueType:  "ApiKey",
+			Confidence: m.Confidence,
+			Hash:       m.Hash,
+			Redacted:   m.Redacted,
+			Location:   &Location{Path: name, Line: m.Line, Column: m.Column, Offset: int64(m.Offset), EnvVar: m.EnvVar},
+		}
+		if withValues {
+			key.Value = NewSecretString(m.Value)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// pathOnly drops the line, column and offset from keys found in text
+// extracted from a binary file, where they do not point into the file
+func pathOnly(keys []DiscoveredKey) []DiscoveredKey {
+	for i := range keys {
+		if l := keys[i].Location; l != nil {
+			keys[i].Location = &Location{Path: l.Path, EnvVar: l.EnvVar}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,67 @@
+package aicred
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const contentFixture = `OPENAI_API_KEY=sk-proj-abcdefghijklmnopqrstuvwx
+ANTHROPIC_API_KEY=sk-ant-REDACTED
+`
+
+func TestScanContent(t *testing.T) {
+	keys, err := ScanContent("upload.env", strings.NewReader(contentFixture), ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(keys))
+	}
+	for _, key := range keys {
+		if key.Source != "upload.env" || key.Hash == "" || key.Redacted == "" {
+			t.Errorf("incomplete key: %+v", key)
+		}
+		if !key.Value.IsZero() {
+			t.Errorf("%s key carries its value at the default redaction", key.Provider)
+		}
+	}
+	if l := keys[1].Location; l == nil || *l != (Location{Path: "upload.env", Line: 2, Column: 19, Offset: 66}) {
+		t.Errorf("anthropic key location = %+v", l)
+	}
+}
+
+func TestScanContentOptions(t *testing.T) {
+	keys, err := ScanContent("upload.env", strings.NewReader(contentFixture), ScanOptions{
+		Redaction:     RedactionNone,
+		OnlyProviders: []string{"openai"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Provider != "openai" {
+		t.Fatalf("keys = %+v, want only openai", keys)
+	}
+	if got := keys[0].Value.Reveal(); got != "sk-proj-abcdefghijklmnopqrstuvwx" {
+		t.Errorf("value = %q", got)
+	}
+
+	keys, err = ScanContent("upload.env", strings.NewReader(contentFixture), ScanOptions{ExcludeProviders: []string{"openai"}})
+	if err != nil || len(keys) != 1 || keys[0].Provider != "anthropic" {
+		t.Fatalf("keys = %+v, err = %v, want only anthropic", keys, err)
+	}
+
+	if _, err := ScanContent("upload.env", strings.NewReader(contentFixture), ScanOptions{MaxFileSize: 10}); err == nil {
+		t.Error("expected an error for content over MaxFileSize")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("boom") }
+
+func TestScanContentReadError(t *testing.T) {
+	if _, err := ScanContent("broken", failingReader{}, ScanOptions{}); !errors.Is(err, ErrIO) {
+		t.Fatalf("err = %v, want ErrIO", err)
+	}
+}
@@ -0,0 +1,209 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Instance metadata keys holding default request parameters. Tools that
+// call the instance read them with RequestDefaultsOf so they get complete
+// connection settings, not just the base URL and key. The organization and
+// project reuse MetadataProviderOrganization and MetadataProviderProject,
+// which InspectInstance also fills in.
+const (
+	// MetadataTemperature is the sampling temperature, from 0 to 2
+	MetadataTemperature = "temperature"
+	// MetadataMaxTokens is the largest number of tokens to generate
+	MetadataMaxTokens = "max_tokens"
+	// MetadataTopP is the nucleus sampling probability, above 0 and at
+	// most 1
+	MetadataTopP = "top_p"
+	// MetadataAPIVersion is the api-version query parameter, which Azure
+	// OpenAI requires
+	MetadataAPIVersion = "api_version"
+	// MetadataHeaderPrefix starts the keys of extra request headers:
+	// "header.X-Team" holds the value sent as X-Team
+	MetadataHeaderPrefix = "header."
+)
+
+// RequestDefaults are the parameters an instance's requests use unless the
+// caller sets them. Nil and empty fields are unset.
+type RequestDefaults struct {
+	Temperature *float64          `json:"temperature,omitempty"`
+	MaxTokens   *int              `json:"max_tokens,omitempty"`
+	TopP        *float64          `json:"top_p,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	// Organization and Project are sent as the OpenAI-Organization and
+	// OpenAI-Project headers
+	Organization string `json:"organization,omitempty"`
+	Project      string `json:"project,omitempty"`
+	APIVersion   string `json:"api_version,omitempty"`
+}
+
+// RequestDefaultsOf reads instance's request defaults from its metadata.
+// A value that does not parse is an error wrapping ErrInvalidInstance; the
+// defaults are also checked with Validate.
+func RequestDefaultsOf(instance ProviderInstance) (RequestDefaults, error) {
+	var d RequestDefaults
+	var problems []error
+	parseFloat := func(key string) *float64 {
+		s, ok := instance.Metadata[key]
+		if !ok {
+			return nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("instance %q: %s %q is not a number: %w", instance.ID, key, s, ErrInvalidInstance))
+			return nil
+		}
+		return &f
+	}
+	d.Temperature = parseFloat(MetadataTemperature)
+	d.TopP = parseFloat(MetadataTopP)
+	if s, ok := instance.Metadata[MetadataMaxTokens]; ok {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			problems = append(problems, fmt.Errorf("instance %q: %s %q is not a whole number: %w", instance.ID, MetadataMaxTokens, s, ErrInvalidInstance))
+		} else {
+			d.MaxTokens = &n
+		}
+	}
+	d.Organization = instance.Metadata[MetadataProviderOrganization]
+	d.Project = instance.Metadata[MetadataProviderProject]
+	d.APIVersion = instance.Metadata[MetadataAPIVersion]
+	for key, value := range instance.Metadata {
+		if name, ok := strings.CutPrefix(key, MetadataHeaderPrefix); ok {
+			if d.Headers == nil {
+				d.Headers = map[string]string{}
+			}
+			d.Headers[name] = value
+		}
+	}
+	if err := errors.Join(problems...); err != nil {
+		return d, err
+	}
+	if err := d.Validate(); err != nil {
+		return d, fmt.Errorf("instance %q: %w", instance.ID, err)
+	}
+	return d, nil
+}
+
+// SetRequestDefaults writes d into instance's metadata, replacing any
+// defaults it had. Unset fields are removed.
+func SetRequestDefaults(instance *ProviderInstance, d RequestDefaults) {
+	if instance.Metadata == nil {
+		instance.Metadata = map[string]string{}
+	}
+	maps.DeleteFunc(instance.Metadata, func(key, _ string) bool {
+		return strings.HasPrefix(key, MetadataHeaderPrefix)
+	})
+	set := func(key, value string) {
+		if value == "" {
+			delete(instance.Metadata, key)
+		} else {
+			instance.Metadata[key] = value
+		}
+	}
+	formatFloat := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*f, 'g', -1, 64)
+	}
+	set(MetadataTemperature, formatFloat(d.Temperature))
+	set(MetadataTopP, formatFloat(d.TopP))
+	maxTokens := ""
+	if d.MaxTokens != nil {
+		maxTokens = strconv.Itoa(*d.MaxTokens)
+	}
+	set(MetadataMaxTokens, maxTokens)
+	set(MetadataProviderOrganization, d.Organization)
+	set(MetadataProviderProject, d.Project)
+	set(MetadataAPIVersion, d.APIVersion)
+	for name, value := range d.Headers {
+		instance.Metadata[MetadataHeaderPrefix+name] = value
+	}
+}
+
+// Validate checks that the parameters are in range and the headers are
+// well-formed. Every problem found is reported, each wrapping
+// ErrInvalidInstance.
+func (d RequestDefaults) Validate() error {
+	var problems []error
+	report := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), ErrInvalidInstance))
+	}
+	if d.Temperature != nil && (*d.Temperature < 0 || *d.Temperature > 2) {
+		report("temperature %g is not between 0 and 2", *d.Temperature)
+	}
+	if d.TopP != nil && (*d.TopP <= 0 || *d.TopP > 1) {
+		report("top_p %g is not above 0 and at most 1", *d.TopP)
+	}
+	if d.MaxTokens != nil && *d.MaxTokens <= 0 {
+		report("max_tokens %d is not positive", *d.MaxTokens)
+	}
+	for _, name := range slices.Sorted(maps.Keys(d.Headers)) {
+		switch {
+		case !validHeaderName(name):
+			report("header name %q is not valid", name)
+		case strings.ContainsAny(d.Headers[name], "\r\n\x00"):
+			report("header %s has an invalid value", name)
+		case strings.EqualFold(name, "Authorization"):
+			report("header %s would override the instance's key", name)
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// validHeaderName reports whether name is an RFC 9110 token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r >= 0x7f || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Header returns the headers the defaults add to each request: Headers
+// plus OpenAI-Organization and OpenAI-Project when set
+func (d RequestDefaults) Header() http.Header {
+	h := http.Header{}
+	for name, value := range d.Headers {
+		h.Set(name, value)
+	}
+	if d.Organization != "" {
+		h.Set("OpenAI-Organization", d.Organization)
+	}
+	if d.Project != "" {
+		h.Set("OpenAI-Project", d.Project)
+	}
+	return h
+}
+
+// ApplyTo sets the temperature, max_tokens and top_p defaults in an
+// OpenAI-style request body, leaving any the body already has
+func (d RequestDefaults) ApplyTo(body map[string]any) {
+	setDefault := func(key string, value any) {
+		if _, ok := body[key]; !ok {
+			body[key] = value
+		}
+	}
+	if d.Temperature != nil {
+		setDefault("temperature", *d.Temperature)
+	}
+	if d.MaxTokens != nil {
+		setDefault("max_tokens", *d.MaxTokens)
+	}
+	if d.TopP != nil {
+		setDefault("top_p", *d.TopP)
+	}
+}
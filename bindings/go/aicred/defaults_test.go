@@ -0,0 +1,69 @@
+package aicred
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequestDefaults(t *testing.T) {
+	temperature, maxTokens := 0.2, 512
+	want := RequestDefaults{
+		Temperature:  &temperature,
+		MaxTokens:    &maxTokens,
+		Headers:      map[string]string{"X-Team": "research"},
+		Organization: "org-1",
+		Project:      "proj-1",
+	}
+	instance := ProviderInstance{ID: "openai-main", Metadata: map[string]string{MetadataTopP: "0.9", "header.X-Old": "1", "env": "prod"}}
+	SetRequestDefaults(&instance, want)
+	if _, ok := instance.Metadata[MetadataTopP]; ok {
+		t.Error("SetRequestDefaults should drop the unset top_p")
+	}
+	if _, ok := instance.Metadata["header.X-Old"]; ok {
+		t.Error("SetRequestDefaults should replace the headers")
+	}
+	if instance.Metadata["env"] != "prod" {
+		t.Error("SetRequestDefaults should keep other metadata")
+	}
+
+	store := NewMemoryStore([]ProviderInstance{instance}, nil)
+	got, err := newInstanceRepository(store).RequestDefaults("openai-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got.Temperature != 0.2 || *got.MaxTokens != 512 || got.TopP != nil || got.Headers["X-Team"] != "research" {
+		t.Errorf("RequestDefaults = %+v", got)
+	}
+	h := got.Header()
+	if h.Get("X-Team") != "research" || h.Get("OpenAI-Organization") != "org-1" || h.Get("OpenAI-Project") != "proj-1" {
+		t.Errorf("Header = %v", h)
+	}
+	body := map[string]any{"model": "gpt-4o", "temperature": 1.0}
+	got.ApplyTo(body)
+	if body["temperature"] != 1.0 || body["max_tokens"] != 512 {
+		t.Errorf("ApplyTo = %v, want the caller's temperature and the default max_tokens", body)
+	}
+}
+
+func TestRequestDefaultsInvalid(t *testing.T) {
+	instance := ProviderInstance{
+		ID:           "openai-main",
+		ProviderType: "openai",
+		BaseURL:      "https://api.openai.com/v1",
+		Metadata: map[string]string{
+			MetadataTemperature:    "3",
+			MetadataMaxTokens:      "many",
+			"header.Authorization": "Bearer x",
+		},
+	}
+	_, err := RequestDefaultsOf(instance)
+	if !errors.Is(err, ErrInvalidInstance) || !strings.Contains(err.Error(), "max_tokens") {
+		t.Errorf("RequestDefaultsOf = %v, want the unparsable max_tokens", err)
+	}
+	delete(instance.Metadata, MetadataMaxTokens)
+	err = ValidateProviderInstance(instance, ValidationOptions{})
+	if !errors.Is(err, ErrInvalidInstance) || !strings.Contains(err.Error(), "temperature") || !strings.Contains(err.Error(), "Authorization") {
+		t.Errorf("ValidateProviderInstance = %v, want the temperature and header problems", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package detect finds provider API keys in in-memory content using pure Go.
+//
+// It mirrors the key prefixes and environment variable names the Rust
+// provider plugins recognize, without the FFI, so it builds for js/wasm and
+// other targets where cgo is unavailable. It only inspects the bytes it is
+// given: there is no file discovery and no application scanners.
+package detect
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Confidence levels, named as the core library reports them
+const (
+	ConfidenceHigh     = "High"
+	ConfidenceVeryHigh = "VeryHigh"
+)
+
+// Match is a key found in content. Value holds the full key and is never
+// marshaled; the other fields are safe to show.
+type Match struct {
+	Provider   string `json:"provider"`
+	Value      string `json:"-"`
+	Confidence string `json:"confidence"`
+	// Line and Column are 1-based and count bytes
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Offset is the byte offset of the value in the content
+	Offset int `json:"offset"`
+	// EnvVar is the variable the value was assigned to, when found by
+	// assignment rather than by prefix
+	EnvVar   string `json:"env_var,omitempty"`
+	Hash     string `json:"hash"`
+	Redacted string `json:"redacted"`
+}
+
+// tokenPattern matches keys by their provider prefix
+var tokenPattern = regexp.MustCompile(`\b(?:sk-|gsk_|hf_)[A-Za-z0-9_\-]{20,}`)
+
+// tokenPrefixes maps key prefixes to providers, most specific first
+var tokenPrefixes = []struct{ prefix, provider string }{
+	{"sk-ant-", "anthropic"},
+	{"sk-or-", "openrouter"},
+	{"gsk_", "groq"},
+	{"hf_", "huggingface"},
+	{"sk-", "openai"},
+}
+
+// envPattern matches KEY=value and KEY: value assignments of known variables
+var envPattern = regexp.MustCompile(`(?i)\b([A-Z_]*(?:API_KEY|HUB_TOKEN|HF_TOKEN))\b\s*[:=]\s*["']?([A-Za-z0-9_\-]{15,})`)
+
+// envProviders maps environment variable names to providers
+var envProviders = map[string]string{
+	"OPENAI_API_KEY":         "openai",
+	"ANTHROPIC_API_KEY":      "anthropic",
+	"GROQ_API_KEY":           "groq",
+	"OPENROUTER_API_KEY":     "openrouter",
+	"HUGGINGFACE_API_KEY":    "huggingface",
+	"HUGGING_FACE_HUB_TOKEN": "huggingface",
+	"HF_TOKEN":               "huggingface",
+}
+
+// Providers returns the provider names Find can report, sorted
+func Providers() []string {
+	seen := map[string]bool{}
+	for _, p := range tokenPrefixes {
+		seen[p.provider] = true
+	}
+	for _, p := range envProviders {
+		seen[p] = true
+	}
+	out := make([]string, 0, len(seen))
+	for p := range seen {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Find returns every key in content, ordered by position. A key that is
+// both prefixed and assigned to a known variable is reported once.
+func Find(content []byte) []Match {
+	var matches []Match
+	taken := map[int]bool{}
+
+	for _, loc := range tokenPattern.FindAllIndex(content, -1) {
+		value := string(content[loc[0]:loc[1]])
+		for _, p := range tokenPrefixes {
+			if strings.HasPrefix(value, p.prefix) {
+				matches = append(matches, newMatch(content, loc[0], p.provider, value, "", ConfidenceVeryHigh))
+				taken[loc[0]] = true
+				break
+			}
+		}
+	}
+
+	for _, loc := range envPattern.FindAllSubmatchIndex(content, -1) {
+		name := strings.ToUpper(string(content[loc[2]:loc[3]]))
+		provider, ok := envProviders[name]
+		if !ok || taken[loc[4]] {
+			continue
+		}
+		matches = append(matches, newMatch(content, loc[4], provider, string(content[loc[4]:loc[5]]), name, ConfidenceHigh))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Line != matches[j].Line {
+			return matches[i].Line < matches[j].Line
+		}
+		return matches[i].Column < matches[j].Column
+	})
+	return matches
+}
+
+func newMatch(content []byte, offset int, provider, value, envVar, confidence string) Match {
+	line := bytes.Count(content[:offset], []byte{'\n'}) + 1
+	column := offset - bytes.LastIndexByte(content[:offset], '\n')
+	sum := sha256.Sum256([]byte(value))
+	return Match{
+		Provider:   provider,
+		Value:      value,
+		Confidence: confidence,
+		Line:       line,
+		Column:     column,
+		Offset:     offset,
+		EnvVar:     envVar,
+		Hash:       hex.EncodeToString(sum[:]),
+		Redacted:   Redact(value),
+	}
+}
+
+// Redact returns the display form the core library uses: the first two
+// characters of short values, otherwise the last four, next to asterisks
+func Redact(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 8 {
+		return string(runes[:min(2, len(runes))]) + "****"
+	}
+	return "****" + string(runes[len(runes)-4:])
+}
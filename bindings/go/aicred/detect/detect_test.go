@@ -0,0 +1,71 @@
+package detect
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	content := strings.Join([]string{
+		`# settings`,
+		`ANTHROPIC_API_KEY=sk-ant-REDACTED`,
+		`groq: "gsk_abcdefghijklmnopqrstuvwxyz"`,
+		`export OPENAI_API_KEY="plainvalue1234567"`,
+		`OTHER_API_KEY=notaprovider1234567`,
+		`{"token": "hf_abcdefghijklmnopqrstuvwx", "or": "sk-or-v1-abcdefghijklmnopqrstu"}`,
+	}, "\n")
+
+	got := Find([]byte(content))
+	want := []struct {
+		provider, confidence string
+		line, column         int
+	}{
+		{"anthropic", ConfidenceVeryHigh, 2, 19},
+		{"groq", ConfidenceVeryHigh, 3, 8},
+		{"openai", ConfidenceHigh, 4, 24},
+		{"huggingface", ConfidenceVeryHigh, 6, 12},
+		{"openrouter", ConfidenceVeryHigh, 6, 49},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d matches, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		m := got[i]
+		if m.Provider != w.provider || m.Confidence != w.confidence || m.Line != w.line || m.Column != w.column {
+			t.Errorf("match %d = %s/%s at %d:%d, want %s/%s at %d:%d",
+				i, m.Provider, m.Confidence, m.Line, m.Column, w.provider, w.confidence, w.line, w.column)
+		}
+	}
+	if got[2].Value != "plainvalue1234567" || got[2].Redacted != "****4567" {
+		t.Errorf("openai match = %q redacted %q", got[2].Value, got[2].Redacted)
+	}
+	if got[2].EnvVar != "OPENAI_API_KEY" || content[got[2].Offset:][:len(got[2].Value)] != got[2].Value {
+		t.Errorf("openai match at offset %d with env var %q", got[2].Offset, got[2].EnvVar)
+	}
+	if got[0].EnvVar != "" {
+		t.Errorf("prefix match has env var %q", got[0].EnvVar)
+	}
+}
+
+func TestMatchJSONOmitsValue(t *testing.T) {
+	matches := Find([]byte("OPENAI_API_KEY=sk-proj-abcdefghijklmnopqrstuvwx"))
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	data, err := json.Marshal(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "abcdefghijkl") {
+		t.Errorf("JSON contains the key: %s", data)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	for in, want := range map[string]string{"": "****", "abc": "ab****", "abcdefghij": "****ghij"} {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -10,7 +10,7 @@ Basic Usage:
 		"fmt"
 		"log"
 
-		aicred "github.com/robottwo/aicred/bindings/go/genai_keyfinder"
+		"github.com/robottwo/aicred/bindings/go/aicred"
 	)
 
 	func main() {
@@ -28,7 +28,10 @@ Basic Usage:
 
 Security:
 
-By default, all secrets are redacted. Only use IncludeFullValues: true in secure environments.
+By default, all secrets are redacted. Only use Redaction: RedactionNone in secure environments.
+
+The older import path github.com/robottwo/aicred/bindings/go/genai_keyfinder
+remains as a deprecated alias of this package.
 
 Supported Providers:
 
@@ -0,0 +1,183 @@
+package aicred
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DriftKind is how an application's configuration departs from a Config
+type DriftKind string
+
+// Drift kinds
+const (
+	// DriftUnmanagedKey is a key that no managed instance holds: one added by
+	// hand, or an old key left behind after rotation
+	DriftUnmanagedKey DriftKind = "unmanaged_key"
+	// DriftStaleEndpoint is a managed key used against a base URL other than
+	// the one its instance declares
+	DriftStaleEndpoint DriftKind = "stale_endpoint"
+)
+
+// Drift is one place where the credentials applications actually use differ
+// from the managed instances
+type Drift struct {
+	Kind DriftKind `json:"kind"`
+	// App is the application whose configuration holds the key, such as
+	// "roo-code"; "" for keys in plain files such as .env
+	App      string `json:"app,omitempty"`
+	Path     string `json:"path"`
+	Provider string `json:"provider"`
+	Redacted string `json:"redacted,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+	// ManagedID is the managed instance the key belongs to, or for an
+	// unmanaged key the only managed instance of its provider, which likely
+	// holds its replacement
+	ManagedID string `json:"managed_id,omitempty"`
+	// Expected and Actual are the declared and configured base URLs of a
+	// stale endpoint
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (d Drift) String() string {
+	where := d.Path
+	if d.App != "" {
+		where = d.App + " (" + d.Path + ")"
+	}
+	switch d.Kind {
+	case DriftStaleEndpoint:
+		return fmt.Sprintf("%s uses %s key %s of %s at %s instead of %s", where, d.Provider, d.Redacted, d.ManagedID, d.Actual, d.Expected)
+	default:
+		msg := fmt.Sprintf("%s uses unmanaged %s key %s", where, d.Provider, d.Redacted)
+		if d.ManagedID != "" {
+			msg += "; " + d.ManagedID + " is managed"
+		}
+		return msg
+	}
+}
+
+// DetectDrift compares the instances config manages with the credentials
+// scan found in application configurations, such as Roo Code's and Claude
+// Desktop's, and in env files. It reports each key, once per file, that no
+// managed instance holds, and each managed key an application uses with a
+// base URL other than its instance's. Keys are matched by hash: config's
+// instances need their keys, as CurrentConfig returns them, and keys the
+// scan redacted fully cannot be matched and are left out.
+func DetectDrift(config *Config, scan *ScanResult) []Drift {
+	managed := map[string]*ProviderInstance{}
+	byProvider := map[string][]string{}
+	for i := range config.Instances {
+		instance := &config.Instances[i]
+		byProvider[strings.ToLower(instance.ProviderType)] = append(byProvider[strings.ToLower(instance.ProviderType)], instance.ID)
+		if hash := secretHash(instance.APIKey); hash != "" {
+			managed[hash] = instance
+		}
+	}
+
+	var drifts []Drift
+	seen := map[string]bool{}
+	check := func(key DiscoveredKey, app, path string, endpoints []ProviderInstance) {
+		hash := key.Hash
+		if hash == "" {
+			hash = secretHash(key.Value)
+		}
+		if hash == "" || seen[hash+"\x00"+path] {
+			return
+		}
+		seen[hash+"\x00"+path] = true
+		d := Drift{App: app, Path: path, Provider: key.Provider, Redacted: key.Redacted, Hash: hash}
+		instance, ok := managed[hash]
+		if !ok {
+			d.Kind = DriftUnmanagedKey
+			if ids := byProvider[strings.ToLower(key.Provider)]; len(ids) == 1 {
+				d.ManagedID = ids[0]
+			}
+			drifts = append(drifts, d)
+			return
+		}
+		// The endpoints the key is used with: those of the same provider in
+		// the same configuration whose key is the same, or has no value
+		var stale *ProviderInstance
+		for i := range endpoints {
+			endpoint := &endpoints[i]
+			if endpoint.BaseURL == "" || !strings.EqualFold(endpoint.ProviderType, instance.ProviderType) {
+				continue
+			}
+			if h := secretHash(endpoint.APIKey); h != "" && h != hash {
+				continue
+			}
+			if sameEndpoint(endpoint.BaseURL, instance.BaseURL) {
+				return
+			}
+			if stale == nil {
+				stale = endpoint
+			}
+		}
+		if stale != nil {
+			d.Kind, d.ManagedID, d.Expected, d.Actual = DriftStaleEndpoint, instance.ID, instance.BaseURL, stale.BaseURL
+			drifts = append(drifts, d)
+		}
+	}
+
+	for _, ci := range scan.ConfigInstances {
+		endpoints := make([]ProviderInstance, 0, len(ci.ProviderInstances))
+		for _, id := range slices.Sorted(maps.Keys(ci.ProviderInstances)) {
+			endpoints = append(endpoints, ci.ProviderInstances[id])
+		}
+		for _, key := range ci.Keys {
+			path := ci.ConfigPath
+			if key.Location != nil && key.Location.Path != "" {
+				path = key.Location.Path
+			}
+			check(key, ci.AppName, cmp.Or(path, key.Source), endpoints)
+		}
+	}
+	for _, key := range scan.Keys {
+		path := key.Source
+		if key.Location != nil && key.Location.Path != "" {
+			path = key.Location.Path
+		}
+		app := ""
+		if key.Attribution != nil {
+			app = key.Attribution.Application
+		}
+		check(key, app, path, nil)
+	}
+
+	slices.SortStableFunc(drifts, func(a, b Drift) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Provider, b.Provider), strings.Compare(a.Hash, b.Hash))
+	})
+	return drifts
+}
+
+// secretHash is the hex SHA-256 of s, as in DiscoveredKey.Hash, or "" for
+// an empty secret
+func secretHash(s Secret) string {
+	if s.IsZero() {
+		return ""
+	}
+	var hash string
+	s.Use(func(b []byte) {
+		sum := sha256.Sum256(b)
+		hash = hex.EncodeToString(sum[:])
+	})
+	return hash
+}
+
+// sameEndpoint reports whether two base URLs name the same endpoint,
+// ignoring the case of the scheme and host and a trailing slash
+func sameEndpoint(a, b string) bool {
+	ua, errA := url.Parse(strings.TrimSpace(a))
+	ub, errB := url.Parse(strings.TrimSpace(b))
+	if errA != nil || errB != nil {
+		return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) &&
+		strings.TrimRight(ua.Path, "/") == strings.TrimRight(ub.Path, "/") && ua.RawQuery == ub.RawQuery
+}
@@ -0,0 +1,63 @@
+package aicred
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	config := &Config{Instances: []ProviderInstance{
+		{ID: "openai-prod", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", APIKey: NewSecretString("sk-proj-managed")},
+		{ID: "anthropic-prod", ProviderType: "anthropic", BaseURL: "https://api.anthropic.com", APIKey: NewSecretString("sk-ant-managed")},
+	}}
+	key := func(provider, value, source string) DiscoveredKey {
+		return DiscoveredKey{Provider: provider, Source: source, Hash: secretHash(NewSecretString(value)), Redacted: value[:4] + "..."}
+	}
+	scan := &ScanResult{
+		Keys: []DiscoveredKey{
+			key("openai", "sk-proj-old", "/home/u/project/.env"),
+			key("openai", "sk-proj-managed", "/home/u/other/.env"),
+			{Provider: "groq", Source: "/home/u/.env", Redacted: "gsk_..."},
+		},
+		ConfigInstances: []ConfigInstance{
+			{
+				AppName:    "roo-code",
+				ConfigPath: "/home/u/.vscode/roo.json",
+				Keys:       []DiscoveredKey{key("openai", "sk-proj-managed", "/home/u/.vscode/roo.json")},
+				ProviderInstances: map[string]ProviderInstance{
+					"openai": {ID: "openai", ProviderType: "openai", BaseURL: "https://proxy.internal/v1"},
+				},
+			},
+			{
+				AppName:    "claude-desktop",
+				ConfigPath: "/home/u/.config/Claude/config.json",
+				Keys:       []DiscoveredKey{key("anthropic", "sk-ant-managed", "/home/u/.config/Claude/config.json")},
+				ProviderInstances: map[string]ProviderInstance{
+					"anthropic": {ID: "anthropic", ProviderType: "anthropic", BaseURL: "https://API.anthropic.com/"},
+				},
+			},
+		},
+	}
+
+	drifts := DetectDrift(config, scan)
+	if len(drifts) != 2 {
+		t.Fatalf("drifts = %+v", drifts)
+	}
+	unmanaged, stale := drifts[1], drifts[0]
+	if unmanaged.Kind != DriftUnmanagedKey || unmanaged.Path != "/home/u/project/.env" || unmanaged.ManagedID != "openai-prod" {
+		t.Errorf("unmanaged = %+v", unmanaged)
+	}
+	if stale.Kind != DriftStaleEndpoint || stale.App != "roo-code" || stale.ManagedID != "openai-prod" ||
+		stale.Expected != "https://api.openai.com/v1" || stale.Actual != "https://proxy.internal/v1" {
+		t.Errorf("stale = %+v", stale)
+	}
+	if got := stale.String(); got != "roo-code (/home/u/.vscode/roo.json) uses openai key sk-p... of openai-prod at https://proxy.internal/v1 instead of https://api.openai.com/v1" {
+		t.Errorf("String() = %q", got)
+	}
+
+	data, _ := json.Marshal(drifts)
+	if strings.Contains(string(data), "sk-proj-") {
+		t.Errorf("drift holds a key: %s", data)
+	}
+}
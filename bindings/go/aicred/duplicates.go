@@ -0,0 +1,77 @@
+package aicred
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// DuplicateKey is one API key configured under several provider instances,
+// which muddles billing and lets a rotation miss some of its copies
+type DuplicateKey struct {
+	// Hash is the hex SHA-256 of the key, as in DiscoveredKey.Hash, so the
+	// duplicate can be matched against scan results
+	Hash string `json:"hash"`
+	// PrefixClass is the key's fixed prefix, such as "sk-proj-"; see
+	// KeyFingerprint
+	PrefixClass string `json:"prefix_class,omitempty"`
+	// InstanceIDs are the instances sharing the key, sorted
+	InstanceIDs []string `json:"instance_ids"`
+	// ProviderTypes are the distinct provider types of those instances,
+	// sorted; more than one usually means a key was pasted into the wrong
+	// instance
+	ProviderTypes []string `json:"provider_types"`
+}
+
+// FindDuplicateKeys reports the API keys configured under more than one of
+// instances, ordered by their first instance ID. Instances without a key
+// are ignored.
+func FindDuplicateKeys(instances []ProviderInstance) []DuplicateKey {
+	byHash := map[string]*DuplicateKey{}
+	var order []string
+	for _, instance := range instances {
+		if instance.APIKey.IsZero() {
+			continue
+		}
+		var hash, prefix string
+		instance.APIKey.Use(func(key []byte) {
+			sum := sha256.Sum256(key)
+			hash = hex.EncodeToString(sum[:])
+			prefix = prefixClass(string(key[:min(len(key), 16)]))
+		})
+		dup, seen := byHash[hash]
+		if !seen {
+			dup = &DuplicateKey{Hash: hash, PrefixClass: prefix}
+			byHash[hash] = dup
+			order = append(order, hash)
+		}
+		dup.InstanceIDs = append(dup.InstanceIDs, instance.ID)
+		if !slices.Contains(dup.ProviderTypes, instance.ProviderType) {
+			dup.ProviderTypes = append(dup.ProviderTypes, instance.ProviderType)
+		}
+	}
+
+	var duplicates []DuplicateKey
+	for _, hash := range order {
+		dup := byHash[hash]
+		if len(dup.InstanceIDs) < 2 {
+			continue
+		}
+		slices.Sort(dup.InstanceIDs)
+		slices.Sort(dup.ProviderTypes)
+		duplicates = append(duplicates, *dup)
+	}
+	slices.SortFunc(duplicates, func(a, b DuplicateKey) int { return strings.Compare(a.InstanceIDs[0], b.InstanceIDs[0]) })
+	return duplicates
+}
+
+// FindDuplicateKeys reports the API keys configured under more than one
+// instance in the Client's store; see FindDuplicateKeys
+func (c *Client) FindDuplicateKeys() ([]DuplicateKey, error) {
+	instances, err := c.store.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	return FindDuplicateKeys(instances), nil
+}
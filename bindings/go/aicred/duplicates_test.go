@@ -0,0 +1,42 @@
+package aicred
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"testing"
+)
+
+func TestFindDuplicateKeys(t *testing.T) {
+	shared := "sk-proj-abcdefghijklmnopqrstuvwxyz"
+	instances := []ProviderInstance{
+		{ID: "openai-prod", ProviderType: "openai", APIKey: NewSecretString(shared)},
+		{ID: "openai-dev", ProviderType: "openai", APIKey: NewSecretString(shared)},
+		{ID: "router", ProviderType: "openrouter", APIKey: NewSecretString(shared)},
+		{ID: "anthropic", ProviderType: "anthropic", APIKey: NewSecretString("sk-ant-unique")},
+		{ID: "ollama-a", ProviderType: "ollama"},
+		{ID: "ollama-b", ProviderType: "ollama"},
+	}
+	dups := FindDuplicateKeys(instances)
+	if len(dups) != 1 {
+		t.Fatalf("FindDuplicateKeys = %+v, want one duplicate", dups)
+	}
+	dup := dups[0]
+	if !slices.Equal(dup.InstanceIDs, []string{"openai-dev", "openai-prod", "router"}) ||
+		!slices.Equal(dup.ProviderTypes, []string{"openai", "openrouter"}) ||
+		dup.PrefixClass != "sk-proj-" {
+		t.Errorf("duplicate = %+v", dup)
+	}
+	if sum := sha256.Sum256([]byte(shared)); dup.Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Hash = %s, want the key's SHA-256", dup.Hash)
+	}
+
+	c, err := New(WithHomeDir(t.TempDir()), WithStore(NewMemoryStore(instances, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if dups, err := c.FindDuplicateKeys(); err != nil || len(dups) != 1 {
+		t.Errorf("Client.FindDuplicateKeys = %+v, %v", dups, err)
+	}
+}
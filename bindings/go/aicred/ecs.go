@@ -0,0 +1,258 @@
+package aicred
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ECSVersion is the Elastic Common Schema version ToECS documents follow
+const ECSVersion = "8.11.0"
+
+// ECSDocument is a finding in the Elastic Common Schema, to be indexed into
+// Elasticsearch as is. Fields outside ECS are under aicred.
+type ECSDocument struct {
+	Timestamp time.Time         `json:"@timestamp"`
+	Message   string            `json:"message"`
+	ECS       ECSVersionField   `json:"ecs"`
+	Event     ECSEvent          `json:"event"`
+	File      *ECSFile          `json:"file,omitempty"`
+	Host      ECSHost           `json:"host"`
+	User      *ECSUser          `json:"user,omitempty"`
+	Rule      ECSRule           `json:"rule"`
+	Related   ECSRelated        `json:"related"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Finding   ECSFinding        `json:"aicred"`
+}
+
+// ECSVersionField is the ecs field set
+type ECSVersionField struct {
+	Version string `json:"version"`
+}
+
+// ECSEvent is the event field set. ID is stable across scans for the same
+// key in the same place, so reindexing a finding overwrites it.
+type ECSEvent struct {
+	ID       string   `json:"id"`
+	Kind     string   `json:"kind"`
+	Category []string `json:"category"`
+	Type     []string `json:"type"`
+	Action   string   `json:"action"`
+	Module   string   `json:"module"`
+	Dataset  string   `json:"dataset"`
+	// Severity is 21, 47, 73 or 99 for low, medium, high and critical
+	// findings, Elastic's risk score bands
+	Severity int `json:"severity"`
+}
+
+// ECSFile is the file field set
+type ECSFile struct {
+	Path      string `json:"path"`
+	Name      string `json:"name,omitempty"`
+	Directory string `json:"directory,omitempty"`
+}
+
+// ECSHost is the host field set
+type ECSHost struct {
+	Name string `json:"name,omitempty"`
+}
+
+// ECSUser is the user field set: the owner of the file, when the scan
+// attributed keys
+type ECSUser struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// ECSRule is the rule field set: the detector that found the key
+type ECSRule struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Ruleset  string `json:"ruleset"`
+}
+
+// ECSRelated is the related field set, for pivoting across documents
+type ECSRelated struct {
+	Hash  []string `json:"hash,omitempty"`
+	User  []string `json:"user,omitempty"`
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// ECSFinding holds what ECS has no field for. It never holds the key value.
+type ECSFinding struct {
+	Provider   string `json:"provider"`
+	Redacted   string `json:"redacted,omitempty"`
+	Confidence string `json:"confidence,omitempty"`
+	ValueType  string `json:"value_type,omitempty"`
+	Source     string `json:"source"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+	EnvVar     string `json:"env_var,omitempty"`
+	// App and InstanceID identify the config instance the key was found
+	// in, if any
+	App        string `json:"app,omitempty"`
+	InstanceID string `json:"instance_id,omitempty"`
+	GitRemote  string `json:"git_remote,omitempty"`
+}
+
+// ToECS maps the keys in result, including those in config instances, to
+// ECS documents with event.kind alert and event.category iam, one per key
+// and file. @timestamp is the scan's start. host.name is the scanned host,
+// or this machine for local scans.
+func ToECS(result *ScanResult) []ECSDocument {
+	timestamp, err := time.Parse(time.RFC3339, result.ScannedAt)
+	if err != nil {
+		timestamp = time.Now()
+	}
+	host := result.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	var docs []ECSDocument
+	seen := map[string]int{}
+	add := func(key DiscoveredKey, instance *ConfigInstance) {
+		path := key.Source
+		if key.Location != nil && key.Location.Path != "" && !strings.Contains(key.Source, "!") {
+			path = key.Location.Path
+		}
+		id := key.Hash + "\x00" + path
+		if i, ok := seen[id]; ok && key.Hash != "" {
+			// The same key in the same file, found again as part of a
+			// config instance
+			if instance != nil && docs[i].Finding.InstanceID == "" {
+				docs[i].Finding.App, docs[i].Finding.InstanceID = instance.AppName, instance.InstanceID
+			}
+			return
+		}
+		seen[id] = len(docs)
+		sum := sha256.Sum256([]byte(strings.Join([]string{host, key.Provider, path, key.Hash}, "\x00")))
+		doc := ECSDocument{
+			Timestamp: timestamp.UTC(),
+			Message:   ecsMessage(key, path),
+			ECS:       ECSVersionField{Version: ECSVersion},
+			Event: ECSEvent{
+				ID:       hex.EncodeToString(sum[:16]),
+				Kind:     "alert",
+				Category: []string{"iam"},
+				Type:     []string{"info"},
+				Action:   "credential-exposed",
+				Module:   "aicred",
+				Dataset:  "aicred.finding",
+				Severity: ecsSeverity(key),
+			},
+			Host: ECSHost{Name: host},
+			Rule: ECSRule{Name: key.Provider + " credential", Category: "credential exposure", Ruleset: "aicred"},
+			Finding: ECSFinding{
+				Provider:   key.Provider,
+				Redacted:   key.Redacted,
+				Confidence: key.Confidence,
+				ValueType:  key.ValueType,
+				Source:     key.Source,
+			},
+		}
+		// Archive entries such as "backup.zip!.env" are not files on disk
+		if outer, _, _ := strings.Cut(path, "!"); outer != "" {
+			doc.File = &ECSFile{Path: outer, Name: filepath.Base(outer), Directory: filepath.Dir(outer)}
+		}
+		if l := key.Location; l != nil {
+			doc.Finding.Line, doc.Finding.Column, doc.Finding.EnvVar = l.Line, l.Column, l.EnvVar
+		}
+		if instance != nil {
+			doc.Finding.App, doc.Finding.InstanceID = instance.AppName, instance.InstanceID
+		}
+		if key.Hash != "" {
+			doc.Related.Hash = []string{key.Hash}
+		}
+		if host != "" {
+			doc.Related.Hosts = []string{host}
+		}
+		if a := key.Attribution; a != nil {
+			if a.User != "" || a.UID != "" {
+				doc.User = &ECSUser{ID: a.UID, Name: a.User}
+			}
+			if a.User != "" {
+				doc.Related.User = []string{a.User}
+			}
+			if doc.Finding.App == "" {
+				doc.Finding.App = a.Application
+			}
+			doc.Finding.GitRemote = a.GitRemote
+		}
+		if key.Scope != nil {
+			doc.Labels = map[string]string{}
+			for name, value := range map[string]string{
+				"provider_organization": key.Scope.Organization,
+				"provider_project":      key.Scope.Project,
+				"provider_workspace":    key.Scope.Workspace,
+				"key_type":              key.Scope.KeyType,
+			} {
+				if value != "" {
+					doc.Labels[name] = value
+				}
+			}
+		}
+		docs = append(docs, doc)
+	}
+	for _, key := range result.Keys {
+		add(key, nil)
+	}
+	for i := range result.ConfigInstances {
+		for _, key := range result.ConfigInstances[i].Keys {
+			add(key, &result.ConfigInstances[i])
+		}
+	}
+	return docs
+}
+
+// WriteECSBulk writes ToECS's documents as an Elasticsearch _bulk request
+// body for index, each under its event.id so that indexing the same scan
+// twice does not duplicate findings
+func WriteECSBulk(w io.Writer, result *ScanResult, index string) error {
+	enc := json.NewEncoder(w)
+	for _, doc := range ToECS(result) {
+		action := map[string]map[string]string{"index": {"_index": index, "_id": doc.Event.ID}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ecsMessage(key DiscoveredKey, path string) string {
+	msg := fmt.Sprintf("%s credential %s found in %s", key.Provider, key.Redacted, path)
+	if key.Location != nil && key.Location.EnvVar != "" {
+		msg += " as " + key.Location.EnvVar
+	}
+	return msg
+}
+
+// ecsSeverity ranks a key as the CI formats do, in Elastic's risk score
+// bands
+func ecsSeverity(key DiscoveredKey) int {
+	switch {
+	case key.HFToken.CanWrite():
+		return 99
+	case key.HFToken != nil && key.HFToken.Rejected:
+		return 21
+	}
+	switch key.Confidence {
+	case "Low":
+		return 21
+	case "Medium":
+		return 47
+	case "VeryHigh":
+		return 99
+	default:
+		return 73
+	}
+}
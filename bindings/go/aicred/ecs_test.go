@@ -0,0 +1,85 @@
+package aicred
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToECS(t *testing.T) {
+	result := &ScanResult{
+		Host:      "ws01",
+		ScannedAt: "2024-05-01T10:00:00Z",
+		Keys: []DiscoveredKey{
+			{Provider: "openai", Source: "/home/u/.env", Confidence: "VeryHigh", Hash: "h1", Redacted: "sk-p...aaaa", Value: NewSecretString("sk-proj-secret"),
+				Location: &Location{Path: "/home/u/.env", Line: 2, EnvVar: "OPENAI_API_KEY"}, Attribution: &Attribution{UID: "1000", User: "u"}},
+			{Provider: "groq", Source: "/srv/backup.zip!.env", Confidence: "Low", Hash: "h2", Redacted: "gsk_...bbbb"},
+		},
+		ConfigInstances: []ConfigInstance{{
+			InstanceID: "roo-1",
+			AppName:    "roo-code",
+			Keys:       []DiscoveredKey{{Provider: "openai", Source: "/home/u/.env", Confidence: "VeryHigh", Hash: "h1", Redacted: "sk-p...aaaa"}},
+		}},
+	}
+	docs := ToECS(result)
+	if len(docs) != 2 {
+		t.Fatalf("docs = %+v", docs)
+	}
+	doc := docs[0]
+	if doc.Event.Kind != "alert" || doc.Event.Category[0] != "iam" || doc.Event.Severity != 99 || doc.Timestamp.Year() != 2024 {
+		t.Errorf("event = %+v at %v", doc.Event, doc.Timestamp)
+	}
+	if doc.Finding.App != "roo-code" || doc.Finding.InstanceID != "roo-1" || doc.File.Name != ".env" || doc.Host.Name != "ws01" {
+		t.Errorf("doc = %+v", doc)
+	}
+	if archived := docs[1]; archived.File.Path != "/srv/backup.zip" || archived.Event.Severity != 21 || archived.User != nil {
+		t.Errorf("archived doc = %+v", archived)
+	}
+	if again := ToECS(result); again[0].Event.ID != doc.Event.ID || doc.Event.ID == docs[1].Event.ID {
+		t.Error("event IDs are not stable and distinct")
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	json.Unmarshal(data, &fields)
+	for _, name := range []string{"@timestamp", "ecs", "event", "file", "host", "related", "aicred"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("document lacks %s: %s", name, data)
+		}
+	}
+	if related := fields["related"].(map[string]any); related["hash"].([]any)[0] != "h1" || related["user"].([]any)[0] != "u" {
+		t.Errorf("related = %v", related)
+	}
+	if strings.Contains(string(data), "sk-proj-secret") {
+		t.Error("document holds a key value")
+	}
+}
+
+func TestWriteECSBulk(t *testing.T) {
+	result := &ScanResult{Keys: []DiscoveredKey{{Provider: "openai", Source: "/a/.env", Hash: "h1"}}}
+	var buf bytes.Buffer
+	if err := WriteECSBulk(&buf, result, "aicred-findings"); err != nil {
+		t.Fatal(err)
+	}
+	lines := bufio.NewScanner(&buf)
+	var got []map[string]any
+	for lines.Scan() {
+		var v map[string]any
+		if err := json.Unmarshal(lines.Bytes(), &v); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if len(got) != 2 {
+		t.Fatalf("lines = %v", got)
+	}
+	action := got[0]["index"].(map[string]any)
+	if action["_index"] != "aicred-findings" || action["_id"] != got[1]["event"].(map[string]any)["id"] {
+		t.Errorf("action = %v", action)
+	}
+}
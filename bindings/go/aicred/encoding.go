@@ -0,0 +1,45 @@
+package aicred
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Encoding selects how results cross the FFI boundary
+type Encoding int
+
+const (
+	// EncodingJSON is the default, readable encoding
+	EncodingJSON Encoding = iota
+	// EncodingCBOR is a compact binary encoding (RFC 8949) that decodes
+	// faster for large scan results
+	EncodingCBOR
+)
+
+// String returns the name of the encoding
+func (e Encoding) String() string {
+	switch e {
+	case EncodingJSON:
+		return "json"
+	case EncodingCBOR:
+		return "cbor"
+	default:
+		return fmt.Sprintf("encoding(%d)", int(e))
+	}
+}
+
+func (e Encoding) unmarshal(data []byte, v any) error {
+	if e == EncodingCBOR {
+		return cbor.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// cborEnvelope mirrors envelope for CBOR-encoded responses
+type cborEnvelope struct {
+	OK     bool            `cbor:"ok"`
+	Result cbor.RawMessage `cbor:"result"`
+	Error  *envelopeError  `cbor:"error"`
+}
@@ -0,0 +1,107 @@
+package aicred
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// coreResult builds a scan result shaped like the core library's output,
+// with n findings
+func coreResult(n int) map[string]any {
+	keys := make([]any, n)
+	for i := range keys {
+		keys[i] = map[string]any{
+			"provider":      "openai",
+			"value":         map[string]any{"Full": fmt.Sprintf("sk-proj-%040d", i)},
+			"confidence":    "High",
+			"hash":          fmt.Sprintf("%064x", i),
+			"source_file":   fmt.Sprintf("/home/u/project-%d/.env", i),
+			"source_line":   i,
+			"value_type":    "ApiKey",
+			"discovered_at": "2025-01-01T00:00:00Z",
+		}
+	}
+	return map[string]any{
+		"keys":              keys,
+		"config_instances":  []any{},
+		"home_directory":    "/home/u",
+		"scan_started_at":   "2025-01-01T00:00:00Z",
+		"providers_scanned": []any{"openai"},
+	}
+}
+
+func TestEncodingsDecodeAlike(t *testing.T) {
+	raw := coreResult(3)
+	jsonData, _ := json.Marshal(raw)
+	cborData, _ := cbor.Marshal(raw)
+
+	var fromJSON, fromCBOR ScanResult
+	if err := EncodingJSON.unmarshal(jsonData, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodingCBOR.unmarshal(cborData, &fromCBOR); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromCBOR.Keys) != 3 || fromCBOR.HomeDir != fromJSON.HomeDir {
+		t.Fatalf("CBOR decode differs: %+v", fromCBOR)
+	}
+	for i := range fromJSON.Keys {
+		if !fromJSON.Keys[i].Value.Equal(fromCBOR.Keys[i].Value) || fromJSON.Keys[i].Hash != fromCBOR.Keys[i].Hash {
+			t.Errorf("key %d differs between encodings", i)
+		}
+	}
+
+	out, err := cbor.Marshal(fromCBOR.Keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back map[string]any
+	if err := cbor.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back["value"] != nil {
+		t.Errorf("Secret should marshal to CBOR null, got %v", back["value"])
+	}
+}
+
+func benchmarkDecode(b *testing.B, enc Encoding) {
+	raw := coreResult(5000)
+	var data []byte
+	if enc == EncodingCBOR {
+		data, _ = cbor.Marshal(raw)
+	} else {
+		data, _ = json.Marshal(raw)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result ScanResult
+		if err := enc.unmarshal(data, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeScanResultJSON(b *testing.B) { benchmarkDecode(b, EncodingJSON) }
+func BenchmarkDecodeScanResultCBOR(b *testing.B) { benchmarkDecode(b, EncodingCBOR) }
+
+func FuzzDecodeEnvelope(f *testing.F) {
+	f.Add([]byte(`{"ok":true,"result":[{"id":"a","api_key":"sk-x","models":["m"],"metadata":{"k":"v"}}]}`), false)
+	f.Add([]byte(`{"ok":false,"error":{"code":2,"message":"missing"}}`), false)
+	if data, err := cbor.Marshal(map[string]any{"ok": true, "result": coreResult(2)}); err == nil {
+		f.Add(data, true)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, useCBOR bool) {
+		enc := EncodingJSON
+		if useCBOR {
+			enc = EncodingCBOR
+		}
+		var instances []ProviderInstance
+		decodeEnvelopeBytes("fuzz", data, enc, &instances)
+		var result ScanResult
+		decodeEnvelopeBytes("fuzz", data, enc, &result)
+	})
+}
@@ -0,0 +1,76 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors for use with errors.Is. Errors returned by this package
+// wrap one of these when the cause is known.
+var (
+	ErrNotFound         = errors.New("aicred: not found")
+	ErrParse            = errors.New("aicred: parse error")
+	ErrIO               = errors.New("aicred: I/O error")
+	ErrPermissionDenied = errors.New("aicred: permission denied")
+	// ErrTimeout is returned when a scan outlives ScanOptions.GlobalTimeout
+	ErrTimeout = errors.New("aicred: timed out")
+	// ErrBadSignature is returned when a result's signature is missing or
+	// does not verify
+	ErrBadSignature = errors.New("aicred: bad signature")
+	// ErrInvalidOption is returned when an option is given a value it cannot
+	// take
+	ErrInvalidOption = errors.New("aicred: invalid option")
+	// ErrBadPassphrase is returned when a backup does not decrypt with the
+	// passphrase given, or has been altered
+	ErrBadPassphrase = errors.New("aicred: wrong passphrase or damaged backup")
+	// ErrThrottled is returned when every instance a label applies to has
+	// run out of requests or tokens
+	ErrThrottled = errors.New("aicred: rate limited")
+	// ErrAmbiguous is returned when a name or hint matches more than one
+	// thing and acting on any of them could be wrong
+	ErrAmbiguous = errors.New("aicred: ambiguous")
+	// ErrLeaseExpired is returned by a leased Credential once its lease
+	// has ended and its key has been wiped
+	ErrLeaseExpired = errors.New("aicred: lease expired")
+)
+
+// ErrorCode is a structured error code reported by the FFI layer
+type ErrorCode int
+
+// Error codes, matching the AICRED_ERROR_* constants in genai_keyfinder.h
+const (
+	CodeOK               ErrorCode = 0
+	CodeUnknown          ErrorCode = 1
+	CodeNotFound         ErrorCode = 2
+	CodeParse            ErrorCode = 3
+	CodeIO               ErrorCode = 4
+	CodePermissionDenied ErrorCode = 5
+)
+
+// Error is a failure reported by the core library through the FFI
+type Error struct {
+	// Op is the operation that failed, e.g. "scan"
+	Op      string
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("FFI %s failed: %s", e.Op, e.Message)
+}
+
+// Unwrap returns the sentinel error for the code, so errors.Is works
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case CodeNotFound:
+		return ErrNotFound
+	case CodeParse:
+		return ErrParse
+	case CodeIO:
+		return ErrIO
+	case CodePermissionDenied:
+		return ErrPermissionDenied
+	default:
+		return nil
+	}
+}
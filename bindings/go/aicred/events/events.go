@@ -0,0 +1,168 @@
+// Package events is a small publish/subscribe bus that lets reactive
+// features (watchers, notifiers, exporters) hear about store mutations, scan
+// completions, validation failures and health-check changes without the
+// code that causes them knowing who is listening.
+//
+//	bus := events.New()
+//	defer events.Subscribe(bus, func(e events.ScanCompleted) {
+//		log.Printf("scan of %s found %d keys", e.HomeDir, e.Keys)
+//	})()
+//	s, err := aicred.OpenSessionWith("", aicred.SessionOptions{Events: bus})
+//
+// Handlers run synchronously on the publishing goroutine, in the order they
+// subscribed, so a slow handler should hand work off to its own goroutine.
+// A nil *Bus is valid and drops every event.
+package events
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Topic names a kind of event
+type Topic string
+
+// Topics published by the aicred packages
+const (
+	TopicStoreChanged     Topic = "store.changed"
+	TopicScanCompleted    Topic = "scan.completed"
+	TopicValidationFailed Topic = "validation.failed"
+	TopicHealthChanged    Topic = "health.changed"
+)
+
+// Event is implemented by every event type
+type Event interface {
+	Topic() Topic
+}
+
+// StoreChanged is published after a store is written
+type StoreChanged struct {
+	Time time.Time
+	// Store is the store written, e.g. "labels"
+	Store string
+	// Count is the number of records the store now holds
+	Count int
+}
+
+// ScanCompleted is published when a scan finishes, successfully or not
+type ScanCompleted struct {
+	Time            time.Time
+	HomeDir         string
+	Keys            int
+	ConfigInstances int
+	Duration        time.Duration
+	// Err is the scan's error, nil on success
+	Err error
+}
+
+// ValidationFailed is published when a check finds problems
+type ValidationFailed struct {
+	Time time.Time
+	// Subject is what was checked, e.g. "labels"
+	Subject  string
+	Problems []string
+}
+
+// HealthChanged is published when a health check's outcome for a target
+// changes
+type HealthChanged struct {
+	Time time.Time
+	// Target identifies what was checked, e.g. a provider instance ID
+	Target  string
+	Healthy bool
+	Message string
+}
+
+// Topic implements Event
+func (StoreChanged) Topic() Topic { return TopicStoreChanged }
+
+// Topic implements Event
+func (ScanCompleted) Topic() Topic { return TopicScanCompleted }
+
+// Topic implements Event
+func (ValidationFailed) Topic() Topic { return TopicValidationFailed }
+
+// Topic implements Event
+func (HealthChanged) Topic() Topic { return TopicHealthChanged }
+
+// Bus delivers published events to subscribers. The zero value is an empty
+// bus ready to use. A Bus is safe for concurrent use.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []*subscription
+	closed bool
+}
+
+type subscription struct {
+	topics  []Topic
+	handler func(Event)
+}
+
+// New returns an empty bus
+func New() *Bus {
+	return &Bus{}
+}
+
+// SubscribeAll calls handler for every event on the given topics, or on
+// every topic when none are given. It returns a function that removes the
+// subscription.
+func (b *Bus) SubscribeAll(handler func(Event), topics ...Topic) (unsubscribe func()) {
+	if b == nil {
+		return func() {}
+	}
+	sub := &subscription{topics: topics, handler: handler}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return func() {}
+	}
+	b.subs = append(b.subs, sub)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.subs = slices.DeleteFunc(b.subs, func(s *subscription) bool { return s == sub })
+	}
+}
+
+// Subscribe calls handler for every event of type T
+func Subscribe[T Event](b *Bus, handler func(T)) (unsubscribe func()) {
+	var topics []Topic
+	// The zero value is nil when T is an interface, which matches any topic
+	if zero, ok := any(*new(T)).(Event); ok {
+		topics = []Topic{zero.Topic()}
+	}
+	return b.SubscribeAll(func(e Event) {
+		if typed, ok := e.(T); ok {
+			handler(typed)
+		}
+	}, topics...)
+}
+
+// Publish delivers event to the current subscribers of its topic
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := slices.Clone(b.subs)
+	b.mu.RUnlock()
+	topic := event.Topic()
+	for _, sub := range subs {
+		if len(sub.topics) == 0 || slices.Contains(sub.topics, topic) {
+			sub.handler(event)
+		}
+	}
+}
+
+// Close removes every subscription; later subscriptions are ignored and
+// later events dropped
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = nil
+	b.closed = true
+}
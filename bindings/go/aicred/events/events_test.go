@@ -0,0 +1,50 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestSubscribeByType(t *testing.T) {
+	bus := New()
+	var scans []ScanCompleted
+	var all []Topic
+	stopScans := Subscribe(bus, func(e ScanCompleted) { scans = append(scans, e) })
+	Subscribe(bus, func(e Event) { all = append(all, e.Topic()) })
+	var health []Event
+	bus.SubscribeAll(func(e Event) { health = append(health, e) }, TopicHealthChanged)
+
+	bus.Publish(ScanCompleted{Keys: 3})
+	bus.Publish(StoreChanged{Store: "labels"})
+	bus.Publish(HealthChanged{Target: "openai-main", Healthy: false})
+
+	if len(scans) != 1 || scans[0].Keys != 3 {
+		t.Errorf("typed subscriber got %+v", scans)
+	}
+	if want := []Topic{TopicScanCompleted, TopicStoreChanged, TopicHealthChanged}; len(all) != 3 || all[0] != want[0] || all[2] != want[2] {
+		t.Errorf("Event subscriber got %v, want %v", all, want)
+	}
+	if len(health) != 1 || health[0].(HealthChanged).Target != "openai-main" {
+		t.Errorf("topic subscriber got %+v", health)
+	}
+
+	stopScans()
+	bus.Publish(ScanCompleted{})
+	if len(scans) != 1 {
+		t.Error("unsubscribed handler still called")
+	}
+
+	bus.Close()
+	bus.Publish(StoreChanged{})
+	Subscribe(bus, func(StoreChanged) { t.Error("subscribed after Close") })
+	bus.Publish(StoreChanged{})
+	if len(all) != 4 {
+		t.Errorf("events delivered after Close: %v", all)
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(StoreChanged{})
+	Subscribe(bus, func(StoreChanged) {})()
+	bus.Close()
+}
@@ -0,0 +1,145 @@
+package aicred
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// KeyFingerprint identifies a credential without revealing it. Provider,
+// Length and PrefixClass describe the kind of key, which survives rotation,
+// and HMAC identifies the key itself, which does not.
+type KeyFingerprint struct {
+	Provider string `json:"provider"`
+	// Length is the key's length in bytes; 0 when the value was not
+	// available
+	Length int `json:"length,omitempty"`
+	// PrefixClass is the key's fixed prefix, such as "sk-proj-" or "gsk_";
+	// "" when it has none or the value was not available
+	PrefixClass string `json:"prefix_class,omitempty"`
+	// HMAC is the hex HMAC-SHA256 of the key's SHA-256 hash under the
+	// fingerprint secret, so fingerprints shared outside the organization
+	// cannot be matched against known keys
+	HMAC string `json:"hmac"`
+}
+
+// prefixClasses are the key prefixes recognized by PrefixClass, most
+// specific first
+var prefixClasses = []string{
+	"sk-ant-api03-", "sk-ant-admin01-", "sk-ant-",
+	"sk-or-v1-", "sk-or-",
+	"sk-proj-", "sk-svcacct-", "sk-admin-", "sk-",
+	"gsk_", "hf_", "xai-", "pplx-", "AIza",
+}
+
+// Fingerprint returns the key's fingerprint under secret. The HMAC is
+// derived from Hash, so it is the same whether or not the value was
+// redacted; Length and PrefixClass need the value.
+func (k DiscoveredKey) Fingerprint(secret []byte) KeyFingerprint {
+	f := KeyFingerprint{Provider: strings.ToLower(k.Provider)}
+	hash := k.Hash
+	if !k.Value.IsZero() {
+		value := k.Value.Reveal()
+		f.Length = len(value)
+		f.PrefixClass = prefixClass(value)
+		if hash == "" {
+			sum := sha256.Sum256([]byte(value))
+			hash = hex.EncodeToString(sum[:])
+		}
+	}
+	if hash != "" {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(hash))
+		f.HMAC = hex.EncodeToString(mac.Sum(nil))
+	}
+	return f
+}
+
+// SameKind reports whether two fingerprints describe the same kind of key,
+// treating unknown lengths and prefixes as matching
+func (f KeyFingerprint) SameKind(other KeyFingerprint) bool {
+	if f.Provider != other.Provider {
+		return false
+	}
+	if f.Length != 0 && other.Length != 0 && f.Length != other.Length {
+		return false
+	}
+	return f.PrefixClass == "" || other.PrefixClass == "" || f.PrefixClass == other.PrefixClass
+}
+
+func prefixClass(value string) string {
+	for _, p := range prefixClasses {
+		if strings.HasPrefix(value, p) {
+			return p
+		}
+	}
+	// Unknown keys often still start with a short lowercase tag and a
+	// separator, e.g. "pk-" or "key_"
+	if i := strings.IndexAny(value, "-_"); i > 0 && i <= 6 && strings.IndexFunc(value[:i], func(r rune) bool { return !unicode.IsLower(r) }) < 0 {
+		return value[:i+1]
+	}
+	return ""
+}
+
+// SameCredential reports whether a and b are the same secret, wherever each
+// was found. Keys are compared by value when both have one, otherwise by
+// hash; keys with neither never match.
+func SameCredential(a, b DiscoveredKey) bool {
+	if !a.Value.IsZero() && !b.Value.IsZero() {
+		return subtle.ConstantTimeCompare([]byte(a.Value.Reveal()), []byte(b.Value.Reveal())) == 1
+	}
+	return a.Hash != "" && a.Hash == b.Hash
+}
+
+// SameSlot reports whether a and b were found in the same place for the same
+// provider: the same source and, when both record one, the same variable
+// or JSON pointer
+func SameSlot(a, b DiscoveredKey) bool {
+	if !strings.EqualFold(a.Provider, b.Provider) || a.Source != b.Source {
+		return false
+	}
+	if a.Location == nil || b.Location == nil {
+		return true
+	}
+	if a.Location.EnvVar != "" && b.Location.EnvVar != "" {
+		return a.Location.EnvVar == b.Location.EnvVar
+	}
+	if a.Location.Pointer != "" && b.Location.Pointer != "" {
+		return a.Location.Pointer == b.Location.Pointer
+	}
+	return true
+}
+
+// KeyRelation is how a key seen before relates to one seen now
+type KeyRelation string
+
+// Key relations returned by Relate
+const (
+	// KeyUnrelated is a different key in a different place
+	KeyUnrelated KeyRelation = "unrelated"
+	// KeyUnchanged is the same key in the same place
+	KeyUnchanged KeyRelation = "unchanged"
+	// KeyRotated is a new key in the place of an old one
+	KeyRotated KeyRelation = "rotated"
+	// KeyDuplicated is the same key found in another place
+	KeyDuplicated KeyRelation = "duplicated"
+)
+
+// Relate classifies before and after, so history and diff views can tell a
+// rotated key from one copied somewhere new
+func Relate(before, after DiscoveredKey) KeyRelation {
+	same, slot := SameCredential(before, after), SameSlot(before, after)
+	switch {
+	case same && slot:
+		return KeyUnchanged
+	case same:
+		return KeyDuplicated
+	case slot:
+		return KeyRotated
+	default:
+		return KeyUnrelated
+	}
+}
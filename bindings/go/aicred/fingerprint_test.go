@@ -0,0 +1,74 @@
+package aicred
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func fingerprintKey(source, envVar, value string) DiscoveredKey {
+	sum := sha256.Sum256([]byte(value))
+	return DiscoveredKey{
+		Provider: "openai",
+		Source:   source,
+		Value:    NewSecretString(value),
+		Hash:     hex.EncodeToString(sum[:]),
+		Location: &Location{Path: source, EnvVar: envVar},
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	secret := []byte("org-secret")
+	key := fingerprintKey("/h/.env", "OPENAI_API_KEY", "sk-proj-abcdefghijklmnopqrstuvwx")
+	f := key.Fingerprint(secret)
+	if f.Provider != "openai" || f.Length != 32 || f.PrefixClass != "sk-proj-" || len(f.HMAC) != 64 {
+		t.Fatalf("fingerprint = %+v", f)
+	}
+
+	redacted := key
+	redacted.Value = Secret{}
+	if r := redacted.Fingerprint(secret); r.HMAC != f.HMAC || r.Length != 0 || !r.SameKind(f) {
+		t.Errorf("redacted fingerprint = %+v, want HMAC %s", r, f.HMAC)
+	}
+	if other := key.Fingerprint([]byte("other-secret")); other.HMAC == f.HMAC {
+		t.Error("HMAC does not depend on the secret")
+	}
+	rotated := fingerprintKey("/h/.env", "OPENAI_API_KEY", "sk-proj-zyxwvutsrqponmlkjihgfedc").Fingerprint(secret)
+	if rotated.HMAC == f.HMAC || !rotated.SameKind(f) {
+		t.Errorf("rotated fingerprint = %+v", rotated)
+	}
+	if got := prefixClass("pk-live-123"); got != "pk-" {
+		t.Errorf("prefixClass = %q", got)
+	}
+	if got := prefixClass("ABCDEF-123"); got != "" {
+		t.Errorf("prefixClass = %q, want none", got)
+	}
+}
+
+func TestRelate(t *testing.T) {
+	old := fingerprintKey("/h/.env", "OPENAI_API_KEY", "sk-proj-abcdefghijklmnopqrstuvwx")
+	tests := []struct {
+		name  string
+		after DiscoveredKey
+		want  KeyRelation
+	}{
+		{"unchanged", fingerprintKey("/h/.env", "OPENAI_API_KEY", "sk-proj-abcdefghijklmnopqrstuvwx"), KeyUnchanged},
+		{"rotated", fingerprintKey("/h/.env", "OPENAI_API_KEY", "sk-proj-new"), KeyRotated},
+		{"duplicated", fingerprintKey("/h/app/.env", "OPENAI_API_KEY", "sk-proj-abcdefghijklmnopqrstuvwx"), KeyDuplicated},
+		{"other variable", fingerprintKey("/h/.env", "OPENAI_API_KEY_2", "sk-proj-new"), KeyUnrelated},
+	}
+	for _, tt := range tests {
+		if got := Relate(old, tt.after); got != tt.want {
+			t.Errorf("%s: Relate = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	hashOnly := old
+	hashOnly.Value = Secret{}
+	if !SameCredential(hashOnly, old) {
+		t.Error("SameCredential should fall back to the hash")
+	}
+	if SameCredential(DiscoveredKey{}, DiscoveredKey{}) {
+		t.Error("keys with no value or hash should never match")
+	}
+}
@@ -0,0 +1,71 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// Agent posts a host's scan results to a Collector
+type Agent struct {
+	// URL is the collector's base URL, such as https://aicred.example.com
+	URL string
+	// Host names this machine in reports; "" means os.Hostname
+	Host string
+	// Key signs reports; the collector must have its public half enrolled
+	// for Host
+	Key    ed25519.PrivateKey
+	Client *http.Client
+}
+
+// Report signs result, redacted to hashes, and posts it to the collector
+func (a *Agent) Report(ctx context.Context, result *aicred.ScanResult) error {
+	host := a.Host
+	if host == "" {
+		// A remote scan already names the machine it came from
+		host = result.Host
+	}
+	if host == "" {
+		var err error
+		if host, err = os.Hostname(); err != nil {
+			return fmt.Errorf("failed to get hostname: %v", err)
+		}
+	}
+	signed, err := Sign(Report{Host: host, SentAt: time.Now().UTC(), Result: result}, a.Key)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(signed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signed report: %v", err)
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.URL, "/")+"/v1/reports", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send report: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("report rejected: %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package fleet
+
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// maxReportBody bounds the size of a posted report
+const maxReportBody = 8 << 20
+
+// defaultMaxSkew is how far a report's SentAt may be from the collector's
+// clock when CollectorOptions.MaxSkew is 0
+const defaultMaxSkew = 5 * time.Minute
+
+// CollectorOptions configures a Collector
+type CollectorOptions struct {
+	// Token is the bearer token for the read endpoints. A collector with no
+	// token serves no reads.
+	Token string
+	// HostKeys maps each enrolled host to the public key its reports must
+	// be signed with. Reports from other hosts are rejected.
+	HostKeys map[string]ed25519.PublicKey
+	// MaxSkew bounds how old, or how far ahead, a report's SentAt may be;
+	// 0 means 5 minutes. A report no newer than the host's last one is a
+	// replay and is rejected too.
+	MaxSkew time.Duration
+}
+
+// HostSummary describes the latest report from one host
+type HostSummary struct {
+	Host       string    `json:"host"`
+	ReportedAt time.Time `json:"reported_at"`
+	Keys       int       `json:"keys"`
+	Providers  []string  `json:"providers"`
+}
+
+// Location is one place a key was found
+type Location struct {
+	Host   string `json:"host"`
+	Source string `json:"source"`
+	// Attribution is the owner the agent recorded, if it scanned with
+	// ScanOptions.Attribute
+	Attribution *aicred.Attribution `json:"attribution,omitempty"`
+}
+
+// Exposure is one credential, identified by its hash, and everywhere the
+// latest reports found it
+type Exposure struct {
+	Hash      string     `json:"hash"`
+	Provider  string     `json:"provider"`
+	Hosts     int        `json:"hosts"`
+	Locations []Location `json:"locations"`
+}
+
+// Collector receives signed reports from agents and aggregates them. It
+// keeps the latest report per host in memory.
+type Collector struct {
+	opts CollectorOptions
+	mux  *http.ServeMux
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	reports map[string]*Report
+}
+
+// NewCollector returns a Collector; serve it with http.Handle
+func NewCollector(opts CollectorOptions) *Collector {
+	if opts.MaxSkew <= 0 {
+		opts.MaxSkew = defaultMaxSkew
+	}
+	c := &Collector{opts: opts, mux: http.NewServeMux(), now: time.Now, reports: map[string]*Report{}}
+	c.mux.HandleFunc("/v1/reports", c.handleReport)
+	c.mux.HandleFunc("/v1/hosts", c.get(func(*http.Request) (any, bool) {
+		return map[string][]HostSummary{"hosts": c.Hosts()}, true
+	}))
+	c.mux.HandleFunc("/v1/hosts/{host}", c.get(func(r *http.Request) (any, bool) {
+		report, ok := c.Latest(r.PathValue("host"))
+		return report, ok
+	}))
+	c.mux.HandleFunc("/v1/exposure", c.get(func(*http.Request) (any, bool) {
+		return map[string][]Exposure{"exposure": c.Exposure()}, true
+	}))
+	return c
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Agents authenticate by signature; everything else needs the token
+	if r.URL.Path != "/v1/reports" && !c.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="aicred-fleet"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	c.mux.ServeHTTP(w, r)
+}
+
+// authorized checks the bearer token in constant time
+func (c *Collector) authorized(r *http.Request) bool {
+	if c.opts.Token == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.opts.Token)) == 1
+}
+
+// Accept verifies a signed report and stores it as its host's latest
+func (c *Collector) Accept(signed *SignedReport) error {
+	report, err := signed.Open(func(host string) (ed25519.PublicKey, bool) {
+		key, ok := c.opts.HostKeys[host]
+		return key, ok
+	})
+	if err != nil {
+		return err
+	}
+	if report.Result == nil {
+		return errors.New("report has no result")
+	}
+	now := c.now()
+	if report.SentAt.Before(now.Add(-c.opts.MaxSkew)) || report.SentAt.After(now.Add(c.opts.MaxSkew)) {
+		return errors.New("report timestamp is outside the allowed clock skew")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last, ok := c.reports[report.Host]; ok && !report.SentAt.After(last.SentAt) {
+		return errors.New("report is not newer than the host's last report")
+	}
+	c.reports[report.Host] = report
+	return nil
+}
+
+// Latest returns the latest report from host
+func (c *Collector) Latest(host string) (*Report, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	report, ok := c.reports[host]
+	return report, ok
+}
+
+// Hosts summarizes the latest report from every host, sorted by host
+func (c *Collector) Hosts() []HostSummary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	hosts := make([]HostSummary, 0, len(c.reports))
+	for _, report := range c.reports {
+		providers := map[string]bool{}
+		keys := reportKeys(report.Result)
+		for _, key := range keys {
+			providers[key.Provider] = true
+		}
+		summary := HostSummary{Host: report.Host, ReportedAt: report.SentAt, Keys: len(keys), Providers: []string{}}
+		for p := range providers {
+			summary.Providers = append(summary.Providers, p)
+		}
+		sort.Strings(summary.Providers)
+		hosts = append(hosts, summary)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+// Exposure lists every key hash in the latest reports with where it was
+// found, most widely spread first
+func (c *Collector) Exposure() []Exposure {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	byHash := map[string]*Exposure{}
+	hostSets := map[string]map[string]bool{}
+	for _, report := range c.reports {
+		for _, key := range reportKeys(report.Result) {
+			exp, ok := byHash[key.Hash]
+			if !ok {
+				exp = &Exposure{Hash: key.Hash, Provider: key.Provider}
+				byHash[key.Hash] = exp
+				hostSets[key.Hash] = map[string]bool{}
+			}
+			exp.Locations = append(exp.Locations, Location{Host: report.Host, Source: key.Source, Attribution: key.Attribution})
+			hostSets[key.Hash][report.Host] = true
+		}
+	}
+
+	exposure := make([]Exposure, 0, len(byHash))
+	for hash, exp := range byHash {
+		exp.Hosts = len(hostSets[hash])
+		sort.Slice(exp.Locations, func(i, j int) bool {
+			a, b := exp.Locations[i], exp.Locations[j]
+			return a.Host < b.Host || a.Host == b.Host && a.Source < b.Source
+		})
+		exposure = append(exposure, *exp)
+	}
+	sort.Slice(exposure, func(i, j int) bool {
+		if exposure[i].Hosts != exposure[j].Hosts {
+			return exposure[i].Hosts > exposure[j].Hosts
+		}
+		return exposure[i].Hash < exposure[j].Hash
+	})
+	return exposure
+}
+
+// reportKeys returns the keys in a result, including those only listed under
+// config instances, once per hash and source
+func reportKeys(result *aicred.ScanResult) []aicred.DiscoveredKey {
+	seen := map[[2]string]bool{}
+	var keys []aicred.DiscoveredKey
+	add := func(key aicred.DiscoveredKey) {
+		id := [2]string{key.Hash, key.Source}
+		if key.Hash == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		keys = append(keys, key)
+	}
+	for _, key := range result.Keys {
+		add(key)
+	}
+	for _, inst := range result.ConfigInstances {
+		for _, key := range inst.Keys {
+			if key.Source == "" {
+				key.Source = inst.ConfigPath
+			}
+			add(key)
+		}
+	}
+	return keys
+}
+
+func (c *Collector) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var signed SignedReport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReportBody)).Decode(&signed); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if err := c.Accept(&signed); err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, ErrBadSignature) {
+			code = http.StatusForbidden
+		}
+		writeError(w, code, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Collector) get(body func(r *http.Request) (any, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		v, ok := body(r)
+		if !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,75 @@
+// Package fleet collects scan results from many machines in one place.
+//
+// An Agent on each host redacts its scan result to key hashes, signs it with
+// the host's Ed25519 key and posts it to a Collector. The Collector verifies
+// the signature against the keys it has enrolled, keeps the latest report
+// per host, and serves a JSON API showing where each credential is exposed:
+//
+//	POST /v1/reports           signed report from an agent
+//	GET  /v1/hosts             latest report summary for every host
+//	GET  /v1/hosts/{host}      latest report from one host
+//	GET  /v1/exposure          every key hash with the hosts and files holding it
+//
+// Reports carry hashes rather than values, so the same key found on several
+// machines shows up as one exposure without the collector ever seeing it.
+// Everything except POST /v1/reports needs "Authorization: Bearer <token>".
+package fleet
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// Report is what an agent sends for one scan
+type Report struct {
+	Host   string             `json:"host"`
+	SentAt time.Time          `json:"sent_at"`
+	Result *aicred.ScanResult `json:"result"`
+}
+
+// SignedReport is a Report as it travels: the exact bytes signed, and the
+// Ed25519 signature over them
+type SignedReport struct {
+	Report    json.RawMessage `json:"report"`
+	Signature []byte          `json:"signature"`
+}
+
+// ErrBadSignature is returned when a report's signature does not verify
+// against the key enrolled for its host
+var ErrBadSignature = aicred.ErrBadSignature
+
+// Sign redacts the report's result to hashes and signs it with key
+func Sign(report Report, key ed25519.PrivateKey) (*SignedReport, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 private key length %d", len(key))
+	}
+	if report.Result != nil {
+		report.Result = report.Result.Redact(aicred.RedactionHashOnly)
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %v", err)
+	}
+	return &SignedReport{Report: body, Signature: ed25519.Sign(key, body)}, nil
+}
+
+// Open checks the signature with the key lookup returns for the report's
+// host and decodes the report. lookup reports false for unknown hosts.
+func (s *SignedReport) Open(lookup func(host string) (ed25519.PublicKey, bool)) (*Report, error) {
+	var report Report
+	if err := json.Unmarshal(s.Report, &report); err != nil {
+		return nil, fmt.Errorf("%w: %v", aicred.ErrParse, err)
+	}
+	key, ok := lookup(report.Host)
+	if !ok {
+		return nil, fmt.Errorf("host %q is not enrolled: %w", report.Host, ErrBadSignature)
+	}
+	if !ed25519.Verify(key, s.Report, s.Signature) {
+		return nil, fmt.Errorf("report from %q: %w", report.Host, ErrBadSignature)
+	}
+	return &report, nil
+}
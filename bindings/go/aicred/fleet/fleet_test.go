@@ -0,0 +1,158 @@
+package fleet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func result(keys ...aicred.DiscoveredKey) *aicred.ScanResult {
+	return &aicred.ScanResult{HomeDir: "/home/u", Keys: keys}
+}
+
+var (
+	shared = aicred.DiscoveredKey{Provider: "openai", Hash: "h-shared", Redacted: "sk-****aaaa", Source: "/home/u/.env", Value: aicred.NewSecretString("sk-shared")}
+	local  = aicred.DiscoveredKey{Provider: "anthropic", Hash: "h-local", Redacted: "sk-ant-****bbbb", Source: "/home/u/.claude.json"}
+)
+
+func get(t *testing.T, h http.Handler, path, token string, v any) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code == http.StatusOK && v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("decoding %s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAgentReportsToCollector(t *testing.T) {
+	pubA, privA := newKey(t)
+	pubB, privB := newKey(t)
+	collector := NewCollector(CollectorOptions{
+		Token:    "t",
+		HostKeys: map[string]ed25519.PublicKey{"web-1": pubA, "web-2": pubB},
+	})
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	ctx := context.Background()
+	if err := (&Agent{URL: srv.URL, Host: "web-1", Key: privA}).Report(ctx, result(shared, local)); err != nil {
+		t.Fatalf("web-1 report: %v", err)
+	}
+	if err := (&Agent{URL: srv.URL + "/", Host: "web-2", Key: privB}).Report(ctx, result(shared)); err != nil {
+		t.Fatalf("web-2 report: %v", err)
+	}
+
+	var hosts struct{ Hosts []HostSummary }
+	if code := get(t, collector, "/v1/hosts", "t", &hosts); code != http.StatusOK {
+		t.Fatalf("GET /v1/hosts: %d", code)
+	}
+	if len(hosts.Hosts) != 2 || hosts.Hosts[0].Host != "web-1" || hosts.Hosts[0].Keys != 2 {
+		t.Errorf("hosts = %+v", hosts.Hosts)
+	}
+
+	var exposure struct{ Exposure []Exposure }
+	get(t, collector, "/v1/exposure", "t", &exposure)
+	if len(exposure.Exposure) != 2 {
+		t.Fatalf("exposure = %+v", exposure.Exposure)
+	}
+	top := exposure.Exposure[0]
+	if top.Hash != "h-shared" || top.Hosts != 2 || len(top.Locations) != 2 || top.Locations[1] != (Location{Host: "web-2", Source: "/home/u/.env"}) {
+		t.Errorf("widest exposure = %+v", top)
+	}
+
+	report, ok := collector.Latest("web-1")
+	if !ok {
+		t.Fatal("no report from web-1")
+	}
+	for _, key := range report.Result.Keys {
+		if !key.Value.IsZero() || key.Redacted != "" {
+			t.Errorf("collector received more than the hash: %+v", key)
+		}
+	}
+	if code := get(t, collector, "/v1/hosts/web-3", "t", nil); code != http.StatusNotFound {
+		t.Errorf("unknown host: %d, want 404", code)
+	}
+}
+
+func TestCollectorRejects(t *testing.T) {
+	pub, priv := newKey(t)
+	_, other := newKey(t)
+	collector := NewCollector(CollectorOptions{HostKeys: map[string]ed25519.PublicKey{"web-1": pub}})
+	now := time.Now().UTC()
+
+	sign := func(host string, at time.Time, key ed25519.PrivateKey) *SignedReport {
+		signed, err := Sign(Report{Host: host, SentAt: at, Result: result(local)}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	if err := collector.Accept(sign("web-1", now, other)); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("wrong key: err = %v, want ErrBadSignature", err)
+	}
+	if err := collector.Accept(sign("web-9", now, priv)); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("unenrolled host: err = %v, want ErrBadSignature", err)
+	}
+	if err := collector.Accept(sign("web-1", now.Add(-time.Hour), priv)); err == nil {
+		t.Error("stale report accepted")
+	}
+
+	signed := sign("web-1", now, priv)
+	if err := collector.Accept(signed); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if err := collector.Accept(signed); err == nil {
+		t.Error("replayed report accepted")
+	}
+
+	// Tampering with the signed bytes breaks the signature
+	signed.Report = json.RawMessage(strings.Replace(string(signed.Report), "h-local", "h-other", 1))
+	if err := collector.Accept(signed); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered report: err = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestCollectorAuth(t *testing.T) {
+	collector := NewCollector(CollectorOptions{Token: "t"})
+	if code := get(t, collector, "/v1/exposure", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("without token: %d, want 401", code)
+	}
+	if code := get(t, collector, "/v1/exposure", "wrong", nil); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: %d, want 401", code)
+	}
+	if code := get(t, NewCollector(CollectorOptions{}), "/v1/hosts", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("collector without a token served a read: %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/reports", strings.NewReader(`{"report":{"host":"x"},"signature":""}`))
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("unsigned report: %d, want 403", rec.Code)
+	}
+}
@@ -1,19 +1,18 @@
 package aicred
 
 /*
-#cgo LDFLAGS: -L../../../target/release -laicred_ffi
-#cgo darwin LDFLAGS: -Wl,-rpath,../../../target/release
-#cgo linux LDFLAGS: -Wl,-rpath,../../../target/release
+#cgo LDFLAGS: -L${SRCDIR}/../../../target/release -laicred_ffi
+#cgo darwin LDFLAGS: -Wl,-rpath,${SRCDIR}/../../../target/release
+#cgo linux LDFLAGS: -Wl,-rpath,${SRCDIR}/../../../target/release
 #cgo windows LDFLAGS: -lws2_32 -luserenv -ladvapi32 -lbcrypt -lntdll -lkernel32 -luser32
 #include <stdlib.h>
 
 // Declare the FFI functions that might not be in the header yet
-extern char* aicred_list_providers();
-extern char* aicred_list_scanners();
-extern char* aicred_scan(const char* home_path, const char* options_json);
+extern char* aicred_list_providers_envelope(void);
+extern char* aicred_list_scanners_envelope(void);
+extern char* aicred_scan_envelope(const char* home_path, const char* options_json);
 extern void aicred_free(char* ptr);
 extern const char* aicred_version(void);
-extern const char* aicred_last_error(void);
 
 // Include the header for existing functions
 #include "../../../ffi/include/genai_keyfinder.h"
@@ -23,17 +22,106 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"slices"
+	"time"
 	"unsafe"
+
+	"github.com/fxamacker/cbor/v2"
 )
 
 // ScanOptions contains options for scanning
 type ScanOptions struct {
-	HomeDir           string   `json:"home_dir,omitempty"`
+	HomeDir string `json:"home_dir,omitempty"`
+	// Redaction sets how much of each key the result carries
+	Redaction RedactionLevel `json:"-"`
+	// Deprecated: set Redaction to RedactionNone instead.
 	IncludeFullValues bool     `json:"include_full_values"`
 	MaxFileSize       int      `json:"max_file_size"`
 	OnlyProviders     []string `json:"only_providers,omitempty"`
 	ExcludeProviders  []string `json:"exclude_providers,omitempty"`
+	// Concurrency bounds how many scanners, and how many files in the
+	// archive and binary config passes, are processed at once; 0 means one
+	// per CPU
+	Concurrency int `json:"concurrency,omitempty"`
+	// ScanArchives makes Scan also search .zip, .jar, .tar, .tar.gz and
+	// .gz files under the home directory, using the Go detector
+	ScanArchives bool `json:"-"`
+	// MaxArchiveDepth bounds archives nested in archives; 0 means 2
+	MaxArchiveDepth int `json:"-"`
+	// MaxArchiveBytes bounds the uncompressed bytes read from each archive;
+	// 0 means 64 MiB
+	MaxArchiveBytes int64 `json:"-"`
+	// ScanBinaryConfigs makes Scan also search binary plists and SQLite
+	// databases in application data directories, using the Go detector
+	ScanBinaryConfigs bool `json:"-"`
+	// ScanBrowserStorage makes Scan also search Chromium and Firefox
+	// localStorage and IndexedDB, where web UIs keep keys, using the Go
+	// detector
+	ScanBrowserStorage bool `json:"-"`
+	// ScanGitHubCLI makes Scan also read the gh CLI's hosts.yml and the
+	// Copilot plugins' hosts.json and apps.json, adding a config instance
+	// per github.com login; see GitHubInstances
+	ScanGitHubCLI bool `json:"-"`
+	// ScanInfraState makes Scan also search Terraform state and tfvars
+	// files and Pulumi stack files under the home directory, where keys
+	// end up as plain-text outputs and variables
+	ScanInfraState bool `json:"-"`
+	// ScanPackageConfigs makes Scan also search .npmrc, .yarnrc.yml,
+	// pip.conf, poetry's auth.toml and the "config" blocks of package.json
+	// files under the home directory
+	ScanPackageConfigs bool `json:"-"`
+	// ScanRegistry makes Scan also search the HKEY_CURRENT_USER registry
+	// keys of known AI clients on Windows; it finds nothing elsewhere.
+	// Keys report their registry path as the Source.
+	ScanRegistry bool `json:"-"`
+	// IgnoreGlobs are gitignore-style patterns, applied after
+	// DefaultIgnorePatterns and the home directory's .aicredignore, naming
+	// paths the archive and binary config passes skip
+	IgnoreGlobs []string `json:"-"`
+	// Incremental makes the archive and binary config passes reuse what
+	// the previous incremental scan found in files whose size and mtime, or
+	// failing that whose SHA-256, are unchanged. Key values are not cached,
+	// so files with keys are reread when Redaction is RedactionNone.
+	Incremental bool `json:"-"`
+	// CacheDir holds the incremental scan cache; "" means StateDir of the
+	// default home directory, usually ~/.config/aicred
+	CacheDir string `json:"-"`
+	// MaxDepth bounds how many directories below the home directory the
+	// archive and binary config passes descend; 0 means no limit
+	MaxDepth int `json:"-"`
+	// FollowSymlinks makes those passes follow symbolic links, skipping any
+	// directory already walked so link cycles end
+	FollowSymlinks bool `json:"-"`
+	// PerFileTimeout skips a file the Go-side passes take longer than this
+	// to scan, such as one on a stalled network mount; 0 means no limit
+	PerFileTimeout time.Duration `json:"-"`
+	// GlobalTimeout makes Scan return ErrTimeout once this much time has
+	// passed, along with what was found until then; 0 means no limit
+	GlobalTimeout time.Duration `json:"-"`
+	// RemoteBinary is a static aicred CLI built for the remote platform that
+	// ScanRemote copies to the host and runs; "" runs the aicred on the
+	// remote PATH
+	RemoteBinary string `json:"-"`
+	// SSHArgs are extra ssh client arguments for ScanRemote, such as
+	// "-i", keyFile or "-p", "2222"
+	SSHArgs []string `json:"-"`
+	// Attribute makes Scan record each key's file owner, git repository
+	// and owning application; see ScanResult.Attribute
+	Attribute bool `json:"-"`
+	// MinConfidence drops keys reported below this confidence level: Low,
+	// Medium, High or VeryHigh; "" keeps every key
+	MinConfidence string `json:"-"`
+}
+
+// redactionLevel resolves Redaction against the deprecated IncludeFullValues
+func (o ScanOptions) redactionLevel() RedactionLevel {
+	if o.IncludeFullValues && o.Redaction == RedactionPartial {
+		return RedactionNone
+	}
+	return o.Redaction
 }
 
 // DiscoveredKey represents a discovered API key
@@ -41,11 +129,40 @@ type DiscoveredKey struct {
 	Provider   string `json:"provider"`
 	Source     string `json:"source"`
 	ValueType  string `json:"value_type"`
-	Value      string `json:"value,omitempty"`
+	Value      Secret `json:"value"`
 	Confidence string `json:"confidence"`
 	Hash       string `json:"hash"`
 	Redacted   string `json:"redacted"`
 	Locked     bool   `json:"locked"`
+	// Location pinpoints the key within Source; nil when the scanner did
+	// not record it
+	Location *Location `json:"location,omitempty"`
+	// Attribution says who the key likely belongs to; set only when
+	// ScanOptions.Attribute is
+	Attribution *Attribution `json:"attribution,omitempty"`
+	// HFToken is what a Hugging Face token can do; set only by
+	// InspectHFTokens
+	HFToken *HFToken `json:"hf_token,omitempty"`
+	// Scope is what the key may reach; set only by InspectKey
+	Scope *KeyScope `json:"scope,omitempty"`
+}
+
+// Location is where exactly a key was found, for remediation and editor
+// integrations that jump to the spot. Fields the scanner could not
+// determine are zero.
+type Location struct {
+	Path string `json:"path"`
+	// Line and Column are 1-based; Column counts bytes
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Offset is the byte offset from the start of the file
+	Offset int64 `json:"offset,omitempty"`
+	// EnvVar is the environment variable or config key the key was
+	// assigned to
+	EnvVar string `json:"env_var,omitempty"`
+	// Pointer is the JSON pointer (RFC 6901) of the key within a JSON or
+	// YAML document
+	Pointer string `json:"pointer,omitempty"`
 }
 
 // ConfigInstance represents an application configuration instance
@@ -56,72 +173,251 @@ type ConfigInstance struct {
 	DiscoveredAt string            `json:"discovered_at"`
 	Keys         []DiscoveredKey   `json:"keys"`
 	Metadata     map[string]string `json:"metadata"`
+	// ProviderInstances are the endpoints the application is configured
+	// with, by instance ID
+	ProviderInstances map[string]ProviderInstance `json:"provider_instances,omitempty"`
 }
 
 // ScanResult contains the results of a scan
 type ScanResult struct {
+	// SchemaVersion is the version of the encoding, ScanResultSchemaVersion
+	// for results this package produces; see ParseScanResult
+	SchemaVersion    int              `json:"schema_version,omitempty"`
 	Keys             []DiscoveredKey  `json:"keys"`
 	ConfigInstances  []ConfigInstance `json:"config_instances"`
 	HomeDir          string           `json:"home_directory"`
 	ScannedAt        string           `json:"scan_started_at"`
 	ProvidersScanned []string         `json:"providers_scanned"`
+	Stats            ScanStats        `json:"stats"`
+	// Warnings lists non-fatal problems, such as unreadable or unparseable
+	// files, so an incomplete scan is visible
+	Warnings []ScanWarning `json:"warnings,omitempty"`
+	// Host is the machine ScanRemote scanned; "" for local scans
+	Host string `json:"host,omitempty"`
+	// Signature is set by SignResult and checked by VerifyResult
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// WarningKind classifies a ScanWarning
+type WarningKind string
+
+// Warning kinds. WarningTimeout comes only from the Go-side passes.
+const (
+	WarningUnreadable       WarningKind = "unreadable"
+	WarningPermissionDenied WarningKind = "permission_denied"
+	WarningParseError       WarningKind = "parse_error"
+	WarningTooLarge         WarningKind = "too_large"
+	WarningTimeout          WarningKind = "timeout"
+)
+
+// ScanWarning is a problem that kept a scanner from examining a file
+type ScanWarning struct {
+	// Scanner is the application scanner or Go-side pass, such as
+	// "archives", that met the problem
+	Scanner string      `json:"scanner"`
+	Path    string      `json:"path"`
+	Kind    WarningKind `json:"kind"`
+	Message string      `json:"message"`
+}
+
+// warningKindOf classifies a Go-side error
+func warningKindOf(err error) WarningKind {
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return WarningPermissionDenied
+	case errors.As(err, &pathErr):
+		return WarningUnreadable
+	default:
+		return WarningParseError
+	}
+}
+
+// ScanStats describes what a scan examined, for judging its coverage and
+// tuning ScanOptions. Counts include the Go-side passes.
+type ScanStats struct {
+	// FilesExamined counts candidate files read
+	FilesExamined int64 `json:"files_examined"`
+	// BytesRead is the size of those files
+	BytesRead int64 `json:"bytes_read"`
+	// DurationMS is the wall-clock duration of the scan in milliseconds
+	DurationMS int64 `json:"duration_ms"`
+	// SkippedBySize counts candidate files over MaxFileSize
+	SkippedBySize int64 `json:"skipped_by_size"`
+	// ErrorsByScanner counts files each scanner, or Go-side pass, could not
+	// read or parse
+	ErrorsByScanner map[string]int64 `json:"errors_by_scanner,omitempty"`
+	// DurationMSByScanner is the time each scanner, or Go-side pass, took in
+	// milliseconds
+	DurationMSByScanner map[string]int64 `json:"duration_ms_by_scanner,omitempty"`
+}
+
+// Duration returns DurationMS as a time.Duration
+func (s ScanStats) Duration() time.Duration {
+	return time.Duration(s.DurationMS) * time.Millisecond
 }
 
-// Scan performs a scan for GenAI credentials and configurations
+// Scan performs a scan for GenAI credentials and configurations. When
+// ScanOptions.GlobalTimeout runs out, it returns the keys found so far
+// together with an error wrapping ErrTimeout.
 func Scan(options ScanOptions) (*ScanResult, error) {
-	// Validate HomeDir if provided
-	if options.HomeDir != "" {
-		info, err := os.Stat(options.HomeDir)
-		if err != nil || !info.IsDir() {
-			return nil, fmt.Errorf("invalid HomeDir: %s", options.HomeDir)
-		}
+	start := time.Now()
+
+	if err := validateHomeDir(options.HomeDir); err != nil {
+		return nil, err
 	}
 
-	// Convert options to JSON
-	optionsJSON, err := json.Marshal(options)
+	level, optionsJSON, err := prepareScan(options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal options to JSON: %v", err)
-	}
-
-	// Convert home directory to C string
-	var homeDir *C.char
-	if options.HomeDir != "" {
-		homeDir = C.CString(options.HomeDir)
-	} else {
-		homeDir = C.CString("")
-	}
-	defer C.free(unsafe.Pointer(homeDir))
-
-	// Convert options JSON to C string
-	optionsStr := C.CString(string(optionsJSON))
-	defer C.free(unsafe.Pointer(optionsStr))
-
-	// Call C function with error handling
-	resultPtr := C.aicred_scan(homeDir, optionsStr)
-	if resultPtr == nil {
-		// Get error message
-		errPtr := C.aicred_last_error()
-		if errPtr != nil {
-			errMsg := C.GoString(errPtr)
-			return nil, fmt.Errorf("FFI scan failed: %s", errMsg)
+		return nil, err
+	}
+
+	run := newScanRun(options, start)
+	// decoded is only read once the FFI call has returned: after a timeout
+	// it may still be written in the background
+	var result, decoded ScanResult
+	err = withDeadline("scan", run.deadline, func() error {
+		// The C strings belong to this call, which may outlive a timed-out Scan
+		homeDir := C.CString(options.HomeDir)
+		defer C.free(unsafe.Pointer(homeDir))
+		optionsStr := C.CString(string(optionsJSON))
+		defer C.free(unsafe.Pointer(optionsStr))
+		// Errors come back in the envelope rather than through aicred_last_error,
+		// which is thread-local and unreliable once the goroutine has moved threads
+		logger().Debug("calling FFI", slog.String("function", "aicred_scan_envelope"))
+		var ffiResult ScanResult
+		if err := decodeEnvelope("scan", C.aicred_scan_envelope(homeDir, optionsStr), &ffiResult); err != nil {
+			return err
 		}
-		return nil, errors.New("scan failed with unknown error (FFI returned null)")
+		decoded = ffiResult
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrTimeout) {
+		logger().Error("FFI scan failed", slog.String("error", err.Error()))
+		return nil, err
 	}
-	defer C.aicred_free(resultPtr)
+	if err == nil {
+		result = decoded
+	}
+	if result.HomeDir == "" {
+		result.HomeDir = options.HomeDir
+	}
+	return completeScan(&result, options, level, start, run)
+}
 
-	// Convert result to Go string
-	resultJSON := C.GoString(resultPtr)
-	if resultJSON == "" {
-		return nil, errors.New("FFI returned empty result")
+// completeScan runs the Go-side passes the options enable over the home
+// directory of result, an FFI scan's result, and finishes it. Scan and
+// Session.Scan share it so both honor every option. The result is
+// returned along with ErrTimeout when GlobalTimeout ran out.
+func completeScan(result *ScanResult, options ScanOptions, level RedactionLevel, start time.Time, run *scanRun) (*ScanResult, error) {
+	home := absHome(result.HomeDir)
+	if options.ScanArchives {
+		result.Keys = append(result.Keys, run.timed(passArchives, func() []DiscoveredKey {
+			return scanArchives(home, options, run)
+		})...)
+	}
+	if options.ScanBinaryConfigs {
+		result.Keys = append(result.Keys, run.timed(passBinaryConfigs, func() []DiscoveredKey {
+			return scanBinaryConfigs(home, options, run)
+		})...)
 	}
+	if options.ScanInfraState {
+		result.Keys = append(result.Keys, run.timed(passInfraState, func() []DiscoveredKey {
+			return scanInfraState(home, options, run)
+		})...)
+	}
+	if options.ScanPackageConfigs {
+		result.Keys = append(result.Keys, run.timed(passPackageConfigs, func() []DiscoveredKey {
+			return scanPackageConfigs(home, options, run)
+		})...)
+	}
+	if options.ScanRegistry && !run.expired() {
+		result.Keys = append(result.Keys, run.timed(passRegistry, func() []DiscoveredKey {
+			return scanRegistry(options, run)
+		})...)
+	}
+	if options.ScanBrowserStorage && !run.expired() {
+		result.Keys = append(result.Keys, run.timed(passBrowserStorage, func() []DiscoveredKey {
+			return scanBrowserStorage(home, options, run)
+		})...)
+	}
+	if options.ScanGitHubCLI && !run.expired() {
+		run.timed(passGitHubCLI, func() []DiscoveredKey {
+			result.ConfigInstances = append(result.ConfigInstances, scanGitHubCLI(home, options, run)...)
+			return nil
+		})
+	}
+	run.cache.save()
+	run.addTo(result)
+	if options.Attribute {
+		result.Attribute()
+	}
+	partial := finishScan(result, options, level, start)
+	if run.expired() {
+		// What was found still needs dealing with, so it is not thrown away
+		return partial, fmt.Errorf("scan: %w after %s", ErrTimeout, options.GlobalTimeout)
+	}
+	return partial, nil
+}
 
-	// Parse JSON result
-	var result ScanResult
-	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON result: %v (raw: %s)", err, resultJSON)
+// validateHomeDir checks that a non-empty HomeDir is an accessible directory
+func validateHomeDir(homeDir string) error {
+	if homeDir == "" {
+		return nil
 	}
+	info, err := os.Stat(homeDir)
+	if err != nil || !info.IsDir() {
+		logger().Warn("invalid home directory", slog.String("home_dir", homeDir))
+		cause := ErrNotFound
+		if errors.Is(err, fs.ErrPermission) {
+			cause = ErrPermissionDenied
+		}
+		return fmt.Errorf("invalid HomeDir: %s: %w", homeDir, cause)
+	}
+	return nil
+}
+
+// prepareScan resolves the redaction level and encodes the options for the FFI
+func prepareScan(options ScanOptions) (RedactionLevel, []byte, error) {
+	level := options.redactionLevel()
+	options.IncludeFullValues = level == RedactionNone
+
+	logger().Debug("starting scan",
+		slog.String("home_dir", options.HomeDir),
+		slog.String("redaction", level.String()),
+		slog.Any("only_providers", options.OnlyProviders),
+		slog.Any("exclude_providers", options.ExcludeProviders))
+
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return level, nil, fmt.Errorf("failed to marshal options to JSON: %v", err)
+	}
+	return level, optionsJSON, nil
+}
 
-	return &result, nil
+// finishScan drops keys below MinConfidence, stamps the schema version, logs
+// the outcome and applies the redaction level
+func finishScan(result *ScanResult, options ScanOptions, level RedactionLevel, start time.Time) *ScanResult {
+	if options.MinConfidence != "" {
+		minimum, _ := confidenceRank(options.MinConfidence)
+		result.Keys = slices.DeleteFunc(result.Keys, func(k DiscoveredKey) bool {
+			rank, _ := confidenceRank(k.Confidence)
+			return rank < minimum
+		})
+	}
+	result.SchemaVersion = ScanResultSchemaVersion
+	result.Stats.DurationMS = time.Since(start).Milliseconds()
+	logger().Info("scan complete",
+		slog.String("home_dir", result.HomeDir),
+		slog.Int("keys", len(result.Keys)),
+		slog.Int("config_instances", len(result.ConfigInstances)),
+		slog.Int64("files_examined", result.Stats.FilesExamined),
+		slog.Duration("duration", result.Stats.Duration()))
+
+	if level != RedactionNone {
+		return result.Redact(level)
+	}
+	return result
 }
 
 // Version returns the library version
@@ -132,46 +428,81 @@ func Version() string {
 
 // ListProviders returns a list of available provider plugins
 func ListProviders() []string {
-	// Call the FFI function to get the list of providers
-	providersPtr := C.aicred_list_providers()
-	if providersPtr == nil {
-		// If FFI is not available, return empty slice to avoid misleading consumers
-		return []string{}
-	}
-	defer C.aicred_free(providersPtr)
-
-	// Convert C string to Go string
-	providersJSON := C.GoString(providersPtr)
-
-	// Parse JSON array
 	var providers []string
-	if err := json.Unmarshal([]byte(providersJSON), &providers); err != nil {
-		// If parsing fails, return empty slice
+	if err := decodeEnvelope("list providers", C.aicred_list_providers_envelope(), &providers); err != nil {
+		// Return an empty slice rather than a misleading partial list
+		logger().Warn("failed to list providers", slog.String("error", err.Error()))
 		return []string{}
 	}
-
 	return providers
 }
 
 // ListScanners returns a list of available application scanners
 func ListScanners() []string {
-	// Call the FFI function to get the list of scanners
-	scannersPtr := C.aicred_list_scanners()
-	if scannersPtr == nil {
-		// If FFI is not available, return empty slice to avoid misleading consumers
+	var scanners []string
+	if err := decodeEnvelope("list scanners", C.aicred_list_scanners_envelope(), &scanners); err != nil {
+		// Return an empty slice rather than a misleading partial list
+		logger().Warn("failed to list scanners", slog.String("error", err.Error()))
 		return []string{}
 	}
-	defer C.aicred_free(scannersPtr)
+	return scanners
+}
 
-	// Convert C string to Go string
-	scannersJSON := C.GoString(scannersPtr)
+// envelopeError is the error half of a result envelope
+type envelopeError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
 
-	// Parse JSON array
-	var scanners []string
-	if err := json.Unmarshal([]byte(scannersJSON), &scanners); err != nil {
-		// If parsing fails, return empty slice
-		return []string{}
+// envelope is the per-call result wrapper returned by the *_envelope FFI functions
+type envelope struct {
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result"`
+	Error  *envelopeError  `json:"error"`
+}
+
+// decodeEnvelope frees ptr and decodes its result into out, returning the
+// call's own error if it failed
+func decodeEnvelope(op string, ptr *C.char, out any) error {
+	if ptr == nil {
+		return fmt.Errorf("%s failed: FFI returned null", op)
 	}
+	defer C.aicred_free(ptr)
+	return decodeEnvelopeBytes(op, []byte(C.GoString(ptr)), EncodingJSON, out)
+}
 
-	return scanners
+// decodeEnvelopeBytes decodes an envelope in the given encoding
+func decodeEnvelopeBytes(op string, data []byte, enc Encoding, out any) error {
+	logger().Debug("FFI returned", slog.String("op", op), slog.String("encoding", enc.String()), slog.Int("bytes", len(data)))
+
+	var (
+		ok     bool
+		result []byte
+		envErr *envelopeError
+	)
+	switch enc {
+	case EncodingCBOR:
+		var env cborEnvelope
+		if err := cbor.Unmarshal(data, &env); err != nil {
+			return fmt.Errorf("%w: failed to parse %s response: %v", ErrParse, op, err)
+		}
+		ok, result, envErr = env.OK, env.Result, env.Error
+	default:
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return fmt.Errorf("%w: failed to parse %s response: %v", ErrParse, op, err)
+		}
+		ok, result, envErr = env.OK, env.Result, env.Error
+	}
+
+	if !ok {
+		if envErr == nil {
+			return &Error{Op: op, Code: CodeUnknown, Message: "unknown error"}
+		}
+		return &Error{Op: op, Code: envErr.Code, Message: envErr.Message}
+	}
+	if err := enc.unmarshal(result, out); err != nil {
+		return fmt.Errorf("%w: failed to parse %s result: %v", ErrParse, op, err)
+	}
+	return nil
 }
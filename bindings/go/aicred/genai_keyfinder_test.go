@@ -1,7 +1,10 @@
 package aicred
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"testing"
 )
@@ -160,6 +163,31 @@ func TestScanInvalidHome(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for invalid home directory")
 	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	tests := []struct {
+		code ErrorCode
+		want error
+	}{
+		{CodeNotFound, ErrNotFound},
+		{CodeParse, ErrParse},
+		{CodeIO, ErrIO},
+		{CodePermissionDenied, ErrPermissionDenied},
+	}
+	for _, tt := range tests {
+		var err error = &Error{Op: "scan", Code: tt.code, Message: "boom"}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("code %d should match %v", tt.code, tt.want)
+		}
+	}
+	err := &Error{Op: "scan", Code: CodeUnknown, Message: "boom"}
+	if errors.Is(err, ErrNotFound) || err.Error() != "FFI scan failed: boom" {
+		t.Errorf("unexpected unknown error behaviour: %v", err)
+	}
 }
 
 func TestScanWithFullValues(t *testing.T) {
@@ -200,3 +228,90 @@ func TestConcurrentScans(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestScanStats(t *testing.T) {
+	home := t.TempDir()
+	good := zipBytes(t, map[string][]byte{".env": []byte(archiveKey)})
+	writeFile(t, filepath.Join(home, "backup.zip"), good)
+	writeFile(t, filepath.Join(home, "broken.zip"), []byte("not a zip"))
+	writeFile(t, filepath.Join(home, ".config", "app", "big.db"), make([]byte, 2000))
+
+	// The Go passes add to what the core library reports for the same home
+	baseline, err := Scan(ScanOptions{HomeDir: home, MaxFileSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Scan(ScanOptions{HomeDir: home, ScanArchives: true, ScanBinaryConfigs: true, MaxFileSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, stats := baseline.Stats, result.Stats
+	if got := stats.FilesExamined - base.FilesExamined; got != 2 {
+		t.Errorf("FilesExamined grew by %d, want 2 archives", got)
+	}
+	if got, want := stats.BytesRead-base.BytesRead, int64(len(good)+len("not a zip")); got != want {
+		t.Errorf("BytesRead grew by %d, want %d", got, want)
+	}
+	if got := stats.SkippedBySize - base.SkippedBySize; got != 1 {
+		t.Errorf("SkippedBySize grew by %d, want 1 database", got)
+	}
+	if stats.ErrorsByScanner[passArchives] != 1 || stats.ErrorsByScanner[passBinaryConfigs] != 0 {
+		t.Errorf("ErrorsByScanner = %v", stats.ErrorsByScanner)
+	}
+	for scanner, n := range base.ErrorsByScanner {
+		if stats.ErrorsByScanner[scanner] != n {
+			t.Errorf("ErrorsByScanner[%s] = %d, baseline %d", scanner, stats.ErrorsByScanner[scanner], n)
+		}
+	}
+	for _, scanner := range []string{passArchives, passBinaryConfigs} {
+		if _, ok := stats.DurationMSByScanner[scanner]; !ok {
+			t.Errorf("DurationMSByScanner = %v, missing %s", stats.DurationMSByScanner, scanner)
+		}
+	}
+	if stats.Duration() < 0 {
+		t.Errorf("Duration = %v", stats.Duration())
+	}
+}
+
+func TestScanWarnings(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, "broken.zip"), []byte("not a zip"))
+	writeFile(t, filepath.Join(home, ".config", "app", "big.db"), make([]byte, 2000))
+
+	result, err := Scan(ScanOptions{HomeDir: home, ScanArchives: true, ScanBinaryConfigs: true, MaxFileSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the Go passes' warnings are checked; the core library reports
+	// its own
+	var got []ScanWarning
+	for _, w := range result.Warnings {
+		if w.Scanner != passArchives && w.Scanner != passBinaryConfigs {
+			continue
+		}
+		if w.Message == "" {
+			t.Errorf("warning without a message: %+v", w)
+		}
+		w.Message = ""
+		got = append(got, w)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+	if len(got) != 2 ||
+		got[0] != (ScanWarning{Scanner: passBinaryConfigs, Path: filepath.Join(home, ".config", "app", "big.db"), Kind: WarningTooLarge}) ||
+		got[1] != (ScanWarning{Scanner: passArchives, Path: filepath.Join(home, "broken.zip"), Kind: WarningParseError}) {
+		t.Errorf("Go-side warnings = %+v", got)
+	}
+}
+
+func TestWarningKindOf(t *testing.T) {
+	_, err := os.Open(filepath.Join(t.TempDir(), "missing"))
+	if kind := warningKindOf(err); kind != WarningUnreadable {
+		t.Errorf("missing file: %s", kind)
+	}
+	if kind := warningKindOf(&os.PathError{Op: "open", Path: "x", Err: os.ErrPermission}); kind != WarningPermissionDenied {
+		t.Errorf("permission error: %s", kind)
+	}
+	if kind := warningKindOf(errors.New("zip: not a valid zip file")); kind != WarningParseError {
+		t.Errorf("format error: %s", kind)
+	}
+}
@@ -0,0 +1,379 @@
+package aicred
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+// GitHub provider types. GitHub Models takes a personal access token with
+// the models scope, or a gh CLI token, as a Bearer key. Copilot takes a
+// short-lived token exchanged for the GitHub OAuth token of a Copilot
+// client; see AuthGitHubCopilot.
+const (
+	ProviderGitHubModels  = "github-models"
+	ProviderGitHubCopilot = "github-copilot"
+)
+
+const (
+	githubModelsURL = "https://models.github.ai/inference"
+	copilotAPIURL   = "https://api.githubcopilot.com"
+	// copilotTokenURL exchanges a GitHub OAuth token for a Copilot token
+	copilotTokenURL = "https://api.github.com/copilot_internal/v2/token"
+	// githubHost is the only host GitHub Models and Copilot tokens are
+	// issued for; tokens for GitHub Enterprise Server hosts are skipped
+	githubHost = "github.com"
+)
+
+func init() {
+	for _, t := range []ProviderType{
+		{Name: ProviderGitHubModels, BaseURLs: []string{githubModelsURL, "https://models.inference.ai.azure.com"}, KeyPattern: regexp.MustCompile(`^(?:github_pat_[A-Za-z0-9_]{20,}|gh[opsu]_[A-Za-z0-9]{20,})$`)},
+		{Name: ProviderGitHubCopilot, BaseURLs: []string{copilotAPIURL, "https://api.individual.githubcopilot.com", "https://api.business.githubcopilot.com", "https://api.enterprise.githubcopilot.com"}, KeyPattern: regexp.MustCompile(`^gh[ou]_[A-Za-z0-9]{20,}$`)},
+	} {
+		providerTypes[t.Name] = t
+	}
+}
+
+// ghHostsFiles are where the gh CLI keeps its logins, relative to the home
+// directory; $GH_CONFIG_DIR replaces them for the current user
+var ghHostsFiles = []string{
+	".config/gh/hosts.yml",
+	"AppData/Roaming/GitHub CLI/hosts.yml",
+}
+
+// copilotDirs are where Copilot editor plugins keep their GitHub logins,
+// in hosts.json and apps.json, relative to the home directory
+var copilotDirs = []string{
+	".config/github-copilot",
+	"AppData/Local/github-copilot",
+}
+
+// githubLogin is a token found in a gh or Copilot configuration file
+type githubLogin struct {
+	host    string
+	user    string
+	token   string
+	line    int
+	pointer string
+}
+
+// scanGitHubCLI reads the gh CLI's hosts.yml and the Copilot plugins'
+// hosts.json and apps.json under home. Each github.com login becomes a
+// config instance: gh logins with a ProviderGitHubModels key and Copilot
+// logins with a ProviderGitHubCopilot key. gh keeps tokens in the system
+// keyring unless told otherwise, so many hosts.yml files hold none.
+func scanGitHubCLI(home string, options ScanOptions, run *scanRun) []ConfigInstance {
+	var ghFiles []string
+	if dir := getenv("GH_CONFIG_DIR"); dir != "" && filepath.IsAbs(dir) && ownHome(home) {
+		ghFiles = append(ghFiles, filepath.Join(dir, "hosts.yml"))
+	}
+	for _, rel := range ghHostsFiles {
+		ghFiles = append(ghFiles, homePath(home, rel))
+	}
+	var copilotFiles []string
+	for _, rel := range copilotDirs {
+		dir := homePath(home, rel)
+		copilotFiles = append(copilotFiles, filepath.Join(dir, "hosts.json"), filepath.Join(dir, "apps.json"))
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	var instances []ConfigInstance
+	seen := map[string]bool{}
+	read := func(files []string, app, provider string, parse func([]byte) ([]githubLogin, error)) {
+		if !providerWanted(provider, options) {
+			return
+		}
+		for _, p := range files {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			data, err := os.ReadFile(p)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					run.warn(passGitHubCLI, p, warningKindOf(err), err)
+				}
+				continue
+			}
+			logins, err := parse(data)
+			zero(data)
+			if err != nil {
+				run.warn(passGitHubCLI, p, WarningParseError, err)
+				continue
+			}
+			for _, login := range logins {
+				if login.host != githubHost || login.token == "" {
+					continue
+				}
+				instances = append(instances, githubConfigInstance(app, provider, p, now, login))
+			}
+		}
+	}
+	read(ghFiles, "gh", ProviderGitHubModels, parseGHHosts)
+	read(copilotFiles, "github-copilot", ProviderGitHubCopilot, parseCopilotHosts)
+	return instances
+}
+
+// ownHome reports whether home is the current user's, to whom environment
+// variables such as $GH_CONFIG_DIR apply
+func ownHome(home string) bool {
+	if own := getenv("HOME"); own != "" && filepath.Clean(own) == filepath.Clean(home) {
+		return true
+	}
+	return isCurrentProfile(home)
+}
+
+func providerWanted(provider string, options ScanOptions) bool {
+	if len(options.OnlyProviders) > 0 && !slices.Contains(options.OnlyProviders, provider) {
+		return false
+	}
+	return !slices.Contains(options.ExcludeProviders, provider)
+}
+
+func githubConfigInstance(app, provider, path, discoveredAt string, login githubLogin) ConfigInstance {
+	sum := sha256.Sum256([]byte(login.token))
+	key := DiscoveredKey{
+		Provider:   provider,
+		Source:     path,
+		ValueType:  "AccessToken",
+		Value:      NewSecretString(login.token),
+		Confidence: detect.ConfidenceVeryHigh,
+		Hash:       hex.EncodeToString(sum[:]),
+		Redacted:   detect.Redact(login.token),
+		Location:   &Location{Path: path, Line: login.line, Pointer: login.pointer},
+	}
+	metadata := map[string]string{"host": login.host}
+	if login.user != "" {
+		metadata["user"] = login.user
+	}
+	return ConfigInstance{
+		InstanceID:   Slugify(app + "-" + login.host + "-" + login.user),
+		AppName:      app,
+		ConfigPath:   path,
+		DiscoveredAt: discoveredAt,
+		Keys:         []DiscoveredKey{key},
+		Metadata:     metadata,
+	}
+}
+
+// parseGHHosts reads the logins in a gh hosts.yml: the oauth_token of each
+// host, and since gh 2.40 those of each account under its users block
+func parseGHHosts(data []byte) ([]githubLogin, error) {
+	type block struct {
+		indent int
+		name   string
+	}
+	var (
+		blocks []block
+		logins []githubLogin
+		// active is each host's active account, which its top-level
+		// oauth_token belongs to
+		active = map[string]string{}
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		a, ok := yamlAssignment(line)
+		if !ok {
+			continue
+		}
+		for len(blocks) > 0 && blocks[len(blocks)-1].indent >= a.indent {
+			blocks = blocks[:len(blocks)-1]
+		}
+		if a.opens {
+			blocks = append(blocks, block{indent: a.indent, name: a.name})
+			continue
+		}
+		path := make([]string, len(blocks))
+		var pointer strings.Builder
+		for j, blk := range blocks {
+			path[j] = blk.name
+			pointer.WriteString("/" + escapePointer(blk.name))
+		}
+		pointer.WriteString("/" + escapePointer(a.name))
+		switch {
+		case len(path) == 1 && a.name == "user":
+			active[path[0]] = a.value
+		case len(path) == 1 && a.name == "oauth_token":
+			logins = append(logins, githubLogin{host: path[0], token: a.value, line: i + 1, pointer: pointer.String()})
+		case len(path) == 3 && path[1] == "users" && a.name == "oauth_token":
+			logins = append(logins, githubLogin{host: path[0], user: path[2], token: a.value, line: i + 1, pointer: pointer.String()})
+		}
+	}
+
+	// The top-level token repeats the active account's
+	var out []githubLogin
+	for _, login := range logins {
+		if login.user == "" {
+			login.user = active[login.host]
+			if slices.ContainsFunc(logins, func(l githubLogin) bool {
+				return l.user != "" && l.host == login.host && l.token == login.token
+			}) {
+				continue
+			}
+		}
+		out = append(out, login)
+	}
+	return out, nil
+}
+
+// parseCopilotHosts reads a Copilot hosts.json, keyed by host, or
+// apps.json, keyed by "host:client ID"
+func parseCopilotHosts(data []byte) ([]githubLogin, error) {
+	var hosts map[string]struct {
+		User       string `json:"user"`
+		OAuthToken string `json:"oauth_token"`
+	}
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, err
+	}
+	var logins []githubLogin
+	for name, h := range hosts {
+		host, _, _ := strings.Cut(name, ":")
+		logins = append(logins, githubLogin{
+			host:    host,
+			user:    h.User,
+			token:   h.OAuthToken,
+			pointer: "/" + escapePointer(name) + "/oauth_token",
+		})
+	}
+	slices.SortFunc(logins, func(a, b githubLogin) int { return strings.Compare(a.pointer, b.pointer) })
+	return logins, nil
+}
+
+// GitHubInstances turns the gh and Copilot logins in a scan made with
+// ScanGitHubCLI and RedactionNone into provider instances the resolver can
+// use, such as through a MemoryStore: a ProviderGitHubModels instance per
+// gh token and a ProviderGitHubCopilot instance, authenticating with
+// AuthGitHubCopilot, per Copilot token. A token found in several files
+// yields one instance. IDs follow GenerateInstanceID, made unique.
+func GitHubInstances(result *ScanResult) []ProviderInstance {
+	var (
+		instances []ProviderInstance
+		taken     []string
+		seen      = map[string]bool{}
+	)
+	for _, ci := range result.ConfigInstances {
+		for _, key := range ci.Keys {
+			if key.Value.IsZero() || seen[key.Provider+"\x00"+key.Hash] {
+				continue
+			}
+			instance := ProviderInstance{
+				ProviderType: key.Provider,
+				APIKey:       key.Value,
+				Capabilities: Capabilities{Chat: true, FunctionCalling: true, Streaming: true},
+				Active:       true,
+				Metadata:     map[string]string{},
+			}
+			switch key.Provider {
+			case ProviderGitHubModels:
+				instance.BaseURL = githubModelsURL
+				instance.Capabilities.Embedding = true
+			case ProviderGitHubCopilot:
+				instance.BaseURL = copilotAPIURL
+				instance.Metadata[MetadataAuth] = AuthGitHubCopilot
+			default:
+				continue
+			}
+			if user := ci.Metadata["user"]; user != "" {
+				instance.Metadata["user"] = user
+			}
+			seen[key.Provider+"\x00"+key.Hash] = true
+			instance.ID = UniqueID(GenerateInstanceID(key.Provider, instance.BaseURL), taken)
+			taken = append(taken, instance.ID)
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
+
+// copilotSource exchanges a GitHub OAuth token for the short-lived token
+// the Copilot API takes, and caches it until shortly before it expires
+type copilotSource struct {
+	tokenURL string
+	oauth    Secret
+	client   *http.Client
+	now      func() time.Time
+
+	mu    sync.Mutex
+	token Token
+}
+
+func newCopilotSource(instance ProviderInstance) (*copilotSource, error) {
+	if instance.APIKey.IsZero() {
+		return nil, fmt.Errorf("instance %q: %s needs the GitHub OAuth token in api_key: %w", instance.ID, AuthGitHubCopilot, ErrInvalidOption)
+	}
+	client, err := HTTPClient(instance)
+	if err != nil {
+		return nil, err
+	}
+	return &copilotSource{
+		tokenURL: firstNonEmpty(instance.Metadata[MetadataTokenURL], copilotTokenURL),
+		oauth:    instance.APIKey,
+		client:   client,
+		now:      time.Now,
+	}, nil
+}
+
+func (s *copilotSource) Token(ctx context.Context) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.Valid(s.now()) {
+		return s.token, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.tokenURL, nil)
+	if err != nil {
+		return Token{}, fmt.Errorf("copilot token request: %w: %v", ErrInvalidOption, err)
+	}
+	req.Header.Set("Authorization", "token "+s.oauth.Reveal())
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("copilot token request: %w: %v", ErrIO, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("copilot token request: %w: %v", ErrIO, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		// GitHub answers 404 for accounts without Copilot
+		return Token{}, fmt.Errorf("copilot token request: %w: %s", ErrPermissionDenied, resp.Status)
+	default:
+		return Token{}, fmt.Errorf("copilot token request: %w: %s", ErrIO, resp.Status)
+	}
+	var reply struct {
+		Token     string `json:"token"`
+		ExpiresAt int64  `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil || reply.Token == "" {
+		return Token{}, fmt.Errorf("copilot token response: %w: no token", ErrParse)
+	}
+	s.token = Token{Value: NewSecretString(reply.Token), Type: "Bearer"}
+	if reply.ExpiresAt > 0 {
+		s.token.Expiry = time.Unix(reply.ExpiresAt, 0)
+	}
+	return s.token, nil
+}
+
+func (s *copilotSource) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := s.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", token.Type+" "+token.Value.Reveal())
+	return nil
+}
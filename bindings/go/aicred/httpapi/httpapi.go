@@ -0,0 +1,341 @@
+// Package httpapi provides an embeddable http.Handler exposing aicred scans,
+// plugin listings and the aicred configuration as JSON endpoints.
+//
+// Endpoints:
+//
+//	GET  /v1/version
+//	GET  /v1/providers
+//	GET  /v1/scanners
+//	POST /v1/scan
+//	GET  /v1/instances
+//	GET  /v1/instances/{id}
+//	GET  /v1/labels
+//	GET  /v1/tags
+//	GET  /v1/models[?label=NAME|?q=SELECTOR]
+//
+// Every request must carry "Authorization: Bearer <token>". Key values are
+// stripped from scan results unless the handler allows full values and the
+// request asks for them. Instances never carry their keys; has_api_key
+// says whether one is set.
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// maxRequestBody bounds the size of a scan request body
+const maxRequestBody = 1 << 20
+
+// Options configures the handler
+type Options struct {
+	// Token is the bearer token clients must present. A handler with no
+	// token rejects every request.
+	Token string
+	// HomeDir is scanned when a request does not name one
+	HomeDir string
+	// AllowHomeDirOverride lets requests choose the directory to scan
+	AllowHomeDirOverride bool
+	// AllowFullValues lets requests ask for unredacted key values
+	AllowFullValues bool
+	// Redaction is the level applied when a request does not choose one,
+	// and the weakest level a request may choose other than "none"
+	Redaction aicred.RedactionLevel
+	// Store holds the instances, labels and tags served by the
+	// configuration endpoints. Nil means a Session over HomeDir, opened on
+	// first use.
+	Store aicred.ConfigStore
+}
+
+// ScanRequest is the body accepted by POST /v1/scan
+type ScanRequest struct {
+	HomeDir string `json:"home_dir,omitempty"`
+	// Redaction names a level: none, partial, hash-only or full
+	Redaction *aicred.RedactionLevel `json:"redaction,omitempty"`
+	// Deprecated: request redaction "none" instead.
+	IncludeFullValues bool     `json:"include_full_values,omitempty"`
+	MaxFileSize       int      `json:"max_file_size,omitempty"`
+	OnlyProviders     []string `json:"only_providers,omitempty"`
+	ExcludeProviders  []string `json:"exclude_providers,omitempty"`
+}
+
+// instanceView is an instance as the API returns it. Its Secret encodes
+// as null, so only HasAPIKey tells whether it has a key.
+type instanceView struct {
+	aicred.ProviderInstance
+	HasAPIKey bool `json:"has_api_key"`
+}
+
+// modelView is one instance/model pair from GET /v1/models
+type modelView struct {
+	InstanceID   string `json:"instance_id"`
+	ProviderType string `json:"provider_type"`
+	BaseURL      string `json:"base_url"`
+	Model        string `json:"model"`
+}
+
+type handler struct {
+	opts Options
+	mux  *http.ServeMux
+	scan func(aicred.ScanOptions) (*aicred.ScanResult, error)
+
+	storeMu sync.Mutex
+	store   aicred.ConfigStore
+}
+
+// NewHandler returns an http.Handler serving the aicred JSON API
+func NewHandler(opts Options) http.Handler {
+	return newHandler(opts, aicred.Scan)
+}
+
+func newHandler(opts Options, scan func(aicred.ScanOptions) (*aicred.ScanResult, error)) *handler {
+	h := &handler{opts: opts, mux: http.NewServeMux(), scan: scan, store: opts.Store}
+	h.mux.HandleFunc("/v1/version", h.get(func() any {
+		return map[string]string{"version": aicred.Version()}
+	}))
+	h.mux.HandleFunc("/v1/providers", h.get(func() any {
+		return map[string][]string{"providers": aicred.ListProviders()}
+	}))
+	h.mux.HandleFunc("/v1/scanners", h.get(func() any {
+		return map[string][]string{"scanners": aicred.ListScanners()}
+	}))
+	h.mux.HandleFunc("/v1/scan", h.handleScan)
+	h.mux.HandleFunc("/v1/instances", h.getConfig(func(store aicred.ConfigStore, r *http.Request) (any, error) {
+		instances, err := store.LoadInstances()
+		views := make([]instanceView, 0, len(instances))
+		for _, instance := range instances {
+			views = append(views, viewInstance(instance))
+		}
+		return map[string][]instanceView{"instances": views}, err
+	}))
+	h.mux.HandleFunc("/v1/instances/{id}", h.getConfig(func(store aicred.ConfigStore, r *http.Request) (any, error) {
+		instance, err := store.GetInstance(r.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		return viewInstance(*instance), nil
+	}))
+	h.mux.HandleFunc("/v1/labels", h.getConfig(func(store aicred.ConfigStore, r *http.Request) (any, error) {
+		labels, err := store.LoadLabels()
+		if labels == nil {
+			labels = []aicred.LabelAssignment{}
+		}
+		return map[string][]aicred.LabelAssignment{"labels": labels}, err
+	}))
+	h.mux.HandleFunc("/v1/tags", h.getConfig(func(store aicred.ConfigStore, r *http.Request) (any, error) {
+		tags, err := store.LoadTags()
+		if tags == nil {
+			tags = []aicred.Tag{}
+		}
+		return map[string][]aicred.Tag{"tags": tags}, err
+	}))
+	h.mux.HandleFunc("/v1/models", h.getConfig(h.models))
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="aicred"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorized checks the bearer token in constant time
+func (h *handler) authorized(r *http.Request) bool {
+	if h.opts.Token == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.Token)) == 1
+}
+
+func (h *handler) get(body func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, body())
+	}
+}
+
+// getConfig serves a GET endpoint that reads the configuration store
+func (h *handler) getConfig(body func(aicred.ConfigStore, *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		store, err := h.configStore()
+		if err == nil {
+			var out any
+			if out, err = body(store, r); err == nil {
+				writeJSON(w, http.StatusOK, out)
+				return
+			}
+		}
+		switch {
+		case errors.Is(err, aicred.ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.As(err, new(badRequest)):
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, "reading configuration failed: "+err.Error())
+		}
+	}
+}
+
+// badRequest is an error in what the client sent, rather than in reading
+// the store
+type badRequest struct{ error }
+
+// models lists instance/model pairs: all of them, those a label applies
+// to, or those a selector query matches
+func (h *handler) models(store aicred.ConfigStore, r *http.Request) (any, error) {
+	query := r.URL.Query()
+	var selector *aicred.Selector
+	if query.Has("q") {
+		var err error
+		if selector, err = aicred.ParseSelector(query.Get("q")); err != nil {
+			return nil, badRequest{err}
+		}
+	}
+	config, err := aicred.CurrentConfig(store)
+	if err != nil {
+		return nil, err
+	}
+
+	var selections []aicred.Selection
+	switch {
+	case selector != nil:
+		selections = selector.SelectConfig(config)
+	case query.Has("label"):
+		selections = aicred.ResolveLabel(config.Instances, config.Labels, query.Get("label"))
+	default:
+		for _, instance := range config.Instances {
+			for _, model := range instance.Models {
+				selections = append(selections, aicred.Selection{Instance: instance, Model: model})
+			}
+		}
+	}
+	models := make([]modelView, 0, len(selections))
+	for _, sel := range selections {
+		models = append(models, modelView{InstanceID: sel.Instance.ID, ProviderType: sel.Instance.ProviderType, BaseURL: sel.Instance.BaseURL, Model: sel.Model})
+	}
+	return map[string][]modelView{"models": models}, nil
+}
+
+// configStore returns Options.Store, or opens a Session over HomeDir the
+// first time one is needed. A failed open is retried on the next request.
+func (h *handler) configStore() (aicred.ConfigStore, error) {
+	h.storeMu.Lock()
+	defer h.storeMu.Unlock()
+	if h.store == nil {
+		session, err := aicred.OpenSession(h.opts.HomeDir)
+		if err != nil {
+			return nil, err
+		}
+		h.store = session
+	}
+	return h.store, nil
+}
+
+func viewInstance(instance aicred.ProviderInstance) instanceView {
+	return instanceView{ProviderInstance: instance, HasAPIKey: !instance.APIKey.IsZero()}
+}
+
+func (h *handler) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req ScanRequest
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	homeDir := h.opts.HomeDir
+	if req.HomeDir != "" {
+		if !h.opts.AllowHomeDirOverride {
+			writeError(w, http.StatusForbidden, "home_dir override is not allowed")
+			return
+		}
+		homeDir = req.HomeDir
+	}
+	level := h.opts.Redaction
+	switch {
+	case req.Redaction != nil:
+		level = *req.Redaction
+	case req.IncludeFullValues:
+		level = aicred.RedactionNone
+	}
+	if level == aicred.RedactionNone {
+		if !h.opts.AllowFullValues {
+			writeError(w, http.StatusForbidden, "full values are not allowed")
+			return
+		}
+	} else if !level.AtLeast(h.opts.Redaction) {
+		writeError(w, http.StatusForbidden, "redaction level "+level.String()+" is weaker than this server allows")
+		return
+	}
+
+	result, err := h.scan(aicred.ScanOptions{
+		HomeDir:          homeDir,
+		Redaction:        level,
+		MaxFileSize:      req.MaxFileSize,
+		OnlyProviders:    req.OnlyProviders,
+		ExcludeProviders: req.ExcludeProviders,
+	})
+	if err != nil {
+		if errors.Is(err, aicred.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "scan failed: "+err.Error())
+		return
+	}
+	if level == aicred.RedactionNone {
+		body, err := result.MarshalWithSecrets()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "encoding result: "+err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(append(body, '\n'))
+		return
+	}
+	writeJSON(w, http.StatusOK, result.Redact(level))
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
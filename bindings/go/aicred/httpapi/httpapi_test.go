@@ -0,0 +1,210 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func fakeScan(opts aicred.ScanOptions) (*aicred.ScanResult, error) {
+	if opts.HomeDir == "/missing" {
+		return nil, fmt.Errorf("invalid HomeDir: /missing: %w", aicred.ErrNotFound)
+	}
+	key := aicred.DiscoveredKey{Provider: "openai", Redacted: "sk-****abcd", Hash: "h-abcd", Value: aicred.NewSecretString("sk-secret")}
+	return &aicred.ScanResult{
+		Keys:            []aicred.DiscoveredKey{key},
+		ConfigInstances: []aicred.ConfigInstance{{InstanceID: "i1", Keys: []aicred.DiscoveredKey{key}}},
+		HomeDir:         opts.HomeDir,
+	}, nil
+}
+
+func do(t *testing.T, h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuth(t *testing.T) {
+	h := newHandler(Options{Token: "s3cret"}, fakeScan)
+
+	if rec := do(t, h, http.MethodGet, "/v1/version", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/v1/version", "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/v1/version", "s3cret", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 with token, got %d", rec.Code)
+	}
+
+	open := newHandler(Options{}, fakeScan)
+	if rec := do(t, open, http.MethodGet, "/v1/version", "anything", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("handler without a token should reject requests, got %d", rec.Code)
+	}
+}
+
+func TestListings(t *testing.T) {
+	h := newHandler(Options{Token: "t"}, fakeScan)
+
+	rec := do(t, h, http.MethodGet, "/v1/providers", "t", "")
+	var body map[string][]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body["providers"]) == 0 {
+		t.Error("expected providers")
+	}
+	if rec := do(t, h, http.MethodPost, "/v1/scanners", "t", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestScanRedactsByDefault(t *testing.T) {
+	h := newHandler(Options{Token: "t", HomeDir: "/srv/home"}, fakeScan)
+
+	rec := do(t, h, http.MethodPost, "/v1/scan", "t", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "sk-secret") {
+		t.Errorf("response leaked a full value: %s", rec.Body)
+	}
+	var result aicred.ScanResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.HomeDir != "/srv/home" || len(result.Keys) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestScanPermissions(t *testing.T) {
+	h := newHandler(Options{Token: "t"}, fakeScan)
+	if rec := do(t, h, http.MethodPost, "/v1/scan", "t", `{"home_dir":"/x"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for home_dir override, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/v1/scan", "t", `{"include_full_values":true}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for full values, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/v1/scan", "t", `{"bogus":1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown field, got %d", rec.Code)
+	}
+
+	allowed := newHandler(Options{Token: "t", AllowHomeDirOverride: true, AllowFullValues: true}, fakeScan)
+	rec := do(t, allowed, http.MethodPost, "/v1/scan", "t", `{"include_full_values":true}`)
+	if !strings.Contains(rec.Body.String(), "sk-secret") {
+		t.Errorf("expected full value when allowed: %s", rec.Body)
+	}
+	if rec := do(t, allowed, http.MethodPost, "/v1/scan", "t", `{"home_dir":"/missing"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid home, got %d", rec.Code)
+	}
+}
+
+func TestScanRedactionLevels(t *testing.T) {
+	h := newHandler(Options{Token: "t", Redaction: aicred.RedactionHashOnly}, fakeScan)
+
+	rec := do(t, h, http.MethodPost, "/v1/scan", "t", "")
+	if body := rec.Body.String(); strings.Contains(body, "sk-****abcd") || !strings.Contains(body, "h-abcd") {
+		t.Errorf("expected hash-only output by default: %s", body)
+	}
+	rec = do(t, h, http.MethodPost, "/v1/scan", "t", `{"redaction":"full"}`)
+	if body := rec.Body.String(); rec.Code != http.StatusOK || strings.Contains(body, "h-abcd") {
+		t.Errorf("expected fully redacted output: %d %s", rec.Code, body)
+	}
+	if rec := do(t, h, http.MethodPost, "/v1/scan", "t", `{"redaction":"partial"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for weaker level, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/v1/scan", "t", `{"redaction":"none"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for none without AllowFullValues, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/v1/scan", "t", `{"redaction":"bogus"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown level, got %d", rec.Code)
+	}
+}
+
+func TestConfigEndpoints(t *testing.T) {
+	store := aicred.NewMemoryStore([]aicred.ProviderInstance{
+		{ID: "groq", ProviderType: "groq", BaseURL: "https://api.groq.com/openai/v1", Models: []string{"llama-3.1-8b"}},
+		{ID: "openai", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", APIKey: aicred.NewSecretString("sk-secret"), Models: []string{"gpt-4o", "gpt-4o-mini"}},
+	}, []aicred.LabelAssignment{
+		{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetModel, InstanceID: "openai", ModelID: "gpt-4o-mini"}},
+	})
+	store.SaveTags([]aicred.Tag{{Name: "prod"}})
+	h := newHandler(Options{Token: "t", Store: store}, fakeScan)
+
+	get := func(path string, want int, out any) {
+		t.Helper()
+		rec := do(t, h, http.MethodGet, path, "t", "")
+		if rec.Code != want {
+			t.Fatalf("GET %s = %d, want %d: %s", path, rec.Code, want, rec.Body)
+		}
+		if strings.Contains(rec.Body.String(), "sk-secret") {
+			t.Errorf("GET %s leaked a key: %s", path, rec.Body)
+		}
+		if out != nil {
+			if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var instances struct {
+		Instances []struct {
+			ID        string `json:"id"`
+			HasAPIKey bool   `json:"has_api_key"`
+		} `json:"instances"`
+	}
+	get("/v1/instances", http.StatusOK, &instances)
+	if len(instances.Instances) != 2 || instances.Instances[0].HasAPIKey || !instances.Instances[1].HasAPIKey {
+		t.Errorf("instances = %+v", instances)
+	}
+	var instance map[string]any
+	get("/v1/instances/openai", http.StatusOK, &instance)
+	if instance["id"] != "openai" || instance["has_api_key"] != true {
+		t.Errorf("instance = %v", instance)
+	}
+	get("/v1/instances/missing", http.StatusNotFound, nil)
+
+	var labels map[string][]aicred.LabelAssignment
+	get("/v1/labels", http.StatusOK, &labels)
+	if len(labels["labels"]) != 1 {
+		t.Errorf("labels = %v", labels)
+	}
+	var tags map[string][]aicred.Tag
+	get("/v1/tags", http.StatusOK, &tags)
+	if len(tags["tags"]) != 1 || tags["tags"][0].Name != "prod" {
+		t.Errorf("tags = %v", tags)
+	}
+
+	var models map[string][]modelView
+	get("/v1/models", http.StatusOK, &models)
+	if len(models["models"]) != 3 {
+		t.Errorf("all models = %v", models)
+	}
+	get("/v1/models?label=fast", http.StatusOK, &models)
+	if m := models["models"]; len(m) != 1 || m[0].Model != "gpt-4o-mini" || m[0].InstanceID != "openai" {
+		t.Errorf("label fast = %v", m)
+	}
+	get("/v1/models?q=provider%3Dgroq", http.StatusOK, &models)
+	if m := models["models"]; len(m) != 1 || m[0].Model != "llama-3.1-8b" {
+		t.Errorf("provider=groq = %v", m)
+	}
+	get("/v1/models?q=provider%3D", http.StatusBadRequest, nil)
+
+	if rec := do(t, h, http.MethodPost, "/v1/tags", "t", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /v1/tags = %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/v1/instances", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /v1/instances without a token = %d", rec.Code)
+	}
+}
@@ -0,0 +1,224 @@
+package aicred
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// HFToken is what a Hugging Face token can do. Type comes from the token
+// itself where its prefix tells; the rest is known only once the Hub has
+// been asked with HFTokenOptions.Validate.
+type HFToken struct {
+	// Type is "read", "write" or "fine-grained", "org" for a legacy
+	// api_org_ token, or "" when not yet known
+	Type string `json:"type,omitempty"`
+	// Name is the name the owner gave the token
+	Name string `json:"name,omitempty"`
+	// User is the account the token belongs to, and Orgs the
+	// organizations that account is a member of
+	User string   `json:"user,omitempty"`
+	Orgs []string `json:"orgs,omitempty"`
+	// Scopes are a fine-grained token's permissions, such as
+	// "inference.serverless.write"; those limited to one user or
+	// organization are prefixed with it, as in "org/acme:repo.write"
+	Scopes []string `json:"scopes,omitempty"`
+	// Validated reports whether the Hub was asked about the token, and
+	// Rejected whether it refused it as invalid or revoked
+	Validated bool `json:"validated,omitempty"`
+	Rejected  bool `json:"rejected,omitempty"`
+}
+
+// CanWrite reports whether the token can change repositories or settings,
+// as write, org and fine-grained tokens with a write permission can.
+// Calling inference endpoints does not count.
+func (t *HFToken) CanWrite() bool {
+	if t == nil || t.Rejected {
+		return false
+	}
+	switch t.Type {
+	case "write", "org":
+		return true
+	}
+	for _, scope := range t.Scopes {
+		_, permission, _ := strings.Cut(scope, ":")
+		if permission == "" {
+			permission = scope
+		}
+		if strings.HasSuffix(permission, ".write") && !strings.HasPrefix(permission, "inference.") {
+			return true
+		}
+	}
+	return false
+}
+
+// HFTokenOptions says how InspectHFTokens looks at tokens
+type HFTokenOptions struct {
+	// Validate sends each token to the Hub's whoami API to learn its type,
+	// owner and scopes. It is off by default because it discloses the
+	// token to Hugging Face and the network in between.
+	Validate bool
+	// Endpoint is the Hub's base URL; "" means $HF_ENDPOINT or
+	// https://huggingface.co
+	Endpoint string
+	Client   *http.Client
+}
+
+// InspectHFTokens sets HFToken on every Hugging Face token in the result,
+// including those of its config instances, and with options.Validate asks
+// the Hub about each distinct token once. The result must come from a scan
+// with RedactionNone; keys without a value are left alone.
+//
+// The returned error joins the failures to reach the Hub. A token the Hub
+// rejects is not an error; it is marked Rejected.
+func InspectHFTokens(ctx context.Context, result *ScanResult, options HFTokenOptions) error {
+	options.Endpoint = strings.TrimSuffix(firstNonEmpty(options.Endpoint, os.Getenv("HF_ENDPOINT"), "https://huggingface.co"), "/")
+	if options.Client == nil {
+		options.Client = http.DefaultClient
+	}
+
+	var errs []error
+	// seen holds what the Hub said about each token, by value hash
+	seen := map[string]*HFToken{}
+	inspect := func(key *DiscoveredKey) {
+		if !isHFToken(*key) {
+			return
+		}
+		if token, ok := seen[key.Hash]; ok && key.Hash != "" {
+			key.HFToken = copyHFToken(token)
+			return
+		}
+		token := parseHFToken(key.Value)
+		if options.Validate {
+			if err := whoami(ctx, &options, key.Value, token); err != nil {
+				errs = append(errs, fmt.Errorf("huggingface key in %s: %w", key.Source, err))
+			}
+		}
+		seen[key.Hash] = token
+		key.HFToken = copyHFToken(token)
+	}
+	for i := range result.Keys {
+		inspect(&result.Keys[i])
+	}
+	for i := range result.ConfigInstances {
+		for j := range result.ConfigInstances[i].Keys {
+			inspect(&result.ConfigInstances[i].Keys[j])
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isHFToken reports whether key holds a Hugging Face token to inspect
+func isHFToken(key DiscoveredKey) bool {
+	if key.Value.IsZero() {
+		return false
+	}
+	if strings.EqualFold(key.Provider, "huggingface") {
+		return true
+	}
+	var prefixed bool
+	key.Value.Use(func(b []byte) { prefixed = hasHFPrefix(b) })
+	return prefixed
+}
+
+func hasHFPrefix(value []byte) bool {
+	return bytes.HasPrefix(value, []byte("hf_")) || bytes.HasPrefix(value, []byte("api_org_"))
+}
+
+// parseHFToken reads what the token's prefix tells: user access tokens all
+// start with hf_ whatever their type, while legacy organization API tokens
+// start with api_org_
+func parseHFToken(value Secret) *HFToken {
+	token := &HFToken{}
+	value.Use(func(b []byte) {
+		if bytes.HasPrefix(b, []byte("api_org_")) {
+			token.Type = "org"
+		}
+	})
+	return token
+}
+
+// whoamiResponse is the part of /api/whoami-v2 InspectHFTokens reads
+type whoamiResponse struct {
+	Name string `json:"name"`
+	Orgs []struct {
+		Name string `json:"name"`
+	} `json:"orgs"`
+	Auth struct {
+		AccessToken struct {
+			DisplayName string `json:"displayName"`
+			Role        string `json:"role"`
+			FineGrained struct {
+				Global []string `json:"global"`
+				Scoped []struct {
+					Entity struct {
+						Type string `json:"type"`
+						Name string `json:"name"`
+					} `json:"entity"`
+					Permissions []string `json:"permissions"`
+				} `json:"scoped"`
+			} `json:"fineGrained"`
+		} `json:"accessToken"`
+	} `json:"auth"`
+}
+
+// whoami asks the Hub who token belongs to and what it may do, filling in
+// token
+func whoami(ctx context.Context, options *HFTokenOptions, value Secret, token *HFToken) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, options.Endpoint+"/api/whoami-v2", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	value.Use(func(b []byte) { req.Header.Set("Authorization", "Bearer "+string(b)) })
+	resp, err := options.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("huggingface: %w: %v", ErrIO, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		token.Validated = true
+		token.Rejected = true
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("huggingface whoami: %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+
+	var who whoamiResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&who); err != nil {
+		return fmt.Errorf("huggingface whoami: %w: %v", ErrParse, err)
+	}
+	token.Validated = true
+	token.User = who.Name
+	for _, org := range who.Orgs {
+		token.Orgs = append(token.Orgs, org.Name)
+	}
+	access := who.Auth.AccessToken
+	token.Name = access.DisplayName
+	if access.Role == "fineGrained" {
+		token.Type = "fine-grained"
+	} else if access.Role != "" {
+		token.Type = access.Role
+	}
+	token.Scopes = append(token.Scopes, access.FineGrained.Global...)
+	for _, scoped := range access.FineGrained.Scoped {
+		for _, permission := range scoped.Permissions {
+			token.Scopes = append(token.Scopes, fmt.Sprintf("%s/%s:%s", scoped.Entity.Type, scoped.Entity.Name, permission))
+		}
+	}
+	return nil
+}
+
+func copyHFToken(token *HFToken) *HFToken {
+	c := *token
+	c.Orgs = append([]string(nil), token.Orgs...)
+	c.Scopes = append([]string(nil), token.Scopes...)
+	return &c
+}
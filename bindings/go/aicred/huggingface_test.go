@@ -0,0 +1,146 @@
+package aicred
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func hfKey(source, value string) DiscoveredKey {
+	key := fingerprintKey(source, "HF_TOKEN", value)
+	key.Provider = "huggingface"
+	return key
+}
+
+func TestInspectHFTokens(t *testing.T) {
+	var calls int
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/api/whoami-v2" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Header.Get("Authorization") {
+		case "Bearer hf_writeTokenabcdefghijklmnopqrstuv":
+			fmt.Fprint(w, `{"type":"user","name":"alice","orgs":[{"name":"acme"}],"auth":{"type":"access_token","accessToken":{"displayName":"ci","role":"write"}}}`)
+		case "Bearer hf_fineTokenabcdefghijklmnopqrstuvw":
+			fmt.Fprint(w, `{"type":"user","name":"alice","orgs":[],"auth":{"type":"access_token","accessToken":{"displayName":"notebook","role":"fineGrained",
+				"fineGrained":{"global":["inference.serverless.write"],"scoped":[{"entity":{"type":"org","name":"acme"},"permissions":["repo.content.read","repo.write"]}]}}}}`)
+		default:
+			http.Error(w, `{"error":"Invalid credentials in Authorization header"}`, http.StatusUnauthorized)
+		}
+	}))
+	defer hub.Close()
+
+	write := hfKey("/h/.env", "hf_writeTokenabcdefghijklmnopqrstuv")
+	result := &ScanResult{
+		Keys: []DiscoveredKey{
+			write,
+			hfKey("/h/.cache/huggingface/token", "hf_fineTokenabcdefghijklmnopqrstuvw"),
+			hfKey("/h/old.env", "hf_revokedTokenabcdefghijklmnopqrs"),
+			fingerprintKey("/h/.env", "OPENAI_API_KEY", "sk-proj-abcdefghijklmnopqrstuvwx"),
+		},
+		ConfigInstances: []ConfigInstance{{AppName: "langchain", Keys: []DiscoveredKey{write}}},
+	}
+	if err := InspectHFTokens(context.Background(), result, HFTokenOptions{Validate: true, Endpoint: hub.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("hub called %d times, want once per distinct token", calls)
+	}
+
+	w := result.Keys[0].HFToken
+	if w == nil || w.Type != "write" || w.User != "alice" || !slices.Equal(w.Orgs, []string{"acme"}) || w.Name != "ci" || !w.CanWrite() {
+		t.Errorf("write token = %+v", w)
+	}
+	fine := result.Keys[1].HFToken
+	if fine == nil || fine.Type != "fine-grained" || !fine.CanWrite() ||
+		!slices.Equal(fine.Scopes, []string{"inference.serverless.write", "org/acme:repo.content.read", "org/acme:repo.write"}) {
+		t.Errorf("fine-grained token = %+v", fine)
+	}
+	if revoked := result.Keys[2].HFToken; revoked == nil || !revoked.Validated || !revoked.Rejected || revoked.CanWrite() {
+		t.Errorf("revoked token = %+v", revoked)
+	}
+	if result.Keys[3].HFToken != nil {
+		t.Error("openai key was inspected")
+	}
+	if got := result.ConfigInstances[0].Keys[0].HFToken; got == nil || got.Type != "write" {
+		t.Errorf("config instance key = %+v", got)
+	}
+}
+
+func TestInspectHFTokensOffline(t *testing.T) {
+	org := fingerprintKey("/h/.env", "HUGGINGFACE_API_KEY", "api_org_abcdefghijklmnopqrstuvwxyz")
+	org.Provider = "unknown"
+	result := &ScanResult{Keys: []DiscoveredKey{
+		hfKey("/h/.env", "hf_readTokenabcdefghijklmnopqrstuvw"),
+		org,
+		{Provider: "huggingface", Source: "/h/redacted.env", Redacted: "****uvwx"},
+	}}
+	client := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Error("offline inspection reached the network")
+		return nil, errors.New("offline")
+	})}
+	if err := InspectHFTokens(context.Background(), result, HFTokenOptions{Client: client}); err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Keys[0].HFToken; got == nil || got.Type != "" || got.Validated {
+		t.Errorf("hf_ token = %+v", got)
+	}
+	if got := result.Keys[1].HFToken; got == nil || got.Type != "org" || !got.CanWrite() {
+		t.Errorf("api_org_ token = %+v", got)
+	}
+	if result.Keys[2].HFToken != nil {
+		t.Error("key without a value was inspected")
+	}
+}
+
+func TestInspectHFTokensUnreachable(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer hub.Close()
+
+	result := &ScanResult{Keys: []DiscoveredKey{hfKey("/h/.env", "hf_readTokenabcdefghijklmnopqrstuvw")}}
+	err := InspectHFTokens(context.Background(), result, HFTokenOptions{Validate: true, Endpoint: hub.URL})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("err = %v, want the hub's status", err)
+	}
+	if got := result.Keys[0].HFToken; got == nil || got.Validated {
+		t.Errorf("token = %+v, want unvalidated", got)
+	}
+}
+
+func TestHFTokenSeverity(t *testing.T) {
+	result := &ScanResult{Keys: []DiscoveredKey{
+		{Provider: "huggingface", Source: "/work/a.env", Confidence: "Medium", HFToken: &HFToken{Type: "write", Validated: true}},
+		{Provider: "huggingface", Source: "/work/b.env", Confidence: "High", HFToken: &HFToken{Validated: true, Rejected: true}},
+		{Provider: "huggingface", Source: "/work/c.env", Confidence: "Medium", HFToken: &HFToken{Type: "read", Validated: true}},
+	}}
+	t.Setenv("GITHUB_WORKSPACE", "/work")
+	var out bytes.Buffer
+	if err := WriteGitHubAnnotations(&out, result); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	for i, want := range []string{"::error ", "::notice ", "::warning "} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("annotation %d = %q, want %s", i, lines[i], want)
+		}
+	}
+	for i, want := range []string{"critical", "info", "minor"} {
+		if got := gitlabSeverity(result.Keys[i]); got != want {
+			t.Errorf("severity %d = %s, want %s", i, got, want)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
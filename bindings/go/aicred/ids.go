@@ -0,0 +1,99 @@
+package aicred
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxSlugLen keeps IDs, which name the instance's YAML file, well under
+// file name limits
+const maxSlugLen = 64
+
+var versionSegment = regexp.MustCompile(`^v[0-9]+(alpha|beta)?[0-9]*$`)
+
+// Slugify lowercases s and replaces each run of characters other than
+// ASCII letters and digits with a single hyphen, trimming hyphens from the
+// ends. The result is at most 64 bytes and may be empty.
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		hyphen = true
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLen {
+		slug = strings.TrimRight(slug[:maxSlugLen], "-")
+	}
+	return slug
+}
+
+// GenerateInstanceID returns a deterministic ID for an instance of
+// providerType served at baseURL, so the same endpoint gets the same ID
+// on every machine and every import. It is the slug of the provider type,
+// the URL's host and port, and its path without a trailing API version
+// segment such as /v1: "openai" at "https://api.openai.com/v1" is
+// "openai-api-openai-com". An empty or unparseable baseURL yields the
+// provider type alone. Use UniqueID to avoid IDs already taken.
+func GenerateInstanceID(providerType, baseURL string) string {
+	parts := []string{providerType}
+	if u, err := url.Parse(strings.TrimSpace(baseURL)); err == nil && u.Host != "" {
+		parts = append(parts, strings.TrimPrefix(strings.ToLower(u.Host), "www."))
+		segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+		if n := len(segments); n > 0 && versionSegment.MatchString(strings.ToLower(segments[n-1])) {
+			segments = segments[:n-1]
+		}
+		parts = append(parts, segments...)
+	}
+	if id := Slugify(strings.Join(parts, "-")); id != "" {
+		return id
+	}
+	return "instance"
+}
+
+// NewUUID returns a random (version 4) UUID, for IDs that need no meaning
+// and must not collide across machines
+func NewUUID() string {
+	var u [16]byte
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// UniqueID returns base if it is not among taken, and otherwise base with
+// the lowest suffix "-2", "-3", ... that is not
+func UniqueID(base string, taken []string) string {
+	id := base
+	for n := 2; slices.Contains(taken, id); n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// NextInstanceID returns GenerateInstanceID(providerType, baseURL) made
+// unique among the instances in store
+func NextInstanceID(store Store, providerType, baseURL string) (string, error) {
+	instances, err := store.LoadInstances()
+	if err != nil {
+		return "", err
+	}
+	taken := make([]string, len(instances))
+	for i, instance := range instances {
+		taken[i] = instance.ID
+	}
+	return UniqueID(GenerateInstanceID(providerType, baseURL), taken), nil
+}
@@ -0,0 +1,58 @@
+package aicred
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	for in, want := range map[string]string{
+		"OpenAI":                "openai",
+		"  Azure OpenAI (EU)":   "azure-openai-eu",
+		"api.openai.com:8443":   "api-openai-com-8443",
+		"---":                   "",
+		"héllo wörld":           "h-llo-w-rld",
+		strings.Repeat("a", 70): strings.Repeat("a", 64),
+	} {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerateInstanceID(t *testing.T) {
+	for _, tc := range []struct{ provider, baseURL, want string }{
+		{"openai", "https://api.openai.com/v1", "openai-api-openai-com"},
+		{"openai", "https://api.openai.com/v1/", "openai-api-openai-com"},
+		{"anthropic", "https://api.anthropic.com", "anthropic-api-anthropic-com"},
+		{"ollama", "http://localhost:11434", "ollama-localhost-11434"},
+		{"azure", "https://my.openai.azure.com/openai/deployments/gpt4o", "azure-my-openai-azure-com-openai-deployments-gpt4o"},
+		{"gemini", "https://generativelanguage.googleapis.com/v1beta", "gemini-generativelanguage-googleapis-com"},
+		{"openai", "", "openai"},
+		{"", "", "instance"},
+	} {
+		if got := GenerateInstanceID(tc.provider, tc.baseURL); got != tc.want {
+			t.Errorf("GenerateInstanceID(%q, %q) = %q, want %q", tc.provider, tc.baseURL, got, tc.want)
+		}
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, b := NewUUID(), NewUUID()
+	if !re.MatchString(a) || a == b {
+		t.Errorf("NewUUID = %q, %q", a, b)
+	}
+}
+
+func TestNextInstanceID(t *testing.T) {
+	if id := UniqueID("x", nil); id != "x" {
+		t.Errorf("UniqueID = %q", id)
+	}
+	store := NewMemoryStore([]ProviderInstance{{ID: "openai-api-openai-com"}, {ID: "openai-api-openai-com-2"}}, nil)
+	id, err := NextInstanceID(store, "openai", "https://api.openai.com/v1")
+	if err != nil || id != "openai-api-openai-com-3" {
+		t.Errorf("NextInstanceID = %q, %v", id, err)
+	}
+}
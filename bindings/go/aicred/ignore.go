@@ -0,0 +1,147 @@
+package aicred
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the gitignore-style file, read from the root of the
+// scanned home directory, that lists paths the Go-side passes of Scan
+// (archives and binary configs) do not descend into
+const IgnoreFileName = ".aicredignore"
+
+// DefaultIgnorePatterns are applied before .aicredignore and
+// ScanOptions.IgnoreGlobs. They skip dependency trees and caches that hold no
+// user configuration; negate one with "!" to scan it anyway.
+var DefaultIgnorePatterns = []string{
+	".git/",
+	"node_modules/",
+	".venv/",
+	"venv/",
+	"__pycache__/",
+	".cache/",
+	".npm/",
+	".rustup/",
+	".cargo/registry/",
+	"go/pkg/mod/",
+	".gradle/caches/",
+	".m2/repository/",
+	"Library/Caches/",
+	".Trash/",
+	".local/share/Trash/",
+	"*.iso",
+	"*.dmg",
+	"*.vmdk",
+	"*.qcow2",
+}
+
+// ignoreRule is one parsed ignore pattern
+type ignoreRule struct {
+	segments []string
+	anchored bool
+	dirOnly  bool
+	negate   bool
+}
+
+// ignoreMatcher applies ignore rules in order; the last match wins
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// newIgnoreMatcher combines the defaults, home's .aicredignore and
+// options.IgnoreGlobs
+func newIgnoreMatcher(home string, options ScanOptions) *ignoreMatcher {
+	m := &ignoreMatcher{}
+	m.add(DefaultIgnorePatterns)
+	if f, err := os.Open(filepath.Join(home, IgnoreFileName)); err == nil {
+		var lines []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+		m.add(lines)
+	}
+	m.add(options.IgnoreGlobs)
+	return m
+}
+
+// add parses gitignore-style patterns: blank lines and "#" comments are
+// skipped, "!" negates, a trailing "/" matches directories only, and a
+// pattern containing any other "/" is relative to the home directory.
+// "*", "?" and "[...]" match within a path element and "**" across them.
+func (m *ignoreMatcher) add(patterns []string) {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		var r ignoreRule
+		if strings.HasPrefix(p, "!") {
+			r.negate = true
+			p = p[1:]
+		}
+		if strings.HasSuffix(p, "/") {
+			r.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+		if strings.Contains(p, "/") {
+			r.anchored = true
+			p = strings.TrimPrefix(p, "/")
+		}
+		if p == "" {
+			continue
+		}
+		r.segments = strings.Split(p, "/")
+		m.rules = append(m.rules, r)
+	}
+}
+
+// ignored reports whether rel, a slash-separated path relative to the home
+// directory, is excluded
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	if m == nil || rel == "" || rel == "." {
+		return false
+	}
+	segments := strings.Split(rel, "/")
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		var hit bool
+		if r.anchored {
+			hit = matchSegments(r.segments, segments)
+		} else {
+			hit = matchSegments(r.segments, segments[len(segments)-1:])
+		}
+		if hit {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path elements against pattern elements, where "**"
+// matches any number of elements
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	return ok && err == nil && matchSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,64 @@
+package aicred
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := &ignoreMatcher{}
+	m.add([]string{
+		"# comment",
+		"node_modules/",
+		"*.log",
+		"/exports/*.zip",
+		"projects/**/build/",
+		"!keep.log",
+	})
+	for _, tc := range []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", true, true},
+		{"a/b/node_modules", true, true},
+		{"node_modules", false, false},
+		{"debug.log", false, true},
+		{"a/keep.log", false, false},
+		{"exports/x.zip", false, true},
+		{"other/exports/x.zip", false, false},
+		{"projects/build", true, true},
+		{"projects/a/b/build", true, true},
+		{"projects/a/build.zip", false, false},
+		{"config/settings.json", false, false},
+	} {
+		if got := m.ignored(tc.rel, tc.isDir); got != tc.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tc.rel, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestScanArchivesIgnore(t *testing.T) {
+	home := t.TempDir()
+	archive := zipBytes(t, map[string][]byte{".env": []byte(archiveKey)})
+	for _, p := range []string{
+		"backup.zip",
+		"node_modules/pkg/fixture.zip",
+		"old/stale.zip",
+		"tmp/scratch.zip",
+	} {
+		writeFile(t, filepath.Join(home, filepath.FromSlash(p)), archive)
+	}
+	writeFile(t, filepath.Join(home, IgnoreFileName), []byte("old/\n"))
+
+	keys := scanArchives(home, ScanOptions{IgnoreGlobs: []string{"tmp/*.zip"}}, nil)
+	if len(keys) != 1 || keys[0].Source != filepath.Join(home, "backup.zip")+"!.env" {
+		t.Errorf("keys = %+v, want only backup.zip", keys)
+	}
+
+	// Negating a default brings the tree back
+	keys = scanArchives(home, ScanOptions{IgnoreGlobs: []string{"!node_modules/"}}, nil)
+	if len(keys) != 3 {
+		t.Errorf("found %d keys with node_modules included, want 3", len(keys))
+	}
+}
@@ -0,0 +1,176 @@
+package aicred
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+// importProviders maps words in a gitleaks rule ID or trufflehog detector
+// name to aicred providers, most specific first
+var importProviders = []struct{ word, provider string }{
+	{"anthropic", "anthropic"},
+	{"openrouter", "openrouter"},
+	{"groq", "groq"},
+	{"huggingface", "huggingface"},
+	{"hugging-face", "huggingface"},
+	{"openai", "openai"},
+}
+
+// gitleaksFinding is one entry of a gitleaks JSON report
+type gitleaksFinding struct {
+	RuleID      string `json:"RuleID"`
+	File        string `json:"File"`
+	StartLine   int    `json:"StartLine"`
+	StartColumn int    `json:"StartColumn"`
+	Secret      string `json:"Secret"`
+}
+
+// trufflehogFinding is one line of trufflehog's --json output
+type trufflehogFinding struct {
+	DetectorName   string `json:"DetectorName"`
+	Verified       bool   `json:"Verified"`
+	Raw            string `json:"Raw"`
+	Redacted       string `json:"Redacted"`
+	SourceMetadata struct {
+		Data map[string]struct {
+			File string `json:"file"`
+			Line int    `json:"line"`
+		} `json:"Data"`
+	} `json:"SourceMetadata"`
+}
+
+// ImportGitleaks converts a gitleaks JSON report, as written by
+// `gitleaks detect --report-format json`, into a ScanResult, so findings
+// from teams already running gitleaks can be merged into aicred's inventory.
+//
+// Providers are taken from the rule ID, or from the secret's prefix for
+// generic rules. Imported keys never carry values: each keeps the hash and
+// redacted preview Scan would report, or neither when the report was
+// written with --redact. Findings are confidence High.
+func ImportGitleaks(r io.Reader) (*ScanResult, error) {
+	var findings []gitleaksFinding
+	if err := json.NewDecoder(r).Decode(&findings); err != nil {
+		return nil, fmt.Errorf("gitleaks report: %w: %v", ErrParse, err)
+	}
+	keys := make([]DiscoveredKey, 0, len(findings))
+	for _, f := range findings {
+		key := importedKey(importProvider(f.RuleID, f.Secret), f.Secret, f.File)
+		key.Confidence = "High"
+		key.Location.Line, key.Location.Column = f.StartLine, f.StartColumn
+		keys = append(keys, key)
+	}
+	return importedResult(keys, nil), nil
+}
+
+// ImportTrufflehog converts trufflehog's --json output, one finding per
+// line, into a ScanResult. Lines that are not findings, such as log output
+// captured with them, become warnings rather than errors.
+//
+// Providers are taken from the detector name, or from the secret's prefix.
+// Imported keys never carry values. Verified findings are confidence
+// VeryHigh and unverified ones Medium.
+func ImportTrufflehog(r io.Reader) (*ScanResult, error) {
+	var (
+		keys     = []DiscoveredKey{}
+		warnings []ScanWarning
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var f trufflehogFinding
+		if err := json.Unmarshal(line, &f); err != nil || f.DetectorName == "" {
+			warnings = append(warnings, ScanWarning{
+				Scanner: "trufflehog",
+				Kind:    WarningParseError,
+				Message: fmt.Sprintf("line %d is not a finding", n),
+			})
+			continue
+		}
+		var file string
+		var lineNo int
+		// Data holds one entry keyed by source type, e.g. Filesystem or Git
+		for _, data := range f.SourceMetadata.Data {
+			file, lineNo = data.File, data.Line
+		}
+		key := importedKey(importProvider(f.DetectorName, f.Raw), f.Raw, file)
+		key.Confidence = "Medium"
+		if f.Verified {
+			key.Confidence = "VeryHigh"
+		}
+		if f.Raw == "" && f.Redacted != "" {
+			key.Redacted = f.Redacted
+		}
+		key.Location.Line = lineNo
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("trufflehog report: %w: %v", ErrIO, err)
+	}
+	return importedResult(keys, warnings), nil
+}
+
+// importProvider maps a rule or detector name to a provider, falling back
+// to the secret's prefix and then to the name itself
+func importProvider(name, secret string) string {
+	lower := strings.ToLower(name)
+	for _, p := range importProviders {
+		if strings.Contains(lower, p.word) {
+			return p.provider
+		}
+	}
+	if matches := detect.Find([]byte(secret)); len(matches) > 0 {
+		return matches[0].Provider
+	}
+	return lower
+}
+
+// importedKey builds a key without a value from a reported secret, which
+// is "" or "REDACTED" when the tool redacted it
+func importedKey(provider, secret, file string) DiscoveredKey {
+	key := DiscoveredKey{
+		Provider:  provider,
+		Source:    file,
+		ValueType: "ApiKey",
+		Location:  &Location{Path: file},
+	}
+	if secret != "" && secret != "REDACTED" {
+		sum := sha256.Sum256([]byte(secret))
+		key.Hash = hex.EncodeToString(sum[:])
+		key.Redacted = detect.Redact(secret)
+	}
+	return key
+}
+
+func importedResult(keys []DiscoveredKey, warnings []ScanWarning) *ScanResult {
+	seen := map[string]bool{}
+	var providers []string
+	for _, k := range keys {
+		if !seen[k.Provider] {
+			seen[k.Provider] = true
+			providers = append(providers, k.Provider)
+		}
+	}
+	sort.Strings(providers)
+	return &ScanResult{
+		SchemaVersion:    ScanResultSchemaVersion,
+		Keys:             keys,
+		ConfigInstances:  []ConfigInstance{},
+		ScannedAt:        time.Now().UTC().Format(time.RFC3339),
+		ProvidersScanned: providers,
+		Warnings:         warnings,
+	}
+}
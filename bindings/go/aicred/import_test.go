@@ -0,0 +1,83 @@
+package aicred
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestImportGitleaks(t *testing.T) {
+	f, err := os.Open("testdata/gitleaks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	result, err := ImportGitleaks(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Keys) != 3 {
+		t.Fatalf("imported %d keys, want 3", len(result.Keys))
+	}
+	openai := result.Keys[0]
+	if openai.Provider != "openai" || openai.Source != "app/.env" || openai.Confidence != "High" ||
+		openai.Redacted != "****6789" || len(openai.Hash) != 64 || !openai.Value.IsZero() {
+		t.Errorf("openai key = %+v", openai)
+	}
+	if l := openai.Location; l == nil || l.Line != 3 || l.Column != 16 {
+		t.Errorf("openai location = %+v", l)
+	}
+	if got := result.Keys[1].Provider; got != "groq" {
+		t.Errorf("generic rule provider = %q, want groq from the prefix", got)
+	}
+	if redacted := result.Keys[2]; redacted.Provider != "anthropic" || redacted.Hash != "" {
+		t.Errorf("redacted key = %+v", redacted)
+	}
+	if got := strings.Join(result.ProvidersScanned, ","); got != "anthropic,groq,openai" {
+		t.Errorf("ProvidersScanned = %s", got)
+	}
+
+	if _, err := ImportGitleaks(strings.NewReader("not json")); !errors.Is(err, ErrParse) {
+		t.Errorf("err = %v, want ErrParse", err)
+	}
+}
+
+func TestImportTrufflehog(t *testing.T) {
+	f, err := os.Open("testdata/trufflehog.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	result, err := ImportTrufflehog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Keys) != 2 || len(result.Warnings) != 1 {
+		t.Fatalf("imported %d keys and %d warnings, want 2 and 1", len(result.Keys), len(result.Warnings))
+	}
+	verified, unverified := result.Keys[0], result.Keys[1]
+	if verified.Provider != "openai" || verified.Confidence != "VeryHigh" || verified.Source != "/srv/app/config.py" || verified.Location.Line != 12 {
+		t.Errorf("verified key = %+v", verified)
+	}
+	if unverified.Provider != "huggingface" || unverified.Confidence != "Medium" || unverified.Source != "src/hf.py" {
+		t.Errorf("unverified key = %+v", unverified)
+	}
+	if w := result.Warnings[0]; w.Scanner != "trufflehog" || w.Kind != WarningParseError {
+		t.Errorf("warning = %+v", w)
+	}
+}
+
+func FuzzImport(f *testing.F) {
+	for _, name := range []string{"testdata/gitleaks.json", "testdata/trufflehog.jsonl"} {
+		if data, err := os.ReadFile(name); err == nil {
+			f.Add(data)
+		}
+	}
+	f.Add([]byte(`[{"Secret":"sk-ant-x","File":"a","StartLine":-1}]`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ImportGitleaks(bytes.NewReader(data))
+		ImportTrufflehog(bytes.NewReader(data))
+	})
+}
@@ -0,0 +1,367 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultMaxInfraStateSize bounds state files read when MaxFileSize is
+// unset; state for a large stack easily exceeds the text config default
+const defaultMaxInfraStateSize = 16 << 20
+
+type infraKind int
+
+const (
+	notInfra infraKind = iota
+	// infraJSON is Terraform state, JSON tfvars and Pulumi checkpoints
+	infraJSON
+	// infraHCL is Terraform .tfvars
+	infraHCL
+	// infraYAML is Pulumi stack configuration, Pulumi.<stack>.yaml
+	infraYAML
+)
+
+// infraKindOf classifies a file by name. Pulumi checkpoints are the JSON
+// files of a local backend, kept under .pulumi/stacks.
+func infraKindOf(p string) infraKind {
+	name := strings.ToLower(filepath.Base(p))
+	switch {
+	case strings.HasSuffix(name, ".tfstate"), strings.HasSuffix(name, ".tfstate.backup"),
+		strings.HasSuffix(name, ".tfvars.json"):
+		return infraJSON
+	case strings.HasSuffix(name, ".tfvars"):
+		return infraHCL
+	case strings.HasPrefix(name, "pulumi.") && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")) &&
+		name != "pulumi.yaml" && name != "pulumi.yml":
+		return infraYAML
+	case (strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.bak")) &&
+		strings.Contains(filepath.ToSlash(p), "/.pulumi/stacks/"):
+		return infraJSON
+	}
+	return notInfra
+}
+
+// genericFields are object keys that hold a value for their parent, such
+// as a Terraform output's "value"; the parent names the value instead
+var genericFields = map[string]bool{
+	"value":           true,
+	"default":         true,
+	"sensitive_value": true,
+	"plaintext":       true,
+}
+
+// scanInfraState walks home for Terraform state and variable files and
+// Pulumi stack files and returns the keys in their values. Each key's
+// Location carries the JSON pointer of the value within its document.
+func scanInfraState(home string, options ScanOptions, run *scanRun) []DiscoveredKey {
+	limit := int64(options.MaxFileSize)
+	if limit <= 0 {
+		limit = defaultMaxInfraStateSize
+	}
+	var paths []string
+	w := newWalker(passInfraState, home, options, run)
+	for _, root := range scanRoots(home) {
+		w.walk(root, 0, func(p string, d fs.DirEntry) {
+			if infraKindOf(p) == notInfra {
+				return
+			}
+			info, err := d.Info()
+			if err != nil {
+				return
+			}
+			if info.Size() > limit {
+				run.warn(passInfraState, p, WarningTooLarge, fmt.Errorf("%d bytes exceeds the limit of %d", info.Size(), limit))
+				return
+			}
+			paths = append(paths, p)
+		})
+	}
+	return run.scanFiles(passInfraState, paths, options, func(p string) []DiscoveredKey {
+		keys, err := scanInfraFile(p, options)
+		if err != nil {
+			run.warn(passInfraState, p, warningKindOf(err), err)
+		}
+		return keys
+	})
+}
+
+// scanInfraFile parses one state or variable file
+func scanInfraFile(p string, options ScanOptions) ([]DiscoveredKey, error) {
+	data, err := os.ReadFile(p)
+	defer zero(data)
+	if err != nil {
+		logger().Debug("skipping unreadable file", slog.String("path", p), slog.String("error", err.Error()))
+		return nil, err
+	}
+	switch infraKindOf(p) {
+	case infraJSON:
+		return jsonDocumentKeys(p, data, options)
+	case infraHCL:
+		return lineDocumentKeys(p, data, options, hclAssignment, false), nil
+	case infraYAML:
+		return lineDocumentKeys(p, data, options, yamlAssignment, true), nil
+	}
+	return nil, nil
+}
+
+// valueKeys runs the detector over one document value. When it finds
+// nothing and the value has a field name, the value is tried again as an
+// assignment to that name, so values under names such as openai_api_key or
+// openaiApiKey are found without a key prefix. Locations are relative to
+// the start of the value.
+func valueKeys(name, field, value string, options ScanOptions) []DiscoveredKey {
+	keys := detectKeys(name, []byte(value), options, true)
+	if len(keys) > 0 || field == "" {
+		return keys
+	}
+	prefix := envName(field) + "="
+	keys = detectKeys(name, []byte(prefix+value), options, true)
+	for i := range keys {
+		keys[i].Location.EnvVar = field
+		keys[i].Location.Offset -= int64(len(prefix))
+	}
+	return keys
+}
+
+// envName converts a field name such as "proj:openaiApiKey" or
+// "openai-api-key" to the environment variable form OPENAI_API_KEY
+func envName(field string) string {
+	if i := strings.LastIndexByte(field, ':'); i >= 0 {
+		field = field[i+1:]
+	}
+	var b strings.Builder
+	var prev rune
+	for _, r := range field {
+		switch {
+		case r == '-' || r == '.' || r == ' ':
+			r = '_'
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		prev = r
+	}
+	return b.String()
+}
+
+// placeKey moves a key located relative to a value at byte offset start in
+// data to its place in the document
+func placeKey(key *DiscoveredKey, data []byte, start int, pointer string) {
+	offset := start + int(key.Location.Offset)
+	key.Location.Offset = int64(offset)
+	key.Location.Line = bytes.Count(data[:offset], []byte{'\n'}) + 1
+	key.Location.Column = offset - bytes.LastIndexByte(data[:offset], '\n')
+	key.Location.Pointer = pointer
+}
+
+// jsonDocumentKeys runs valueKeys over every string in a JSON document
+func jsonDocumentKeys(name string, data []byte, options ScanOptions) ([]DiscoveredKey, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var keys []DiscoveredKey
+	var walk func(pointer, field string) error
+	walk = func(pointer, field string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			if t != '{' && t != '[' {
+				return fmt.Errorf("unexpected %v", t)
+			}
+			for i := 0; dec.More(); i++ {
+				child, childField := pointer+"/"+strconv.Itoa(i), field
+				if t == '{' {
+					tok, err := dec.Token()
+					if err != nil {
+						return err
+					}
+					k, _ := tok.(string)
+					child = pointer + "/" + escapePointer(k)
+					if !genericFields[strings.ToLower(k)] {
+						childField = k
+					}
+				}
+				if err := walk(child, childField); err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		case string:
+			found := valueKeys(name, field, t, options)
+			start, exact := jsonStringStart(data, int(dec.InputOffset()), t)
+			for i := range found {
+				if !exact {
+					found[i].Location.Offset = 0
+				}
+				placeKey(&found[i], data, start, pointer)
+			}
+			keys = append(keys, found...)
+		}
+		return nil
+	}
+	if err := walk("", ""); err != nil {
+		return nil, fmt.Errorf("%s: not valid JSON: %v", name, err)
+	}
+	return keys, nil
+}
+
+// jsonStringStart returns the offset of the first byte inside the JSON
+// string that ends at end, and whether offsets into value map directly onto
+// the raw bytes, which they do unless the string has escapes
+func jsonStringStart(data []byte, end int, value string) (int, bool) {
+	for i := end - 2; i >= 0; i-- {
+		if data[i] != '"' {
+			continue
+		}
+		slashes := 0
+		for j := i - 1; j >= 0 && data[j] == '\\'; j-- {
+			slashes++
+		}
+		if slashes%2 == 0 {
+			return i + 1, end-1-(i+1) == len(value)
+		}
+	}
+	return 0, false
+}
+
+// escapePointer escapes a reference token as RFC 6901 requires
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// assignment is a "name = value" or "name: value" line of a line-oriented
+// document. value is empty for a line that opens a nested block; start is
+// the value's byte offset within the line.
+type assignment struct {
+	indent int
+	name   string
+	value  string
+	start  int
+	// opens reports whether the line opens a block that later lines nest in
+	opens bool
+	// closes counts the blocks the line closes
+	closes int
+}
+
+var (
+	hclLine  = regexp.MustCompile(`^(\s*)"?([A-Za-z_][\w-]*)"?\s*=\s*(.*?)\s*$`)
+	yamlLine = regexp.MustCompile(`^(\s*)("[^"]*"|'[^']*'|[^\s#'"-][^#]*?)\s*:(?:\s+(.*?))?\s*$`)
+)
+
+// hclAssignment parses a tfvars line. Blocks are tracked by braces.
+func hclAssignment(line string) (assignment, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "}" || trimmed == "}," || trimmed == "]" || trimmed == "]," {
+		return assignment{closes: 1}, false
+	}
+	m := hclLine.FindStringSubmatchIndex(line)
+	if m == nil {
+		return assignment{}, false
+	}
+	a := assignment{indent: m[3] - m[2], name: line[m[4]:m[5]], start: m[6]}
+	value := line[m[6]:m[7]]
+	if value == "{" || value == "[" {
+		a.opens = true
+		return a, true
+	}
+	if strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) >= 2 {
+		value = value[1 : len(value)-1]
+		a.start++
+	}
+	a.value = value
+	return a, true
+}
+
+// yamlAssignment parses a YAML mapping line. Blocks are tracked by
+// indentation; sequences and multi-line scalars are not followed.
+func yamlAssignment(line string) (assignment, bool) {
+	if t := strings.TrimSpace(line); t == "" || strings.HasPrefix(t, "#") {
+		return assignment{}, false
+	}
+	m := yamlLine.FindStringSubmatchIndex(line)
+	if m == nil {
+		return assignment{}, false
+	}
+	a := assignment{indent: m[3] - m[2], name: strings.Trim(line[m[4]:m[5]], `"'`)}
+	if m[6] < 0 {
+		a.opens = true
+		return a, true
+	}
+	value, start := line[m[6]:m[7]], m[6]
+	if i := strings.Index(value, " #"); i >= 0 && !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "'") {
+		value = strings.TrimSpace(value[:i])
+	}
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+		start++
+	}
+	a.value, a.start = value, start
+	return a, true
+}
+
+// lineDocumentKeys scans a tfvars or YAML document line by line, giving
+// each key the pointer of the assignment it is in. Blocks nest by
+// indentation when byIndent is set and by the lines that open and close
+// them otherwise. Lines that are not assignments are still searched for
+// prefixed keys.
+func lineDocumentKeys(name string, data []byte, options ScanOptions, parse func(string) (assignment, bool), byIndent bool) []DiscoveredKey {
+	type block struct {
+		indent int
+		name   string
+	}
+	var (
+		keys   []DiscoveredKey
+		blocks []block
+	)
+	pointer := func() string {
+		var b strings.Builder
+		for _, blk := range blocks {
+			b.WriteString("/" + escapePointer(blk.name))
+		}
+		return b.String()
+	}
+	for offset := 0; offset < len(data); {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			end = len(data) - offset
+		}
+		line := strings.TrimSuffix(string(data[offset:offset+end]), "\r")
+
+		a, ok := parse(line)
+		if byIndent && ok {
+			for len(blocks) > 0 && blocks[len(blocks)-1].indent >= a.indent {
+				blocks = blocks[:len(blocks)-1]
+			}
+		}
+		for ; a.closes > 0 && len(blocks) > 0; a.closes-- {
+			blocks = blocks[:len(blocks)-1]
+		}
+		switch {
+		case ok && a.opens:
+			blocks = append(blocks, block{a.indent, a.name})
+		case ok:
+			for _, key := range valueKeys(name, a.name, a.value, options) {
+				placeKey(&key, data, offset+a.start, pointer()+"/"+escapePointer(a.name))
+				keys = append(keys, key)
+			}
+		default:
+			for _, key := range valueKeys(name, "", line, options) {
+				placeKey(&key, data, offset, pointer())
+				keys = append(keys, key)
+			}
+		}
+		offset += end + 1
+	}
+	return keys
+}
@@ -0,0 +1,98 @@
+package aicred
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestScanInfraState(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, "infra", "terraform.tfstate"), []byte(`{
+  "version": 4,
+  "outputs": {
+    "openai_api_key": {"value": "abcdefghijklmnopqrstuvwx0123", "type": "string", "sensitive": true}
+  },
+  "resources": [
+    {"instances": [{"attributes": {"environment": [{"variables": {"LOG": "debug", "TOKEN": "sk-ant-REDACTED"}}]}}]}
+  ]
+}`))
+	writeFile(t, filepath.Join(home, "infra", "prod.tfvars"), []byte("region = \"us-east-1\"\n"+
+		"llm = {\n  groq_api_key = \"abcdefghijklmnopqrstuvwx\"\n}\n"+
+		"hf_token = \"hf_abcdefghijklmnopqrstuvwx\"\n"))
+	writeFile(t, filepath.Join(home, "stack", "Pulumi.dev.yaml"), []byte("config:\n"+
+		"  aws:region: us-west-2\n"+
+		"  app:openaiApiKey: abcdefghijklmnopqrstuvwxyz\n"+
+		"  app:secret:\n    secure: AAABAKmDn\n"))
+	writeFile(t, filepath.Join(home, "stack", "Pulumi.yaml"), []byte("name: app\nopenai: sk-abcdefghijklmnopqrstuvwxyz\n"))
+	writeFile(t, filepath.Join(home, ".pulumi", "stacks", "app", "dev.json"), []byte(`{"checkpoint": {"latest": {"resources": [{"outputs": {"apiKey": "gsk_abcdefghijklmnopqrstuvwx"}}]}}}`))
+	writeFile(t, filepath.Join(home, "broken.tfstate"), []byte(`{"outputs": `))
+
+	run := &scanRun{}
+	keys := scanInfraState(home, ScanOptions{}, run)
+	got := map[string]DiscoveredKey{}
+	var pointers []string
+	for _, key := range keys {
+		got[key.Location.Pointer] = key
+		pointers = append(pointers, key.Location.Pointer)
+	}
+	sort.Strings(pointers)
+	want := []string{
+		"/checkpoint/latest/resources/0/outputs/apiKey",
+		"/config/app:openaiApiKey",
+		"/hf_token",
+		"/llm/groq_api_key",
+		"/outputs/openai_api_key/value",
+		"/resources/0/instances/0/attributes/environment/0/variables/TOKEN",
+	}
+	if len(pointers) != len(want) {
+		t.Fatalf("pointers = %q, want %q", pointers, want)
+	}
+	for i := range want {
+		if pointers[i] != want[i] {
+			t.Errorf("pointer %d = %q, want %q", i, pointers[i], want[i])
+		}
+	}
+
+	if k := got["/outputs/openai_api_key/value"]; k.Provider != "openai" || k.Location.EnvVar != "openai_api_key" || k.Location.Line != 4 || k.Location.Column != 34 {
+		t.Errorf("output key = %+v at %+v", k, k.Location)
+	}
+	if k := got["/llm/groq_api_key"]; k.Provider != "groq" || k.Location.Line != 3 || k.Location.Column != 19 {
+		t.Errorf("tfvars key = %+v at %+v", k, k.Location)
+	}
+	if k := got["/config/app:openaiApiKey"]; k.Provider != "openai" || k.Location.Line != 3 || k.Location.Column != 21 {
+		t.Errorf("pulumi key = %+v at %+v", k, k.Location)
+	}
+	if k := got["/resources/0/instances/0/attributes/environment/0/variables/TOKEN"]; k.Provider != "anthropic" {
+		t.Errorf("resource key = %+v", k)
+	}
+	if len(run.warnings) != 1 || run.warnings[0].Kind != WarningParseError {
+		t.Errorf("warnings = %+v, want one parse error", run.warnings)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for in, want := range map[string]string{
+		"openai_api_key":     "OPENAI_API_KEY",
+		"proj:openaiApiKey":  "OPENAI_API_KEY",
+		"openai-api-key":     "OPENAI_API_KEY",
+		"HF_TOKEN":           "HF_TOKEN",
+		"anthropic.apiKey2x": "ANTHROPIC_API_KEY2X",
+	} {
+		if got := envName(in); got != want {
+			t.Errorf("envName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func FuzzDocumentKeys(f *testing.F) {
+	f.Add([]byte(`{"outputs":{"openai_api_key":{"value":"abcdefghijklmnopqrstuvwx0123"}},"a~b/c":["sk-ant-REDACTED"]}`))
+	f.Add([]byte("config:\n  app:openaiApiKey: sk-proj-abcdefghijklmnopqrstuvwx\n"))
+	f.Add([]byte("openai_api_key = \"sk-proj-abcdefghijklmnopqrstuvwx\"\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		jsonDocumentKeys("fuzz.json", data, ScanOptions{})
+		lineDocumentKeys("fuzz.yaml", data, ScanOptions{}, yamlAssignment, true)
+		lineDocumentKeys("fuzz.tfvars", data, ScanOptions{}, hclAssignment, false)
+		lineDocumentKeys("pip.conf", data, ScanOptions{}, iniAssignment, false)
+	})
+}
@@ -0,0 +1,70 @@
+package aicred
+
+import "time"
+
+// ProviderInstance is a configured provider endpoint from the aicred
+// configuration directory
+type ProviderInstance struct {
+	ID           string            `json:"id"`
+	ProviderType string            `json:"provider_type"`
+	BaseURL      string            `json:"base_url"`
+	APIKey       Secret            `json:"api_key"`
+	Models       []string          `json:"models"`
+	Capabilities Capabilities      `json:"capabilities"`
+	Active       bool              `json:"active"`
+	Metadata     map[string]string `json:"metadata"`
+}
+
+// Capabilities lists what a provider instance supports
+type Capabilities struct {
+	Chat            bool `json:"chat"`
+	Completion      bool `json:"completion"`
+	Embedding       bool `json:"embedding"`
+	ImageGeneration bool `json:"image_generation"`
+	FunctionCalling bool `json:"function_calling"`
+	Streaming       bool `json:"streaming"`
+}
+
+// Names returns the capabilities c has, by their names in the configuration
+// files and selector queries, such as "chat" and "streaming"
+func (c Capabilities) Names() []string {
+	var names []string
+	for _, capability := range []struct {
+		name string
+		on   bool
+	}{
+		{"chat", c.Chat},
+		{"completion", c.Completion},
+		{"embedding", c.Embedding},
+		{"image_generation", c.ImageGeneration},
+		{"function_calling", c.FunctionCalling},
+		{"streaming", c.Streaming},
+	} {
+		if capability.on {
+			names = append(names, capability.name)
+		}
+	}
+	return names
+}
+
+// Label target types
+const (
+	LabelTargetInstance = "provider_instance"
+	LabelTargetModel    = "provider_model"
+)
+
+// LabelTarget is what a label points at: a whole instance, or one model
+// within it when Type is LabelTargetModel
+type LabelTarget struct {
+	Type       string `json:"type"`
+	InstanceID string `json:"instance_id"`
+	ModelID    string `json:"model_id,omitempty"`
+}
+
+// LabelAssignment links a label name such as "fast" to a target
+type LabelAssignment struct {
+	LabelName  string      `json:"label_name"`
+	Target     LabelTarget `json:"target"`
+	AssignedAt time.Time   `json:"assigned_at"`
+	AssignedBy string      `json:"assigned_by,omitempty"`
+}
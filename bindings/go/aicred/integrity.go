@@ -0,0 +1,153 @@
+package aicred
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// IntegrityKind classifies an IntegrityIssue
+type IntegrityKind string
+
+// Integrity issue kinds
+const (
+	// IntegrityDanglingInstance is a label assigned to an instance that
+	// does not exist
+	IntegrityDanglingInstance IntegrityKind = "dangling_instance"
+	// IntegrityDanglingModel is a label assigned to a model its instance
+	// does not list
+	IntegrityDanglingModel IntegrityKind = "dangling_model"
+	// IntegrityDuplicateAssignment is a label assigned to the same target
+	// more than once
+	IntegrityDuplicateAssignment IntegrityKind = "duplicate_assignment"
+	// IntegrityDuplicateInstanceID is an ID shared by several instances
+	IntegrityDuplicateInstanceID IntegrityKind = "duplicate_instance_id"
+)
+
+// IntegrityIssue is one problem found by CheckIntegrity
+type IntegrityIssue struct {
+	Kind    IntegrityKind
+	Message string
+	// Assignment is the offending assignment, nil for instance issues
+	Assignment *LabelAssignment
+	InstanceID string
+	// Repairable reports whether RepairAssignments removes the issue
+	Repairable bool
+}
+
+// CheckIntegrity reports label assignments whose instance or model does not
+// exist, assignments repeated for the same target, and instance IDs used
+// more than once. A model assignment is checked only when its instance
+// lists models, since an empty list means the models are unknown.
+func CheckIntegrity(instances []ProviderInstance, assignments []LabelAssignment) []IntegrityIssue {
+	var issues []IntegrityIssue
+	byID := map[string]*ProviderInstance{}
+	reported := map[string]bool{}
+	for i := range instances {
+		id := instances[i].ID
+		if _, dup := byID[id]; !dup {
+			byID[id] = &instances[i]
+		} else if !reported[id] {
+			reported[id] = true
+			issues = append(issues, IntegrityIssue{
+				Kind:       IntegrityDuplicateInstanceID,
+				Message:    fmt.Sprintf("instance ID %q is used more than once", id),
+				InstanceID: id,
+			})
+		}
+	}
+
+	seen := map[LabelAssignment]bool{}
+	for i := range assignments {
+		a := &assignments[i]
+		issue := IntegrityIssue{Assignment: a, InstanceID: a.Target.InstanceID, Repairable: true}
+		key := LabelAssignment{LabelName: a.LabelName, Target: a.Target}
+		instance, exists := byID[a.Target.InstanceID]
+		switch {
+		case !exists:
+			issue.Kind = IntegrityDanglingInstance
+			issue.Message = fmt.Sprintf("label %q is assigned to missing instance %q", a.LabelName, a.Target.InstanceID)
+		case a.Target.Type == LabelTargetModel && len(instance.Models) > 0 && !slices.Contains(instance.Models, a.Target.ModelID):
+			issue.Kind = IntegrityDanglingModel
+			issue.Message = fmt.Sprintf("label %q is assigned to model %q, which instance %q does not list", a.LabelName, a.Target.ModelID, a.Target.InstanceID)
+		case seen[key]:
+			issue.Kind = IntegrityDuplicateAssignment
+			issue.Message = fmt.Sprintf("label %q is assigned to the same target more than once", a.LabelName)
+		default:
+			seen[key] = true
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// RepairAssignments returns assignments without the dangling and duplicate
+// ones CheckIntegrity reports, keeping the first of each duplicate
+func RepairAssignments(instances []ProviderInstance, assignments []LabelAssignment) []LabelAssignment {
+	drop := map[*LabelAssignment]bool{}
+	for _, issue := range CheckIntegrity(instances, assignments) {
+		if issue.Repairable {
+			drop[issue.Assignment] = true
+		}
+	}
+	repaired := make([]LabelAssignment, 0, len(assignments))
+	for i := range assignments {
+		if !drop[&assignments[i]] {
+			repaired = append(repaired, assignments[i])
+		}
+	}
+	return repaired
+}
+
+// CheckIntegrity checks the session's label assignments against its
+// instances
+func (s *Session) CheckIntegrity() ([]IntegrityIssue, error) {
+	instances, err := s.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.LoadLabels()
+	if err != nil {
+		return nil, err
+	}
+	issues := CheckIntegrity(instances, assignments)
+	s.publishIssues(issues)
+	return issues, nil
+}
+
+// publishIssues reports integrity issues to the session's event bus
+func (s *Session) publishIssues(issues []IntegrityIssue) {
+	if len(issues) == 0 {
+		return
+	}
+	problems := make([]string, len(issues))
+	for i, issue := range issues {
+		problems[i] = issue.Message
+	}
+	s.events.Publish(events.ValidationFailed{Time: time.Now().UTC(), Subject: "labels", Problems: problems})
+}
+
+// RepairIntegrity removes dangling and duplicate label assignments and
+// saves the rest. It returns the issues found; those not Repairable, such
+// as duplicate instance IDs, remain and need fixing by hand.
+func (s *Session) RepairIntegrity() ([]IntegrityIssue, error) {
+	instances, err := s.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.LoadLabels()
+	if err != nil {
+		return nil, err
+	}
+	issues := CheckIntegrity(instances, assignments)
+	s.publishIssues(issues)
+	for _, issue := range issues {
+		if issue.Repairable {
+			return issues, s.SaveLabels(RepairAssignments(instances, assignments))
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,83 @@
+package aicred
+
+import (
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	instances := []ProviderInstance{
+		{ID: "openai-main", Models: []string{"gpt-4o"}},
+		{ID: "ollama-local"},
+		{ID: "openai-main"},
+	}
+	model := func(label, instance, model string) LabelAssignment {
+		return LabelAssignment{LabelName: label, Target: LabelTarget{Type: LabelTargetModel, InstanceID: instance, ModelID: model}}
+	}
+	assignments := []LabelAssignment{
+		model("fast", "openai-main", "gpt-4o"),
+		model("fast", "openai-main", "gpt-4o"),
+		model("smart", "openai-main", "gpt-5"),
+		model("local", "ollama-local", "llama3"),
+		{LabelName: "old", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "azure-gone"}},
+	}
+
+	kinds := map[IntegrityKind]int{}
+	for _, issue := range CheckIntegrity(instances, assignments) {
+		kinds[issue.Kind]++
+		if issue.Repairable != (issue.Assignment != nil) {
+			t.Errorf("%s: Repairable = %v", issue.Message, issue.Repairable)
+		}
+	}
+	want := map[IntegrityKind]int{
+		IntegrityDuplicateInstanceID: 1,
+		IntegrityDuplicateAssignment: 1,
+		IntegrityDanglingModel:       1,
+		IntegrityDanglingInstance:    1,
+	}
+	if len(kinds) != len(want) {
+		t.Errorf("issue kinds = %v, want %v", kinds, want)
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Errorf("%s issues = %d, want %d", kind, kinds[kind], n)
+		}
+	}
+
+	repaired := RepairAssignments(instances, assignments)
+	if len(repaired) != 2 || repaired[0].LabelName != "fast" || repaired[1].LabelName != "local" {
+		t.Errorf("repaired = %+v", repaired)
+	}
+	for _, issue := range CheckIntegrity(instances, repaired) {
+		if issue.Repairable {
+			t.Errorf("repair left %s", issue.Message)
+		}
+	}
+}
+
+func TestSessionRepairIntegrity(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = s.SaveLabels([]LabelAssignment{
+		{LabelName: "fast", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "openai-main", ModelID: "gpt-4o"}},
+		{LabelName: "old", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "gone"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := s.RepairIntegrity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Kind != IntegrityDanglingInstance {
+		t.Errorf("issues = %+v", issues)
+	}
+	if issues, _ := s.CheckIntegrity(); len(issues) != 0 {
+		t.Errorf("after repair: %+v", issues)
+	}
+}
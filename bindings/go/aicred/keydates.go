@@ -0,0 +1,106 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Instance metadata keys holding dates in the life of an instance's API
+// key, in RFC 3339, so ops tooling can alert before enterprise-issued keys
+// lapse
+const (
+	// MetadataKeyCreatedAt is when the key was created or last rotated.
+	// Key-age policies read it.
+	MetadataKeyCreatedAt = "key_created_at"
+	// MetadataKeyExpiresAt is when the key stops working
+	MetadataKeyExpiresAt = "key_expires_at"
+	// MetadataKeyValidatedAt is when the provider last accepted the key.
+	// InspectInstance sets it.
+	MetadataKeyValidatedAt = "key_validated_at"
+)
+
+// KeyDates are the known dates of an instance's API key. Zero fields are
+// unknown.
+type KeyDates struct {
+	CreatedAt       time.Time `json:"created_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	LastValidatedAt time.Time `json:"last_validated_at"`
+}
+
+// KeyDatesOf reads the dates of instance's API key from its metadata. A
+// date that is not RFC 3339 is an error wrapping ErrInvalidInstance.
+func KeyDatesOf(instance ProviderInstance) (KeyDates, error) {
+	var d KeyDates
+	var problems []error
+	for key, field := range map[string]*time.Time{
+		MetadataKeyCreatedAt:   &d.CreatedAt,
+		MetadataKeyExpiresAt:   &d.ExpiresAt,
+		MetadataKeyValidatedAt: &d.LastValidatedAt,
+	} {
+		s, ok := instance.Metadata[key]
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("instance %q: %s %q is not an RFC 3339 time: %w", instance.ID, key, s, ErrInvalidInstance))
+			continue
+		}
+		*field = t
+	}
+	return d, errors.Join(problems...)
+}
+
+// SetKeyDates writes d into instance's metadata. Zero fields are removed.
+func SetKeyDates(instance *ProviderInstance, d KeyDates) {
+	if instance.Metadata == nil {
+		instance.Metadata = map[string]string{}
+	}
+	for key, t := range map[string]time.Time{
+		MetadataKeyCreatedAt:   d.CreatedAt,
+		MetadataKeyExpiresAt:   d.ExpiresAt,
+		MetadataKeyValidatedAt: d.LastValidatedAt,
+	} {
+		if t.IsZero() {
+			delete(instance.Metadata, key)
+		} else {
+			instance.Metadata[key] = t.UTC().Format(time.RFC3339)
+		}
+	}
+}
+
+// ExpiringKey is an instance whose API key expires soon or has expired
+type ExpiringKey struct {
+	InstanceID      string    `json:"instance_id"`
+	ProviderType    string    `json:"provider_type"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	LastValidatedAt time.Time `json:"last_validated_at"`
+}
+
+// ExpiringKeys returns the instances with an API key that expires within
+// the given duration of now, including keys already expired, soonest
+// first. Instances without a key or an expiry date are skipped, as are
+// those whose dates do not parse; ValidateProviderInstance reports them.
+func ExpiringKeys(instances []ProviderInstance, within time.Duration, now time.Time) []ExpiringKey {
+	deadline := now.Add(within)
+	var expiring []ExpiringKey
+	for _, instance := range instances {
+		if instance.APIKey.IsZero() {
+			continue
+		}
+		d, err := KeyDatesOf(instance)
+		if err != nil || d.ExpiresAt.IsZero() || d.ExpiresAt.After(deadline) {
+			continue
+		}
+		expiring = append(expiring, ExpiringKey{
+			InstanceID:      instance.ID,
+			ProviderType:    instance.ProviderType,
+			ExpiresAt:       d.ExpiresAt,
+			LastValidatedAt: d.LastValidatedAt,
+		})
+	}
+	slices.SortStableFunc(expiring, func(a, b ExpiringKey) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	return expiring
+}
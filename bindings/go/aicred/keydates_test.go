@@ -0,0 +1,45 @@
+package aicred
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpiringKeys(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	validated := now.Add(-24 * time.Hour)
+	instances := []ProviderInstance{
+		{ID: "soon", ProviderType: "openai", APIKey: NewSecretString("sk-a")},
+		{ID: "expired", ProviderType: "anthropic", APIKey: NewSecretString("sk-ant-b")},
+		{ID: "later", ProviderType: "openai", APIKey: NewSecretString("sk-c")},
+		{ID: "keyless", ProviderType: "ollama"},
+		{ID: "undated", ProviderType: "groq", APIKey: NewSecretString("gsk-d")},
+	}
+	SetKeyDates(&instances[0], KeyDates{ExpiresAt: now.Add(10 * 24 * time.Hour), LastValidatedAt: validated})
+	SetKeyDates(&instances[1], KeyDates{ExpiresAt: now.Add(-time.Hour)})
+	SetKeyDates(&instances[2], KeyDates{ExpiresAt: now.Add(90 * 24 * time.Hour)})
+	SetKeyDates(&instances[3], KeyDates{ExpiresAt: now})
+
+	expiring := ExpiringKeys(instances, 30*24*time.Hour, now)
+	if len(expiring) != 2 || expiring[0].InstanceID != "expired" || expiring[1].InstanceID != "soon" {
+		t.Fatalf("ExpiringKeys = %+v, want expired then soon", expiring)
+	}
+	if !expiring[1].LastValidatedAt.Equal(validated) {
+		t.Errorf("LastValidatedAt = %v, want %v", expiring[1].LastValidatedAt, validated)
+	}
+
+	store := NewMemoryStore(instances, nil)
+	if got, err := newInstanceRepository(store).ExpiringKeys(0); err != nil || len(got) != 1 || got[0].InstanceID != "expired" {
+		t.Errorf("repository ExpiringKeys(0) = %+v, %v, want only the expired key", got, err)
+	}
+
+	SetKeyDates(&instances[0], KeyDates{})
+	if len(instances[0].Metadata) != 0 {
+		t.Errorf("SetKeyDates with zero dates left %v", instances[0].Metadata)
+	}
+	instances[0].Metadata[MetadataKeyExpiresAt] = "next week"
+	if _, err := KeyDatesOf(instances[0]); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("KeyDatesOf = %v, want ErrInvalidInstance", err)
+	}
+}
@@ -0,0 +1,323 @@
+package aicred
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Instance metadata keys InspectInstance records a key's scope under, for
+// audit. They describe the key as the provider sees it, unlike
+// MetadataOrganization, which groups instances in aicred.
+const (
+	MetadataKeyType              = "key_type"
+	MetadataProviderOrganization = "provider_organization"
+	MetadataProviderProject      = "provider_project"
+	MetadataProviderWorkspace    = "provider_workspace"
+	// MetadataKeyScopes holds the key's permission scopes, comma-separated
+	MetadataKeyScopes = "key_scopes"
+	// MetadataKeyInspectedAt is when the key was inspected, in RFC 3339
+	MetadataKeyInspectedAt = "key_inspected_at"
+)
+
+// KeyScope is what a provider says a key may reach: the organization,
+// project or workspace it belongs to and the permissions it carries.
+// Fields the provider does not expose are empty.
+type KeyScope struct {
+	Provider string `json:"provider"`
+	// KeyType is the kind of key, such as "project", "service_account" or
+	// "admin" for OpenAI, "api" or "admin" for Anthropic, and "read",
+	// "write" or "fine-grained" for Hugging Face
+	KeyType string `json:"key_type,omitempty"`
+	// Name is the name the owner gave the key
+	Name string `json:"name,omitempty"`
+	// Owner is the account the key belongs to
+	Owner        string `json:"owner,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	Project      string `json:"project,omitempty"`
+	Workspace    string `json:"workspace,omitempty"`
+	// Scopes are the key's permissions, such as Hugging Face's
+	// "inference.serverless.write"
+	Scopes []string `json:"scopes,omitempty"`
+	// Restricted reports that the key lacks a permission the inspection
+	// needed, as restricted OpenAI keys without model access do
+	Restricted  bool      `json:"restricted,omitempty"`
+	InspectedAt time.Time `json:"inspected_at"`
+}
+
+// Provider endpoints InspectKey and Revoke call; tests point them at a
+// fake
+var (
+	openAIEndpoint    = "https://api.openai.com"
+	anthropicEndpoint = "https://api.anthropic.com"
+	inspectClient     = http.DefaultClient
+)
+
+// InspectKey asks the key's provider what the key may reach and sets
+// key.Scope to the answer. It supports OpenAI, whose responses name the
+// key's organization and project; Anthropic, whose responses name the
+// organization, and whose Admin API, with an admin key in
+// $ANTHROPIC_ADMIN_KEY, names the key's workspace; and Hugging Face,
+// whose whoami API lists a fine-grained token's scopes.
+//
+// The key needs its value, from a scan with RedactionNone. InspectKey
+// sends it to the provider. It returns an error wrapping ErrInvalidOption
+// for other providers or a key without a value, ErrPermissionDenied if the
+// provider rejects the key, and ErrIO if it cannot be reached.
+func InspectKey(ctx context.Context, key *DiscoveredKey) (*KeyScope, error) {
+	if key.Value.IsZero() {
+		return nil, fmt.Errorf("inspect %s key from %s: no value: %w", key.Provider, key.Source, ErrInvalidOption)
+	}
+	scope := &KeyScope{Provider: key.Provider, InspectedAt: time.Now().UTC()}
+	var err error
+	switch strings.ToLower(key.Provider) {
+	case "openai":
+		err = inspectOpenAIKey(ctx, key.Value, scope)
+	case "anthropic":
+		err = inspectAnthropicKey(ctx, key.Value, scope)
+	case "huggingface":
+		err = inspectHFKey(ctx, key.Value, scope)
+	default:
+		return nil, fmt.Errorf("inspect %s key: the provider does not expose key scopes: %w", key.Provider, ErrInvalidOption)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("inspect %s key from %s: %w", key.Provider, key.Source, err)
+	}
+	key.Scope = scope
+	return scope, nil
+}
+
+// InspectInstance inspects the instance's API key as InspectKey does and
+// records the scope in its metadata under the MetadataKey* and
+// MetadataProvider* keys, replacing what an earlier inspection recorded.
+// The key was accepted, so MetadataKeyValidatedAt is set too.
+func InspectInstance(ctx context.Context, instance *ProviderInstance) (*KeyScope, error) {
+	key := DiscoveredKey{Provider: instance.ProviderType, Source: "instance " + instance.ID, Value: instance.APIKey}
+	scope, err := InspectKey(ctx, &key)
+	if err != nil {
+		return nil, err
+	}
+	if instance.Metadata == nil {
+		instance.Metadata = map[string]string{}
+	}
+	for name, value := range map[string]string{
+		MetadataKeyType:              scope.KeyType,
+		MetadataProviderOrganization: scope.Organization,
+		MetadataProviderProject:      scope.Project,
+		MetadataProviderWorkspace:    scope.Workspace,
+		MetadataKeyScopes:            strings.Join(scope.Scopes, ","),
+		MetadataKeyInspectedAt:       scope.InspectedAt.Format(time.RFC3339),
+		MetadataKeyValidatedAt:       scope.InspectedAt.Format(time.RFC3339),
+	} {
+		if value == "" {
+			delete(instance.Metadata, name)
+		} else {
+			instance.Metadata[name] = value
+		}
+	}
+	return scope, nil
+}
+
+// inspectOpenAIKey lists models, the cheapest call every key type may
+// make, and reads the organization and project OpenAI names in the
+// response headers. The key type comes from its prefix.
+func inspectOpenAIKey(ctx context.Context, value Secret, scope *KeyScope) error {
+	value.Use(func(b []byte) {
+		switch {
+		case strings.HasPrefix(string(b), "sk-proj-"):
+			scope.KeyType = "project"
+		case strings.HasPrefix(string(b), "sk-svcacct-"):
+			scope.KeyType = "service_account"
+		case strings.HasPrefix(string(b), "sk-admin-"):
+			scope.KeyType = "admin"
+		default:
+			scope.KeyType = "user"
+		}
+	})
+	resp, err := inspectRequest(ctx, openAIEndpoint+"/v1/models", func(h http.Header) {
+		h.Set("Authorization", "Bearer "+value.Reveal())
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		scope.Restricted = true
+	default:
+		return inspectStatusError(resp)
+	}
+	scope.Organization = resp.Header.Get("openai-organization")
+	scope.Project = resp.Header.Get("openai-project")
+	return nil
+}
+
+// anthropicAPIKey is the part of an Admin API key listing InspectKey reads
+type anthropicAPIKey struct {
+	Name           string `json:"name"`
+	WorkspaceID    string `json:"workspace_id"`
+	PartialKeyHint string `json:"partial_key_hint"`
+	CreatedBy      struct {
+		ID string `json:"id"`
+	} `json:"created_by"`
+}
+
+// inspectAnthropicKey lists models to learn the key's organization, then
+// finds the key in the Admin API's key list, matched by its partial hint,
+// to learn its name and workspace. Keys outside any workspace are in the
+// organization's default workspace, reported as "default". Admin keys
+// cannot list models and ask the Admin API for their organization.
+func inspectAnthropicKey(ctx context.Context, value Secret, scope *KeyScope) error {
+	scope.KeyType = "api"
+	value.Use(func(b []byte) {
+		if strings.HasPrefix(string(b), "sk-ant-admin") {
+			scope.KeyType = "admin"
+		}
+	})
+	if scope.KeyType == "admin" {
+		resp, err := inspectRequest(ctx, anthropicEndpoint+"/v1/organizations/me", anthropicAuth(value.Reveal()))
+		if err != nil {
+			return err
+		}
+		var org struct {
+			ID string `json:"id"`
+		}
+		if err := decodeInspectResponse(resp, &org); err != nil {
+			return err
+		}
+		scope.Organization = org.ID
+		return nil
+	}
+
+	resp, err := inspectRequest(ctx, anthropicEndpoint+"/v1/models", anthropicAuth(value.Reveal()))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return inspectStatusError(resp)
+	}
+	scope.Organization = resp.Header.Get("anthropic-organization-id")
+
+	admin := getenv("ANTHROPIC_ADMIN_KEY")
+	if admin == "" {
+		return nil
+	}
+	after := ""
+	for {
+		url := anthropicEndpoint + "/v1/organizations/api_keys?limit=100"
+		if after != "" {
+			url += "&after_id=" + after
+		}
+		resp, err := inspectRequest(ctx, url, anthropicAuth(admin))
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Data    []anthropicAPIKey `json:"data"`
+			HasMore bool              `json:"has_more"`
+			LastID  string            `json:"last_id"`
+		}
+		if err := decodeInspectResponse(resp, &page); err != nil {
+			return fmt.Errorf("admin API: %w", err)
+		}
+		for _, k := range page.Data {
+			if matchesKeyHint(value, k.PartialKeyHint) {
+				scope.Name = k.Name
+				scope.Owner = k.CreatedBy.ID
+				scope.Workspace = firstNonEmpty(k.WorkspaceID, "default")
+				return nil
+			}
+		}
+		if !page.HasMore || page.LastID == "" {
+			return nil
+		}
+		after = page.LastID
+	}
+}
+
+func anthropicAuth(key string) func(http.Header) {
+	return func(h http.Header) {
+		h.Set("x-api-key", key)
+		h.Set("anthropic-version", "2023-06-01")
+	}
+}
+
+// matchesKeyHint reports whether value fits a hint such as
+// "sk-ant-api03-R2D...igAA", the start and end of a key
+func matchesKeyHint(value Secret, hint string) bool {
+	prefix, suffix, ok := strings.Cut(hint, "...")
+	if !ok || prefix == "" || suffix == "" {
+		return false
+	}
+	var match bool
+	value.Use(func(b []byte) {
+		match = len(b) >= len(prefix)+len(suffix) && strings.HasPrefix(string(b), prefix) && strings.HasSuffix(string(b), suffix)
+	})
+	return match
+}
+
+// inspectHFKey asks the Hub's whoami API, as InspectHFTokens does
+func inspectHFKey(ctx context.Context, value Secret, scope *KeyScope) error {
+	options := HFTokenOptions{
+		Endpoint: strings.TrimSuffix(firstNonEmpty(getenv("HF_ENDPOINT"), "https://huggingface.co"), "/"),
+		Client:   inspectClient,
+	}
+	token := parseHFToken(value)
+	if err := whoami(ctx, &options, value, token); err != nil {
+		return err
+	}
+	if token.Rejected {
+		return fmt.Errorf("the Hub rejected the token: %w", ErrPermissionDenied)
+	}
+	scope.KeyType = token.Type
+	scope.Name = token.Name
+	scope.Owner = token.User
+	scope.Scopes = token.Scopes
+	// A token scoped to one organization belongs to it
+	for _, s := range token.Scopes {
+		if entity, _, ok := strings.Cut(s, ":"); ok && strings.HasPrefix(entity, "org/") {
+			scope.Organization = strings.TrimPrefix(entity, "org/")
+			break
+		}
+	}
+	return nil
+}
+
+func inspectRequest(ctx context.Context, url string, auth func(http.Header)) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOption, err)
+	}
+	auth(req.Header)
+	resp, err := inspectClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIO, err)
+	}
+	return resp, nil
+}
+
+func decodeInspectResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return inspectStatusError(resp)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	return nil
+}
+
+// inspectStatusError describes a failed response; rejected keys wrap
+// ErrPermissionDenied
+func inspectStatusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, resp.Status)
+	}
+	return fmt.Errorf("%w: %s", ErrIO, resp.Status)
+}
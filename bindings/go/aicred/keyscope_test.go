@@ -0,0 +1,147 @@
+package aicred
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+const (
+	projectKey   = "sk-proj-AbCdEfGhIjKlMnOpQrStUvWx"
+	anthropicKey = "sk-ant-REDACTED"
+)
+
+// fakeProviders serves the provider APIs InspectKey calls
+func fakeProviders(t *testing.T) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") == "Bearer "+projectKey:
+			w.Header().Set("openai-organization", "org-acme")
+			w.Header().Set("openai-project", "proj_chatbot")
+		case r.Header.Get("Authorization") == "Bearer sk-restrictedKeyWithoutModelRead":
+			w.Header().Set("openai-organization", "org-acme")
+			w.WriteHeader(http.StatusForbidden)
+		case r.Header.Get("x-api-key") == anthropicKey && r.Header.Get("anthropic-version") != "":
+			w.Header().Set("anthropic-organization-id", "8a6b3c1e-0000-4000-8000-000000000001")
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		w.Write([]byte(`{"data":[]}`))
+	})
+	mux.HandleFunc("GET /v1/organizations/api_keys", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "sk-ant-admin01-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("after_id") == "" {
+			w.Write([]byte(`{"data":[{"id":"apikey_1","name":"ci","workspace_id":null,"partial_key_hint":"sk-ant-api03-XyZ...abCD"}],"has_more":true,"last_id":"apikey_1"}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"apikey_2","name":"chatbot prod","workspace_id":"wrkspc_01","partial_key_hint":"sk-ant-api03-R2D...igAA","created_by":{"id":"user_01","type":"user"}}],"has_more":false,"last_id":"apikey_2"}`))
+	})
+	mux.HandleFunc("GET /api/whoami-v2", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer hf_fineGrainedTokenForTestingOnly" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name":"alice","orgs":[{"name":"acme"}],"auth":{"accessToken":{"displayName":"inference","role":"fineGrained","fineGrained":{"global":["inference.serverless.write"],"scoped":[{"entity":{"type":"org","name":"acme"},"permissions":["repo.content.read"]}]}}}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	saved := [...]string{openAIEndpoint, anthropicEndpoint}
+	savedClient := inspectClient
+	t.Cleanup(func() { openAIEndpoint, anthropicEndpoint, inspectClient = saved[0], saved[1], savedClient })
+	openAIEndpoint, anthropicEndpoint, inspectClient = srv.URL, srv.URL, srv.Client()
+	fakeEnv(t, false, map[string]string{"HF_ENDPOINT": srv.URL})
+}
+
+func TestInspectKey(t *testing.T) {
+	fakeProviders(t)
+	ctx := context.Background()
+
+	key := DiscoveredKey{Provider: "openai", Source: ".env", Value: NewSecretString(projectKey)}
+	scope, err := InspectKey(ctx, &key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.KeyType != "project" || scope.Organization != "org-acme" || scope.Project != "proj_chatbot" || key.Scope != scope {
+		t.Errorf("openai scope = %+v", scope)
+	}
+	key = DiscoveredKey{Provider: "openai", Value: NewSecretString("sk-restrictedKeyWithoutModelRead")}
+	if scope, err := InspectKey(ctx, &key); err != nil || !scope.Restricted || scope.Organization != "org-acme" {
+		t.Errorf("restricted openai key: %+v, %v", scope, err)
+	}
+
+	key = DiscoveredKey{Provider: "anthropic", Value: NewSecretString(anthropicKey)}
+	if scope, err := InspectKey(ctx, &key); err != nil || scope.Organization == "" || scope.Workspace != "" {
+		t.Errorf("anthropic scope without an admin key: %+v, %v", scope, err)
+	}
+
+	key = DiscoveredKey{Provider: "huggingface", Value: NewSecretString("hf_fineGrainedTokenForTestingOnly")}
+	scope, err = InspectKey(ctx, &key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.KeyType != "fine-grained" || scope.Owner != "alice" || scope.Organization != "acme" ||
+		!slices.Equal(scope.Scopes, []string{"inference.serverless.write", "org/acme:repo.content.read"}) {
+		t.Errorf("huggingface scope = %+v", scope)
+	}
+
+	for _, tc := range []struct {
+		key  DiscoveredKey
+		want error
+	}{
+		{DiscoveredKey{Provider: "openai", Value: NewSecretString("sk-revokedKey0000000000000")}, ErrPermissionDenied},
+		{DiscoveredKey{Provider: "huggingface", Value: NewSecretString("hf_revokedToken000000000000")}, ErrPermissionDenied},
+		{DiscoveredKey{Provider: "groq", Value: NewSecretString("gsk_0000000000000000000000")}, ErrInvalidOption},
+		{DiscoveredKey{Provider: "openai"}, ErrInvalidOption},
+	} {
+		if _, err := InspectKey(ctx, &tc.key); !errors.Is(err, tc.want) {
+			t.Errorf("%s key: err = %v, want %v", tc.key.Provider, err, tc.want)
+		} else if tc.key.Scope != nil {
+			t.Errorf("%s key: Scope set on failure", tc.key.Provider)
+		}
+	}
+}
+
+func TestInspectInstance(t *testing.T) {
+	fakeProviders(t)
+	fakeEnv(t, false, map[string]string{"ANTHROPIC_ADMIN_KEY": "sk-ant-admin01-secret"})
+
+	instance := ProviderInstance{
+		ID:           "anthropic",
+		ProviderType: "anthropic",
+		APIKey:       NewSecretString(anthropicKey),
+		Metadata:     map[string]string{MetadataProviderProject: "stale", "team": "search"},
+	}
+	scope, err := InspectInstance(context.Background(), &instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.Name != "chatbot prod" || scope.Owner != "user_01" {
+		t.Errorf("scope = %+v", scope)
+	}
+	want := map[string]string{
+		MetadataKeyType:              "api",
+		MetadataProviderOrganization: "8a6b3c1e-0000-4000-8000-000000000001",
+		MetadataProviderWorkspace:    "wrkspc_01",
+		"team":                       "search",
+	}
+	for name, value := range want {
+		if instance.Metadata[name] != value {
+			t.Errorf("metadata %s = %q, want %q", name, instance.Metadata[name], value)
+		}
+	}
+	if _, ok := instance.Metadata[MetadataProviderProject]; ok {
+		t.Error("stale project kept")
+	}
+	if instance.Metadata[MetadataKeyInspectedAt] == "" {
+		t.Error("inspection time not recorded")
+	}
+}
@@ -0,0 +1,94 @@
+package aicred
+
+import (
+	"iter"
+	"slices"
+)
+
+// KeySeq is an iterator over discovered keys, each paired with the
+// application it belongs to, or "" when that is unknown. Ranging with one
+// variable yields just the keys:
+//
+//	for key := range result.Iter().ByProvider("openai").ByConfidence("High") {
+//		fmt.Println(key.Source)
+//	}
+type KeySeq iter.Seq2[DiscoveredKey, string]
+
+// Iter returns an iterator over the result's keys, then over the keys of
+// each config instance in order. A top-level key's application is its
+// Attribution.Application, set by Attribute; a config instance key's is the
+// instance's AppName. The same key may be yielded once at the top level and
+// again under its config instance.
+func (r *ScanResult) Iter() KeySeq {
+	return func(yield func(DiscoveredKey, string) bool) {
+		for _, key := range r.Keys {
+			var app string
+			if key.Attribution != nil {
+				app = key.Attribution.Application
+			}
+			if !yield(key, app) {
+				return
+			}
+		}
+		for _, inst := range r.ConfigInstances {
+			for _, key := range inst.Keys {
+				if !yield(key, inst.AppName) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Filter returns the keys for which keep returns true
+func (s KeySeq) Filter(keep func(key DiscoveredKey, app string) bool) KeySeq {
+	return func(yield func(DiscoveredKey, string) bool) {
+		for key, app := range s {
+			if keep(key, app) && !yield(key, app) {
+				return
+			}
+		}
+	}
+}
+
+// ByProvider returns the keys of the named providers
+func (s KeySeq) ByProvider(providers ...string) KeySeq {
+	return s.Filter(func(key DiscoveredKey, _ string) bool {
+		return slices.Contains(providers, key.Provider)
+	})
+}
+
+// ByConfidence returns the keys reported at or above the given confidence
+// level: Low, Medium, High or VeryHigh. An unknown level keeps every key,
+// and keys with an unknown confidence rank as Low.
+func (s KeySeq) ByConfidence(minimum string) KeySeq {
+	threshold, _ := confidenceRank(minimum)
+	return s.Filter(func(key DiscoveredKey, _ string) bool {
+		rank, _ := confidenceRank(key.Confidence)
+		return rank >= threshold
+	})
+}
+
+// ByApp returns the keys belonging to the named applications
+func (s KeySeq) ByApp(apps ...string) KeySeq {
+	return s.Filter(func(_ DiscoveredKey, app string) bool {
+		return app != "" && slices.Contains(apps, app)
+	})
+}
+
+// Keys returns an iterator over the keys alone, for functions such as
+// slices.Collect
+func (s KeySeq) Keys() iter.Seq[DiscoveredKey] {
+	return func(yield func(DiscoveredKey) bool) {
+		for key := range s {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Collect returns the keys as a slice
+func (s KeySeq) Collect() []DiscoveredKey {
+	return slices.Collect(s.Keys())
+}
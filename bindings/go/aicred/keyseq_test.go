@@ -0,0 +1,56 @@
+package aicred
+
+import "testing"
+
+func TestKeySeq(t *testing.T) {
+	result := &ScanResult{
+		Keys: []DiscoveredKey{
+			{Provider: "openai", Source: "a", Confidence: "High"},
+			{Provider: "anthropic", Source: "b", Confidence: "Low"},
+			{Provider: "openai", Source: "c", Confidence: "VeryHigh", Attribution: &Attribution{Application: "cursor"}},
+		},
+		ConfigInstances: []ConfigInstance{
+			{AppName: "roo-code", Keys: []DiscoveredKey{{Provider: "openai", Source: "d", Confidence: "Medium"}}},
+		},
+	}
+
+	sources := func(keys []DiscoveredKey) string {
+		var s string
+		for _, key := range keys {
+			s += key.Source
+		}
+		return s
+	}
+	for name, tc := range map[string]struct {
+		seq  KeySeq
+		want string
+	}{
+		"all":         {result.Iter(), "abcd"},
+		"provider":    {result.Iter().ByProvider("openai"), "acd"},
+		"confidence":  {result.Iter().ByConfidence("High"), "ac"},
+		"app":         {result.Iter().ByApp("roo-code", "cursor"), "cd"},
+		"chained":     {result.Iter().ByProvider("openai").ByConfidence("medium").ByApp("roo-code"), "d"},
+		"no provider": {result.Iter().ByProvider(), ""},
+	} {
+		if got := sources(tc.seq.Collect()); got != tc.want {
+			t.Errorf("%s: got %q, want %q", name, got, tc.want)
+		}
+	}
+
+	var apps []string
+	for _, app := range result.Iter() {
+		apps = append(apps, app)
+	}
+	if len(apps) != 4 || apps[2] != "cursor" || apps[3] != "roo-code" {
+		t.Errorf("apps = %q", apps)
+	}
+
+	var seen int
+	for range result.Iter().ByProvider("openai") {
+		seen++
+		break
+	}
+	if seen != 1 {
+		t.Errorf("Iter kept yielding after break")
+	}
+}
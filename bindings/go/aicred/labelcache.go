@@ -0,0 +1,157 @@
+package aicred
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// DefaultLabelCacheTTL is how long a LabelCache keeps what it loaded when
+// LabelCacheOptions.TTL is 0
+const DefaultLabelCacheTTL = 30 * time.Second
+
+// LabelCacheOptions configures NewLabelCache
+type LabelCacheOptions struct {
+	// TTL is how long loaded instances and labels are used before the
+	// store is read again; 0 means DefaultLabelCacheTTL and a negative TTL
+	// keeps them until Invalidate
+	TTL time.Duration
+	// Events, if set, invalidates the cache whenever a StoreChanged event
+	// for labels or instances is published on it, so writes through a
+	// Session or Client are seen at once
+	Events *events.Bus
+}
+
+// LabelCache resolves labels to instance/model pairs from an in-process
+// copy of a store's instances and label assignments, so hot paths such as
+// proxies and resolvers do not cross the FFI on every request. A
+// LabelCache is safe for concurrent use.
+type LabelCache struct {
+	store       Store
+	ttl         time.Duration
+	now         func() time.Time
+	unsubscribe func()
+
+	mu          sync.Mutex
+	loadedAt    time.Time
+	loaded      bool
+	instances   []ProviderInstance
+	assignments []LabelAssignment
+	resolved    map[string][]Selection
+}
+
+// NewLabelCache returns a cache over store. Nothing is loaded until the
+// first lookup. Close the cache to stop listening for events.
+func NewLabelCache(store Store, options LabelCacheOptions) *LabelCache {
+	ttl := options.TTL
+	if ttl == 0 {
+		ttl = DefaultLabelCacheTTL
+	}
+	c := &LabelCache{store: store, ttl: ttl, now: time.Now}
+	c.unsubscribe = events.Subscribe(options.Events, func(e events.StoreChanged) {
+		if e.Store == "labels" || e.Store == "instances" {
+			c.Invalidate()
+		}
+	})
+	return c
+}
+
+// LabelCache returns a cache over the Client's store, invalidated by the
+// Client's events
+func (c *Client) LabelCache(ttl time.Duration) *LabelCache {
+	return NewLabelCache(c.store, LabelCacheOptions{TTL: ttl, Events: c.events})
+}
+
+// Resolve returns the instance/model pairs label applies to, in instance
+// order. A label assigned to an instance applies to all of its models.
+// The result is shared with later calls and must not be modified.
+func (c *LabelCache) Resolve(label string) ([]Selection, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	if selections, ok := c.resolved[label]; ok {
+		return selections, nil
+	}
+	selections := ResolveLabel(c.instances, c.assignments, label)
+	c.resolved[label] = selections
+	return selections, nil
+}
+
+// ResolveLabel returns the instance/model pairs in instances that label
+// applies to, in instance order. A label assigned to an instance applies
+// to all of its models. Unlike a selector query, any label name resolves,
+// whatever characters it holds.
+func ResolveLabel(instances []ProviderInstance, assignments []LabelAssignment, label string) []Selection {
+	var selections []Selection
+	for _, instance := range instances {
+		models := instance.Models
+		if len(models) == 0 {
+			models = []string{""}
+		}
+		for _, model := range models {
+			if slices.Contains(pairLabels(instance.ID, model, assignments), label) {
+				selections = append(selections, Selection{Instance: instance, Model: model})
+			}
+		}
+	}
+	return selections
+}
+
+// Select matches a selector query against the cached instances and labels;
+// see Select
+func (c *LabelCache) Select(query string) ([]Selection, error) {
+	selector, err := ParseSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	return selector.Select(c.instances, c.assignments), nil
+}
+
+// Invalidate drops what the cache holds, so the next lookup reads the
+// store again
+func (c *LabelCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = false
+}
+
+// Close stops the cache listening for events. It is safe to call more
+// than once.
+func (c *LabelCache) Close() {
+	c.unsubscribe()
+}
+
+// refresh reloads the store if the cache is empty or expired. A store with
+// a Reload method, such as a Session, is reloaded first so changes made on
+// disk since it was last read are seen. The caller holds c.mu.
+func (c *LabelCache) refresh() error {
+	if c.loaded && (c.ttl < 0 || c.now().Sub(c.loadedAt) < c.ttl) {
+		return nil
+	}
+	if reloader, ok := c.store.(interface{ Reload() error }); ok {
+		if err := reloader.Reload(); err != nil {
+			return err
+		}
+	}
+	instances, err := c.store.LoadInstances()
+	if err != nil {
+		return err
+	}
+	assignments, err := c.store.LoadLabels()
+	if err != nil {
+		return err
+	}
+	c.instances, c.assignments = instances, assignments
+	c.resolved = map[string][]Selection{}
+	c.loaded, c.loadedAt = true, c.now()
+	return nil
+}
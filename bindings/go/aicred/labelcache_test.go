@@ -0,0 +1,91 @@
+package aicred
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// countingStore counts how often the wrapped store is read
+type countingStore struct {
+	Store
+	loads int
+}
+
+func (s *countingStore) LoadLabels() ([]LabelAssignment, error) {
+	s.loads++
+	return s.Store.LoadLabels()
+}
+
+func TestLabelCache(t *testing.T) {
+	instanceTarget := LabelTarget{Type: LabelTargetInstance, InstanceID: "a"}
+	store := &countingStore{Store: NewMemoryStore(
+		[]ProviderInstance{{ID: "a", Models: []string{"m1", "m2"}}, {ID: "b"}},
+		[]LabelAssignment{
+			{LabelName: "prod", Target: instanceTarget},
+			{LabelName: "fast", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "a", ModelID: "m2"}},
+			{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "b"}},
+		},
+	)}
+	bus := events.New()
+	cache := NewLabelCache(store, LabelCacheOptions{TTL: time.Minute, Events: bus})
+	defer cache.Close()
+	clock := time.Unix(0, 0)
+	cache.now = func() time.Time { return clock }
+
+	fast, err := cache.Resolve("fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fast) != 2 || fast[0].Model != "m2" || fast[1].Instance.ID != "b" || fast[1].Model != "" {
+		t.Errorf("Resolve(fast) = %+v", fast)
+	}
+	if prod, _ := cache.Resolve("prod"); len(prod) != 2 {
+		t.Errorf("Resolve(prod) = %+v", prod)
+	}
+	if sel, err := cache.Select("label=prod AND model=m1"); err != nil || len(sel) != 1 {
+		t.Errorf("Select = %+v, %v", sel, err)
+	}
+	if store.loads != 1 {
+		t.Errorf("store read %d times within the TTL, want 1", store.loads)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	cache.Resolve("fast")
+	if store.loads != 2 {
+		t.Errorf("store read %d times after the TTL, want 2", store.loads)
+	}
+
+	store.SaveLabels(nil)
+	bus.Publish(events.StoreChanged{Store: "labels"})
+	if fast, _ := cache.Resolve("fast"); len(fast) != 0 || store.loads != 3 {
+		t.Errorf("after StoreChanged: Resolve = %+v with %d loads", fast, store.loads)
+	}
+	cache.Close()
+	bus.Publish(events.StoreChanged{Store: "labels"})
+	cache.Resolve("fast")
+	if store.loads != 3 {
+		t.Errorf("closed cache was invalidated by an event")
+	}
+	cache.Invalidate()
+	cache.Resolve("fast")
+	if store.loads != 4 {
+		t.Errorf("Invalidate did not force a read")
+	}
+}
+
+func TestResolveLabel(t *testing.T) {
+	instances := []ProviderInstance{{ID: "a", Models: []string{"m1", "m2"}}, {ID: "b"}}
+	assignments := []LabelAssignment{
+		{LabelName: `team "x"`, Target: LabelTarget{Type: LabelTargetModel, InstanceID: "a", ModelID: "m1"}},
+		{LabelName: `team "x"`, Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "b"}},
+	}
+	got := ResolveLabel(instances, assignments, `team "x"`)
+	if len(got) != 2 || got[0].Instance.ID != "a" || got[0].Model != "m1" || got[1].Instance.ID != "b" || got[1].Model != "" {
+		t.Errorf("ResolveLabel = %+v", got)
+	}
+	if got := ResolveLabel(instances, assignments, "missing"); got != nil {
+		t.Errorf("unknown label = %+v", got)
+	}
+}
@@ -0,0 +1,261 @@
+package aicred
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Leaser hands out short-lived copies of stored API keys, so applications
+// hold a key only while they need it instead of reading APIKey directly.
+// Each lease has its own copy of the key, wiped when the lease expires,
+// its context is done, or it is released. The Leaser counts leases and
+// uses per instance. A Leaser is safe for concurrent use.
+type Leaser struct {
+	store Store
+	now   func() time.Time
+
+	mu     sync.Mutex
+	active map[*Credential]struct{}
+	usage  map[string]*LeaseUsage
+}
+
+// LeaseUsage is what a Leaser counted for one instance
+type LeaseUsage struct {
+	InstanceID string `json:"instance_id"`
+	// Leases is how many leases were handed out, and Active how many of
+	// them have not ended
+	Leases int `json:"leases"`
+	Active int `json:"active"`
+	// Uses counts the calls to Use and Authorize on the leases
+	Uses       int       `json:"uses"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// Credential is a leased copy of an instance's API key. Its key can only
+// be reached through Use and Authorize, and only until ExpiresAt.
+type Credential struct {
+	InstanceID   string
+	ProviderType string
+	BaseURL      string
+	// Model is the model the label resolved to; empty when an instance
+	// was leased by ID
+	Model     string
+	ExpiresAt time.Time
+
+	leaser *Leaser
+	mu     sync.Mutex
+	key    Secret
+	ended  bool
+	stop   []func() bool
+}
+
+// NewLeaser returns a Leaser over the instances and labels in store
+func NewLeaser(store Store) *Leaser {
+	return &Leaser{store: store, now: time.Now, active: map[*Credential]struct{}{}, usage: map[string]*LeaseUsage{}}
+}
+
+// Lease leases the API key of the instance with ID labelOrInstance or,
+// if there is none, of the first active instance with a key that the label
+// labelOrInstance applies to. The lease ends after ttl or when ctx is done,
+// whichever is first, and its key is wiped. Names that match nothing give
+// an error wrapping ErrNotFound. A non-positive ttl, or an instance that is
+// inactive, has no key or does not authenticate with a static key, gives
+// one wrapping ErrInvalidOption.
+func (l *Leaser) Lease(ctx context.Context, labelOrInstance string, ttl time.Duration) (*Credential, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lease %q: ttl %v is not positive: %w", labelOrInstance, ttl, ErrInvalidOption)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	selection, err := l.resolve(labelOrInstance)
+	if err != nil {
+		return nil, err
+	}
+	instance := selection.Instance
+
+	c := &Credential{
+		InstanceID:   instance.ID,
+		ProviderType: instance.ProviderType,
+		BaseURL:      instance.BaseURL,
+		Model:        selection.Model,
+		ExpiresAt:    l.now().Add(ttl),
+		leaser:       l,
+	}
+	instance.APIKey.Use(func(key []byte) { c.key = NewSecret(key) })
+
+	l.mu.Lock()
+	l.active[c] = struct{}{}
+	u := l.usageFor(instance.ID)
+	u.Leases++
+	u.Active++
+	l.mu.Unlock()
+
+	c.mu.Lock()
+	timer := time.AfterFunc(ttl, c.Release)
+	c.stop = []func() bool{timer.Stop, context.AfterFunc(ctx, c.Release)}
+	c.mu.Unlock()
+	return c, nil
+}
+
+// resolve finds the instance to lease: by ID, else by label
+func (l *Leaser) resolve(name string) (Selection, error) {
+	instance, err := l.store.GetInstance(name)
+	if err == nil {
+		if err := leasable(*instance); err != nil {
+			return Selection{}, err
+		}
+		return Selection{Instance: *instance}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return Selection{}, err
+	}
+	instances, err := l.store.LoadInstances()
+	if err != nil {
+		return Selection{}, err
+	}
+	labels, err := l.store.LoadLabels()
+	if err != nil {
+		return Selection{}, err
+	}
+	selections := ResolveLabel(instances, labels, name)
+	if len(selections) == 0 {
+		return Selection{}, fmt.Errorf("lease %q: no instance or label of that name: %w", name, ErrNotFound)
+	}
+	for _, s := range selections {
+		if leasable(s.Instance) == nil {
+			return s, nil
+		}
+	}
+	return Selection{}, fmt.Errorf("lease %q: no active instance with an API key has the label: %w", name, ErrInvalidOption)
+}
+
+// leasable reports why instance's key cannot be leased, if it cannot
+func leasable(instance ProviderInstance) error {
+	switch {
+	case !instance.Active:
+		return fmt.Errorf("lease %q: instance is inactive: %w", instance.ID, ErrInvalidOption)
+	case instance.APIKey.IsZero():
+		return fmt.Errorf("lease %q: instance has no API key: %w", instance.ID, ErrInvalidOption)
+	}
+	if auth := instance.Metadata[MetadataAuth]; auth != "" && auth != AuthAPIKey {
+		return fmt.Errorf("lease %q: instance authenticates with %s, not a static key; use TokenSource: %w", instance.ID, auth, ErrInvalidOption)
+	}
+	return nil
+}
+
+// Usage returns what the Leaser counted, by instance ID
+func (l *Leaser) Usage() []LeaseUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	usage := make([]LeaseUsage, 0, len(l.usage))
+	for _, u := range l.usage {
+		usage = append(usage, *u)
+	}
+	slices.SortFunc(usage, func(a, b LeaseUsage) int { return strings.Compare(a.InstanceID, b.InstanceID) })
+	return usage
+}
+
+// Close ends every active lease
+func (l *Leaser) Close() {
+	l.mu.Lock()
+	active := make([]*Credential, 0, len(l.active))
+	for c := range l.active {
+		active = append(active, c)
+	}
+	l.mu.Unlock()
+	for _, c := range active {
+		c.Release()
+	}
+}
+
+// usageFor returns the counters for an instance. The caller holds l.mu.
+func (l *Leaser) usageFor(id string) *LeaseUsage {
+	u, ok := l.usage[id]
+	if !ok {
+		u = &LeaseUsage{InstanceID: id}
+		l.usage[id] = u
+	}
+	return u
+}
+
+// Use calls fn with the key and counts a use. fn must not retain or modify
+// the slice. After the lease ends it returns an error wrapping
+// ErrLeaseExpired.
+func (c *Credential) Use(fn func(key []byte)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	c.key.Use(fn)
+	return nil
+}
+
+// Authorize sets the key in the header c's provider expects, as the
+// static-key TokenSource does, and counts a use. After the lease ends it
+// returns an error wrapping ErrLeaseExpired.
+func (c *Credential) Authorize(ctx context.Context, req *http.Request) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	return staticSource{token: Token{Value: c.key, Type: "Bearer"}, providerType: c.ProviderType}.Authorize(ctx, req)
+}
+
+// Expired reports whether the lease has ended
+func (c *Credential) Expired() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ended || !c.leaser.now().Before(c.ExpiresAt)
+}
+
+// Release ends the lease and wipes the key. It is safe to call more than
+// once, and is called when the lease expires or its context is done.
+func (c *Credential) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.end()
+}
+
+// check ends the lease if it has expired and otherwise counts a use. The
+// caller holds c.mu.
+func (c *Credential) check() error {
+	now := c.leaser.now()
+	if !c.ended && !now.Before(c.ExpiresAt) {
+		c.end()
+	}
+	if c.ended {
+		return fmt.Errorf("instance %q: %w", c.InstanceID, ErrLeaseExpired)
+	}
+	c.leaser.mu.Lock()
+	u := c.leaser.usageFor(c.InstanceID)
+	u.Uses++
+	u.LastUsedAt = now
+	c.leaser.mu.Unlock()
+	return nil
+}
+
+// end wipes the key and updates the Leaser's counts. The caller holds
+// c.mu.
+func (c *Credential) end() {
+	if c.ended {
+		return
+	}
+	c.ended = true
+	c.key.Destroy()
+	for _, stop := range c.stop {
+		stop()
+	}
+	c.leaser.mu.Lock()
+	delete(c.leaser.active, c)
+	c.leaser.usageFor(c.InstanceID).Active--
+	c.leaser.mu.Unlock()
+}
@@ -0,0 +1,141 @@
+package aicred
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func leaseStore() *MemoryStore {
+	return NewMemoryStore([]ProviderInstance{
+		{ID: "anthropic-main", ProviderType: "anthropic", BaseURL: "https://api.anthropic.com", Active: true, APIKey: NewSecretString("sk-ant-a"), Models: []string{"claude"}},
+		{ID: "openai-main", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", Active: true, APIKey: NewSecretString("sk-b"), Models: []string{"gpt-4o"}},
+		{ID: "openai-off", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", APIKey: NewSecretString("sk-c"), Models: []string{"gpt-4o"}},
+		{ID: "oauth", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", Active: true, APIKey: NewSecretString("secret"), Metadata: map[string]string{MetadataAuth: AuthOAuthClientCredentials}},
+	}, []LabelAssignment{
+		{LabelName: "fast", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "openai-off", ModelID: "gpt-4o"}},
+		{LabelName: "fast", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "openai-main", ModelID: "gpt-4o"}},
+		{LabelName: "dead", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "openai-off"}},
+	})
+}
+
+func TestLease(t *testing.T) {
+	store := leaseStore()
+	l := NewLeaser(store)
+	ctx := context.Background()
+
+	c, err := l.Lease(ctx, "fast", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.InstanceID != "openai-main" || c.Model != "gpt-4o" {
+		t.Errorf("lease of fast = %s/%s, want the active openai-main/gpt-4o", c.InstanceID, c.Model)
+	}
+	var key string
+	if err := c.Use(func(b []byte) { key = string(b) }); err != nil || key != "sk-b" {
+		t.Errorf("Use = %q, %v", key, err)
+	}
+
+	byID, err := l.Lease(ctx, "anthropic-main", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil)
+	if err := byID.Authorize(ctx, req); err != nil || req.Header.Get("x-api-key") != "sk-ant-a" {
+		t.Errorf("Authorize = %v, headers %v", err, req.Header)
+	}
+
+	c.Release()
+	if err := c.Use(func([]byte) {}); !errors.Is(err, ErrLeaseExpired) {
+		t.Errorf("Use after Release = %v, want ErrLeaseExpired", err)
+	}
+	if !c.key.IsZero() {
+		t.Error("Release should wipe the leased key")
+	}
+	if instance, _ := store.GetInstance("openai-main"); instance.APIKey.Reveal() != "sk-b" {
+		t.Error("Release should not wipe the stored key")
+	}
+	c.Release()
+
+	usage := l.Usage()
+	if len(usage) != 2 || usage[1].InstanceID != "openai-main" || usage[1].Leases != 1 || usage[1].Active != 0 || usage[1].Uses != 1 {
+		t.Errorf("Usage = %+v", usage)
+	}
+	if usage[0].Active != 1 || usage[0].Uses != 1 || usage[0].LastUsedAt.IsZero() {
+		t.Errorf("anthropic usage = %+v", usage[0])
+	}
+	l.Close()
+	if !byID.Expired() || l.Usage()[0].Active != 0 {
+		t.Error("Close should end every lease")
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	l := NewLeaser(leaseStore())
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	c, err := l.Lease(context.Background(), "openai-main", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	if !c.Expired() {
+		t.Error("lease should expire at its ttl")
+	}
+	if err := c.Use(func([]byte) {}); !errors.Is(err, ErrLeaseExpired) || !c.key.IsZero() {
+		t.Errorf("Use after expiry = %v, want ErrLeaseExpired and a wiped key", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err = l.Lease(ctx, "openai-main", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for !c.Expired() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !c.Expired() {
+		t.Error("lease should end when its context is done")
+	}
+
+	timed := NewLeaser(leaseStore())
+	c, err = timed.Lease(context.Background(), "openai-main", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !c.key.IsZero() {
+		t.Error("the key should be wiped when the ttl passes, without a call")
+	}
+}
+
+func TestLeaseErrors(t *testing.T) {
+	l := NewLeaser(leaseStore())
+	ctx := context.Background()
+	for name, want := range map[string]error{
+		"missing":    ErrNotFound,
+		"dead":       ErrInvalidOption,
+		"openai-off": ErrInvalidOption,
+		"oauth":      ErrInvalidOption,
+	} {
+		if _, err := l.Lease(ctx, name, time.Minute); !errors.Is(err, want) {
+			t.Errorf("Lease(%q) = %v, want %v", name, err, want)
+		}
+	}
+	if _, err := l.Lease(ctx, "openai-main", 0); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Lease with zero ttl = %v, want ErrInvalidOption", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := l.Lease(cancelled, "openai-main", time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Lease with a done context = %v", err)
+	}
+	if len(l.Usage()) != 0 {
+		t.Errorf("failed leases were counted: %+v", l.Usage())
+	}
+}
@@ -0,0 +1,345 @@
+package aicred
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A minimal read-only LevelDB reader, enough to recover the records of a
+// browser storage directory. It reads sorted tables (.ldb, .sst) and the
+// write-ahead log (.log) and does not verify checksums.
+
+// leveldbTableMagic ends every LevelDB table file
+const leveldbTableMagic = 0xdb4775248b80fb57
+
+// leveldbLogBlockSize is the fixed block size of LevelDB log files
+const leveldbLogBlockSize = 32768
+
+// leveldbMaxTableBytes bounds the block data decoded from one table. A
+// damaged index can name overlapping blocks, each decoded in full; LevelDB
+// writes tables of a few megabytes.
+const leveldbMaxTableBytes = 64 << 20
+
+var (
+	errLevelDBCorrupt  = errors.New("corrupt leveldb data")
+	errLevelDBTooLarge = errors.New("leveldb table decodes to too much data")
+)
+
+// leveldbRecords calls fn for every live key and value in the LevelDB
+// directory dir, skipping files larger than limit. The same key may be
+// reported more than once if it was rewritten. Files read, skipped or
+// unreadable are recorded in run under pass.
+func leveldbRecords(dir string, limit int64, pass string, run *scanRun, fn func(key, value []byte)) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if ext != ".ldb" && ext != ".sst" && ext != ".log" {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info.Size() > limit {
+			run.warn(pass, p, WarningTooLarge, fmt.Errorf("%d bytes exceeds the limit of %d", info.Size(), limit))
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			run.warn(pass, p, warningKindOf(err), err)
+			continue
+		}
+		run.read(int64(len(data)))
+		if ext == ".log" {
+			err = leveldbLog(data, fn)
+		} else {
+			err = leveldbTable(data, fn)
+		}
+		zero(data)
+		if err != nil {
+			logger().Debug("leveldb file partly read", slog.String("path", p), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// leveldbTable reads every data block of a sorted table. A block the index
+// names more than once is read once, and reading stops with
+// errLevelDBTooLarge after leveldbMaxTableBytes of decoded blocks.
+func leveldbTable(data []byte, fn func(key, value []byte)) error {
+	if len(data) < 48 || binary.LittleEndian.Uint64(data[len(data)-8:]) != leveldbTableMagic {
+		return errLevelDBCorrupt
+	}
+	footer := data[len(data)-48:]
+	_, n := leveldbHandle(footer) // metaindex
+	if n <= 0 {
+		return errLevelDBCorrupt
+	}
+	index, m := leveldbHandle(footer[n:])
+	if m <= 0 {
+		return errLevelDBCorrupt
+	}
+	indexBlock, err := leveldbBlock(data, index)
+	if err != nil {
+		return err
+	}
+	seen := make(map[leveldbBlockHandle]bool)
+	decoded := 0
+	return leveldbBlockEntries(indexBlock, func(_, handle []byte) error {
+		h, n := leveldbHandle(handle)
+		if n <= 0 {
+			return errLevelDBCorrupt
+		}
+		if seen[h] {
+			return nil
+		}
+		seen[h] = true
+		block, err := leveldbBlock(data, h)
+		if err != nil {
+			return err
+		}
+		defer zero(block)
+		if decoded += len(block); decoded > leveldbMaxTableBytes {
+			return errLevelDBTooLarge
+		}
+		return leveldbBlockEntries(block, func(ikey, value []byte) error {
+			// Internal keys end with 8 bytes of sequence number and type;
+			// type 1 is a value, 0 a deletion
+			if len(ikey) >= 8 && ikey[len(ikey)-8] == 1 {
+				fn(ikey[:len(ikey)-8], value)
+			}
+			return nil
+		})
+	})
+}
+
+type leveldbBlockHandle struct{ offset, size uint64 }
+
+func leveldbHandle(b []byte) (leveldbBlockHandle, int) {
+	offset, n := binary.Uvarint(b)
+	if n <= 0 {
+		return leveldbBlockHandle{}, n
+	}
+	size, m := binary.Uvarint(b[n:])
+	if m <= 0 {
+		return leveldbBlockHandle{}, m
+	}
+	return leveldbBlockHandle{offset, size}, n + m
+}
+
+// leveldbBlock returns the contents of a block, decompressing it if needed
+func leveldbBlock(data []byte, h leveldbBlockHandle) ([]byte, error) {
+	// Each block is followed by a compression type byte and a checksum
+	if h.offset > uint64(len(data)) || h.size > uint64(len(data))-h.offset || h.offset+h.size+5 > uint64(len(data)) {
+		return nil, errLevelDBCorrupt
+	}
+	block := data[h.offset : h.offset+h.size]
+	switch data[h.offset+h.size] {
+	case 0:
+		return append([]byte(nil), block...), nil
+	case 1:
+		return snappyDecode(block)
+	}
+	return nil, errLevelDBCorrupt
+}
+
+// leveldbBlockEntries walks the prefix-compressed entries of a block
+func leveldbBlockEntries(block []byte, fn func(key, value []byte) error) error {
+	if len(block) < 4 {
+		return errLevelDBCorrupt
+	}
+	restarts := uint64(binary.LittleEndian.Uint32(block[len(block)-4:]))
+	if restarts*4+4 > uint64(len(block)) {
+		return errLevelDBCorrupt
+	}
+	entries := block[:uint64(len(block))-4-restarts*4]
+
+	var key []byte
+	for len(entries) > 0 {
+		shared, n1 := binary.Uvarint(entries)
+		if n1 <= 0 {
+			return errLevelDBCorrupt
+		}
+		unshared, n2 := binary.Uvarint(entries[n1:])
+		if n2 <= 0 {
+			return errLevelDBCorrupt
+		}
+		valueLen, n3 := binary.Uvarint(entries[n1+n2:])
+		if n3 <= 0 {
+			return errLevelDBCorrupt
+		}
+		rest := entries[n1+n2+n3:]
+		if shared > uint64(len(key)) || unshared > uint64(len(rest)) || valueLen > uint64(len(rest))-unshared {
+			return errLevelDBCorrupt
+		}
+		key = append(key[:shared], rest[:unshared]...)
+		value := rest[unshared : unshared+valueLen]
+		if err := fn(key, value); err != nil {
+			return err
+		}
+		entries = rest[unshared+valueLen:]
+	}
+	return nil
+}
+
+// leveldbLog reassembles the write batches in a log file and reports the
+// values they put
+func leveldbLog(data []byte, fn func(key, value []byte)) error {
+	var record []byte
+	for block := 0; block < len(data); block += leveldbLogBlockSize {
+		b := data[block:min(block+leveldbLogBlockSize, len(data))]
+		for len(b) >= 7 {
+			length := int(binary.LittleEndian.Uint16(b[4:6]))
+			kind := b[6]
+			if kind == 0 || 7+length > len(b) {
+				// Zero padding or a torn write ends the block
+				break
+			}
+			chunk := b[7 : 7+length]
+			b = b[7+length:]
+			switch kind {
+			case 1: // full
+				leveldbBatch(chunk, fn)
+			case 2: // first
+				record = append(record[:0], chunk...)
+			case 3: // middle
+				record = append(record, chunk...)
+			case 4: // last
+				leveldbBatch(append(record, chunk...), fn)
+				record = record[:0]
+			}
+		}
+	}
+	zero(record)
+	return nil
+}
+
+// leveldbBatch decodes a write batch: an 8 byte sequence number, a 4 byte
+// count, then tagged put and delete operations
+func leveldbBatch(batch []byte, fn func(key, value []byte)) {
+	if len(batch) < 12 {
+		return
+	}
+	b := batch[12:]
+	next := func() ([]byte, bool) {
+		n, m := binary.Uvarint(b)
+		if m <= 0 || n > uint64(len(b)-m) {
+			return nil, false
+		}
+		s := b[m : m+int(n)]
+		b = b[m+int(n):]
+		return s, true
+	}
+	for len(b) > 0 {
+		tag := b[0]
+		b = b[1:]
+		key, ok := next()
+		if !ok {
+			return
+		}
+		switch tag {
+		case 1:
+			value, ok := next()
+			if !ok {
+				return
+			}
+			fn(key, value)
+		case 0:
+		default:
+			return
+		}
+	}
+}
+
+// snappyDecode decompresses a Snappy block, as used by LevelDB tables and
+// Firefox local storage
+func snappyDecode(src []byte) ([]byte, error) {
+	n, m := binary.Uvarint(src)
+	// A copy element expands at most 64 bytes from 3, so larger claims are
+	// corrupt and would only force a large allocation
+	if m <= 0 || n > 1<<28 || n > uint64(len(src))*22 {
+		return nil, errLevelDBCorrupt
+	}
+	dst := make([]byte, 0, n)
+	src = src[m:]
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0: // literal
+			length := int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errLevelDBCorrupt
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			length++
+			if length <= 0 || length > len(src) {
+				return nil, errLevelDBCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, errLevelDBCorrupt
+			}
+			length := 4 + int(tag>>2&7)
+			offset := int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+			if err := snappyCopy(&dst, offset, length); err != nil {
+				return nil, err
+			}
+		case 2:
+			if len(src) < 3 {
+				return nil, errLevelDBCorrupt
+			}
+			offset := int(binary.LittleEndian.Uint16(src[1:3]))
+			if err := snappyCopy(&dst, offset, 1+int(tag>>2)); err != nil {
+				return nil, err
+			}
+			src = src[3:]
+		case 3:
+			if len(src) < 5 {
+				return nil, errLevelDBCorrupt
+			}
+			offset := int(binary.LittleEndian.Uint32(src[1:5]))
+			if err := snappyCopy(&dst, offset, 1+int(tag>>2)); err != nil {
+				return nil, err
+			}
+			src = src[5:]
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, errLevelDBCorrupt
+	}
+	return dst, nil
+}
+
+// snappyCopy appends length bytes starting offset bytes back; the ranges
+// may overlap
+func snappyCopy(dst *[]byte, offset, length int) error {
+	d := *dst
+	if offset <= 0 || offset > len(d) || len(d)+length > cap(d) {
+		return errLevelDBCorrupt
+	}
+	for i := 0; i < length; i++ {
+		d = append(d, d[len(d)-offset])
+	}
+	*dst = d
+	return nil
+}
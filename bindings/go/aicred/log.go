@@ -0,0 +1,109 @@
+package aicred
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// redactedValue replaces sensitive attribute values in log records
+const redactedValue = "[REDACTED]"
+
+// sensitiveLogKeys are attribute keys whose values are never logged
+var sensitiveLogKeys = map[string]bool{
+	"value":      true,
+	"api_key":    true,
+	"apikey":     true,
+	"secret":     true,
+	"token":      true,
+	"password":   true,
+	"full_value": true,
+}
+
+var pkgLogger atomic.Pointer[slog.Logger]
+
+func init() {
+	pkgLogger.Store(slog.New(discardHandler{}))
+}
+
+// SetLogger installs the logger used for debug and diagnostic output.
+// Attributes with secret-looking keys are redacted before reaching the handler.
+// Passing nil restores the default, which discards all output.
+func SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		pkgLogger.Store(slog.New(discardHandler{}))
+		return
+	}
+	pkgLogger.Store(slog.New(&redactingHandler{next: logger.Handler()}))
+}
+
+// logger returns the current package logger
+func logger() *slog.Logger {
+	return pkgLogger.Load()
+}
+
+// LogValue implements slog.LogValuer so keys never log their full value
+func (k DiscoveredKey) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("provider", k.Provider),
+		slog.String("source", k.Source),
+		slog.String("redacted", k.Redacted),
+		slog.String("confidence", k.Confidence),
+	)
+}
+
+// redactingHandler scrubs sensitive attributes before passing records on
+type redactingHandler struct {
+	next slog.Handler
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	clean := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		clean.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		clean[i] = redactAttr(attr)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name)}
+}
+
+// redactAttr replaces sensitive values, descending into groups
+func redactAttr(attr slog.Attr) slog.Attr {
+	if sensitiveLogKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redactedValue)
+	}
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		return slog.Attr{Key: attr.Key, Value: value}
+	}
+	group := value.Group()
+	clean := make([]any, len(group))
+	for i, a := range group {
+		clean[i] = redactAttr(a)
+	}
+	return slog.Group(attr.Key, clean...)
+}
+
+// discardHandler drops every record; slog.DiscardHandler needs Go 1.24
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,61 @@
+package aicred
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { SetLogger(nil) })
+	return &buf
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	buf := captureLogs(t)
+
+	key := DiscoveredKey{Provider: "openai", Redacted: "sk-****abcd", Value: NewSecretString("sk-live-secret")}
+	logger().Info("found key", slog.Any("key", key), slog.String("api_key", "sk-other-secret"))
+	logger().With(slog.String("token", "hf_secret")).Info("with attrs")
+	logger().Info("grouped", slog.Group("creds", slog.String("secret", "nested-secret")))
+
+	out := buf.String()
+	for _, leaked := range []string{"sk-live-secret", "sk-other-secret", "hf_secret", "nested-secret"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("log output leaked %q:\n%s", leaked, out)
+		}
+	}
+	if !strings.Contains(out, "key.redacted=sk-****abcd") {
+		t.Errorf("expected redacted key in output:\n%s", out)
+	}
+}
+
+func TestScanLogs(t *testing.T) {
+	buf := captureLogs(t)
+
+	tmpDir, err := os.MkdirTemp("", "aicred-log-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if _, err := Scan(ScanOptions{HomeDir: tmpDir}); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "scan complete") {
+		t.Errorf("expected scan completion log, got:\n%s", buf.String())
+	}
+}
+
+func TestDefaultLoggerIsSilent(t *testing.T) {
+	SetLogger(nil)
+	if logger().Enabled(context.Background(), slog.LevelError) {
+		t.Error("default logger should discard output")
+	}
+}
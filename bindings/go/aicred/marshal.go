@@ -0,0 +1,44 @@
+package aicred
+
+import "encoding/json"
+
+// Secret values marshal as null, so MarshalWithSecrets encodes through these
+// mirrors, which reveal each key's value explicitly.
+
+type revealedKey struct {
+	DiscoveredKey
+	Value string `json:"value,omitempty"`
+}
+
+type revealedInstance struct {
+	ConfigInstance
+	Keys []revealedKey `json:"keys"`
+}
+
+type revealedResult struct {
+	ScanResult
+	Keys            []revealedKey      `json:"keys"`
+	ConfigInstances []revealedInstance `json:"config_instances"`
+}
+
+// MarshalWithSecrets encodes the result as JSON including full key values.
+// Plain json.Marshal never includes them; use this only when the output is
+// meant to carry secrets.
+func (r *ScanResult) MarshalWithSecrets() ([]byte, error) {
+	out := revealedResult{ScanResult: *r, Keys: revealKeys(r.Keys)}
+	for _, inst := range r.ConfigInstances {
+		out.ConfigInstances = append(out.ConfigInstances, revealedInstance{ConfigInstance: inst, Keys: revealKeys(inst.Keys)})
+	}
+	return json.Marshal(out)
+}
+
+func revealKeys(keys []DiscoveredKey) []revealedKey {
+	if keys == nil {
+		return nil
+	}
+	out := make([]revealedKey, len(keys))
+	for i, key := range keys {
+		out[i] = revealedKey{DiscoveredKey: key, Value: key.Value.Reveal()}
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+// Package mcp implements a Model Context Protocol server over stdio that
+// exposes aicred's provider listings, redacted scan inventory, label
+// resolution and cost estimates as MCP tools, so Claude Desktop and other
+// MCP clients can query them directly.
+//
+// Messages are newline-delimited JSON-RPC 2.0, as in the MCP stdio transport.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// ProtocolVersion is the MCP revision this server implements
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Instance metadata keys estimate_cost reads a price from when
+// Options.Prices has none for the model, in US dollars per token as in the
+// core library's model pricing
+const (
+	MetadataInputCostPerToken  = "input_cost_per_token"
+	MetadataOutputCostPerToken = "output_cost_per_token"
+)
+
+// Options configures the server
+type Options struct {
+	// HomeDir is scanned by the scan tool; empty means the user's home directory
+	HomeDir string
+	// Store holds the instances and labels resolve_label and estimate_cost
+	// read. Nil means a Session over HomeDir, opened on first use.
+	Store aicred.Store
+	// Prices are model prices for estimate_cost, by model ID
+	Prices map[string]Price
+}
+
+// Price is what a model costs, in US dollars per million tokens
+type Price struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// Server answers MCP requests
+type Server struct {
+	opts  Options
+	scan  func(aicred.ScanOptions) (*aicred.ScanResult, error)
+	tools map[string]tool
+
+	storeMu sync.Mutex
+	store   aicred.Store
+}
+
+// New creates a Server backed by aicred.Scan
+func New(opts Options) *Server {
+	s := &Server{opts: opts, scan: aicred.Scan, store: opts.Store}
+	s.tools = s.builtinTools()
+	return s
+}
+
+// configStore returns Options.Store, or opens a Session over HomeDir the
+// first time one is needed. A failed open is retried on the next call.
+func (s *Server) configStore() (aicred.Store, error) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	if s.store == nil {
+		session, err := aicred.OpenSession(s.opts.HomeDir)
+		if err != nil {
+			return nil, err
+		}
+		s.store = session
+	}
+	return s.store, nil
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted or ctx is cancelled
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if resp := s.handle(ctx, line); resp != nil {
+			if err := enc.Encode(resp); err != nil {
+				return fmt.Errorf("failed to write response: %v", err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// handle processes one message, returning nil for notifications
+func (s *Server) handle(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "parse error: "+err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(idOrNull(req.ID), codeInvalidRequest, "invalid request")
+	}
+	isNotification := len(req.ID) == 0
+
+	var result any
+	var rerr *rpcError
+	switch req.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "aicred", "version": aicred.Version()},
+		}
+	case "ping":
+		result = map[string]any{}
+	case "tools/list":
+		result = map[string]any{"tools": s.toolList()}
+	case "tools/call":
+		result, rerr = s.callTool(ctx, req.Params)
+	default:
+		if isNotification {
+			// Notifications such as notifications/initialized need no reply
+			return nil
+		}
+		rerr = &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	if isNotification {
+		return nil
+	}
+	if rerr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func errorResponse(id json.RawMessage, code int, msg string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: msg}}
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
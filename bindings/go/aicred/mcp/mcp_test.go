@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func serve(t *testing.T, s *Server, lines ...string) []response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	var responses []response
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp response
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func testServer() *Server {
+	s := New(Options{HomeDir: "/home/u"})
+	s.scan = func(opts aicred.ScanOptions) (*aicred.ScanResult, error) {
+		key := aicred.DiscoveredKey{Provider: "openai", Redacted: "sk-****abcd", Value: aicred.NewSecretString("sk-secret")}
+		return &aicred.ScanResult{
+			HomeDir:         opts.HomeDir,
+			Keys:            []aicred.DiscoveredKey{key},
+			ConfigInstances: []aicred.ConfigInstance{{InstanceID: "i1", AppName: "roo-code", Keys: []aicred.DiscoveredKey{key}}},
+		}, nil
+	}
+	return s
+}
+
+func TestInitializeAndList(t *testing.T) {
+	responses := serve(t, testServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+	)
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses (notification gets none), got %d", len(responses))
+	}
+
+	init, _ := json.Marshal(responses[0].Result)
+	if !strings.Contains(string(init), `"protocolVersion":"2024-11-05"`) {
+		t.Errorf("unexpected initialize result: %s", init)
+	}
+
+	list, _ := json.Marshal(responses[1].Result)
+	for _, name := range []string{"list_providers", "list_scanners", "scan_keys", "resolve_label", "estimate_cost"} {
+		if !strings.Contains(string(list), `"name":"`+name+`"`) {
+			t.Errorf("tools/list missing %s: %s", name, list)
+		}
+	}
+}
+
+func TestScanToolIsRedacted(t *testing.T) {
+	responses := serve(t, testServer(),
+		`{"jsonrpc":"2.0","id":"a","method":"tools/call","params":{"name":"scan_keys","arguments":{"only_providers":["openai"]}}}`,
+	)
+	if len(responses) != 1 || responses[0].Error != nil {
+		t.Fatalf("unexpected responses: %+v", responses)
+	}
+	out, _ := json.Marshal(responses[0].Result)
+	if strings.Contains(string(out), "sk-secret") {
+		t.Errorf("scan tool leaked a full value: %s", out)
+	}
+	if !strings.Contains(string(out), "sk-****abcd") {
+		t.Errorf("expected redacted key in output: %s", out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	responses := serve(t, testServer(),
+		`not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"nope"}}`,
+	)
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(responses))
+	}
+	wantCodes := []int{codeParseError, codeMethodNotFound, codeInvalidParams}
+	for i, want := range wantCodes {
+		if responses[i].Error == nil || responses[i].Error.Code != want {
+			t.Errorf("response %d: expected error code %d, got %+v", i, want, responses[i].Error)
+		}
+	}
+}
+
+func configServer() *Server {
+	s := testServer()
+	s.opts.Prices = map[string]Price{"gpt-4o-mini": {InputPerMillion: 0.15, OutputPerMillion: 0.6}}
+	s.store = aicred.NewMemoryStore([]aicred.ProviderInstance{
+		{ID: "groq", ProviderType: "groq", BaseURL: "https://api.groq.com/openai/v1", Models: []string{"llama-3.1-8b"},
+			Metadata: map[string]string{MetadataInputCostPerToken: "0.00000005", MetadataOutputCostPerToken: "0.00000008"}},
+		{ID: "local", ProviderType: "ollama", BaseURL: "http://localhost:11434", Models: []string{"llama3"}},
+		{ID: "openai", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", APIKey: aicred.NewSecretString("sk-secret"), Models: []string{"gpt-4o-mini"}},
+	}, []aicred.LabelAssignment{
+		{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetModel, InstanceID: "openai", ModelID: "gpt-4o-mini"}},
+		{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetInstance, InstanceID: "groq"}},
+		{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetInstance, InstanceID: "local"}},
+	})
+	return s
+}
+
+// callText returns the text of a tools/call result and whether it is an
+// error
+func callText(t *testing.T, resp response) (string, bool) {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("rpc error: %+v", resp.Error)
+	}
+	data, _ := json.Marshal(resp.Result)
+	var result callResult
+	if err := json.Unmarshal(data, &result); err != nil || len(result.Content) != 1 {
+		t.Fatalf("bad result %s: %v", data, err)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestResolveLabelTool(t *testing.T) {
+	responses := serve(t, configServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"resolve_label","arguments":{"label":"fast"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"resolve_label","arguments":{}}}`,
+	)
+	text, isError := callText(t, responses[0])
+	if isError || strings.Contains(text, "sk-secret") {
+		t.Fatalf("resolve_label = %s", text)
+	}
+	var out struct {
+		Models []resolvedModel `json:"models"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Models) != 3 || out.Models[2].InstanceID != "openai" || out.Models[2].Model != "gpt-4o-mini" || !out.Models[2].HasAPIKey {
+		t.Errorf("models = %+v", out.Models)
+	}
+	if _, isError := callText(t, responses[1]); !isError {
+		t.Error("resolve_label without a label succeeded")
+	}
+}
+
+func TestEstimateCostTool(t *testing.T) {
+	responses := serve(t, configServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"estimate_cost","arguments":{"label":"fast","input_tokens":1000000,"output_tokens":500000}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"estimate_cost","arguments":{"model":"gpt-4o-mini","input_tokens":2000,"output_tokens":0}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"estimate_cost","arguments":{"model":"unknown","input_tokens":1,"output_tokens":1}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"estimate_cost","arguments":{"label":"fast","model":"x","input_tokens":1,"output_tokens":1}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"estimate_cost","arguments":{"model":"gpt-4o-mini","input_tokens":-1,"output_tokens":1}}}`,
+	)
+	var out struct {
+		Estimates []costEstimate `json:"estimates"`
+		Unpriced  []string       `json:"unpriced"`
+	}
+	text, isError := callText(t, responses[0])
+	if isError {
+		t.Fatal(text)
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Estimates) != 2 || len(out.Unpriced) != 1 || out.Unpriced[0] != "local/llama3" {
+		t.Fatalf("estimate for fast = %s", text)
+	}
+	near := func(got, want float64) bool { return got > want-1e-9 && got < want+1e-9 }
+	if e := out.Estimates[0]; e.InstanceID != "groq" || !near(e.TotalCost, 0.05+0.04) {
+		t.Errorf("groq estimate = %+v", e)
+	}
+	if e := out.Estimates[1]; e.InstanceID != "openai" || !near(e.InputCost, 0.15) || !near(e.OutputCost, 0.3) || e.Currency != "USD" {
+		t.Errorf("openai estimate = %+v", e)
+	}
+
+	text, _ = callText(t, responses[1])
+	out.Estimates = nil
+	if err := json.Unmarshal([]byte(text), &out); err != nil || len(out.Estimates) != 1 || !near(out.Estimates[0].TotalCost, 0.0003) {
+		t.Errorf("estimate for gpt-4o-mini = %s", text)
+	}
+	for _, resp := range responses[2:] {
+		if text, isError := callText(t, resp); !isError {
+			t.Errorf("request %s succeeded: %s", resp.ID, text)
+		}
+	}
+}
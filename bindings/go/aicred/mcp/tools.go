@@ -0,0 +1,379 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// tool is a single MCP tool
+type tool struct {
+	description string
+	inputSchema map[string]any
+	run         func(ctx context.Context, args json.RawMessage) (any, error)
+}
+
+type toolInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type callParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type callResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// scanArgs are the arguments accepted by the scan_keys tool
+type scanArgs struct {
+	OnlyProviders    []string `json:"only_providers,omitempty"`
+	ExcludeProviders []string `json:"exclude_providers,omitempty"`
+}
+
+// redactedKey is what the scan_keys tool reports for each key; it has no
+// field that could carry a full value
+type redactedKey struct {
+	Provider   string `json:"provider"`
+	Source     string `json:"source"`
+	ValueType  string `json:"value_type"`
+	Confidence string `json:"confidence"`
+	Redacted   string `json:"redacted"`
+}
+
+type redactedInstance struct {
+	InstanceID string        `json:"instance_id"`
+	AppName    string        `json:"app_name"`
+	ConfigPath string        `json:"config_path"`
+	Keys       []redactedKey `json:"keys"`
+}
+
+// resolveArgs are the arguments accepted by the resolve_label tool
+type resolveArgs struct {
+	Label string `json:"label"`
+}
+
+// resolvedModel is one instance/model pair a label applies to. It never
+// carries the instance's key.
+type resolvedModel struct {
+	InstanceID   string `json:"instance_id"`
+	ProviderType string `json:"provider_type"`
+	BaseURL      string `json:"base_url"`
+	Model        string `json:"model"`
+	HasAPIKey    bool   `json:"has_api_key"`
+}
+
+// costArgs are the arguments accepted by the estimate_cost tool
+type costArgs struct {
+	Label        string `json:"label,omitempty"`
+	Model        string `json:"model,omitempty"`
+	InputTokens  int64  `json:"input_tokens"`
+	OutputTokens int64  `json:"output_tokens"`
+}
+
+// costEstimate is the cost of one request on one instance/model pair
+type costEstimate struct {
+	InstanceID string  `json:"instance_id,omitempty"`
+	Model      string  `json:"model"`
+	InputCost  float64 `json:"input_cost"`
+	OutputCost float64 `json:"output_cost"`
+	TotalCost  float64 `json:"total_cost"`
+	Currency   string  `json:"currency"`
+}
+
+func emptySchema() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+func (s *Server) builtinTools() map[string]tool {
+	providerList := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	return map[string]tool{
+		"list_providers": {
+			description: "List the provider plugins aicred can detect credentials for.",
+			inputSchema: emptySchema(),
+			run: func(context.Context, json.RawMessage) (any, error) {
+				return aicred.ListProviders(), nil
+			},
+		},
+		"list_scanners": {
+			description: "List the applications whose configuration aicred can scan.",
+			inputSchema: emptySchema(),
+			run: func(context.Context, json.RawMessage) (any, error) {
+				return aicred.ListScanners(), nil
+			},
+		},
+		"scan_keys": {
+			description: "Scan the user's home directory for GenAI API keys and application configs. Key values are always redacted.",
+			inputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"only_providers":    providerList,
+					"exclude_providers": providerList,
+				},
+			},
+			run: s.runScan,
+		},
+		"resolve_label": {
+			description: "Resolve an aicred label such as \"fast\" to the configured provider instances and models it is assigned to. Keys are never returned.",
+			inputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"label": map[string]any{"type": "string"}},
+				"required":   []string{"label"},
+			},
+			run: s.runResolve,
+		},
+		"estimate_cost": {
+			description: "Estimate the cost in US dollars of a request with the given token counts, for a model ID or for every model a label resolves to.",
+			inputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"label":         map[string]any{"type": "string"},
+					"model":         map[string]any{"type": "string"},
+					"input_tokens":  map[string]any{"type": "integer", "minimum": 0},
+					"output_tokens": map[string]any{"type": "integer", "minimum": 0},
+				},
+				"required": []string{"input_tokens", "output_tokens"},
+			},
+			run: s.runEstimateCost,
+		},
+	}
+}
+
+func (s *Server) toolList() []toolInfo {
+	names := make([]string, 0, len(s.tools))
+	for name := range s.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	infos := make([]toolInfo, 0, len(names))
+	for _, name := range names {
+		t := s.tools[name]
+		infos = append(infos, toolInfo{Name: name, Description: t.description, InputSchema: t.inputSchema})
+	}
+	return infos
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, *rpcError) {
+	var p callParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	t, ok := s.tools[p.Name]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+
+	// Tool failures are reported in the result so the model can see them
+	out, err := t.run(ctx, p.Arguments)
+	if err != nil {
+		return callResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	text, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return callResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return callResult{Content: []textContent{{Type: "text", Text: string(text)}}}, nil
+}
+
+func (s *Server) runScan(ctx context.Context, raw json.RawMessage) (any, error) {
+	var args scanArgs
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, err
+		}
+	}
+	result, err := s.scan(aicred.ScanOptions{
+		HomeDir:          s.opts.HomeDir,
+		OnlyProviders:    args.OnlyProviders,
+		ExcludeProviders: args.ExcludeProviders,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]redactedKey, 0, len(result.Keys))
+	for _, key := range result.Keys {
+		keys = append(keys, redactKey(key))
+	}
+	instances := make([]redactedInstance, 0, len(result.ConfigInstances))
+	for _, instance := range result.ConfigInstances {
+		ri := redactedInstance{
+			InstanceID: instance.InstanceID,
+			AppName:    instance.AppName,
+			ConfigPath: instance.ConfigPath,
+			Keys:       make([]redactedKey, 0, len(instance.Keys)),
+		}
+		for _, key := range instance.Keys {
+			ri.Keys = append(ri.Keys, redactKey(key))
+		}
+		instances = append(instances, ri)
+	}
+	return map[string]any{
+		"home_directory":   result.HomeDir,
+		"keys":             keys,
+		"config_instances": instances,
+	}, nil
+}
+
+func redactKey(key aicred.DiscoveredKey) redactedKey {
+	return redactedKey{
+		Provider:   key.Provider,
+		Source:     key.Source,
+		ValueType:  key.ValueType,
+		Confidence: key.Confidence,
+		Redacted:   key.Redacted,
+	}
+}
+
+func (s *Server) runResolve(ctx context.Context, raw json.RawMessage) (any, error) {
+	var args resolveArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Label == "" {
+		return nil, errors.New("label is required")
+	}
+	selections, err := s.resolve(args.Label)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]resolvedModel, 0, len(selections))
+	for _, sel := range selections {
+		models = append(models, resolvedModel{
+			InstanceID:   sel.Instance.ID,
+			ProviderType: sel.Instance.ProviderType,
+			BaseURL:      sel.Instance.BaseURL,
+			Model:        sel.Model,
+			HasAPIKey:    !sel.Instance.APIKey.IsZero(),
+		})
+	}
+	return map[string]any{"label": args.Label, "models": models}, nil
+}
+
+func (s *Server) runEstimateCost(ctx context.Context, raw json.RawMessage) (any, error) {
+	var args costArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if (args.Label == "") == (args.Model == "") {
+		return nil, errors.New("set exactly one of label and model")
+	}
+	if args.InputTokens < 0 || args.OutputTokens < 0 {
+		return nil, errors.New("token counts must not be negative")
+	}
+
+	var selections []aicred.Selection
+	if args.Label != "" {
+		var err error
+		if selections, err = s.resolve(args.Label); err != nil {
+			return nil, err
+		}
+	} else if _, priced := s.opts.Prices[args.Model]; priced {
+		// A listed price needs no configured instance
+		selections = []aicred.Selection{{Model: args.Model}}
+	} else {
+		store, err := s.configStore()
+		if err != nil {
+			return nil, err
+		}
+		instances, err := store.LoadInstances()
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			for _, model := range instance.Models {
+				if model == args.Model {
+					selections = append(selections, aicred.Selection{Instance: instance, Model: model})
+				}
+			}
+		}
+	}
+
+	estimates := []costEstimate{}
+	unpriced := []string{}
+	for _, sel := range selections {
+		price, ok := s.price(sel)
+		if !ok {
+			unpriced = append(unpriced, pairName(sel))
+			continue
+		}
+		input := float64(args.InputTokens) * price.InputPerMillion / 1e6
+		output := float64(args.OutputTokens) * price.OutputPerMillion / 1e6
+		estimates = append(estimates, costEstimate{
+			InstanceID: sel.Instance.ID,
+			Model:      sel.Model,
+			InputCost:  input,
+			OutputCost: output,
+			TotalCost:  input + output,
+			Currency:   "USD",
+		})
+	}
+	if len(estimates) == 0 && len(unpriced) == 0 {
+		return nil, errors.New("no configured model matches")
+	}
+	return map[string]any{"estimates": estimates, "unpriced": unpriced}, nil
+}
+
+// resolve resolves a label against the store's instances and labels
+func (s *Server) resolve(label string) ([]aicred.Selection, error) {
+	store, err := s.configStore()
+	if err != nil {
+		return nil, err
+	}
+	instances, err := store.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := store.LoadLabels()
+	if err != nil {
+		return nil, err
+	}
+	return aicred.ResolveLabel(instances, assignments, label), nil
+}
+
+// price returns the price of a pair from Options.Prices, or from the
+// instance's per-token metadata
+func (s *Server) price(sel aicred.Selection) (Price, bool) {
+	if p, ok := s.opts.Prices[sel.Model]; ok {
+		return p, true
+	}
+	input, ok := perToken(sel.Instance.Metadata[MetadataInputCostPerToken])
+	if !ok {
+		return Price{}, false
+	}
+	output, ok := perToken(sel.Instance.Metadata[MetadataOutputCostPerToken])
+	if !ok {
+		return Price{}, false
+	}
+	return Price{InputPerMillion: input * 1e6, OutputPerMillion: output * 1e6}, true
+}
+
+// perToken parses a per-token price, which must be a number no less than 0
+func perToken(value string) (float64, bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	return f, err == nil && f >= 0 && !math.IsInf(f, 0)
+}
+
+func pairName(sel aicred.Selection) string {
+	if sel.Instance.ID == "" {
+		return sel.Model
+	}
+	if sel.Model == "" {
+		return sel.Instance.ID
+	}
+	return sel.Instance.ID + "/" + sel.Model
+}
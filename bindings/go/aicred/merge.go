@@ -0,0 +1,332 @@
+package aicred
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// deleted stands in for a resource one side of a merge removed
+const deleted = "(deleted)"
+
+// Conflict is a change MergeConfigs could not reconcile: both sides
+// changed the same field of an instance or tag differently, both created
+// it differently, or one deleted what the other changed. The merged Config
+// holds ours in its place.
+type Conflict struct {
+	Kind ChangeKind
+	// Name is the instance ID or tag name
+	Name string
+	// Field is the field in conflict, such as "base_url" or
+	// "metadata.temperature", or "" for the whole resource
+	Field string
+	// Base, Ours and Theirs render the three versions as a ChangeSet
+	// does. API keys show as (sensitive), and a missing resource or
+	// metadata key as (deleted) or "".
+	Base, Ours, Theirs string
+}
+
+// String describes the conflict on one line
+func (c Conflict) String() string {
+	name := c.Name
+	if c.Field != "" {
+		name += " " + c.Field
+	}
+	return fmt.Sprintf("%s %q: base %s, ours %s, theirs %s", c.Kind, name, c.Base, c.Ours, c.Theirs)
+}
+
+// MergeConfigs merges two edits, ours and theirs, of the configuration
+// base, as when the same configuration is edited on two machines.
+// Instances are matched by ID and tags by name, and merged field by field:
+// a field one side changed takes that side's value, and metadata keys are
+// merged one by one. Label and tag assignments are merged as sets: one
+// added or removed on either side is added or removed. What both sides changed
+// differently is returned as a Conflict, with ours kept in the merged
+// Config. When one side deleted what the other changed, the changed
+// version is kept, so nothing is lost silently. A nil section of ours or
+// theirs means that side left it alone, and a nil Config is an empty one.
+// Inputs naming an instance or tag twice give an error wrapping
+// ErrInvalidOption.
+func MergeConfigs(base, ours, theirs *Config) (*Config, []Conflict, error) {
+	for _, c := range []**Config{&base, &ours, &theirs} {
+		if *c == nil {
+			*c = &Config{}
+		}
+	}
+	merged := &Config{}
+	var conflicts []Conflict
+
+	if ours.Instances != nil || theirs.Instances != nil || base.Instances != nil {
+		b, o, t, err := indexInstances(base.Instances, ours.Instances, theirs.Instances)
+		if err != nil {
+			return nil, nil, err
+		}
+		merged.Instances = []ProviderInstance{}
+		for _, id := range mergeKeys(b, o, t) {
+			instance, ok, found := mergeResource(KindInstance, id, b, o, t, mergeInstance, sameInstance, renderInstance)
+			conflicts = append(conflicts, found...)
+			if ok {
+				merged.Instances = append(merged.Instances, instance)
+			}
+		}
+	}
+	if ours.Tags != nil || theirs.Tags != nil || base.Tags != nil {
+		b, o, t, err := indexTags(base.Tags, ours.Tags, theirs.Tags)
+		if err != nil {
+			return nil, nil, err
+		}
+		merged.Tags = []Tag{}
+		for _, name := range mergeKeys(b, o, t) {
+			tag, ok, found := mergeResource(KindTag, name, b, o, t, mergeTag, sameTag, renderTag)
+			conflicts = append(conflicts, found...)
+			if ok {
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+	}
+	if ours.Labels != nil || theirs.Labels != nil || base.Labels != nil {
+		merged.Labels = mergeLabels(base.Labels, side(ours.Labels, base.Labels), side(theirs.Labels, base.Labels))
+	}
+	if ours.TagAssignments != nil || theirs.TagAssignments != nil || base.TagAssignments != nil {
+		merged.TagAssignments = mergeLabels(base.TagAssignments,
+			side(ours.TagAssignments, base.TagAssignments), side(theirs.TagAssignments, base.TagAssignments))
+	}
+	return merged, conflicts, nil
+}
+
+// side returns a side's section, or base's if the side left it alone
+func side[T any](section, base []T) []T {
+	if section == nil {
+		return base
+	}
+	return section
+}
+
+func indexInstances(base, ours, theirs []ProviderInstance) (b, o, t map[string]ProviderInstance, err error) {
+	index := func(instances []ProviderInstance) (map[string]ProviderInstance, error) {
+		m := map[string]ProviderInstance{}
+		for _, instance := range instances {
+			if _, ok := m[instance.ID]; ok {
+				return nil, fmt.Errorf("merge: instance %q is given twice: %w", instance.ID, ErrInvalidOption)
+			}
+			m[instance.ID] = instance
+		}
+		return m, nil
+	}
+	if b, err = index(base); err != nil {
+		return
+	}
+	if o, err = index(side(ours, base)); err != nil {
+		return
+	}
+	t, err = index(side(theirs, base))
+	return
+}
+
+func indexTags(base, ours, theirs []Tag) (b, o, t map[string]Tag, err error) {
+	index := func(tags []Tag) (map[string]Tag, error) {
+		m := map[string]Tag{}
+		for _, tag := range tags {
+			if _, ok := m[tag.Name]; ok {
+				return nil, fmt.Errorf("merge: tag %q is given twice: %w", tag.Name, ErrInvalidOption)
+			}
+			m[tag.Name] = tag
+		}
+		return m, nil
+	}
+	if b, err = index(base); err != nil {
+		return
+	}
+	if o, err = index(side(ours, base)); err != nil {
+		return
+	}
+	t, err = index(side(theirs, base))
+	return
+}
+
+// mergeKeys returns every key of the three maps, sorted
+func mergeKeys[T any](b, o, t map[string]T) []string {
+	keys := slices.Collect(maps.Keys(b))
+	keys = slices.AppendSeq(keys, maps.Keys(o))
+	keys = slices.AppendSeq(keys, maps.Keys(t))
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// mergeResource merges the versions of one instance or tag. It returns the
+// merged resource and whether it survives, with any conflicts.
+func mergeResource[T any](kind ChangeKind, name string, b, o, t map[string]T,
+	merge func(base, ours, theirs T, report func(field, base, ours, theirs string)) T,
+	same func(a, b T) bool, render func(T) string,
+) (T, bool, []Conflict) {
+	var conflicts []Conflict
+	report := func(field, base, ours, theirs string) {
+		conflicts = append(conflicts, Conflict{Kind: kind, Name: name, Field: field, Base: base, Ours: ours, Theirs: theirs})
+	}
+	base, inBase := b[name]
+	ours, inOurs := o[name]
+	theirs, inTheirs := t[name]
+	var zero T
+	switch {
+	case !inBase && inOurs && inTheirs:
+		// Created on both sides: merge against nothing, so any difference
+		// is a conflict
+		return merge(zero, ours, theirs, report), true, conflicts
+	case !inBase:
+		if inOurs {
+			return ours, true, nil
+		}
+		return theirs, inTheirs, nil
+	case inOurs && inTheirs:
+		return merge(base, ours, theirs, report), true, conflicts
+	case !inOurs && !inTheirs:
+		return zero, false, nil
+	}
+	// Deleted on one side: gone, unless the other side changed it
+	changed, deletedByUs := theirs, !inOurs
+	if inOurs {
+		changed = ours
+	}
+	if same(changed, base) {
+		return zero, false, nil
+	}
+	if deletedByUs {
+		report("", render(base), deleted, render(changed))
+	} else {
+		report("", render(base), render(changed), deleted)
+	}
+	return changed, true, conflicts
+}
+
+// merge3 merges one field: a side that left it as in base takes the
+// other's value. Differing changes are reported, and ours is kept.
+func merge3[T any](field string, base, ours, theirs T, equal func(a, b T) bool, render func(T) string, report func(field, base, ours, theirs string)) T {
+	switch {
+	case equal(ours, theirs), equal(theirs, base):
+		return ours
+	case equal(ours, base):
+		return theirs
+	}
+	report(field, render(base), render(ours), render(theirs))
+	return ours
+}
+
+// mergeMetadata merges metadata key by key
+func mergeMetadata(base, ours, theirs map[string]string, report func(field, base, ours, theirs string)) map[string]string {
+	merged := map[string]string{}
+	keys := slices.Collect(maps.Keys(base))
+	keys = slices.AppendSeq(keys, maps.Keys(ours))
+	keys = slices.AppendSeq(keys, maps.Keys(theirs))
+	slices.Sort(keys)
+	type value struct {
+		s  string
+		ok bool
+	}
+	equal := func(a, b value) bool { return a == b }
+	render := func(v value) string {
+		if !v.ok {
+			return deleted
+		}
+		return quoteField(v.s)
+	}
+	for _, k := range slices.Compact(keys) {
+		b, inBase := base[k]
+		o, inOurs := ours[k]
+		t, inTheirs := theirs[k]
+		v := merge3("metadata."+k, value{b, inBase}, value{o, inOurs}, value{t, inTheirs}, equal, render, report)
+		if v.ok {
+			merged[k] = v.s
+		}
+	}
+	if len(merged) == 0 && ours == nil && theirs == nil {
+		return nil
+	}
+	return merged
+}
+
+func mergeInstance(base, ours, theirs ProviderInstance, report func(field, base, ours, theirs string)) ProviderInstance {
+	eq := func(a, b string) bool { return a == b }
+	merged := ours
+	merged.ProviderType = merge3("provider_type", base.ProviderType, ours.ProviderType, theirs.ProviderType, eq, quoteField, report)
+	merged.BaseURL = merge3("base_url", base.BaseURL, ours.BaseURL, theirs.BaseURL, eq, quoteField, report)
+	merged.APIKey = merge3("api_key", base.APIKey, ours.APIKey, theirs.APIKey, Secret.Equal, renderKey, report)
+	merged.Models = merge3("models", base.Models, ours.Models, theirs.Models, slices.Equal[[]string], listField, report)
+	merged.Capabilities = merge3("capabilities", base.Capabilities, ours.Capabilities, theirs.Capabilities,
+		func(a, b Capabilities) bool { return a == b }, func(c Capabilities) string { return listField(c.Names()) }, report)
+	merged.Active = merge3("active", base.Active, ours.Active, theirs.Active, func(a, b bool) bool { return a == b }, strconv.FormatBool, report)
+	merged.Metadata = mergeMetadata(base.Metadata, ours.Metadata, theirs.Metadata, report)
+	return merged
+}
+
+func mergeTag(base, ours, theirs Tag, report func(field, base, ours, theirs string)) Tag {
+	eq := func(a, b string) bool { return a == b }
+	merged := ours
+	merged.Description = merge3("description", base.Description, ours.Description, theirs.Description, eq, quoteField, report)
+	merged.Parent = merge3("parent", base.Parent, ours.Parent, theirs.Parent, eq, quoteField, report)
+	merged.Uniqueness = merge3("uniqueness", base.Uniqueness, ours.Uniqueness, theirs.Uniqueness, eq, quoteField, report)
+	merged.Metadata = mergeMetadata(base.Metadata, ours.Metadata, theirs.Metadata, report)
+	if merged.CreatedAt.IsZero() || !theirs.CreatedAt.IsZero() && theirs.CreatedAt.Before(merged.CreatedAt) {
+		merged.CreatedAt = theirs.CreatedAt
+	}
+	return merged
+}
+
+func renderKey(s Secret) string {
+	if s.IsZero() {
+		return `""`
+	}
+	return sensitive
+}
+
+func sameInstance(a, b ProviderInstance) bool {
+	return len(instanceFields(a, b)) == 0
+}
+
+// renderInstance renders the fields an instance sets, for a Conflict
+func renderInstance(instance ProviderInstance) string {
+	var fields []string
+	for _, f := range createFields(instanceFields(ProviderInstance{}, instance)) {
+		fields = append(fields, f.Field+"="+f.New)
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+func sameTag(a, b Tag) bool {
+	return renderTag(a) == renderTag(b)
+}
+
+// renderTag renders the fields of a tag, for a Conflict
+func renderTag(tag Tag) string {
+	return fmt.Sprintf("{description=%s parent=%s uniqueness=%s metadata=%s}",
+		quoteField(tag.Description), quoteField(tag.Parent), quoteField(tag.Uniqueness), mapField(tag.Metadata))
+}
+
+// mergeLabels merges label or tag assignments as a set keyed by name and
+// target: an assignment is kept if both sides have it, or if one side has
+// it and it was not in base
+func mergeLabels(base, ours, theirs []LabelAssignment) []LabelAssignment {
+	keys := func(labels []LabelAssignment) map[string]bool {
+		m := map[string]bool{}
+		for _, label := range labels {
+			m[labelName(label)] = true
+		}
+		return m
+	}
+	inBase, inOurs, inTheirs := keys(base), keys(ours), keys(theirs)
+	merged := []LabelAssignment{}
+	seen := map[string]bool{}
+	for _, label := range slices.Concat(ours, theirs) {
+		name := labelName(label)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		keep := inOurs[name] && inTheirs[name] || !inBase[name]
+		if keep {
+			merged = append(merged, label)
+		}
+	}
+	return merged
+}
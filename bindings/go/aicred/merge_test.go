@@ -0,0 +1,112 @@
+package aicred
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeConfigs(t *testing.T) {
+	instance := func(id, url string, models ...string) ProviderInstance {
+		return ProviderInstance{ID: id, ProviderType: "openai", BaseURL: url, APIKey: NewSecretString("sk-" + id), Models: models, Active: true,
+			Metadata: map[string]string{"team": "core"}}
+	}
+	prod := func(id string) LabelAssignment {
+		return LabelAssignment{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: id}}
+	}
+	base := &Config{
+		Instances: []ProviderInstance{instance("a", "https://a"), instance("b", "https://b"), instance("c", "https://c"), instance("d", "https://d")},
+		Tags:      []Tag{{Name: "prod", Description: "Production"}},
+		Labels:    []LabelAssignment{prod("a"), prod("b")},
+	}
+	ours := &Config{
+		Instances: []ProviderInstance{instance("a", "https://a", "gpt-4o"), instance("b", "https://b-ours"), instance("d", "https://d-ours"), instance("e", "https://e")},
+		Tags:      []Tag{{Name: "prod", Description: "Production", Metadata: map[string]string{"color": "red"}}},
+		Labels:    []LabelAssignment{prod("a"), prod("b"), prod("e")},
+	}
+	theirs := &Config{
+		Instances: []ProviderInstance{instance("a", "https://a-theirs"), instance("b", "https://b-theirs"), instance("c", "https://c")},
+		Tags:      []Tag{{Name: "prod", Description: "Prod", Metadata: map[string]string{"color": "blue"}}},
+		Labels:    []LabelAssignment{prod("a")},
+	}
+	theirs.Instances[2].Metadata = map[string]string{"team": "core", "owner": "ops"}
+
+	merged, conflicts, err := MergeConfigs(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]ProviderInstance{}
+	for _, instance := range merged.Instances {
+		ids[instance.ID] = instance
+	}
+	if a := ids["a"]; a.BaseURL != "https://a-theirs" || !slices.Equal(a.Models, []string{"gpt-4o"}) {
+		t.Errorf("a = %+v, want both edits", a)
+	}
+	if b := ids["b"]; b.BaseURL != "https://b-ours" {
+		t.Errorf("b = %+v, want ours kept in a conflict", b)
+	}
+	if c := ids["c"]; c.Metadata["owner"] != "ops" {
+		t.Errorf("c = %+v, want theirs kept against our delete", c)
+	}
+	if _, ok := ids["d"]; !ok {
+		t.Error("d, changed by us and deleted by them, is gone")
+	}
+	if _, ok := ids["e"]; !ok || len(merged.Instances) != 5 {
+		t.Errorf("instances = %+v", merged.Instances)
+	}
+	if tag := merged.Tags[0]; tag.Description != "Prod" || tag.Metadata["color"] != "red" {
+		t.Errorf("tag = %+v", tag)
+	}
+	var labels []string
+	for _, label := range merged.Labels {
+		labels = append(labels, labelName(label))
+	}
+	if !slices.Equal(labels, []string{"prod on a", "prod on e"}) {
+		t.Errorf("labels = %v", labels)
+	}
+
+	var got []string
+	for _, c := range conflicts {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`instance "b base_url": base "https://b", ours "https://b-ours", theirs "https://b-theirs"`,
+		`instance "c": base {provider_type="openai" base_url="https://c" api_key=(sensitive) active=true metadata={team=core}}, ours (deleted), theirs {provider_type="openai" base_url="https://c" api_key=(sensitive) active=true metadata={owner=ops, team=core}}`,
+		`instance "d": base {provider_type="openai" base_url="https://d" api_key=(sensitive) active=true metadata={team=core}}, ours {provider_type="openai" base_url="https://d-ours" api_key=(sensitive) active=true metadata={team=core}}, theirs (deleted)`,
+		`tag "prod metadata.color": base (deleted), ours "red", theirs "blue"`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("conflicts:\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestMergeConfigsSections(t *testing.T) {
+	base := &Config{Instances: []ProviderInstance{{ID: "a", BaseURL: "https://a"}}}
+	ours := &Config{Instances: []ProviderInstance{{ID: "a", BaseURL: "https://a2"}}}
+
+	// theirs leaves instances alone, and nobody manages tags or labels
+	merged, conflicts, err := MergeConfigs(base, ours, &Config{})
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("merge = %v, %v", conflicts, err)
+	}
+	if len(merged.Instances) != 1 || merged.Instances[0].BaseURL != "https://a2" {
+		t.Errorf("instances = %+v", merged.Instances)
+	}
+	if merged.Tags != nil || merged.Labels != nil {
+		t.Errorf("unmanaged sections = %+v, %+v", merged.Tags, merged.Labels)
+	}
+
+	// An API key changed on one side and deleted on the other conflicts
+	keyed := &Config{Instances: []ProviderInstance{{ID: "a", BaseURL: "https://a", APIKey: NewSecretString("sk-new")}}}
+	if _, conflicts, _ := MergeConfigs(base, keyed, &Config{Instances: []ProviderInstance{}}); len(conflicts) != 1 {
+		t.Errorf("key change against delete: conflicts = %v", conflicts)
+	}
+
+	if merged, _, err := MergeConfigs(nil, nil, ours); err != nil || len(merged.Instances) != 1 {
+		t.Errorf("merge into nil = %+v, %v", merged, err)
+	}
+	twice := &Config{Instances: []ProviderInstance{{ID: "a"}, {ID: "a"}}}
+	if _, _, err := MergeConfigs(base, twice, ours); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("duplicate instance: err = %v", err)
+	}
+}
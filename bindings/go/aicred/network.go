@@ -0,0 +1,126 @@
+package aicred
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Instance metadata keys holding the network settings HTTPClient honors,
+// for instances reached through an enterprise gateway or proxy
+const (
+	// MetadataProxy is the proxy URL, with scheme http, https or socks5,
+	// or "direct" for none; empty means the HTTPS_PROXY and related
+	// variables
+	MetadataProxy = "proxy"
+	// MetadataCABundle is the path of a PEM file of CA certificates to
+	// trust in addition to the system pool
+	MetadataCABundle = "ca_bundle"
+	// MetadataInsecureSkipVerify set to "true" disables TLS certificate
+	// verification. Strict validation rejects it.
+	MetadataInsecureSkipVerify = "insecure_skip_verify"
+	// MetadataTimeout is the overall request timeout, as a Go duration
+	// such as "30s"
+	MetadataTimeout = "timeout"
+)
+
+// ProxyDirect is the MetadataProxy value that bypasses any proxy
+const ProxyDirect = "direct"
+
+// NetworkSettings are an instance's proxy, TLS and timeout settings. Zero
+// fields are unset.
+type NetworkSettings struct {
+	Proxy              string        `json:"proxy,omitempty"`
+	CABundle           string        `json:"ca_bundle,omitempty"`
+	InsecureSkipVerify bool          `json:"insecure_skip_verify,omitempty"`
+	Timeout            time.Duration `json:"timeout,omitempty"`
+}
+
+// IsZero reports whether no setting is set
+func (n NetworkSettings) IsZero() bool {
+	return n == NetworkSettings{}
+}
+
+// NetworkSettingsOf reads instance's network settings from its metadata.
+// A malformed proxy URL, flag or timeout is an error wrapping
+// ErrInvalidInstance; the CA bundle is not read until HTTPClient.
+func NetworkSettingsOf(instance ProviderInstance) (NetworkSettings, error) {
+	meta := instance.Metadata
+	n := NetworkSettings{Proxy: meta[MetadataProxy], CABundle: meta[MetadataCABundle]}
+	var problems []error
+	report := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf("instance %q: %s: %w", instance.ID, fmt.Sprintf(format, args...), ErrInvalidInstance))
+	}
+	if n.Proxy != "" && n.Proxy != ProxyDirect {
+		u, err := url.Parse(n.Proxy)
+		if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+			report("proxy %q is not an http, https or socks5 URL", n.Proxy)
+		}
+	}
+	if s, ok := meta[MetadataInsecureSkipVerify]; ok {
+		skip, err := strconv.ParseBool(s)
+		if err != nil {
+			report("%s %q is not true or false", MetadataInsecureSkipVerify, s)
+		}
+		n.InsecureSkipVerify = skip
+	}
+	if s, ok := meta[MetadataTimeout]; ok {
+		timeout, err := time.ParseDuration(s)
+		if err != nil || timeout <= 0 {
+			report("%s %q is not a positive duration", MetadataTimeout, s)
+		}
+		n.Timeout = timeout
+	}
+	return n, errors.Join(problems...)
+}
+
+// HTTPClient returns an *http.Client that reaches instance through its
+// proxy, trusts its CA bundle, skips TLS verification if it says so and
+// gives up after its timeout. An instance with no network settings gets
+// http.DefaultClient. Malformed settings give an error wrapping
+// ErrInvalidInstance, and a CA bundle that cannot be read one wrapping
+// ErrIO.
+func HTTPClient(instance ProviderInstance) (*http.Client, error) {
+	n, err := NetworkSettingsOf(instance)
+	if err != nil {
+		return nil, err
+	}
+	if n.IsZero() {
+		return http.DefaultClient, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch n.Proxy {
+	case "":
+	case ProxyDirect:
+		transport.Proxy = nil
+	default:
+		u, _ := url.Parse(n.Proxy)
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if n.CABundle != "" || n.InsecureSkipVerify {
+		config := &tls.Config{InsecureSkipVerify: n.InsecureSkipVerify}
+		if n.CABundle != "" {
+			pem, err := os.ReadFile(n.CABundle)
+			if err != nil {
+				return nil, fmt.Errorf("instance %q: read CA bundle: %w: %v", instance.ID, ErrIO, err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("instance %q: CA bundle %s has no PEM certificates: %w", instance.ID, n.CABundle, ErrInvalidInstance)
+			}
+			config.RootCAs = pool
+		}
+		transport.TLSClientConfig = config
+	}
+	return &http.Client{Transport: transport, Timeout: n.Timeout}, nil
+}
@@ -0,0 +1,105 @@
+package aicred
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	instance := ProviderInstance{ID: "gateway", Metadata: map[string]string{MetadataCABundle: bundle, MetadataTimeout: "5s"}}
+	client, err := HTTPClient(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Timeout != 5*time.Second {
+		t.Errorf("Timeout = %v, want 5s", client.Timeout)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("client should trust the CA bundle: %v", err)
+	}
+	resp.Body.Close()
+	if _, err := http.DefaultClient.Get(server.URL); err == nil {
+		t.Error("the default client should not trust the test server")
+	}
+
+	client, err = HTTPClient(ProviderInstance{ID: "skip", Metadata: map[string]string{MetadataInsecureSkipVerify: "true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("insecure_skip_verify should accept the certificate: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	store := NewMemoryStore([]ProviderInstance{{ID: "proxied", Metadata: map[string]string{MetadataProxy: proxy.URL}}}, nil)
+	client, err := newInstanceRepository(store).HTTPClient("proxied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get("http://api.example.invalid/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if proxied != "http://api.example.invalid/v1/models" {
+		t.Errorf("proxy saw %q", proxied)
+	}
+
+	if client, err := HTTPClient(ProviderInstance{ID: "plain"}); err != nil || client != http.DefaultClient {
+		t.Errorf("an instance without settings should get http.DefaultClient, got %v, %v", client, err)
+	}
+}
+
+func TestHTTPClientInvalid(t *testing.T) {
+	instance := ProviderInstance{
+		ID:           "gateway",
+		ProviderType: "openai",
+		BaseURL:      "https://api.openai.com/v1",
+		Metadata: map[string]string{
+			MetadataProxy:   "ftp://proxy.example.com",
+			MetadataTimeout: "soon",
+		},
+	}
+	_, err := HTTPClient(instance)
+	if !errors.Is(err, ErrInvalidInstance) || !strings.Contains(err.Error(), "proxy") || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("HTTPClient = %v, want the proxy and timeout problems", err)
+	}
+
+	instance.Metadata = map[string]string{MetadataCABundle: filepath.Join(t.TempDir(), "missing.pem")}
+	if _, err := HTTPClient(instance); !errors.Is(err, ErrIO) {
+		t.Errorf("HTTPClient with a missing CA bundle = %v, want ErrIO", err)
+	}
+
+	instance.Metadata = map[string]string{MetadataInsecureSkipVerify: "true"}
+	if err := ValidateProviderInstance(instance, ValidationOptions{}); err != nil {
+		t.Errorf("non-strict validation should allow insecure_skip_verify: %v", err)
+	}
+	if err := ValidateProviderInstance(instance, ValidationOptions{Strict: true}); !errors.Is(err, ErrInvalidInstance) || !strings.Contains(err.Error(), MetadataInsecureSkipVerify) {
+		t.Errorf("strict validation = %v, want insecure_skip_verify rejected", err)
+	}
+}
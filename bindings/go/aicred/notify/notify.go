@@ -0,0 +1,119 @@
+// Package notify pushes alerts about scan findings and config changes to
+// Slack, generic webhooks, email, and syslog collectors and SIEMs as CEF.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// EventKind identifies what triggered a notification
+type EventKind string
+
+const (
+	// EventNewFindings is sent when a scan reports keys that were not present before
+	EventNewFindings EventKind = "new_findings"
+	// EventConfigChanged is sent when a config instance is added, removed, or modified
+	EventConfigChanged EventKind = "config_changed"
+)
+
+// Event is the payload handed to notifiers and templates.
+// Findings never carry full key values; see NewFindingsEvent.
+type Event struct {
+	Kind      EventKind               `json:"kind"`
+	Time      time.Time               `json:"time"`
+	HomeDir   string                  `json:"home_directory,omitempty"`
+	Findings  []aicred.DiscoveredKey  `json:"findings,omitempty"`
+	Instances []aicred.ConfigInstance `json:"instances,omitempty"`
+	Message   string                  `json:"message,omitempty"`
+}
+
+// Notifier delivers an event to a single destination
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// DefaultTemplate renders a short human-readable summary of an event
+const DefaultTemplate = `{{if eq .Kind "new_findings"}}aicred: {{len .Findings}} new credential(s) found{{if .HomeDir}} in {{.HomeDir}}{{end}}
+{{range .Findings}}- {{.Provider}} {{.Redacted}} ({{.Source}})
+{{end}}{{else}}aicred: {{.Message}}
+{{range .Instances}}- {{.AppName}} {{.ConfigPath}}
+{{end}}{{end}}`
+
+// NewFindings returns the keys in current whose hash does not appear in previous.
+// A nil previous result treats every key in current as new.
+func NewFindings(previous, current *aicred.ScanResult) []aicred.DiscoveredKey {
+	if current == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	if previous != nil {
+		for _, key := range previous.Keys {
+			seen[key.Hash] = true
+		}
+	}
+	var fresh []aicred.DiscoveredKey
+	for _, key := range current.Keys {
+		if seen[key.Hash] {
+			continue
+		}
+		seen[key.Hash] = true
+		fresh = append(fresh, key)
+	}
+	return fresh
+}
+
+// NewFindingsEvent builds an EventNewFindings event for the keys that are new
+// in current, or returns false if there is nothing to report
+func NewFindingsEvent(previous, current *aicred.ScanResult) (Event, bool) {
+	fresh := NewFindings(previous, current)
+	if len(fresh) == 0 {
+		return Event{}, false
+	}
+	for i := range fresh {
+		fresh[i].Value = aicred.Secret{}
+	}
+	return Event{
+		Kind:     EventNewFindings,
+		Time:     time.Now().UTC(),
+		HomeDir:  current.HomeDir,
+		Findings: fresh,
+	}, true
+}
+
+// Render executes a text/template against the event.
+// An empty template falls back to DefaultTemplate.
+func Render(tmpl string, event Event) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	t, err := template.New("notify").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("failed to render template: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// Multi fans an event out to several notifiers, returning the joined errors
+type Multi []Notifier
+
+// Notify delivers the event to every notifier, even if some fail
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,309 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func scanResults() (*aicred.ScanResult, *aicred.ScanResult) {
+	previous := &aicred.ScanResult{
+		Keys: []aicred.DiscoveredKey{{Provider: "openai", Hash: "h1", Redacted: "sk-****aaaa"}},
+	}
+	current := &aicred.ScanResult{
+		HomeDir: "/home/u",
+		Keys: []aicred.DiscoveredKey{
+			{Provider: "openai", Hash: "h1", Redacted: "sk-****aaaa"},
+			{Provider: "anthropic", Hash: "h2", Redacted: "sk-ant-****bbbb", Value: aicred.NewSecretString("sk-ant-secret"), Source: "/home/u/.env"},
+		},
+	}
+	return previous, current
+}
+
+func TestNewFindingsEvent(t *testing.T) {
+	previous, current := scanResults()
+
+	event, ok := NewFindingsEvent(previous, current)
+	if !ok {
+		t.Fatal("expected an event")
+	}
+	if len(event.Findings) != 1 || event.Findings[0].Hash != "h2" {
+		t.Fatalf("expected only the new key, got %+v", event.Findings)
+	}
+	if !event.Findings[0].Value.IsZero() {
+		t.Error("event findings must not carry full values")
+	}
+	if current.Keys[1].Value.IsZero() {
+		t.Error("building an event must not modify the scan result")
+	}
+
+	if _, ok := NewFindingsEvent(current, current); ok {
+		t.Error("expected no event when nothing changed")
+	}
+}
+
+func TestRenderDefaultTemplate(t *testing.T) {
+	previous, current := scanResults()
+	event, _ := NewFindingsEvent(previous, current)
+
+	text, err := Render("", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "1 new credential(s) found in /home/u") ||
+		!strings.Contains(text, "anthropic sk-ant-****bbbb (/home/u/.env)") {
+		t.Errorf("unexpected rendering:\n%s", text)
+	}
+	if strings.Contains(text, "sk-ant-secret") {
+		t.Error("rendered message leaked a full value")
+	}
+}
+
+func TestWebhookJSON(t *testing.T) {
+	var got Event
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("invalid JSON body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	hook := &Webhook{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}}
+	event := Event{Kind: EventConfigChanged, Message: "instance added"}
+	if err := hook.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != EventConfigChanged || got.Message != "instance added" {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+	if auth != "Bearer t" {
+		t.Errorf("expected custom header, got %q", auth)
+	}
+}
+
+func TestSlackAndErrors(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer srv.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer failing.Close()
+
+	event := Event{Kind: EventConfigChanged, Message: "hello"}
+	err := Multi{
+		&Slack{WebhookURL: srv.URL, Template: "{{.Message}} world"},
+		&Webhook{URL: failing.URL},
+	}.Notify(context.Background(), event)
+
+	if body != `{"text":"hello world"}` {
+		t.Errorf("unexpected slack body: %s", body)
+	}
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected rejection error, got %v", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	e := &Email{From: "aicred@example.com", To: []string{"a@example.com", "b@example.com"}}
+	msg, err := e.message(Event{Kind: EventConfigChanged, Message: "changed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(msg)
+	for _, want := range []string{
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: aicred: config_changed\r\n",
+		"aicred: changed\r\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("message missing %q:\n%s", want, text)
+		}
+	}
+}
+
+// fakeSMTP accepts one connection and answers it as a mail server, or
+// not at all when silent
+func fakeSMTP(t *testing.T, silent bool) (addr string, received <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if silent {
+			io.Copy(io.Discard, conn)
+			return
+		}
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 mail.test ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				out <- data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+func TestEmailNotify(t *testing.T) {
+	addr, received := fakeSMTP(t, false)
+	e := &Email{Addr: addr, From: "aicred@example.com", To: []string{"a@example.com"}}
+	if err := e.Notify(context.Background(), Event{Kind: EventConfigChanged, Message: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-received; !strings.Contains(msg, "Subject: aicred: config_changed\r\n") {
+		t.Errorf("received:\n%s", msg)
+	}
+
+	// A server that never answers does not outlive the context
+	addr, _ = fakeSMTP(t, true)
+	e.Addr = addr
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := e.Notify(ctx, Event{Kind: EventConfigChanged}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify took %s", elapsed)
+	}
+}
+
+func TestSyslogCEF(t *testing.T) {
+	s := &Syslog{Hostname: "ws 01", Facility: 4}
+	event := Event{
+		Kind: EventNewFindings,
+		Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Findings: []aicred.DiscoveredKey{{
+			Provider:   "openai",
+			Source:     `C:\Users\u\a=b|c.env`,
+			Confidence: "VeryHigh",
+			Hash:       "h1",
+			Redacted:   "sk-****aaaa",
+			Location:   &aicred.Location{EnvVar: "OPENAI_API_KEY", Line: 3},
+		}},
+	}
+	messages, err := s.messages(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("messages = %q", messages)
+	}
+	msg := messages[0]
+	// facility 4 * 8 + critical 2
+	if !strings.HasPrefix(msg, "<34>1 2024-05-01T10:00:00Z ws01 aicred ") {
+		t.Errorf("header: %s", msg)
+	}
+	for _, want := range []string{
+		" new_findings - CEF:0|robottwo|aicred|",
+		"|new_findings|openai credential found|10|rt=1714557600000 dvchost=ws 01 ",
+		"msg=openai credential sk-****aaaa in OPENAI_API_KEY ",
+		`filePath=C:\\Users\\u\\a\=b|c.env `,
+		"cs1Label=provider cs1=openai cs2Label=redacted cs2=sk-****aaaa cs3Label=confidence cs3=VeryHigh cs4Label=hash cs4=h1 cs5Label=envVar cs5=OPENAI_API_KEY cn1Label=line cn1=3",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "duser") || strings.Contains(msg, "cs6") {
+		t.Errorf("empty fields sent:\n%s", msg)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	event := Event{
+		Kind:      EventConfigChanged,
+		Time:      time.Now(),
+		Message:   "instance added",
+		Instances: []aicred.ConfigInstance{{InstanceID: "i1", AppName: "roo-code"}, {InstanceID: "i2", AppName: "cline"}},
+	}
+	s := &Syslog{Network: "tcp", Addr: ln.Addr().String(), Hostname: "ws01"}
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	data := <-received
+	// Octet-counted frames: "LEN MSG" twice
+	for i := 0; i < 2; i++ {
+		n, rest, ok := strings.Cut(data, " ")
+		size, err := strconv.Atoi(n)
+		if !ok || err != nil || size > len(rest) {
+			t.Fatalf("frame %d: %q", i, data)
+		}
+		frame := rest[:size]
+		if !strings.HasPrefix(frame, "<109>1 ") || !strings.Contains(frame, "externalId=i"+strconv.Itoa(i+1)) {
+			t.Errorf("frame %d: %s", i, frame)
+		}
+		data = rest[size:]
+	}
+	if data != "" {
+		t.Errorf("trailing data: %q", data)
+	}
+
+	if err := (&Syslog{Network: "sctp"}).Notify(context.Background(), event); !errors.Is(err, aicred.ErrInvalidOption) {
+		t.Errorf("unknown network: err = %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/policy"
+	"github.com/robottwo/aicred/bindings/go/aicred/report"
+)
+
+// Syslog sends events to a syslog collector or SIEM as RFC 5424 messages,
+// one per finding or changed instance, each carrying a CEF (ArcSight Common
+// Event Format) record. With a Template, each event is instead sent as one
+// message holding the rendered template.
+//
+// Findings map to CEF as follows; fields without a value are left out:
+//
+//	Signature ID     the event kind, new_findings or config_changed
+//	Name             "<provider> credential found", or the event message
+//	Severity         10 critical, 8 high, 5 medium, 3 low, as in the report package
+//	rt               the event time, in milliseconds since the epoch
+//	dvchost          Hostname
+//	msg              provider, redacted key and the variable it was assigned to
+//	filePath         the file the key or config instance is in
+//	duser            the owner of that file, when the scan attributed keys
+//	externalId       the config instance ID
+//	cs1 provider     the key's provider
+//	cs2 redacted     the redacted key; key values are never sent
+//	cs3 confidence   the detector's confidence
+//	cs4 hash         the SHA-256 of the key, to correlate findings
+//	cs5 envVar       the variable or config key the key was assigned to
+//	cs6 app          the application whose configuration held the key
+//	cn1 line         the line the key is on
+//
+// The syslog severity follows the CEF one: critical (2) for critical
+// findings, error (3) for high, warning (4) for medium and notice (5) for
+// low and config changes.
+type Syslog struct {
+	// Network is "udp", "tcp" or "tls"; empty means "udp". Over TCP and TLS
+	// messages are framed by octet counting, as in RFC 6587 and RFC 5425.
+	Network string
+	// Addr is the collector as host:port
+	Addr string
+	// TLSConfig configures "tls" connections; nil verifies the collector
+	// against the system roots
+	TLSConfig *tls.Config
+	// Facility is the syslog facility code; zero means 13, log audit
+	Facility int
+	// Hostname and AppName fill the message headers; empty means the
+	// machine's hostname and "aicred"
+	Hostname string
+	AppName  string
+	Template string
+	// Timeout bounds connecting and sending; zero means 10 seconds
+	Timeout time.Duration
+}
+
+// syslogAudit is the log audit facility
+const syslogAudit = 13
+
+// Notify sends the event's messages over one connection
+func (s *Syslog) Notify(ctx context.Context, event Event) error {
+	messages, err := s.messages(event)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var conn net.Conn
+	network := s.Network
+	switch network {
+	case "", "udp":
+		network = "udp"
+		conn, err = (&net.Dialer{}).DialContext(ctx, "udp", s.Addr)
+	case "tcp":
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", s.Addr)
+	case "tls":
+		conn, err = (&tls.Dialer{Config: s.TLSConfig}).DialContext(ctx, "tcp", s.Addr)
+	default:
+		return fmt.Errorf("syslog: unknown network %q: %w", s.Network, aicred.ErrInvalidOption)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog collector: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	for _, msg := range messages {
+		frame := msg
+		if network != "udp" {
+			frame = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := conn.Write([]byte(frame)); err != nil {
+			return fmt.Errorf("failed to send syslog message: %v", err)
+		}
+	}
+	return nil
+}
+
+// messages builds the RFC 5424 messages for the event
+func (s *Syslog) messages(event Event) ([]string, error) {
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	header := func(severity int, msgID string) string {
+		facility := s.Facility
+		if facility == 0 {
+			facility = syslogAudit
+		}
+		app := s.AppName
+		if app == "" {
+			app = "aicred"
+		}
+		return fmt.Sprintf("<%d>1 %s %s %s %d %s - ", facility*8+severity, event.Time.UTC().Format(time.RFC3339Nano),
+			syslogHeaderField(hostname), syslogHeaderField(app), os.Getpid(), syslogHeaderField(msgID))
+	}
+
+	if s.Template != "" {
+		text, err := Render(s.Template, event)
+		if err != nil {
+			return nil, err
+		}
+		return []string{header(5, string(event.Kind)) + text}, nil
+	}
+
+	var messages []string
+	switch event.Kind {
+	case EventNewFindings:
+		for _, key := range event.Findings {
+			sev := report.Severity(key)
+			cef := cefRecord{signature: string(event.Kind), name: key.Provider + " credential found", severity: cefSeverity(sev)}
+			cef.add("rt", strconv.FormatInt(event.Time.UnixMilli(), 10))
+			cef.add("dvchost", hostname)
+			msg := key.Provider + " credential " + key.Redacted
+			path, envVar, line := key.Source, "", 0
+			if key.Location != nil {
+				if key.Location.Path != "" {
+					path = key.Location.Path
+				}
+				envVar, line = key.Location.EnvVar, key.Location.Line
+			}
+			if envVar != "" {
+				msg += " in " + envVar
+			}
+			cef.add("msg", msg)
+			cef.add("filePath", path)
+			if key.Attribution != nil {
+				cef.add("duser", key.Attribution.User)
+			}
+			cef.addCustom("cs1", "provider", key.Provider)
+			cef.addCustom("cs2", "redacted", key.Redacted)
+			cef.addCustom("cs3", "confidence", key.Confidence)
+			cef.addCustom("cs4", "hash", key.Hash)
+			cef.addCustom("cs5", "envVar", envVar)
+			if key.Attribution != nil {
+				cef.addCustom("cs6", "app", key.Attribution.Application)
+			}
+			if line > 0 {
+				cef.addCustom("cn1", "line", strconv.Itoa(line))
+			}
+			messages = append(messages, header(syslogSeverity(sev), cef.signature)+cef.String())
+		}
+	default:
+		for _, instance := range event.Instances {
+			cef := cefRecord{signature: string(event.Kind), name: event.Message, severity: 3}
+			if cef.name == "" {
+				cef.name = "config instance changed"
+			}
+			cef.add("rt", strconv.FormatInt(event.Time.UnixMilli(), 10))
+			cef.add("dvchost", hostname)
+			cef.add("msg", event.Message)
+			cef.add("filePath", instance.ConfigPath)
+			cef.add("externalId", instance.InstanceID)
+			cef.addCustom("cs6", "app", instance.AppName)
+			messages = append(messages, header(5, cef.signature)+cef.String())
+		}
+	}
+	return messages, nil
+}
+
+// cefRecord is a CEF record under construction
+type cefRecord struct {
+	signature, name string
+	severity        int
+	extension       []string
+}
+
+// add appends key=value to the extension, unless value is empty
+func (r *cefRecord) add(key, value string) {
+	if value != "" {
+		r.extension = append(r.extension, key+"="+cefExtensionEscaper.Replace(value))
+	}
+}
+
+// addCustom adds a custom field and its label
+func (r *cefRecord) addCustom(key, label, value string) {
+	if value != "" {
+		r.add(key+"Label", label)
+		r.add(key, value)
+	}
+}
+
+func (r *cefRecord) String() string {
+	return fmt.Sprintf("CEF:0|robottwo|aicred|%s|%s|%s|%d|%s", cefHeaderEscaper.Replace(aicred.Version()),
+		cefHeaderEscaper.Replace(r.signature), cefHeaderEscaper.Replace(r.name), r.severity, strings.Join(r.extension, " "))
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefSeverity(sev policy.Severity) int {
+	switch sev {
+	case policy.SeverityCritical:
+		return 10
+	case policy.SeverityHigh:
+		return 8
+	case policy.SeverityMedium:
+		return 5
+	default:
+		return 3
+	}
+}
+
+func syslogSeverity(sev policy.Severity) int {
+	switch sev {
+	case policy.SeverityCritical:
+		return 2
+	case policy.SeverityHigh:
+		return 3
+	case policy.SeverityMedium:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// syslogHeaderField makes s a valid RFC 5424 header field: printable
+// ASCII without spaces, or "-" when empty
+func syslogHeaderField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
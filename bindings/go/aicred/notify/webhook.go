@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Webhook posts events to an arbitrary HTTP endpoint.
+// Without a Template the event is sent as JSON; with one, the rendered
+// template is sent as the body using ContentType.
+type Webhook struct {
+	URL         string
+	Headers     map[string]string
+	Template    string
+	ContentType string
+	Client      *http.Client
+}
+
+// Notify posts the event to the webhook URL
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	var body []byte
+	contentType := w.ContentType
+	if w.Template == "" {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %v", err)
+		}
+		body = data
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	} else {
+		rendered, err := Render(w.Template, event)
+		if err != nil {
+			return err
+		}
+		body = []byte(rendered)
+		if contentType == "" {
+			contentType = "text/plain; charset=utf-8"
+		}
+	}
+	return post(ctx, w.Client, w.URL, contentType, w.Headers, body)
+}
+
+// Slack posts events to a Slack incoming webhook
+type Slack struct {
+	WebhookURL string
+	Template   string
+	Client     *http.Client
+}
+
+// Notify posts the rendered event as a Slack message
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	text, err := Render(s.Template, event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack payload: %v", err)
+	}
+	return post(ctx, s.Client, s.WebhookURL, "application/json", nil, body)
+}
+
+func post(ctx context.Context, client *http.Client, url, contentType string, headers map[string]string, body []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notification rejected: %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package aicred
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// MetadataOrganization is the instance metadata key holding the ID of the
+// Organization the instance belongs to. Set it with the CLI or in the
+// instance's YAML.
+const MetadataOrganization = "organization"
+
+// Organization is a team, department or customer that provider instances
+// are grouped under, so configuration with many keys can be sliced by who
+// owns and pays for them
+type Organization struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// BillingContact is who receives the provider invoices, usually an
+	// email address
+	BillingContact string            `json:"billing_contact,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// OrganizationOf returns the ID of the organization instance belongs to, or
+// "" if it belongs to none
+func OrganizationOf(instance ProviderInstance) string {
+	return instance.Metadata[MetadataOrganization]
+}
+
+// GroupByOrganization groups instances by organization ID, keeping their
+// order; instances that belong to none are under ""
+func GroupByOrganization(instances []ProviderInstance) map[string][]ProviderInstance {
+	groups := map[string][]ProviderInstance{}
+	for _, instance := range instances {
+		id := OrganizationOf(instance)
+		groups[id] = append(groups[id], instance)
+	}
+	return groups
+}
+
+// OrganizationStore is where organizations are kept. A Session keeps them
+// in organizations.json in the aicred configuration directory, which the
+// core library does not read; a MemoryStore keeps them in memory.
+type OrganizationStore interface {
+	// LoadOrganizations returns every organization, sorted by ID
+	LoadOrganizations() ([]Organization, error)
+	// SaveOrganizations replaces all organizations
+	SaveOrganizations(organizations []Organization) error
+}
+
+var (
+	_ OrganizationStore = (*Session)(nil)
+	_ OrganizationStore = (*MemoryStore)(nil)
+)
+
+// organizationsPath is the file a Session keeps organizations in
+func (s *Session) organizationsPath() string {
+	return filepath.Join(ConfigDir(s.homeDir), "organizations.json")
+}
+
+// LoadOrganizations returns every organization, sorted by ID
+func (s *Session) LoadOrganizations() ([]Organization, error) {
+	data, err := os.ReadFile(s.organizationsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read organizations: %w: %v", ErrIO, err)
+	}
+	var organizations []Organization
+	if err := json.Unmarshal(data, &organizations); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", s.organizationsPath(), ErrParse, err)
+	}
+	sortOrganizations(organizations)
+	return organizations, nil
+}
+
+// SaveOrganizations replaces all organizations and writes them to
+// organizations.json
+func (s *Session) SaveOrganizations(organizations []Organization) error {
+	if organizations == nil {
+		organizations = []Organization{}
+	}
+	if err := s.snapshot(); err != nil {
+		return fmt.Errorf("save organizations: %w", err)
+	}
+	if err := hardenConfigDir(ConfigDir(s.homeDir)); err != nil {
+		return fmt.Errorf("save organizations: %w: %v", ErrIO, err)
+	}
+	if err := writeFileAtomic(s.organizationsPath(), organizations); err != nil {
+		return fmt.Errorf("save organizations: %w: %v", ErrIO, err)
+	}
+	s.events.Publish(events.StoreChanged{Time: time.Now().UTC(), Store: "organizations", Count: len(organizations)})
+	return nil
+}
+
+// LoadOrganizations returns every organization, sorted by ID
+func (m *MemoryStore) LoadOrganizations() ([]Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.organizations), nil
+}
+
+// SaveOrganizations replaces all organizations
+func (m *MemoryStore) SaveOrganizations(organizations []Organization) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.organizations = slices.Clone(organizations)
+	sortOrganizations(m.organizations)
+	return nil
+}
+
+func sortOrganizations(organizations []Organization) {
+	slices.SortStableFunc(organizations, func(a, b Organization) int { return strings.Compare(a.ID, b.ID) })
+}
+
+// OrganizationRepository is the Repository of organizations, sorted by ID,
+// with queries over the instances that belong to them
+type OrganizationRepository struct {
+	loadRepository[Organization]
+	store     OrganizationStore
+	instances Store
+}
+
+func newOrganizationRepository(store OrganizationStore, instances Store) *OrganizationRepository {
+	return &OrganizationRepository{loadRepository: loadRepository[Organization]{store.LoadOrganizations}, store: store, instances: instances}
+}
+
+// Organizations returns a repository over the session's organizations
+func (s *Session) Organizations() *OrganizationRepository {
+	return newOrganizationRepository(s, s)
+}
+
+// Get returns the organization with the given ID, or an error wrapping
+// ErrNotFound
+func (r *OrganizationRepository) Get(id string) (*Organization, error) {
+	organizations, err := r.store.LoadOrganizations()
+	if err != nil {
+		return nil, err
+	}
+	for _, org := range organizations {
+		if org.ID == id {
+			return &org, nil
+		}
+	}
+	return nil, fmt.Errorf("organization %q: %w", id, ErrNotFound)
+}
+
+// Save adds org, or replaces the organization with its ID, and saves the
+// organizations
+func (r *OrganizationRepository) Save(org Organization) error {
+	if strings.TrimSpace(org.ID) == "" {
+		return fmt.Errorf("organization has no ID: %w", ErrInvalidOption)
+	}
+	organizations, err := r.store.LoadOrganizations()
+	if err != nil {
+		return err
+	}
+	if i := slices.IndexFunc(organizations, func(o Organization) bool { return o.ID == org.ID }); i >= 0 {
+		organizations[i] = org
+	} else {
+		organizations = append(organizations, org)
+	}
+	return r.store.SaveOrganizations(organizations)
+}
+
+// Delete removes the organization and saves the organizations, or returns
+// an error wrapping ErrNotFound if there is none with that ID. Instances
+// naming it are left as they are and show up in Orphans.
+func (r *OrganizationRepository) Delete(id string) error {
+	organizations, err := r.store.LoadOrganizations()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(organizations), func(o Organization) bool { return o.ID == id })
+	if len(kept) == len(organizations) {
+		return fmt.Errorf("organization %q: %w", id, ErrNotFound)
+	}
+	return r.store.SaveOrganizations(kept)
+}
+
+// Instances returns the instances that belong to the organization, sorted
+// by ID
+func (r *OrganizationRepository) Instances(id string) ([]ProviderInstance, error) {
+	instances, err := r.instances.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(instances, func(i ProviderInstance) bool { return OrganizationOf(i) != id }), nil
+}
+
+// Unassigned returns the instances that belong to no organization
+func (r *OrganizationRepository) Unassigned() ([]ProviderInstance, error) {
+	return r.Instances("")
+}
+
+// Orphans returns the instances naming an organization that does not exist
+func (r *OrganizationRepository) Orphans() ([]ProviderInstance, error) {
+	organizations, err := r.store.LoadOrganizations()
+	if err != nil {
+		return nil, err
+	}
+	instances, err := r.instances.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(instances, func(i ProviderInstance) bool {
+		id := OrganizationOf(i)
+		return id == "" || slices.ContainsFunc(organizations, func(o Organization) bool { return o.ID == id })
+	}), nil
+}
+
+var _ Repository[Organization] = (*OrganizationRepository)(nil)
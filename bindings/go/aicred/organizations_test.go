@@ -0,0 +1,104 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOrganizations(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	orgs := s.Organizations()
+	if list, err := orgs.List(); err != nil || len(list) != 0 {
+		t.Fatalf("List before any save = %+v, %v", list, err)
+	}
+	for _, org := range []Organization{
+		{ID: "research", Name: "Research"},
+		{ID: "platform", Name: "Platform", BillingContact: "billing@example.com"},
+		{ID: "research", Name: "Research & Dev"},
+	} {
+		if err := orgs.Save(org); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := orgs.Save(Organization{Name: "nameless"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Save without ID: err = %v, want ErrInvalidOption", err)
+	}
+
+	list, err := orgs.List()
+	if err != nil || len(list) != 2 || list[0].ID != "platform" || list[1].Name != "Research & Dev" {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+	info, err := os.Stat(filepath.Join(home, ".config", "aicred", "organizations.json"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("organizations.json: %v, mode %v", err, info.Mode())
+	}
+
+	if _, err := orgs.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing): err = %v, want ErrNotFound", err)
+	}
+	if err := orgs.Delete("research"); err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.Delete("research"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+	if unassigned, err := orgs.Unassigned(); err != nil || len(unassigned) != 1 {
+		t.Errorf("Unassigned = %+v, %v", unassigned, err)
+	}
+}
+
+func TestOrganizationQueries(t *testing.T) {
+	store := NewMemoryStore([]ProviderInstance{
+		{ID: "a", Metadata: map[string]string{MetadataOrganization: "platform"}},
+		{ID: "b", Metadata: map[string]string{MetadataOrganization: "gone"}},
+		{ID: "c"},
+		{ID: "d", Metadata: map[string]string{MetadataOrganization: "platform"}},
+	}, nil)
+	c, err := New(WithHomeDir(t.TempDir()), WithStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	orgs := c.Organizations()
+	if err := orgs.Save(Organization{ID: "platform", Name: "Platform"}); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := store.LoadOrganizations(); len(saved) != 1 {
+		t.Fatalf("store holds %+v", saved)
+	}
+	ids := func(instances []ProviderInstance, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s string
+		for _, instance := range instances {
+			s += instance.ID
+		}
+		return s
+	}
+	if got := ids(orgs.Instances("platform")); got != "ad" {
+		t.Errorf("Instances(platform) = %s", got)
+	}
+	if got := ids(orgs.Unassigned()); got != "c" {
+		t.Errorf("Unassigned = %s", got)
+	}
+	if got := ids(orgs.Orphans()); got != "b" {
+		t.Errorf("Orphans = %s", got)
+	}
+
+	instances, _ := store.LoadInstances()
+	groups := GroupByOrganization(instances)
+	if len(groups) != 3 || len(groups["platform"]) != 2 || len(groups[""]) != 1 {
+		t.Errorf("GroupByOrganization = %+v", groups)
+	}
+}
@@ -0,0 +1,142 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ProjectConfigFile is where a project keeps configuration that overlays
+// the user's, relative to the project directory
+var ProjectConfigFile = filepath.Join(".aicred", "config.yaml")
+
+// FindProjectConfig returns the project configuration file that applies to
+// dir: the ProjectConfigFile in dir or, as for .npmrc, in the nearest
+// parent that has one. It returns "" if there is none.
+func FindProjectConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("project config: %w: %v", ErrIO, err)
+	}
+	for {
+		p := filepath.Join(dir, ProjectConfigFile)
+		info, err := os.Stat(p)
+		if err == nil && info.Mode().IsRegular() {
+			return p, nil
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("project config: %w: %v", ErrIO, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// OverlayConfig returns base with overlay's choices taking precedence. An
+// instance in overlay replaces base's instance with the same ID, keeping
+// its API key when overlay gives none, and is added otherwise. A tag
+// replaces base's tag of the same name. The assignments of a label or tag
+// named in overlay replace all of base's assignments of it. A nil section
+// of overlay leaves base's alone. Neither argument is modified.
+func OverlayConfig(base, overlay *Config) *Config {
+	c := &Config{
+		Instances: slices.Clone(base.Instances),
+		Tags:      slices.Clone(base.Tags),
+		Labels:    slices.Clone(base.Labels),
+		// Tag assignments are overlaid as labels are
+		TagAssignments: slices.Clone(base.TagAssignments),
+	}
+	if overlay.Instances != nil {
+		if c.Instances == nil {
+			c.Instances = []ProviderInstance{}
+		}
+		for _, instance := range overlay.Instances {
+			i := slices.IndexFunc(c.Instances, func(p ProviderInstance) bool { return p.ID == instance.ID })
+			if i < 0 {
+				c.Instances = append(c.Instances, instance)
+				continue
+			}
+			if instance.APIKey.IsZero() {
+				instance.APIKey = c.Instances[i].APIKey
+			}
+			c.Instances[i] = instance
+		}
+		slices.SortStableFunc(c.Instances, func(a, b ProviderInstance) int { return strings.Compare(a.ID, b.ID) })
+	}
+	if overlay.Tags != nil {
+		if c.Tags == nil {
+			c.Tags = []Tag{}
+		}
+		for _, tag := range overlay.Tags {
+			if i := slices.IndexFunc(c.Tags, func(t Tag) bool { return t.Name == tag.Name }); i >= 0 {
+				c.Tags[i] = tag
+			} else {
+				c.Tags = append(c.Tags, tag)
+			}
+		}
+		sortTags(c.Tags)
+	}
+	if overlay.Labels != nil {
+		c.Labels = overlayAssignments(c.Labels, overlay.Labels)
+	}
+	if overlay.TagAssignments != nil {
+		c.TagAssignments = overlayAssignments(c.TagAssignments, overlay.TagAssignments)
+	}
+	return c
+}
+
+// overlayAssignments replaces base's assignments of each name overlay
+// assigns with overlay's
+func overlayAssignments(base, overlay []LabelAssignment) []LabelAssignment {
+	named := map[string]bool{}
+	for _, label := range overlay {
+		named[label.LabelName] = true
+	}
+	base = slices.DeleteFunc(base, func(label LabelAssignment) bool { return named[label.LabelName] })
+	return append(base, overlay...)
+}
+
+// LoadEffectiveConfig returns the configuration that applies in
+// projectDir: the current user's, from DefaultHomeDir, overlaid with the
+// project configuration FindProjectConfig finds, if any. See
+// Session.LoadEffectiveConfig.
+func LoadEffectiveConfig(projectDir string) (*Config, error) {
+	s, err := OpenSession("")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.LoadEffectiveConfig(projectDir)
+}
+
+// LoadEffectiveConfig returns the session's configuration overlaid, as by
+// OverlayConfig, with the project configuration that applies to
+// projectDir, so a repository's model and label choices override the
+// user's. The project file is read as by ParseConfig, and errors in it
+// wrap ErrParse or ErrInvalidOption; nothing is written to the store.
+func (s *Session) LoadEffectiveConfig(projectDir string) (*Config, error) {
+	current, err := CurrentConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	p, err := FindProjectConfig(projectDir)
+	if err != nil || p == "" {
+		return current, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("project config: %w: %v", ErrIO, err)
+	}
+	overlay, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return OverlayConfig(current, overlay), nil
+}
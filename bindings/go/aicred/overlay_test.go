@@ -0,0 +1,94 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverlayConfig(t *testing.T) {
+	base := &Config{
+		Instances: []ProviderInstance{
+			{ID: "anthropic", ProviderType: "anthropic", APIKey: NewSecretString("sk-ant"), Models: []string{"claude-sonnet"}},
+			{ID: "openai", ProviderType: "openai", APIKey: NewSecretString("sk-openai"), Models: []string{"gpt-4o"}},
+		},
+		Tags: []Tag{{Name: "prod", Description: "global"}},
+		Labels: []LabelAssignment{
+			{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "openai"}},
+			{LabelName: "smart", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "anthropic"}},
+		},
+	}
+	overlay := &Config{
+		Instances: []ProviderInstance{
+			{ID: "openai", ProviderType: "openai", Models: []string{"gpt-4o-mini"}},
+			{ID: "local", ProviderType: "ollama"},
+		},
+		Labels:         []LabelAssignment{{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "local"}}},
+		TagAssignments: []LabelAssignment{{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "local"}}},
+	}
+
+	c := OverlayConfig(base, overlay)
+	if len(c.Instances) != 3 || c.Instances[1].ID != "local" || c.Instances[2].Models[0] != "gpt-4o-mini" || c.Instances[2].APIKey.Reveal() != "sk-openai" {
+		t.Errorf("instances = %+v, want openai overridden with its key kept and local added", c.Instances)
+	}
+	if len(c.Tags) != 1 || c.Tags[0].Description != "global" {
+		t.Errorf("tags = %+v, want base's, as the overlay leaves them alone", c.Tags)
+	}
+	if len(c.Labels) != 2 || c.Labels[0].LabelName != "smart" || c.Labels[1].Target.InstanceID != "local" {
+		t.Errorf("labels = %+v, want fast moved to local and smart kept", c.Labels)
+	}
+	if len(c.TagAssignments) != 1 || c.InstancesByTag("prod")[0].ID != "local" {
+		t.Errorf("tag assignments = %+v, want prod on local", c.TagAssignments)
+	}
+	if base.Instances[1].Models[0] != "gpt-4o" || len(base.Labels) != 2 {
+		t.Error("OverlayConfig modified base")
+	}
+}
+
+func TestLoadEffectiveConfig(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.SaveTags([]Tag{{Name: "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	project := t.TempDir()
+	sub := filepath.Join(project, "services", "api")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := s.LoadEffectiveConfig(sub); err != nil || len(c.Tags) != 1 {
+		t.Errorf("without a project config: %+v, %v, want the user's", c, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(project, ".aicred"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	doc := "tags:\n  - name: prod\n    description: this repo\n  - name: staging\n"
+	if err := os.WriteFile(filepath.Join(project, ProjectConfigFile), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.LoadEffectiveConfig(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Tags) != 2 || c.Tags[0].Description != "this repo" || c.Instances == nil {
+		t.Errorf("effective config = %+v, want the project's tags over the user's", c)
+	}
+	if tags, _ := s.LoadTags(); len(tags) != 1 {
+		t.Error("LoadEffectiveConfig wrote to the store")
+	}
+
+	if err := os.WriteFile(filepath.Join(project, ProjectConfigFile), []byte("tags: ["), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.LoadEffectiveConfig(sub); !errors.Is(err, ErrParse) {
+		t.Errorf("malformed project config = %v, want ErrParse", err)
+	}
+}
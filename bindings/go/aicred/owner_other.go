@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package aicred
+
+import "io/fs"
+
+// fileOwner is unsupported on this platform; attribution omits the owner
+func fileOwner(info fs.FileInfo) (string, bool) {
+	return "", false
+}
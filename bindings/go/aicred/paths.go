@@ -0,0 +1,98 @@
+package aicred
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	// EnvHome names the variable that replaces the current user's home
+	// directory wherever an empty home directory means the default, in the
+	// bindings and the FFI alike
+	EnvHome = "AICRED_HOME"
+	// EnvConfigDir names the variable that replaces the aicred
+	// configuration directory for every home directory
+	EnvConfigDir = "AICRED_CONFIG_DIR"
+)
+
+// getenv reads the environment; tests replace it to fake a Windows profile
+// or an XDG layout
+var getenv = os.Getenv
+
+// xdgPlatform reports whether the XDG base directory variables apply: on
+// Linux and other Unix systems, but not macOS or Windows
+var xdgPlatform = runtime.GOOS != "windows" && runtime.GOOS != "darwin"
+
+// DefaultHomeDir returns the home directory used when none is given:
+// $AICRED_HOME if set, or the current user's home directory
+func DefaultHomeDir() (string, error) {
+	if home := getenv(EnvHome); home != "" {
+		return home, nil
+	}
+	return os.UserHomeDir()
+}
+
+// ConfigDir returns the directory aicred keeps its configuration in for
+// home: $AICRED_CONFIG_DIR if set, $XDG_CONFIG_HOME/aicred if home is the
+// current user's and the variable is set, or home/.config/aicred. When a
+// profile other than DefaultProfile is active, it is that profile's
+// directory under profiles. The FFI resolves the same directory, so
+// instances, labels and the files the bindings add stay together.
+func ConfigDir(home string) string {
+	base := baseConfigDir(home)
+	if name := activeProfile(base); name != DefaultProfile {
+		return filepath.Join(base, profilesDir, name)
+	}
+	return base
+}
+
+// baseConfigDir is ConfigDir without profiles: the directory holding the
+// default profile, the other profiles and the active-profile pointer
+func baseConfigDir(home string) string {
+	if dir := getenv(EnvConfigDir); dir != "" {
+		return dir
+	}
+	if xdg := xdgDir(home, "XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "aicred")
+	}
+	return filepath.Join(home, ".config", "aicred")
+}
+
+// DataDir returns the directory for data aicred keeps on behalf of home,
+// such as history and baselines: $XDG_DATA_HOME/aicred if home is the current user's
+// and the variable is set, or ConfigDir
+func DataDir(home string) string {
+	if xdg := xdgDir(home, "XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "aicred")
+	}
+	return ConfigDir(home)
+}
+
+// StateDir returns the directory for state that can be rebuilt, such as the
+// incremental scan cache: $XDG_STATE_HOME/aicred if home is the current
+// user's and the variable is set, or ConfigDir
+func StateDir(home string) string {
+	if xdg := xdgDir(home, "XDG_STATE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "aicred")
+	}
+	return ConfigDir(home)
+}
+
+// xdgDir returns the XDG base directory in the variable name when it
+// applies to home. The variables describe the current user, so a scan of
+// another home ignores them, and relative values are ignored as the
+// specification requires.
+func xdgDir(home, name string) string {
+	if !xdgPlatform {
+		return ""
+	}
+	dir := getenv(name)
+	if dir == "" || !filepath.IsAbs(dir) {
+		return ""
+	}
+	if own := getenv("HOME"); own == "" || filepath.Clean(own) != filepath.Clean(home) {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,82 @@
+package aicred
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// fakeEnv replaces the environment and platform seen by the path helpers
+// until the test ends
+func fakeEnv(t *testing.T, xdg bool, env map[string]string) {
+	t.Helper()
+	savedEnv, savedXDG := getenv, xdgPlatform
+	t.Cleanup(func() { getenv, xdgPlatform = savedEnv, savedXDG })
+	getenv = func(name string) string { return env[name] }
+	xdgPlatform = xdg
+}
+
+func TestConfigDir(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "alice")
+	other := filepath.Join(t.TempDir(), "bob")
+	xdg := t.TempDir()
+	env := map[string]string{
+		"HOME":            home,
+		"XDG_CONFIG_HOME": filepath.Join(xdg, "config"),
+		"XDG_DATA_HOME":   filepath.Join(xdg, "data"),
+		"XDG_STATE_HOME":  "relative/state",
+	}
+
+	fakeEnv(t, true, env)
+	for name, tc := range map[string]struct{ got, want string }{
+		"config":       {ConfigDir(home), filepath.Join(xdg, "config", "aicred")},
+		"data":         {DataDir(home), filepath.Join(xdg, "data", "aicred")},
+		"state":        {StateDir(home), filepath.Join(xdg, "config", "aicred")},
+		"other config": {ConfigDir(other), filepath.Join(other, ".config", "aicred")},
+		"other data":   {DataDir(other), filepath.Join(other, ".config", "aicred")},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %s, want %s", name, tc.got, tc.want)
+		}
+	}
+
+	fakeEnv(t, false, env)
+	if got, want := ConfigDir(home), filepath.Join(home, ".config", "aicred"); got != want {
+		t.Errorf("without XDG: ConfigDir = %s, want %s", got, want)
+	}
+
+	relocated := t.TempDir()
+	env[EnvConfigDir] = relocated
+	fakeEnv(t, true, env)
+	if ConfigDir(home) != relocated || ConfigDir(other) != relocated || StateDir(other) != relocated {
+		t.Errorf("%s did not relocate the configuration", EnvConfigDir)
+	}
+}
+
+func TestDefaultHomeDir(t *testing.T) {
+	home := t.TempDir()
+	fakeEnv(t, false, map[string]string{EnvHome: home})
+	if got, err := DefaultHomeDir(); err != nil || got != home {
+		t.Errorf("DefaultHomeDir = %s, %v, want %s", got, err, home)
+	}
+
+	fakeEnv(t, false, map[string]string{EnvHome: home, EnvConfigDir: t.TempDir()})
+	if dir, err := scanCacheDir(ScanOptions{}); err != nil || dir != getenv(EnvConfigDir) {
+		t.Errorf("scan cache in %s, %v", dir, err)
+	}
+}
+
+func TestSessionOrganizationsFollowConfigDir(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	relocated := t.TempDir()
+	fakeEnv(t, false, map[string]string{EnvConfigDir: relocated})
+	if got, want := s.organizationsPath(), filepath.Join(relocated, "organizations.json"); got != want {
+		t.Errorf("organizations in %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package aicred
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+const (
+	// configDirMode is enforced on the configuration directory when aicred
+	// saves into it
+	configDirMode fs.FileMode = 0o700
+	// configFileMode is the mode aicred writes its files with
+	configFileMode fs.FileMode = 0o600
+)
+
+// PermissionKind classifies a PermissionIssue
+type PermissionKind string
+
+// Permission issue kinds
+const (
+	// PermissionWorldReadable is a credential file other users can read,
+	// or a configuration directory they can list or enter
+	PermissionWorldReadable PermissionKind = "world_readable"
+	// PermissionWorldWritable is a credential file or configuration
+	// directory other users can modify
+	PermissionWorldWritable PermissionKind = "world_writable"
+)
+
+// PermissionIssue is one file or directory found by CheckPermissions
+type PermissionIssue struct {
+	Kind    PermissionKind
+	Message string
+	Path    string
+	Mode    fs.FileMode
+	// Own reports that the path is part of aicred's configuration rather
+	// than an application config a scan found a key in
+	Own bool
+}
+
+// CheckPermissions reports the files in the configuration directory of
+// home, the directory itself, and the files result found keys in that
+// other users can read or modify. result may be nil. Keys found in
+// archives are checked against the archive. Windows access is governed
+// by ACLs that mode bits do not reflect, so nothing is reported there.
+func CheckPermissions(home string, result *ScanResult) []PermissionIssue {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	var issues []PermissionIssue
+	check := func(p string, info fs.FileInfo, own bool) {
+		mode := info.Mode().Perm()
+		what := "file"
+		if info.IsDir() {
+			what = "directory"
+		}
+		if mode&0o002 != 0 {
+			issues = append(issues, PermissionIssue{Kind: PermissionWorldWritable, Path: p, Mode: mode, Own: own,
+				Message: fmt.Sprintf("%s %s is writable by other users (mode %04o)", what, p, mode)})
+		}
+		if mode&0o004 != 0 || (info.IsDir() && mode&0o001 != 0) {
+			issues = append(issues, PermissionIssue{Kind: PermissionWorldReadable, Path: p, Mode: mode, Own: own,
+				Message: fmt.Sprintf("%s %s is readable by other users (mode %04o)", what, p, mode)})
+		}
+	}
+
+	checked := map[string]bool{}
+	_ = filepath.WalkDir(ConfigDir(home), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !(info.IsDir() || info.Mode().IsRegular()) {
+			return nil
+		}
+		checked[p] = true
+		check(p, info, true)
+		return nil
+	})
+
+	if result == nil {
+		return issues
+	}
+	var sources []string
+	for _, key := range result.Keys {
+		sources = append(sources, key.Source)
+	}
+	for _, ci := range result.ConfigInstances {
+		sources = append(sources, ci.ConfigPath)
+	}
+	for _, source := range sources {
+		p, info := sourceFile(source)
+		if info == nil || checked[p] {
+			continue
+		}
+		checked[p] = true
+		check(p, info, false)
+	}
+	return issues
+}
+
+// sourceFile returns the regular file a key's source names, or a nil
+// FileInfo for sources that are not files, such as registry paths or
+// browser storage. Archive members resolve to their archive.
+func sourceFile(source string) (string, fs.FileInfo) {
+	if source == "" {
+		return "", nil
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		archive, _, found := strings.Cut(source, "!")
+		if !found {
+			return "", nil
+		}
+		source = archive
+		if info, err = os.Stat(source); err != nil {
+			return "", nil
+		}
+	}
+	if !info.Mode().IsRegular() {
+		return "", nil
+	}
+	return filepath.Clean(source), info
+}
+
+// CheckPermissions scans the Client's home directory with default options
+// and reports what CheckPermissions finds in it and the Client's
+// configuration directory, publishing any issues as a ValidationFailed
+// event
+func (c *Client) CheckPermissions() ([]PermissionIssue, error) {
+	result, err := c.Scan(ScanOptions{})
+	if err != nil {
+		return nil, err
+	}
+	issues := CheckPermissions(c.homeDir, result)
+	if len(issues) > 0 {
+		problems := make([]string, len(issues))
+		for i, issue := range issues {
+			problems[i] = issue.Message
+		}
+		c.events.Publish(events.ValidationFailed{Time: time.Now().UTC(), Subject: "permissions", Problems: problems})
+	}
+	return issues, nil
+}
+
+// hardenConfigDir creates dir if needed and restricts it to its owner.
+// It is called before aicred saves into its configuration directory, so a
+// directory created by hand or by an older version is tightened too.
+func hardenConfigDir(dir string) error {
+	if err := os.MkdirAll(dir, configDirMode); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm() != configDirMode {
+		return os.Chmod(dir, configDirMode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package aicred
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits do not describe Windows ACLs")
+	}
+	home := t.TempDir()
+	writeInstance(t, home)
+	config := filepath.Join(home, ".config", "aicred")
+	instance := filepath.Join(config, "inference_services", "openai-main.yaml")
+	app := filepath.Join(home, ".claude.json")
+	archive := filepath.Join(home, "backup.zip")
+	private := filepath.Join(home, ".env")
+	for _, p := range []string{app, archive, private} {
+		writeFile(t, p, []byte("{}"))
+	}
+	for p, mode := range map[string]os.FileMode{config: 0o755, instance: 0o644, app: 0o666, archive: 0o604} {
+		if err := os.Chmod(p, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := &ScanResult{
+		Keys: []DiscoveredKey{
+			{Source: archive + "!config/.env"},
+			{Source: private},
+			{Source: `HKCU\Software\Claude`},
+		},
+		ConfigInstances: []ConfigInstance{{ConfigPath: app}, {ConfigPath: app}},
+	}
+	var got []string
+	for _, issue := range CheckPermissions(home, result) {
+		rel, _ := filepath.Rel(home, issue.Path)
+		own := map[bool]string{true: "own", false: "app"}[issue.Own]
+		got = append(got, own+" "+string(issue.Kind)+" "+filepath.ToSlash(rel))
+	}
+	slices.Sort(got)
+	want := []string{
+		"app world_readable .claude.json",
+		"app world_readable backup.zip",
+		"app world_writable .claude.json",
+		"own world_readable .config/aicred",
+		"own world_readable .config/aicred/inference_services/openai-main.yaml",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CheckPermissions =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSaveHardensConfigDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits do not describe Windows ACLs")
+	}
+	home := t.TempDir()
+	writeInstance(t, home)
+	config := filepath.Join(home, ".config", "aicred")
+	if err := os.Chmod(config, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Organizations().Save(Organization{ID: "platform"}); err != nil {
+		t.Fatal(err)
+	}
+	if issues := CheckPermissions(home, nil); len(issues) != 0 {
+		t.Errorf("after save: %+v", issues)
+	}
+}
@@ -0,0 +1,131 @@
+package aicred
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type pkgConfigKind int
+
+const (
+	notPkgConfig pkgConfigKind = iota
+	// pkgINI is .npmrc, pip.conf and poetry's auth.toml: key = value lines
+	// under optional [section] headers
+	pkgINI
+	// pkgYAML is .yarnrc.yml
+	pkgYAML
+	// pkgJSON is package.json, of which only the "config" block is searched
+	pkgJSON
+)
+
+// pkgConfigKindOf classifies a file by name. auth.toml is only poetry's,
+// which lives in a pypoetry directory.
+func pkgConfigKindOf(p string) pkgConfigKind {
+	switch name := filepath.Base(p); name {
+	case ".npmrc", "npmrc", ".yarnrc", "pip.conf", "pip.ini":
+		return pkgINI
+	case ".yarnrc.yml", ".yarnrc.yaml":
+		return pkgYAML
+	case "package.json":
+		return pkgJSON
+	case "auth.toml":
+		if strings.EqualFold(filepath.Base(filepath.Dir(p)), "pypoetry") {
+			return pkgINI
+		}
+	}
+	return notPkgConfig
+}
+
+// scanPackageConfigs walks home for npm, yarn, pip and poetry
+// configuration and package.json files and returns the keys in their
+// values, with JSON-pointer locations such as /global/index-url
+func scanPackageConfigs(home string, options ScanOptions, run *scanRun) []DiscoveredKey {
+	limit := int64(options.MaxFileSize)
+	if limit <= 0 {
+		limit = defaultMaxFileSize
+	}
+	var paths []string
+	w := newWalker(passPackageConfigs, home, options, run)
+	for _, root := range scanRoots(home) {
+		w.walk(root, 0, func(p string, d fs.DirEntry) {
+			if pkgConfigKindOf(p) == notPkgConfig {
+				return
+			}
+			info, err := d.Info()
+			if err != nil {
+				return
+			}
+			if info.Size() > limit {
+				run.warn(passPackageConfigs, p, WarningTooLarge, fmt.Errorf("%d bytes exceeds the limit of %d", info.Size(), limit))
+				return
+			}
+			paths = append(paths, p)
+		})
+	}
+	return run.scanFiles(passPackageConfigs, paths, options, func(p string) []DiscoveredKey {
+		keys, err := scanPackageConfig(p, options)
+		if err != nil {
+			run.warn(passPackageConfigs, p, warningKindOf(err), err)
+		}
+		return keys
+	})
+}
+
+// scanPackageConfig parses one package manager configuration file
+func scanPackageConfig(p string, options ScanOptions) ([]DiscoveredKey, error) {
+	data, err := os.ReadFile(p)
+	defer zero(data)
+	if err != nil {
+		logger().Debug("skipping unreadable file", slog.String("path", p), slog.String("error", err.Error()))
+		return nil, err
+	}
+	switch pkgConfigKindOf(p) {
+	case pkgINI:
+		return lineDocumentKeys(p, data, options, iniAssignment, false), nil
+	case pkgYAML:
+		return lineDocumentKeys(p, data, options, yamlAssignment, true), nil
+	case pkgJSON:
+		keys, err := jsonDocumentKeys(p, data, options)
+		if err != nil {
+			return nil, err
+		}
+		// Dependencies and scripts are code, not configuration
+		var config []DiscoveredKey
+		for _, key := range keys {
+			if strings.HasPrefix(key.Location.Pointer, "/config/") {
+				config = append(config, key)
+			}
+		}
+		return config, nil
+	}
+	return nil, nil
+}
+
+var iniLine = regexp.MustCompile(`^(\s*)([^\s=;#\[][^=]*?)\s*=\s*(.*?)\s*$`)
+
+// iniAssignment parses a key = value line or a [section] header, which
+// closes the previous section. npm's registry keys such as
+// //host/:_authToken keep their colons and slashes.
+func iniAssignment(line string) (assignment, bool) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+		return assignment{name: strings.Trim(trimmed, "[] "), opens: true, closes: 1}, true
+	}
+	m := iniLine.FindStringSubmatchIndex(line)
+	if m == nil {
+		return assignment{}, false
+	}
+	a := assignment{indent: m[3] - m[2], name: line[m[4]:m[5]], start: m[6]}
+	value := line[m[6]:m[7]]
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+		a.start++
+	}
+	a.value = value
+	return a, true
+}
@@ -0,0 +1,409 @@
+package aicred
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeAction is what a Change does
+type ChangeAction string
+
+// Change actions
+const (
+	ActionCreate ChangeAction = "create"
+	ActionUpdate ChangeAction = "update"
+	ActionDelete ChangeAction = "delete"
+)
+
+// ChangeKind is what a Change applies to
+type ChangeKind string
+
+// Change kinds
+const (
+	KindInstance ChangeKind = "instance"
+	KindTag      ChangeKind = "tag"
+	KindLabel    ChangeKind = "label"
+	// KindTagAssignment is the assignment of a tag, kept apart from label
+	// assignments in tag_assignments.yaml
+	KindTagAssignment ChangeKind = "tag_assignment"
+)
+
+// sensitive stands in for API keys in FieldChanges
+const sensitive = "(sensitive)"
+
+// FieldChange is one field a Change sets: Old is "" for creates
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Change is one step of a ChangeSet. Name is the instance ID, the tag name,
+// or the label and its target, such as "fast on openai-prod/gpt-4o".
+type Change struct {
+	Action ChangeAction  `json:"action"`
+	Kind   ChangeKind    `json:"kind"`
+	Name   string        `json:"name"`
+	Fields []FieldChange `json:"fields,omitempty"`
+
+	instance ProviderInstance
+	tag      Tag
+	label    LabelAssignment
+}
+
+// ChangeSet is what Apply does to make a store match a desired Config
+type ChangeSet struct {
+	Changes []Change
+}
+
+// Empty reports whether the desired configuration is already in place
+func (cs *ChangeSet) Empty() bool {
+	return len(cs.Changes) == 0
+}
+
+// Counts returns how many resources the change set creates, updates and
+// deletes
+func (cs *ChangeSet) Counts() (create, update, delete int) {
+	for _, c := range cs.Changes {
+		switch c.Action {
+		case ActionCreate:
+			create++
+		case ActionUpdate:
+			update++
+		case ActionDelete:
+			delete++
+		}
+	}
+	return create, update, delete
+}
+
+// String renders the change set as a preview to review before Apply,
+// marking creates with +, updates with ~ and deletes with -, and ending
+// with a summary line. API keys show as (sensitive).
+func (cs *ChangeSet) String() string {
+	if cs.Empty() {
+		return "No changes. The configuration matches.\n"
+	}
+	var b strings.Builder
+	for _, c := range cs.Changes {
+		sign := map[ChangeAction]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[c.Action]
+		fmt.Fprintf(&b, "%s %s %q\n", sign, c.Kind, c.Name)
+		for _, f := range c.Fields {
+			if c.Action == ActionCreate {
+				fmt.Fprintf(&b, "    %s: %s\n", f.Field, f.New)
+			} else {
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", f.Field, f.Old, f.New)
+			}
+		}
+	}
+	create, update, del := cs.Counts()
+	fmt.Fprintf(&b, "\nPlan: %d to add, %d to change, %d to destroy.\n", create, update, del)
+	return b.String()
+}
+
+// Plan compares desired with current, usually a ParseConfig document and
+// CurrentConfig, and returns the changes that make current match: instances
+// and tags to create, update or delete, and label and tag assignments to
+// create or delete. Sections of desired that are nil are left alone. A desired
+// instance without an API key keeps the current one. A nil Config is an
+// empty one, such as the current side on a machine not yet configured.
+func Plan(desired, current *Config) *ChangeSet {
+	if desired == nil {
+		desired = &Config{}
+	}
+	if current == nil {
+		current = &Config{}
+	}
+	cs := &ChangeSet{}
+	if desired.Instances != nil {
+		cs.Changes = append(cs.Changes, planInstances(desired.Instances, current.Instances)...)
+	}
+	if desired.Tags != nil {
+		cs.Changes = append(cs.Changes, planTags(desired.Tags, current.Tags)...)
+	}
+	if desired.Labels != nil {
+		cs.Changes = append(cs.Changes, planLabels(desired.Labels, current.Labels)...)
+	}
+	if desired.TagAssignments != nil {
+		cs.Changes = append(cs.Changes, planTagAssignments(desired.TagAssignments, current.TagAssignments)...)
+	}
+	return cs
+}
+
+func planInstances(desired, current []ProviderInstance) []Change {
+	have := map[string]ProviderInstance{}
+	for _, instance := range current {
+		have[instance.ID] = instance
+	}
+	var changes []Change
+	want := map[string]bool{}
+	for _, instance := range desired {
+		want[instance.ID] = true
+		old, ok := have[instance.ID]
+		if !ok {
+			changes = append(changes, Change{Action: ActionCreate, Kind: KindInstance, Name: instance.ID,
+				Fields: createFields(instanceFields(ProviderInstance{}, instance)), instance: instance})
+			continue
+		}
+		if instance.APIKey.IsZero() {
+			instance.APIKey = old.APIKey
+		}
+		if fields := instanceFields(old, instance); len(fields) > 0 {
+			changes = append(changes, Change{Action: ActionUpdate, Kind: KindInstance, Name: instance.ID, Fields: fields, instance: instance})
+		}
+	}
+	for _, instance := range current {
+		if !want[instance.ID] {
+			changes = append(changes, Change{Action: ActionDelete, Kind: KindInstance, Name: instance.ID, instance: instance})
+		}
+	}
+	sortChanges(changes)
+	return changes
+}
+
+// instanceFields lists the fields that differ between old and new
+func instanceFields(old, new ProviderInstance) []FieldChange {
+	var fields []FieldChange
+	diff := func(field, o, n string) {
+		if o != n {
+			fields = append(fields, FieldChange{Field: field, Old: o, New: n})
+		}
+	}
+	diff("provider_type", quoteField(old.ProviderType), quoteField(new.ProviderType))
+	diff("base_url", quoteField(old.BaseURL), quoteField(new.BaseURL))
+	if !old.APIKey.Equal(new.APIKey) {
+		o := ""
+		if !old.APIKey.IsZero() {
+			o = sensitive
+		}
+		fields = append(fields, FieldChange{Field: "api_key", Old: o, New: sensitive})
+	}
+	diff("models", listField(old.Models), listField(new.Models))
+	diff("capabilities", listField(old.Capabilities.Names()), listField(new.Capabilities.Names()))
+	diff("active", strconv.FormatBool(old.Active), strconv.FormatBool(new.Active))
+	diff("metadata", mapField(old.Metadata), mapField(new.Metadata))
+	return fields
+}
+
+// createFields lists what a create sets, leaving out empty values
+func createFields(fields []FieldChange) []FieldChange {
+	return slices.DeleteFunc(fields, func(f FieldChange) bool { return f.New == `""` || f.New == "[]" || f.New == "{}" })
+}
+
+func planTags(desired, current []Tag) []Change {
+	have := map[string]Tag{}
+	for _, tag := range current {
+		have[tag.Name] = tag
+	}
+	var changes []Change
+	want := map[string]bool{}
+	for _, tag := range desired {
+		want[tag.Name] = true
+		old, ok := have[tag.Name]
+		if ok {
+			tag.CreatedAt = old.CreatedAt
+		}
+		var fields []FieldChange
+		diff := func(field, o, n string) {
+			if o != n {
+				fields = append(fields, FieldChange{Field: field, Old: o, New: n})
+			}
+		}
+		diff("description", quoteField(old.Description), quoteField(tag.Description))
+		diff("parent", quoteField(old.Parent), quoteField(tag.Parent))
+		diff("uniqueness", quoteField(cmp.Or(old.Uniqueness, TagUniqueGlobal)), quoteField(cmp.Or(tag.Uniqueness, TagUniqueGlobal)))
+		diff("metadata", mapField(old.Metadata), mapField(tag.Metadata))
+		switch {
+		case !ok:
+			changes = append(changes, Change{Action: ActionCreate, Kind: KindTag, Name: tag.Name, Fields: createFields(fields), tag: tag})
+		case len(fields) > 0:
+			changes = append(changes, Change{Action: ActionUpdate, Kind: KindTag, Name: tag.Name, Fields: fields, tag: tag})
+		}
+	}
+	for _, tag := range current {
+		if !want[tag.Name] {
+			changes = append(changes, Change{Action: ActionDelete, Kind: KindTag, Name: tag.Name, tag: tag})
+		}
+	}
+	sortChanges(changes)
+	return changes
+}
+
+func planLabels(desired, current []LabelAssignment) []Change {
+	return planAssignments(KindLabel, desired, current)
+}
+
+// planTagAssignments plans tag assignments as planLabels does label
+// assignments
+func planTagAssignments(desired, current []LabelAssignment) []Change {
+	return planAssignments(KindTagAssignment, desired, current)
+}
+
+func planAssignments(kind ChangeKind, desired, current []LabelAssignment) []Change {
+	have := map[string]bool{}
+	for _, label := range current {
+		have[labelName(label)] = true
+	}
+	var changes []Change
+	want := map[string]bool{}
+	for _, label := range desired {
+		name := labelName(label)
+		if want[name] {
+			continue
+		}
+		want[name] = true
+		if !have[name] {
+			changes = append(changes, Change{Action: ActionCreate, Kind: kind, Name: name, label: label})
+		}
+	}
+	for _, label := range current {
+		if name := labelName(label); !want[name] {
+			want[name] = true
+			changes = append(changes, Change{Action: ActionDelete, Kind: kind, Name: name, label: label})
+		}
+	}
+	sortChanges(changes)
+	return changes
+}
+
+// labelName names an assignment by its label and target, which is all that
+// identifies it
+func labelName(label LabelAssignment) string {
+	target := label.Target.InstanceID
+	if label.Target.Type == LabelTargetModel {
+		target += "/" + label.Target.ModelID
+	}
+	return label.LabelName + " on " + target
+}
+
+func sortChanges(changes []Change) {
+	slices.SortStableFunc(changes, func(a, b Change) int { return strings.Compare(a.Name, b.Name) })
+}
+
+func quoteField(s string) string {
+	return strconv.Quote(s)
+}
+
+func listField(values []string) string {
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+func mapField(m map[string]string) string {
+	var pairs []string
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		pairs = append(pairs, k+"="+m[k])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Apply makes the changes in cs to store: instance creates and updates
+// first, then tags, then label and tag assignments, and instance deletes
+// last, so that assignments never point at a missing instance. It stops at the first
+// change that fails.
+func Apply(store ConfigStore, cs *ChangeSet) error {
+	return batched(store, func() error { return apply(store, cs) })
+}
+
+// batchStore is a ConfigStore that can group writes, as a Session does to
+// take one revision for a whole Apply
+type batchStore interface {
+	batch(fn func() error) error
+}
+
+// batched runs fn, which writes to store, as one batch if the store can
+// group writes
+func batched(store ConfigStore, fn func() error) error {
+	if b, ok := store.(batchStore); ok {
+		return b.batch(fn)
+	}
+	return fn()
+}
+
+func apply(store ConfigStore, cs *ChangeSet) error {
+	var tags, labels, tagAssignments, deletes []Change
+	for _, c := range cs.Changes {
+		switch {
+		case c.Kind == KindTag:
+			tags = append(tags, c)
+		case c.Kind == KindLabel:
+			labels = append(labels, c)
+		case c.Kind == KindTagAssignment:
+			tagAssignments = append(tagAssignments, c)
+		case c.Action == ActionDelete:
+			deletes = append(deletes, c)
+		default:
+			if err := store.SaveInstance(c.instance); err != nil {
+				return fmt.Errorf("apply: %s instance %q: %w", c.Action, c.Name, err)
+			}
+		}
+	}
+	if len(tags) > 0 {
+		if err := applyTags(store, tags); err != nil {
+			return fmt.Errorf("apply: tags: %w", err)
+		}
+	}
+	if len(labels) > 0 {
+		if err := applyAssignments(labels, store.LoadLabels, store.SaveLabels); err != nil {
+			return fmt.Errorf("apply: labels: %w", err)
+		}
+	}
+	if len(tagAssignments) > 0 {
+		if err := applyAssignments(tagAssignments, store.LoadTagAssignments, store.SaveTagAssignments); err != nil {
+			return fmt.Errorf("apply: tag assignments: %w", err)
+		}
+	}
+	for _, c := range deletes {
+		if err := store.DeleteInstance(c.Name); err != nil {
+			return fmt.Errorf("apply: delete instance %q: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyTags(store ConfigStore, changes []Change) error {
+	current, err := store.LoadTags()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, c := range changes {
+		current = slices.DeleteFunc(current, func(t Tag) bool { return t.Name == c.Name })
+		if c.Action == ActionDelete {
+			continue
+		}
+		tag := c.tag
+		if tag.CreatedAt.IsZero() {
+			tag.CreatedAt = now
+		}
+		current = append(current, tag)
+	}
+	sortTags(current)
+	return store.SaveTags(current)
+}
+
+// applyAssignments applies label or tag assignment changes to what load
+// returns and saves the result
+func applyAssignments(changes []Change, load func() ([]LabelAssignment, error), save func([]LabelAssignment) error) error {
+	current, err := load()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, c := range changes {
+		current = slices.DeleteFunc(current, func(l LabelAssignment) bool { return labelName(l) == c.Name })
+		if c.Action == ActionDelete {
+			continue
+		}
+		label := c.label
+		if label.AssignedAt.IsZero() {
+			label.AssignedAt = now
+		}
+		current = append(current, label)
+	}
+	return save(current)
+}
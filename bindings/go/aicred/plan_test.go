@@ -0,0 +1,124 @@
+package aicred
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanAndApply(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore([]ProviderInstance{
+		{ID: "anthropic", ProviderType: "anthropic", BaseURL: "https://api.anthropic.com", APIKey: NewSecretString("sk-ant-old"), Models: []string{"claude-3"}, Active: true},
+		{ID: "legacy", ProviderType: "openai", BaseURL: "https://api.openai.com/v1"},
+	}, []LabelAssignment{
+		{LabelName: "old", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "legacy"}},
+		{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "anthropic"}},
+	})
+	store.SaveTags([]Tag{{Name: "old", CreatedAt: created}, {Name: "prod", CreatedAt: created}})
+
+	fakeEnv(t, false, map[string]string{"OPENAI_API_KEY": "sk-proj-new"})
+	desired, err := ParseConfig([]byte(`instances:
+  - id: anthropic
+    provider_type: anthropic
+    base_url: https://api.anthropic.com
+    models: [claude-3, claude-3-5]
+    active: true
+  - id: openai
+    provider_type: openai
+    base_url: https://api.openai.com/v1
+    api_key: ${OPENAI_API_KEY}
+    capabilities: {chat: true}
+tags:
+  - name: prod
+    description: Production
+    metadata: {color: "#ff0000"}
+labels:
+  - label_name: prod
+    target: {type: provider_instance, instance_id: anthropic}
+  - label_name: prod
+    target: {type: provider_model, instance_id: openai, model_id: gpt-4o}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := CurrentConfig(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := Plan(desired, current)
+	if create, update, del := cs.Counts(); create != 2 || update != 2 || del != 3 {
+		t.Errorf("counts = %d, %d, %d:\n%s", create, update, del, cs)
+	}
+	preview := cs.String()
+	for _, want := range []string{
+		`~ instance "anthropic"` + "\n    models: [claude-3] -> [claude-3, claude-3-5]\n",
+		`+ instance "openai"` + "\n    provider_type: \"openai\"\n    base_url: \"https://api.openai.com/v1\"\n    api_key: (sensitive)\n    capabilities: [chat]\n",
+		`- instance "legacy"`,
+		`~ tag "prod"` + "\n    description: \"\" -> \"Production\"\n    metadata: {} -> {color=#ff0000}\n",
+		`- tag "old"`,
+		`+ label "prod on openai/gpt-4o"`,
+		`- label "old on legacy"`,
+		"Plan: 2 to add, 2 to change, 3 to destroy.",
+	} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview lacks %q:\n%s", want, preview)
+		}
+	}
+	if strings.Contains(preview, "sk-") {
+		t.Errorf("preview holds a key:\n%s", preview)
+	}
+
+	if err := Apply(store, cs); err != nil {
+		t.Fatal(err)
+	}
+	anthropic, err := store.GetInstance("anthropic")
+	if err != nil || anthropic.APIKey.Reveal() != "sk-ant-old" || len(anthropic.Models) != 2 {
+		t.Errorf("anthropic = %+v, %v", anthropic, err)
+	}
+	if openai, err := store.GetInstance("openai"); err != nil || openai.APIKey.Reveal() != "sk-proj-new" {
+		t.Errorf("openai = %+v, %v", openai, err)
+	}
+	if _, err := store.GetInstance("legacy"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("legacy was not deleted: %v", err)
+	}
+	tags, _ := store.LoadTags()
+	if len(tags) != 1 || tags[0].Description != "Production" || !tags[0].CreatedAt.Equal(created) {
+		t.Errorf("tags = %+v", tags)
+	}
+	labels, _ := store.LoadLabels()
+	if len(labels) != 2 || labels[1].Target.ModelID != "gpt-4o" || labels[1].AssignedAt.IsZero() {
+		t.Errorf("labels = %+v", labels)
+	}
+
+	current, _ = CurrentConfig(store)
+	if again := Plan(desired, current); !again.Empty() {
+		t.Errorf("plan after apply:\n%s", again)
+	}
+}
+
+func TestPlanUnmanagedSections(t *testing.T) {
+	current := &Config{
+		Instances: []ProviderInstance{{ID: "a"}},
+		Tags:      []Tag{{Name: "prod"}},
+		Labels:    []LabelAssignment{{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "a"}}},
+	}
+	if cs := Plan(&Config{}, current); !cs.Empty() || cs.String() != "No changes. The configuration matches.\n" {
+		t.Errorf("nil sections planned changes:\n%s", cs)
+	}
+	if cs := Plan(nil, current); !cs.Empty() {
+		t.Errorf("nil desired planned changes:\n%s", cs)
+	}
+	if cs := Plan(&Config{Instances: []ProviderInstance{{ID: "a"}}}, nil); len(cs.Changes) != 1 || cs.Changes[0].Action != ActionCreate {
+		t.Errorf("nil current = %+v", cs.Changes)
+	}
+	if cs := Plan(&Config{Tags: []Tag{}}, current); len(cs.Changes) != 1 || cs.Changes[0].Action != ActionDelete || cs.Changes[0].Kind != KindTag {
+		t.Errorf("empty tags = %+v", cs.Changes)
+	}
+	tagged := &Config{TagAssignments: current.Labels}
+	if cs := Plan(tagged, current); len(cs.Changes) != 1 || cs.Changes[0].Action != ActionCreate || cs.Changes[0].Kind != KindTagAssignment {
+		t.Errorf("tag assignment named like a label = %+v", cs.Changes)
+	}
+}
@@ -0,0 +1,193 @@
+package aicred
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// bplistMagic starts every binary property list
+const bplistMagic = "bplist00"
+
+const (
+	// bplistMaxStrings and bplistMaxBytes bound the text bplistStrings
+	// returns, and the strings it decodes, as the offset table of a damaged
+	// file can point every object at one large string
+	bplistMaxStrings = 1 << 16
+	bplistMaxBytes   = 8 << 20
+	// bplistMaxEntries bounds the dictionary entries read, as overlapping
+	// dictionaries can share their references
+	bplistMaxEntries = 1 << 20
+)
+
+// bplistStrings returns the text of a binary property list for the
+// detector: each dictionary entry with string key and value as "key = value"
+// and every other string on its own line. Damaged input yields whatever was
+// read before the damage. Each object offset is decoded once, however many
+// table entries name it, and the text is capped at bplistMaxStrings lines
+// and bplistMaxBytes.
+func bplistStrings(data []byte) []string {
+	if len(data) < len(bplistMagic)+32 || string(data[:len(bplistMagic)]) != bplistMagic {
+		return nil
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:32])
+	if offsetSize == 0 || refSize == 0 || numObjects > uint64(len(data)) || tableOffset >= uint64(len(data)) {
+		return nil
+	}
+
+	p := &bplist{data: data, refSize: refSize}
+	offsets := make([]int, 0, numObjects)
+	var distinct []int
+	seen := make(map[int]bool)
+	for i := uint64(0); i < numObjects; i++ {
+		at := int(tableOffset) + int(i)*offsetSize
+		off, ok := p.uint(at, offsetSize)
+		if !ok || off >= uint64(len(data)) {
+			break
+		}
+		offsets = append(offsets, int(off))
+		if !seen[int(off)] {
+			seen[int(off)] = true
+			distinct = append(distinct, int(off))
+		}
+	}
+
+	strs := make(map[int]string)
+	decoded := 0
+	for _, off := range distinct {
+		if s, ok := p.string(off); ok {
+			if decoded += len(s); decoded > bplistMaxBytes {
+				break
+			}
+			strs[off] = s
+		}
+	}
+	str := func(ref int) (int, string, bool) {
+		if ref < 0 || ref >= len(offsets) {
+			return 0, "", false
+		}
+		s, ok := strs[offsets[ref]]
+		return offsets[ref], s, ok
+	}
+
+	var lines []string
+	size := 0
+	add := func(line string) bool {
+		if len(lines) >= bplistMaxStrings || size+len(line) > bplistMaxBytes {
+			return false
+		}
+		size += len(line)
+		lines = append(lines, line)
+		return true
+	}
+	paired := make(map[int]bool)
+	entries := bplistMaxEntries
+	for _, off := range distinct {
+		keys, values, ok := p.dict(off, entries)
+		if !ok {
+			continue
+		}
+		entries -= len(keys)
+		for j := range keys {
+			koff, k, kok := str(keys[j])
+			voff, v, vok := str(values[j])
+			if !kok || !vok {
+				continue
+			}
+			if !add(k + " = " + v) {
+				return lines
+			}
+			paired[koff], paired[voff] = true, true
+		}
+	}
+	for _, off := range distinct {
+		if s, ok := strs[off]; ok && !paired[off] && !add(s) {
+			break
+		}
+	}
+	return lines
+}
+
+type bplist struct {
+	data    []byte
+	refSize int
+}
+
+// uint reads a big-endian unsigned integer of size bytes at off
+func (p *bplist) uint(off, size int) (uint64, bool) {
+	if off < 0 || size > 8 || off+size > len(p.data) {
+		return 0, false
+	}
+	var v uint64
+	for _, b := range p.data[off : off+size] {
+		v = v<<8 | uint64(b)
+	}
+	return v, true
+}
+
+// length decodes the count in an object marker, returning it and the
+// offset of the object's contents
+func (p *bplist) length(off int) (int, int, bool) {
+	info := int(p.data[off] & 0x0f)
+	if info != 0x0f {
+		return info, off + 1, true
+	}
+	if off+1 >= len(p.data) || p.data[off+1]>>4 != 0x1 {
+		return 0, 0, false
+	}
+	size := 1 << (p.data[off+1] & 0x0f)
+	n, ok := p.uint(off+2, size)
+	if !ok || n > uint64(len(p.data)) {
+		return 0, 0, false
+	}
+	return int(n), off + 2 + size, true
+}
+
+// string decodes an ASCII or UTF-16 string object
+func (p *bplist) string(off int) (string, bool) {
+	kind := p.data[off] >> 4
+	if kind != 0x5 && kind != 0x6 {
+		return "", false
+	}
+	n, start, ok := p.length(off)
+	if !ok {
+		return "", false
+	}
+	if kind == 0x5 {
+		if start+n > len(p.data) {
+			return "", false
+		}
+		return string(p.data[start : start+n]), true
+	}
+	if start+2*n > len(p.data) {
+		return "", false
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(p.data[start+2*i:])
+	}
+	return string(utf16.Decode(units)), true
+}
+
+// dict returns the key and value object indexes of a dictionary object
+// of at most limit entries
+func (p *bplist) dict(off, limit int) ([]int, []int, bool) {
+	if p.data[off]>>4 != 0xd {
+		return nil, nil, false
+	}
+	n, start, ok := p.length(off)
+	if !ok || n > limit || start+2*n*p.refSize > len(p.data) {
+		return nil, nil, false
+	}
+	keys := make([]int, n)
+	values := make([]int, n)
+	for i := 0; i < n; i++ {
+		k, _ := p.uint(start+i*p.refSize, p.refSize)
+		v, _ := p.uint(start+(n+i)*p.refSize, p.refSize)
+		keys[i], values[i] = int(k), int(v)
+	}
+	return keys, values, true
+}
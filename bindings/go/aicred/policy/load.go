@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// ruleFile is the YAML form of a policy:
+//
+//	rules:
+//	  - rule: no-full-values
+//	    severity: critical
+//	  - rule: forbidden-provider
+//	    severity: high
+//	    providers: [groq]
+//	  - rule: max-key-age
+//	    max_age: 90d
+//	  - rule: require-label
+//	    labels: ["env:*"]
+type ruleFile struct {
+	Rules []ruleSpec `yaml:"rules"`
+}
+
+// ruleSpec holds one rule and the options of every rule kind; Load rejects
+// options that do not belong to the named rule
+type ruleSpec struct {
+	Rule      string   `yaml:"rule"`
+	Severity  string   `yaml:"severity"`
+	Providers []string `yaml:"providers"`
+	Minimum   string   `yaml:"minimum"`
+	Keys      []string `yaml:"keys"`
+	Labels    []string `yaml:"labels"`
+	MaxAge    string   `yaml:"max_age"`
+}
+
+// Load builds a policy from a YAML rule file. Each entry names a rule by
+// the value its Name method returns and gives its options; severity
+// defaults to medium. max_age takes a whole number of days such as "90d"
+// or a Go duration. Malformed YAML wraps aicred.ErrParse; unknown rules,
+// fields or option values wrap aicred.ErrInvalidOption.
+func Load(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var file ruleFile
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("load policy: %w: %v", aicred.ErrInvalidOption, err)
+		}
+		return nil, fmt.Errorf("load policy: %w: %v", aicred.ErrParse, err)
+	}
+	p := New()
+	for i, spec := range file.Rules {
+		rule, err := spec.build()
+		if err != nil {
+			return nil, fmt.Errorf("load policy: rule %d: %w: %v", i+1, aicred.ErrInvalidOption, err)
+		}
+		p.Rules = append(p.Rules, rule)
+	}
+	return p, nil
+}
+
+// LoadFile reads a YAML rule file from path and builds a policy with Load
+func LoadFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load policy: %w: %v", aicred.ErrIO, err)
+	}
+	return Load(data)
+}
+
+func (s ruleSpec) build() (Rule, error) {
+	severity := SeverityMedium
+	if s.Severity != "" {
+		sev, err := ParseSeverity(s.Severity)
+		if err != nil {
+			return nil, err
+		}
+		severity = sev
+	}
+
+	var used []string
+	if s.Providers != nil {
+		used = append(used, "providers")
+	}
+	if s.Minimum != "" {
+		used = append(used, "minimum")
+	}
+	if s.Keys != nil {
+		used = append(used, "keys")
+	}
+	if s.Labels != nil {
+		used = append(used, "labels")
+	}
+	if s.MaxAge != "" {
+		used = append(used, "max_age")
+	}
+	allow := func(option string) error {
+		for _, u := range used {
+			if u != option {
+				return fmt.Errorf("%s does not take %s", s.Rule, u)
+			}
+		}
+		return nil
+	}
+
+	switch s.Rule {
+	case "no-full-values":
+		if err := allow(""); err != nil {
+			return nil, err
+		}
+		return NoFullValues(severity), nil
+	case "forbidden-provider":
+		if err := allow("providers"); err != nil {
+			return nil, err
+		}
+		if len(s.Providers) == 0 {
+			return nil, fmt.Errorf("%s needs providers", s.Rule)
+		}
+		return ForbiddenProviders(severity, s.Providers...), nil
+	case "min-confidence":
+		if err := allow("minimum"); err != nil {
+			return nil, err
+		}
+		if _, ok := confidenceRank(s.Minimum); !ok {
+			return nil, fmt.Errorf("%s needs minimum to be low, medium, high or very high, not %q", s.Rule, s.Minimum)
+		}
+		return MinConfidence(severity, s.Minimum), nil
+	case "require-instance-metadata":
+		if err := allow("keys"); err != nil {
+			return nil, err
+		}
+		if len(s.Keys) == 0 {
+			return nil, fmt.Errorf("%s needs keys", s.Rule)
+		}
+		return RequireInstanceMetadata(severity, s.Keys...), nil
+	case "require-label":
+		if err := allow("labels"); err != nil {
+			return nil, err
+		}
+		if len(s.Labels) == 0 {
+			return nil, fmt.Errorf("%s needs labels", s.Rule)
+		}
+		return RequireLabel(severity, s.Labels...), nil
+	case "max-key-age":
+		if err := allow("max_age"); err != nil {
+			return nil, err
+		}
+		maxAge, err := parseAge(s.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.Rule, err)
+		}
+		return MaxKeyAge(severity, maxAge), nil
+	case "":
+		return nil, errors.New("rule name is required")
+	default:
+		return nil, fmt.Errorf("unknown rule %q", s.Rule)
+	}
+}
+
+// parseAge accepts a whole number of days such as "90d" or a Go duration
+func parseAge(s string) (time.Duration, error) {
+	var age time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		age, err = time.ParseDuration(s)
+	}
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("max_age must be a positive number of days such as 90d or a duration, not %q", s)
+	}
+	return age, nil
+}
@@ -0,0 +1,186 @@
+// Package policy evaluates credential hygiene rules against scan results and
+// aicred configurations. Rules are built with the Go API or loaded from YAML
+// with Load.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// Severity ranks how serious a violation is
+type Severity int
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// String returns the lowercase name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the severity by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name
+func (s *Severity) UnmarshalText(text []byte) error {
+	sev, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = sev
+	return nil
+}
+
+// ParseSeverity converts a severity name into a Severity
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "low":
+		return SeverityLow, nil
+	case "medium":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	case "critical":
+		return SeverityCritical, nil
+	default:
+		return 0, fmt.Errorf("unknown severity: %q", name)
+	}
+}
+
+// Violation describes a single rule failure
+type Violation struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Provider string   `json:"provider,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	// InstanceID is the configured instance at fault, for Config rules
+	InstanceID string `json:"instance_id,omitempty"`
+}
+
+// Rule is a single hygiene check evaluated against a scan result. A rule
+// that only applies to a configuration returns nothing from Evaluate.
+type Rule interface {
+	Name() string
+	Evaluate(result *aicred.ScanResult) []Violation
+}
+
+// ConfigRule is a Rule that also checks an aicred configuration: its
+// provider instances, tags and label assignments
+type ConfigRule interface {
+	Rule
+	EvaluateConfig(config *aicred.Config) []Violation
+}
+
+// Policy is an ordered set of rules
+type Policy struct {
+	Rules []Rule
+}
+
+// New creates a policy from the given rules
+func New(rules ...Rule) *Policy {
+	return &Policy{Rules: rules}
+}
+
+// Evaluate runs every rule against the result and returns all violations
+func (p *Policy) Evaluate(result *aicred.ScanResult) Violations {
+	if result == nil {
+		return nil
+	}
+	var violations Violations
+	for _, rule := range p.Rules {
+		violations = append(violations, rule.Evaluate(result)...)
+	}
+	return violations
+}
+
+// EvaluateConfig runs every ConfigRule against config and returns all
+// violations. Rules that only check scan results are skipped.
+func (p *Policy) EvaluateConfig(config *aicred.Config) Violations {
+	if config == nil {
+		return nil
+	}
+	var violations Violations
+	for _, rule := range p.Rules {
+		if rule, ok := rule.(ConfigRule); ok {
+			violations = append(violations, rule.EvaluateConfig(config)...)
+		}
+	}
+	return violations
+}
+
+// Violations is a list of rule failures
+type Violations []Violation
+
+// MaxSeverity returns the highest severity present, and false if there are no violations
+func (v Violations) MaxSeverity() (Severity, bool) {
+	if len(v) == 0 {
+		return 0, false
+	}
+	max := v[0].Severity
+	for _, violation := range v[1:] {
+		if violation.Severity > max {
+			max = violation.Severity
+		}
+	}
+	return max, true
+}
+
+// AtLeast returns the violations whose severity is at or above the threshold
+func (v Violations) AtLeast(threshold Severity) Violations {
+	var filtered Violations
+	for _, violation := range v {
+		if violation.Severity >= threshold {
+			filtered = append(filtered, violation)
+		}
+	}
+	return filtered
+}
+
+// Fails reports whether any violation meets the threshold, for use as a CI gate
+func (v Violations) Fails(threshold Severity) bool {
+	return len(v.AtLeast(threshold)) > 0
+}
+
+// allKeys returns the top-level keys followed by any per-instance keys not already seen
+func allKeys(result *aicred.ScanResult) []aicred.DiscoveredKey {
+	seen := make(map[string]bool)
+	var keys []aicred.DiscoveredKey
+	add := func(key aicred.DiscoveredKey) {
+		id := key.Hash + "\x00" + key.Source
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		keys = append(keys, key)
+	}
+	for _, key := range result.Keys {
+		add(key)
+	}
+	for _, instance := range result.ConfigInstances {
+		for _, key := range instance.Keys {
+			add(key)
+		}
+	}
+	return keys
+}
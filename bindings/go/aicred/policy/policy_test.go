@@ -0,0 +1,215 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func testResult() *aicred.ScanResult {
+	return &aicred.ScanResult{
+		Keys: []aicred.DiscoveredKey{
+			{Provider: "openai", Source: "/home/u/.env", Confidence: "VeryHigh", Hash: "a1", Value: aicred.NewSecretString("sk-full")},
+			{Provider: "groq", Source: "/home/u/.config/x.json", Confidence: "Low", Hash: "b2"},
+		},
+		ConfigInstances: []aicred.ConfigInstance{
+			{
+				InstanceID: "roo-1",
+				AppName:    "roo-code",
+				ConfigPath: "/home/u/.roo",
+				Keys: []aicred.DiscoveredKey{
+					{Provider: "openai", Source: "/home/u/.env", Confidence: "VeryHigh", Hash: "a1", Value: aicred.NewSecretString("sk-full")},
+				},
+				Metadata: map[string]string{"version": "1"},
+			},
+		},
+	}
+}
+
+func TestNoFullValues(t *testing.T) {
+	violations := New(NoFullValues(SeverityCritical)).Evaluate(testResult())
+	if len(violations) != 1 {
+		t.Fatalf("expected 1 violation (duplicates collapsed), got %d", len(violations))
+	}
+	if violations[0].Provider != "openai" || violations[0].Severity != SeverityCritical {
+		t.Errorf("unexpected violation: %+v", violations[0])
+	}
+}
+
+func TestForbiddenProviders(t *testing.T) {
+	violations := New(ForbiddenProviders(SeverityHigh, "GROQ")).Evaluate(testResult())
+	if len(violations) != 1 || violations[0].Provider != "groq" {
+		t.Fatalf("expected groq violation, got %+v", violations)
+	}
+}
+
+func TestMinConfidence(t *testing.T) {
+	violations := New(MinConfidence(SeverityLow, "Medium")).Evaluate(testResult())
+	if len(violations) != 1 || violations[0].Provider != "groq" {
+		t.Fatalf("expected low-confidence groq violation, got %+v", violations)
+	}
+}
+
+func TestRequireInstanceMetadata(t *testing.T) {
+	violations := New(RequireInstanceMetadata(SeverityMedium, "version", "env")).Evaluate(testResult())
+	if len(violations) != 1 || violations[0].Source != "/home/u/.roo" {
+		t.Fatalf("expected missing env metadata violation, got %+v", violations)
+	}
+}
+
+func TestViolationsThreshold(t *testing.T) {
+	p := New(
+		NoFullValues(SeverityCritical),
+		ForbiddenProviders(SeverityLow, "groq"),
+	)
+	violations := p.Evaluate(testResult())
+
+	max, ok := violations.MaxSeverity()
+	if !ok || max != SeverityCritical {
+		t.Errorf("expected critical max severity, got %v", max)
+	}
+	if !violations.Fails(SeverityHigh) {
+		t.Error("expected policy to fail at high threshold")
+	}
+	if n := len(violations.AtLeast(SeverityMedium)); n != 1 {
+		t.Errorf("expected 1 violation at medium or above, got %d", n)
+	}
+	if Violations(nil).Fails(SeverityLow) {
+		t.Error("empty violations should not fail")
+	}
+}
+
+func TestSeverityJSON(t *testing.T) {
+	data, err := json.Marshal(Violation{Rule: "r", Severity: SeverityHigh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v Violation
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Severity != SeverityHigh {
+		t.Errorf("expected high after round-trip, got %v", v.Severity)
+	}
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func testConfig() *aicred.Config {
+	created := time.Now().Add(-120 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	return &aicred.Config{
+		Instances: []aicred.ProviderInstance{
+			{ID: "openai-main", ProviderType: "openai", APIKey: aicred.NewSecretString("sk-a"), Metadata: map[string]string{aicred.MetadataKeyCreatedAt: created}},
+			{ID: "groq-dev", ProviderType: "groq", APIKey: aicred.NewSecretString("gsk-b")},
+		},
+		Labels: []aicred.LabelAssignment{
+			{LabelName: "env:prod", Target: aicred.LabelTarget{Type: aicred.LabelTargetModel, InstanceID: "openai-main", ModelID: "gpt-4o"}},
+		},
+	}
+}
+
+func TestEvaluateConfig(t *testing.T) {
+	p := New(
+		NoFullValues(SeverityCritical),
+		ForbiddenProviders(SeverityHigh, "groq"),
+		RequireLabel(SeverityMedium, "env:*"),
+		MaxKeyAge(SeverityHigh, 90*24*time.Hour),
+	)
+	violations := p.EvaluateConfig(testConfig())
+	got := map[string]string{}
+	for _, v := range violations {
+		got[v.Rule] = v.InstanceID
+	}
+	want := map[string]string{"forbidden-provider": "groq-dev", "require-label": "groq-dev", "max-key-age": "openai-main"}
+	if len(violations) != len(want) {
+		t.Fatalf("violations = %+v", violations)
+	}
+	for rule, id := range want {
+		if got[rule] != id {
+			t.Errorf("%s flagged %q, want %q", rule, got[rule], id)
+		}
+	}
+	if New(MaxKeyAge(SeverityHigh, 200*24*time.Hour)).EvaluateConfig(testConfig()) != nil {
+		t.Error("a key younger than the limit should pass")
+	}
+}
+
+func TestMaxKeyAgeScan(t *testing.T) {
+	dir := t.TempDir()
+	old, fresh := filepath.Join(dir, "old.env"), filepath.Join(dir, "fresh.env")
+	for _, path := range []string{old, fresh} {
+		if err := os.WriteFile(path, []byte("KEY=x\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := time.Now().Add(-100 * 24 * time.Hour)
+	if err := os.Chtimes(old, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	result := &aicred.ScanResult{Keys: []aicred.DiscoveredKey{
+		{Provider: "openai", Source: old, Hash: "a"},
+		{Provider: "openai", Source: fresh, Hash: "b"},
+		{Provider: "anthropic", Source: "environment", Hash: "c"},
+	}}
+	violations := New(MaxKeyAge(SeverityHigh, 90*24*time.Hour)).Evaluate(result)
+	if len(violations) != 1 || violations[0].Source != old {
+		t.Fatalf("violations = %+v, want only %s", violations, old)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	p, err := Load([]byte(`rules:
+  - rule: no-full-values
+    severity: critical
+  - rule: forbidden-provider
+    severity: high
+    providers: [groq]
+  - rule: min-confidence
+    minimum: Medium
+  - rule: require-instance-metadata
+    keys: [env]
+  - rule: require-label
+    labels: ["env:*"]
+  - rule: max-key-age
+    max_age: 90d
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, rule := range p.Rules {
+		names = append(names, rule.Name())
+	}
+	if len(names) != 6 || names[5] != "max-key-age" {
+		t.Fatalf("rules = %v", names)
+	}
+	if n := len(p.EvaluateConfig(testConfig())); n != 3 {
+		t.Errorf("got %d config violations, want 3", n)
+	}
+	if !p.Evaluate(testResult()).Fails(SeverityCritical) {
+		t.Error("loaded policy should flag the full value")
+	}
+
+	for _, tc := range []struct {
+		yaml string
+		err  error
+	}{
+		{"rules: [", aicred.ErrParse},
+		{"rules: [{rule: unheard-of}]", aicred.ErrInvalidOption},
+		{"rules: [{rule: no-full-values, severity: urgent}]", aicred.ErrInvalidOption},
+		{"rules: [{rule: no-full-values, providers: [groq]}]", aicred.ErrInvalidOption},
+		{"rules: [{rule: max-key-age, max_age: soon}]", aicred.ErrInvalidOption},
+		{"rules: [{rule: forbidden-provider}]", aicred.ErrInvalidOption},
+		{"rules: [{rule: no-full-values, sevrity: high}]", aicred.ErrInvalidOption},
+	} {
+		if _, err := Load([]byte(tc.yaml)); !errors.Is(err, tc.err) {
+			t.Errorf("Load(%q) = %v, want %v", tc.yaml, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// NoFullValues flags any key whose full secret value is present in the result
+func NoFullValues(severity Severity) Rule {
+	return &noFullValues{severity: severity}
+}
+
+type noFullValues struct {
+	severity Severity
+}
+
+func (r *noFullValues) Name() string { return "no-full-values" }
+
+func (r *noFullValues) Evaluate(result *aicred.ScanResult) []Violation {
+	var violations []Violation
+	for _, key := range allKeys(result) {
+		if key.Value.IsZero() {
+			continue
+		}
+		violations = append(violations, Violation{
+			Rule:     r.Name(),
+			Severity: r.severity,
+			Message:  "scan output contains a full key value",
+			Provider: key.Provider,
+			Source:   key.Source,
+		})
+	}
+	return violations
+}
+
+// ForbiddenProviders flags keys belonging to any of the given providers and,
+// in a Config, instances of those provider types
+func ForbiddenProviders(severity Severity, providers ...string) Rule {
+	forbidden := make(map[string]bool, len(providers))
+	for _, p := range providers {
+		forbidden[strings.ToLower(p)] = true
+	}
+	return &forbiddenProviders{severity: severity, forbidden: forbidden}
+}
+
+type forbiddenProviders struct {
+	severity  Severity
+	forbidden map[string]bool
+}
+
+func (r *forbiddenProviders) Name() string { return "forbidden-provider" }
+
+func (r *forbiddenProviders) Evaluate(result *aicred.ScanResult) []Violation {
+	var violations []Violation
+	for _, key := range allKeys(result) {
+		if !r.forbidden[strings.ToLower(key.Provider)] {
+			continue
+		}
+		violations = append(violations, Violation{
+			Rule:     r.Name(),
+			Severity: r.severity,
+			Message:  fmt.Sprintf("provider %q is not allowed", key.Provider),
+			Provider: key.Provider,
+			Source:   key.Source,
+		})
+	}
+	return violations
+}
+
+func (r *forbiddenProviders) EvaluateConfig(config *aicred.Config) []Violation {
+	var violations []Violation
+	for _, instance := range config.Instances {
+		if !r.forbidden[strings.ToLower(instance.ProviderType)] {
+			continue
+		}
+		violations = append(violations, Violation{
+			Rule:       r.Name(),
+			Severity:   r.severity,
+			Message:    fmt.Sprintf("instance %s uses provider %q, which is not allowed", instance.ID, instance.ProviderType),
+			Provider:   instance.ProviderType,
+			InstanceID: instance.ID,
+		})
+	}
+	return violations
+}
+
+// MinConfidence flags keys reported below the given confidence level,
+// which usually indicates a stale or malformed credential worth reviewing
+func MinConfidence(severity Severity, minimum string) Rule {
+	return &minConfidence{severity: severity, minimum: minimum}
+}
+
+type minConfidence struct {
+	severity Severity
+	minimum  string
+}
+
+func (r *minConfidence) Name() string { return "min-confidence" }
+
+func (r *minConfidence) Evaluate(result *aicred.ScanResult) []Violation {
+	threshold, ok := confidenceRank(r.minimum)
+	if !ok {
+		return nil
+	}
+	var violations []Violation
+	for _, key := range allKeys(result) {
+		rank, ok := confidenceRank(key.Confidence)
+		if !ok || rank >= threshold {
+			continue
+		}
+		violations = append(violations, Violation{
+			Rule:     r.Name(),
+			Severity: r.severity,
+			Message:  fmt.Sprintf("key confidence %s is below %s", key.Confidence, r.minimum),
+			Provider: key.Provider,
+			Source:   key.Source,
+		})
+	}
+	return violations
+}
+
+// RequireInstanceMetadata flags config instances missing any of the given metadata keys
+func RequireInstanceMetadata(severity Severity, keys ...string) Rule {
+	return &requireInstanceMetadata{severity: severity, keys: keys}
+}
+
+type requireInstanceMetadata struct {
+	severity Severity
+	keys     []string
+}
+
+func (r *requireInstanceMetadata) Name() string { return "require-instance-metadata" }
+
+func (r *requireInstanceMetadata) Evaluate(result *aicred.ScanResult) []Violation {
+	var violations []Violation
+	for _, instance := range result.ConfigInstances {
+		for _, key := range r.keys {
+			if _, ok := instance.Metadata[key]; ok {
+				continue
+			}
+			violations = append(violations, Violation{
+				Rule:     r.Name(),
+				Severity: r.severity,
+				Message:  fmt.Sprintf("%s instance %s has no %q metadata", instance.AppName, instance.InstanceID, key),
+				Source:   instance.ConfigPath,
+			})
+		}
+	}
+	return violations
+}
+
+// RequireLabel flags configured instances that carry none of the given
+// labels, on the instance or on one of its models. Labels may use *
+// wildcards, so RequireLabel(SeverityMedium, "env:*") asks every instance
+// for an env label. It checks a Config only.
+func RequireLabel(severity Severity, labels ...string) Rule {
+	return &requireLabel{severity: severity, labels: labels}
+}
+
+type requireLabel struct {
+	severity Severity
+	labels   []string
+}
+
+func (r *requireLabel) Name() string { return "require-label" }
+
+func (r *requireLabel) Evaluate(*aicred.ScanResult) []Violation { return nil }
+
+func (r *requireLabel) EvaluateConfig(config *aicred.Config) []Violation {
+	labeled := map[string]bool{}
+	for _, a := range config.Labels {
+		for _, pattern := range r.labels {
+			if ok, _ := path.Match(pattern, a.LabelName); ok {
+				labeled[a.Target.InstanceID] = true
+			}
+		}
+	}
+	var violations []Violation
+	for _, instance := range config.Instances {
+		if labeled[instance.ID] {
+			continue
+		}
+		violations = append(violations, Violation{
+			Rule:       r.Name(),
+			Severity:   r.severity,
+			Message:    fmt.Sprintf("instance %s has none of the labels %s", instance.ID, strings.Join(r.labels, ", ")),
+			Provider:   instance.ProviderType,
+			InstanceID: instance.ID,
+		})
+	}
+	return violations
+}
+
+// MaxKeyAge flags keys older than maxAge. In a scan result a key's age is
+// how long its source file has gone unmodified; keys from sources that are
+// not files, such as the environment, are not checked. In a Config it is
+// the age given by the instance's aicred.MetadataKeyCreatedAt; instances
+// without a key or without that metadata are not checked.
+func MaxKeyAge(severity Severity, maxAge time.Duration) Rule {
+	return &maxKeyAge{severity: severity, maxAge: maxAge, now: time.Now}
+}
+
+type maxKeyAge struct {
+	severity Severity
+	maxAge   time.Duration
+	now      func() time.Time
+}
+
+func (r *maxKeyAge) Name() string { return "max-key-age" }
+
+func (r *maxKeyAge) Evaluate(result *aicred.ScanResult) []Violation {
+	var violations []Violation
+	var checked []string
+	for _, key := range allKeys(result) {
+		info, err := os.Stat(key.Source)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		age := r.now().Sub(info.ModTime())
+		if age <= r.maxAge || slices.Contains(checked, key.Hash+"\x00"+key.Source) {
+			continue
+		}
+		checked = append(checked, key.Hash+"\x00"+key.Source)
+		violations = append(violations, Violation{
+			Rule:     r.Name(),
+			Severity: r.severity,
+			Message:  fmt.Sprintf("key has not changed in %s, longer than %s", formatAge(age), formatAge(r.maxAge)),
+			Provider: key.Provider,
+			Source:   key.Source,
+		})
+	}
+	return violations
+}
+
+func (r *maxKeyAge) EvaluateConfig(config *aicred.Config) []Violation {
+	var violations []Violation
+	for _, instance := range config.Instances {
+		if instance.APIKey.IsZero() {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, instance.Metadata[aicred.MetadataKeyCreatedAt])
+		if err != nil {
+			continue
+		}
+		if age := r.now().Sub(created); age > r.maxAge {
+			violations = append(violations, Violation{
+				Rule:       r.Name(),
+				Severity:   r.severity,
+				Message:    fmt.Sprintf("instance %s has a key %s old, older than %s", instance.ID, formatAge(age), formatAge(r.maxAge)),
+				Provider:   instance.ProviderType,
+				InstanceID: instance.ID,
+			})
+		}
+	}
+	return violations
+}
+
+// formatAge writes whole days, which is how key ages are set
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	}
+	return d.Round(time.Minute).String()
+}
+
+// confidenceRank orders the confidence names emitted by the core library
+func confidenceRank(confidence string) (int, bool) {
+	switch strings.ToLower(strings.ReplaceAll(confidence, " ", "")) {
+	case "low":
+		return 0, true
+	case "medium":
+		return 1, true
+	case "high":
+		return 2, true
+	case "veryhigh":
+		return 3, true
+	default:
+		return 0, false
+	}
+}
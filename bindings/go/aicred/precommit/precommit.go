@@ -0,0 +1,115 @@
+// Package precommit checks what is about to be committed for provider keys,
+// for use as a git pre-commit hook.
+//
+// Only the lines a commit adds are scanned, read from a single
+// `git diff --cached` and run through the Go detector, so the check stays
+// well under the 200ms a hook can spend without getting in the way. The
+// aicred-precommit command wraps CheckStagedFiles for the pre-commit
+// framework and for plain .git/hooks/pre-commit scripts.
+package precommit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// addedFile is the lines a staged change adds to one file
+type addedFile struct {
+	path    string
+	content bytes.Buffer
+	// lines maps each line of content to its line number in the file
+	lines []int
+}
+
+// CheckStagedFiles returns the keys in the lines staged for commit in the
+// repository at repoPath. Each key's Source and Location.Path are relative
+// to the repository root, and Location.Line is its line in the staged file.
+// Deleted lines, deleted files and binary files are not scanned. Key values
+// are redacted as by a default Scan.
+func CheckStagedFiles(repoPath string) ([]aicred.DiscoveredKey, error) {
+	cmd := exec.Command("git", "-C", repoPath, "-c", "core.quotePath=false",
+		"diff", "--cached", "--no-color", "--no-ext-diff", "--no-prefix",
+		"--unified=0", "--diff-filter=ACMR")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git diff --cached: %w: %s", aicred.ErrIO, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("git diff --cached: %w: %v", aicred.ErrIO, err)
+	}
+
+	var keys []aicred.DiscoveredKey
+	for _, file := range parseAdded(out) {
+		found, err := aicred.ScanContent(file.path, &file.content, aicred.ScanOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range found {
+			if key.Location != nil && key.Location.Line > 0 && key.Location.Line <= len(file.lines) {
+				key.Location.Line = file.lines[key.Location.Line-1]
+				key.Location.Offset = 0
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// parseAdded collects the added lines of each file in a zero-context,
+// prefix-free unified diff
+func parseAdded(diff []byte) []*addedFile {
+	var (
+		files   []*addedFile
+		current *addedFile
+		next    int
+		// inHunk is set between a hunk header and the next file, where a
+		// line starting "+++ " is an added line rather than a header
+		inHunk bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current, inHunk = nil, false
+		case strings.HasPrefix(line, "+++ ") && !inHunk:
+			// git ends the name with a tab when it contains spaces
+			path := strings.TrimSuffix(strings.TrimPrefix(line, "+++ "), "\t")
+			if path == "/dev/null" {
+				current = nil
+				continue
+			}
+			current = &addedFile{path: path}
+			files = append(files, current)
+		case strings.HasPrefix(line, "@@ "):
+			next, inHunk = hunkStart(line), true
+		case strings.HasPrefix(line, "+") && current != nil:
+			current.content.WriteString(line[1:])
+			current.content.WriteByte('\n')
+			current.lines = append(current.lines, next)
+			next++
+		}
+	}
+	return files
+}
+
+// hunkStart returns the first new-file line of a hunk header such as
+// "@@ -3,0 +4,2 @@"
+func hunkStart(header string) int {
+	_, rest, _ := strings.Cut(header, " +")
+	rest, _, _ = strings.Cut(rest, " ")
+	rest, _, _ = strings.Cut(rest, ",")
+	n, _ := strconv.Atoi(rest)
+	return n
+}
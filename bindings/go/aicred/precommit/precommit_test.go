@@ -0,0 +1,73 @@
+package precommit
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckStagedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git(t, repo, "init", "-q")
+	write(t, filepath.Join(repo, "config.env"), "# settings\nDEBUG=1\n")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "initial")
+
+	// Committed keys are not reported, only staged additions
+	write(t, filepath.Join(repo, "config.env"), "# settings\nDEBUG=1\nOPENAI_API_KEY=sk-proj-abcdefghijklmnopqrstuvwx\n")
+	write(t, filepath.Join(repo, "dir name", "notes.txt"), "++ not a header\ngsk_abcdefghijklmnopqrstuvwxyz\n")
+	write(t, filepath.Join(repo, "unstaged.env"), "OPENAI_API_KEY=sk-proj-zyxwvutsrqponmlkjihgfedc\n")
+	git(t, repo, "add", "config.env", "dir name")
+
+	keys, err := CheckStagedFiles(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("found %d keys, want 2: %+v", len(keys), keys)
+	}
+	if k := keys[0]; k.Provider != "openai" || k.Source != "config.env" || k.Location.Line != 3 || k.Location.Column != 16 || !k.Value.IsZero() {
+		t.Errorf("first key = %+v at %+v", k, k.Location)
+	}
+	if k := keys[1]; k.Provider != "groq" || k.Source != "dir name/notes.txt" || k.Location.Line != 2 {
+		t.Errorf("second key = %+v at %+v", k, k.Location)
+	}
+
+	if _, err := CheckStagedFiles(t.TempDir()); err == nil {
+		t.Error("CheckStagedFiles outside a repository succeeded")
+	}
+}
+
+func TestHunkStart(t *testing.T) {
+	for header, want := range map[string]int{
+		"@@ -3,0 +4,2 @@":        4,
+		"@@ -0,0 +1 @@":          1,
+		"@@ -10 +12,3 @@ func x": 12,
+	} {
+		if got := hunkStart(header); got != want {
+			t.Errorf("hunkStart(%q) = %d, want %d", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// DefaultProfile is the profile kept in the configuration directory
+// itself, active unless another has been switched to
+const DefaultProfile = "default"
+
+const (
+	// profilesDir holds one directory per profile other than the default
+	profilesDir = "profiles"
+	// activeProfileFile names the active profile. The FFI and the CLI read
+	// it too.
+	activeProfileFile = "active_profile"
+)
+
+// profileName matches the names a profile may have: a directory name that
+// is safe on every platform
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// activeProfile returns the profile named in base's pointer file, or
+// DefaultProfile if there is none or it names no valid profile
+func activeProfile(base string) string {
+	data, err := os.ReadFile(filepath.Join(base, activeProfileFile))
+	if err != nil {
+		return DefaultProfile
+	}
+	name := strings.TrimSpace(string(data))
+	if !profileName.MatchString(name) {
+		return DefaultProfile
+	}
+	return name
+}
+
+// ActiveProfile returns the name of the profile ConfigDir(home) is in
+func ActiveProfile(home string) string {
+	return activeProfile(baseConfigDir(home))
+}
+
+// ListProfiles returns the profiles of home: DefaultProfile, then the
+// others by name. It returns an error wrapping ErrIO if the profiles
+// cannot be listed.
+func ListProfiles(home string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(baseConfigDir(home), profilesDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("list profiles: %w: %v", ErrIO, err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && profileName.MatchString(e.Name()) && e.Name() != DefaultProfile {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// SwitchProfile makes name the active profile of home, creating it empty
+// if it does not exist. Every profile has its own instances, labels, tags
+// and organizations; the Load and Save methods of sessions over home use
+// the active one, after a Reload for sessions already open. A name that is
+// not a plain directory name gives an error wrapping ErrInvalidOption.
+func SwitchProfile(home, name string) error {
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("switch profile %q: not a valid profile name: %w", name, ErrInvalidOption)
+	}
+	base := baseConfigDir(home)
+	pointer := filepath.Join(base, activeProfileFile)
+	if name == DefaultProfile {
+		if err := os.Remove(pointer); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("switch profile %q: %w: %v", name, ErrIO, err)
+		}
+		return nil
+	}
+	if err := hardenConfigDir(filepath.Join(base, profilesDir, name)); err != nil {
+		return fmt.Errorf("switch profile %q: %w: %v", name, ErrIO, err)
+	}
+	if err := writeBytesAtomic(pointer, []byte(name+"\n")); err != nil {
+		return fmt.Errorf("switch profile %q: %w: %v", name, ErrIO, err)
+	}
+	return nil
+}
+
+// SwitchProfile makes name the active profile of the session's home
+// directory, as the package-level SwitchProfile does, then reloads the
+// session and publishes StoreChanged events for the profile's contents
+func (s *Session) SwitchProfile(name string) error {
+	if err := SwitchProfile(s.homeDir, name); err != nil {
+		return err
+	}
+	if err := s.Reload(); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	s.storesChanged(now)
+	if tags, err := s.LoadTags(); err == nil {
+		s.events.Publish(events.StoreChanged{Time: now, Store: "tags", Count: len(tags)})
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+func TestProfiles(t *testing.T) {
+	home := t.TempDir()
+	base := filepath.Join(home, ".config", "aicred")
+	if got, err := ListProfiles(home); err != nil || !slices.Equal(got, []string{DefaultProfile}) {
+		t.Errorf("ListProfiles = %v, %v, want only the default", got, err)
+	}
+
+	for _, name := range []string{"work", "personal"} {
+		if err := SwitchProfile(home, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ActiveProfile(home) != "personal" || ConfigDir(home) != filepath.Join(base, "profiles", "personal") {
+		t.Errorf("after switching: profile %s in %s", ActiveProfile(home), ConfigDir(home))
+	}
+	if got, _ := ListProfiles(home); !slices.Equal(got, []string{DefaultProfile, "personal", "work"}) {
+		t.Errorf("ListProfiles = %v", got)
+	}
+
+	if err := SwitchProfile(home, DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if ActiveProfile(home) != DefaultProfile || ConfigDir(home) != base {
+		t.Errorf("back on the default: profile %s in %s", ActiveProfile(home), ConfigDir(home))
+	}
+
+	for _, name := range []string{"", "../escape", ".hidden", "a/b"} {
+		if err := SwitchProfile(home, name); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("SwitchProfile(%q) = %v, want ErrInvalidOption", name, err)
+		}
+	}
+	// A pointer edited by hand to something unsafe is ignored
+	if err := os.WriteFile(filepath.Join(base, activeProfileFile), []byte("../../etc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ConfigDir(home) != base {
+		t.Errorf("ConfigDir followed an unsafe pointer to %s", ConfigDir(home))
+	}
+}
+
+func TestSessionSwitchProfile(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	bus := events.New()
+	changed := map[string]int{}
+	defer events.Subscribe(bus, func(e events.StoreChanged) { changed[e.Store] = e.Count })()
+	s, err := OpenSessionWith(home, SessionOptions{Events: bus})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.SaveTags([]Tag{{Name: "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SwitchProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := s.LoadTags(); err != nil || len(tags) != 0 || changed["tags"] != 0 {
+		t.Errorf("the new profile holds tags %v, %v, events %v; want none", tags, err, changed)
+	}
+	if err := s.SaveInstance(ProviderInstance{ID: "work-openai", ProviderType: "openai", BaseURL: "https://api.openai.com/v1"}); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(home, ".config", "aicred")
+	if _, err := os.Stat(filepath.Join(base, "profiles", "work", "inference_services", "openai-work.yaml")); err != nil {
+		t.Errorf("instance not saved in the profile: %v", err)
+	}
+
+	if err := s.SwitchProfile(DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if tags, _ := s.LoadTags(); len(tags) != 1 || changed["tags"] != 1 {
+		t.Errorf("back on the default: tags %v, events %v", tags, changed)
+	}
+	if _, err := os.Stat(filepath.Join(base, "inference_services", "openai-work.yaml")); err == nil {
+		t.Error("the profile's instance leaked into the default")
+	}
+}
@@ -0,0 +1,220 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidInstance is wrapped by every problem ValidateProviderInstance
+// reports
+var ErrInvalidInstance = errors.New("aicred: invalid provider instance")
+
+// ProviderType describes a kind of provider endpoint for strict
+// validation
+type ProviderType struct {
+	Name string
+	// BaseURLs are the provider's own endpoints. In strict mode an
+	// instance's BaseURL must be on one of their hosts unless SelfHosted
+	// is set or the host is allowed by ValidationOptions.AllowedHosts. The
+	// first is the default.
+	BaseURLs []string
+	// Hosts are further hosts, or "*.example.com" domain wildcards, for
+	// providers such as Azure OpenAI that serve each customer on its own
+	// host
+	Hosts []string
+	// SelfHosted types, such as Ollama, may be served from any host
+	SelfHosted bool
+	// KeyPattern matches a well-formed API key; nil accepts any key
+	KeyPattern *regexp.Regexp
+	// KeyOptional types accept instances with no API key
+	KeyOptional bool
+}
+
+var (
+	providerTypesMu sync.RWMutex
+	providerTypes   = map[string]ProviderType{}
+)
+
+func init() {
+	for _, t := range []ProviderType{
+		{Name: "openai", BaseURLs: []string{"https://api.openai.com/v1"}, KeyPattern: regexp.MustCompile(`^sk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_\-]{20,}$`)},
+		{Name: "anthropic", BaseURLs: []string{"https://api.anthropic.com", "https://api.anthropic.ai", "https://claude-api.anthropic.com"}, KeyPattern: regexp.MustCompile(`^sk-ant-[A-Za-z0-9_\-]{20,}$`)},
+		{Name: "groq", BaseURLs: []string{"https://api.groq.com/openai/v1", "https://groq.com"}, KeyPattern: regexp.MustCompile(`^gsk[_-][A-Za-z0-9]{20,}$`)},
+		{Name: "huggingface", BaseURLs: []string{"https://huggingface.co", "https://api-inference.huggingface.co", "https://router.huggingface.co"}, KeyPattern: regexp.MustCompile(`^hf_[A-Za-z0-9]{20,}$`)},
+		{Name: "openrouter", BaseURLs: []string{"https://openrouter.ai/api/v1"}, KeyPattern: regexp.MustCompile(`^sk-or-[A-Za-z0-9_\-]{20,}$`)},
+		{Name: "ollama", BaseURLs: []string{"http://localhost:11434"}, SelfHosted: true, KeyOptional: true},
+		{Name: "litellm", BaseURLs: []string{"http://localhost:4000"}, SelfHosted: true, KeyOptional: true},
+	} {
+		providerTypes[t.Name] = t
+	}
+}
+
+// RegisterProviderType adds t to the provider types strict validation
+// accepts, replacing any type of the same name, so programs can allow
+// in-house gateways and providers this package does not know
+func RegisterProviderType(t ProviderType) error {
+	if t.Name == "" {
+		return fmt.Errorf("provider type has no name: %w", ErrInvalidOption)
+	}
+	for _, base := range t.BaseURLs {
+		if u, err := url.Parse(base); err != nil || u.Host == "" {
+			return fmt.Errorf("provider type %s: invalid base URL %q: %w", t.Name, base, ErrInvalidOption)
+		}
+	}
+	providerTypesMu.Lock()
+	defer providerTypesMu.Unlock()
+	providerTypes[t.Name] = t
+	return nil
+}
+
+// LookupProviderType returns the registered provider type with the given
+// name
+func LookupProviderType(name string) (ProviderType, bool) {
+	providerTypesMu.RLock()
+	defer providerTypesMu.RUnlock()
+	t, ok := providerTypes[name]
+	return t, ok
+}
+
+// ProviderTypes returns the registered provider types, sorted by name
+func ProviderTypes() []ProviderType {
+	providerTypesMu.RLock()
+	defer providerTypesMu.RUnlock()
+	types := make([]ProviderType, 0, len(providerTypes))
+	for _, t := range providerTypes {
+		types = append(types, t)
+	}
+	slices.SortFunc(types, func(a, b ProviderType) int { return strings.Compare(a.Name, b.Name) })
+	return types
+}
+
+// ValidationOptions configures ValidateProviderInstance
+type ValidationOptions struct {
+	// Strict also rejects unknown provider types, malformed keys and
+	// suspicious base URLs
+	Strict bool
+	// AllowedHosts are hosts, or "*.example.com" domain wildcards, that
+	// strict mode accepts for any provider type, such as a corporate
+	// gateway
+	AllowedHosts []string
+}
+
+// ValidateProviderInstance checks that instance has an ID, a provider type
+// and an http or https BaseURL, and that its RequestDefaults,
+// NetworkSettings and KeyDates are well-formed. In strict mode it also checks it against its registered ProviderType:
+// the type must be known, the API key must match KeyPattern, and the
+// BaseURL must be on one of the type's hosts or an allowed one; an Azure
+// OpenAI instance also needs an api_version and a deployment for each
+// model. Strict mode always rejects URLs carrying credentials, plain http
+// to hosts other than loopback, and insecure_skip_verify. Every problem found is reported, each
+// wrapping ErrInvalidInstance.
+func ValidateProviderInstance(instance ProviderInstance, options ValidationOptions) error {
+	var problems []error
+	report := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf("instance %q: %s: %w", instance.ID, fmt.Sprintf(format, args...), ErrInvalidInstance))
+	}
+
+	if strings.TrimSpace(instance.ID) == "" {
+		report("ID is empty")
+	}
+	if strings.TrimSpace(instance.ProviderType) == "" {
+		report("provider type is empty")
+	}
+	u, err := url.Parse(instance.BaseURL)
+	switch {
+	case instance.BaseURL == "":
+		report("base URL is empty")
+	case err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https":
+		report("base URL %q is not an http or https URL", instance.BaseURL)
+		u = nil
+	}
+	if _, err := RequestDefaultsOf(instance); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := KeyDatesOf(instance); err != nil {
+		problems = append(problems, err)
+	}
+	network, err := NetworkSettingsOf(instance)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	if !options.Strict {
+		return errors.Join(problems...)
+	}
+
+	t, known := LookupProviderType(instance.ProviderType)
+	if !known && instance.ProviderType != "" {
+		report("unknown provider type %q", instance.ProviderType)
+	}
+	if known {
+		switch {
+		case instance.APIKey.IsZero():
+			if !t.KeyOptional {
+				report("%s instance has no API key", t.Name)
+			}
+		case t.KeyPattern != nil:
+			var matches bool
+			instance.APIKey.Use(func(key []byte) { matches = t.KeyPattern.Match(key) })
+			if !matches {
+				report("API key is not a well-formed %s key", t.Name)
+			}
+		}
+	}
+	if instance.ProviderType == ProviderAzureOpenAI {
+		validateAzure(instance, report)
+	}
+	if network.InsecureSkipVerify {
+		report("%s disables TLS certificate verification", MetadataInsecureSkipVerify)
+	}
+	if u != nil {
+		host := strings.ToLower(u.Hostname())
+		if u.User != nil {
+			report("base URL carries credentials")
+		}
+		if u.Scheme == "http" && !isLoopbackHost(host) {
+			report("base URL sends the key unencrypted to %s", host)
+		}
+		if known && !t.SelfHosted && !t.ownsHost(host) && !hostAllowed(host, t.Hosts) && !hostAllowed(host, options.AllowedHosts) {
+			report("base URL host %s is not one of the %s endpoints", host, t.Name)
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// ownsHost reports whether host is the host of one of t's BaseURLs
+func (t ProviderType) ownsHost(host string) bool {
+	for _, base := range t.BaseURLs {
+		if u, err := url.Parse(base); err == nil && strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostAllowed(host string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
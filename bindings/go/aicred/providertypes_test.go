@@ -0,0 +1,94 @@
+package aicred
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateProviderInstance(t *testing.T) {
+	openai := ProviderInstance{
+		ID:           "openai-main",
+		ProviderType: "openai",
+		BaseURL:      "https://api.openai.com/v1",
+		APIKey:       NewSecretString("sk-proj-abcdefghijklmnopqrstuvwxyz"),
+	}
+	strict := ValidationOptions{Strict: true}
+	if err := ValidateProviderInstance(openai, strict); err != nil {
+		t.Fatalf("valid instance: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		edit    func(*ProviderInstance)
+		options ValidationOptions
+		want    string // "" means valid
+	}{
+		"lenient accepts unknown type": {func(i *ProviderInstance) { i.ProviderType = "acme" }, ValidationOptions{}, ""},
+		"empty ID":                     {func(i *ProviderInstance) { i.ID = "" }, ValidationOptions{}, "ID is empty"},
+		"not http":                     {func(i *ProviderInstance) { i.BaseURL = "ftp://api.openai.com" }, ValidationOptions{}, "not an http"},
+		"unknown type":                 {func(i *ProviderInstance) { i.ProviderType = "acme" }, strict, "unknown provider type"},
+		"malformed key":                {func(i *ProviderInstance) { i.APIKey = NewSecretString("pk-live-abcdefghijklmnopqrstuvwxyz") }, strict, "not a well-formed openai key"},
+		"missing key":                  {func(i *ProviderInstance) { i.APIKey = Secret{} }, strict, "no API key"},
+		"foreign host":                 {func(i *ProviderInstance) { i.BaseURL = "https://api.openai.com.evil.example/v1" }, strict, "not one of the openai endpoints"},
+		"allowed gateway": {
+			func(i *ProviderInstance) { i.BaseURL = "https://llm.corp.example/v1" },
+			ValidationOptions{Strict: true, AllowedHosts: []string{"*.corp.example"}}, "",
+		},
+		"credentials in URL": {func(i *ProviderInstance) { i.BaseURL = "https://user:pw@api.openai.com/v1" }, strict, "carries credentials"},
+		"plain http":         {func(i *ProviderInstance) { i.BaseURL = "http://api.openai.com/v1" }, strict, "unencrypted"},
+		"self-hosted ollama": {
+			func(i *ProviderInstance) {
+				i.ProviderType, i.BaseURL, i.APIKey = "ollama", "http://127.0.0.1:11434", Secret{}
+			}, strict, "",
+		},
+	} {
+		instance := openai
+		tc.edit(&instance)
+		err := ValidateProviderInstance(instance, tc.options)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", name, err)
+		case tc.want != "" && (!errors.Is(err, ErrInvalidInstance) || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: err = %v, want one mentioning %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestRegisterProviderType(t *testing.T) {
+	if err := RegisterProviderType(ProviderType{}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("unnamed type: err = %v", err)
+	}
+	acme := ProviderType{Name: "acme-test", BaseURLs: []string{"https://llm.acme.example"}, KeyPattern: regexp.MustCompile(`^acme_[a-z]{8}$`)}
+	if err := RegisterProviderType(acme); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		providerTypesMu.Lock()
+		delete(providerTypes, acme.Name)
+		providerTypesMu.Unlock()
+	})
+	instance := ProviderInstance{ID: "acme", ProviderType: "acme-test", BaseURL: "https://llm.acme.example/v2", APIKey: NewSecretString("acme_abcdefgh")}
+	if err := ValidateProviderInstance(instance, ValidationOptions{Strict: true}); err != nil {
+		t.Errorf("registered type: %v", err)
+	}
+	if _, ok := LookupProviderType("acme-test"); !ok {
+		t.Error("LookupProviderType did not find the registered type")
+	}
+}
+
+func FuzzValidateProviderInstance(f *testing.F) {
+	f.Add("openai", "https://api.openai.com/v1", "sk-proj-abcdefghijklmnopqrstuvwxyz")
+	f.Add("ollama", "http://[::1]:11434", "")
+	f.Add("acme", "https://user:pw@host:99999/%zz", "k")
+	f.Fuzz(func(t *testing.T, providerType, baseURL, key string) {
+		instance := ProviderInstance{ID: GenerateInstanceID(providerType, baseURL), ProviderType: providerType, BaseURL: baseURL, APIKey: NewSecretString(key)}
+		if instance.ID == "" || len(instance.ID) > maxSlugLen {
+			t.Fatalf("GenerateInstanceID = %q", instance.ID)
+		}
+		err := ValidateProviderInstance(instance, ValidationOptions{Strict: true, AllowedHosts: []string{"*.example.com"}})
+		if err != nil && !errors.Is(err, ErrInvalidInstance) {
+			t.Fatalf("error does not wrap ErrInvalidInstance: %v", err)
+		}
+	})
+}
@@ -0,0 +1,394 @@
+// Package proxy is an OpenAI-compatible reverse proxy that injects stored
+// credentials, so client applications never hold a provider key.
+//
+// Clients point an OpenAI SDK at the proxy, present the proxy's token as
+// their API key, and name a label or an instance instead of a model:
+//
+//	model="label:fast"             any instance/model labeled fast
+//	model="instance:openai-main/gpt-4o"
+//
+// The proxy resolves the model to an instance and model ID, rewrites the
+// request body's model, fills in the instance's request defaults, adds the
+// instance's credentials and forwards the request to the instance. Azure
+// OpenAI models go to their deployment. Responses, including server-sent
+// event streams, are relayed as they arrive. GET /v1/models lists the
+// names clients may use.
+package proxy
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// maxRequestBody bounds the size of a request body
+const maxRequestBody = 32 << 20
+
+// Model name prefixes clients address the proxy with
+const (
+	LabelPrefix    = "label:"
+	InstancePrefix = "instance:"
+)
+
+// versionSegment matches a base URL path that already ends in an API
+// version, such as /v1 or /openai/v1
+var versionSegment = regexp.MustCompile(`/v[0-9]+(?:alpha|beta)?[0-9]*$`)
+
+// hopHeaders are not forwarded in either direction
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Options configures a Proxy
+type Options struct {
+	// Token is the bearer token clients present as their API key. A
+	// proxy with no token rejects every request.
+	Token string
+	// HomeDir is the home directory whose aicred configuration is used
+	// when Store is nil
+	HomeDir string
+	// Store holds the instances and labels requests are resolved against.
+	// Nil means a Session over HomeDir, which Close closes.
+	Store aicred.Store
+	// Router picks among the instances a label resolves to, and is told
+	// each response's latency and rate limits. Nil means a Router with no
+	// routes over a LabelCache of Store.
+	Router *aicred.Router
+}
+
+// Proxy is an http.Handler forwarding OpenAI-compatible requests with
+// injected credentials. It is safe for concurrent use.
+type Proxy struct {
+	token  string
+	store  aicred.Store
+	router *aicred.Router
+	// session is the Session New opened, if any
+	session *aicred.Session
+
+	mu        sync.Mutex
+	upstreams map[string]*upstream
+}
+
+// upstream is what the proxy keeps per instance: its credentials and
+// client, rebuilt when the instance's key or metadata change
+type upstream struct {
+	apiKey   aicred.Secret
+	metadata map[string]string
+	source   aicred.CredentialSource
+	client   *http.Client
+}
+
+// New returns a Proxy. It opens a Session over opts.HomeDir when
+// opts.Store is nil.
+func New(opts Options) (*Proxy, error) {
+	p := &Proxy{token: opts.Token, store: opts.Store, router: opts.Router, upstreams: map[string]*upstream{}}
+	if p.store == nil {
+		s, err := aicred.OpenSession(opts.HomeDir)
+		if err != nil {
+			return nil, err
+		}
+		p.store, p.session = s, s
+	}
+	if p.router == nil {
+		p.router = aicred.NewRouter(aicred.NewLabelCache(p.store, aicred.LabelCacheOptions{}))
+	}
+	return p, nil
+}
+
+// Close closes the Session New opened, if any
+func (p *Proxy) Close() error {
+	if p.session == nil {
+		return nil
+	}
+	return p.session.Close()
+}
+
+// ServeHTTP serves GET /v1/models and forwards POST requests under /v1/
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "authentication_error", "missing or invalid proxy token")
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "unknown path "+r.URL.Path)
+		return
+	}
+	if r.URL.Path == "/v1/models" && r.Method == http.MethodGet {
+		p.listModels(w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "only POST requests with a JSON body naming a model are forwarded")
+		return
+	}
+	p.forward(w, r)
+}
+
+// authorized checks the client's bearer token in constant time. OpenAI
+// SDKs send it in Authorization; Azure-style clients send it in api-key.
+func (p *Proxy) authorized(r *http.Request) bool {
+	if p.token == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.Header.Get("api-key")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(p.token)) == 1
+}
+
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "request body is too large")
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var body map[string]any
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "request body is not a JSON object")
+		return
+	}
+	name, _ := body["model"].(string)
+	selection, status, err := p.resolve(name)
+	if err != nil {
+		writeError(w, status, "invalid_request_error", err.Error())
+		return
+	}
+	instance := selection.Instance
+
+	defaults, err := aicred.RequestDefaultsOf(instance)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	body["model"] = selection.Model
+	defaults.ApplyTo(body)
+	if data, err = json.Marshal(body); err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+
+	target, err := upstreamURL(selection, r.URL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	up, err := p.upstream(instance)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	out, err := http.NewRequestWithContext(r.Context(), http.MethodPost, target.String(), bytes.NewReader(data))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	copyHeader(out.Header, r.Header)
+	// the client's credentials are for the proxy, not the provider
+	for _, name := range []string{"Authorization", "Api-Key", "X-Api-Key", "Cookie", "Content-Length"} {
+		out.Header.Del(name)
+	}
+	for name, values := range defaults.Header() {
+		out.Header[name] = values
+	}
+	out.Header.Set("Content-Type", "application/json")
+	if err := up.source.Authorize(r.Context(), out); err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", fmt.Sprintf("instance %s: %v", instance.ID, err))
+		return
+	}
+
+	start := time.Now()
+	resp, err := up.client.Do(out)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", fmt.Sprintf("instance %s: %v", instance.ID, err))
+		return
+	}
+	defer resp.Body.Close()
+	p.router.ObserveLatency(instance.ID, time.Since(start))
+	p.router.ReportUsage(instance.ID, resp.Header)
+
+	copyHeader(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+	relay(w, resp.Body)
+}
+
+// resolve turns a request's model into an instance/model pair, with the
+// status to answer with if it cannot
+func (p *Proxy) resolve(name string) (aicred.Selection, int, error) {
+	var selection aicred.Selection
+	switch {
+	case strings.HasPrefix(name, LabelPrefix):
+		label := strings.TrimPrefix(name, LabelPrefix)
+		var err error
+		if selection, err = p.router.Pick(label); err != nil {
+			switch {
+			case errors.Is(err, aicred.ErrThrottled):
+				return selection, http.StatusTooManyRequests, fmt.Errorf("every instance labeled %q is rate limited", label)
+			case errors.Is(err, aicred.ErrNotFound):
+				return selection, http.StatusNotFound, fmt.Errorf("label %q applies to no model", label)
+			default:
+				return selection, http.StatusInternalServerError, err
+			}
+		}
+	case strings.HasPrefix(name, InstancePrefix):
+		id, model, ok := strings.Cut(strings.TrimPrefix(name, InstancePrefix), "/")
+		if !ok || id == "" || model == "" {
+			return selection, http.StatusBadRequest, fmt.Errorf("model %q is not instance:ID/MODEL", name)
+		}
+		instance, err := p.store.GetInstance(id)
+		if errors.Is(err, aicred.ErrNotFound) {
+			return selection, http.StatusNotFound, fmt.Errorf("no instance %q", id)
+		}
+		if err != nil {
+			return selection, http.StatusInternalServerError, err
+		}
+		selection = aicred.Selection{Instance: *instance, Model: model}
+	default:
+		return selection, http.StatusBadRequest, fmt.Errorf("model %q must be %sNAME or %sID/MODEL", name, LabelPrefix, InstancePrefix)
+	}
+	if selection.Model == "" {
+		return selection, http.StatusNotFound, fmt.Errorf("instance %s lists no models", selection.Instance.ID)
+	}
+	if !selection.Instance.Active {
+		return selection, http.StatusForbidden, fmt.Errorf("instance %s is inactive", selection.Instance.ID)
+	}
+	return selection, 0, nil
+}
+
+// upstream returns the credentials and client for instance, building them
+// again if the instance changed since they were built
+func (p *Proxy) upstream(instance aicred.ProviderInstance) (*upstream, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if up, ok := p.upstreams[instance.ID]; ok && up.apiKey.Equal(instance.APIKey) && maps.Equal(up.metadata, instance.Metadata) {
+		return up, nil
+	}
+	source, err := aicred.TokenSource(instance)
+	if err != nil {
+		return nil, err
+	}
+	client, err := aicred.HTTPClient(instance)
+	if err != nil {
+		return nil, err
+	}
+	up := &upstream{apiKey: instance.APIKey, metadata: maps.Clone(instance.Metadata), source: source, client: client}
+	p.upstreams[instance.ID] = up
+	return up, nil
+}
+
+// upstreamURL is where a request to the proxy at path is sent: the
+// selection's Resolve URL, with the path after /v1 joined on. /v1 is kept
+// for base URLs that do not already end in an API version, such as
+// Ollama's.
+func upstreamURL(selection aicred.Selection, in *url.URL) (*url.URL, error) {
+	base, err := selection.URL()
+	if err != nil {
+		return nil, err
+	}
+	rest := strings.TrimPrefix(in.Path, "/v1")
+	if selection.Instance.ProviderType != aicred.ProviderAzureOpenAI && !versionSegment.MatchString(strings.TrimSuffix(base.Path, "/")) {
+		rest = "/v1" + rest
+	}
+	target := base.JoinPath(rest)
+	query := target.Query()
+	for name, values := range in.Query() {
+		if !query.Has(name) {
+			query[name] = values
+		}
+	}
+	target.RawQuery = query.Encode()
+	return target, nil
+}
+
+// listModels answers GET /v1/models with every label and instance/model
+// pair clients may name
+func (p *Proxy) listModels(w http.ResponseWriter) {
+	instances, err := p.store.LoadInstances()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	labels, err := p.store.LoadLabels()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		OwnedBy string `json:"owned_by"`
+	}
+	var names []string
+	for _, a := range labels {
+		names = append(names, LabelPrefix+a.LabelName)
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+	for _, instance := range instances {
+		if !instance.Active {
+			continue
+		}
+		for _, m := range instance.Models {
+			names = append(names, InstancePrefix+instance.ID+"/"+m)
+		}
+	}
+	models := make([]model, 0, len(names))
+	for _, name := range names {
+		models = append(models, model{ID: name, Object: "model", OwnedBy: "aicred"})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"object": "list", "data": models})
+}
+
+// relay copies the upstream body to the client, flushing after each read
+// so streamed completions arrive as they are generated
+func relay(w http.ResponseWriter, body io.Reader) {
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// copyHeader adds src's end-to-end headers to dst
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
+		if slices.Contains(hopHeaders, http.CanonicalHeaderKey(name)) {
+			continue
+		}
+		dst[name] = slices.Clone(values)
+	}
+}
+
+// writeError answers in the OpenAI error format, so SDKs surface the
+// message
+func writeError(w http.ResponseWriter, status int, kind, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": message, "type": kind}})
+}
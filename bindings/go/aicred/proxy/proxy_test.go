@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// upstreamRequest is what the fake provider saw
+type upstreamRequest struct {
+	path   string
+	query  string
+	header http.Header
+	body   map[string]any
+}
+
+func fakeProvider(t *testing.T, got *upstreamRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path, got.query, got.header = r.URL.Path, r.URL.RawQuery, r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&got.body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "99")
+		for _, chunk := range []string{`data: {"delta":"he"}`, `data: {"delta":"llo"}`, "data: [DONE]"} {
+			io.WriteString(w, chunk+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testProxy(t *testing.T, baseURL string) *Proxy {
+	t.Helper()
+	openai := aicred.ProviderInstance{
+		ID: "openai-main", ProviderType: "openai", BaseURL: baseURL + "/v1", Active: true,
+		APIKey: aicred.NewSecretString("sk-upstream"), Models: []string{"gpt-4o"},
+		Metadata: map[string]string{aicred.MetadataTemperature: "0.2", "header.X-Team": "research"},
+	}
+	azure := aicred.ProviderInstance{
+		ID: "azure-acme", ProviderType: aicred.ProviderAzureOpenAI, BaseURL: baseURL, Active: true,
+		APIKey: aicred.NewSecretString("azure-key"), Metadata: map[string]string{aicred.MetadataAPIVersion: "2024-10-21"},
+	}
+	aicred.SetAzureDeployment(&azure, "gpt-4o", "prod-gpt4o")
+	ollama := aicred.ProviderInstance{ID: "ollama", ProviderType: "ollama", BaseURL: baseURL, Models: []string{"llama3"}}
+	labels := []aicred.LabelAssignment{{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetModel, InstanceID: "openai-main", ModelID: "gpt-4o"}}}
+	p, err := New(Options{Token: "local", Store: aicred.NewMemoryStore([]aicred.ProviderInstance{openai, azure, ollama}, labels)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func do(p *Proxy, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestForwardLabel(t *testing.T) {
+	var got upstreamRequest
+	p := testProxy(t, fakeProvider(t, &got).URL)
+
+	rec := do(p, http.MethodPost, "/v1/chat/completions", "local", `{"model":"label:fast","stream":true,"max_tokens":5}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got.path != "/v1/chat/completions" {
+		t.Errorf("upstream path = %s", got.path)
+	}
+	if got.header.Get("Authorization") != "Bearer sk-upstream" || got.header.Get("X-Team") != "research" {
+		t.Errorf("upstream headers = %v, want the stored key and default header", got.header)
+	}
+	if got.body["model"] != "gpt-4o" || got.body["temperature"] != 0.2 || got.body["max_tokens"] != 5.0 || got.body["stream"] != true {
+		t.Errorf("upstream body = %v, want the real model, the default temperature and the client's fields", got.body)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"llo"`) || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Errorf("client body = %q, want the relayed stream", body)
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("client headers = %v", rec.Header())
+	}
+}
+
+func TestForwardInstance(t *testing.T) {
+	var got upstreamRequest
+	p := testProxy(t, fakeProvider(t, &got).URL)
+
+	rec := do(p, http.MethodPost, "/v1/chat/completions", "local", `{"model":"instance:azure-acme/gpt-4o"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got.path != "/openai/deployments/prod-gpt4o/chat/completions" || got.query != "api-version=2024-10-21" {
+		t.Errorf("upstream URL = %s?%s, want the Azure deployment", got.path, got.query)
+	}
+	if got.header.Get("api-key") != "azure-key" || got.header.Get("Authorization") != "" {
+		t.Errorf("upstream headers = %v, want only the Azure api-key", got.header)
+	}
+}
+
+func TestForwardErrors(t *testing.T) {
+	var got upstreamRequest
+	p := testProxy(t, fakeProvider(t, &got).URL)
+
+	for _, tc := range []struct {
+		token, body string
+		status      int
+	}{
+		{"", `{"model":"label:fast"}`, http.StatusUnauthorized},
+		{"wrong", `{"model":"label:fast"}`, http.StatusUnauthorized},
+		{"local", `not json`, http.StatusBadRequest},
+		{"local", `{"model":"gpt-4o"}`, http.StatusBadRequest},
+		{"local", `{"model":"label:slow"}`, http.StatusNotFound},
+		{"local", `{"model":"instance:gone/gpt-4o"}`, http.StatusNotFound},
+		{"local", `{"model":"instance:ollama/llama3"}`, http.StatusForbidden},
+	} {
+		rec := do(p, http.MethodPost, "/v1/chat/completions", tc.token, tc.body)
+		if rec.Code != tc.status {
+			t.Errorf("%s with token %q: status %d, want %d", tc.body, tc.token, rec.Code, tc.status)
+		}
+		var e struct {
+			Error struct{ Message string } `json:"error"`
+		}
+		if json.Unmarshal(rec.Body.Bytes(), &e); e.Error.Message == "" {
+			t.Errorf("%s: body %q is not an OpenAI error", tc.body, rec.Body)
+		}
+	}
+	if got.path != "" {
+		t.Errorf("a rejected request reached the provider at %s", got.path)
+	}
+	if strings.Contains(do(p, http.MethodPost, "/v1/chat/completions", "local", `{"model":"label:slow"}`).Body.String(), "sk-upstream") {
+		t.Error("an error leaked the stored key")
+	}
+}
+
+func TestListModels(t *testing.T) {
+	p := testProxy(t, "http://127.0.0.1:1")
+	rec := do(p, http.MethodGet, "/v1/models", "local", "")
+	var list struct {
+		Data []struct{ ID string } `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range list.Data {
+		ids = append(ids, m.ID)
+	}
+	want := "label:fast instance:azure-acme/gpt-4o instance:openai-main/gpt-4o"
+	if strings.Join(ids, " ") != want {
+		t.Errorf("models = %v, want %s", ids, want)
+	}
+}
@@ -0,0 +1,143 @@
+package aicred
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultThrottle is how long an instance is skipped when it runs out of
+// requests or tokens without saying when they reset
+const defaultThrottle = time.Minute
+
+// RateLimit is the rate-limit state an instance last reported in its
+// response headers
+type RateLimit struct {
+	// RemainingRequests and RemainingTokens are what the provider allows
+	// until its window resets, or -1 when it did not say
+	RemainingRequests int
+	RemainingTokens   int
+	// Reset is when the exhausted limit resets, or the zero time when
+	// nothing is exhausted
+	Reset time.Time
+	// ReportedAt is when the headers were reported
+	ReportedAt time.Time
+}
+
+// Throttled reports whether the instance is out of requests or tokens at
+// now
+func (l RateLimit) Throttled(now time.Time) bool {
+	return !l.Reset.IsZero() && now.Before(l.Reset)
+}
+
+// Rate-limit headers of the OpenAI-compatible providers (OpenAI, Groq,
+// OpenRouter, Together and most proxies) and of Anthropic, most specific
+// first
+var (
+	remainingRequestHeaders = []string{"x-ratelimit-remaining-requests", "anthropic-ratelimit-requests-remaining", "x-ratelimit-remaining"}
+	remainingTokenHeaders   = []string{"x-ratelimit-remaining-tokens", "anthropic-ratelimit-tokens-remaining"}
+	resetRequestHeaders     = []string{"x-ratelimit-reset-requests", "anthropic-ratelimit-requests-reset", "x-ratelimit-reset"}
+	resetTokenHeaders       = []string{"x-ratelimit-reset-tokens", "anthropic-ratelimit-tokens-reset"}
+)
+
+// parseRateLimit reads the rate-limit headers of a response received at
+// now. An exhausted limit, or a Retry-After header, sets Reset.
+func parseRateLimit(headers http.Header, now time.Time) RateLimit {
+	l := RateLimit{
+		RemainingRequests: headerInt(headers, remainingRequestHeaders),
+		RemainingTokens:   headerInt(headers, remainingTokenHeaders),
+		ReportedAt:        now,
+	}
+	exhaust := func(names []string) {
+		reset, ok := headerTime(headers, names, now)
+		if !ok {
+			reset = now.Add(defaultThrottle)
+		}
+		if reset.After(l.Reset) {
+			l.Reset = reset
+		}
+	}
+	if l.RemainingRequests == 0 {
+		exhaust(resetRequestHeaders)
+	}
+	if l.RemainingTokens == 0 {
+		exhaust(resetTokenHeaders)
+	}
+	if headers.Get("Retry-After") != "" {
+		exhaust([]string{"Retry-After"})
+	}
+	return l
+}
+
+// headerInt returns the first of names present as an integer, or -1
+func headerInt(headers http.Header, names []string) int {
+	for _, name := range names {
+		if v := headers.Get(name); v != "" {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && n >= 0 {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+// headerTime returns the first of names present as a time. Values may be a
+// duration such as "6m0s" (OpenAI), an RFC 3339 time (Anthropic), an HTTP
+// date, a number of seconds, or a Unix time.
+func headerTime(headers http.Header, names []string, now time.Time) (time.Time, bool) {
+	for _, name := range names {
+		v := strings.TrimSpace(headers.Get(name))
+		if v == "" {
+			continue
+		}
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs >= 0 {
+			if secs > 1e9 {
+				return time.Unix(int64(secs), 0), true
+			}
+			return now.Add(time.Duration(secs * float64(time.Second))), true
+		}
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return now.Add(d), true
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ReportUsage records the rate-limit headers of a response from the
+// instance. While the instance is out of requests or tokens, or after a
+// Retry-After, Pick skips it until the limit resets. Callers and proxies
+// should report every response, so an instance is picked again as soon as
+// it has capacity.
+func (r *Router) ReportUsage(instanceID string, headers http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limits[instanceID] = parseRateLimit(headers, r.now())
+}
+
+// RateLimit returns what the instance last reported to ReportUsage
+func (r *Router) RateLimit(instanceID string) (RateLimit, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.limits[instanceID]
+	return l, ok
+}
+
+// available drops the selections whose instance is throttled. The caller
+// holds r.mu.
+func (r *Router) available(selections []Selection) []Selection {
+	now := r.now()
+	var open []Selection
+	for _, s := range selections {
+		if !r.limits[s.Instance.ID].Throttled(now) {
+			open = append(open, s)
+		}
+	}
+	return open
+}
@@ -0,0 +1,66 @@
+package aicred
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		reset   time.Time
+	}{
+		{"openai capacity", map[string]string{"x-ratelimit-remaining-requests": "59", "x-ratelimit-reset-requests": "1s"}, time.Time{}},
+		{"openai requests", map[string]string{"x-ratelimit-remaining-requests": "0", "x-ratelimit-reset-requests": "6m0s"}, now.Add(6 * time.Minute)},
+		{"openai tokens", map[string]string{"x-ratelimit-remaining-tokens": "0", "x-ratelimit-reset-tokens": "20ms"}, now.Add(20 * time.Millisecond)},
+		{"anthropic", map[string]string{"anthropic-ratelimit-requests-remaining": "0", "anthropic-ratelimit-requests-reset": "2026-05-01T12:00:30Z"}, now.Add(30 * time.Second)},
+		{"generic unix reset", map[string]string{"x-ratelimit-remaining": "0", "x-ratelimit-reset": "1777637100"}, time.Unix(1777637100, 0)},
+		{"no reset", map[string]string{"x-ratelimit-remaining": "0"}, now.Add(defaultThrottle)},
+		{"retry-after", map[string]string{"Retry-After": "120"}, now.Add(2 * time.Minute)},
+		{"retry-after date", map[string]string{"Retry-After": "Fri, 01 May 2026 12:05:00 GMT"}, now.Add(5 * time.Minute)},
+	} {
+		headers := http.Header{}
+		for k, v := range tc.headers {
+			headers.Set(k, v)
+		}
+		if got := parseRateLimit(headers, now); !got.Reset.Equal(tc.reset) {
+			t.Errorf("%s: Reset = %v, want %v", tc.name, got.Reset, tc.reset)
+		}
+	}
+}
+
+func TestRouterSkipsThrottled(t *testing.T) {
+	r := NewRouter(routingCache(t))
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	if err := r.SetRoute("fast", Route{Policy: RouteRoundRobin}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.ReportUsage("b", http.Header{"X-Ratelimit-Remaining-Requests": {"0"}, "X-Ratelimit-Reset-Requests": {"30s"}})
+	if got := picks(t, r, "fast", 4); got != "acac" {
+		t.Errorf("with b throttled: %s", got)
+	}
+	if l, ok := r.RateLimit("b"); !ok || l.RemainingRequests != 0 || l.RemainingTokens != -1 || !l.Throttled(now) {
+		t.Errorf("RateLimit(b) = %+v, %v", l, ok)
+	}
+
+	r.ReportUsage("a", http.Header{"Retry-After": {"10"}})
+	r.ReportUsage("c", http.Header{"Anthropic-Ratelimit-Tokens-Remaining": {"0"}})
+	if _, err := r.Pick("fast"); !errors.Is(err, ErrThrottled) {
+		t.Errorf("all throttled: err = %v", err)
+	}
+
+	now = now.Add(15 * time.Second)
+	if got := picks(t, r, "fast", 2); got != "aa" {
+		t.Errorf("after a's Retry-After: %s", got)
+	}
+	r.ReportUsage("c", http.Header{"Anthropic-Ratelimit-Tokens-Remaining": {"5000"}})
+	if got := picks(t, r, "fast", 2); got != "ac" && got != "ca" {
+		t.Errorf("after c reported capacity: %s", got)
+	}
+}
@@ -0,0 +1,127 @@
+package aicred
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RedactionLevel controls how much of each discovered key a consumer sees.
+// The zero value is RedactionPartial, the library's long-standing default.
+type RedactionLevel int
+
+const (
+	// RedactionPartial keeps the redacted preview and hash but drops full values
+	RedactionPartial RedactionLevel = iota
+	// RedactionNone keeps full values. Use only in secure environments.
+	RedactionNone
+	// RedactionHashOnly keeps the hash for correlation and drops the preview
+	RedactionHashOnly
+	// RedactionFull drops the value, preview and hash
+	RedactionFull
+)
+
+// String returns the name of the level
+func (l RedactionLevel) String() string {
+	switch l {
+	case RedactionPartial:
+		return "partial"
+	case RedactionNone:
+		return "none"
+	case RedactionHashOnly:
+		return "hash-only"
+	case RedactionFull:
+		return "full"
+	default:
+		return fmt.Sprintf("redaction(%d)", int(l))
+	}
+}
+
+// MarshalText encodes the level by name
+func (l RedactionLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a level name
+func (l *RedactionLevel) UnmarshalText(text []byte) error {
+	level, err := ParseRedactionLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// ParseRedactionLevel converts a level name into a RedactionLevel
+func ParseRedactionLevel(name string) (RedactionLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "partial":
+		return RedactionPartial, nil
+	case "none":
+		return RedactionNone, nil
+	case "hash-only", "hashonly", "hash_only":
+		return RedactionHashOnly, nil
+	case "full":
+		return RedactionFull, nil
+	default:
+		return 0, fmt.Errorf("unknown redaction level: %q", name)
+	}
+}
+
+// AtLeast reports whether l hides at least as much as other
+func (l RedactionLevel) AtLeast(other RedactionLevel) bool {
+	return l.rank() >= other.rank()
+}
+
+func (l RedactionLevel) rank() int {
+	switch l {
+	case RedactionNone:
+		return 0
+	case RedactionPartial:
+		return 1
+	case RedactionHashOnly:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Redact returns a copy of the result with keys reduced to the given level.
+// The copy shares no key slices with r.
+func (r *ScanResult) Redact(level RedactionLevel) *ScanResult {
+	out := *r
+	out.Keys = redactKeys(r.Keys, level)
+	if r.ConfigInstances != nil {
+		out.ConfigInstances = make([]ConfigInstance, len(r.ConfigInstances))
+		for i, inst := range r.ConfigInstances {
+			inst.Keys = redactKeys(inst.Keys, level)
+			out.ConfigInstances[i] = inst
+		}
+	}
+	return &out
+}
+
+// Redacted returns a copy of the result with every full key value removed.
+// It is shorthand for Redact(RedactionPartial).
+func (r *ScanResult) Redacted() *ScanResult {
+	return r.Redact(RedactionPartial)
+}
+
+func redactKeys(keys []DiscoveredKey, level RedactionLevel) []DiscoveredKey {
+	if keys == nil {
+		return nil
+	}
+	out := make([]DiscoveredKey, len(keys))
+	for i, key := range keys {
+		if level != RedactionNone {
+			key.Value = Secret{}
+		}
+		if level == RedactionHashOnly || level == RedactionFull {
+			key.Redacted = ""
+		}
+		if level == RedactionFull {
+			key.Hash = ""
+		}
+		out[i] = key
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package aicred
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseRedactionLevel(t *testing.T) {
+	for _, level := range []RedactionLevel{RedactionNone, RedactionPartial, RedactionHashOnly, RedactionFull} {
+		parsed, err := ParseRedactionLevel(level.String())
+		if err != nil || parsed != level {
+			t.Errorf("round trip of %s gave %v, %v", level, parsed, err)
+		}
+	}
+	if _, err := ParseRedactionLevel("most"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+
+	var opts struct {
+		Level RedactionLevel `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(`{"level":"hash-only"}`), &opts); err != nil || opts.Level != RedactionHashOnly {
+		t.Errorf("unmarshal gave %v, %v", opts.Level, err)
+	}
+}
+
+func TestRedactionLevelAtLeast(t *testing.T) {
+	if !RedactionFull.AtLeast(RedactionHashOnly) || !RedactionHashOnly.AtLeast(RedactionPartial) || !RedactionPartial.AtLeast(RedactionNone) {
+		t.Error("levels should be ordered none < partial < hash-only < full")
+	}
+	if RedactionNone.AtLeast(RedactionPartial) {
+		t.Error("none should be weaker than partial")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	result := &ScanResult{Keys: []DiscoveredKey{{Provider: "openai", Redacted: "sk-****abcd", Hash: "h1", Value: NewSecretString("sk-secret")}}}
+
+	tests := []struct {
+		level                            RedactionLevel
+		wantValue, wantPreview, wantHash bool
+	}{
+		{RedactionNone, true, true, true},
+		{RedactionPartial, false, true, true},
+		{RedactionHashOnly, false, false, true},
+		{RedactionFull, false, false, false},
+	}
+	for _, tt := range tests {
+		key := result.Redact(tt.level).Keys[0]
+		if !key.Value.IsZero() != tt.wantValue || (key.Redacted != "") != tt.wantPreview || (key.Hash != "") != tt.wantHash {
+			t.Errorf("%s: got %+v", tt.level, key)
+		}
+	}
+	if result.Keys[0].Redacted == "" || result.Keys[0].Value.IsZero() {
+		t.Error("Redact modified the original result")
+	}
+}
+
+func TestScanOptionsRedactionLevel(t *testing.T) {
+	if (ScanOptions{}).redactionLevel() != RedactionPartial {
+		t.Error("default should be partial")
+	}
+	if (ScanOptions{IncludeFullValues: true}).redactionLevel() != RedactionNone {
+		t.Error("IncludeFullValues should map to none")
+	}
+	if (ScanOptions{IncludeFullValues: true, Redaction: RedactionFull}).redactionLevel() != RedactionFull {
+		t.Error("an explicit level should win over IncludeFullValues")
+	}
+}
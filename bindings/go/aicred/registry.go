@@ -0,0 +1,97 @@
+package aicred
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// registryDepth bounds how far below each application key the registry
+// pass descends
+const registryDepth = 5
+
+// registryApps are the keys under HKEY_CURRENT_USER where desktop AI
+// clients keep their settings
+var registryApps = []string{
+	`Software\Anthropic`,
+	`Software\Claude`,
+	`Software\OpenAI`,
+	`Software\ChatGPT`,
+	`Software\Chatbox`,
+	`Software\Jan`,
+	`Software\LM Studio`,
+	`Software\Msty`,
+	`Software\Cursor`,
+	`Software\Codeium`,
+	`Software\Raycast`,
+	`Software\TypingMind`,
+}
+
+// registryKey is an open registry key. The Windows implementation wraps
+// golang.org/x/sys/windows/registry.
+type registryKey interface {
+	// subkeys returns the names of the key's subkeys
+	subkeys() ([]string, error)
+	// values returns the key's string-like values by name; binary values
+	// are decoded as UTF-16 when they look like it
+	values() (map[string]string, error)
+	open(name string) (registryKey, error)
+	close()
+}
+
+// scanRegistryKey searches the values of key, whose full path is path, and
+// of its subkeys down to depth levels, for keys. Each key's Source is the
+// registry path of the key holding it and its pointer the value name.
+func scanRegistryKey(key registryKey, path string, depth int, options ScanOptions, run *scanRun) []DiscoveredKey {
+	var keys []DiscoveredKey
+	values, err := key.values()
+	if err != nil {
+		run.warn(passRegistry, path, warningKindOf(err), err)
+	}
+	for name, value := range values {
+		for _, k := range valueKeys(path, name, value, options) {
+			k.Location = &Location{Path: path, EnvVar: k.Location.EnvVar, Pointer: "/" + escapePointer(name)}
+			keys = append(keys, k)
+		}
+	}
+	if depth <= 0 {
+		return keys
+	}
+	names, err := key.subkeys()
+	if err != nil {
+		run.warn(passRegistry, path, warningKindOf(err), err)
+		return keys
+	}
+	for _, name := range names {
+		sub, err := key.open(name)
+		if err != nil {
+			run.warn(passRegistry, path+`\`+name, warningKindOf(err), err)
+			continue
+		}
+		keys = append(keys, scanRegistryKey(sub, path+`\`+name, depth-1, options, run)...)
+		sub.close()
+	}
+	return keys
+}
+
+// decodeRegistryBinary returns a binary value as text: UTF-16LE when every
+// other byte is zero, as applications storing strings as binary write it,
+// and the bytes as they are otherwise
+func decodeRegistryBinary(b []byte) string {
+	if len(b) >= 2 && len(b)%2 == 0 {
+		wide := true
+		for i := 1; i < len(b); i += 2 {
+			if b[i] != 0 {
+				wide = false
+				break
+			}
+		}
+		if wide {
+			units := make([]uint16, len(b)/2)
+			for i := range units {
+				units[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+			}
+			return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package aicred
+
+// scanRegistry finds nothing on platforms without a registry
+func scanRegistry(options ScanOptions, run *scanRun) []DiscoveredKey {
+	return nil
+}
@@ -0,0 +1,80 @@
+package aicred
+
+import (
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// fakeRegistryKey is an in-memory registry tree
+type fakeRegistryKey struct {
+	vals map[string]string
+	subs map[string]*fakeRegistryKey
+}
+
+func (k *fakeRegistryKey) subkeys() ([]string, error) {
+	var names []string
+	for name := range k.subs {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (k *fakeRegistryKey) values() (map[string]string, error) { return k.vals, nil }
+
+func (k *fakeRegistryKey) open(name string) (registryKey, error) {
+	if sub, ok := k.subs[name]; ok && sub != nil {
+		return sub, nil
+	}
+	return nil, errors.New("access denied")
+}
+
+func (k *fakeRegistryKey) close() {}
+
+func TestScanRegistryKey(t *testing.T) {
+	root := &fakeRegistryKey{
+		vals: map[string]string{"Theme": "dark"},
+		subs: map[string]*fakeRegistryKey{
+			"Settings": {vals: map[string]string{
+				"ApiKey":         "sk-ant-REDACTED",
+				"OPENAI_API_KEY": "abcdefghijklmnopqrstuvwx",
+			}},
+			"Locked": nil,
+		},
+	}
+	run := &scanRun{}
+	keys := scanRegistryKey(root, `HKCU\Software\Claude`, registryDepth, ScanOptions{}, run)
+	if len(keys) != 2 {
+		t.Fatalf("found %d keys, want 2: %+v", len(keys), keys)
+	}
+	byProvider := map[string]DiscoveredKey{}
+	for _, k := range keys {
+		byProvider[k.Provider] = k
+	}
+	if k := byProvider["anthropic"]; k.Source != `HKCU\Software\Claude\Settings` || k.Location.Pointer != "/ApiKey" || k.Location.Line != 0 {
+		t.Errorf("anthropic key = %+v at %+v", k, k.Location)
+	}
+	if k := byProvider["openai"]; k.Location.EnvVar != "OPENAI_API_KEY" {
+		t.Errorf("openai key = %+v at %+v", k, k.Location)
+	}
+	if len(run.warnings) != 1 || run.warnings[0].Path != `HKCU\Software\Claude\Locked` {
+		t.Errorf("warnings = %+v", run.warnings)
+	}
+
+	if keys := scanRegistryKey(root, `HKCU\Software\Claude`, 0, ScanOptions{}, nil); len(keys) != 0 {
+		t.Errorf("depth 0 found %d keys in subkeys", len(keys))
+	}
+}
+
+func TestDecodeRegistryBinary(t *testing.T) {
+	var wide []byte
+	for _, u := range utf16.Encode([]rune("hf_abc\x00")) {
+		wide = append(wide, byte(u), byte(u>>8))
+	}
+	if got := decodeRegistryBinary(wide); got != "hf_abc" {
+		t.Errorf("UTF-16 value = %q", got)
+	}
+	if got := decodeRegistryBinary([]byte("plain")); got != "plain" {
+		t.Errorf("byte value = %q", got)
+	}
+}
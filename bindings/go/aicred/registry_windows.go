@@ -0,0 +1,78 @@
+//go:build windows
+
+package aicred
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// scanRegistry searches the HKEY_CURRENT_USER keys of known AI clients for
+// keys stored in their values. Applications that are not installed have no
+// key and are skipped.
+func scanRegistry(options ScanOptions, run *scanRun) []DiscoveredKey {
+	var keys []DiscoveredKey
+	for _, app := range registryApps {
+		k, err := registry.OpenKey(registry.CURRENT_USER, app, registry.READ)
+		if err != nil {
+			if !errors.Is(err, registry.ErrNotExist) {
+				run.warn(passRegistry, `HKCU\`+app, warningKindOf(err), err)
+			}
+			continue
+		}
+		keys = append(keys, scanRegistryKey(winRegistryKey{k}, `HKCU\`+app, registryDepth, options, run)...)
+		k.Close()
+	}
+	return keys
+}
+
+type winRegistryKey struct {
+	key registry.Key
+}
+
+func (k winRegistryKey) subkeys() ([]string, error) {
+	return k.key.ReadSubKeyNames(0)
+}
+
+func (k winRegistryKey) values() (map[string]string, error) {
+	names, err := k.key.ReadValueNames(0)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		_, valtype, err := k.key.GetValue(name, nil)
+		if err != nil {
+			continue
+		}
+		switch valtype {
+		case registry.SZ, registry.EXPAND_SZ:
+			if v, _, err := k.key.GetStringValue(name); err == nil {
+				values[name] = v
+			}
+		case registry.MULTI_SZ:
+			if v, _, err := k.key.GetStringsValue(name); err == nil {
+				values[name] = strings.Join(v, "\n")
+			}
+		case registry.BINARY:
+			if v, _, err := k.key.GetBinaryValue(name); err == nil {
+				values[name] = decodeRegistryBinary(v)
+			}
+		}
+	}
+	return values, nil
+}
+
+func (k winRegistryKey) open(name string) (registryKey, error) {
+	sub, err := registry.OpenKey(k.key, name, registry.READ)
+	if err != nil {
+		return nil, err
+	}
+	return winRegistryKey{sub}, nil
+}
+
+func (k winRegistryKey) close() {
+	k.key.Close()
+}
@@ -0,0 +1,169 @@
+package aicred
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sshCommand is the ssh client ScanRemote runs; tests replace it
+var sshCommand = "ssh"
+
+// ScanRemote scans a home directory on another machine over SSH and returns
+// the result with Host set to the machine's hostname. sshTarget is anything
+// ssh accepts as a destination, such as "user@host" or a Host alias from
+// ~/.ssh/config, and the remote login shell must be POSIX.
+//
+// The scan runs the aicred CLI on the remote host: the one on its PATH, or
+// with options.RemoteBinary the given static build, which is copied over the
+// same connection, run and deleted. Key values cross the network only when
+// options.Redaction is RedactionNone. options.HomeDir names a remote
+// directory; "" means the remote user's home. The Go-side passes, such as
+// ScanArchives, and the incremental cache do not apply to remote scans.
+//
+// ssh runs non-interactively, so authentication must not need a prompt.
+// Cancelling ctx, or exceeding options.GlobalTimeout, kills it.
+func ScanRemote(ctx context.Context, sshTarget string, options ScanOptions) (*ScanResult, error) {
+	start := time.Now()
+	if sshTarget == "" || strings.HasPrefix(sshTarget, "-") {
+		return nil, fmt.Errorf("invalid ssh target %q: %w", sshTarget, ErrNotFound)
+	}
+	if options.GlobalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.GlobalTimeout)
+		defer cancel()
+	}
+
+	level := options.redactionLevel()
+	script, err := remoteScript(options, level)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"-o", "BatchMode=yes"}, options.SSHArgs...)
+	args = append(args, "--", sshTarget, script)
+	cmd := exec.CommandContext(ctx, sshCommand, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Stop waiting for output held open by anything ssh left behind
+	cmd.WaitDelay = time.Second
+	if options.RemoteBinary != "" {
+		bin, err := os.Open(options.RemoteBinary)
+		if err != nil {
+			return nil, fmt.Errorf("remote binary: %w: %v", ErrNotFound, err)
+		}
+		defer bin.Close()
+		cmd.Stdin = bin
+	}
+
+	logger().Debug("starting remote scan", slog.String("target", sshTarget), slog.Bool("upload", options.RemoteBinary != ""))
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("remote scan of %s: %w", sshTarget, ErrTimeout)
+		}
+		return nil, fmt.Errorf("remote scan of %s: %w", sshTarget, ctx.Err())
+	}
+	if err := remoteExitError(sshTarget, runErr, stderr.Bytes()); err != nil {
+		logger().Error("remote scan failed", slog.String("target", sshTarget), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	host, output, _ := bytes.Cut(stdout.Bytes(), []byte("\n"))
+	// Older CLIs print a progress line on stdout before the JSON
+	if i := bytes.IndexByte(output, '{'); i > 0 {
+		output = output[i:]
+	}
+	// The remote CLI may be older than this package
+	result, err := ParseScanResult(output)
+	if err != nil {
+		return nil, fmt.Errorf("remote scan of %s: %w", sshTarget, err)
+	}
+	result.Host = strings.TrimSpace(string(host))
+	if result.Host == "" {
+		result.Host = targetHost(sshTarget)
+	}
+	return finishScan(result, options, level, start), nil
+}
+
+// remoteScript builds the shell command run on the remote host. It prints
+// the hostname on the first line and the scan JSON after it.
+func remoteScript(options ScanOptions, level RedactionLevel) (string, error) {
+	scan := []string{"scan", "--format", "json", "--no-probe"}
+	if options.HomeDir != "" {
+		scan = append(scan, "--home", options.HomeDir)
+	}
+	if level == RedactionNone {
+		scan = append(scan, "--include-values")
+	}
+	if len(options.OnlyProviders) > 0 {
+		scan = append(scan, "--only", strings.Join(options.OnlyProviders, ","))
+	}
+	if len(options.ExcludeProviders) > 0 {
+		scan = append(scan, "--exclude", strings.Join(options.ExcludeProviders, ","))
+	}
+	if options.MaxFileSize > 0 {
+		scan = append(scan, "--max-bytes-per-file", strconv.Itoa(options.MaxFileSize))
+	}
+	quoted := make([]string, len(scan))
+	for i, arg := range scan {
+		if strings.ContainsAny(arg, "\x00\n") {
+			return "", fmt.Errorf("remote scan argument %q: %w", arg, ErrParse)
+		}
+		quoted[i] = shellQuote(arg)
+	}
+	args := strings.Join(quoted, " ")
+
+	if options.RemoteBinary == "" {
+		return "uname -n && aicred " + args, nil
+	}
+	// The binary arrives on stdin and is removed however the scan ends
+	return `f=$(mktemp) || exit 1; trap 'rm -f "$f"' EXIT; ` +
+		`cat > "$f" && chmod 700 "$f" && uname -n && "$f" ` + args, nil
+}
+
+// remoteExitError turns a failed ssh run into an error. The CLI exits 1
+// when it finds nothing, which is not a failure.
+func remoteExitError(target string, err error, stderr []byte) error {
+	var exit *exec.ExitError
+	if err == nil || errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return nil
+	}
+	detail := strings.TrimSpace(string(stderr))
+	if exit == nil {
+		return fmt.Errorf("remote scan of %s: %w: %v", target, ErrIO, err)
+	}
+	switch exit.ExitCode() {
+	case 255:
+		return fmt.Errorf("remote scan of %s: ssh: %w: %s", target, ErrIO, detail)
+	case 126, 127:
+		return fmt.Errorf("remote scan of %s: aicred not runnable on the remote host, set RemoteBinary: %w: %s", target, ErrNotFound, detail)
+	default:
+		return fmt.Errorf("remote scan of %s: %w: exit status %d: %s", target, ErrIO, exit.ExitCode(), detail)
+	}
+}
+
+// targetHost strips the user and port from an ssh destination
+func targetHost(target string) string {
+	target = strings.TrimPrefix(target, "ssh://")
+	if i := strings.LastIndexByte(target, '@'); i >= 0 {
+		target = target[i+1:]
+	}
+	if i := strings.LastIndexByte(target, ':'); i >= 0 && !strings.Contains(target[:i], ":") {
+		target = target[:i]
+	}
+	return target
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
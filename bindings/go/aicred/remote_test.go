@@ -0,0 +1,128 @@
+package aicred
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// remoteJSON is what the aicred CLI prints for a scan with one key
+const remoteJSON = `{
+  "keys": [{"provider": "openai", "source": "/home/alice/.env", "value_type": "ApiKey",
+            "value": {"Full": "sk-remote"}, "confidence": "High", "hash": "h1", "redacted": "sk-***"}],
+  "config_instances": [],
+  "home_directory": "/home/alice",
+  "scan_started_at": "2025-01-01T00:00:00Z",
+  "providers_scanned": ["openai"]
+}`
+
+// fakeSSH installs an ssh that runs the remote command locally with a PATH
+// holding an aicred that logs its arguments and prints remoteJSON
+func fakeSSH(t *testing.T) (argsLog string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	argsLog = filepath.Join(dir, "args")
+	writeFile(t, filepath.Join(dir, "ssh"), []byte(`#!/bin/sh
+while [ "$1" != "--" ]; do shift; done
+shift 2
+exec sh -c "$1"
+`))
+	writeFile(t, filepath.Join(dir, "bin", "aicred"), []byte(`#!/bin/sh
+echo "$@" > `+argsLog+`
+cat <<'EOF'
+`+remoteJSON+`
+EOF
+`))
+	for _, p := range []string{filepath.Join(dir, "ssh"), filepath.Join(dir, "bin", "aicred")} {
+		if err := os.Chmod(p, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := sshCommand
+	sshCommand = filepath.Join(dir, "ssh")
+	t.Cleanup(func() { sshCommand = old })
+	t.Setenv("PATH", filepath.Join(dir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return argsLog
+}
+
+func TestScanRemote(t *testing.T) {
+	argsLog := fakeSSH(t)
+	hostname, _ := os.Hostname()
+
+	result, err := ScanRemote(context.Background(), "alice@build-01", ScanOptions{
+		HomeDir:       "/home/alice",
+		OnlyProviders: []string{"openai", "groq"},
+	})
+	if err != nil {
+		t.Fatalf("ScanRemote: %v", err)
+	}
+	if result.Host != hostname {
+		t.Errorf("Host = %q, want this machine's name %q", result.Host, hostname)
+	}
+	if len(result.Keys) != 1 || result.Keys[0].Source != "/home/alice/.env" {
+		t.Fatalf("Keys = %+v", result.Keys)
+	}
+	if !result.Keys[0].Value.IsZero() {
+		t.Error("key value kept without RedactionNone")
+	}
+
+	args, err := os.ReadFile(argsLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "scan --format json --no-probe --home /home/alice --only openai,groq"
+	if got := strings.TrimSpace(string(args)); got != want {
+		t.Errorf("remote args = %q, want %q", got, want)
+	}
+}
+
+func TestScanRemoteUploadsBinary(t *testing.T) {
+	fakeSSH(t)
+	// Hide the aicred on PATH so only the uploaded one can answer
+	t.Setenv("PATH", "/usr/bin:/bin")
+	upload := filepath.Join(t.TempDir(), "aicred-static")
+	writeFile(t, upload, []byte("#!/bin/sh\ncat <<'EOF'\n"+remoteJSON+"\nEOF\n"))
+
+	result, err := ScanRemote(context.Background(), "build-01", ScanOptions{RemoteBinary: upload, Redaction: RedactionNone})
+	if err != nil {
+		t.Fatalf("ScanRemote: %v", err)
+	}
+	if len(result.Keys) != 1 || result.Keys[0].Value.Reveal() != "sk-remote" {
+		t.Errorf("Keys = %+v", result.Keys)
+	}
+
+	if _, err := ScanRemote(context.Background(), "build-01", ScanOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("without aicred on the remote PATH: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestScanRemoteTimeout(t *testing.T) {
+	fakeSSH(t)
+	writeFile(t, sshCommand, []byte("#!/bin/sh\nexec sleep 5\n"))
+
+	_, err := ScanRemote(context.Background(), "build-01", ScanOptions{GlobalTimeout: 50 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want ErrTimeout", err)
+	}
+}
+
+func TestTargetHost(t *testing.T) {
+	for target, want := range map[string]string{
+		"build-01":                  "build-01",
+		"alice@build-01":            "build-01",
+		"ssh://alice@build-01:2222": "build-01",
+		"alice@[::1]":               "[::1]",
+	} {
+		if got := targetHost(target); got != want {
+			t.Errorf("targetHost(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
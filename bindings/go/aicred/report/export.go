@@ -0,0 +1,248 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// Sheet is a table for spreadsheets: a header row of column names and the
+// rows under it. WriteCSV writes one sheet; WriteXLSX writes several as
+// the tabs of one workbook.
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// column is a named column of a sheet and how to fill it from a row's
+// source
+type column[T any] struct {
+	name  string
+	value func(T) string
+}
+
+// keyRow is a key with the context eachKey gives it
+type keyRow struct {
+	key       aicred.DiscoveredKey
+	app, path string
+}
+
+func (r keyRow) location() aicred.Location {
+	if r.key.Location != nil {
+		return *r.key.Location
+	}
+	return aicred.Location{}
+}
+
+// keyColumns are the columns KeysSheet can fill, in their default order.
+// There is deliberately no column for key values.
+var keyColumns = []column[keyRow]{
+	{"provider", func(r keyRow) string { return r.key.Provider }},
+	{"severity", func(r keyRow) string { return Severity(r.key).String() }},
+	{"confidence", func(r keyRow) string { return r.key.Confidence }},
+	{"app", func(r keyRow) string { return r.app }},
+	{"path", func(r keyRow) string { return r.path }},
+	{"line", func(r keyRow) string { return itoa(r.location().Line) }},
+	{"env_var", func(r keyRow) string { return r.location().EnvVar }},
+	{"value_type", func(r keyRow) string { return r.key.ValueType }},
+	{"redacted", func(r keyRow) string { return r.key.Redacted }},
+	{"hash", func(r keyRow) string { return r.key.Hash }},
+	{"locked", func(r keyRow) string { return strconv.FormatBool(r.key.Locked) }},
+	{"owner", func(r keyRow) string {
+		if r.key.Attribution == nil {
+			return ""
+		}
+		return r.key.Attribution.User
+	}},
+	{"organization", func(r keyRow) string {
+		if r.key.Scope == nil {
+			return ""
+		}
+		return r.key.Scope.Organization
+	}},
+}
+
+// instanceColumns are the columns InstancesSheet can fill, in their
+// default order
+var instanceColumns = []column[aicred.ConfigInstance]{
+	{"instance_id", func(i aicred.ConfigInstance) string { return i.InstanceID }},
+	{"app", func(i aicred.ConfigInstance) string { return i.AppName }},
+	{"config_path", func(i aicred.ConfigInstance) string { return i.ConfigPath }},
+	{"discovered_at", func(i aicred.ConfigInstance) string { return i.DiscoveredAt }},
+	{"keys", func(i aicred.ConfigInstance) string { return strconv.Itoa(len(i.Keys)) }},
+	{"providers", func(i aicred.ConfigInstance) string {
+		var providers []string
+		for _, key := range i.Keys {
+			if !slices.Contains(providers, key.Provider) {
+				providers = append(providers, key.Provider)
+			}
+		}
+		slices.Sort(providers)
+		return strings.Join(providers, "; ")
+	}},
+	{"metadata", func(i aicred.ConfigInstance) string {
+		pairs := make([]string, 0, len(i.Metadata))
+		for _, name := range slices.Sorted(maps.Keys(i.Metadata)) {
+			pairs = append(pairs, name+"="+i.Metadata[name])
+		}
+		return strings.Join(pairs, "; ")
+	}},
+}
+
+// modelRow is one model of a provider instance, with the labels on the
+// model or on the whole instance
+type modelRow struct {
+	instance aicred.ProviderInstance
+	model    string
+	labels   []string
+}
+
+// modelColumns are the columns ModelsSheet can fill, in their default
+// order. There is deliberately no column for API keys.
+var modelColumns = []column[modelRow]{
+	{"instance_id", func(r modelRow) string { return r.instance.ID }},
+	{"provider_type", func(r modelRow) string { return r.instance.ProviderType }},
+	{"base_url", func(r modelRow) string { return r.instance.BaseURL }},
+	{"model", func(r modelRow) string { return r.model }},
+	{"labels", func(r modelRow) string { return strings.Join(r.labels, "; ") }},
+	{"active", func(r modelRow) string { return strconv.FormatBool(r.instance.Active) }},
+	{"has_key", func(r modelRow) string { return strconv.FormatBool(!r.instance.APIKey.IsZero()) }},
+}
+
+// KeyColumns returns the columns KeysSheet fills by default, in order
+func KeyColumns() []string { return columnNames(keyColumns) }
+
+// InstanceColumns returns the columns InstancesSheet fills by default, in
+// order
+func InstanceColumns() []string { return columnNames(instanceColumns) }
+
+// ModelColumns returns the columns ModelsSheet fills by default, in order
+func ModelColumns() []string { return columnNames(modelColumns) }
+
+func columnNames[T any](cols []column[T]) []string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+	return names
+}
+
+// buildSheet fills the named columns, or all of them when names is empty,
+// for each source row. It returns an error wrapping
+// aicred.ErrInvalidOption for an unknown column.
+func buildSheet[T any](name string, all []column[T], rows []T, names []string) (Sheet, error) {
+	if len(names) == 0 {
+		names = columnNames(all)
+	}
+	cols := make([]column[T], len(names))
+	for i, n := range names {
+		j := slices.IndexFunc(all, func(c column[T]) bool { return c.name == n })
+		if j < 0 {
+			return Sheet{}, fmt.Errorf("report: %s sheet has no column %q (have %s): %w",
+				name, n, strings.Join(columnNames(all), ", "), aicred.ErrInvalidOption)
+		}
+		cols[i] = all[j]
+	}
+	sheet := Sheet{Name: name, Header: slices.Clone(names), Rows: make([][]string, len(rows))}
+	for i, row := range rows {
+		cells := make([]string, len(cols))
+		for j, c := range cols {
+			cells[j] = c.value(row)
+		}
+		sheet.Rows[i] = cells
+	}
+	return sheet, nil
+}
+
+// KeysSheet tabulates the keys in result, one row per key and file as in
+// Summarize, most severe first. columns picks and orders the columns from
+// KeyColumns; none means all of them. Key values are never exported.
+func KeysSheet(result *aicred.ScanResult, columns ...string) (Sheet, error) {
+	var rows []keyRow
+	eachKey(result, func(key aicred.DiscoveredKey, app, path string) {
+		rows = append(rows, keyRow{key, app, path})
+	})
+	slices.SortStableFunc(rows, func(a, b keyRow) int { return int(Severity(b.key)) - int(Severity(a.key)) })
+	return buildSheet("Keys", keyColumns, rows, columns)
+}
+
+// InstancesSheet tabulates the config instances in result, one row per
+// instance. columns picks and orders the columns from InstanceColumns;
+// none means all of them.
+func InstancesSheet(result *aicred.ScanResult, columns ...string) (Sheet, error) {
+	return buildSheet("Config instances", instanceColumns, result.ConfigInstances, columns)
+}
+
+// ModelsSheet tabulates the model registry: a row per model of each
+// provider instance, or one row with no model for instances that list
+// none, with the labels assigned to the model or the instance. columns
+// picks and orders the columns from ModelColumns; none means all of them.
+// API keys are never exported.
+func ModelsSheet(instances []aicred.ProviderInstance, labels []aicred.LabelAssignment, columns ...string) (Sheet, error) {
+	var rows []modelRow
+	for _, instance := range instances {
+		labelsOf := func(model string) []string {
+			var names []string
+			for _, a := range labels {
+				if a.Target.InstanceID != instance.ID {
+					continue
+				}
+				if a.Target.Type == aicred.LabelTargetInstance || (a.Target.Type == aicred.LabelTargetModel && a.Target.ModelID == model) {
+					names = append(names, a.LabelName)
+				}
+			}
+			slices.Sort(names)
+			return slices.Compact(names)
+		}
+		if len(instance.Models) == 0 {
+			rows = append(rows, modelRow{instance, "", labelsOf("")})
+		}
+		for _, model := range instance.Models {
+			rows = append(rows, modelRow{instance, model, labelsOf(model)})
+		}
+	}
+	return buildSheet("Models", modelColumns, rows, columns)
+}
+
+// WriteCSV writes sheet as CSV with a header row. Cells that spreadsheet
+// applications would run as formulas, those starting with =, +, -, @, a
+// tab or a carriage return, are prefixed with a single quote: paths and
+// metadata come from scanned files, which anyone may have written.
+func WriteCSV(w io.Writer, sheet Sheet) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(sheet.Header); err != nil {
+		return err
+	}
+	for _, row := range sheet.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = defuseFormula(cell)
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func defuseFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,149 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func TestKeysSheet(t *testing.T) {
+	sheet, err := KeysSheet(testResult())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sheet.Header, KeyColumns()) || len(sheet.Rows) != 3 {
+		t.Fatalf("sheet = %+v", sheet)
+	}
+	for _, row := range sheet.Rows {
+		if slices.Contains(row, fullValue) {
+			t.Errorf("row holds a key value: %q", row)
+		}
+	}
+
+	sheet, err = KeysSheet(testResult(), "redacted", "provider", "line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"sk-p...pear", "openai", ""}, {"sk-a...igAA", "anthropic", ""}, {"gsk_...0000", "groq", "7"}}
+	if !slices.EqualFunc(sheet.Rows, want, slices.Equal) {
+		t.Errorf("rows = %q", sheet.Rows)
+	}
+
+	if _, err := KeysSheet(testResult(), "provider", "value"); !errors.Is(err, aicred.ErrInvalidOption) {
+		t.Errorf("value column: err = %v", err)
+	}
+}
+
+func TestInstancesSheet(t *testing.T) {
+	result := testResult()
+	result.ConfigInstances[0].Metadata = map[string]string{"version": "2", "channel": "stable"}
+	sheet, err := InstancesSheet(result, "app", "keys", "providers", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"roo-code", "2", "anthropic; openai", "channel=stable; version=2"}; len(sheet.Rows) != 1 || !slices.Equal(sheet.Rows[0], want) {
+		t.Errorf("rows = %q", sheet.Rows)
+	}
+}
+
+func TestModelsSheet(t *testing.T) {
+	instances := []aicred.ProviderInstance{
+		{ID: "openai-prod", ProviderType: "openai", Models: []string{"gpt-4o", "gpt-4o-mini"}, APIKey: aicred.NewSecretString(fullValue), Active: true},
+		{ID: "local", ProviderType: "ollama"},
+	}
+	labels := []aicred.LabelAssignment{
+		{LabelName: "prod", Target: aicred.LabelTarget{Type: aicred.LabelTargetInstance, InstanceID: "openai-prod"}},
+		{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetModel, InstanceID: "openai-prod", ModelID: "gpt-4o-mini"}},
+	}
+	sheet, err := ModelsSheet(instances, labels, "instance_id", "model", "labels", "has_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"openai-prod", "gpt-4o", "prod", "true"},
+		{"openai-prod", "gpt-4o-mini", "fast; prod", "true"},
+		{"local", "", "", "false"},
+	}
+	if !slices.EqualFunc(sheet.Rows, want, slices.Equal) {
+		t.Errorf("rows = %q", sheet.Rows)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	sheet := Sheet{Header: []string{"path", "note"}, Rows: [][]string{{"=HYPERLINK(\"x\")", "a, \"b\""}, {"-1", "plain"}}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, sheet); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"path", "note"}, {"'=HYPERLINK(\"x\")", "a, \"b\""}, {"'-1", "plain"}}
+	if !slices.EqualFunc(records, want, slices.Equal) {
+		t.Errorf("records = %q", records)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	keys, _ := KeysSheet(testResult(), "provider", "path")
+	other := Sheet{Name: "Keys", Header: []string{"a"}, Rows: [][]string{{"x < y & \x01"}}}
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, keys, other); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(data)
+		for dec := xml.NewDecoder(bytes.NewReader(data)); ; {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s is not XML: %v", f.Name, err)
+				break
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("workbook lacks %s", name)
+		}
+	}
+	if wb := parts["xl/workbook.xml"]; !strings.Contains(wb, `name="Keys"`) || !strings.Contains(wb, `name="Keys (2)"`) {
+		t.Errorf("workbook.xml = %s", wb)
+	}
+	sheet1 := parts["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet1, `<c r="B4" t="inlineStr"><is><t xml:space="preserve">/home/u/notes/&lt;script&gt;.txt</t>`) {
+		t.Errorf("sheet1.xml = %s", sheet1)
+	}
+	if sheet2 := parts["xl/worksheets/sheet2.xml"]; !strings.Contains(sheet2, "x &lt; y &amp; �") {
+		t.Errorf("sheet2.xml = %s", sheet2)
+	}
+
+	if err := WriteXLSX(io.Discard); !errors.Is(err, aicred.ErrInvalidOption) {
+		t.Errorf("no sheets: err = %v", err)
+	}
+}
+
+func TestColumnLetters(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := columnLetters(i); got != want {
+			t.Errorf("columnLetters(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
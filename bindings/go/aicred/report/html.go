@@ -0,0 +1,126 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+)
+
+// severityColors are the chart colors of each severity, by name; other
+// buckets are gray
+var severityColors = map[string]string{
+	"critical": "#b91c1c",
+	"high":     "#ea580c",
+	"medium":   "#ca8a04",
+	"low":      "#2563eb",
+}
+
+const otherColor = "#4b5563"
+
+// bar is one bar of a chart, laid out for the template
+type bar struct {
+	Count
+	Y, Width float64
+	Color    string
+}
+
+// chart is a titled bar chart
+type chart struct {
+	Title  string
+	Counts []Count
+}
+
+// chartBars lays out a horizontal bar chart whose longest bar is 300 units
+func chartBars(counts []Count) []bar {
+	most := 1
+	for _, c := range counts {
+		most = max(most, c.Count)
+	}
+	bars := make([]bar, len(counts))
+	for i, c := range counts {
+		color := severityColors[c.Name]
+		if color == "" {
+			color = otherColor
+		}
+		width := 300 * float64(c.Count) / float64(most)
+		if c.Count > 0 {
+			width = math.Max(2, width)
+		}
+		bars[i] = bar{Count: c, Y: float64(i) * 24, Width: width, Color: color}
+	}
+	return bars
+}
+
+// label shortens name to n runes for a chart's label column
+func label(name string, n int) string {
+	if r := []rune(name); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return name
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bars":   chartBars,
+	"chart":  func(title string, counts []Count) chart { return chart{title, counts} },
+	"height": func(counts []Count) int { return 24*len(counts) + 4 },
+	"label":  label,
+}).Parse(htmlSource))
+
+// htmlSource is a single page without scripts or external assets, so it
+// can be mailed or archived as is
+const htmlSource = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #111827; margin: 2rem auto; max-width: 60rem; padding: 0 1rem; }
+h1 { margin-bottom: 0.25rem; }
+.meta { color: #4b5563; margin-top: 0; }
+.total { font-size: 1.25rem; }
+.charts { display: flex; flex-wrap: wrap; gap: 2rem; }
+.chart h2 { font-size: 1rem; }
+svg text { font-size: 12px; fill: #111827; }
+table { border-collapse: collapse; width: 100%; margin-top: 1rem; font-size: 0.875rem; }
+th, td { border-bottom: 1px solid #e5e7eb; padding: 0.375rem 0.5rem; text-align: left; vertical-align: top; }
+td.path { word-break: break-all; }
+.sev { color: #fff; border-radius: 0.25rem; padding: 0.125rem 0.375rem; }
+.sev-critical { background: #b91c1c; } .sev-high { background: #ea580c; } .sev-medium { background: #ca8a04; } .sev-low { background: #2563eb; }
+@media print { .charts { display: block; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{if .Host}}Host {{.Host}} · {{end}}{{if .HomeDir}}Home {{.HomeDir}} · {{end}}{{if .ScannedAt}}Scanned {{.ScannedAt}} · {{end}}Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</p>
+<p class="total"><strong>{{len .Findings}}</strong> credential finding{{if ne (len .Findings) 1}}s{{end}}</p>
+{{if .Findings}}
+<div class="charts">
+{{template "chart" (chart "By severity" .BySeverity)}}
+{{template "chart" (chart "By provider" .ByProvider)}}
+{{template "chart" (chart "By application" .ByApp)}}
+</div>
+<h2>Findings</h2>
+<table>
+<thead><tr><th>Severity</th><th>Provider</th><th>Application</th><th>Location</th><th>Key</th></tr></thead>
+<tbody>
+{{range .Findings}}<tr><td><span class="sev sev-{{.Severity}}">{{.Severity}}</span></td><td>{{.Provider}}</td><td>{{.App}}</td><td class="path">{{.Path}}{{if .Line}}:{{.Line}}{{end}}</td><td><code>{{.Redacted}}</code></td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+</body>
+</html>
+{{define "chart"}}<div class="chart">
+<h2>{{.Title}}</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="480" height="{{height .Counts}}" role="img" aria-label="{{.Title}}">
+{{range bars .Counts}}<text x="0" y="{{.Y}}" dy="15">{{label .Name 20}}</text><rect x="140" y="{{.Y}}" width="{{.Width}}" height="18" fill="{{.Color}}"/><text x="{{.Width}}" y="{{.Y}}" dx="146" dy="15">{{.Count.Count}}</text>
+{{end}}</svg>
+</div>{{end}}`
+
+func renderHTML(s Summary) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, s); err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,217 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A4 in points, and the page margin
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+)
+
+// pdfDoc lays out text and bars top to bottom over as many pages as it
+// takes. It uses only the standard Helvetica fonts, which every reader has,
+// so the document embeds nothing.
+type pdfDoc struct {
+	pages []*bytes.Buffer
+	// y is the baseline of the next line on the last page, from the bottom
+	y float64
+}
+
+func (d *pdfDoc) newPage() {
+	d.pages = append(d.pages, new(bytes.Buffer))
+	d.y = pageHeight - margin
+}
+
+// space moves down by h, starting a new page if h does not fit
+func (d *pdfDoc) space(h float64) {
+	if len(d.pages) == 0 || d.y-h < margin {
+		d.newPage()
+	}
+	d.y -= h
+}
+
+func (d *pdfDoc) text(x float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.pages[len(d.pages)-1], "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font, size, x, d.y, pdfString(s))
+}
+
+// rect fills a rectangle of height h standing on the current baseline
+func (d *pdfDoc) rect(x, w, h float64, color string) {
+	r, g, b := rgb(color)
+	fmt.Fprintf(d.pages[len(d.pages)-1], "%.3f %.3f %.3f rg %g %g %g %g re f 0 g\n", r, g, b, x, d.y-2, w, h)
+}
+
+// rgb parses a #rrggbb color into PDF's 0 to 1 components
+func rgb(color string) (r, g, b float64) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(color, "#"), 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+	return float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255
+}
+
+// pdfString escapes s for a literal string in WinAnsiEncoding. Characters
+// the encoding lacks become '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case r == '…':
+			b.WriteString("\\205")
+		case r == '–':
+			b.WriteString("\\226")
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// clip shortens s to n runes, keeping the end of paths, where the file
+// name is, and the start of anything else
+func clip(s string, n int, keepEnd bool) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if keepEnd {
+		return "…" + string(r[len(r)-n+1:])
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func (d *pdfDoc) chart(title string, counts []Count) {
+	d.space(28)
+	d.text(margin, 12, true, title)
+	d.space(6)
+	bars := chartBars(counts)
+	for _, b := range bars {
+		d.space(16)
+		d.text(margin, 9, false, clip(b.Name, 26, false))
+		// chartBars' longest bar is 300 units; here it is 260 points
+		w := b.Width * 260 / 300
+		if w > 0 {
+			d.rect(190, w, 11, b.Color)
+		}
+		d.text(196+w, 9, false, strconv.Itoa(b.Count.Count))
+	}
+}
+
+// findingColumns are the x positions of the findings table's columns
+var findingColumns = [...]float64{margin, 100, 170, 260, 465}
+
+func (d *pdfDoc) findingsHeader() {
+	d.space(16)
+	for i, h := range [...]string{"Severity", "Provider", "Application", "Location", "Key"} {
+		d.text(findingColumns[i], 8, true, h)
+	}
+}
+
+func renderPDF(s Summary) []byte {
+	var d pdfDoc
+	d.space(20)
+	d.text(margin, 20, true, s.Title)
+	var meta []string
+	if s.Host != "" {
+		meta = append(meta, "Host "+s.Host)
+	}
+	if s.HomeDir != "" {
+		meta = append(meta, "Home "+s.HomeDir)
+	}
+	if s.ScannedAt != "" {
+		meta = append(meta, "Scanned "+s.ScannedAt)
+	}
+	meta = append(meta, "Generated "+s.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	d.space(18)
+	d.text(margin, 9, false, clip(strings.Join(meta, " · "), 100, false))
+	d.space(24)
+	plural := "s"
+	if len(s.Findings) == 1 {
+		plural = ""
+	}
+	d.text(margin, 12, true, fmt.Sprintf("%d credential finding%s", len(s.Findings), plural))
+
+	if len(s.Findings) > 0 {
+		d.chart("By severity", s.BySeverity)
+		d.chart("By provider", s.ByProvider)
+		d.chart("By application", s.ByApp)
+
+		d.space(30)
+		d.text(margin, 12, true, "Findings")
+		d.findingsHeader()
+		for _, f := range s.Findings {
+			if d.y-13 < margin {
+				d.newPage()
+				d.findingsHeader()
+			}
+			d.space(13)
+			location := f.Path
+			if f.Line > 0 {
+				location += ":" + strconv.Itoa(f.Line)
+			}
+			d.text(findingColumns[0], 8, false, f.Severity.String())
+			d.text(findingColumns[1], 8, false, clip(f.Provider, 14, false))
+			d.text(findingColumns[2], 8, false, clip(f.App, 18, false))
+			d.text(findingColumns[3], 8, false, clip(location, 44, true))
+			d.text(findingColumns[4], 8, false, clip(f.Redacted, 20, false))
+		}
+	}
+	return d.bytes(s.Title)
+}
+
+// bytes assembles the PDF: the catalog, page tree, fonts and info, then a
+// page and a content stream per page, and the cross-reference table
+func (d *pdfDoc) bytes(title string) []byte {
+	var objects []string
+	kids := make([]string, len(d.pages))
+	const firstPage = 6
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (aicred) >>", pdfString(title)),
+	)
+	for i, page := range d.pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, firstPage+2*i+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.Bytes()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
@@ -0,0 +1,183 @@
+// Package report renders scan results as executive reports for auditors:
+// totals, charts of findings by provider, severity and application, and a
+// table of every finding. It also exports keys, config instances and the
+// model registry as CSV and xlsx sheets for spreadsheet-based reviews.
+// Reports and sheets carry only redacted previews, never key values,
+// whatever the result's redaction level.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/policy"
+)
+
+// ReportFormat is the file format Render produces
+type ReportFormat string
+
+// Report formats
+const (
+	// FormatHTML is a single self-contained HTML page with SVG charts
+	FormatHTML ReportFormat = "html"
+	// FormatPDF is an A4 PDF document using the standard PDF fonts
+	FormatPDF ReportFormat = "pdf"
+)
+
+// otherFiles is the application of keys found outside any application's
+// configuration, by the file scans
+const otherFiles = "Other files"
+
+// Count is how many findings fall in one bucket of a chart
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Finding is one row of a report
+type Finding struct {
+	Provider   string
+	Severity   policy.Severity
+	Confidence string
+	App        string
+	Path       string
+	Line       int
+	Redacted   string
+}
+
+// Summary is what a report shows
+type Summary struct {
+	Title       string
+	Host        string
+	HomeDir     string
+	ScannedAt   string
+	GeneratedAt time.Time
+	Findings    []Finding
+	// ByProvider and ByApp are sorted by count, largest first; BySeverity
+	// runs from critical to low
+	ByProvider []Count
+	BySeverity []Count
+	ByApp      []Count
+}
+
+// Render renders result as a report in format. It returns an error
+// wrapping aicred.ErrInvalidOption for an unknown format.
+func Render(result *aicred.ScanResult, format ReportFormat) ([]byte, error) {
+	summary := Summarize(result)
+	switch format {
+	case FormatHTML:
+		return renderHTML(summary)
+	case FormatPDF:
+		return renderPDF(summary), nil
+	default:
+		return nil, fmt.Errorf("report: unknown format %q: %w", format, aicred.ErrInvalidOption)
+	}
+}
+
+// Summarize counts result's findings for a report. A key found by several
+// scanners in the same file is one finding.
+func Summarize(result *aicred.ScanResult) Summary {
+	s := Summary{
+		Title:       "AI Credential Exposure Report",
+		Host:        result.Host,
+		HomeDir:     result.HomeDir,
+		ScannedAt:   result.ScannedAt,
+		GeneratedAt: time.Now().UTC(),
+	}
+	eachKey(result, func(key aicred.DiscoveredKey, app, path string) {
+		f := Finding{
+			Provider:   key.Provider,
+			Severity:   Severity(key),
+			Confidence: key.Confidence,
+			App:        app,
+			Path:       path,
+			Redacted:   key.Redacted,
+		}
+		if key.Location != nil {
+			f.Line = key.Location.Line
+		}
+		s.Findings = append(s.Findings, f)
+	})
+	sort.SliceStable(s.Findings, func(i, j int) bool { return s.Findings[i].Severity > s.Findings[j].Severity })
+
+	providers, apps := map[string]int{}, map[string]int{}
+	severities := map[policy.Severity]int{}
+	for _, f := range s.Findings {
+		providers[f.Provider]++
+		apps[f.App]++
+		severities[f.Severity]++
+	}
+	s.ByProvider = sortedCounts(providers)
+	s.ByApp = sortedCounts(apps)
+	for sev := policy.SeverityCritical; sev >= policy.SeverityLow; sev-- {
+		s.BySeverity = append(s.BySeverity, Count{Name: sev.String(), Count: severities[sev]})
+	}
+	return s
+}
+
+// eachKey calls visit with each key in result, the application whose
+// configuration held it and the file it is in. Keys in config instances
+// come first; a key found again in the same file is skipped.
+func eachKey(result *aicred.ScanResult, visit func(key aicred.DiscoveredKey, app, path string)) {
+	seen := map[string]bool{}
+	add := func(key aicred.DiscoveredKey, app string) {
+		// Sources such as "archive.zip!.env" say more than the path
+		path := key.Source
+		if key.Location != nil && key.Location.Path != "" && !strings.Contains(key.Source, "!") {
+			path = key.Location.Path
+		}
+		id := key.Hash + "\x00" + path
+		if key.Hash != "" && seen[id] {
+			return
+		}
+		seen[id] = true
+		visit(key, app, path)
+	}
+	for _, instance := range result.ConfigInstances {
+		for _, key := range instance.Keys {
+			add(key, instance.AppName)
+		}
+	}
+	for _, key := range result.Keys {
+		add(key, otherFiles)
+	}
+}
+
+// Severity ranks a finding as the CI formats do: by the detector's
+// confidence, raised to critical for Hugging Face tokens that can write
+// and lowered to low for tokens the Hub rejected
+func Severity(key aicred.DiscoveredKey) policy.Severity {
+	switch {
+	case key.HFToken.CanWrite():
+		return policy.SeverityCritical
+	case key.HFToken != nil && key.HFToken.Rejected:
+		return policy.SeverityLow
+	}
+	switch key.Confidence {
+	case "Low":
+		return policy.SeverityLow
+	case "Medium":
+		return policy.SeverityMedium
+	case "VeryHigh":
+		return policy.SeverityCritical
+	default:
+		return policy.SeverityHigh
+	}
+}
+
+func sortedCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, Count{Name: name, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
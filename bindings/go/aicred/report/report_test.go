@@ -0,0 +1,114 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/policy"
+)
+
+const fullValue = "sk-proj-fullValueThatMustNeverAppear"
+
+func testResult() *aicred.ScanResult {
+	return &aicred.ScanResult{
+		HomeDir:   "/home/u",
+		ScannedAt: "2024-05-01T10:00:00Z",
+		Keys: []aicred.DiscoveredKey{
+			{Provider: "openai", Source: "/home/u/.env", Confidence: "VeryHigh", Hash: "a1", Redacted: "sk-p...pear", Value: aicred.NewSecretString(fullValue)},
+			{Provider: "groq", Source: "/home/u/notes/<script>.txt", Confidence: "Low", Hash: "b2", Redacted: "gsk_...0000", Location: &aicred.Location{Path: "/home/u/notes/<script>.txt", Line: 7}},
+		},
+		ConfigInstances: []aicred.ConfigInstance{
+			{
+				AppName: "roo-code",
+				Keys: []aicred.DiscoveredKey{
+					{Provider: "openai", Source: "/home/u/.env", Confidence: "VeryHigh", Hash: "a1", Redacted: "sk-p...pear", Value: aicred.NewSecretString(fullValue)},
+					{Provider: "anthropic", Source: "/home/u/.roo/settings.json", Confidence: "Medium", Hash: "c3", Redacted: "sk-a...igAA"},
+				},
+			},
+		},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize(testResult())
+	if len(s.Findings) != 3 {
+		t.Fatalf("findings = %+v", s.Findings)
+	}
+	if f := s.Findings[0]; f.Provider != "openai" || f.Severity != policy.SeverityCritical || f.App != "roo-code" {
+		t.Errorf("first finding = %+v", f)
+	}
+	if f := s.Findings[2]; f.App != otherFiles || f.Line != 7 {
+		t.Errorf("last finding = %+v", f)
+	}
+	want := []Count{{"critical", 1}, {"high", 0}, {"medium", 1}, {"low", 1}}
+	if fmt.Sprint(s.BySeverity) != fmt.Sprint(want) {
+		t.Errorf("BySeverity = %v", s.BySeverity)
+	}
+	if want := []Count{{"roo-code", 2}, {otherFiles, 1}}; fmt.Sprint(s.ByApp) != fmt.Sprint(want) {
+		t.Errorf("ByApp = %v", s.ByApp)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	out, err := Render(testResult(), FormatHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(out)
+	for _, want := range []string{"<!DOCTYPE html>", "3</strong> credential findings", "By provider", "roo-code", "sk-a...igAA", "&lt;script&gt;.txt:7"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	if strings.Contains(page, fullValue) || strings.Contains(page, "<script>") {
+		t.Error("page holds a key value or unescaped text")
+	}
+
+	out, err = Render(&aicred.ScanResult{}, FormatHTML)
+	if err != nil || !bytes.Contains(out, []byte("0</strong> credential findings")) {
+		t.Errorf("empty report: %v\n%s", err, out)
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	result := testResult()
+	// Enough findings for a second page
+	for i := range 80 {
+		result.Keys = append(result.Keys, aicred.DiscoveredKey{Provider: "groq", Source: fmt.Sprintf("/srv/app%d/.env", i), Confidence: "High", Hash: fmt.Sprint(i), Redacted: "gsk_...(1)"})
+	}
+	out, err := Render(result, FormatPDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Error("not a PDF")
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) || !bytes.Contains(out, []byte(`gsk_...\(1\)`)) {
+		t.Error("PDF lacks the second page or escaped text")
+	}
+	if bytes.Contains(out, []byte(fullValue)) {
+		t.Error("PDF holds a key value")
+	}
+	// The cross-reference table points at each object
+	xref := bytes.LastIndex(out, []byte("startxref\n"))
+	var offset int
+	fmt.Sscan(string(out[xref+len("startxref\n"):]), &offset)
+	if !bytes.HasPrefix(out[offset:], []byte("xref\n")) {
+		t.Errorf("startxref %d does not point at the table", offset)
+	}
+	var first int
+	fmt.Sscanf(string(out[offset:]), "xref\n0 %d\n0000000000 65535 f \n%d", new(int), &first)
+	if !bytes.HasPrefix(out[first:], []byte("1 0 obj")) {
+		t.Errorf("object 1 is not at %d", first)
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if _, err := Render(testResult(), "docx"); !errors.Is(err, aicred.ErrInvalidOption) {
+		t.Errorf("err = %v", err)
+	}
+}
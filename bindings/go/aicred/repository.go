@@ -0,0 +1,335 @@
+package aicred
+
+import (
+	"fmt"
+	"iter"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Repository is a uniform view over one kind of stored record, so callers can
+// list, filter, page through or stream instances and label assignments the
+// same way
+type Repository[T any] interface {
+	// List returns every record
+	List() ([]T, error)
+	// Filter returns the records keep reports true for
+	Filter(keep func(T) bool) ([]T, error)
+	// Page returns up to limit records starting at offset, and the total
+	// number of records. A limit of 0 or less means no limit.
+	Page(offset, limit int) (page []T, total int, err error)
+	// All streams the records. A load failure is yielded once, with the
+	// zero T, and ends the sequence.
+	All() iter.Seq2[T, error]
+}
+
+// loadRepository implements Repository over a function that loads every
+// record
+type loadRepository[T any] struct {
+	load func() ([]T, error)
+}
+
+func (r loadRepository[T]) List() ([]T, error) {
+	return r.load()
+}
+
+func (r loadRepository[T]) Filter(keep func(T) bool) ([]T, error) {
+	records, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	var kept []T
+	for _, record := range records {
+		if keep(record) {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
+
+func (r loadRepository[T]) Page(offset, limit int) ([]T, int, error) {
+	records, err := r.load()
+	if err != nil {
+		return nil, 0, err
+	}
+	offset = min(max(offset, 0), len(records))
+	end := len(records)
+	if limit > 0 {
+		end = min(offset+limit, end)
+	}
+	return records[offset:end], len(records), nil
+}
+
+func (r loadRepository[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		records, err := r.load()
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// InstanceRepository is the Repository of a store's provider instances,
+// sorted by ID
+type InstanceRepository struct {
+	loadRepository[ProviderInstance]
+	store Store
+}
+
+// Instances returns a repository over the session's provider instances
+func (s *Session) Instances() *InstanceRepository {
+	return newInstanceRepository(s)
+}
+
+func newInstanceRepository(store Store) *InstanceRepository {
+	return &InstanceRepository{loadRepository: loadRepository[ProviderInstance]{store.LoadInstances}, store: store}
+}
+
+// Get returns the instance with the given ID, or an error wrapping
+// ErrNotFound
+func (r *InstanceRepository) Get(id string) (*ProviderInstance, error) {
+	return r.store.GetInstance(id)
+}
+
+// RequestDefaults returns the request defaults of the instance with the
+// given ID, as RequestDefaultsOf reads them
+func (r *InstanceRepository) RequestDefaults(id string) (RequestDefaults, error) {
+	instance, err := r.store.GetInstance(id)
+	if err != nil {
+		return RequestDefaults{}, err
+	}
+	return RequestDefaultsOf(*instance)
+}
+
+// ExpiringKeys returns the store's instances whose API key expires within
+// the given duration from now, as the package-level ExpiringKeys finds
+// them
+func (r *InstanceRepository) ExpiringKeys(within time.Duration) ([]ExpiringKey, error) {
+	instances, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	return ExpiringKeys(instances, within, time.Now()), nil
+}
+
+// HTTPClient returns the client for the instance with the given ID, as
+// the package-level HTTPClient builds it
+func (r *InstanceRepository) HTTPClient(id string) (*http.Client, error) {
+	instance, err := r.store.GetInstance(id)
+	if err != nil {
+		return nil, err
+	}
+	return HTTPClient(*instance)
+}
+
+// LabelRepository is the Repository of a store's label assignments
+type LabelRepository struct {
+	loadRepository[LabelAssignment]
+	store Store
+}
+
+// Labels returns a repository over the session's label assignments
+func (s *Session) Labels() *LabelRepository {
+	return newLabelRepository(s)
+}
+
+func newLabelRepository(store Store) *LabelRepository {
+	return &LabelRepository{loadRepository: loadRepository[LabelAssignment]{store.LoadLabels}, store: store}
+}
+
+// ForTarget returns the assignments made directly to target
+func (r *LabelRepository) ForTarget(target LabelTarget) ([]LabelAssignment, error) {
+	return r.Filter(func(a LabelAssignment) bool { return a.Target == target })
+}
+
+// Assign adds an assignment and saves the labels. Assigning a label to a
+// target it already has is a no-op.
+func (r *LabelRepository) Assign(assignment LabelAssignment) error {
+	assignments, err := r.List()
+	if err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		if a.LabelName == assignment.LabelName && a.Target == assignment.Target {
+			return nil
+		}
+	}
+	return r.store.SaveLabels(append(assignments, assignment))
+}
+
+// Unassign removes the label from target and saves the labels, or returns an
+// error wrapping ErrNotFound if it was not assigned there
+func (r *LabelRepository) Unassign(labelName string, target LabelTarget) error {
+	assignments, err := r.List()
+	if err != nil {
+		return err
+	}
+	kept := assignments[:0]
+	for _, a := range assignments {
+		if a.LabelName != labelName || a.Target != target {
+			kept = append(kept, a)
+		}
+	}
+	if len(kept) == len(assignments) {
+		return fmt.Errorf("label %q is not assigned to that target: %w", labelName, ErrNotFound)
+	}
+	return r.store.SaveLabels(kept)
+}
+
+// TagRepository is the Repository of a store's tags, sorted by name, with
+// their assignments. Tag assignments are kept apart from label
+// assignments, in tag_assignments.yaml as the CLI keeps them, so a tag
+// never becomes a label and each side sees the other's assignments.
+type TagRepository struct {
+	loadRepository[Tag]
+	store ConfigStore
+}
+
+// Tags returns a repository over the session's tags
+func (s *Session) Tags() *TagRepository {
+	return newTagRepository(s)
+}
+
+func newTagRepository(store ConfigStore) *TagRepository {
+	return &TagRepository{loadRepository: loadRepository[Tag]{store.LoadTags}, store: store}
+}
+
+// Get returns the tag with the given name, or an error wrapping
+// ErrNotFound
+func (r *TagRepository) Get(name string) (*Tag, error) {
+	tags, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if tag.Name == name {
+			return &tag, nil
+		}
+	}
+	return nil, fmt.Errorf("tag %q: %w", name, ErrNotFound)
+}
+
+// Save adds tag, or replaces the tag with its name, and saves the tags.
+// A replaced tag keeps its CreatedAt unless tag sets one, and a new tag
+// without one is stamped with the current time.
+func (r *TagRepository) Save(tag Tag) error {
+	if strings.TrimSpace(tag.Name) == "" {
+		return fmt.Errorf("tag has no name: %w", ErrInvalidOption)
+	}
+	tags, err := r.List()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(tags, func(t Tag) bool { return t.Name == tag.Name })
+	if tag.CreatedAt.IsZero() && i >= 0 {
+		tag.CreatedAt = tags[i].CreatedAt
+	}
+	if tag.CreatedAt.IsZero() {
+		tag.CreatedAt = time.Now().UTC()
+	}
+	if i >= 0 {
+		tags[i] = tag
+	} else {
+		tags = append(tags, tag)
+	}
+	return r.store.SaveTags(tags)
+}
+
+// Delete removes the tag and its assignments, or returns an error wrapping
+// ErrNotFound if there is no tag with that name. Tags under it keep their
+// Parent.
+func (r *TagRepository) Delete(name string) error {
+	tags, err := r.List()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(tags), func(t Tag) bool { return t.Name == name })
+	if len(kept) == len(tags) {
+		return fmt.Errorf("tag %q: %w", name, ErrNotFound)
+	}
+	assignments, err := r.store.LoadTagAssignments()
+	if err != nil {
+		return err
+	}
+	remaining := slices.DeleteFunc(slices.Clone(assignments), func(a LabelAssignment) bool { return a.LabelName == name })
+	// Assignments go first, so a failure leaves none without its tag
+	return batched(r.store, func() error {
+		if len(remaining) != len(assignments) {
+			if err := r.store.SaveTagAssignments(remaining); err != nil {
+				return err
+			}
+		}
+		return r.store.SaveTags(kept)
+	})
+}
+
+// Assign assigns the tag to target and saves the tag assignments.
+// Assigning a tag to a target it already has is a no-op, and a tag the
+// store does not define gives an error wrapping ErrNotFound.
+func (r *TagRepository) Assign(name string, target LabelTarget) error {
+	if _, err := r.Get(name); err != nil {
+		return err
+	}
+	assignments, err := r.store.LoadTagAssignments()
+	if err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		if a.LabelName == name && a.Target == target {
+			return nil
+		}
+	}
+	assignment := LabelAssignment{LabelName: name, Target: target, AssignedAt: time.Now().UTC()}
+	return r.store.SaveTagAssignments(append(assignments, assignment))
+}
+
+// Unassign removes the tag from target and saves the tag assignments, or
+// returns an error wrapping ErrNotFound if it was not assigned there
+func (r *TagRepository) Unassign(name string, target LabelTarget) error {
+	assignments, err := r.store.LoadTagAssignments()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(assignments), func(a LabelAssignment) bool {
+		return a.LabelName == name && a.Target == target
+	})
+	if len(kept) == len(assignments) {
+		return fmt.Errorf("tag %q is not assigned to that target: %w", name, ErrNotFound)
+	}
+	return r.store.SaveTagAssignments(kept)
+}
+
+// Assignments returns the assignments of the tag itself, not of the tags
+// under it
+func (r *TagRepository) Assignments(name string) ([]LabelAssignment, error) {
+	assignments, err := r.store.LoadTagAssignments()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(assignments, func(a LabelAssignment) bool { return a.LabelName != name }), nil
+}
+
+// Instances returns the instances with the tag, as Config.InstancesByTag
+// finds them
+func (r *TagRepository) Instances(name string) ([]ProviderInstance, error) {
+	config, err := CurrentConfig(r.store)
+	if err != nil {
+		return nil, err
+	}
+	return config.InstancesByTag(name), nil
+}
+
+var (
+	_ Repository[ProviderInstance] = (*InstanceRepository)(nil)
+	_ Repository[LabelAssignment]  = (*LabelRepository)(nil)
+	_ Repository[Tag]              = (*TagRepository)(nil)
+)
@@ -0,0 +1,234 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRepositories(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	instances := s.Instances()
+	var ids []string
+	for instance, err := range instances.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, instance.ID)
+	}
+	if len(ids) != 1 || ids[0] != "openai-main" {
+		t.Fatalf("All yielded %v", ids)
+	}
+	if inst, err := instances.Get("openai-main"); err != nil || inst.ID != "openai-main" {
+		t.Fatalf("Get = %+v, %v", inst, err)
+	}
+
+	labels := s.Labels()
+	model := LabelTarget{Type: LabelTargetModel, InstanceID: "openai-main", ModelID: "gpt-4o"}
+	instance := LabelTarget{Type: LabelTargetInstance, InstanceID: "openai-main"}
+	for _, a := range []LabelAssignment{
+		{LabelName: "fast", Target: model},
+		{LabelName: "prod", Target: instance},
+		{LabelName: "cheap", Target: model},
+		{LabelName: "fast", Target: model},
+	} {
+		if err := labels.Assign(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, total, err := labels.Page(1, 1)
+	if err != nil || total != 3 || len(page) != 1 || page[0].LabelName != "prod" {
+		t.Fatalf("Page(1, 1) = %+v, %d, %v", page, total, err)
+	}
+	if page, _, _ := labels.Page(2, 0); len(page) != 1 {
+		t.Errorf("Page(2, 0) = %+v, want the rest", page)
+	}
+	if page, _, _ := labels.Page(10, 5); len(page) != 0 {
+		t.Errorf("Page past the end = %+v", page)
+	}
+	onModel, err := labels.ForTarget(model)
+	if err != nil || len(onModel) != 2 {
+		t.Fatalf("ForTarget = %+v, %v", onModel, err)
+	}
+
+	var seen int
+	for range labels.All() {
+		seen++
+		break
+	}
+	if seen != 1 {
+		t.Errorf("All kept yielding after break")
+	}
+
+	if err := labels.Unassign("fast", model); err != nil {
+		t.Fatal(err)
+	}
+	if err := labels.Unassign("fast", model); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Unassign: err = %v, want ErrNotFound", err)
+	}
+
+	s.Close()
+	for _, err := range labels.All() {
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("All after Close: err = %v, want ErrClosed", err)
+		}
+	}
+}
+
+func TestTagRepositoryRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tags := s.Tags()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := tags.Save(Tag{Name: "prod", Description: "Production", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tags.Save(Tag{Name: "prod-eu", Parent: "prod", Metadata: map[string]string{"color": "#0000ff"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tags.Save(Tag{Name: "prod", Description: "Production traffic"}); err != nil {
+		t.Fatal(err)
+	}
+	model := LabelTarget{Type: LabelTargetModel, InstanceID: "openai-main", ModelID: "gpt-4o"}
+	if err := tags.Assign("prod-eu", model); err != nil {
+		t.Fatal(err)
+	}
+	if err := tags.Assign("prod-eu", model); err != nil {
+		t.Fatal(err)
+	}
+	if err := tags.Assign("staging", model); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Assign of an undefined tag: err = %v", err)
+	}
+	if err := tags.Save(Tag{}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Save of an unnamed tag: err = %v", err)
+	}
+
+	// Tags and their assignments are read back from tags.yaml and
+	// tag_assignments.yaml by a second session, and never become labels
+	reopened, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	again := reopened.Tags()
+	prod, err := again.Get("prod")
+	if err != nil || prod.Description != "Production traffic" || !prod.CreatedAt.Equal(created) {
+		t.Errorf("prod = %+v, %v", prod, err)
+	}
+	if eu, err := again.Get("prod-eu"); err != nil || eu.Parent != "prod" || eu.Metadata["color"] != "#0000ff" || eu.CreatedAt.IsZero() {
+		t.Errorf("prod-eu = %+v, %v", eu, err)
+	}
+	assignments, err := again.Assignments("prod-eu")
+	if err != nil || len(assignments) != 1 || assignments[0].Target != model || assignments[0].AssignedAt.IsZero() {
+		t.Errorf("assignments = %+v, %v", assignments, err)
+	}
+	if instances, err := again.Instances("prod"); err != nil || len(instances) != 1 || instances[0].ID != "openai-main" {
+		t.Errorf("Instances(prod) = %+v, %v", instances, err)
+	}
+	if labels, err := s.LoadLabels(); err != nil || len(labels) != 0 {
+		t.Errorf("labels = %+v, %v", labels, err)
+	}
+
+	// An assignment the CLI wrote is read too
+	cli := `- label_name: prod
+  target:
+    type: provider_instance
+    instance_id: openai-main
+  assigned_at: 2024-02-01T00:00:00Z
+  assigned_by: null
+`
+	if err := os.WriteFile(filepath.Join(ConfigDir(home), "tag_assignments.yaml"), []byte(cli), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	assignments, err = again.Assignments("prod")
+	if err != nil || len(assignments) != 1 || assignments[0].Target.InstanceID != "openai-main" || assignments[0].Target.Type != LabelTargetInstance {
+		t.Errorf("CLI assignments = %+v, %v", assignments, err)
+	}
+	if err := again.Assign("prod-eu", model); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := again.Delete("prod-eu"); err != nil {
+		t.Fatal(err)
+	}
+	if assignments, _ := again.Assignments("prod-eu"); len(assignments) != 0 {
+		t.Errorf("assignments of a deleted tag = %+v", assignments)
+	}
+	if err := again.Delete("prod-eu"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: err = %v", err)
+	}
+	if err := again.Unassign("prod-eu", model); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Unassign after Delete: err = %v", err)
+	}
+}
+
+func TestConfigAssignmentsRoundTrip(t *testing.T) {
+	assigned := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore([]ProviderInstance{{ID: "a", ProviderType: "openai", Models: []string{"gpt-4o"}}}, nil)
+	tags := newTagRepository(store)
+	if err := tags.Save(Tag{Name: "prod", Uniqueness: TagUniquePerInstance}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tags.Assign("prod", LabelTarget{Type: LabelTargetModel, InstanceID: "a", ModelID: "gpt-4o"}); err != nil {
+		t.Fatal(err)
+	}
+	labels := newLabelRepository(store)
+	if err := labels.Assign(LabelAssignment{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "a"}, AssignedAt: assigned, AssignedBy: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	current, err := CurrentConfig(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []ConfigFormat{ConfigYAML, ConfigJSON, ConfigTOML} {
+		data, err := MarshalConfigAs(current, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseConfigAs(data, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if cs := Plan(parsed, current); len(cs.Changes) != 0 {
+			t.Errorf("%s round trip changed:\n%s", format, cs)
+		}
+
+		// Applied to an empty store, the document gives the same assignments
+		fresh := NewMemoryStore(nil, nil)
+		if err := Apply(fresh, Plan(parsed, nil)); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := fresh.LoadLabels()
+		if len(got) != 1 {
+			t.Fatalf("%s: applied labels = %+v", format, got)
+		}
+		for _, label := range got {
+			if label.LabelName == "fast" && (!label.AssignedAt.Equal(assigned) || label.AssignedBy != "ops") {
+				t.Errorf("%s: fast = %+v", format, label)
+			}
+		}
+		if tagged, _ := fresh.LoadTagAssignments(); len(tagged) != 1 || tagged[0].Target.ModelID != "gpt-4o" {
+			t.Errorf("%s: applied tag assignments = %+v", format, tagged)
+		}
+		if got, _ := newTagRepository(fresh).Instances("prod"); len(got) != 1 {
+			t.Errorf("%s: tagged instances = %+v", format, got)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// DefaultRevisions is how many revisions a Session keeps when
+// SessionOptions.Revisions is zero
+const DefaultRevisions = 10
+
+const (
+	// revisionsDir holds one directory per revision in the configuration
+	// directory
+	revisionsDir = "revisions"
+	// revisionLayout names revisions by when they were taken, so they
+	// sort in order
+	revisionLayout = "20060102T150405.000000000Z"
+)
+
+// revisionFiles are the files a revision holds besides the instance files
+// in inference_services
+var revisionFiles = []string{"labels.yaml", "labels_metadata.yaml", "tags.yaml", "tag_assignments.yaml", "organizations.json"}
+
+// Revision is a snapshot of the configuration a Session took before
+// changing it
+type Revision struct {
+	// ID names the revision for RollbackTo
+	ID string `json:"id"`
+	// Time is when the snapshot was taken
+	Time time.Time `json:"time"`
+	// Instances is how many instances the snapshot holds, to spot the
+	// revision before instances went missing
+	Instances int `json:"instances"`
+}
+
+// keepRevisions returns how many revisions the session keeps
+func (s *Session) keepRevisions() int {
+	if s.revisions == 0 {
+		return DefaultRevisions
+	}
+	return max(s.revisions, 0)
+}
+
+// snapshot takes a revision of the configuration before a write, unless
+// revisions are off or the write is part of a batch that took one. It
+// returns an error wrapping ErrIO if the snapshot cannot be written, and
+// the write should not go ahead.
+func (s *Session) snapshot() error {
+	s.revMu.Lock()
+	defer s.revMu.Unlock()
+	if s.batching > 0 || s.keepRevisions() == 0 {
+		return nil
+	}
+	return s.snapshotLocked()
+}
+
+// batch runs fn with one snapshot for all of its writes, so that one Apply
+// is one revision however many files it changes
+func (s *Session) batch(fn func() error) error {
+	if err := s.snapshot(); err != nil {
+		return err
+	}
+	s.revMu.Lock()
+	s.batching++
+	s.revMu.Unlock()
+	defer func() {
+		s.revMu.Lock()
+		s.batching--
+		s.revMu.Unlock()
+	}()
+	return fn()
+}
+
+// snapshotLocked copies the configuration into a new revision and prunes
+// the oldest past keepRevisions. The caller holds s.revMu.
+func (s *Session) snapshotLocked() error {
+	config := ConfigDir(s.homeDir)
+	id := time.Now().UTC().Format(revisionLayout)
+	dir := filepath.Join(config, revisionsDir, id)
+	for n := 1; ; n++ {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		dir = filepath.Join(config, revisionsDir, id+"-"+strconv.Itoa(n))
+	}
+	if err := hardenConfigDir(filepath.Join(dir, "inference_services")); err != nil {
+		return fmt.Errorf("snapshot: %w: %v", ErrIO, err)
+	}
+	files, err := revisionContents(config)
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	for name, data := range files {
+		if err := writeBytesAtomic(filepath.Join(dir, filepath.FromSlash(name)), data); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("snapshot: %w: %v", ErrIO, err)
+		}
+	}
+
+	revisions, err := s.ListRevisions()
+	if err != nil {
+		return err
+	}
+	for _, old := range revisions[min(s.keepRevisions(), len(revisions)):] {
+		if err := os.RemoveAll(filepath.Join(config, revisionsDir, old.ID)); err != nil {
+			return fmt.Errorf("snapshot: prune %s: %w: %v", old.ID, ErrIO, err)
+		}
+	}
+	return nil
+}
+
+// revisionContents reads the files a revision of dir holds, by slash
+// path relative to dir
+func revisionContents(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	instances, err := filepath.Glob(filepath.Join(dir, "inference_services", "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIO, err)
+	}
+	names := slices.Clone(revisionFiles)
+	for _, p := range instances {
+		names = append(names, "inference_services/"+filepath.Base(p))
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrIO, err)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+// ListRevisions returns the revisions of the session's configuration,
+// newest first. The session snapshots its configuration before every
+// SaveInstance, DeleteInstance, SaveTags, SaveLabels, SaveOrganizations
+// and Restore, once for a whole Apply, and before RollbackTo, and keeps
+// SessionOptions.Revisions of them.
+func (s *Session) ListRevisions() ([]Revision, error) {
+	root := filepath.Join(ConfigDir(s.homeDir), revisionsDir)
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list revisions: %w: %v", ErrIO, err)
+	}
+	var revisions []Revision
+	for _, e := range entries {
+		stamp, _, _ := strings.Cut(e.Name(), "-")
+		t, err := time.Parse(revisionLayout, stamp)
+		if !e.IsDir() || err != nil {
+			continue
+		}
+		instances, _ := filepath.Glob(filepath.Join(root, e.Name(), "inference_services", "*.yaml"))
+		revisions = append(revisions, Revision{ID: e.Name(), Time: t, Instances: len(instances)})
+	}
+	slices.SortFunc(revisions, func(a, b Revision) int {
+		if c := b.Time.Compare(a.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return revisions, nil
+}
+
+// RollbackTo puts the configuration back as it was in the revision with
+// the given ID: its instances, labels, tags and organizations replace the
+// current ones, and files the revision did not have are removed. The
+// current configuration is snapshotted first, so a rollback can itself be
+// rolled back. The session is reloaded and StoreChanged events are
+// published. An unknown ID gives an error wrapping ErrNotFound.
+func (s *Session) RollbackTo(id string) error {
+	s.revMu.Lock()
+	defer s.revMu.Unlock()
+	revisions, err := s.ListRevisions()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(revisions, func(r Revision) bool { return r.ID == id }) {
+		return fmt.Errorf("rollback to %q: %w", id, ErrNotFound)
+	}
+	config := ConfigDir(s.homeDir)
+	// Read the revision before the snapshot can prune it
+	files, err := revisionContents(filepath.Join(config, revisionsDir, id))
+	if err != nil {
+		return fmt.Errorf("rollback to %q: %w", id, err)
+	}
+	before := s.auditBefore()
+	if s.keepRevisions() > 0 {
+		if err := s.snapshotLocked(); err != nil {
+			return fmt.Errorf("rollback to %q: %w", id, err)
+		}
+	}
+
+	current, err := revisionContents(config)
+	if err != nil {
+		return fmt.Errorf("rollback to %q: %w", id, err)
+	}
+	if err := hardenConfigDir(filepath.Join(config, "inference_services")); err != nil {
+		return fmt.Errorf("rollback to %q: %w: %v", id, ErrIO, err)
+	}
+	for name, data := range files {
+		if err := writeBytesAtomic(filepath.Join(config, filepath.FromSlash(name)), data); err != nil {
+			return fmt.Errorf("rollback to %q: %w: %v", id, ErrIO, err)
+		}
+	}
+	for name := range current {
+		if _, ok := files[name]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(config, filepath.FromSlash(name))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rollback to %q: %w: %v", id, ErrIO, err)
+		}
+	}
+
+	if err := s.Reload(); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	s.storesChanged(now)
+	if tags, err := s.LoadTags(); err == nil {
+		s.events.Publish(events.StoreChanged{Time: now, Store: "tags", Count: len(tags)})
+	}
+	return s.auditReplaced("RollbackTo", before)
+}
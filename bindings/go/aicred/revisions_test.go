@@ -0,0 +1,109 @@
+package aicred
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+func TestRevisions(t *testing.T) {
+	home := t.TempDir()
+	bus := events.New()
+	changed := map[string]int{}
+	defer events.Subscribe(bus, func(e events.StoreChanged) { changed[e.Store]++ })()
+	s, err := OpenSessionWith(home, SessionOptions{Events: bus, Revisions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir := filepath.Join(home, ".config", "aicred", "inference_services")
+
+	if revisions, err := s.ListRevisions(); err != nil || len(revisions) != 0 {
+		t.Errorf("ListRevisions before any save = %v, %v", revisions, err)
+	}
+	for _, id := range []string{"openai-a", "openai-b"} {
+		if err := s.SaveInstance(ProviderInstance{ID: id, ProviderType: "openai", BaseURL: "https://api.openai.com/v1", APIKey: NewSecretString("sk-" + id)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveTags([]Tag{{Name: "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := s.ListRevisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || revisions[0].Instances != 2 || revisions[2].Instances != 0 || !revisions[0].Time.After(revisions[2].Time) {
+		t.Fatalf("revisions = %+v, want three, newest first", revisions)
+	}
+	beforeWipe := revisions[0].ID
+
+	// A bulk update through Apply is one revision
+	current := &Config{Instances: []ProviderInstance{{ID: "openai-a"}, {ID: "openai-b"}}, Tags: []Tag{{Name: "prod"}}}
+	if err := Apply(s, Plan(&Config{Instances: []ProviderInstance{}, Tags: []Tag{}}, current)); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.yaml")); len(files) != 0 {
+		t.Fatalf("instances left after the wipe: %v", files)
+	}
+	revisions, _ = s.ListRevisions()
+	if len(revisions) != 3 || revisions[0].Instances != 2 || revisions[1].ID != beforeWipe {
+		t.Fatalf("after Apply: %+v, want one new revision with the two instances", revisions)
+	}
+
+	clear(changed)
+	if err := s.RollbackTo(revisions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	tags, _ := s.LoadTags()
+	if len(files) != 2 || len(tags) != 1 {
+		t.Errorf("after rollback: instance files %v, tags %v", files, tags)
+	}
+	if data, _ := os.ReadFile(files[0]); !strings.Contains(string(data), "sk-openai-") {
+		t.Error("rollback lost the API key")
+	}
+	if changed["instances"] != 1 || changed["tags"] != 1 {
+		t.Errorf("rollback events = %v", changed)
+	}
+	revisions, _ = s.ListRevisions()
+	if len(revisions) != 3 || revisions[0].Instances != 0 {
+		t.Errorf("the rollback should snapshot the wiped state first: %+v", revisions)
+	}
+
+	// Files the revision did not have are removed
+	if err := s.RollbackTo(revisions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.yaml")); len(files) != 0 {
+		t.Errorf("rolled back to no instances, have %v", files)
+	}
+	if tags, _ := s.LoadTags(); len(tags) != 0 {
+		t.Errorf("rolled back to no tags, have %v", tags)
+	}
+
+	for _, id := range []string{"missing", "../revisions"} {
+		if err := s.RollbackTo(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("RollbackTo(%q) = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestRevisionsOff(t *testing.T) {
+	home := t.TempDir()
+	s, err := OpenSessionWith(home, SessionOptions{Revisions: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.SaveTags([]Tag{{Name: "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, err := s.ListRevisions(); err != nil || len(revisions) != 0 {
+		t.Errorf("revisions with Revisions: -1 = %v, %v", revisions, err)
+	}
+}
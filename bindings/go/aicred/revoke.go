@@ -0,0 +1,198 @@
+package aicred
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Revocation says how to revoke a provider's leaked keys
+type Revocation struct {
+	Provider string
+	// ConsoleURL is the page where the provider's keys are revoked by hand
+	ConsoleURL string
+	// Instructions say what to do there, or what else to do
+	Instructions string
+	// API reports whether Revoke can revoke the provider's keys
+	API bool
+}
+
+// revocations are the providers RevocationInfo knows, by provider name
+var revocations = map[string]Revocation{
+	"openai": {
+		ConsoleURL:   "https://platform.openai.com/api-keys",
+		Instructions: "Delete the key in the project it belongs to, or call Revoke with an admin key.",
+		API:          true,
+	},
+	"anthropic": {
+		ConsoleURL:   "https://console.anthropic.com/settings/keys",
+		Instructions: "Find the key by its hint and delete it. Keys in a workspace are listed under that workspace.",
+	},
+	"groq": {
+		ConsoleURL:   "https://console.groq.com/keys",
+		Instructions: "Delete the key.",
+	},
+	"huggingface": {
+		ConsoleURL:   "https://huggingface.co/settings/tokens",
+		Instructions: "Invalidate and refresh the token, or delete it. Organization API tokens are managed in the organization's settings.",
+	},
+	"openrouter": {
+		ConsoleURL:   "https://openrouter.ai/settings/keys",
+		Instructions: "Delete the key.",
+	},
+	ProviderGitHubModels: {
+		ConsoleURL:   "https://github.com/settings/tokens",
+		Instructions: "Delete personal access tokens here. Revoke gh CLI logins under Settings > Applications > Authorized OAuth Apps, or run gh auth logout.",
+	},
+	ProviderGitHubCopilot: {
+		ConsoleURL:   "https://github.com/settings/applications",
+		Instructions: "Revoke the editor plugin under Authorized OAuth Apps or Authorized GitHub Apps, then sign in again.",
+	},
+}
+
+// RevocationInfo returns how to revoke keys of provider after a leak: the
+// console page to do it by hand and whether Revoke can do it by API. The
+// second result is false for providers without a revocation page, such as
+// self-hosted Ollama and LiteLLM, whose keys are rotated in their own
+// configuration.
+func RevocationInfo(provider string) (Revocation, bool) {
+	r, ok := revocations[strings.ToLower(provider)]
+	r.Provider = provider
+	return r, ok
+}
+
+// Revoke revokes a leaked key through its provider's API, authenticating
+// with adminCred. It supports OpenAI project keys, which need an OpenAI
+// admin key: the key is found among the organization's project keys by
+// its redacted form, or only in the project InspectKey recorded in
+// key.Scope, and deleted.
+//
+// The key needs its value, from a scan with RedactionNone. Revoke returns
+// an error wrapping ErrInvalidOption for providers RevocationInfo reports
+// no API for and for keys without a value, ErrNotFound if the key is not
+// among the organization's, ErrAmbiguous if more than one key fits its
+// redacted form, in which case nothing is deleted, ErrPermissionDenied if
+// adminCred is rejected, and ErrIO if the provider cannot be reached.
+func Revoke(ctx context.Context, key DiscoveredKey, adminCred Secret) error {
+	if key.Value.IsZero() {
+		return fmt.Errorf("revoke %s key from %s: no value: %w", key.Provider, key.Source, ErrInvalidOption)
+	}
+	if adminCred.IsZero() {
+		return fmt.Errorf("revoke %s key from %s: no admin credential: %w", key.Provider, key.Source, ErrInvalidOption)
+	}
+	var err error
+	switch strings.ToLower(key.Provider) {
+	case "openai":
+		err = revokeOpenAIKey(ctx, key, adminCred)
+	default:
+		return fmt.Errorf("revoke %s key: the provider has no revocation API; see RevocationInfo: %w", key.Provider, ErrInvalidOption)
+	}
+	if err != nil {
+		return fmt.Errorf("revoke %s key from %s: %w", key.Provider, key.Source, err)
+	}
+	logger().Info("revoked key", slog.String("provider", key.Provider), slog.String("source", key.Source), slog.String("redacted", key.Redacted))
+	return nil
+}
+
+// openAIList is a page of an OpenAI administration API list
+type openAIList struct {
+	Data []struct {
+		ID            string `json:"id"`
+		RedactedValue string `json:"redacted_value"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// revokeOpenAIKey finds the project key whose redacted value fits the key
+// and deletes it, unless several keys fit
+func revokeOpenAIKey(ctx context.Context, key DiscoveredKey, adminCred Secret) error {
+	auth := func(h http.Header) { h.Set("Authorization", "Bearer "+adminCred.Reveal()) }
+	var projects []string
+	if key.Scope != nil && key.Scope.Project != "" {
+		projects = []string{key.Scope.Project}
+	} else {
+		err := openAIPages(ctx, "/v1/organization/projects", auth, func(page *openAIList) bool {
+			for _, p := range page.Data {
+				projects = append(projects, p.ID)
+			}
+			return false
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Hints show a few characters at each end, so different keys can share
+	// one. Every project is searched before anything is deleted.
+	type match struct{ project, keyID string }
+	var matches []match
+	for _, project := range projects {
+		keys := "/v1/organization/projects/" + url.PathEscape(project) + "/api_keys"
+		err := openAIPages(ctx, keys, auth, func(page *openAIList) bool {
+			for _, k := range page.Data {
+				if matchesKeyHint(key.Value, k.RedactedValue) {
+					matches = append(matches, match{project, k.ID})
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("the key is in none of the organization's %d projects: %w", len(projects), ErrNotFound)
+	}
+	if len(matches) > 1 {
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.project + "/" + m.keyID
+		}
+		return fmt.Errorf("%d keys fit the hint (%s); delete the right one by hand: %w", len(matches), strings.Join(ids, ", "), ErrAmbiguous)
+	}
+
+	project, keyID := matches[0].project, matches[0].keyID
+	keys := "/v1/organization/projects/" + url.PathEscape(project) + "/api_keys"
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, openAIEndpoint+keys+"/"+url.PathEscape(keyID), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOption, err)
+	}
+	auth(req.Header)
+	resp, err := inspectClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIO, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("delete key %s in project %s: %w", keyID, project, inspectStatusError(resp))
+	}
+	return nil
+}
+
+// openAIPages calls visit with each page of an administration API list
+// until it returns true or the pages run out
+func openAIPages(ctx context.Context, path string, auth func(http.Header), visit func(*openAIList) bool) error {
+	after := ""
+	for {
+		u := openAIEndpoint + path + "?limit=100"
+		if after != "" {
+			u += "&after=" + url.QueryEscape(after)
+		}
+		resp, err := inspectRequest(ctx, u, auth)
+		if err != nil {
+			return err
+		}
+		var page openAIList
+		if err := decodeInspectResponse(resp, &page); err != nil {
+			return err
+		}
+		if visit(&page) || !page.HasMore || page.LastID == "" {
+			return nil
+		}
+		after = page.LastID
+	}
+}
@@ -0,0 +1,110 @@
+package aicred
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRevocationInfo(t *testing.T) {
+	for _, provider := range []string{"openai", "anthropic", "groq", "huggingface", "openrouter", ProviderGitHubModels, ProviderGitHubCopilot} {
+		r, ok := RevocationInfo(provider)
+		if !ok || r.Provider != provider || r.ConsoleURL == "" || r.Instructions == "" {
+			t.Errorf("RevocationInfo(%q) = %+v, %v", provider, r, ok)
+		}
+		if r.API != (provider == "openai") {
+			t.Errorf("%s: API = %v", provider, r.API)
+		}
+	}
+	if r, ok := RevocationInfo("OpenAI"); !ok || r.Provider != "OpenAI" {
+		t.Errorf("provider names are not case-insensitive: %+v", r)
+	}
+	if _, ok := RevocationInfo("ollama"); ok {
+		t.Error("ollama has a revocation page")
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	const admin = "sk-admin-testAdminKey00000000"
+	var deleted, twin atomic.Value
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/organization/projects", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after") == "" {
+			w.Write([]byte(`{"object":"list","data":[{"id":"proj_internal","object":"organization.project"}],"has_more":true,"last_id":"proj_internal"}`))
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"proj_chatbot","object":"organization.project"}],"has_more":false,"last_id":"proj_chatbot"}`))
+	})
+	mux.HandleFunc("GET /v1/organization/projects/{project}/api_keys", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("project") == "proj_chatbot" {
+			w.Write([]byte(`{"object":"list","data":[{"id":"key_other","redacted_value":"sk-proj-Zzz...0000"},{"id":"key_leaked","redacted_value":"sk-proj-AbC...UvWx"}],"has_more":false}`))
+			return
+		}
+		if twin.Load() == true {
+			w.Write([]byte(`{"object":"list","data":[{"id":"key_twin","redacted_value":"sk-proj-AbC...UvWx"}],"has_more":false}`))
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[],"has_more":false}`))
+	})
+	mux.HandleFunc("DELETE /v1/organization/projects/{project}/api_keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		deleted.Store(r.PathValue("project") + "/" + r.PathValue("key"))
+		w.Write([]byte(`{"object":"organization.project.api_key.deleted","id":"` + r.PathValue("key") + `","deleted":true}`))
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+admin {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	saved, savedClient := openAIEndpoint, inspectClient
+	t.Cleanup(func() { openAIEndpoint, inspectClient = saved, savedClient })
+	openAIEndpoint, inspectClient = srv.URL, srv.Client()
+	ctx := context.Background()
+
+	leaked := DiscoveredKey{Provider: "openai", Source: ".env", Value: NewSecretString(projectKey)}
+	if err := Revoke(ctx, leaked, NewSecretString(admin)); err != nil {
+		t.Fatal(err)
+	}
+	if got := deleted.Load(); got != "proj_chatbot/key_leaked" {
+		t.Errorf("deleted %v", got)
+	}
+
+	// A hint that fits keys in two projects deletes neither
+	deleted.Store("")
+	twin.Store(true)
+	if err := Revoke(ctx, leaked, NewSecretString(admin)); !errors.Is(err, ErrAmbiguous) || !strings.Contains(err.Error(), "proj_internal/key_twin") {
+		t.Errorf("two matching keys: err = %v", err)
+	}
+	if got := deleted.Load(); got != "" {
+		t.Errorf("deleted %v with an ambiguous hint", got)
+	}
+	twin.Store(false)
+
+	// A scope from InspectKey narrows the search to its project
+	leaked.Scope = &KeyScope{Project: "proj_internal"}
+	if err := Revoke(ctx, leaked, NewSecretString(admin)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("key outside the inspected project: err = %v", err)
+	}
+	leaked.Scope = nil
+
+	for name, tc := range map[string]struct {
+		key   DiscoveredKey
+		admin Secret
+		want  error
+	}{
+		"rejected admin key": {leaked, NewSecretString("sk-admin-wrong"), ErrPermissionDenied},
+		"no admin key":       {leaked, Secret{}, ErrInvalidOption},
+		"no value":           {DiscoveredKey{Provider: "openai"}, NewSecretString(admin), ErrInvalidOption},
+		"no API":             {DiscoveredKey{Provider: "groq", Value: NewSecretString("gsk_0000000000000000000000")}, NewSecretString(admin), ErrInvalidOption},
+	} {
+		if err := Revoke(ctx, tc.key, tc.admin); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package aicred
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// MetadataWeight is the instance metadata key holding the instance's
+	// weight under RouteWeighted when the route sets none, such as "3"
+	MetadataWeight = "weight"
+	// MetadataCost is the instance metadata key holding the instance's
+	// relative cost under RouteLeastCost when the route sets none, such as
+	// the USD price per million tokens
+	MetadataCost = "cost"
+)
+
+// RoutingPolicy is how a Router chooses among the instance/model pairs a
+// label resolves to
+type RoutingPolicy string
+
+// Routing policies
+const (
+	// RouteFirst always picks the first pair, in instance order; it is
+	// the policy of labels without a route
+	RouteFirst RoutingPolicy = "first"
+	// RouteRoundRobin picks each pair in turn
+	RouteRoundRobin RoutingPolicy = "round_robin"
+	// RouteWeighted spreads picks in proportion to instance weights,
+	// interleaving them smoothly rather than in runs
+	RouteWeighted RoutingPolicy = "weighted"
+	// RouteLeastLatency picks the pair whose instance has the lowest
+	// latency reported to ObserveLatency. Instances without reports are
+	// picked first so they get measured.
+	RouteLeastLatency RoutingPolicy = "least_latency"
+	// RouteLeastCost picks the pair whose instance is cheapest. Instances
+	// without a cost are picked last.
+	RouteLeastCost RoutingPolicy = "least_cost"
+)
+
+// Route configures how a Router picks for one label
+type Route struct {
+	Policy RoutingPolicy
+	// Weights are instance weights for RouteWeighted, by instance ID. An
+	// instance missing here uses its MetadataWeight, or 1; a weight of 0
+	// takes it out of rotation.
+	Weights map[string]int
+	// Costs are instance costs for RouteLeastCost, by instance ID. An
+	// instance missing here uses its MetadataCost.
+	Costs map[string]float64
+}
+
+// LabelResolver resolves a label to the instance/model pairs it applies
+// to; LabelCache is one
+type LabelResolver interface {
+	Resolve(label string) ([]Selection, error)
+}
+
+// latencyAlpha is the weight an ObserveLatency report gets in the moving
+// average, so a few slow responses do not swing routing
+const latencyAlpha = 0.2
+
+// Router load-balances a label across the pairs it resolves to, following
+// the label's Route. Round-robin positions, weighted-rotation credits,
+// latency averages and rate limits are kept in process and reset when it is discarded. A
+// Router is safe for concurrent use.
+type Router struct {
+	resolver LabelResolver
+
+	mu      sync.Mutex
+	routes  map[string]Route
+	next    map[string]int
+	credits map[string]map[string]int
+	latency map[string]float64
+	limits  map[string]RateLimit
+	now     func() time.Time
+}
+
+// NewRouter returns a Router over resolver with no routes, so every label
+// uses RouteFirst until SetRoute
+func NewRouter(resolver LabelResolver) *Router {
+	return &Router{
+		resolver: resolver,
+		routes:   map[string]Route{},
+		next:     map[string]int{},
+		credits:  map[string]map[string]int{},
+		latency:  map[string]float64{},
+		limits:   map[string]RateLimit{},
+		now:      time.Now,
+	}
+}
+
+// Router returns a Router over a LabelCache of the Client's store; see
+// Client.LabelCache
+func (c *Client) Router(ttl time.Duration) *Router {
+	return NewRouter(c.LabelCache(ttl))
+}
+
+// SetRoute sets the route for label and resets its rotation. It returns an
+// error wrapping ErrInvalidOption for an unknown policy or a negative
+// weight.
+func (r *Router) SetRoute(label string, route Route) error {
+	switch route.Policy {
+	case RouteFirst, RouteRoundRobin, RouteWeighted, RouteLeastLatency, RouteLeastCost:
+	case "":
+		route.Policy = RouteFirst
+	default:
+		return fmt.Errorf("label %q: unknown routing policy %q: %w", label, route.Policy, ErrInvalidOption)
+	}
+	for id, w := range route.Weights {
+		if w < 0 {
+			return fmt.Errorf("label %q: instance %q has negative weight %d: %w", label, id, w, ErrInvalidOption)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[label] = route
+	delete(r.next, label)
+	delete(r.credits, label)
+	return nil
+}
+
+// Route returns the route set for label, or a RouteFirst route
+func (r *Router) Route(label string) Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if route, ok := r.routes[label]; ok {
+		return route
+	}
+	return Route{Policy: RouteFirst}
+}
+
+// ObserveLatency records how long a request to the instance took, for
+// RouteLeastLatency
+func (r *Router) ObserveLatency(instanceID string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ms := float64(d) / float64(time.Millisecond)
+	if avg, ok := r.latency[instanceID]; ok {
+		ms = avg + latencyAlpha*(ms-avg)
+	}
+	r.latency[instanceID] = ms
+}
+
+// Pick resolves label and picks one of its pairs by the label's route,
+// skipping instances that ReportUsage found throttled. It returns an error
+// wrapping ErrNotFound when the label applies to nothing or every instance
+// is weighted 0, and ErrThrottled when every instance is throttled.
+func (r *Router) Pick(label string) (Selection, error) {
+	selections, err := r.resolver.Resolve(label)
+	if err != nil {
+		return Selection{}, err
+	}
+	if len(selections) == 0 {
+		return Selection{}, fmt.Errorf("label %q: %w", label, ErrNotFound)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if selections = r.available(selections); len(selections) == 0 {
+		return Selection{}, fmt.Errorf("label %q: %w", label, ErrThrottled)
+	}
+	route := r.routes[label]
+	var i int
+	switch route.Policy {
+	case RouteRoundRobin:
+		i = r.next[label] % len(selections)
+		r.next[label] = i + 1
+	case RouteWeighted:
+		i = r.pickWeighted(label, route, selections)
+	case RouteLeastLatency:
+		i = argmin(selections, func(s Selection) float64 {
+			if ms, ok := r.latency[s.Instance.ID]; ok {
+				return ms
+			}
+			return -1
+		})
+	case RouteLeastCost:
+		i = argmin(selections, func(s Selection) float64 { return route.cost(s.Instance) })
+	}
+	if i < 0 {
+		return Selection{}, fmt.Errorf("label %q: every instance has weight 0: %w", label, ErrNotFound)
+	}
+	return selections[i], nil
+}
+
+// pickWeighted is smooth weighted round-robin: every pair gains its
+// weight in credit, the richest is picked and pays the total back. Pairs
+// that stopped resolving lose their credit. The caller holds r.mu.
+func (r *Router) pickWeighted(label string, route Route, selections []Selection) int {
+	credits := r.credits[label]
+	fresh := make(map[string]int, len(selections))
+	best, bestKey, total := -1, "", 0
+	for i, s := range selections {
+		w := route.weight(s.Instance)
+		if w == 0 {
+			continue
+		}
+		key := s.Instance.ID + "\x00" + s.Model
+		fresh[key] = credits[key] + w
+		total += w
+		if best < 0 || fresh[key] > fresh[bestKey] {
+			best, bestKey = i, key
+		}
+	}
+	if best >= 0 {
+		fresh[bestKey] -= total
+	}
+	r.credits[label] = fresh
+	return best
+}
+
+func (route Route) weight(instance ProviderInstance) int {
+	if w, ok := route.Weights[instance.ID]; ok {
+		return w
+	}
+	if w, err := strconv.Atoi(instance.Metadata[MetadataWeight]); err == nil && w >= 0 {
+		return w
+	}
+	return 1
+}
+
+func (route Route) cost(instance ProviderInstance) float64 {
+	if c, ok := route.Costs[instance.ID]; ok && !math.IsNaN(c) {
+		return c
+	}
+	if c, err := strconv.ParseFloat(instance.Metadata[MetadataCost], 64); err == nil && !math.IsNaN(c) {
+		return c
+	}
+	return math.Inf(1)
+}
+
+// argmin returns the index of the selection with the lowest value, the
+// first on ties
+func argmin(selections []Selection, value func(Selection) float64) int {
+	values := make([]float64, len(selections))
+	for i, s := range selections {
+		values[i] = value(s)
+	}
+	return slices.Index(values, slices.Min(values))
+}
@@ -0,0 +1,101 @@
+package aicred
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func routingCache(t *testing.T) *LabelCache {
+	t.Helper()
+	store := NewMemoryStore([]ProviderInstance{
+		{ID: "a", Models: []string{"m"}},
+		{ID: "b", Models: []string{"m"}, Metadata: map[string]string{MetadataWeight: "2", MetadataCost: "0.5"}},
+		{ID: "c", Models: []string{"m"}, Metadata: map[string]string{MetadataCost: "3"}},
+	}, []LabelAssignment{
+		{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "a"}},
+		{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "b"}},
+		{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "c"}},
+	})
+	c := NewLabelCache(store, LabelCacheOptions{})
+	t.Cleanup(c.Close)
+	return c
+}
+
+func picks(t *testing.T, r *Router, label string, n int) string {
+	t.Helper()
+	var ids []string
+	for range n {
+		s, err := r.Pick(label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.Instance.ID)
+	}
+	return strings.Join(ids, "")
+}
+
+func TestRouterPolicies(t *testing.T) {
+	r := NewRouter(routingCache(t))
+	if got := picks(t, r, "fast", 3); got != "aaa" {
+		t.Errorf("without a route: %s", got)
+	}
+	if _, err := r.Pick("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Pick(missing): err = %v", err)
+	}
+
+	for _, tc := range []struct {
+		route Route
+		want  string
+	}{
+		{Route{Policy: RouteRoundRobin}, "abcabca"},
+		// b's weight comes from its metadata
+		{Route{Policy: RouteWeighted}, "bacbbac"},
+		{Route{Policy: RouteWeighted, Weights: map[string]int{"a": 0, "c": 3}}, "cbcbccb"},
+		{Route{Policy: RouteLeastCost}, "bbbbbbb"},
+		{Route{Policy: RouteLeastCost, Costs: map[string]float64{"a": 0.1}}, "aaaaaaa"},
+	} {
+		if err := r.SetRoute("fast", tc.route); err != nil {
+			t.Fatal(err)
+		}
+		if got := picks(t, r, "fast", 7); got != tc.want {
+			t.Errorf("%s %v: %s, want %s", tc.route.Policy, tc.route.Weights, got, tc.want)
+		}
+	}
+
+	if err := r.SetRoute("fast", Route{Policy: RouteWeighted, Weights: map[string]int{"a": 0, "b": 0, "c": 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Pick("fast"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("all weights 0: err = %v", err)
+	}
+	if err := r.SetRoute("fast", Route{Policy: "random"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("unknown policy: err = %v", err)
+	}
+	if err := r.SetRoute("fast", Route{Policy: RouteWeighted, Weights: map[string]int{"a": -1}}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("negative weight: err = %v", err)
+	}
+}
+
+func TestRouterLeastLatency(t *testing.T) {
+	r := NewRouter(routingCache(t))
+	if err := r.SetRoute("fast", Route{Policy: RouteLeastLatency}); err != nil {
+		t.Fatal(err)
+	}
+	r.ObserveLatency("a", 300*time.Millisecond)
+	r.ObserveLatency("b", 100*time.Millisecond)
+	// c has no reports yet, so it is measured first
+	if got := picks(t, r, "fast", 1); got != "c" {
+		t.Errorf("before c is measured: %s", got)
+	}
+	r.ObserveLatency("c", 200*time.Millisecond)
+	if got := picks(t, r, "fast", 1); got != "b" {
+		t.Errorf("fastest: %s", got)
+	}
+	// One slow response moves b's average to 280ms, behind c
+	r.ObserveLatency("b", 1000*time.Millisecond)
+	if got := picks(t, r, "fast", 1); got != "c" {
+		t.Errorf("after b slowed: %s", got)
+	}
+}
@@ -0,0 +1,209 @@
+package aicred
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Names the Go-side passes report under in ScanStats
+const (
+	passArchives       = "archives"
+	passBinaryConfigs  = "binary-configs"
+	passBrowserStorage = "browser-storage"
+	passGitHubCLI      = "github-cli"
+	passInfraState     = "infra-state"
+	passPackageConfigs = "package-configs"
+	passRegistry       = "registry"
+)
+
+// scanRun is the state one Scan call shares among its Go-side passes. A nil
+// *scanRun has no cache and no deadline, and counts nothing.
+type scanRun struct {
+	cache    *scanCache
+	deadline time.Time
+
+	mu       sync.Mutex
+	stats    ScanStats
+	warnings []ScanWarning
+}
+
+// newScanRun starts the clock for GlobalTimeout and loads the incremental
+// cache when a pass that uses it is enabled
+func newScanRun(options ScanOptions, start time.Time) *scanRun {
+	run := &scanRun{}
+	if options.GlobalTimeout > 0 {
+		run.deadline = start.Add(options.GlobalTimeout)
+	}
+	if options.Incremental && (options.ScanArchives || options.ScanBinaryConfigs || options.ScanInfraState || options.ScanPackageConfigs) {
+		run.cache = loadScanCache(options)
+	}
+	return run
+}
+
+func (r *scanRun) deadlineOrZero() time.Time {
+	if r == nil {
+		return time.Time{}
+	}
+	return r.deadline
+}
+
+func (r *scanRun) cacheOrNil() *scanCache {
+	if r == nil {
+		return nil
+	}
+	return r.cache
+}
+
+// expired reports whether GlobalTimeout has run out
+func (r *scanRun) expired() bool {
+	d := r.deadlineOrZero()
+	return !d.IsZero() && !time.Now().Before(d)
+}
+
+// scanFiles runs scan over the candidate paths of the named pass with the
+// cache, PerFileTimeout, deadline and Concurrency applied
+func (r *scanRun) scanFiles(pass string, paths []string, options ScanOptions, scan func(p string) []DiscoveredKey) []DiscoveredKey {
+	scan = r.counted(scan)
+	scan = r.cacheOrNil().wrap(scan, options.redactionLevel() == RedactionNone)
+	scan = r.timeLimited(pass, scan, options.PerFileTimeout)
+	return scanParallel(paths, options.Concurrency, r.deadlineOrZero(), scan)
+}
+
+// timeLimited gives each call of scan at most limit, or until the deadline
+// if that is sooner, and logs and skips files that take longer. A read
+// blocked in the kernel cannot be interrupted, so its goroutine finishes in
+// the background; the scan just stops waiting for it.
+func (r *scanRun) timeLimited(pass string, scan func(p string) []DiscoveredKey, limit time.Duration) func(p string) []DiscoveredKey {
+	deadline := r.deadlineOrZero()
+	if limit <= 0 && deadline.IsZero() {
+		return scan
+	}
+	return func(p string) []DiscoveredKey {
+		wait := limit
+		if !deadline.IsZero() && (wait <= 0 || time.Until(deadline) < wait) {
+			wait = time.Until(deadline)
+		}
+		done := make(chan []DiscoveredKey, 1)
+		go func() { done <- scan(p) }()
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case keys := <-done:
+			return keys
+		case <-timer.C:
+			logger().Warn("skipping file that timed out", slog.String("path", p), slog.Duration("timeout", wait))
+			r.warn(pass, p, WarningTimeout, fmt.Errorf("not scanned within %s", wait))
+			return nil
+		}
+	}
+}
+
+// withDeadline runs fn, giving up with ErrTimeout if the deadline passes
+// first. fn keeps running in the background and must own its resources.
+func withDeadline(op string, deadline time.Time, fn func() error) error {
+	if deadline.IsZero() {
+		return fn()
+	}
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%s: %w", op, ErrTimeout)
+	}
+}
+
+// counted records each file scan reads; files the cache answers for are not
+// read and so not counted
+func (r *scanRun) counted(scan func(p string) []DiscoveredKey) func(p string) []DiscoveredKey {
+	if r == nil {
+		return scan
+	}
+	return func(p string) []DiscoveredKey {
+		var size int64
+		if info, err := os.Stat(p); err == nil {
+			size = info.Size()
+		}
+		r.read(size)
+		return scan(p)
+	}
+}
+
+// read records a file of size bytes read by a pass that does its own
+// reading, such as the browser storage pass
+func (r *scanRun) read(size int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.stats.FilesExamined++
+	r.stats.BytesRead += size
+	r.mu.Unlock()
+}
+
+// timed runs the named pass and records how long it took
+func (r *scanRun) timed(pass string, fn func() []DiscoveredKey) []DiscoveredKey {
+	start := time.Now()
+	keys := fn()
+	if r != nil {
+		r.mu.Lock()
+		if r.stats.DurationMSByScanner == nil {
+			r.stats.DurationMSByScanner = map[string]int64{}
+		}
+		r.stats.DurationMSByScanner[pass] += time.Since(start).Milliseconds()
+		r.mu.Unlock()
+	}
+	return keys
+}
+
+// warn records a problem the named pass met with path. Files over a size
+// limit count as skipped, anything else as an error for the pass.
+func (r *scanRun) warn(pass, path string, kind WarningKind, err error) {
+	logger().Debug("scan warning", slog.String("pass", pass), slog.String("path", path), slog.String("kind", string(kind)), slog.String("error", err.Error()))
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if kind == WarningTooLarge {
+		r.stats.SkippedBySize++
+	} else {
+		if r.stats.ErrorsByScanner == nil {
+			r.stats.ErrorsByScanner = map[string]int64{}
+		}
+		r.stats.ErrorsByScanner[pass]++
+	}
+	r.warnings = append(r.warnings, ScanWarning{Scanner: pass, Path: path, Kind: kind, Message: err.Error()})
+}
+
+// addTo adds what the Go-side passes counted and met to result
+func (r *scanRun) addTo(result *ScanResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := &result.Stats
+	stats.FilesExamined += r.stats.FilesExamined
+	stats.BytesRead += r.stats.BytesRead
+	stats.SkippedBySize += r.stats.SkippedBySize
+	for pass, n := range r.stats.ErrorsByScanner {
+		if stats.ErrorsByScanner == nil {
+			stats.ErrorsByScanner = map[string]int64{}
+		}
+		stats.ErrorsByScanner[pass] += n
+	}
+	for pass, ms := range r.stats.DurationMSByScanner {
+		if stats.DurationMSByScanner == nil {
+			stats.DurationMSByScanner = map[string]int64{}
+		}
+		stats.DurationMSByScanner[pass] += ms
+	}
+	result.Warnings = append(result.Warnings, r.warnings...)
+}
@@ -0,0 +1,146 @@
+package aicred
+
+/*
+#include <stdlib.h>
+#include "../../../ffi/include/genai_keyfinder.h"
+*/
+import "C"
+import (
+	"log/slog"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// ScanPages is a scan whose result is delivered in pages of bounded size, so
+// home directories with thousands of findings are never held as one giant
+// FFI response. Use it like sql.Rows:
+//
+//	pages, err := aicred.ScanPaged(opts, 500)
+//	if err != nil {
+//		return err
+//	}
+//	defer pages.Close()
+//	for pages.Next() {
+//		page := pages.Page()
+//		...
+//	}
+//	return pages.Err()
+//
+// Each page is a ScanResult holding a slice of one scanner's keys or config
+// instances; HomeDir, ScannedAt and ProvidersScanned repeat on every page,
+// and the last page carries the finished scan's Stats and Warnings. Each
+// scanner's keys come before its config instances. A ScanPages is safe for
+// concurrent use, but pages are only meaningful when read in order by one
+// goroutine.
+//
+// The core library scans on its own thread, one scanner ahead of the pages
+// read, so neither side holds every finding at once. Close stops a scan
+// still running once the scanners it has started finish.
+type ScanPages struct {
+	mu     sync.Mutex
+	cursor *C.AicredScanCursor
+	level  RedactionLevel
+	page   *ScanResult
+	done   bool
+	err    error
+}
+
+// scanPage is the page shape returned by aicred_scan_next
+type scanPage struct {
+	ScanResult
+	Done bool `json:"done"`
+}
+
+// ScanPaged starts a paged scan returning at most pageSize keys or config
+// instances per page. A pageSize of 0 or less uses the library default. The
+// scan itself starts on the first call to Next.
+func ScanPaged(options ScanOptions, pageSize int) (*ScanPages, error) {
+	if err := validateHomeDir(options.HomeDir); err != nil {
+		return nil, err
+	}
+	level, optionsJSON, err := prepareScan(options)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize < 0 {
+		pageSize = 0
+	}
+
+	homeDir := C.CString(options.HomeDir)
+	defer C.free(unsafe.Pointer(homeDir))
+	optionsStr := C.CString(string(optionsJSON))
+	defer C.free(unsafe.Pointer(optionsStr))
+
+	cursor := C.aicred_scan_begin(homeDir, optionsStr, C.uintptr_t(pageSize))
+	if cursor == nil {
+		return nil, &Error{Op: "scan", Code: CodeUnknown, Message: "invalid scan arguments"}
+	}
+	p := &ScanPages{cursor: cursor, level: level}
+	runtime.SetFinalizer(p, (*ScanPages).Close)
+	return p, nil
+}
+
+// Next fetches the next page, returning false when the scan is exhausted or
+// has failed. Check Err after Next returns false.
+func (p *ScanPages) Next() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.page = nil
+	if p.done || p.err != nil {
+		return false
+	}
+
+	var page scanPage
+	if err := decodeEnvelope("scan", C.aicred_scan_next(p.cursor), &page); err != nil {
+		logger().Error("FFI scan page failed", slog.String("error", err.Error()))
+		p.err = err
+		return false
+	}
+	p.done = page.Done
+	logger().Debug("scan page",
+		slog.Int("keys", len(page.Keys)),
+		slog.Int("config_instances", len(page.ConfigInstances)),
+		slog.Bool("done", page.Done))
+
+	// The final page of an empty scan carries only the scalar fields
+	if p.done && len(page.Keys) == 0 && len(page.ConfigInstances) == 0 {
+		return false
+	}
+	result := &page.ScanResult
+	if p.level != RedactionNone {
+		result = result.Redact(p.level)
+	}
+	p.page = result
+	return true
+}
+
+// Page returns the page fetched by the last successful call to Next
+func (p *ScanPages) Page() *ScanResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.page
+}
+
+// Err returns the error that ended iteration, if any
+func (p *ScanPages) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Close frees the cursor and ends iteration. It is safe to call more than
+// once.
+func (p *ScanPages) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cursor == nil {
+		return nil
+	}
+	C.aicred_scan_close(p.cursor)
+	p.cursor = nil
+	p.done = true
+	runtime.SetFinalizer(p, nil)
+	return nil
+}
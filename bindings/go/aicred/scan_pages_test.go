@@ -0,0 +1,49 @@
+package aicred
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScanPaged(t *testing.T) {
+	pages, err := ScanPaged(ScanOptions{HomeDir: t.TempDir(), Redaction: RedactionHashOnly}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pages.Close()
+
+	seen := map[string]bool{}
+	for pages.Next() {
+		page := pages.Page()
+		if len(page.Keys) > 2 || len(page.ConfigInstances) > 2 {
+			t.Fatalf("page exceeds size: %d keys, %d instances", len(page.Keys), len(page.ConfigInstances))
+		}
+		for _, key := range page.Keys {
+			if !key.Value.IsZero() || key.Redacted != "" {
+				t.Errorf("key %s not redacted to hash-only", key.Hash)
+			}
+			if seen[key.Hash] {
+				t.Errorf("key %s delivered twice", key.Hash)
+			}
+			seen[key.Hash] = true
+		}
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if pages.Next() {
+		t.Error("Next returned true after the last page")
+	}
+	if err := pages.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pages.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestScanPagedInvalidHome(t *testing.T) {
+	if _, err := ScanPaged(ScanOptions{HomeDir: "/nonexistent/aicred"}, 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
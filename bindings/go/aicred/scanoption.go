@@ -0,0 +1,255 @@
+package aicred
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ScanOption sets a field of ScanOptions, returning an error wrapping
+// ErrInvalidOption if the value cannot be used
+type ScanOption func(*ScanOptions) error
+
+// NewScanOptions builds ScanOptions from opts, applied in order, so a
+// misconfiguration fails here rather than partway through a scan. Besides
+// each option's own checks, it rejects provider names the library does not
+// know and providers both included and excluded.
+func NewScanOptions(opts ...ScanOption) (ScanOptions, error) {
+	var options ScanOptions
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return ScanOptions{}, err
+		}
+	}
+	if len(options.OnlyProviders) > 0 || len(options.ExcludeProviders) > 0 {
+		// The GitHub providers come from the Go-side gh CLI pass
+		known := append(ListProviders(), ProviderGitHubModels, ProviderGitHubCopilot)
+		for _, name := range slices.Concat(options.OnlyProviders, options.ExcludeProviders) {
+			if !slices.Contains(known, name) {
+				return ScanOptions{}, fmt.Errorf("unknown provider %q: %w", name, ErrInvalidOption)
+			}
+		}
+	}
+	for _, name := range options.OnlyProviders {
+		if slices.Contains(options.ExcludeProviders, name) {
+			return ScanOptions{}, fmt.Errorf("provider %q is both included and excluded: %w", name, ErrInvalidOption)
+		}
+	}
+	return options, nil
+}
+
+// ScanWith scans with the options built by NewScanOptions
+func ScanWith(opts ...ScanOption) (*ScanResult, error) {
+	options, err := NewScanOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Scan(options)
+}
+
+// WithScanHomeDir sets the home directory scanned, which must be an
+// accessible directory. WithHomeDir configures a Client instead.
+func WithScanHomeDir(dir string) ScanOption {
+	return func(o *ScanOptions) error {
+		if err := validateHomeDir(dir); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidOption, err)
+		}
+		o.HomeDir = dir
+		return nil
+	}
+}
+
+// WithProviders limits the scan to the named providers. It adds to the
+// providers of earlier WithProviders options.
+func WithProviders(names ...string) ScanOption {
+	return func(o *ScanOptions) error {
+		if err := checkProviderNames(names); err != nil {
+			return err
+		}
+		o.OnlyProviders = append(o.OnlyProviders, names...)
+		return nil
+	}
+}
+
+// WithoutProviders skips the named providers. It adds to the providers of
+// earlier WithoutProviders options.
+func WithoutProviders(names ...string) ScanOption {
+	return func(o *ScanOptions) error {
+		if err := checkProviderNames(names); err != nil {
+			return err
+		}
+		o.ExcludeProviders = append(o.ExcludeProviders, names...)
+		return nil
+	}
+}
+
+func checkProviderNames(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("no provider names given: %w", ErrInvalidOption)
+	}
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("empty provider name: %w", ErrInvalidOption)
+		}
+	}
+	return nil
+}
+
+// WithConcurrency sets ScanOptions.Concurrency; n must be positive
+func WithConcurrency(n int) ScanOption {
+	return func(o *ScanOptions) error {
+		if n <= 0 {
+			return fmt.Errorf("concurrency %d is not positive: %w", n, ErrInvalidOption)
+		}
+		o.Concurrency = n
+		return nil
+	}
+}
+
+// WithMinConfidence drops keys reported below level: Low, Medium, High or
+// VeryHigh
+func WithMinConfidence(level string) ScanOption {
+	return func(o *ScanOptions) error {
+		if _, ok := confidenceRank(level); !ok {
+			return fmt.Errorf("unknown confidence level %q: %w", level, ErrInvalidOption)
+		}
+		o.MinConfidence = level
+		return nil
+	}
+}
+
+// WithRedaction sets how much of each key the result carries
+func WithRedaction(level RedactionLevel) ScanOption {
+	return func(o *ScanOptions) error {
+		if level < RedactionPartial || level > RedactionFull {
+			return fmt.Errorf("unknown %s: %w", level, ErrInvalidOption)
+		}
+		o.Redaction = level
+		return nil
+	}
+}
+
+// WithMaxFileSize skips files larger than n bytes; n must be positive
+func WithMaxFileSize(n int) ScanOption {
+	return func(o *ScanOptions) error {
+		if n <= 0 {
+			return fmt.Errorf("max file size %d is not positive: %w", n, ErrInvalidOption)
+		}
+		o.MaxFileSize = n
+		return nil
+	}
+}
+
+// WithMaxDepth sets ScanOptions.MaxDepth; n must be positive
+func WithMaxDepth(n int) ScanOption {
+	return func(o *ScanOptions) error {
+		if n <= 0 {
+			return fmt.Errorf("max depth %d is not positive: %w", n, ErrInvalidOption)
+		}
+		o.MaxDepth = n
+		return nil
+	}
+}
+
+// WithTimeout sets ScanOptions.GlobalTimeout; d must be positive
+func WithTimeout(d time.Duration) ScanOption {
+	return func(o *ScanOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout %s is not positive: %w", d, ErrInvalidOption)
+		}
+		o.GlobalTimeout = d
+		return nil
+	}
+}
+
+// WithPerFileTimeout sets ScanOptions.PerFileTimeout; d must be positive
+func WithPerFileTimeout(d time.Duration) ScanOption {
+	return func(o *ScanOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("per-file timeout %s is not positive: %w", d, ErrInvalidOption)
+		}
+		o.PerFileTimeout = d
+		return nil
+	}
+}
+
+// WithIgnoreGlobs adds to ScanOptions.IgnoreGlobs
+func WithIgnoreGlobs(patterns ...string) ScanOption {
+	return func(o *ScanOptions) error {
+		for _, pattern := range patterns {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("empty ignore pattern: %w", ErrInvalidOption)
+			}
+		}
+		o.IgnoreGlobs = append(o.IgnoreGlobs, patterns...)
+		return nil
+	}
+}
+
+// WithArchives sets ScanOptions.ScanArchives
+func WithArchives() ScanOption {
+	return func(o *ScanOptions) error { o.ScanArchives = true; return nil }
+}
+
+// WithBinaryConfigs sets ScanOptions.ScanBinaryConfigs
+func WithBinaryConfigs() ScanOption {
+	return func(o *ScanOptions) error { o.ScanBinaryConfigs = true; return nil }
+}
+
+// WithBrowserStorage sets ScanOptions.ScanBrowserStorage
+func WithBrowserStorage() ScanOption {
+	return func(o *ScanOptions) error { o.ScanBrowserStorage = true; return nil }
+}
+
+// WithGitHubCLI sets ScanOptions.ScanGitHubCLI
+func WithGitHubCLI() ScanOption {
+	return func(o *ScanOptions) error { o.ScanGitHubCLI = true; return nil }
+}
+
+// WithInfraState sets ScanOptions.ScanInfraState
+func WithInfraState() ScanOption {
+	return func(o *ScanOptions) error { o.ScanInfraState = true; return nil }
+}
+
+// WithPackageConfigs sets ScanOptions.ScanPackageConfigs
+func WithPackageConfigs() ScanOption {
+	return func(o *ScanOptions) error { o.ScanPackageConfigs = true; return nil }
+}
+
+// WithRegistry sets ScanOptions.ScanRegistry
+func WithRegistry() ScanOption {
+	return func(o *ScanOptions) error { o.ScanRegistry = true; return nil }
+}
+
+// WithAttribution sets ScanOptions.Attribute
+func WithAttribution() ScanOption {
+	return func(o *ScanOptions) error { o.Attribute = true; return nil }
+}
+
+// WithIncremental sets ScanOptions.Incremental, keeping the cache in dir,
+// or in StateDir of the default home directory when dir is ""
+func WithIncremental(dir string) ScanOption {
+	return func(o *ScanOptions) error {
+		o.Incremental = true
+		o.CacheDir = dir
+		return nil
+	}
+}
+
+// confidenceRank orders the confidence levels the core library reports,
+// ignoring case and spaces
+func confidenceRank(confidence string) (int, bool) {
+	switch strings.ToLower(strings.ReplaceAll(confidence, " ", "")) {
+	case "low":
+		return 0, true
+	case "medium":
+		return 1, true
+	case "high":
+		return 2, true
+	case "veryhigh":
+		return 3, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,71 @@
+package aicred
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewScanOptions(t *testing.T) {
+	home := t.TempDir()
+	options, err := NewScanOptions(
+		WithScanHomeDir(home),
+		WithProviders("openai"),
+		WithProviders("anthropic"),
+		WithConcurrency(4),
+		WithMinConfidence("High"),
+		WithTimeout(time.Minute),
+		WithArchives(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.HomeDir != home || options.Concurrency != 4 || options.MinConfidence != "High" ||
+		options.GlobalTimeout != time.Minute || !options.ScanArchives ||
+		!slices.Equal(options.OnlyProviders, []string{"openai", "anthropic"}) {
+		t.Errorf("NewScanOptions = %+v", options)
+	}
+
+	for name, opts := range map[string][]ScanOption{
+		"missing home":        {WithScanHomeDir("/nonexistent/aicred/home")},
+		"no providers":        {WithProviders()},
+		"empty provider":      {WithProviders("")},
+		"unknown provider":    {WithProviders("no-such-provider")},
+		"included & excluded": {WithProviders("openai"), WithoutProviders("openai")},
+		"zero concurrency":    {WithConcurrency(0)},
+		"unknown confidence":  {WithMinConfidence("certain")},
+		"unknown redaction":   {WithRedaction(RedactionLevel(99))},
+		"negative timeout":    {WithTimeout(-time.Second)},
+		"empty ignore glob":   {WithIgnoreGlobs(" ")},
+	} {
+		if _, err := NewScanOptions(opts...); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%s: err = %v, want ErrInvalidOption", name, err)
+		}
+	}
+}
+
+func TestScanWith(t *testing.T) {
+	result, err := ScanWith(WithScanHomeDir(t.TempDir()), WithMinConfidence("VeryHigh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil {
+		t.Fatal("Result should not be nil")
+	}
+	if _, err := ScanWith(WithConcurrency(-1)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("ScanWith(WithConcurrency(-1)): err = %v, want ErrInvalidOption", err)
+	}
+}
+
+func TestFinishScanMinConfidence(t *testing.T) {
+	result := &ScanResult{Keys: []DiscoveredKey{
+		{Provider: "openai", Confidence: "Low"},
+		{Provider: "openai", Confidence: "High"},
+		{Provider: "openai", Confidence: "VeryHigh"},
+	}}
+	result = finishScan(result, ScanOptions{MinConfidence: "high"}, RedactionNone, time.Now())
+	if len(result.Keys) != 2 || result.Keys[0].Confidence != "High" {
+		t.Errorf("kept %+v", result.Keys)
+	}
+}
@@ -0,0 +1,94 @@
+package aicred
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ScanResultSchemaVersion is the version of the ScanResult JSON encoding
+// this package writes, recorded in its schema_version field.
+//
+// Version 1 is every encoding written before the field existed. Its keys
+// carry no location, or, when the result came straight from the core
+// library, only its source_file, source_line and column_number fields.
+// Version 2 adds structured locations.
+const ScanResultSchemaVersion = 2
+
+// ParseScanResult decodes a scan result written by json.Marshal,
+// MarshalWithSecrets or the aicred CLI's JSON output, in any schema version
+// up to ScanResultSchemaVersion, so archived results stay loadable as the
+// encoding evolves. Older encodings are upgraded to the current one, which
+// the returned result records in SchemaVersion.
+//
+// Upgrading changes what the result encodes to, so check the signature of
+// a signed result of an older version with VerifyResult on what
+// json.Unmarshal decodes, before parsing it here. Data that is not a scan
+// result, or is of a newer version than this package knows, returns an
+// error wrapping ErrParse.
+func ParseScanResult(data []byte) (*ScanResult, error) {
+	var header struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("scan result: %w: %v", ErrParse, err)
+	}
+	version := max(header.SchemaVersion, 1)
+	if version > ScanResultSchemaVersion {
+		return nil, fmt.Errorf("scan result schema version %d is newer than %d: %w", version, ScanResultSchemaVersion, ErrParse)
+	}
+
+	var result ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("scan result: %w: %v", ErrParse, err)
+	}
+	if version < 2 {
+		if err := upgradeV1(data, &result); err != nil {
+			return nil, fmt.Errorf("scan result: %w: %v", ErrParse, err)
+		}
+	}
+	result.SchemaVersion = ScanResultSchemaVersion
+	return &result, nil
+}
+
+// v1Key holds the fields the core library wrote for a key before
+// structured locations
+type v1Key struct {
+	SourceFile   string `json:"source_file"`
+	SourceLine   int    `json:"source_line"`
+	ColumnNumber int    `json:"column_number"`
+}
+
+// upgradeV1 fills in what a version 1 encoding kept elsewhere: the source
+// and position of core library keys, and its count of files scanned
+func upgradeV1(data []byte, result *ScanResult) error {
+	var v1 struct {
+		Keys            []v1Key `json:"keys"`
+		ConfigInstances []struct {
+			Keys []v1Key `json:"keys"`
+		} `json:"config_instances"`
+		FilesScanned int64 `json:"files_scanned"`
+	}
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return err
+	}
+	upgradeV1Keys(result.Keys, v1.Keys)
+	for i := range min(len(result.ConfigInstances), len(v1.ConfigInstances)) {
+		upgradeV1Keys(result.ConfigInstances[i].Keys, v1.ConfigInstances[i].Keys)
+	}
+	if result.Stats.FilesExamined == 0 {
+		result.Stats.FilesExamined = v1.FilesScanned
+	}
+	return nil
+}
+
+func upgradeV1Keys(keys []DiscoveredKey, v1 []v1Key) {
+	for i := range min(len(keys), len(v1)) {
+		key, old := &keys[i], v1[i]
+		if key.Source == "" {
+			key.Source = old.SourceFile
+		}
+		if key.Location == nil && old.SourceLine > 0 {
+			key.Location = &Location{Path: key.Source, Line: old.SourceLine, Column: old.ColumnNumber}
+		}
+	}
+}
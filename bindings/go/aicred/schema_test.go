@@ -0,0 +1,105 @@
+package aicred
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestParseScanResultV1(t *testing.T) {
+	data, err := os.ReadFile("testdata/scan-result-v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParseScanResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SchemaVersion != ScanResultSchemaVersion || result.HomeDir != "/home/alice" || result.Stats.FilesExamined != 42 {
+		t.Errorf("result = %+v", result)
+	}
+
+	core := result.Keys[0]
+	if core.Source != "/home/alice/.env" || core.Value.Reveal() != "sk-proj-abcdefghijklmnopqrstuvwx" {
+		t.Errorf("core key = %+v", core)
+	}
+	if l := core.Location; l == nil || *l != (Location{Path: "/home/alice/.env", Line: 3, Column: 16}) {
+		t.Errorf("core key location = %+v", l)
+	}
+	if goKey := result.Keys[1]; goKey.Source != "/home/alice/.claude.json" || goKey.Location != nil || goKey.Redacted != "****wxyz" {
+		t.Errorf("go key = %+v", goKey)
+	}
+	if l := result.ConfigInstances[0].Keys[0].Location; l == nil || *l != (Location{Path: "/home/alice/.gshrc", Line: 7}) {
+		t.Errorf("instance key location = %+v", l)
+	}
+}
+
+func TestParseScanResultRoundTrip(t *testing.T) {
+	want := &ScanResult{
+		SchemaVersion: ScanResultSchemaVersion,
+		Keys: []DiscoveredKey{{
+			Provider: "openai",
+			Source:   "/h/.env",
+			Value:    NewSecretString("sk-proj-abcdefghijklmnopqrstuvwx"),
+			Hash:     "abc",
+			Location: &Location{Path: "/h/.env", Line: 1, Column: 16, EnvVar: "OPENAI_API_KEY"},
+		}},
+		ConfigInstances: []ConfigInstance{},
+		HomeDir:         "/h",
+	}
+	data, err := want.MarshalWithSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseScanResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Keys[0].Value.Reveal() != "sk-proj-abcdefghijklmnopqrstuvwx" || *got.Keys[0].Location != *want.Keys[0].Location {
+		t.Errorf("key = %+v", got.Keys[0])
+	}
+
+	// Current results encode back to the bytes they were decoded from, so
+	// signatures still verify
+	plain, _ := json.Marshal(want)
+	parsed, err := ParseScanResult(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := json.Marshal(parsed); string(again) != string(plain) {
+		t.Errorf("re-encoded =\n%s\nwant\n%s", again, plain)
+	}
+}
+
+func TestParseScanResultRejects(t *testing.T) {
+	for name, data := range map[string]string{
+		"newer":   `{"schema_version": 99, "keys": []}`,
+		"invalid": `{"keys": [`,
+		"array":   `[]`,
+	} {
+		if _, err := ParseScanResult([]byte(data)); !errors.Is(err, ErrParse) {
+			t.Errorf("%s: err = %v, want ErrParse", name, err)
+		}
+	}
+}
+
+func FuzzParseScanResult(f *testing.F) {
+	if data, err := os.ReadFile("testdata/scan-result-v1.json"); err == nil {
+		f.Add(data)
+	}
+	f.Add([]byte(`{"schema_version":2,"keys":[{"provider":"openai","location":{"path":"a","line":1}}],"config_instances":[{"keys":[{}]}]}`))
+	f.Add([]byte(`{"keys":[{"source_file":"a","source_line":3}],"config_instances":[{"keys":[{"column_number":2}]}]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		result, err := ParseScanResult(data)
+		if err != nil {
+			if !errors.Is(err, ErrParse) {
+				t.Fatalf("error does not wrap ErrParse: %v", err)
+			}
+			return
+		}
+		if result.SchemaVersion != ScanResultSchemaVersion {
+			t.Fatalf("SchemaVersion = %d", result.SchemaVersion)
+		}
+	})
+}
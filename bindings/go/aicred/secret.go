@@ -0,0 +1,231 @@
+package aicred
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Secret holds credential material outside the reach of accidental output.
+//
+// The bytes live in a buffer that is locked against swapping where the
+// platform allows it and zeroed when the Secret is destroyed or garbage
+// collected. Formatting with fmt, logging with slog, and json.Marshal all
+// produce a redacted placeholder; use Reveal or Use to get at the value.
+//
+// Copies of a Secret share the same buffer, so Destroy on any copy wipes
+// them all.
+type Secret struct {
+	buf *secretBuffer
+}
+
+type secretBuffer struct {
+	mu     sync.Mutex
+	data   []byte
+	locked bool
+}
+
+// NewSecret copies value into a protected buffer
+func NewSecret(value []byte) Secret {
+	if len(value) == 0 {
+		return Secret{}
+	}
+	b := &secretBuffer{data: make([]byte, len(value))}
+	copy(b.data, value)
+	b.locked = lockMemory(b.data) == nil
+	runtime.SetFinalizer(b, (*secretBuffer).wipe)
+	return Secret{buf: b}
+}
+
+// NewSecretString copies value into a protected buffer
+func NewSecretString(value string) Secret {
+	return NewSecret([]byte(value))
+}
+
+// IsZero reports whether the secret holds no value
+func (s Secret) IsZero() bool {
+	if s.buf == nil {
+		return true
+	}
+	s.buf.mu.Lock()
+	defer s.buf.mu.Unlock()
+	return len(s.buf.data) == 0
+}
+
+// Len returns the length of the value in bytes
+func (s Secret) Len() int {
+	if s.buf == nil {
+		return 0
+	}
+	s.buf.mu.Lock()
+	defer s.buf.mu.Unlock()
+	return len(s.buf.data)
+}
+
+// Locked reports whether the buffer is locked against swapping
+func (s Secret) Locked() bool {
+	if s.buf == nil {
+		return false
+	}
+	s.buf.mu.Lock()
+	defer s.buf.mu.Unlock()
+	return s.buf.locked
+}
+
+// Reveal returns the value as a string. The returned string is ordinary
+// Go memory and cannot be wiped; prefer Use where possible.
+func (s Secret) Reveal() string {
+	var out string
+	s.Use(func(b []byte) { out = string(b) })
+	return out
+}
+
+// Use calls fn with the protected bytes. fn must not retain or modify the slice.
+func (s Secret) Use(fn func([]byte)) {
+	if s.buf == nil {
+		fn(nil)
+		return
+	}
+	s.buf.mu.Lock()
+	defer s.buf.mu.Unlock()
+	fn(s.buf.data)
+}
+
+// Equal reports whether two secrets hold the same value, in constant time
+func (s Secret) Equal(other Secret) bool {
+	if s.buf == other.buf {
+		return true
+	}
+	var a []byte
+	s.Use(func(b []byte) { a = append([]byte(nil), b...) })
+	defer zero(a)
+
+	var equal bool
+	other.Use(func(b []byte) { equal = subtle.ConstantTimeCompare(a, b) == 1 })
+	return equal
+}
+
+// Destroy zeroes the value and releases the memory lock
+func (s Secret) Destroy() {
+	if s.buf != nil {
+		s.buf.wipe()
+	}
+}
+
+func (b *secretBuffer) wipe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	zero(b.data)
+	if b.locked {
+		_ = unlockMemory(b.data)
+		b.locked = false
+	}
+	b.data = nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// String implements fmt.Stringer with a redacted placeholder
+func (s Secret) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return redactedValue
+}
+
+// GoString implements fmt.GoStringer so %#v is redacted too
+func (s Secret) GoString() string {
+	return fmt.Sprintf("aicred.Secret(%q)", s.String())
+}
+
+// Format implements fmt.Formatter so every verb is redacted
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprint(f, s.GoString())
+		return
+	}
+	fmt.Fprint(f, s.String())
+}
+
+// LogValue implements slog.LogValuer with a redacted placeholder
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalJSON always encodes the secret as null so values never leave
+// through the common json.Marshal path
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON accepts a plain string, null, or the core library's
+// {"Full": "..."} / {"Redacted": {...}} credential value encodings.
+// Redacted values carry no secret material and decode to an empty Secret.
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*s = Secret{}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = NewSecretString(value)
+		return nil
+	}
+
+	var tagged struct {
+		Full     *string         `json:"Full"`
+		Redacted json.RawMessage `json:"Redacted"`
+	}
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return fmt.Errorf("invalid secret value: %v", err)
+	}
+	if tagged.Full != nil {
+		*s = NewSecretString(*tagged.Full)
+		return nil
+	}
+	*s = Secret{}
+	return nil
+}
+
+// MarshalCBOR always encodes the secret as CBOR null, like MarshalJSON
+func (s Secret) MarshalCBOR() ([]byte, error) {
+	return []byte{0xf6}, nil
+}
+
+// UnmarshalCBOR accepts the same shapes as UnmarshalJSON, CBOR-encoded
+func (s *Secret) UnmarshalCBOR(data []byte) error {
+	var v any
+	if err := cbor.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid secret value: %v", err)
+	}
+	switch v := v.(type) {
+	case nil:
+		*s = Secret{}
+	case string:
+		*s = NewSecretString(v)
+	case map[any]any:
+		if full, ok := v["Full"].(string); ok {
+			*s = NewSecretString(full)
+		} else {
+			*s = Secret{}
+		}
+	default:
+		return fmt.Errorf("invalid secret value of type %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package aicred
+
+import "errors"
+
+// lockMemory is unsupported on this platform; secrets are still zeroed on destroy
+func lockMemory(b []byte) error {
+	return errors.New("memory locking not supported on this platform")
+}
+
+func unlockMemory(b []byte) error {
+	return nil
+}
@@ -0,0 +1,67 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package aicred
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Locks do not nest: one munlock releases a page however many mlock calls
+// covered it. Small secrets share pages with each other and with unrelated
+// heap objects, so the pages are counted and released only when the last
+// buffer on them is unlocked.
+var (
+	lockedMu    sync.Mutex
+	lockedPages = map[uintptr]int{}
+)
+
+// lockMemory keeps the pages backing b out of swap
+func lockMemory(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	lockedMu.Lock()
+	defer lockedMu.Unlock()
+	if err := syscall.Mlock(b); err != nil {
+		return err
+	}
+	eachPage(b, func(page uintptr, _ []byte) { lockedPages[page]++ })
+	return nil
+}
+
+// unlockMemory releases a lock taken by lockMemory on the pages of b no
+// other locked buffer uses
+func unlockMemory(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	lockedMu.Lock()
+	defer lockedMu.Unlock()
+	var err error
+	eachPage(b, func(page uintptr, part []byte) {
+		if lockedPages[page]--; lockedPages[page] > 0 {
+			return
+		}
+		delete(lockedPages, page)
+		if e := syscall.Munlock(part); e != nil && err == nil {
+			err = e
+		}
+	})
+	return err
+}
+
+// eachPage calls fn with the address of each page b spans and the part of
+// b on it
+func eachPage(b []byte, fn func(page uintptr, part []byte)) {
+	size := uintptr(os.Getpagesize())
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	for off := uintptr(0); off < uintptr(len(b)); {
+		page := (start + off) &^ (size - 1)
+		end := min(page+size-start, uintptr(len(b)))
+		fn(page, b[off:end])
+		off = end
+	}
+}
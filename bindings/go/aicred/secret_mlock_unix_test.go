@@ -0,0 +1,44 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package aicred
+
+import (
+	"os"
+	"testing"
+	"unsafe"
+)
+
+func TestUnlockKeepsSharedPages(t *testing.T) {
+	page := os.Getpagesize()
+	buf := make([]byte, 4*page)
+	// Start buf on a page boundary
+	buf = buf[page-int(uintptr(unsafe.Pointer(&buf[0])))%page:]
+	// Two buffers on one page, and one spanning two pages
+	first, second, spanning := buf[page+8:page+16], buf[page+32:page+40], buf[2*page-4:2*page+4]
+	for _, b := range [][]byte{first, second, spanning} {
+		if err := lockMemory(b); err != nil {
+			t.Skip("mlock unavailable:", err)
+		}
+	}
+	count := func(b []byte) (n []int) {
+		lockedMu.Lock()
+		defer lockedMu.Unlock()
+		eachPage(b, func(page uintptr, _ []byte) { n = append(n, lockedPages[page]) })
+		return n
+	}
+	if got := count(spanning); len(got) != 2 || got[0] != 3 || got[1] != 1 {
+		t.Fatalf("spanning buffer pages = %v, want [3 1]", got)
+	}
+
+	if err := unlockMemory(first); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(second); got[0] != 2 {
+		t.Errorf("after unlocking a neighbor, page count = %v, want [2]", got)
+	}
+	unlockMemory(second)
+	unlockMemory(spanning)
+	if got := count(spanning); got[0] != 0 || got[1] != 0 {
+		t.Errorf("after unlocking all, pages = %v", got)
+	}
+}
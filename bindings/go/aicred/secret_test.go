@@ -0,0 +1,125 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecretRedactsFormatting(t *testing.T) {
+	s := NewSecretString("sk-live-secret")
+	for _, out := range []string{
+		fmt.Sprint(s),
+		fmt.Sprintf("%s %v %q %x %#v", s, s, s, s, s),
+		fmt.Sprintf("%+v", DiscoveredKey{Value: s}),
+	} {
+		if strings.Contains(out, "sk-live") {
+			t.Errorf("secret leaked through formatting: %s", out)
+		}
+	}
+	if s.Reveal() != "sk-live-secret" {
+		t.Errorf("Reveal = %q", s.Reveal())
+	}
+}
+
+func TestSecretLogging(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("key", "v", NewSecretString("sk-live-secret"))
+	if strings.Contains(buf.String(), "sk-live") {
+		t.Errorf("secret leaked through slog: %s", buf.String())
+	}
+}
+
+func TestSecretJSON(t *testing.T) {
+	out, err := json.Marshal(DiscoveredKey{Provider: "openai", Value: NewSecretString("sk-live-secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "sk-live") {
+		t.Errorf("secret leaked through json: %s", out)
+	}
+
+	cases := map[string]string{
+		`"sk-plain"`:         "sk-plain",
+		`{"Full":"sk-full"}`: "sk-full",
+		`{"Redacted":{"sha256":"abc","prefix":"sk-"}}`: "",
+		`null`: "",
+	}
+	for in, want := range cases {
+		var s Secret
+		if err := json.Unmarshal([]byte(in), &s); err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if s.Reveal() != want {
+			t.Errorf("%s: got %q, want %q", in, s.Reveal(), want)
+		}
+	}
+}
+
+func TestSecretEqualAndDestroy(t *testing.T) {
+	a := NewSecretString("sk-one")
+	if !a.Equal(NewSecretString("sk-one")) || a.Equal(NewSecretString("sk-two")) {
+		t.Error("Equal compared values incorrectly")
+	}
+	if !a.Equal(a) {
+		t.Error("secret should equal itself")
+	}
+
+	copied := a
+	a.Destroy()
+	if !copied.IsZero() || copied.Reveal() != "" {
+		t.Error("Destroy should wipe every copy")
+	}
+	if !(Secret{}).IsZero() || (Secret{}).String() != "" {
+		t.Error("zero Secret should be empty")
+	}
+}
+
+func TestMarshalWithSecrets(t *testing.T) {
+	result := &ScanResult{
+		Keys:            []DiscoveredKey{{Provider: "openai", Value: NewSecretString("sk-live-secret")}},
+		ConfigInstances: []ConfigInstance{{InstanceID: "i1", Keys: []DiscoveredKey{{Value: NewSecretString("sk-inst-secret")}}}},
+	}
+	plain, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "secret") {
+		t.Errorf("json.Marshal leaked values: %s", plain)
+	}
+
+	full, err := result.MarshalWithSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(full), "sk-live-secret") || !strings.Contains(string(full), "sk-inst-secret") {
+		t.Errorf("MarshalWithSecrets dropped values: %s", full)
+	}
+	if !strings.Contains(string(full), `"instance_id":"i1"`) {
+		t.Errorf("MarshalWithSecrets dropped instance fields: %s", full)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	result := &ScanResult{
+		Keys:            []DiscoveredKey{{Provider: "openai", Value: NewSecretString("sk-live-secret")}},
+		ConfigInstances: []ConfigInstance{{InstanceID: "i1", Keys: []DiscoveredKey{{Value: NewSecretString("sk-inst-secret")}}}},
+	}
+	redacted := result.Redacted()
+	if !redacted.Keys[0].Value.IsZero() || !redacted.ConfigInstances[0].Keys[0].Value.IsZero() {
+		t.Error("Redacted kept full values")
+	}
+	if result.Keys[0].Value.IsZero() || result.ConfigInstances[0].Keys[0].Value.IsZero() {
+		t.Error("Redacted modified the original result")
+	}
+	full, err := redacted.MarshalWithSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(full), "secret") {
+		t.Errorf("redacted result leaked values: %s", full)
+	}
+}
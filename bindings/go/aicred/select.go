@@ -0,0 +1,425 @@
+package aicred
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Selection is one instance/model pair matched by a selector. Model is ""
+// for an instance that lists no models.
+type Selection struct {
+	Instance ProviderInstance
+	Model    string
+}
+
+// Selector is a parsed selector query such as
+//
+//	provider=openai AND label=prod AND capability:streaming
+//
+// Terms compare a field with = or != against a value, which may be quoted
+// and may use * wildcards: provider (the instance's provider type), id,
+// model, label, tag and active ("true" or "false"). A tag term also matches
+// pairs assigned a tag below it in the tag hierarchy, so tag=prod holds for
+// a model tagged prod-eu when prod-eu's parent is prod. capability:<name> holds for
+// instances with that capability: chat, completion, embedding,
+// image_generation, function_calling or streaming. Terms combine with AND,
+// OR, NOT and parentheses; AND binds tighter than OR, and keywords are
+// case-insensitive.
+type Selector struct {
+	query string
+	root  selectNode
+}
+
+// ParseSelector parses a selector query. Syntax errors wrap ErrParse.
+func ParseSelector(query string) (*Selector, error) {
+	tokens, err := lexSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &selectorParser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Selector{query: query, root: root}, nil
+}
+
+// String returns the query the selector was parsed from
+func (s *Selector) String() string {
+	return s.query
+}
+
+// Select returns the instance/model pairs in instances that match the
+// selector query, with labels resolved from assignments. A label assigned
+// to an instance applies to all of its models. No pair has a tag; use
+// Config.Select for queries with tag terms.
+func Select(instances []ProviderInstance, assignments []LabelAssignment, query string) ([]Selection, error) {
+	selector, err := ParseSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	return selector.Select(instances, assignments), nil
+}
+
+// Select returns the instance/model pairs the selector matches, in instance
+// order. No pair has a tag; use SelectConfig for queries with tag terms.
+func (s *Selector) Select(instances []ProviderInstance, assignments []LabelAssignment) []Selection {
+	return s.SelectConfig(&Config{Instances: instances, Labels: assignments})
+}
+
+// SelectConfig returns the pairs of config's instances the selector
+// matches, in instance order, with labels resolved from config.Labels and
+// tags from config.TagAssignments and config.Tags. A tag assigned to an
+// instance applies to all of its models, as a label does.
+func (s *Selector) SelectConfig(config *Config) []Selection {
+	var selections []Selection
+	for _, instance := range config.Instances {
+		models := instance.Models
+		if len(models) == 0 {
+			models = []string{""}
+		}
+		for _, model := range models {
+			pair := selectPair{
+				instance: &instance,
+				model:    model,
+				labels:   pairLabels(instance.ID, model, config.Labels),
+				tags:     pairTags(instance.ID, model, config.TagAssignments, config.Tags),
+			}
+			if s.root.match(&pair) {
+				selections = append(selections, Selection{Instance: instance, Model: model})
+			}
+		}
+	}
+	return selections
+}
+
+// Select returns the instance/model pairs of the configuration that match
+// the selector query, as Selector.SelectConfig finds them
+func (c *Config) Select(query string) ([]Selection, error) {
+	selector, err := ParseSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	return selector.SelectConfig(c), nil
+}
+
+// Select matches the query against the session's instances, labels and
+// tags
+func (s *Session) Select(query string) ([]Selection, error) {
+	selector, err := ParseSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	config, err := CurrentConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	return selector.SelectConfig(config), nil
+}
+
+// selectPair is what one selector evaluation sees
+type selectPair struct {
+	instance *ProviderInstance
+	model    string
+	labels   []string
+	// tags holds the tags assigned to the pair and every tag above them
+	tags []string
+}
+
+func pairLabels(instanceID, model string, assignments []LabelAssignment) []string {
+	var labels []string
+	for _, a := range assignments {
+		if a.Target.InstanceID != instanceID {
+			continue
+		}
+		if a.Target.Type == LabelTargetModel && a.Target.ModelID != model {
+			continue
+		}
+		labels = append(labels, a.LabelName)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func pairTags(instanceID, model string, assignments []LabelAssignment, tags []Tag) []string {
+	names := pairLabels(instanceID, model, assignments)
+	parents := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		parents[tag.Name] = tag.Parent
+	}
+	for i := 0; i < len(names); i++ {
+		if parent := parents[names[i]]; parent != "" && !slices.Contains(names, parent) {
+			names = append(names, parent)
+		}
+	}
+	return names
+}
+
+// selectorCapabilities maps capability names to their flags
+var selectorCapabilities = map[string]func(Capabilities) bool{
+	"chat":             func(c Capabilities) bool { return c.Chat },
+	"completion":       func(c Capabilities) bool { return c.Completion },
+	"embedding":        func(c Capabilities) bool { return c.Embedding },
+	"image_generation": func(c Capabilities) bool { return c.ImageGeneration },
+	"function_calling": func(c Capabilities) bool { return c.FunctionCalling },
+	"streaming":        func(c Capabilities) bool { return c.Streaming },
+}
+
+type selectNode interface {
+	match(p *selectPair) bool
+}
+
+type (
+	andNode []selectNode
+	orNode  []selectNode
+	notNode struct{ inner selectNode }
+
+	termNode struct {
+		field, value string
+		negate       bool
+	}
+	capabilityNode struct {
+		has func(Capabilities) bool
+	}
+)
+
+func (n andNode) match(p *selectPair) bool {
+	for _, c := range n {
+		if !c.match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n orNode) match(p *selectPair) bool {
+	for _, c := range n {
+		if c.match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n notNode) match(p *selectPair) bool { return !n.inner.match(p) }
+
+func (n capabilityNode) match(p *selectPair) bool { return n.has(p.instance.Capabilities) }
+
+func (n termNode) match(p *selectPair) bool {
+	var matched bool
+	switch n.field {
+	case "provider":
+		matched = valueMatches(n.value, p.instance.ProviderType)
+	case "id":
+		matched = valueMatches(n.value, p.instance.ID)
+	case "model":
+		// Models are often namespaced, e.g. "openai/gpt-4o"
+		matched = valueMatches(n.value, p.model) || valueMatches(n.value, p.model[strings.LastIndexByte(p.model, '/')+1:])
+	case "active":
+		matched = valueMatches(n.value, fmt.Sprint(p.instance.Active))
+	case "label":
+		for _, l := range p.labels {
+			if valueMatches(n.value, l) {
+				matched = true
+				break
+			}
+		}
+	case "tag":
+		for _, t := range p.tags {
+			if valueMatches(n.value, t) {
+				matched = true
+				break
+			}
+		}
+	}
+	return matched != n.negate
+}
+
+// valueMatches compares case-insensitively, treating * as a wildcard
+func valueMatches(pattern, value string) bool {
+	pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+	if !strings.Contains(pattern, "*") {
+		return pattern == value
+	}
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokEq
+	tokNe
+	tokLParen
+	tokRParen
+)
+
+type selectorToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lexSelector(query string) ([]selectorToken, error) {
+	var tokens []selectorToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, selectorToken{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, selectorToken{tokRParen, ")", i})
+			i++
+		case c == '=':
+			tokens = append(tokens, selectorToken{tokEq, "=", i})
+			i++
+		case c == '!' && i+1 < len(query) && query[i+1] == '=':
+			tokens = append(tokens, selectorToken{tokNe, "!=", i})
+			i += 2
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("selector %q: unterminated string at %d: %w", query, i, ErrParse)
+			}
+			tokens = append(tokens, selectorToken{tokString, query[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(query) && !strings.ContainsRune(" \t\r\n()=\"", rune(query[i])) && !(query[i] == '!' && i+1 < len(query) && query[i+1] == '=') {
+				i++
+			}
+			tokens = append(tokens, selectorToken{tokWord, query[start:i], start})
+		}
+	}
+	return append(tokens, selectorToken{kind: tokEOF, pos: len(query)}), nil
+}
+
+type selectorParser struct {
+	tokens []selectorToken
+	pos    int
+}
+
+func (p *selectorParser) peek() selectorToken { return p.tokens[p.pos] }
+
+func (p *selectorParser) next() selectorToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *selectorParser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *selectorParser) errorf(t selectorToken, format string, args ...any) error {
+	return fmt.Errorf("selector: %s at %d: %w", fmt.Sprintf(format, args...), t.pos, ErrParse)
+}
+
+func (p *selectorParser) or() (selectNode, error) {
+	var nodes orNode
+	for {
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !p.keyword("OR") {
+			break
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *selectorParser) and() (selectNode, error) {
+	var nodes andNode
+	for {
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !p.keyword("AND") {
+			break
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *selectorParser) unary() (selectNode, error) {
+	if p.keyword("NOT") {
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if close := p.next(); close.kind != tokRParen {
+			return nil, p.errorf(close, "expected \")\"")
+		}
+		return inner, nil
+	case tokWord:
+		return p.term(t)
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of query")
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+func (p *selectorParser) term(field selectorToken) (selectNode, error) {
+	name := strings.ToLower(field.text)
+	if capability, ok := strings.CutPrefix(name, "capability:"); ok {
+		has, known := selectorCapabilities[capability]
+		if !known {
+			return nil, p.errorf(field, "unknown capability %q", capability)
+		}
+		return capabilityNode{has}, nil
+	}
+	switch name {
+	case "provider", "id", "model", "label", "tag", "active":
+	default:
+		return nil, p.errorf(field, "unknown field %q", field.text)
+	}
+
+	op := p.next()
+	if op.kind != tokEq && op.kind != tokNe {
+		return nil, p.errorf(op, "expected = or != after %s", name)
+	}
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, p.errorf(value, "expected a value for %s", name)
+	}
+	return termNode{field: name, value: value.text, negate: op.kind == tokNe}, nil
+}
@@ -0,0 +1,139 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func selectFixture() ([]ProviderInstance, []LabelAssignment) {
+	instances := []ProviderInstance{
+		{ID: "openai-prod", ProviderType: "openai", Active: true, Models: []string{"gpt-4o", "openai/gpt-4o-mini"}, Capabilities: Capabilities{Chat: true, Streaming: true}},
+		{ID: "anthropic-main", ProviderType: "anthropic", Active: true, Models: []string{"claude-3-5-sonnet"}, Capabilities: Capabilities{Chat: true}},
+		{ID: "ollama-local", ProviderType: "ollama", Active: false, Capabilities: Capabilities{Embedding: true}},
+	}
+	labels := []LabelAssignment{
+		{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "openai-prod"}},
+		{LabelName: "fast", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "openai-prod", ModelID: "openai/gpt-4o-mini"}},
+		{LabelName: "prod", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "anthropic-main", ModelID: "claude-3-5-sonnet"}},
+	}
+	return instances, labels
+}
+
+func TestSelect(t *testing.T) {
+	instances, labels := selectFixture()
+	for query, want := range map[string][]string{
+		"provider=openai":                                    {"openai-prod/gpt-4o", "openai-prod/openai/gpt-4o-mini"},
+		"provider=openai AND label=fast":                     {"openai-prod/openai/gpt-4o-mini"},
+		"label=prod AND capability:streaming":                {"openai-prod/gpt-4o", "openai-prod/openai/gpt-4o-mini"},
+		"label=prod AND NOT provider=openai":                 {"anthropic-main/claude-3-5-sonnet"},
+		"model=gpt-4o-mini OR active=false":                  {"openai-prod/openai/gpt-4o-mini", "ollama-local/"},
+		`(provider=ollama OR provider="anthropic") AND chat`: nil,
+		"model=gpt-4* and label!=fast":                       {"openai-prod/gpt-4o"},
+		"capability:embedding":                               {"ollama-local/"},
+		"id=*-main":                                          {"anthropic-main/claude-3-5-sonnet"},
+	} {
+		selections, err := Select(instances, labels, query)
+		if want == nil {
+			if !errors.Is(err, ErrParse) {
+				t.Errorf("%s: err = %v, want ErrParse", query, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		var got []string
+		for _, s := range selections {
+			got = append(got, s.Instance.ID+"/"+s.Model)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestSelectTags(t *testing.T) {
+	instances, labels := selectFixture()
+	config := &Config{
+		Instances: instances,
+		Labels:    labels,
+		Tags: []Tag{
+			{Name: "prod"},
+			{Name: "prod-eu", Parent: "prod"},
+			{Name: "prod-eu-west", Parent: "prod-eu"},
+		},
+		TagAssignments: []LabelAssignment{
+			{LabelName: "prod-eu-west", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "openai-prod", ModelID: "gpt-4o"}},
+			{LabelName: "prod", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "ollama-local"}},
+		},
+	}
+	for query, want := range map[string][]string{
+		"tag=prod":                     {"openai-prod/gpt-4o", "ollama-local/"},
+		"tag=prod-eu":                  {"openai-prod/gpt-4o"},
+		"tag=prod-eu-*":                {"openai-prod/gpt-4o"},
+		"tag!=prod AND label=prod":     {"openai-prod/openai/gpt-4o-mini", "anthropic-main/claude-3-5-sonnet"},
+		"tag=prod AND capability:chat": {"openai-prod/gpt-4o"},
+		// Labels and tags are kept apart
+		"tag=fast": {},
+	} {
+		selections, err := config.Select(query)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		got := []string{}
+		for _, s := range selections {
+			got = append(got, s.Instance.ID+"/"+s.Model)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+
+	// Without the configuration's tags, no pair has one
+	if selections, err := Select(instances, labels, "tag=prod"); err != nil || len(selections) != 0 {
+		t.Errorf("Select(tag=prod) = %v, %v", selections, err)
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"provider",
+		"provider=",
+		"colour=red",
+		"capability:teleport",
+		"(provider=openai",
+		"provider=openai)",
+		`label="prod`,
+		"provider=openai AND",
+	} {
+		if _, err := ParseSelector(query); !errors.Is(err, ErrParse) {
+			t.Errorf("ParseSelector(%q): err = %v, want ErrParse", query, err)
+		}
+	}
+}
+
+func FuzzParseSelector(f *testing.F) {
+	for _, query := range []string{
+		"provider=openai AND label=prod AND capability:streaming",
+		"tag=prod-* OR NOT tag!=prod",
+		`NOT (id!="a*" OR model=gpt-4o) and active=true`,
+		"((((",
+	} {
+		f.Add(query)
+	}
+	instances, labels := selectFixture()
+	f.Fuzz(func(t *testing.T, query string) {
+		selector, err := ParseSelector(query)
+		if err != nil {
+			if !errors.Is(err, ErrParse) {
+				t.Fatalf("error does not wrap ErrParse: %v", err)
+			}
+			return
+		}
+		selector.Select(instances, labels)
+	})
+}
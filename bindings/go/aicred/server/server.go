@@ -0,0 +1,340 @@
+// Package server exposes aicred scanning and the aicred configuration over
+// gRPC so non-Go processes and remote agents can use it without cgo.
+//
+// The service is defined in aicredpb/aicred.proto. Regenerate the Go code with:
+//
+//	go generate ./aicred/server/...
+package server
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative aicredpb/aicred.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/server/aicredpb"
+)
+
+// Options controls what remote callers are allowed to do
+type Options struct {
+	// HomeDir is scanned when a request does not name one
+	HomeDir string
+	// AllowHomeDirOverride lets requests choose the directory to scan
+	AllowHomeDirOverride bool
+	// AllowFullValues lets requests ask for unredacted key values
+	AllowFullValues bool
+	// Redaction is the level applied when a request does not choose one,
+	// and the weakest level a request may choose other than "none"
+	Redaction aicred.RedactionLevel
+	// Store holds the instances and labels served by ListInstances,
+	// Resolve and Validate. Nil means a Session over HomeDir, opened on
+	// first use. API keys are never sent.
+	Store aicred.Store
+}
+
+// Server implements the aicred.v1.AICred gRPC service
+type Server struct {
+	aicredpb.UnimplementedAICredServer
+
+	opts Options
+	scan func(aicred.ScanOptions) (*aicred.ScanResult, error)
+
+	mu    sync.Mutex
+	store aicred.Store
+}
+
+// New creates a Server backed by aicred.Scan
+func New(opts Options) *Server {
+	return &Server{opts: opts, scan: aicred.Scan, store: opts.Store}
+}
+
+// Register attaches the service to a gRPC server
+func (s *Server) Register(g *grpc.Server) {
+	aicredpb.RegisterAICredServer(g, s)
+}
+
+// Scan runs a scan on the server host and returns results at the requested
+// redaction level, within the limits set by Options
+func (s *Server) Scan(ctx context.Context, req *aicredpb.ScanRequest) (*aicredpb.ScanResponse, error) {
+	homeDir := s.opts.HomeDir
+	if req.GetHomeDir() != "" {
+		if !s.opts.AllowHomeDirOverride {
+			return nil, status.Error(codes.PermissionDenied, "home_dir override is not allowed by this server")
+		}
+		homeDir = req.GetHomeDir()
+	}
+	level := s.opts.Redaction
+	switch {
+	case req.GetRedaction() != "":
+		var err error
+		if level, err = aicred.ParseRedactionLevel(req.GetRedaction()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	case req.GetIncludeFullValues():
+		level = aicred.RedactionNone
+	}
+	if level == aicred.RedactionNone {
+		if !s.opts.AllowFullValues {
+			return nil, status.Error(codes.PermissionDenied, "full values are not allowed by this server")
+		}
+	} else if !level.AtLeast(s.opts.Redaction) {
+		return nil, status.Errorf(codes.PermissionDenied, "redaction level %s is weaker than this server allows", level)
+	}
+
+	result, err := s.scan(aicred.ScanOptions{
+		HomeDir:          homeDir,
+		Redaction:        level,
+		MaxFileSize:      int(req.GetMaxFileSize()),
+		OnlyProviders:    req.GetOnlyProviders(),
+		ExcludeProviders: req.GetExcludeProviders(),
+	})
+	if err != nil {
+		if errors.Is(err, aicred.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "scan failed: %v", err)
+	}
+	return toProto(result.Redact(level), level == aicred.RedactionNone), nil
+}
+
+// ListProviders returns the available provider plugins
+func (s *Server) ListProviders(ctx context.Context, req *aicredpb.ListProvidersRequest) (*aicredpb.ListProvidersResponse, error) {
+	return &aicredpb.ListProvidersResponse{Providers: aicred.ListProviders()}, nil
+}
+
+// ListScanners returns the available application scanners
+func (s *Server) ListScanners(ctx context.Context, req *aicredpb.ListScannersRequest) (*aicredpb.ListScannersResponse, error) {
+	return &aicredpb.ListScannersResponse{Scanners: aicred.ListScanners()}, nil
+}
+
+// Version returns the library version
+func (s *Server) Version(ctx context.Context, req *aicredpb.VersionRequest) (*aicredpb.VersionResponse, error) {
+	return &aicredpb.VersionResponse{Version: aicred.Version()}, nil
+}
+
+// ListInstances returns the configured provider instances without their keys
+func (s *Server) ListInstances(ctx context.Context, req *aicredpb.ListInstancesRequest) (*aicredpb.ListInstancesResponse, error) {
+	store, err := s.configStore()
+	if err != nil {
+		return nil, err
+	}
+	instances, err := store.LoadInstances()
+	if err != nil {
+		return nil, storeError(err)
+	}
+	resp := &aicredpb.ListInstancesResponse{}
+	for _, instance := range instances {
+		resp.Instances = append(resp.Instances, instanceToProto(instance))
+	}
+	return resp, nil
+}
+
+// Resolve returns the instance/model pairs a label is assigned to, or that
+// a selector query matches
+func (s *Server) Resolve(ctx context.Context, req *aicredpb.ResolveRequest) (*aicredpb.ResolveResponse, error) {
+	if (req.GetLabel() == "") == (req.GetQuery() == "") {
+		return nil, status.Error(codes.InvalidArgument, "set exactly one of label and query")
+	}
+	var selector *aicred.Selector
+	if req.GetQuery() != "" {
+		var err error
+		if selector, err = aicred.ParseSelector(req.GetQuery()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	store, err := s.configStore()
+	if err != nil {
+		return nil, err
+	}
+	config, err := storeConfig(store)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	var selections []aicred.Selection
+	if selector != nil {
+		selections = selector.SelectConfig(config)
+	} else {
+		selections = aicred.ResolveLabel(config.Instances, config.Labels, req.GetLabel())
+	}
+	resp := &aicredpb.ResolveResponse{}
+	for _, sel := range selections {
+		resp.Selections = append(resp.Selections, &aicredpb.Selection{Instance: instanceToProto(sel.Instance), Model: sel.Model})
+	}
+	return resp, nil
+}
+
+// Validate checks every configured instance with
+// aicred.ValidateProviderInstance and the label assignments with
+// aicred.CheckIntegrity. Problems are the response, not an error.
+func (s *Server) Validate(ctx context.Context, req *aicredpb.ValidateRequest) (*aicredpb.ValidateResponse, error) {
+	store, err := s.configStore()
+	if err != nil {
+		return nil, err
+	}
+	instances, err := store.LoadInstances()
+	if err != nil {
+		return nil, storeError(err)
+	}
+	assignments, err := store.LoadLabels()
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	resp := &aicredpb.ValidateResponse{}
+	options := aicred.ValidationOptions{Strict: req.GetStrict(), AllowedHosts: req.GetAllowedHosts()}
+	for _, instance := range instances {
+		err := aicred.ValidateProviderInstance(instance, options)
+		if err == nil {
+			continue
+		}
+		problems := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			problems = joined.Unwrap()
+		}
+		for _, problem := range problems {
+			resp.Problems = append(resp.Problems, &aicredpb.ValidationProblem{InstanceId: instance.ID, Message: problem.Error()})
+		}
+	}
+	for _, issue := range aicred.CheckIntegrity(instances, assignments) {
+		problem := &aicredpb.ValidationProblem{InstanceId: issue.InstanceID, Kind: string(issue.Kind), Message: issue.Message}
+		if issue.Assignment != nil {
+			problem.InstanceId = issue.Assignment.Target.InstanceID
+		}
+		resp.Problems = append(resp.Problems, problem)
+	}
+	return resp, nil
+}
+
+// configStore returns Options.Store, or opens a Session over HomeDir the
+// first time one is needed. A failed open is retried on the next call.
+func (s *Server) configStore() (aicred.Store, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		session, err := aicred.OpenSession(s.opts.HomeDir)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		s.store = session
+	}
+	return s.store, nil
+}
+
+// storeConfig reads store's configuration. Tags are read from a store
+// that keeps them, so selector queries can use tag terms.
+func storeConfig(store aicred.Store) (*aicred.Config, error) {
+	if cs, ok := store.(aicred.ConfigStore); ok {
+		return aicred.CurrentConfig(cs)
+	}
+	instances, err := store.LoadInstances()
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := store.LoadLabels()
+	if err != nil {
+		return nil, err
+	}
+	return &aicred.Config{Instances: instances, Labels: assignments}, nil
+}
+
+// storeError maps an error reading the configuration to a status
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, aicred.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, aicred.ErrParse):
+		return status.Errorf(codes.FailedPrecondition, "configuration does not parse: %v", err)
+	default:
+		return status.Errorf(codes.Internal, "reading configuration failed: %v", err)
+	}
+}
+
+// MTLSCredentials builds server transport credentials that require clients
+// to present a certificate signed by the CA in clientCAFile
+func MTLSCredentials(certFile, keyFile, clientCAFile string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %v", err)
+	}
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+func toProto(result *aicred.ScanResult, includeValues bool) *aicredpb.ScanResponse {
+	resp := &aicredpb.ScanResponse{
+		HomeDir:          result.HomeDir,
+		ScannedAt:        result.ScannedAt,
+		ProvidersScanned: result.ProvidersScanned,
+	}
+	for _, key := range result.Keys {
+		resp.Keys = append(resp.Keys, keyToProto(key, includeValues))
+	}
+	for _, instance := range result.ConfigInstances {
+		pi := &aicredpb.ConfigInstance{
+			InstanceId:   instance.InstanceID,
+			AppName:      instance.AppName,
+			ConfigPath:   instance.ConfigPath,
+			DiscoveredAt: instance.DiscoveredAt,
+			Metadata:     instance.Metadata,
+		}
+		for _, key := range instance.Keys {
+			pi.Keys = append(pi.Keys, keyToProto(key, includeValues))
+		}
+		resp.ConfigInstances = append(resp.ConfigInstances, pi)
+	}
+	return resp
+}
+
+func keyToProto(key aicred.DiscoveredKey, includeValue bool) *aicredpb.DiscoveredKey {
+	pk := &aicredpb.DiscoveredKey{
+		Provider:   key.Provider,
+		Source:     key.Source,
+		ValueType:  key.ValueType,
+		Confidence: key.Confidence,
+		Hash:       key.Hash,
+		Redacted:   key.Redacted,
+		Locked:     key.Locked,
+	}
+	if includeValue {
+		pk.Value = key.Value.Reveal()
+	}
+	return pk
+}
+
+// instanceToProto converts an instance, leaving its key out
+func instanceToProto(instance aicred.ProviderInstance) *aicredpb.ProviderInstance {
+	return &aicredpb.ProviderInstance{
+		Id:           instance.ID,
+		ProviderType: instance.ProviderType,
+		BaseUrl:      instance.BaseURL,
+		Models:       instance.Models,
+		Capabilities: instance.Capabilities.Names(),
+		Active:       instance.Active,
+		Metadata:     instance.Metadata,
+		HasApiKey:    !instance.APIKey.IsZero(),
+	}
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/server/aicredpb"
+)
+
+func fakeScan(opts aicred.ScanOptions) (*aicred.ScanResult, error) {
+	if opts.HomeDir == "/missing" {
+		return nil, fmt.Errorf("invalid HomeDir: /missing: %w", aicred.ErrNotFound)
+	}
+	key := aicred.DiscoveredKey{Provider: "openai", Redacted: "sk-****abcd", Hash: "h1"}
+	if opts.Redaction == aicred.RedactionNone {
+		key.Value = aicred.NewSecretString("sk-secret")
+	}
+	return &aicred.ScanResult{
+		Keys:    []aicred.DiscoveredKey{key},
+		HomeDir: opts.HomeDir,
+		ConfigInstances: []aicred.ConfigInstance{
+			{InstanceID: "i1", AppName: "roo-code", Keys: []aicred.DiscoveredKey{key}},
+		},
+	}, nil
+}
+
+func dial(t *testing.T, opts Options) aicredpb.AICredClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	s := New(opts)
+	s.scan = fakeScan
+	s.Register(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return aicredpb.NewAICredClient(conn)
+}
+
+func TestScanUsesServerHomeDir(t *testing.T) {
+	client := dial(t, Options{HomeDir: "/srv/home"})
+
+	resp, err := client.Scan(context.Background(), &aicredpb.ScanRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetHomeDir() != "/srv/home" {
+		t.Errorf("expected server home dir, got %q", resp.GetHomeDir())
+	}
+	if len(resp.GetKeys()) != 1 || resp.GetKeys()[0].GetRedacted() != "sk-****abcd" {
+		t.Errorf("unexpected keys: %v", resp.GetKeys())
+	}
+	if len(resp.GetConfigInstances()) != 1 || len(resp.GetConfigInstances()[0].GetKeys()) != 1 {
+		t.Errorf("unexpected instances: %v", resp.GetConfigInstances())
+	}
+
+	_, err = client.Scan(context.Background(), &aicredpb.ScanRequest{HomeDir: "/other"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for home_dir override, got %v", err)
+	}
+}
+
+func TestScanFullValues(t *testing.T) {
+	client := dial(t, Options{})
+	_, err := client.Scan(context.Background(), &aicredpb.ScanRequest{IncludeFullValues: true})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for full values, got %v", err)
+	}
+
+	client = dial(t, Options{AllowFullValues: true})
+	resp, err := client.Scan(context.Background(), &aicredpb.ScanRequest{IncludeFullValues: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetKeys()[0].GetValue() != "sk-secret" {
+		t.Error("expected full value when allowed and requested")
+	}
+}
+
+func TestScanRedactionLevels(t *testing.T) {
+	client := dial(t, Options{Redaction: aicred.RedactionHashOnly})
+	resp, err := client.Scan(context.Background(), &aicredpb.ScanRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := resp.GetKeys()[0]; key.GetRedacted() != "" || key.GetHash() != "h1" {
+		t.Errorf("expected hash-only key by default, got %v", key)
+	}
+
+	resp, err = client.Scan(context.Background(), &aicredpb.ScanRequest{Redaction: "full"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := resp.GetConfigInstances()[0].GetKeys()[0]; key.GetHash() != "" {
+		t.Errorf("expected fully redacted instance key, got %v", key)
+	}
+
+	_, err = client.Scan(context.Background(), &aicredpb.ScanRequest{Redaction: "partial"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for weaker level, got %v", err)
+	}
+	_, err = client.Scan(context.Background(), &aicredpb.ScanRequest{Redaction: "bogus"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for unknown level, got %v", err)
+	}
+}
+
+func TestScanInvalidHome(t *testing.T) {
+	client := dial(t, Options{AllowHomeDirOverride: true})
+	_, err := client.Scan(context.Background(), &aicredpb.ScanRequest{HomeDir: "/missing"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestListAndVersion(t *testing.T) {
+	client := dial(t, Options{})
+	providers, err := client.ListProviders(context.Background(), &aicredpb.ListProvidersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers.GetProviders()) == 0 {
+		t.Error("expected providers")
+	}
+	version, err := client.Version(context.Background(), &aicredpb.VersionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.GetVersion() == "" {
+		t.Error("expected version")
+	}
+}
+
+func configStore() aicred.Store {
+	return aicred.NewMemoryStore([]aicred.ProviderInstance{
+		{ID: "groq", ProviderType: "groq", BaseURL: "http://api.groq.com/openai/v1", Models: []string{"llama-3.1-8b"}},
+		{ID: "openai", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", APIKey: aicred.NewSecretString("sk-secret"),
+			Models: []string{"gpt-4o", "gpt-4o-mini"}, Capabilities: aicred.Capabilities{Chat: true, Streaming: true}, Active: true},
+	}, []aicred.LabelAssignment{
+		{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetModel, InstanceID: "openai", ModelID: "gpt-4o-mini"}},
+		{LabelName: "fast", Target: aicred.LabelTarget{Type: aicred.LabelTargetInstance, InstanceID: "groq"}},
+		{LabelName: "old", Target: aicred.LabelTarget{Type: aicred.LabelTargetInstance, InstanceID: "gone"}},
+	})
+}
+
+func TestListInstances(t *testing.T) {
+	client := dial(t, Options{Store: configStore()})
+	resp, err := client.ListInstances(context.Background(), &aicredpb.ListInstancesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances := resp.GetInstances()
+	if len(instances) != 2 || instances[1].GetId() != "openai" || !instances[1].GetHasApiKey() || instances[0].GetHasApiKey() {
+		t.Fatalf("instances = %v", instances)
+	}
+	if caps := instances[1].GetCapabilities(); len(caps) != 2 || caps[0] != "chat" || caps[1] != "streaming" {
+		t.Errorf("capabilities = %v", caps)
+	}
+	if strings.Contains(resp.String(), "sk-secret") {
+		t.Error("response carries an API key")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	client := dial(t, Options{Store: configStore()})
+	resp, err := client.Resolve(context.Background(), &aicredpb.ResolveRequest{Label: "fast"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, sel := range resp.GetSelections() {
+		got = append(got, sel.GetInstance().GetId()+"/"+sel.GetModel())
+	}
+	if strings.Join(got, " ") != "groq/llama-3.1-8b openai/gpt-4o-mini" {
+		t.Errorf("label fast = %v", got)
+	}
+
+	resp, err = client.Resolve(context.Background(), &aicredpb.ResolveRequest{Query: "provider=openai AND capability:streaming"})
+	if err != nil || len(resp.GetSelections()) != 2 {
+		t.Errorf("query = %v, %v", resp, err)
+	}
+	for _, req := range []*aicredpb.ResolveRequest{{}, {Label: "fast", Query: "id=groq"}, {Query: "provider="}} {
+		if _, err := client.Resolve(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Resolve(%v) = %v, want InvalidArgument", req, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	client := dial(t, Options{Store: configStore()})
+	resp, err := client.Validate(context.Background(), &aicredpb.ValidateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := resp.GetProblems(); len(problems) != 1 || problems[0].GetKind() != "dangling_instance" || problems[0].GetInstanceId() != "gone" {
+		t.Errorf("problems = %v", problems)
+	}
+
+	resp, err = client.Validate(context.Background(), &aicredpb.ValidateRequest{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groq []string
+	for _, p := range resp.GetProblems() {
+		if p.GetInstanceId() == "groq" {
+			groq = append(groq, p.GetMessage())
+		}
+	}
+	// No key, and the key would travel unencrypted
+	if len(groq) != 2 {
+		t.Errorf("strict problems for groq = %q", groq)
+	}
+}
@@ -0,0 +1,248 @@
+package aicred
+
+/*
+#include <stdlib.h>
+#include "../../../ffi/include/genai_keyfinder.h"
+*/
+import "C"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+// ErrClosed is returned by Session methods after Close
+var ErrClosed = errors.New("aicred: session closed")
+
+// Session holds parsed configuration state on the Rust side so repeated
+// lookups do not re-read and re-parse YAML. The state is read on first use
+// and kept until Reload or Close. A Session is safe for concurrent use.
+type Session struct {
+	mu       sync.Mutex
+	handle   *C.AicredSession
+	homeDir  string
+	encoding Encoding
+	events   *events.Bus
+
+	// revMu guards the revisions taken before writes
+	revMu     sync.Mutex
+	revisions int
+	batching  int
+
+	// actor is who audit entries say made the session's changes
+	actor string
+}
+
+// SessionOptions configures OpenSessionWith
+type SessionOptions struct {
+	// Encoding is used for Scan results. CBOR is faster to decode for
+	// home directories with many findings.
+	Encoding Encoding
+	// Events, if set, receives StoreChanged, ScanCompleted and
+	// ValidationFailed events for this session
+	Events *events.Bus
+	// Revisions is how many snapshots of the configuration the session
+	// keeps for RollbackTo: DefaultRevisions if zero, none if negative
+	Revisions int
+	// Actor is who the audit log says made the session's changes, such
+	// as the user of a service acting for others. Empty means the user
+	// running the program.
+	Actor string
+}
+
+// OpenSession opens a session over ConfigDir(homeDir), usually
+// homeDir/.config/aicred. An empty homeDir means DefaultHomeDir. Instances
+// are loaded eagerly so configuration errors surface here.
+func OpenSession(homeDir string) (*Session, error) {
+	return OpenSessionWith(homeDir, SessionOptions{})
+}
+
+// OpenSessionWith is OpenSession with options
+func OpenSessionWith(homeDir string, opts SessionOptions) (*Session, error) {
+	if homeDir == "" {
+		home, err := DefaultHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot determine home directory: %v", ErrNotFound, err)
+		}
+		homeDir = home
+	}
+
+	cHome := C.CString(homeDir)
+	defer C.free(unsafe.Pointer(cHome))
+
+	handle := C.aicred_session_open_with_encoding(cHome, C.int32_t(opts.Encoding))
+	if handle == nil {
+		return nil, &Error{Op: "open session", Code: CodeUnknown, Message: "invalid home directory or unsupported encoding " + opts.Encoding.String()}
+	}
+	s := &Session{handle: handle, homeDir: homeDir, encoding: opts.Encoding, events: opts.Events, revisions: opts.Revisions, actor: opts.Actor}
+	if s.actor == "" {
+		s.actor = defaultActor()
+	}
+	runtime.SetFinalizer(s, (*Session).Close)
+
+	if _, err := s.LoadInstances(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadInstances returns every provider instance, sorted by ID
+func (s *Session) LoadInstances() ([]ProviderInstance, error) {
+	var instances []ProviderInstance
+	err := s.call("load instances", &instances, func(h *C.AicredSession) *C.char {
+		return C.aicred_session_instances(h)
+	})
+	return instances, err
+}
+
+// GetInstance returns the instance with the given ID, or an error wrapping
+// ErrNotFound
+func (s *Session) GetInstance(id string) (*ProviderInstance, error) {
+	cID := C.CString(id)
+	defer C.free(unsafe.Pointer(cID))
+
+	var instance ProviderInstance
+	err := s.call("get instance", &instance, func(h *C.AicredSession) *C.char {
+		return C.aicred_session_get_instance(h, cID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// LoadLabels returns every label assignment
+func (s *Session) LoadLabels() ([]LabelAssignment, error) {
+	var labels []LabelAssignment
+	err := s.call("load labels", &labels, func(h *C.AicredSession) *C.char {
+		return C.aicred_session_labels(h)
+	})
+	return labels, err
+}
+
+// SaveLabels replaces all label assignments and writes them to labels.yaml
+func (s *Session) SaveLabels(labels []LabelAssignment) error {
+	if labels == nil {
+		labels = []LabelAssignment{}
+	}
+	before, err := s.LoadLabels()
+	if err != nil {
+		return err
+	}
+	if err := s.snapshot(); err != nil {
+		return fmt.Errorf("save labels: %w", err)
+	}
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels: %v", err)
+	}
+	cLabels := C.CString(string(data))
+	defer C.free(unsafe.Pointer(cLabels))
+
+	var ignored json.RawMessage
+	err = s.call("save labels", &ignored, func(h *C.AicredSession) *C.char {
+		return C.aicred_session_save_labels(h, cLabels)
+	})
+	if err != nil {
+		return err
+	}
+	s.events.Publish(events.StoreChanged{Time: time.Now().UTC(), Store: "labels", Count: len(labels)})
+	return s.audit("SaveLabels", planLabels(labels, before))
+}
+
+// Scan scans the session's home directory. options.HomeDir is ignored.
+// Results cross the FFI in the session's encoding. The Go-side passes run
+// as they do for Scan.
+func (s *Session) Scan(options ScanOptions) (*ScanResult, error) {
+	start := time.Now()
+	result, err := s.scan(options, start)
+	completed := events.ScanCompleted{Time: time.Now().UTC(), HomeDir: s.homeDir, Duration: time.Since(start), Err: err}
+	if result != nil {
+		completed.Keys, completed.ConfigInstances = len(result.Keys), len(result.ConfigInstances)
+	}
+	s.events.Publish(completed)
+	return result, err
+}
+
+func (s *Session) scan(options ScanOptions, start time.Time) (*ScanResult, error) {
+	options.HomeDir = s.homeDir
+	level, optionsJSON, err := prepareScan(options)
+	if err != nil {
+		return nil, err
+	}
+	run := newScanRun(options, start)
+	result, err := s.ffiScan(optionsJSON)
+	if err != nil {
+		return nil, err
+	}
+	if result.HomeDir == "" {
+		result.HomeDir = s.homeDir
+	}
+	return completeScan(result, options, level, start, run)
+}
+
+// ffiScan runs the core library's scan on the session handle
+func (s *Session) ffiScan(optionsJSON []byte) (*ScanResult, error) {
+	cOptions := C.CString(string(optionsJSON))
+	defer C.free(unsafe.Pointer(cOptions))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle == nil {
+		return nil, ErrClosed
+	}
+
+	var n C.uintptr_t
+	ptr := C.aicred_session_scan(s.handle, cOptions, &n)
+	if ptr == nil {
+		return nil, fmt.Errorf("scan failed: FFI returned null")
+	}
+	defer C.aicred_bytes_free(ptr, n)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(ptr)), int(n))
+
+	var result ScanResult
+	if err := decodeEnvelopeBytes("scan", data, s.encoding, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Reload drops the cached state so the next call re-reads the files
+func (s *Session) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle == nil {
+		return ErrClosed
+	}
+	C.aicred_session_reload(s.handle)
+	return nil
+}
+
+// Close releases the Rust-side handle. It is safe to call more than once.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle != nil {
+		C.aicred_session_close(s.handle)
+		s.handle = nil
+		runtime.SetFinalizer(s, nil)
+	}
+	return nil
+}
+
+// call runs fn against the open handle and decodes its envelope into out
+func (s *Session) call(op string, out any, fn func(*C.AicredSession) *C.char) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle == nil {
+		return ErrClosed
+	}
+	return decodeEnvelope(op, fn(s.handle), out)
+}
@@ -0,0 +1,169 @@
+package aicred
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/events"
+)
+
+func writeInstance(t *testing.T, home string) {
+	t.Helper()
+	dir := filepath.Join(home, ".config", "aicred", "inference_services")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	yaml := "id: openai-main\nprovider_type: openai\nbase_url: https://api.openai.com/v1\napi_key: sk-test\nmodels: [gpt-4o]\n" +
+		"capabilities: {chat: true, completion: false, embedding: false, image_generation: false, function_calling: false, streaming: true}\n"
+	if err := os.WriteFile(filepath.Join(dir, "openai-main.yaml"), []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	instances, err := s.LoadInstances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 1 || instances[0].ID != "openai-main" || !instances[0].Capabilities.Chat {
+		t.Fatalf("unexpected instances: %+v", instances)
+	}
+	if instances[0].APIKey.Reveal() != "sk-test" {
+		t.Error("API key should decode into a Secret")
+	}
+	if got := fmt.Sprintf("%+v", instances[0]); strings.Contains(got, "sk-test") {
+		t.Error("API key should format redacted")
+	}
+
+	if _, err := s.GetInstance("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	labels := []LabelAssignment{{
+		LabelName:  "fast",
+		Target:     LabelTarget{Type: LabelTargetModel, InstanceID: "openai-main", ModelID: "gpt-4o"},
+		AssignedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	if err := s.SaveLabels(labels); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.LoadLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].Target != labels[0].Target {
+		t.Errorf("labels did not round trip: %+v", loaded)
+	}
+
+	s.Close()
+	if _, err := s.LoadInstances(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after Close, got %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close should be a no-op, got %v", err)
+	}
+}
+
+func TestSessionScanEncodings(t *testing.T) {
+	home := t.TempDir()
+	for _, enc := range []Encoding{EncodingJSON, EncodingCBOR} {
+		s, err := OpenSessionWith(home, SessionOptions{Encoding: enc})
+		if err != nil {
+			t.Fatalf("%s: %v", enc, err)
+		}
+		result, err := s.Scan(ScanOptions{HomeDir: "/ignored"})
+		if err != nil {
+			t.Fatalf("%s: %v", enc, err)
+		}
+		if result.HomeDir != home {
+			t.Errorf("%s: scanned %q, want the session home %q", enc, result.HomeDir, home)
+		}
+		for _, key := range result.Keys {
+			if !key.Value.IsZero() {
+				t.Errorf("%s: default redaction should drop values", enc)
+			}
+		}
+		s.Close()
+	}
+
+	if _, err := OpenSessionWith(home, SessionOptions{Encoding: Encoding(7)}); err == nil {
+		t.Error("expected error for unsupported encoding")
+	}
+}
+
+func TestSessionScanGoPasses(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, "backup.zip"), zipBytes(t, map[string][]byte{".env": []byte(archiveKey)}))
+	s, err := OpenSession(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	result, err := s.Scan(ScanOptions{ScanArchives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(result.Keys, func(k DiscoveredKey) bool { return k.Source == filepath.Join(home, "backup.zip")+"!.env" }) {
+		t.Errorf("keys = %+v, want the archived key", result.Keys)
+	}
+	if _, ok := result.Stats.DurationMSByScanner[passArchives]; !ok {
+		t.Errorf("stats = %+v, want the archives pass", result.Stats)
+	}
+}
+
+func TestSessionEvents(t *testing.T) {
+	home := t.TempDir()
+	writeInstance(t, home)
+	bus := events.New()
+	var got []events.Event
+	bus.SubscribeAll(func(e events.Event) { got = append(got, e) })
+
+	s, err := OpenSessionWith(home, SessionOptions{Events: bus})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Scan(ScanOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dangling := LabelAssignment{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "gone"}}
+	if err := s.SaveLabels([]LabelAssignment{dangling}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CheckIntegrity(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d events: %+v", len(got), got)
+	}
+	if scan, ok := got[0].(events.ScanCompleted); !ok || scan.HomeDir != home || scan.Err != nil {
+		t.Errorf("first event = %+v, want ScanCompleted", got[0])
+	}
+	if store, ok := got[1].(events.StoreChanged); !ok || store.Store != "labels" || store.Count != 1 {
+		t.Errorf("second event = %+v, want StoreChanged", got[1])
+	}
+	if failed, ok := got[2].(events.ValidationFailed); !ok || len(failed.Problems) != 1 || !strings.Contains(failed.Problems[0], "gone") {
+		t.Errorf("third event = %+v, want ValidationFailed", got[2])
+	}
+}
@@ -0,0 +1,56 @@
+package aicred
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+)
+
+// SignResult signs result with an Ed25519 key and stores the signature in
+// result.Signature, so an agent or CI job can submit a report whose
+// recipient can tell it was not altered. The signature covers the result as
+// json.Marshal encodes it, which never includes key values; hashes, sources
+// and everything else are covered.
+func SignResult(result *ScanResult, key ed25519.PrivateKey) error {
+	if len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid Ed25519 private key length %d", len(key))
+	}
+	msg, err := signedBytes(result)
+	if err != nil {
+		return err
+	}
+	result.Signature = ed25519.Sign(key, msg)
+	return nil
+}
+
+// VerifyResult checks result.Signature against an Ed25519 public key. It
+// returns an error wrapping ErrBadSignature if the result is unsigned or
+// was changed after signing.
+func VerifyResult(result *ScanResult, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid Ed25519 public key length %d", len(key))
+	}
+	if len(result.Signature) == 0 {
+		return fmt.Errorf("result is not signed: %w", ErrBadSignature)
+	}
+	msg, err := signedBytes(result)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, msg, result.Signature) {
+		return fmt.Errorf("verifying result: %w", ErrBadSignature)
+	}
+	return nil
+}
+
+// signedBytes is the encoding of result a signature covers: its JSON without
+// the signature. Results decoded from JSON encode back to the same bytes.
+func signedBytes(result *ScanResult) ([]byte, error) {
+	unsigned := *result
+	unsigned.Signature = nil
+	msg, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,52 @@
+package aicred
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSignResult(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &ScanResult{
+		HomeDir: "/home/u",
+		Keys:    []DiscoveredKey{{Provider: "openai", Source: "/home/u/.env", Hash: "h1", Value: NewSecretString("sk-secret")}},
+		Stats:   ScanStats{FilesExamined: 3, ErrorsByScanner: map[string]int64{"gsh": 1}},
+	}
+	if err := VerifyResult(result, pub); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("unsigned result: err = %v, want ErrBadSignature", err)
+	}
+	if err := SignResult(result, priv); err != nil {
+		t.Fatalf("SignResult: %v", err)
+	}
+
+	// The signature survives the trip to whoever verifies it
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received ScanResult
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyResult(&received, pub); err != nil {
+		t.Fatalf("VerifyResult after JSON round trip: %v", err)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	if err := VerifyResult(&received, otherPub); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("wrong key: err = %v, want ErrBadSignature", err)
+	}
+	received.Keys[0].Source = "/tmp/elsewhere"
+	if err := VerifyResult(&received, pub); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered result: err = %v, want ErrBadSignature", err)
+	}
+
+	if err := SignResult(result, priv[:10]); err == nil {
+		t.Error("SignResult accepted a truncated key")
+	}
+}
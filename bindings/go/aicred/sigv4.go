@@ -0,0 +1,182 @@
+package aicred
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sigV4Source signs requests with AWS Signature Version 4, as Bedrock
+// requires
+type sigV4Source struct {
+	region       string
+	service      string
+	accessKeyID  string
+	secretKey    Secret
+	sessionToken Secret
+	now          func() time.Time
+}
+
+func newSigV4Source(instance ProviderInstance) (*sigV4Source, error) {
+	meta := instance.Metadata
+	s := &sigV4Source{
+		region:      meta[MetadataAWSRegion],
+		service:     meta[MetadataAWSService],
+		accessKeyID: meta[MetadataAWSAccessKeyID],
+		secretKey:   instance.APIKey,
+		now:         time.Now,
+	}
+	if s.service == "" {
+		s.service = "bedrock"
+	}
+	if s.accessKeyID == "" {
+		s.accessKeyID = getenv("AWS_ACCESS_KEY_ID")
+		s.secretKey = NewSecretString(getenv("AWS_SECRET_ACCESS_KEY"))
+		if token := getenv("AWS_SESSION_TOKEN"); token != "" {
+			s.sessionToken = NewSecretString(token)
+		}
+	}
+	if s.region == "" {
+		s.region = getenv("AWS_REGION")
+	}
+	switch {
+	case s.region == "":
+		return nil, fmt.Errorf("instance %q: %s needs %s: %w", instance.ID, AuthAWSSigV4, MetadataAWSRegion, ErrInvalidOption)
+	case s.accessKeyID == "" || s.secretKey.IsZero():
+		return nil, fmt.Errorf("instance %q: %s needs %s and the secret access key in api_key: %w", instance.ID, AuthAWSSigV4, MetadataAWSAccessKeyID, ErrInvalidOption)
+	}
+	return s, nil
+}
+
+func (s *sigV4Source) Token(context.Context) (Token, error) {
+	return Token{}, fmt.Errorf("%s signs each request and has no token; use Authorize: %w", AuthAWSSigV4, ErrInvalidOption)
+}
+
+// Authorize signs req, reading and restoring its body to hash it. It sets
+// X-Amz-Date, X-Amz-Security-Token for temporary credentials, and
+// Authorization.
+func (s *sigV4Source) Authorize(_ context.Context, req *http.Request) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return fmt.Errorf("sign request: %w: %v", ErrIO, err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	payload := sha256.Sum256(body)
+
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if !s.sessionToken.IsZero() {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken.Reveal())
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	// Host and the X-Amz-* headers are signed; others may be changed by
+	// proxies on the way
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4Path(req.URL),
+		sigV4Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+	scope := date + "/" + s.region + "/" + s.service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	var key []byte
+	s.secretKey.Use(func(secret []byte) {
+		key = hmacSHA256(append([]byte("AWS4"), secret...), date)
+	})
+	for _, part := range []string{s.region, s.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	zero(key)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sigV4Path is the path as sent, with each segment URI-encoded once more
+// as every service but S3 expects; Bedrock model IDs containing ":"
+// depend on it
+func sigV4Path(u *url.URL) string {
+	p := u.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = sigV4Escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// sigV4Query is the query string sorted by name and then value
+func sigV4Query(values url.Values) string {
+	var pairs []string
+	for name, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, sigV4Escape(name)+"="+sigV4Escape(v))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape percent-encodes everything but the unreserved characters
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
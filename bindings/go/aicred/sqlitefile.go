@@ -0,0 +1,161 @@
+package aicred
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// sqliteMagic starts every SQLite 3 database file
+const sqliteMagic = "SQLite format 3\x00"
+
+// sqliteStrings returns one line per table row of a SQLite database, holding
+// the row's text columns joined with " = " so that key/value settings tables
+// read like assignments. Only the part of each row stored on its leaf page is
+// read; overflow pages, indexes and UTF-16 databases are not decoded.
+func sqliteStrings(data []byte) []string {
+	var lines []string
+	sqliteRows(data, func(row []sqliteColumn) {
+		var text []string
+		for _, col := range row {
+			if col.text {
+				text = append(text, string(col.data))
+			}
+		}
+		if len(text) > 0 {
+			lines = append(lines, strings.Join(text, " = "))
+		}
+	})
+	return lines
+}
+
+// sqliteColumn is a text or blob value of a row
+type sqliteColumn struct {
+	text bool
+	data []byte
+}
+
+// sqliteRows calls fn with the text and blob columns of every table row,
+// with the same limits as sqliteStrings. Column data aliases data.
+func sqliteRows(data []byte, fn func(row []sqliteColumn)) {
+	if len(data) < 100 || string(data[:len(sqliteMagic)]) != sqliteMagic {
+		return
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || binary.BigEndian.Uint32(data[56:60]) > 1 {
+		// Invalid page size, or a UTF-16 text encoding
+		return
+	}
+	usable := pageSize - int(data[20])
+
+	for start := 0; start+pageSize <= len(data); start += pageSize {
+		page := data[start : start+pageSize]
+		header := 0
+		if start == 0 {
+			header = 100
+		}
+		// 0x0d marks a table b-tree leaf page, which holds the rows
+		if page[header] != 0x0d {
+			continue
+		}
+		cells := int(binary.BigEndian.Uint16(page[header+3:]))
+		for i := 0; i < cells; i++ {
+			ptr := header + 8 + 2*i
+			if ptr+2 > len(page) {
+				break
+			}
+			if row := sqliteRow(page, int(binary.BigEndian.Uint16(page[ptr:])), usable); len(row) > 0 {
+				fn(row)
+			}
+		}
+	}
+}
+
+// sqliteRow decodes the text and blob columns of the table leaf cell at off
+func sqliteRow(page []byte, off, usable int) []sqliteColumn {
+	payloadSize, n := sqliteVarint(page, off)
+	if n == 0 || payloadSize > 1<<31 {
+		return nil
+	}
+	off += n
+	if _, n = sqliteVarint(page, off); n == 0 { // rowid
+		return nil
+	}
+	off += n
+
+	// Rows too large for the page keep only a prefix locally
+	local := int(payloadSize)
+	if maxLocal := usable - 35; local > maxLocal {
+		minLocal := (usable-12)*32/255 - 23
+		local = minLocal + (int(payloadSize)-minLocal)%(usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if off+local > len(page) {
+		return nil
+	}
+	payload := page[off : off+local]
+
+	headerSize, n := sqliteVarint(payload, 0)
+	if n == 0 || int(headerSize) > len(payload) {
+		return nil
+	}
+	var row []sqliteColumn
+	body := int(headerSize)
+	for at := n; at < int(headerSize); {
+		serial, n := sqliteVarint(payload, at)
+		if n == 0 {
+			return row
+		}
+		at += n
+		size := sqliteSerialSize(serial)
+		if size < 0 || body+size > len(payload) {
+			return row
+		}
+		if serial >= 12 && size > 0 {
+			row = append(row, sqliteColumn{text: serial%2 == 1, data: payload[body : body+size]})
+		}
+		body += size
+	}
+	return row
+}
+
+// sqliteSerialSize is the byte length of a value of the given serial type
+func sqliteSerialSize(serial uint64) int {
+	switch {
+	case serial <= 4:
+		return int(serial)
+	case serial == 5:
+		return 6
+	case serial == 6, serial == 7:
+		return 8
+	case serial >= 12 && serial < 1<<32:
+		return int((serial - 12) / 2)
+	case serial >= 12:
+		return -1
+	}
+	return 0
+}
+
+// sqliteVarint decodes a SQLite variable-length integer, returning the value
+// and its length in bytes, or a length of 0 if b is too short
+func sqliteVarint(b []byte, off int) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if off+i >= len(b) {
+			return 0, 0
+		}
+		c := b[off+i]
+		if i == 8 {
+			return v<<8 | uint64(c), 9
+		}
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, 9
+}
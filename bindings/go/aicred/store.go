@@ -0,0 +1,81 @@
+package aicred
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Store is where provider instances and label assignments are kept. A
+// Session is the Store over the aicred configuration directory; a
+// MemoryStore stands in for it in tests and for programs that keep their
+// configuration elsewhere.
+type Store interface {
+	// LoadInstances returns every provider instance, sorted by ID
+	LoadInstances() ([]ProviderInstance, error)
+	// GetInstance returns the instance with the given ID, or an error
+	// wrapping ErrNotFound
+	GetInstance(id string) (*ProviderInstance, error)
+	// LoadLabels returns every label assignment
+	LoadLabels() ([]LabelAssignment, error)
+	// SaveLabels replaces all label assignments
+	SaveLabels(labels []LabelAssignment) error
+}
+
+var (
+	_ Store = (*Session)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// MemoryStore is a Store held in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu             sync.Mutex
+	instances      []ProviderInstance
+	labels         []LabelAssignment
+	organizations  []Organization
+	tags           []Tag
+	tagAssignments []LabelAssignment
+}
+
+// NewMemoryStore returns a store holding copies of instances and labels
+func NewMemoryStore(instances []ProviderInstance, labels []LabelAssignment) *MemoryStore {
+	instances = slices.Clone(instances)
+	slices.SortStableFunc(instances, func(a, b ProviderInstance) int { return strings.Compare(a.ID, b.ID) })
+	return &MemoryStore{instances: instances, labels: slices.Clone(labels)}
+}
+
+// LoadInstances returns every provider instance, sorted by ID
+func (m *MemoryStore) LoadInstances() ([]ProviderInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.instances), nil
+}
+
+// GetInstance returns the instance with the given ID, or an error wrapping
+// ErrNotFound
+func (m *MemoryStore) GetInstance(id string) (*ProviderInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, instance := range m.instances {
+		if instance.ID == id {
+			return &instance, nil
+		}
+	}
+	return nil, fmt.Errorf("instance %q: %w", id, ErrNotFound)
+}
+
+// LoadLabels returns every label assignment
+func (m *MemoryStore) LoadLabels() ([]LabelAssignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.labels), nil
+}
+
+// SaveLabels replaces all label assignments
+func (m *MemoryStore) SaveLabels(labels []LabelAssignment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.labels = slices.Clone(labels)
+	return nil
+}
@@ -0,0 +1,109 @@
+package aicred
+
+import (
+	"fmt"
+	"slices"
+)
+
+// InstancesByTag returns the instances the tag is assigned to in
+// TagAssignments, directly, through one of their models or through any tag
+// under it in the tag hierarchy, in config order, as ExportTemplate's byTag
+// reads it
+func (c *Config) InstancesByTag(name string) []ProviderInstance {
+	return instancesLabeled(c.Instances, c.TagAssignments, tagTree(c.Tags, name))
+}
+
+// ExportByTag returns the part of c that InstancesByTag selects: those
+// instances, the label assignments on them, and the tag with the tags
+// under it and their assignments to those instances. Pass it to MarshalConfig, SaveConfigFile or ExportTemplate to
+// hand one environment's configuration to a tool or another machine.
+func (c *Config) ExportByTag(name string) *Config {
+	instances := c.InstancesByTag(name)
+	names := tagTree(c.Tags, name)
+	export := &Config{Instances: instances, Tags: []Tag{}, Labels: []LabelAssignment{}, TagAssignments: []LabelAssignment{}}
+	for _, tag := range c.Tags {
+		if slices.Contains(names, tag.Name) {
+			export.Tags = append(export.Tags, tag)
+		}
+	}
+	exported := func(a LabelAssignment) bool {
+		return slices.ContainsFunc(instances, func(p ProviderInstance) bool { return p.ID == a.Target.InstanceID })
+	}
+	for _, label := range c.Labels {
+		if exported(label) {
+			export.Labels = append(export.Labels, label)
+		}
+	}
+	for _, assignment := range c.TagAssignments {
+		if exported(assignment) && slices.Contains(names, assignment.LabelName) {
+			export.TagAssignments = append(export.TagAssignments, assignment)
+		}
+	}
+	return export
+}
+
+// DeactivateByTag marks every instance in store that has the tag, as
+// InstancesByTag finds them, inactive. It returns the IDs of the instances
+// it changed; those already inactive are left alone. A tag store does not
+// define gives an error wrapping ErrNotFound. A Session takes one revision
+// for the whole operation, so RollbackTo can undo it.
+func DeactivateByTag(store ConfigStore, name string) ([]string, error) {
+	return updateByTag(store, "deactivate", name, func(instance *ProviderInstance) (bool, error) {
+		if !instance.Active {
+			return false, nil
+		}
+		instance.Active = false
+		return true, nil
+	})
+}
+
+// RotateKeysByTag gives every instance in store that has the tag the key
+// newKey returns for it, such as one just issued by the provider's console
+// API. It returns the IDs of the instances it changed, and stops at the
+// first error from newKey or the store, leaving the instances before it
+// rotated. A tag store does not define gives an error wrapping
+// ErrNotFound, and an empty key one wrapping ErrInvalidOption.
+func RotateKeysByTag(store ConfigStore, name string, newKey func(instance ProviderInstance) (Secret, error)) ([]string, error) {
+	return updateByTag(store, "rotate keys", name, func(instance *ProviderInstance) (bool, error) {
+		key, err := newKey(*instance)
+		if err != nil {
+			return false, err
+		}
+		if key.IsZero() {
+			return false, fmt.Errorf("no new key for instance %q: %w", instance.ID, ErrInvalidOption)
+		}
+		instance.APIKey = key
+		return true, nil
+	})
+}
+
+// updateByTag applies update to each instance with the tag and saves those
+// it changed, in one batch when the store can group writes
+func updateByTag(store ConfigStore, op, name string, update func(instance *ProviderInstance) (bool, error)) ([]string, error) {
+	config, err := CurrentConfig(store)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(config.Tags, func(t Tag) bool { return t.Name == name }) {
+		return nil, fmt.Errorf("%s by tag %q: %w", op, name, ErrNotFound)
+	}
+	var changed []string
+	run := func() error {
+		for _, instance := range config.InstancesByTag(name) {
+			ok, err := update(&instance)
+			if err != nil {
+				return fmt.Errorf("%s by tag %q: %w", op, name, err)
+			}
+			if !ok {
+				continue
+			}
+			if err := store.SaveInstance(instance); err != nil {
+				return fmt.Errorf("%s by tag %q: %w", op, name, err)
+			}
+			changed = append(changed, instance.ID)
+		}
+		return nil
+	}
+	err = batched(store, run)
+	return changed, err
+}
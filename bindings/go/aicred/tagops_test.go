@@ -0,0 +1,110 @@
+package aicred
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func tagStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	label := func(name, id string) LabelAssignment {
+		return LabelAssignment{LabelName: name, Target: LabelTarget{Type: LabelTargetInstance, InstanceID: id}}
+	}
+	store := NewMemoryStore([]ProviderInstance{
+		{ID: "eu", ProviderType: "openai", APIKey: NewSecretString("sk-eu"), Active: true},
+		{ID: "us", ProviderType: "openai", APIKey: NewSecretString("sk-us"), Active: false},
+		{ID: "dev", ProviderType: "openai", APIKey: NewSecretString("sk-dev"), Active: true},
+		{ID: "model", ProviderType: "anthropic", APIKey: NewSecretString("sk-model"), Active: true},
+	}, []LabelAssignment{
+		// A label that shares a tag's name does not assign the tag
+		label("fast", "eu"), label("prod", "dev"),
+	})
+	store.SaveTags([]Tag{{Name: "prod"}, {Name: "prod-eu", Parent: "prod"}, {Name: "dev"}})
+	store.SaveTagAssignments([]LabelAssignment{
+		label("prod-eu", "eu"), label("prod", "us"), label("dev", "dev"),
+		{LabelName: "prod", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "model", ModelID: "claude-3"}},
+	})
+	return store
+}
+
+func TestInstancesByTag(t *testing.T) {
+	config, err := CurrentConfig(tagStore(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, instance := range config.InstancesByTag("prod") {
+		ids = append(ids, instance.ID)
+	}
+	if !slices.Equal(ids, []string{"eu", "model", "us"}) {
+		t.Errorf("InstancesByTag(prod) = %v", ids)
+	}
+	if got := config.InstancesByTag("missing"); got != nil {
+		t.Errorf("InstancesByTag(missing) = %+v", got)
+	}
+
+	export := config.ExportByTag("prod-eu")
+	if len(export.Instances) != 1 || export.Instances[0].ID != "eu" {
+		t.Errorf("exported instances = %+v", export.Instances)
+	}
+	if len(export.Tags) != 1 || export.Tags[0].Name != "prod-eu" {
+		t.Errorf("exported tags = %+v", export.Tags)
+	}
+	if len(export.Labels) != 1 || export.Labels[0].LabelName != "fast" {
+		t.Errorf("exported labels = %+v", export.Labels)
+	}
+	if len(export.TagAssignments) != 1 || export.TagAssignments[0].LabelName != "prod-eu" {
+		t.Errorf("exported tag assignments = %+v", export.TagAssignments)
+	}
+}
+
+func TestDeactivateByTag(t *testing.T) {
+	store := tagStore(t)
+	changed, err := DeactivateByTag(store, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"eu", "model"}) {
+		t.Errorf("deactivated %v", changed)
+	}
+	instances, _ := store.LoadInstances()
+	for _, instance := range instances {
+		if instance.Active != (instance.ID == "dev") {
+			t.Errorf("%s active = %v", instance.ID, instance.Active)
+		}
+	}
+	if _, err := DeactivateByTag(store, "staging"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown tag: err = %v", err)
+	}
+}
+
+func TestRotateKeysByTag(t *testing.T) {
+	store := tagStore(t)
+	changed, err := RotateKeysByTag(store, "prod-eu", func(instance ProviderInstance) (Secret, error) {
+		return NewSecretString("sk-new-" + instance.ID), nil
+	})
+	if err != nil || !slices.Equal(changed, []string{"eu"}) {
+		t.Fatalf("rotated %v, %v", changed, err)
+	}
+	if eu, _ := store.GetInstance("eu"); eu.APIKey.Reveal() != "sk-new-eu" {
+		t.Errorf("eu key = %q", eu.APIKey.Reveal())
+	}
+	if us, _ := store.GetInstance("us"); us.APIKey.Reveal() != "sk-us" {
+		t.Errorf("us key = %q", us.APIKey.Reveal())
+	}
+
+	failure := errors.New("console unavailable")
+	changed, err = RotateKeysByTag(store, "prod", func(instance ProviderInstance) (Secret, error) {
+		if instance.ID == "model" {
+			return Secret{}, failure
+		}
+		return NewSecretString("sk-newer"), nil
+	})
+	if !errors.Is(err, failure) || !slices.Equal(changed, []string{"eu"}) {
+		t.Errorf("rotation stopping at a failure: %v, %v", changed, err)
+	}
+	if _, err := RotateKeysByTag(store, "dev", func(ProviderInstance) (Secret, error) { return Secret{}, nil }); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("empty key: err = %v", err)
+	}
+}
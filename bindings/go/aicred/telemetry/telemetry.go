@@ -0,0 +1,193 @@
+// Package telemetry reports anonymous, aggregate scan counts that help
+// decide which scanners and providers to work on next. It is off until the
+// caller enables it.
+//
+// A payload holds counts and timings only: how many keys each known provider
+// had, how long each scanner took and how often it failed, and the library
+// version and platform. It never holds key values, hashes, redacted
+// prefixes, paths, hostnames or user names. Preview returns the exact bytes
+// Send would post so they can be shown to the user first.
+//
+// Setting DO_NOT_TRACK=1 or AICRED_TELEMETRY=0 in the environment disables
+// telemetry regardless of Enable.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+// SchemaVersion identifies the payload layout
+const SchemaVersion = 1
+
+// otherProvider counts keys whose provider is not a built-in one, since a
+// custom provider name could identify the user
+const otherProvider = "other"
+
+// ErrDisabled is returned by Send when telemetry is not enabled
+var ErrDisabled = errors.New("telemetry: disabled")
+
+// Payload is everything one telemetry report contains
+type Payload struct {
+	Schema  int    `json:"schema"`
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	// KeysByProvider counts keys found per built-in provider
+	KeysByProvider  map[string]int `json:"keys_by_provider"`
+	ConfigInstances int            `json:"config_instances"`
+	FilesExamined   int64          `json:"files_examined"`
+	DurationMS      int64          `json:"duration_ms"`
+	// ScannerDurationMS and ScannerErrors are keyed by scanner or Go-side
+	// pass name
+	ScannerDurationMS map[string]int64 `json:"scanner_duration_ms"`
+	ScannerErrors     map[string]int64 `json:"scanner_errors"`
+	// WarningsByKind counts warnings such as "parse_error"
+	WarningsByKind map[string]int `json:"warnings_by_kind"`
+}
+
+// NewPayload reduces a scan result to its telemetry payload
+func NewPayload(result *aicred.ScanResult) Payload {
+	known := map[string]bool{}
+	for _, p := range aicred.ListProviders() {
+		known[p] = true
+	}
+	for _, p := range detect.Providers() {
+		known[p] = true
+	}
+
+	p := Payload{
+		Schema:            SchemaVersion,
+		Version:           aicred.Version(),
+		OS:                runtime.GOOS,
+		Arch:              runtime.GOARCH,
+		KeysByProvider:    map[string]int{},
+		ConfigInstances:   len(result.ConfigInstances),
+		FilesExamined:     result.Stats.FilesExamined,
+		DurationMS:        result.Stats.DurationMS,
+		ScannerDurationMS: map[string]int64{},
+		ScannerErrors:     map[string]int64{},
+		WarningsByKind:    map[string]int{},
+	}
+	for _, key := range result.Keys {
+		provider := strings.ToLower(key.Provider)
+		if !known[provider] {
+			provider = otherProvider
+		}
+		p.KeysByProvider[provider]++
+	}
+	for scanner, ms := range result.Stats.DurationMSByScanner {
+		p.ScannerDurationMS[scanner] = ms
+	}
+	for scanner, n := range result.Stats.ErrorsByScanner {
+		p.ScannerErrors[scanner] = n
+	}
+	for _, w := range result.Warnings {
+		p.WarningsByKind[string(w.Kind)]++
+	}
+	return p
+}
+
+// Client sends telemetry to an endpoint. The zero value is disabled and
+// has no endpoint.
+type Client struct {
+	// Endpoint is the URL payloads are posted to
+	Endpoint   string
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	enabled bool
+}
+
+// Enable turns telemetry on. Callers should do so only with the user's
+// consent and remember that choice themselves.
+func (c *Client) Enable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = true
+}
+
+// Disable turns telemetry off
+func (c *Client) Disable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = false
+}
+
+// Enabled reports whether Send will post, taking the DO_NOT_TRACK and
+// AICRED_TELEMETRY environment variables into account
+func (c *Client) Enabled() bool {
+	if optedOut() {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled
+}
+
+func optedOut() bool {
+	if v := os.Getenv("DO_NOT_TRACK"); v != "" && v != "0" {
+		return true
+	}
+	switch strings.ToLower(os.Getenv("AICRED_TELEMETRY")) {
+	case "0", "false", "off", "no":
+		return true
+	}
+	return false
+}
+
+// Preview returns the exact body Send would post for result
+func (c *Client) Preview(result *aicred.ScanResult) ([]byte, error) {
+	body, err := json.Marshal(NewPayload(result))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	return body, nil
+}
+
+// Send posts the payload for result, or returns ErrDisabled without doing
+// anything when telemetry is not enabled
+func (c *Client) Send(ctx context.Context, result *aicred.ScanResult) error {
+	if !c.Enabled() {
+		return ErrDisabled
+	}
+	if c.Endpoint == "" {
+		return errors.New("telemetry: no endpoint")
+	}
+	body, err := c.Preview(result)
+	if err != nil {
+		return err
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("telemetry rejected: %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
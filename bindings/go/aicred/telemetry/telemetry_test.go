@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func scanResult() *aicred.ScanResult {
+	return &aicred.ScanResult{
+		HomeDir: "/home/alice",
+		Host:    "alice-laptop",
+		Keys: []aicred.DiscoveredKey{
+			{Provider: "openai", Source: "/home/alice/.env", Hash: "deadbeefhash", Redacted: "sk-****wxyz", Value: aicred.NewSecretString("sk-secretvalue")},
+			{Provider: "openai", Source: "/home/alice/.zshrc", Hash: "h2"},
+			{Provider: "acme-internal-llm", Source: "/home/alice/acme.yaml", Hash: "h3"},
+		},
+		ConfigInstances: []aicred.ConfigInstance{{InstanceID: "i1", ConfigPath: "/home/alice/.roo"}},
+		Stats: aicred.ScanStats{
+			FilesExamined:       12,
+			DurationMS:          40,
+			ErrorsByScanner:     map[string]int64{"gsh": 1},
+			DurationMSByScanner: map[string]int64{"gsh": 3, "archives": 20},
+		},
+		Warnings: []aicred.ScanWarning{{Scanner: "gsh", Path: "/home/alice/.gshrc", Kind: aicred.WarningParseError, Message: "bad line"}},
+	}
+}
+
+func TestPayloadIsAnonymous(t *testing.T) {
+	p := NewPayload(scanResult())
+	if p.KeysByProvider["openai"] != 2 || p.KeysByProvider[otherProvider] != 1 || len(p.KeysByProvider) != 2 {
+		t.Errorf("KeysByProvider = %v", p.KeysByProvider)
+	}
+	if p.ScannerDurationMS["archives"] != 20 || p.ScannerErrors["gsh"] != 1 || p.WarningsByKind["parse_error"] != 1 {
+		t.Errorf("payload = %+v", p)
+	}
+
+	body, err := (&Client{}).Preview(scanResult())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{"alice", "deadbeef", "sk-", "wxyz", "acme", ".env", "bad line"} {
+		if strings.Contains(string(body), leak) {
+			t.Errorf("payload contains %q: %s", leak, body)
+		}
+	}
+}
+
+func TestSendOnlyWhenEnabled(t *testing.T) {
+	t.Setenv("DO_NOT_TRACK", "")
+	t.Setenv("AICRED_TELEMETRY", "")
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	c := &Client{Endpoint: srv.URL}
+	if err := c.Send(context.Background(), scanResult()); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("disabled Send: err = %v, want ErrDisabled", err)
+	}
+	if received != nil {
+		t.Fatal("disabled client posted a payload")
+	}
+
+	c.Enable()
+	if err := c.Send(context.Background(), scanResult()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	preview, _ := c.Preview(scanResult())
+	if !bytes.Equal(received, preview) {
+		t.Errorf("sent %s, previewed %s", received, preview)
+	}
+
+	t.Setenv("DO_NOT_TRACK", "1")
+	if c.Enabled() {
+		t.Error("DO_NOT_TRACK did not disable telemetry")
+	}
+	t.Setenv("DO_NOT_TRACK", "")
+	t.Setenv("AICRED_TELEMETRY", "off")
+	if c.Enabled() {
+		t.Error("AICRED_TELEMETRY=off did not disable telemetry")
+	}
+	t.Setenv("AICRED_TELEMETRY", "")
+	c.Disable()
+	if c.Enabled() {
+		t.Error("Disable did not disable telemetry")
+	}
+}
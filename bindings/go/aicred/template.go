@@ -0,0 +1,96 @@
+package aicred
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/template"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+// ExportTemplate writes config through a text/template, to generate the
+// configuration of tools aicred has no exporter for. The template sees the
+// Config, so {{range .Instances}} visits every instance, and has these
+// functions besides the built-in ones:
+//
+//	byLabel "name"  the instances the label is assigned to, directly or
+//	                through one of their models
+//	byTag "name"    the instances assigned the tag or any tag under it in
+//	                the tag hierarchy
+//	labels .        the labels assigned to an instance, sorted
+//	redact .APIKey  the key in the redacted form scans report, such as ****abcd
+//	reveal .APIKey  the key itself
+//	json .          the value as JSON, such as a quoted string
+//
+// Keys print as a placeholder unless revealed, so a template writes real
+// keys only where it says so. For example, a LiteLLM proxy model list of
+// the production instances:
+//
+//	model_list:
+//	{{- range byTag "prod"}}{{$instance := .}}{{range .Models}}
+//	  - model_name: {{.}}
+//	    litellm_params:
+//	      model: {{$instance.ProviderType}}/{{.}}
+//	      api_base: {{$instance.BaseURL}}
+//	      api_key: {{reveal $instance.APIKey | json}}
+//	{{- end}}{{end}}
+//
+// A template that does not parse gives an error wrapping ErrInvalidOption.
+func ExportTemplate(config *Config, tmpl string, w io.Writer) error {
+	t, err := template.New("export").Funcs(template.FuncMap{
+		"byLabel": func(name string) []ProviderInstance {
+			return instancesLabeled(config.Instances, config.Labels, []string{name})
+		},
+		"byTag": config.InstancesByTag,
+		"labels": func(instance ProviderInstance) []string {
+			return slices.Compact(pairLabels(instance.ID, "", config.Labels))
+		},
+		"redact": func(s Secret) string {
+			if s.IsZero() {
+				return ""
+			}
+			return detect.Redact(s.Reveal())
+		},
+		"reveal": func(s Secret) string { return s.Reveal() },
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w: %v", ErrInvalidOption, err)
+	}
+	if err := t.Execute(w, config); err != nil {
+		return fmt.Errorf("failed to render template: %v", err)
+	}
+	return nil
+}
+
+// instancesLabeled returns the instances that assignments give any of the
+// names, on the instance or on one of its models, in order
+func instancesLabeled(all []ProviderInstance, assignments []LabelAssignment, names []string) []ProviderInstance {
+	var instances []ProviderInstance
+	for _, instance := range all {
+		if slices.ContainsFunc(assignments, func(a LabelAssignment) bool {
+			return a.Target.InstanceID == instance.ID && slices.Contains(names, a.LabelName)
+		}) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
+
+// tagTree returns name and the names of every tag below it
+func tagTree(tags []Tag, name string) []string {
+	names := []string{name}
+	for i := 0; i < len(names); i++ {
+		for _, tag := range tags {
+			if tag.Parent == names[i] && !slices.Contains(names, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+	}
+	return names
+}
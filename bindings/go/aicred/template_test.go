@@ -0,0 +1,65 @@
+package aicred
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestExportTemplate(t *testing.T) {
+	config := &Config{
+		Instances: []ProviderInstance{
+			{ID: "openai-us", ProviderType: "openai", BaseURL: "https://api.openai.com/v1", APIKey: NewSecretString("sk-proj-0123456789"), Models: []string{"gpt-4o"}},
+			{ID: "groq", ProviderType: "groq", BaseURL: "https://api.groq.com/openai/v1", APIKey: NewSecretString("gsk_abcdefghij")},
+			{ID: "ollama", ProviderType: "ollama", BaseURL: "http://localhost:11434", Models: []string{"llama3"}},
+		},
+		Tags: []Tag{{Name: "prod"}, {Name: "prod-us", Parent: "prod"}},
+		TagAssignments: []LabelAssignment{
+			{LabelName: "prod-us", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "openai-us"}},
+		},
+		Labels: []LabelAssignment{
+			{LabelName: "fast", Target: LabelTarget{Type: LabelTargetModel, InstanceID: "groq", ModelID: "llama-3.1-8b"}},
+			{LabelName: "fast", Target: LabelTarget{Type: LabelTargetInstance, InstanceID: "openai-us"}},
+		},
+	}
+
+	tmpl := `model_list:
+{{- range byTag "prod"}}{{$instance := .}}{{range .Models}}
+  - model_name: {{.}}
+    litellm_params:
+      model: {{$instance.ProviderType}}/{{.}}
+      api_base: {{$instance.BaseURL}}
+      api_key: {{reveal $instance.APIKey | json}}
+{{- end}}{{end}}
+fast:{{range byLabel "fast"}} {{.ID}}={{redact .APIKey}}{{end}}
+{{range .Instances}}{{.ID}}{{range labels .}} #{{.}}{{end}}{{if not .APIKey.IsZero}} {{.APIKey}}{{end}}
+{{end}}`
+	var buf bytes.Buffer
+	if err := ExportTemplate(config, tmpl, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `model_list:
+  - model_name: gpt-4o
+    litellm_params:
+      model: openai/gpt-4o
+      api_base: https://api.openai.com/v1
+      api_key: "sk-proj-0123456789"
+fast: openai-us=****6789 groq=****ghij
+openai-us #fast ` + redactedValue + `
+groq ` + redactedValue + `
+ollama
+`
+	if buf.String() != want {
+		t.Errorf("output\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestExportTemplateErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportTemplate(&Config{}, "{{range}}", &buf); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("bad template = %v", err)
+	}
+	if err := ExportTemplate(&Config{}, "{{reveal 1}}", &buf); err == nil {
+		t.Error("executed reveal of a number")
+	}
+}
@@ -0,0 +1,247 @@
+package aicred
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Instance metadata keys describing how an instance authenticates when it
+// does not use a static API key. The secret part of the credential, such
+// as the client secret, refresh token or AWS secret access key, is the
+// instance's APIKey.
+const (
+	// MetadataAuth selects the scheme: one of the Auth* constants; empty
+	// means AuthAPIKey
+	MetadataAuth = "auth"
+	// MetadataTokenURL is the OAuth token endpoint
+	MetadataTokenURL = "token_url"
+	// MetadataClientID is the OAuth client ID
+	MetadataClientID = "client_id"
+	// MetadataScopes are the OAuth scopes to request, space-separated
+	MetadataScopes = "scopes"
+	// MetadataAWSRegion is the AWS region requests are signed for
+	MetadataAWSRegion = "aws_region"
+	// MetadataAWSAccessKeyID is the AWS access key ID; without it the
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+	// variables are used
+	MetadataAWSAccessKeyID = "aws_access_key_id"
+	// MetadataAWSService is the AWS service name requests are signed for;
+	// empty means "bedrock"
+	MetadataAWSService = "aws_service"
+)
+
+// Authentication schemes for MetadataAuth
+const (
+	AuthAPIKey                 = "api_key"
+	AuthOAuthClientCredentials = "oauth_client_credentials"
+	AuthOAuthRefreshToken      = "oauth_refresh_token"
+	AuthAWSSigV4               = "aws_sigv4"
+	// AuthGitHubCopilot exchanges the GitHub OAuth token in the APIKey
+	// for a short-lived Copilot token, from MetadataTokenURL if set
+	AuthGitHubCopilot = "github_copilot"
+)
+
+// tokenExpiryDelta is how long before its expiry a token is refreshed, so
+// it does not lapse in flight
+const tokenExpiryDelta = 30 * time.Second
+
+// Token is a credential to present to a provider
+type Token struct {
+	Value Secret
+	// Type is the Authorization scheme, such as "Bearer"
+	Type string
+	// Expiry is when the token stops working; zero means never
+	Expiry time.Time
+}
+
+// Valid reports whether the token can still be used at now
+func (t Token) Valid(now time.Time) bool {
+	return !t.Value.IsZero() && (t.Expiry.IsZero() || now.Add(tokenExpiryDelta).Before(t.Expiry))
+}
+
+// CredentialSource supplies an instance's credentials, refreshing
+// short-lived ones as they expire. It is safe for concurrent use.
+type CredentialSource interface {
+	// Token returns a valid token, fetching a new one when needed.
+	// AWS SigV4 has no token and returns an error wrapping
+	// ErrInvalidOption; use Authorize.
+	Token(ctx context.Context) (Token, error)
+	// Authorize adds the credentials to req
+	Authorize(ctx context.Context, req *http.Request) error
+}
+
+// TokenSource returns the CredentialSource for instance, chosen by its
+// MetadataAuth: the static APIKey, an OAuth 2.0 client-credentials or
+// refresh-token grant against MetadataTokenURL, AWS SigV4 request signing
+// for Bedrock, or a GitHub Copilot token exchange. Token endpoints are
+// reached with the instance's HTTPClient. It returns an error wrapping
+// ErrInvalidOption if the scheme is unknown or its settings are missing.
+func TokenSource(instance ProviderInstance) (CredentialSource, error) {
+	meta := instance.Metadata
+	switch auth := meta[MetadataAuth]; auth {
+	case "", AuthAPIKey:
+		if instance.APIKey.IsZero() {
+			return nil, fmt.Errorf("instance %q has no API key: %w", instance.ID, ErrInvalidOption)
+		}
+		return staticSource{token: Token{Value: instance.APIKey, Type: "Bearer"}, providerType: instance.ProviderType}, nil
+	case AuthOAuthClientCredentials, AuthOAuthRefreshToken:
+		if meta[MetadataTokenURL] == "" {
+			return nil, fmt.Errorf("instance %q: %s needs %s: %w", instance.ID, auth, MetadataTokenURL, ErrInvalidOption)
+		}
+		if instance.APIKey.IsZero() {
+			return nil, fmt.Errorf("instance %q: %s needs the secret in api_key: %w", instance.ID, auth, ErrInvalidOption)
+		}
+		if auth == AuthOAuthClientCredentials && meta[MetadataClientID] == "" {
+			return nil, fmt.Errorf("instance %q: %s needs %s: %w", instance.ID, auth, MetadataClientID, ErrInvalidOption)
+		}
+		client, err := HTTPClient(instance)
+		if err != nil {
+			return nil, err
+		}
+		return &oauthSource{
+			grant:    auth,
+			tokenURL: meta[MetadataTokenURL],
+			clientID: meta[MetadataClientID],
+			scopes:   meta[MetadataScopes],
+			secret:   instance.APIKey,
+			client:   client,
+			now:      time.Now,
+		}, nil
+	case AuthAWSSigV4:
+		return newSigV4Source(instance)
+	case AuthGitHubCopilot:
+		return newCopilotSource(instance)
+	default:
+		return nil, fmt.Errorf("instance %q: unknown auth scheme %q: %w", instance.ID, auth, ErrInvalidOption)
+	}
+}
+
+// staticSource serves a static API key
+type staticSource struct {
+	token        Token
+	providerType string
+}
+
+func (s staticSource) Token(context.Context) (Token, error) {
+	return s.token, nil
+}
+
+// Authorize sets the key in the header the provider expects:
+// Anthropic's x-api-key, Azure OpenAI's api-key, or a Bearer Authorization
+// header
+func (s staticSource) Authorize(_ context.Context, req *http.Request) error {
+	switch s.providerType {
+	case "anthropic":
+		req.Header.Set("x-api-key", s.token.Value.Reveal())
+		return nil
+	case ProviderAzureOpenAI:
+		req.Header.Set("api-key", s.token.Value.Reveal())
+		return nil
+	}
+	req.Header.Set("Authorization", s.token.Type+" "+s.token.Value.Reveal())
+	return nil
+}
+
+// oauthSource fetches access tokens from an OAuth 2.0 token endpoint and
+// caches them until shortly before they expire
+type oauthSource struct {
+	grant    string
+	tokenURL string
+	clientID string
+	scopes   string
+	client   *http.Client
+	now      func() time.Time
+
+	mu sync.Mutex
+	// secret is the client secret, or the refresh token, which a server
+	// that rotates refresh tokens replaces on every refresh
+	secret Secret
+	token  Token
+}
+
+func (s *oauthSource) Token(ctx context.Context) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.Valid(s.now()) {
+		return s.token, nil
+	}
+	form := url.Values{}
+	if s.clientID != "" {
+		form.Set("client_id", s.clientID)
+	}
+	if s.scopes != "" {
+		form.Set("scope", s.scopes)
+	}
+	if s.grant == AuthOAuthClientCredentials {
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_secret", s.secret.Reveal())
+	} else {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", s.secret.Reveal())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, fmt.Errorf("token request: %w: %v", ErrInvalidOption, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	start := s.now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("token request: %w: %v", ErrIO, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("token request: %w: %v", ErrIO, err)
+	}
+	var reply struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		RefreshToken     string `json:"refresh_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil && resp.StatusCode == http.StatusOK {
+		return Token{}, fmt.Errorf("token response: %w: %v", ErrParse, err)
+	}
+	if resp.StatusCode != http.StatusOK || reply.AccessToken == "" {
+		cause := ErrIO
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+			cause = ErrPermissionDenied
+		}
+		return Token{}, fmt.Errorf("token request: %w: %s %s %s", cause, resp.Status, reply.Error, reply.ErrorDescription)
+	}
+
+	s.token = Token{Value: NewSecretString(reply.AccessToken), Type: "Bearer"}
+	if strings.EqualFold(reply.TokenType, "bearer") || reply.TokenType == "" {
+		s.token.Type = "Bearer"
+	} else {
+		s.token.Type = reply.TokenType
+	}
+	if reply.ExpiresIn > 0 {
+		s.token.Expiry = start.Add(time.Duration(reply.ExpiresIn) * time.Second)
+	}
+	if s.grant == AuthOAuthRefreshToken && reply.RefreshToken != "" {
+		s.secret = NewSecretString(reply.RefreshToken)
+	}
+	return s.token, nil
+}
+
+func (s *oauthSource) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := s.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", token.Type+" "+token.Value.Reveal())
+	return nil
+}
@@ -0,0 +1,156 @@
+package aicred
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenSourceStatic(t *testing.T) {
+	for providerType, header := range map[string]string{"openai": "Authorization", "anthropic": "X-Api-Key"} {
+		ts, err := TokenSource(ProviderInstance{ID: providerType, ProviderType: providerType, APIKey: NewSecretString("sk-static")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/chat", nil)
+		if err := ts.Authorize(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get(header); !strings.HasSuffix(got, "sk-static") {
+			t.Errorf("%s: %s = %q", providerType, header, got)
+		}
+	}
+	if _, err := TokenSource(ProviderInstance{ID: "keyless"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("without a key: err = %v", err)
+	}
+	if _, err := TokenSource(ProviderInstance{ID: "x", Metadata: map[string]string{MetadataAuth: "kerberos"}}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("unknown scheme: err = %v", err)
+	}
+}
+
+func TestTokenSourceOAuth(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		switch r.Form.Get("grant_type") {
+		case "client_credentials":
+			if r.Form.Get("client_id") != "gateway" || r.Form.Get("client_secret") != "s3cret" || r.Form.Get("scope") != "inference" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client"}`))
+				return
+			}
+		case "refresh_token":
+			// The server rotates refresh tokens
+			if want := map[int32]string{1: "rt-1", 2: "rt-2"}[n]; r.Form.Get("refresh_token") != want {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"at-` + string('0'+n) + `","token_type":"bearer","expires_in":3600,"refresh_token":"rt-` + string('1'+n) + `"}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	meta := map[string]string{MetadataAuth: AuthOAuthClientCredentials, MetadataTokenURL: srv.URL, MetadataClientID: "gateway", MetadataScopes: "inference"}
+	ts, err := TokenSource(ProviderInstance{ID: "sso", APIKey: NewSecretString("s3cret"), Metadata: meta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	ts.(*oauthSource).now = func() time.Time { return now }
+	for range 3 {
+		token, err := ts.Token(ctx)
+		if err != nil || token.Value.Reveal() != "at-1" || token.Type != "Bearer" {
+			t.Fatalf("Token = %+v, %v", token, err)
+		}
+	}
+	now = now.Add(time.Hour)
+	req, _ := http.NewRequest(http.MethodGet, "https://gateway.example.com/v1/models", nil)
+	if err := ts.Authorize(ctx, req); err != nil || req.Header.Get("Authorization") != "Bearer at-2" {
+		t.Errorf("after expiry: Authorization = %q, %v", req.Header.Get("Authorization"), err)
+	}
+
+	requests.Store(0)
+	meta = map[string]string{MetadataAuth: AuthOAuthRefreshToken, MetadataTokenURL: srv.URL}
+	ts, err = TokenSource(ProviderInstance{ID: "sso", APIKey: NewSecretString("rt-1"), Metadata: meta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.(*oauthSource).now = func() time.Time { return now }
+	if _, err := ts.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	if token, err := ts.Token(ctx); err != nil || token.Value.Reveal() != "at-2" {
+		t.Errorf("refresh with the rotated token: %+v, %v", token, err)
+	}
+
+	meta = map[string]string{MetadataAuth: AuthOAuthClientCredentials, MetadataTokenURL: srv.URL, MetadataClientID: "gateway"}
+	ts, _ = TokenSource(ProviderInstance{ID: "sso", APIKey: NewSecretString("wrong"), Metadata: meta})
+	if _, err := ts.Token(ctx); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("bad client secret: err = %v", err)
+	}
+}
+
+func TestSigV4(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	ts, err := TokenSource(ProviderInstance{
+		ID:     "bedrock",
+		APIKey: NewSecretString("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"),
+		Metadata: map[string]string{
+			MetadataAuth:           AuthAWSSigV4,
+			MetadataAWSRegion:      "us-east-1",
+			MetadataAWSService:     "service",
+			MetadataAWSAccessKeyID: "AKIDEXAMPLE",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.(*sigV4Source).now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err := ts.Authorize(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := ts.Token(context.Background()); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Token: err = %v", err)
+	}
+
+	body := `{"prompt":"hi"}`
+	req, _ = http.NewRequest(http.MethodPost, "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-v2:1/invoke", strings.NewReader(body))
+	if err := ts.Authorize(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(req.Body); err != nil || string(got) != body {
+		t.Errorf("body after signing = %q, %v", got, err)
+	}
+
+	fakeEnv(t, false, map[string]string{"AWS_ACCESS_KEY_ID": "ASIAEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_SESSION_TOKEN": "session", "AWS_REGION": "eu-west-1"})
+	ts, err = TokenSource(ProviderInstance{ID: "bedrock", Metadata: map[string]string{MetadataAuth: AuthAWSSigV4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "https://bedrock.eu-west-1.amazonaws.com/foundation-models", nil)
+	if err := ts.Authorize(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "ASIAEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/bedrock/") ||
+		!strings.Contains(auth, "x-amz-security-token") || req.Header.Get("X-Amz-Security-Token") != "session" {
+		t.Errorf("from the environment: %q", auth)
+	}
+}
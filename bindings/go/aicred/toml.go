@@ -0,0 +1,763 @@
+package aicred
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeTOML decodes a TOML document into v through its JSON form, as
+// decodeYAML does. Dates and times stay strings, which time.Time reads when
+// they carry an offset.
+func decodeTOML(data []byte, v any) error {
+	p := &tomlParser{src: string(data), line: 1, root: map[string]any{}, defined: map[string]bool{}}
+	if err := p.parse(); err != nil {
+		return err
+	}
+	js, err := json.Marshal(p.root)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, v)
+}
+
+// tomlParser reads the TOML that configuration files use: tables, arrays
+// of tables, dotted keys, inline tables and arrays, the four string forms,
+// integers, floats, booleans and dates
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+	root map[string]any
+	// table is the table key/value pairs go into
+	table map[string]any
+	// defined holds the [table] headers seen, which may not repeat
+	defined map[string]bool
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) parse() error {
+	p.table = p.root
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.src) {
+			return nil
+		}
+		var err error
+		if p.src[p.pos] == '[' {
+			err = p.header()
+		} else {
+			err = p.keyValue(p.table)
+		}
+		if err != nil {
+			return err
+		}
+		p.skipBlank(false)
+		if p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+			return p.errorf("unexpected %q after value", p.src[p.pos])
+		}
+	}
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines too if
+// newlines is set
+func (p *tomlParser) skipBlank(newlines bool) {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// header reads a [table] or [[array of tables]] header and makes its table
+// the current one
+func (p *tomlParser) header() error {
+	array := strings.HasPrefix(p.src[p.pos:], "[[")
+	p.pos++
+	if array {
+		p.pos++
+	}
+	p.skipBlank(false)
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return p.errorf("header is not closed with %s", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(p.root, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if array {
+		tables, ok := parent[last].([]any)
+		if _, exists := parent[last]; exists && !ok {
+			return p.errorf("%s is not an array of tables", strings.Join(keys, "."))
+		}
+		table := map[string]any{}
+		parent[last] = append(tables, table)
+		p.table = table
+		// Each table of the array may define its own sub-tables again
+		prefix := strings.Join(keys, "\x00") + "\x00"
+		for path := range p.defined {
+			if strings.HasPrefix(path, prefix) {
+				delete(p.defined, path)
+			}
+		}
+		return nil
+	}
+	path := strings.Join(keys, "\x00")
+	if p.defined[path] {
+		return p.errorf("table %s is defined twice", strings.Join(keys, "."))
+	}
+	p.defined[path] = true
+	table, err := p.descend(parent, []string{last})
+	if err != nil {
+		return err
+	}
+	p.table = table
+	return nil
+}
+
+// descend returns the table at keys under t, creating tables as needed.
+// An array of tables stands for its last table.
+func (p *tomlParser) descend(t map[string]any, keys []string) (map[string]any, error) {
+	for _, k := range keys {
+		switch next := t[k].(type) {
+		case nil:
+			table := map[string]any{}
+			t[k] = table
+			t = table
+		case map[string]any:
+			t = next
+		case []any:
+			var table map[string]any
+			if len(next) > 0 {
+				table, _ = next[len(next)-1].(map[string]any)
+			}
+			if table == nil {
+				return nil, p.errorf("%s is not a table", k)
+			}
+			t = table
+		default:
+			return nil, p.errorf("%s is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+// keyValue reads key = value into t
+func (p *tomlParser) keyValue(t map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return p.errorf("expected = after %s", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipBlank(false)
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	t, err = p.descend(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := t[last]; exists {
+		return p.errorf("%s is given twice", strings.Join(keys, "."))
+	}
+	t[last] = value
+	return nil
+}
+
+// key reads a dotted key
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		var k string
+		var err error
+		switch {
+		case p.pos >= len(p.src):
+			return nil, p.errorf("expected a key")
+		case p.src[p.pos] == '"':
+			k, err = p.basicString()
+		case p.src[p.pos] == '\'':
+			k, err = p.literalString()
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && isBareKeyByte(p.src[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a key, found %q", p.src[p.pos])
+			}
+			k = p.src[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+		p.skipBlank(false)
+		if p.pos >= len(p.src) || p.src[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+		p.skipBlank(false)
+	}
+}
+
+func isBareKeyByte(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// tomlDate matches the dates, times and date-times TOML writes bare
+var tomlDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?)?$|^\d{2}:\d{2}:\d{2}(\.\d+)?$`)
+
+// tomlFloat matches a float once underscores are removed
+var tomlFloat = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// value reads any value
+func (p *tomlParser) value() (any, error) {
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected a value")
+	}
+	switch rest := p.src[p.pos:]; {
+	case strings.HasPrefix(rest, `"""`):
+		return p.multilineString(`"""`)
+	case strings.HasPrefix(rest, "'''"):
+		return p.multilineString("'''")
+	case rest[0] == '"':
+		return p.basicString()
+	case rest[0] == '\'':
+		return p.literalString()
+	case rest[0] == '[':
+		return p.array()
+	case rest[0] == '{':
+		return p.inlineTable()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	// A date and time may be separated by a space
+	if p.pos-start == 10 && p.pos+1 < len(p.src) && p.src[p.pos] == ' ' && p.src[p.pos+1] >= '0' && p.src[p.pos+1] <= '9' {
+		p.pos++
+		for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.src[p.pos])) {
+			p.pos++
+		}
+	}
+	token := p.src[start:p.pos]
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, p.errorf("expected a value")
+	}
+	if tomlDate.MatchString(token) {
+		if len(token) > 10 && token[10] == ' ' {
+			token = token[:10] + "T" + token[11:]
+		}
+		return token, nil
+	}
+	digits := strings.ReplaceAll(token, "_", "")
+	if n, ok := tomlInt(digits); ok {
+		return n, nil
+	}
+	// JSON has no infinities or NaN, so inf and nan are refused too
+	if tomlFloat.MatchString(digits) {
+		if f, err := strconv.ParseFloat(digits, 64); err == nil && !math.IsInf(f, 0) {
+			return f, nil
+		}
+	}
+	return nil, p.errorf("%q is not a value", token)
+}
+
+// tomlInt parses a decimal integer without leading zeros, or a 0x, 0o or
+// 0b one
+func tomlInt(s string) (int64, bool) {
+	if len(s) > 2 && s[0] == '0' && strings.ContainsRune("xob", rune(s[1])) {
+		n, err := strconv.ParseInt(s, 0, 64)
+		return n, err == nil
+	}
+	unsigned := strings.TrimLeft(s, "+-")
+	if unsigned == "" || len(s)-len(unsigned) > 1 || len(unsigned) > 1 && unsigned[0] == '0' || strings.Trim(unsigned, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// basicString reads a "..." string
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			if isTOMLControl(c) {
+				return "", p.errorf("control character %#02x in string", c)
+			}
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// isTOMLControl reports whether c is a control character, other than
+// tab, which TOML strings may not hold unescaped
+func isTOMLControl(c byte) bool {
+	return c < 0x20 && c != '\t' || c == 0x7f
+}
+
+// literalString reads a '...' string, which has no escapes
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	if i := strings.IndexFunc(s, func(r rune) bool { return r < 0x80 && isTOMLControl(byte(r)) }); i >= 0 {
+		p.pos += i
+		return "", p.errorf("control character %#02x in string", s[i])
+	}
+	p.pos += end + 1
+	return s, nil
+}
+
+// multilineString reads a triple-quoted basic or literal string. A
+// newline right after the opening delimiter is dropped, and in basic
+// strings a backslash at the end of a line trims the line break and the
+// whitespace after it.
+func (p *tomlParser) multilineString(delim string) (string, error) {
+	p.pos += len(delim)
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if strings.HasPrefix(p.src[p.pos:], "\n") {
+		p.pos++
+		p.line++
+	}
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], delim) {
+			// Up to two quotes may end the content before the delimiter
+			end := p.pos + len(delim)
+			for end < len(p.src) && p.src[end] == delim[0] && end-p.pos < len(delim)+2 {
+				end++
+			}
+			b.WriteString(p.src[p.pos : end-len(delim)])
+			p.pos = end
+			return b.String(), nil
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && delim == `"""`:
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t\r")
+			if strings.HasPrefix(rest, "\n") {
+				p.pos = len(p.src) - len(rest)
+				for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+					if p.src[p.pos] == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			} else if c != '\r' && isTOMLControl(c) {
+				return "", p.errorf("control character %#02x in string", c)
+			}
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// escape reads the escape sequence at p.pos into b
+func (p *tomlParser) escape(b *strings.Builder) error {
+	if p.pos+1 >= len(p.src) {
+		return p.errorf("unterminated escape")
+	}
+	c := p.src[p.pos+1]
+	p.pos += 2
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return p.errorf("short \\%c escape", c)
+		}
+		r, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return p.errorf("bad \\%c escape", c)
+		}
+		b.WriteRune(rune(r))
+		p.pos += n
+	default:
+		return p.errorf("unknown escape \\%c", c)
+	}
+	return nil
+}
+
+// array reads [a, b, ...], which may span lines
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++
+	items := []any{}
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return items, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skipBlank(true)
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable reads {k = v, ...} on one line
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.pos++
+	t := map[string]any{}
+	p.skipBlank(false)
+	if p.pos < len(p.src) && p.src[p.pos] == '}' {
+		p.pos++
+		return t, nil
+	}
+	for {
+		p.skipBlank(false)
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.src[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return t, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// encodeTOML encodes v as TOML through its JSON form, keeping the order of
+// struct fields. TOML has no null, so null values, such as Secrets, are
+// left out.
+func encodeTOML(v any) ([]byte, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	root, err := jsonNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("only a table encodes as a TOML document")
+	}
+	var b strings.Builder
+	if err := writeTOMLTable(&b, nil, root, false); err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimPrefix(b.String(), "\n")), nil
+}
+
+// jsonNode reads the next JSON value from dec as a YAML node, which keeps
+// the order of object keys. Numbers that do not fit an int64 become
+// floats, as TOML integers must fit one.
+func jsonNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := &yaml.Node{Kind: yaml.SequenceNode}
+		if tok == '{' {
+			n.Kind = yaml.MappingNode
+		}
+		for dec.More() {
+			if n.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			item, err := jsonNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, item)
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tok}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(tok)}, nil
+	case json.Number:
+		if _, err := strconv.ParseInt(tok.String(), 10, 64); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: tok.String()}, nil
+		}
+		value := tok.String()
+		if !strings.ContainsAny(value, ".eE") {
+			value += ".0"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}, nil
+}
+
+// writeTOMLTable writes the mapping n as the table at path: its plain
+// values, then its subtables and arrays of tables. array says whether the
+// header is an [[array of tables]] header.
+func writeTOMLTable(b *strings.Builder, path []string, n *yaml.Node, array bool) error {
+	if len(path) > 0 {
+		header := tomlPath(path)
+		if array {
+			fmt.Fprintf(b, "\n[[%s]]\n", header)
+		} else {
+			fmt.Fprintf(b, "\n[%s]\n", header)
+		}
+	}
+	var nested []int
+	for i := 0; i < len(n.Content); i += 2 {
+		value := n.Content[i+1]
+		if isTOMLTable(value) || isTOMLTableArray(value) {
+			nested = append(nested, i)
+			continue
+		}
+		if isNull(value) {
+			continue
+		}
+		inline, err := tomlInline(value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s = %s\n", tomlKey(n.Content[i].Value), inline)
+	}
+	for _, i := range nested {
+		sub := append(slices.Clip(path), n.Content[i].Value)
+		value := n.Content[i+1]
+		if isTOMLTable(value) {
+			if err := writeTOMLTable(b, sub, value, false); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, item := range value.Content {
+			if err := writeTOMLTable(b, sub, item, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTOMLTable reports whether n is written as a [table]: a non-empty
+// mapping. Empty ones are written inline as {}.
+func isTOMLTable(n *yaml.Node) bool {
+	return n.Kind == yaml.MappingNode && len(n.Content) > 0
+}
+
+// isTOMLTableArray reports whether n is written as [[tables]]: a non-empty
+// sequence of mappings
+func isTOMLTableArray(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		return false
+	}
+	for _, item := range n.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+// tomlInline writes n as an inline value
+func tomlInline(n *yaml.Node) (string, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!str":
+			return tomlString(n.Value), nil
+		case "!!int", "!!float", "!!bool":
+			return n.Value, nil
+		}
+		return "", fmt.Errorf("cannot encode %s %q as TOML", n.Tag, n.Value)
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			if isNull(item) {
+				return "", errors.New("TOML arrays cannot hold null")
+			}
+			s, err := tomlInline(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case yaml.MappingNode:
+		var parts []string
+		for i := 0; i < len(n.Content); i += 2 {
+			if isNull(n.Content[i+1]) {
+				continue
+			}
+			s, err := tomlInline(n.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKey(n.Content[i].Value)+" = "+s)
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("cannot encode YAML node kind %d as TOML", n.Kind)
+}
+
+// tomlPath writes a dotted key
+func tomlPath(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = tomlKey(k)
+	}
+	return strings.Join(quoted, ".")
+}
+
+// tomlKey writes a key bare when TOML allows it and quoted otherwise
+func tomlKey(k string) string {
+	if k != "" && strings.IndexFunc(k, func(r rune) bool { return r > 0x7f || !isBareKeyByte(byte(r)) }) < 0 {
+		return k
+	}
+	return tomlString(k)
+}
+
+// tomlString writes s as a basic string
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,215 @@
+package aicred
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tomlSample uses every form decodeTOML reads
+const tomlSample = `# aicred configuration
+title = "basic \"quoted\" \u00e9"
+literal = 'C:\path'
+multi = """
+first\
+   second"""
+raw = '''
+line one
+line two'''
+int = 1_000
+hex = 0xff
+neg = -7
+float = 3.5e2
+bool = true
+date = 2024-01-01T00:00:00Z
+spaced = 2024-01-01 12:30:00+02:00
+list = [
+  "a", # comment
+  "b",
+]
+inline = { color = "#ff0000", "header.X-Team" = "research", nested.deep = 1 }
+dotted.key = "value"
+
+[table]
+key = "in table"
+
+[table.sub]
+key = 2
+
+[[items]]
+name = "first"
+[items.meta]
+k = "v"
+
+[[items]]
+name = "second"
+[items.meta]
+k = "w"
+`
+
+func TestDecodeTOML(t *testing.T) {
+	var got map[string]any
+	if err := decodeTOML([]byte(tomlSample), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"title":   `basic "quoted" é`,
+		"literal": `C:\path`,
+		"multi":   "firstsecond",
+		"raw":     "line one\nline two",
+		"int":     1000.0,
+		"hex":     255.0,
+		"neg":     -7.0,
+		"float":   350.0,
+		"bool":    true,
+		"date":    "2024-01-01T00:00:00Z",
+		"spaced":  "2024-01-01T12:30:00+02:00",
+		"list":    []any{"a", "b"},
+		"inline":  map[string]any{"color": "#ff0000", "header.X-Team": "research", "nested": map[string]any{"deep": 1.0}},
+		"dotted":  map[string]any{"key": "value"},
+		"table":   map[string]any{"key": "in table", "sub": map[string]any{"key": 2.0}},
+		"items": []any{
+			map[string]any{"name": "first", "meta": map[string]any{"k": "v"}},
+			map[string]any{"name": "second", "meta": map[string]any{"k": "w"}},
+		},
+	}
+	for k := range want {
+		if !reflect.DeepEqual(got[k], want[k]) {
+			t.Errorf("%s = %#v, want %#v", k, got[k], want[k])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("decoded %d keys, want %d", len(got), len(want))
+	}
+}
+
+// tomlInvalid are documents decodeTOML rejects
+var tomlInvalid = []string{
+	"a = ",
+	"a = 1\na = 2",
+	"[t]\n[t]",
+	"[[t]]\n[t.s]\n[t.s]",
+	`a = "unterminated`,
+	"a = [1, 2",
+	"a = 1 b = 2",
+	"a = 012",
+	"a = inf",
+	`a = "\q"`,
+	"a = 1\n[a]",
+	"= 1",
+	"a = \"\x01\"",
+	"a = '\x7f'",
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	for _, doc := range tomlInvalid {
+		var v map[string]any
+		if err := decodeTOML([]byte(doc), &v); err == nil {
+			t.Errorf("decodeTOML(%q) = %v, want an error", doc, v)
+		}
+	}
+	var v map[string]any
+	err := decodeTOML([]byte("a = 1\n\nb = ?"), &v)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error = %v, want it on line 3", err)
+	}
+}
+
+func TestEncodeTOML(t *testing.T) {
+	type item struct {
+		Name     string            `json:"name"`
+		Key      Secret            `json:"key"`
+		Models   []string          `json:"models"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	type doc struct {
+		Title string `json:"title"`
+		Empty []item `json:"empty"`
+		Items []item `json:"items"`
+	}
+	in := doc{
+		Title: "a \"b\"\n",
+		Empty: []item{},
+		Items: []item{
+			{Name: "first", Key: NewSecretString("sk-secret"), Models: []string{"m1", "m2"}, Metadata: map[string]string{"header.X-Team": "research"}},
+			{Name: "second", Metadata: map[string]string{}},
+		},
+	}
+	data, err := encodeTOML(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `title = "a \"b\"\n"
+empty = []
+
+[[items]]
+name = "first"
+models = ["m1", "m2"]
+
+[items.metadata]
+"header.X-Team" = "research"
+
+[[items]]
+name = "second"
+metadata = {}
+`
+	if string(data) != want {
+		t.Errorf("encoded\n%s\nwant\n%s", data, want)
+	}
+	var back doc
+	if err := decodeTOML(data, &back); err != nil {
+		t.Fatalf("%v:\n%s", err, data)
+	}
+	if back.Title != in.Title || back.Items[0].Metadata["header.X-Team"] != "research" || len(back.Items[0].Models) != 2 || !back.Items[0].Key.IsZero() {
+		t.Errorf("round trip = %+v", back)
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	for _, doc := range []string{
+		tomlSample,
+		"big = 1e20\nsmall = -1.5e-300\n",
+		"[" + strings.Repeat("k", 2000) + "]\na = 1\n",
+	} {
+		var decoded map[string]any
+		if err := decodeTOML([]byte(doc), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		data, err := encodeTOML(decoded)
+		if err != nil {
+			t.Fatalf("%v:\n%s", err, doc)
+		}
+		var back map[string]any
+		if err := decodeTOML(data, &back); err != nil {
+			t.Fatalf("%v:\n%s", err, data)
+		}
+		if !reflect.DeepEqual(back, decoded) {
+			t.Errorf("round trip = %#v\nwant %#v\n%s", back, decoded, data)
+		}
+	}
+}
+
+func FuzzTOML(f *testing.F) {
+	f.Add([]byte(tomlSample))
+	for _, doc := range tomlInvalid {
+		f.Add([]byte(doc))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var decoded map[string]any
+		if decodeTOML(data, &decoded) != nil {
+			return
+		}
+		// What decodes encodes, and decodes again to the same values
+		out, err := encodeTOML(decoded)
+		if err != nil {
+			t.Fatalf("%v\n%q", err, data)
+		}
+		var back map[string]any
+		if err := decodeTOML(out, &back); err != nil {
+			t.Fatalf("%v\n%s", err, out)
+		}
+		if !reflect.DeepEqual(back, decoded) {
+			t.Fatalf("round trip = %#v\nwant %#v\n%s", back, decoded, out)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// maxContextFileSize bounds how much of a source file is read for context
+const maxContextFileSize = 1 << 20
+
+// secretLike matches long token-shaped runs that may be credentials
+var secretLike = regexp.MustCompile(`[A-Za-z0-9_\-]{20,}`)
+
+// maskSecrets replaces token-shaped runs with their first four characters
+// followed by asterisks so file context never shows a full key
+func maskSecrets(line string) string {
+	return secretLike.ReplaceAllStringFunc(line, maskToken)
+}
+
+// maskToken keeps the first four characters of a token long enough that
+// they give little away
+func maskToken(s string) string {
+	if len(s) < 12 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
+
+// keyByte reports whether c can be part of a key
+func keyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~+/=", c) >= 0
+}
+
+// keySpans returns the byte ranges of line that hold the finding's key:
+// occurrences of its value, tokens that start with its redacted prefix or
+// end with its redacted suffix, and on the line of its Location the token
+// at its column. The first two catch keys too short for secretLike.
+func keySpans(line string, key aicred.DiscoveredKey, column int) [][2]int {
+	var spans [][2]int
+	key.Value.Use(func(v []byte) {
+		if len(v) == 0 {
+			return
+		}
+		b := []byte(line)
+		for off := 0; ; {
+			i := bytes.Index(b[off:], v)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, [2]int{off + i, off + i + len(v)})
+			off += i + len(v)
+		}
+	})
+	extendRight := func(start int) [2]int {
+		end := start
+		for end < len(line) && keyByte(line[end]) {
+			end++
+		}
+		return [2]int{start, end}
+	}
+	prefix, _, _ := strings.Cut(key.Redacted, "*")
+	if len(prefix) >= 3 {
+		for off := 0; ; {
+			i := strings.Index(line[off:], prefix)
+			if i < 0 {
+				break
+			}
+			span := extendRight(off + i)
+			spans = append(spans, span)
+			off = span[1]
+		}
+	}
+	suffix := key.Redacted[strings.LastIndexByte(key.Redacted, '*')+1:]
+	if len(suffix) >= 3 && suffix != key.Redacted {
+		for off := 0; ; {
+			i := strings.Index(line[off:], suffix)
+			if i < 0 {
+				break
+			}
+			start, end := off+i, off+i+len(suffix)
+			for start > 0 && keyByte(line[start-1]) && line[start-1] != '=' {
+				start--
+			}
+			spans = append(spans, [2]int{start, end})
+			off = end
+		}
+	}
+	if column > 0 && column <= len(line) {
+		spans = append(spans, extendRight(column-1))
+	}
+	return spans
+}
+
+// maskLine masks the spans of line, then any other token-shaped run
+func maskLine(line string, spans [][2]int) string {
+	covered := make([]bool, len(line))
+	for _, s := range spans {
+		for i := s[0]; i < s[1]; i++ {
+			covered[i] = true
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		if !covered[i] {
+			j := i
+			for j < len(line) && !covered[j] {
+				j++
+			}
+			b.WriteString(maskSecrets(line[i:j]))
+			i = j
+			continue
+		}
+		j := i
+		for j < len(line) && covered[j] {
+			j++
+		}
+		b.WriteString(maskToken(line[i:j]))
+		i = j
+	}
+	return b.String()
+}
+
+// fileContext returns numbered, masked lines around the key's line: the
+// line of its Location when the scanner recorded one, else the first line
+// that holds its value or redacted form, else the start of the file
+func fileContext(key aicred.DiscoveredKey, radius int) ([]string, error) {
+	path := key.Source
+	if key.Location != nil && key.Location.Path != "" {
+		path = key.Location.Path
+	}
+	if path == "" {
+		return nil, errors.New("finding has no source file")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > maxContextFileSize {
+		return nil, fmt.Errorf("%s is too large to display", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var all []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		all = append(all, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	hit, column := 0, 0
+	if key.Location != nil && key.Location.Line > 0 && key.Location.Line <= len(all) {
+		hit, column = key.Location.Line-1, key.Location.Column
+	} else {
+		for i, line := range all {
+			if len(keySpans(line, key, 0)) > 0 {
+				hit = i
+				break
+			}
+		}
+	}
+
+	start := max(hit-radius, 0)
+	end := min(hit+radius+1, len(all))
+	out := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		marker, col := " ", 0
+		if i == hit {
+			marker, col = ">", column
+		}
+		out = append(out, fmt.Sprintf("%s %4d | %s", marker, i+1, maskLine(all[i], keySpans(all[i], key, col))))
+	}
+	return out, nil
+}
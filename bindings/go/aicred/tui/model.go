@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// confidenceLevels orders the confidence names emitted by the core library
+var confidenceLevels = []string{"Low", "Medium", "High", "VeryHigh"}
+
+type model struct {
+	opts      Options
+	keys      []aicred.DiscoveredKey
+	handled   map[int]Action
+	providers []string
+
+	// providerIdx selects providers[providerIdx-1]; 0 means all providers
+	providerIdx int
+	// minConfidence indexes confidenceLevels
+	minConfidence int
+
+	cursor    int
+	detail    bool
+	height    int
+	decisions []Decision
+	status    string
+}
+
+func newModel(result *aicred.ScanResult, opts Options) *model {
+	if opts.ContextLines <= 0 {
+		opts.ContextLines = 3
+	}
+	m := &model{opts: opts, handled: make(map[int]Action), height: 24}
+	if result == nil {
+		return m
+	}
+	m.keys = result.Keys
+	seen := make(map[string]bool)
+	for _, key := range m.keys {
+		if !seen[key.Provider] {
+			seen[key.Provider] = true
+			m.providers = append(m.providers, key.Provider)
+		}
+	}
+	sort.Strings(m.providers)
+	return m
+}
+
+// visible returns indexes into m.keys that pass the filters and are not handled
+func (m *model) visible() []int {
+	var idx []int
+	for i, key := range m.keys {
+		if _, done := m.handled[i]; done {
+			continue
+		}
+		if m.providerIdx > 0 && key.Provider != m.providers[m.providerIdx-1] {
+			continue
+		}
+		if confidenceIndex(key.Confidence) < m.minConfidence {
+			continue
+		}
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+func confidenceIndex(confidence string) int {
+	normalized := strings.ReplaceAll(confidence, " ", "")
+	for i, level := range confidenceLevels {
+		if strings.EqualFold(level, normalized) {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		return m.handleKey(msg.String())
+	}
+	return m, nil
+}
+
+func (m *model) handleKey(key string) (tea.Model, tea.Cmd) {
+	visible := m.visible()
+	m.status = ""
+
+	switch key {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.detail = false
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(visible)-1 {
+			m.cursor++
+		}
+	case "enter":
+		m.detail = !m.detail && len(visible) > 0
+	case "p":
+		m.providerIdx = (m.providerIdx + 1) % (len(m.providers) + 1)
+		m.cursor = 0
+	case "c":
+		m.minConfidence = (m.minConfidence + 1) % len(confidenceLevels)
+		m.cursor = 0
+	case "i":
+		m.decide(visible, ActionIgnore)
+	case "b":
+		m.decide(visible, ActionBaseline)
+	case "r":
+		m.decide(visible, ActionRemediate)
+	case "m":
+		m.decide(visible, ActionImport)
+	}
+
+	if n := len(m.visible()); m.cursor >= n {
+		m.cursor = max(n-1, 0)
+	}
+	return m, nil
+}
+
+// decide records an action for the finding under the cursor and hides it
+func (m *model) decide(visible []int, action Action) {
+	if len(visible) == 0 {
+		return
+	}
+	i := visible[m.cursor]
+	m.handled[i] = action
+	m.decisions = append(m.decisions, Decision{Action: action, Key: m.keys[i]})
+	m.status = fmt.Sprintf("%s: %s %s", action, m.keys[i].Provider, m.keys[i].Redacted)
+	m.detail = false
+}
+
+func (m *model) View() string {
+	var b strings.Builder
+	visible := m.visible()
+
+	provider := "all"
+	if m.providerIdx > 0 {
+		provider = m.providers[m.providerIdx-1]
+	}
+	fmt.Fprintf(&b, "aicred findings  %d shown / %d total  provider: %s  confidence >= %s\n\n",
+		len(visible), len(m.keys), provider, confidenceLevels[m.minConfidence])
+
+	if m.detail && len(visible) > 0 {
+		b.WriteString(m.detailView(m.keys[visible[m.cursor]]))
+	} else {
+		b.WriteString(m.listView(visible))
+	}
+
+	if m.status != "" {
+		fmt.Fprintf(&b, "\n%s\n", m.status)
+	}
+	b.WriteString("\n↑/↓ move  enter details  p provider  c confidence  i ignore  b baseline  r remediate  m import  q quit\n")
+	return b.String()
+}
+
+func (m *model) listView(visible []int) string {
+	if len(visible) == 0 {
+		return "No findings match the current filters.\n"
+	}
+
+	// Keep the cursor on screen, leaving room for the header and help lines
+	rows := max(m.height-6, 1)
+	start := 0
+	if m.cursor >= rows {
+		start = m.cursor - rows + 1
+	}
+	end := min(start+rows, len(visible))
+
+	var b strings.Builder
+	for pos := start; pos < end; pos++ {
+		key := m.keys[visible[pos]]
+		marker := "  "
+		if pos == m.cursor {
+			marker = "> "
+		}
+		fmt.Fprintf(&b, "%s%-12s %-20s %-9s %s\n", marker, key.Provider, key.Redacted, key.Confidence, key.Source)
+	}
+	return b.String()
+}
+
+func (m *model) detailView(key aicred.DiscoveredKey) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Provider:   %s\n", key.Provider)
+	fmt.Fprintf(&b, "Key:        %s\n", key.Redacted)
+	fmt.Fprintf(&b, "Type:       %s\n", key.ValueType)
+	fmt.Fprintf(&b, "Confidence: %s\n", key.Confidence)
+	fmt.Fprintf(&b, "Source:     %s\n\n", key.Source)
+
+	lines, err := fileContext(key, m.opts.ContextLines)
+	if err != nil {
+		fmt.Fprintf(&b, "(no file context: %v)\n", err)
+		return b.String()
+	}
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteString("\nesc back\n")
+	return b.String()
+}
@@ -0,0 +1,58 @@
+// Package tui provides an interactive terminal UI for reviewing scan findings.
+//
+// Findings can be filtered by provider and confidence, inspected with the
+// surrounding file context (secrets masked), and marked with an action. The
+// UI does not change anything on disk itself: Run returns the decisions so
+// the caller can apply them.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// Action is what the reviewer decided to do with a finding
+type Action string
+
+const (
+	ActionIgnore    Action = "ignore"
+	ActionBaseline  Action = "baseline"
+	ActionRemediate Action = "remediate"
+	ActionImport    Action = "import"
+)
+
+// Decision records an action taken on a finding
+type Decision struct {
+	Action Action
+	Key    aicred.DiscoveredKey
+}
+
+// Options configures the UI
+type Options struct {
+	// ContextLines is how many lines around a finding to show; defaults to 3
+	ContextLines int
+	Input        io.Reader
+	Output       io.Writer
+}
+
+// Run shows the findings in result and blocks until the user quits,
+// returning the decisions made in the order they were taken
+func Run(result *aicred.ScanResult, opts Options) ([]Decision, error) {
+	if opts.Input == nil {
+		opts.Input = os.Stdin
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	m := newModel(result, opts)
+	final, err := tea.NewProgram(m, tea.WithInput(opts.Input), tea.WithOutput(opts.Output), tea.WithAltScreen()).Run()
+	if err != nil {
+		return nil, fmt.Errorf("tui failed: %v", err)
+	}
+	return final.(*model).decisions, nil
+}
@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func testResult() *aicred.ScanResult {
+	return &aicred.ScanResult{Keys: []aicred.DiscoveredKey{
+		{Provider: "openai", Redacted: "sk-proj-****", Confidence: "VeryHigh", Hash: "h1"},
+		{Provider: "groq", Redacted: "gsk_****", Confidence: "Low", Hash: "h2"},
+		{Provider: "openai", Redacted: "sk-****", Confidence: "Medium", Hash: "h3"},
+	}}
+}
+
+func TestFilters(t *testing.T) {
+	m := newModel(testResult(), Options{})
+	if n := len(m.visible()); n != 3 {
+		t.Fatalf("expected 3 visible findings, got %d", n)
+	}
+
+	// providers are sorted: groq, openai
+	m.handleKey("p")
+	if n := len(m.visible()); n != 1 {
+		t.Errorf("expected 1 groq finding, got %d", n)
+	}
+	m.handleKey("p")
+	if n := len(m.visible()); n != 2 {
+		t.Errorf("expected 2 openai findings, got %d", n)
+	}
+	m.handleKey("p")
+	m.handleKey("c")
+	m.handleKey("c")
+	if n := len(m.visible()); n != 1 {
+		t.Errorf("expected 1 finding at High or above, got %d", n)
+	}
+	if !strings.Contains(m.View(), "confidence >= High") {
+		t.Errorf("header should show the confidence filter:\n%s", m.View())
+	}
+}
+
+func TestDecisions(t *testing.T) {
+	m := newModel(testResult(), Options{})
+	m.handleKey("down")
+	m.handleKey("i")
+	m.handleKey("b")
+
+	if len(m.decisions) != 2 {
+		t.Fatalf("expected 2 decisions, got %d", len(m.decisions))
+	}
+	if m.decisions[0].Action != ActionIgnore || m.decisions[0].Key.Hash != "h2" {
+		t.Errorf("unexpected first decision: %+v", m.decisions[0])
+	}
+	if m.decisions[1].Action != ActionBaseline || m.decisions[1].Key.Hash != "h3" {
+		t.Errorf("unexpected second decision: %+v", m.decisions[1])
+	}
+	if n := len(m.visible()); n != 1 {
+		t.Errorf("handled findings should be hidden, %d visible", n)
+	}
+	if m.cursor != 0 {
+		t.Errorf("cursor should clamp to the remaining finding, got %d", m.cursor)
+	}
+}
+
+func TestFileContextMasksSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	content := "# settings\nDEBUG=1\nOPENAI_API_KEY=sk-proj-abcdefghijklmnopqrstuvwxyz0123\nOTHER=1\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := fileContext(aicred.DiscoveredKey{Source: path, Redacted: "sk-proj-****"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines of context, got %d: %q", len(lines), lines)
+	}
+	joined := strings.Join(lines, "\n")
+	if strings.Contains(joined, "abcdefghijklmnop") {
+		t.Errorf("context leaked the key:\n%s", joined)
+	}
+	if !strings.Contains(lines[1], ">    3 | OPENAI_API_KEY=sk-p****") {
+		t.Errorf("expected highlighted, masked key line, got %q", lines[1])
+	}
+
+	if _, err := fileContext(aicred.DiscoveredKey{Source: filepath.Join(dir, "missing")}, 1); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestFileContextMasksShortKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	// Keys shorter than secretLike's 20 characters, one of them twice
+	content := "a: 1\nkey: gsk_short123\nb: 2\nother: 'xai-0123abcd'\nagain: gsk_short123\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, key := range map[string]aicred.DiscoveredKey{
+		"value":    {Source: path, Value: aicred.NewSecretString("gsk_short123")},
+		"suffix":   {Source: path, Redacted: "****t123"},
+		"location": {Source: path, Location: &aicred.Location{Path: path, Line: 2, Column: 6}},
+	} {
+		lines, err := fileContext(key, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		joined := strings.Join(lines, "\n")
+		if strings.Contains(joined, "short") {
+			t.Errorf("%s: context leaked the key:\n%s", name, joined)
+		}
+		if !strings.Contains(joined, ">    2 | key: gsk_****") {
+			t.Errorf("%s: expected the masked key line highlighted:\n%s", name, joined)
+		}
+	}
+
+	// The line of the location wins over a search
+	lines, _ := fileContext(aicred.DiscoveredKey{Source: path, Redacted: "****t123", Location: &aicred.Location{Line: 5, Column: 8}}, 0)
+	if len(lines) != 1 || lines[0] != ">    5 | again: gsk_****" {
+		t.Errorf("lines = %q", lines)
+	}
+}
@@ -0,0 +1,236 @@
+package aicred
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VaultMapping says where PushToVault stores each key in HashiCorp Vault.
+// Keys are written to a KV version 2 secrets engine.
+type VaultMapping struct {
+	// Address is Vault's base URL; "" means $VAULT_ADDR
+	Address string
+	// Token authenticates the writes; "" means $VAULT_TOKEN
+	Token string
+	// Namespace is sent as X-Vault-Namespace on Vault Enterprise; ""
+	// means $VAULT_NAMESPACE, if set
+	Namespace string
+	// Mount is the KV v2 mount; "" means "secret"
+	Mount string
+	// Path returns a key's secret path within the mount, or "" to skip the
+	// key. nil stores each key at aicred/<provider>/<hash prefix>, so
+	// copies of one key share a path.
+	Path func(DiscoveredKey) string
+	// Field is the name the value is stored under; "" means "api_key"
+	Field string
+	// Remediate replaces each stored key in its source file with a
+	// reference to where it now lives
+	Remediate bool
+	// Reference returns the text that replaces a key in its source file;
+	// nil means "vault:<mount>/<path>#<field>"
+	Reference func(key DiscoveredKey, path string) string
+	Client    *http.Client
+}
+
+// VaultPush is what PushToVault did with one key
+type VaultPush struct {
+	Key DiscoveredKey
+	// Path is the secret path within the mount; "" when the key was skipped
+	Path string
+	// Remediated reports whether the key was replaced in its source file
+	Remediated bool
+	// Err is why the key was not stored or remediated
+	Err error
+}
+
+// PushToVault writes the full value of every key in result.Keys to Vault,
+// and, with mapping.Remediate, replaces each stored key in its source file
+// with a reference to it. The result must come from a scan with RedactionNone;
+// keys without a value are reported with an error wrapping ErrNotFound.
+//
+// Each key gets a VaultPush. The returned error joins the per-key errors,
+// or reports a mapping with no address or token.
+func PushToVault(ctx context.Context, result *ScanResult, mapping VaultMapping) ([]VaultPush, error) {
+	address := firstNonEmpty(mapping.Address, os.Getenv("VAULT_ADDR"))
+	token := firstNonEmpty(mapping.Token, os.Getenv("VAULT_TOKEN"))
+	if address == "" || token == "" {
+		return nil, errors.New("vault: address and token are required")
+	}
+	mapping.Address = strings.TrimSuffix(address, "/")
+	mapping.Token = token
+	mapping.Namespace = firstNonEmpty(mapping.Namespace, os.Getenv("VAULT_NAMESPACE"))
+	mapping.Mount = strings.Trim(firstNonEmpty(mapping.Mount, "secret"), "/")
+	mapping.Field = firstNonEmpty(mapping.Field, "api_key")
+	if mapping.Path == nil {
+		mapping.Path = defaultVaultPath
+	}
+	if mapping.Reference == nil {
+		mapping.Reference = func(_ DiscoveredKey, path string) string {
+			return fmt.Sprintf("vault:%s/%s#%s", mapping.Mount, path, mapping.Field)
+		}
+	}
+	if mapping.Client == nil {
+		mapping.Client = http.DefaultClient
+	}
+
+	var (
+		pushes []VaultPush
+		errs   []error
+		// written maps each path stored by this call to the key stored there
+		written = map[string]DiscoveredKey{}
+	)
+	for _, key := range result.Keys {
+		push := VaultPush{Key: key}
+		push.Err = pushKey(ctx, &mapping, &push, written)
+		if push.Err != nil {
+			errs = append(errs, fmt.Errorf("%s key in %s: %w", key.Provider, key.Source, push.Err))
+		}
+		pushes = append(pushes, push)
+	}
+	return pushes, errors.Join(errs...)
+}
+
+func pushKey(ctx context.Context, mapping *VaultMapping, push *VaultPush, written map[string]DiscoveredKey) error {
+	key := push.Key
+	if key.Value.IsZero() {
+		return fmt.Errorf("no value to store, scan with RedactionNone: %w", ErrNotFound)
+	}
+	path := strings.Trim(mapping.Path(key), "/")
+	if path == "" {
+		return nil
+	}
+	push.Path = path
+	switch prev, ok := written[path]; {
+	case ok && !SameCredential(prev, key):
+		return fmt.Errorf("vault path %s already holds a different key", path)
+	case !ok:
+		if err := writeVault(ctx, mapping, path, key.Value); err != nil {
+			return err
+		}
+		written[path] = key
+	}
+	if !mapping.Remediate {
+		return nil
+	}
+	if err := remediateFile(key, mapping.Reference(key, path)); err != nil {
+		return err
+	}
+	push.Remediated = true
+	return nil
+}
+
+// defaultVaultPath stores a key at aicred/<provider>/<first 12 hex digits of
+// its hash>
+func defaultVaultPath(key DiscoveredKey) string {
+	hash := key.Hash
+	if hash == "" {
+		hash = key.Fingerprint(nil).HMAC
+	}
+	return fmt.Sprintf("aicred/%s/%s", strings.ToLower(key.Provider), hash[:min(12, len(hash))])
+}
+
+// writeVault stores value under the mapping's field at path with the KV v2
+// API
+func writeVault(ctx context.Context, mapping *VaultMapping, path string, value Secret) error {
+	var body []byte
+	var err error
+	value.Use(func(b []byte) {
+		body, err = json.Marshal(map[string]map[string]string{"data": {mapping.Field: string(b)}})
+	})
+	defer zero(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault payload: %v", err)
+	}
+	url := fmt.Sprintf("%s/v1/%s/data/%s", mapping.Address, mapping.Mount, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", mapping.Token)
+	if mapping.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", mapping.Namespace)
+	}
+	resp, err := mapping.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %w: %v", ErrIO, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("vault rejected write to %s: %w: %s", path, ErrPermissionDenied, strings.TrimSpace(string(snippet)))
+		}
+		return fmt.Errorf("vault rejected write to %s: %s: %s", path, resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// remediateFile replaces every occurrence of key's value in its source file
+// with reference, keeping the file's permissions. Keys found inside
+// archives, binary configs or browser storage cannot be rewritten.
+func remediateFile(key DiscoveredKey, reference string) error {
+	path := key.Source
+	if key.Location != nil && key.Location.Path != "" {
+		path = key.Location.Path
+	}
+	if path == "" || strings.Contains(path, "!") {
+		return fmt.Errorf("cannot remediate %q: not a plain file", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("remediating %s: %w: %v", path, ErrIO, err)
+	}
+	content, err := os.ReadFile(path)
+	defer zero(content)
+	if err != nil {
+		return fmt.Errorf("remediating %s: %w: %v", path, ErrIO, err)
+	}
+	var updated []byte
+	key.Value.Use(func(value []byte) {
+		if bytes.Contains(content, value) {
+			updated = bytes.ReplaceAll(content, value, []byte(reference))
+		}
+	})
+	if updated == nil {
+		return fmt.Errorf("remediating %s: key no longer present: %w", path, ErrNotFound)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("remediating %s: %w: %v", path, ErrIO, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(updated); err != nil {
+		tmp.Close()
+		return fmt.Errorf("remediating %s: %w: %v", path, ErrIO, err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("remediating %s: %w: %v", path, ErrIO, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("remediating %s: %w: %v", path, ErrIO, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("remediating %s: %w: %v", path, ErrIO, err)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package aicred
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPushToVault(t *testing.T) {
+	stored := map[string]map[string]string{}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stored[r.URL.Path] = body.Data
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer vault.Close()
+
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	writeFile(t, env, []byte("OPENAI_API_KEY=sk-proj-abcdefghijklmnopqrstuvwx\n"))
+	if err := os.Chmod(env, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	key := fingerprintKey(env, "OPENAI_API_KEY", "sk-proj-abcdefghijklmnopqrstuvwx")
+	result := &ScanResult{Keys: []DiscoveredKey{key, {Provider: "groq", Source: env, Hash: "abc"}}}
+
+	pushes, err := PushToVault(context.Background(), result, VaultMapping{
+		Address:   vault.URL,
+		Token:     "root",
+		Mount:     "kv",
+		Path:      func(k DiscoveredKey) string { return "teams/ml/" + k.Provider },
+		Remediate: true,
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want the valueless key's ErrNotFound", err)
+	}
+	if len(pushes) != 2 || pushes[0].Err != nil || !pushes[0].Remediated || pushes[0].Path != "teams/ml/openai" {
+		t.Fatalf("pushes = %+v", pushes)
+	}
+	if got := stored["/v1/kv/data/teams/ml/openai"]["api_key"]; got != "sk-proj-abcdefghijklmnopqrstuvwx" {
+		t.Errorf("stored %q", got)
+	}
+
+	content, err := os.ReadFile(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "OPENAI_API_KEY=vault:kv/teams/ml/openai#api_key\n" {
+		t.Errorf("remediated file = %q", content)
+	}
+	if info, _ := os.Stat(env); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	_, err = PushToVault(context.Background(), &ScanResult{Keys: []DiscoveredKey{key}}, VaultMapping{Address: vault.URL, Token: "wrong"})
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("err = %v, want ErrPermissionDenied", err)
+	}
+	if !strings.HasPrefix(defaultVaultPath(key), "aicred/openai/") {
+		t.Errorf("default path = %s", defaultVaultPath(key))
+	}
+}
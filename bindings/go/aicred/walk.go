@@ -0,0 +1,197 @@
+package aicred
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// walker walks directories inside the home directory for the Go-side
+// passes, applying the ignore rules and the MaxDepth, FollowSymlinks and
+// deadline guards
+type walker struct {
+	pass     string
+	run      *scanRun
+	home     string
+	ignore   *ignoreMatcher
+	maxDepth int
+	follow   bool
+	deadline time.Time
+	// visited holds the real path of each directory entered while following
+	// symbolic links, so a link back to an ancestor is not walked again
+	visited map[string]bool
+}
+
+func newWalker(pass, home string, options ScanOptions, run *scanRun) *walker {
+	return &walker{
+		pass:     pass,
+		run:      run,
+		home:     home,
+		ignore:   newIgnoreMatcher(home, options),
+		maxDepth: options.MaxDepth,
+		follow:   options.FollowSymlinks,
+		deadline: run.deadlineOrZero(),
+		visited:  map[string]bool{},
+	}
+}
+
+// walk calls fn for each regular file under root, which usually lies inside
+// home. It skips paths the ignore matcher excludes, unreadable paths, which
+// it reports as warnings, directories more than maxDepth levels below root when maxDepth > 0, and
+// directories more than MaxDepth levels below home. Symbolic links are
+// followed only with FollowSymlinks, and the walk stops at the deadline.
+func (w *walker) walk(root string, maxDepth int, fn func(p string, d fs.DirEntry)) {
+	w.walkDir(root, root, maxDepth, fn)
+}
+
+func (w *walker) walkDir(root, dir string, maxDepth int, fn func(p string, d fs.DirEntry)) {
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// A missing root just means the application is not installed
+			if !(p == dir && errors.Is(err, fs.ErrNotExist)) {
+				w.run.warn(w.pass, p, warningKindOf(err), err)
+			}
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !w.deadline.IsZero() && time.Now().After(w.deadline) {
+			return fs.SkipAll
+		}
+		clean := filepath.Clean(p)
+
+		isDir := d.IsDir()
+		link := d.Type()&fs.ModeSymlink != 0
+		if link {
+			if !w.follow {
+				return nil
+			}
+			info, err := os.Stat(p)
+			if err != nil {
+				w.run.warn(w.pass, clean, warningKindOf(err), err)
+				return nil
+			}
+			isDir = info.IsDir()
+			if !isDir {
+				if info.Mode().IsRegular() && !w.ignored(clean, false) {
+					fn(clean, fs.FileInfoToDirEntry(info))
+				}
+				return nil
+			}
+		}
+		if w.ignored(clean, isDir) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !isDir {
+			if d.Type().IsRegular() {
+				fn(clean, d)
+			}
+			return nil
+		}
+
+		if w.tooDeep(root, clean, maxDepth) {
+			return fs.SkipDir
+		}
+		if link {
+			// A trailing separator makes WalkDir resolve the link and descend;
+			// the cycle check happens when that walk enters the directory
+			w.walkDir(root, clean+string(filepath.Separator), maxDepth, fn)
+			return nil
+		}
+		if w.follow {
+			real, err := filepath.EvalSymlinks(p)
+			if err != nil || w.visited[real] {
+				logger().Debug("skipping directory already walked", slog.String("path", clean))
+				return fs.SkipDir
+			}
+			w.visited[real] = true
+		}
+		return nil
+	})
+}
+
+func (w *walker) ignored(p string, isDir bool) bool {
+	rel, err := filepath.Rel(w.home, p)
+	return err == nil && w.ignore.ignored(filepath.ToSlash(rel), isDir)
+}
+
+// ignoredPath reports whether p or a directory above it inside home is
+// excluded, for passes that go straight to known paths instead of walking
+// down to them
+func (w *walker) ignoredPath(p string, isDir bool) bool {
+	rel, err := filepath.Rel(w.home, p)
+	if err != nil || !within(w.home, p) {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i < len(parts); i++ {
+		if w.ignore.ignored(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return w.ignore.ignored(filepath.ToSlash(rel), isDir)
+}
+
+// tooDeep reports whether directory p lies beyond maxDepth levels below root
+// or MaxDepth levels below home
+func (w *walker) tooDeep(root, p string, maxDepth int) bool {
+	depth := func(base string) int {
+		return strings.Count(strings.TrimPrefix(p, filepath.Clean(base)), string(filepath.Separator))
+	}
+	if maxDepth > 0 && p != root && depth(root) > maxDepth {
+		return true
+	}
+	// Roots outside home, such as a redirected OneDrive, count from the root
+	base := w.home
+	if !within(base, p) {
+		base = root
+	}
+	return w.maxDepth > 0 && depth(base) > w.maxDepth
+}
+
+// scanParallel runs scan over paths in a pool of at most concurrency
+// workers, one per GOMAXPROCS when concurrency <= 0, and returns the keys in
+// path order so results do not depend on scheduling. Paths not yet started
+// when the deadline passes are skipped.
+func scanParallel(paths []string, concurrency int, deadline time.Time, scan func(p string) []DiscoveredKey) []DiscoveredKey {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	concurrency = min(concurrency, len(paths))
+
+	results := make([][]DiscoveredKey, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if deadline.IsZero() || time.Now().Before(deadline) {
+					results[i] = scan(paths[i])
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var keys []DiscoveredKey
+	for _, r := range results {
+		keys = append(keys, r...)
+	}
+	return keys
+}
@@ -0,0 +1,115 @@
+package aicred
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func walked(t *testing.T, home string, options ScanOptions) []string {
+	t.Helper()
+	var got []string
+	newWalker("test", home, options, nil).walk(home, 0, func(p string, d fs.DirEntry) {
+		rel, _ := filepath.Rel(home, p)
+		got = append(got, filepath.ToSlash(rel))
+	})
+	sort.Strings(got)
+	return got
+}
+
+func TestWalkerMaxDepth(t *testing.T) {
+	home := t.TempDir()
+	for _, rel := range []string{"top", "a/one", "a/b/two", "a/b/c/three"} {
+		writeFile(t, filepath.Join(home, filepath.FromSlash(rel)), nil)
+	}
+	if got := walked(t, home, ScanOptions{}); len(got) != 4 {
+		t.Errorf("unlimited walk found %v", got)
+	}
+	if got := walked(t, home, ScanOptions{MaxDepth: 2}); len(got) != 3 || got[2] != "top" {
+		t.Errorf("MaxDepth 2 found %v, want top, a/one and a/b/two", got)
+	}
+}
+
+func TestWalkerSymlinks(t *testing.T) {
+	home, outside := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(home, "real", "key.env"), nil)
+	writeFile(t, filepath.Join(outside, "linked.env"), nil)
+	for link, target := range map[string]string{
+		"real/loop": "..",                                 // a cycle back to home
+		"mirror":    "real",                               // a second route to real
+		"elsewhere": outside,                              // a directory outside home
+		"file.env":  filepath.Join(outside, "linked.env"), // a linked file
+	} {
+		if err := os.Symlink(target, filepath.Join(home, filepath.FromSlash(link))); err != nil {
+			t.Skip("symlinks unsupported:", err)
+		}
+	}
+
+	if got := walked(t, home, ScanOptions{}); len(got) != 1 || got[0] != "real/key.env" {
+		t.Errorf("walk without FollowSymlinks found %v", got)
+	}
+
+	done := make(chan []string)
+	go func() { done <- walked(t, home, ScanOptions{FollowSymlinks: true}) }()
+	select {
+	case got := <-done:
+		// real is reached once, directly or through mirror, and loop ends
+		want := map[string]bool{"elsewhere/linked.env": true, "file.env": true}
+		var direct int
+		for _, p := range got {
+			switch {
+			case want[p]:
+				delete(want, p)
+			case filepath.Base(p) == "key.env":
+				direct++
+			default:
+				t.Errorf("unexpected path %s", p)
+			}
+		}
+		if direct != 1 || len(want) != 0 {
+			t.Errorf("FollowSymlinks found %v", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("walk did not end on a symlink cycle")
+	}
+}
+
+func TestTimeLimitedSkipsSlowFiles(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	scan := func(p string) []DiscoveredKey {
+		if p == "slow" {
+			<-release
+		}
+		return []DiscoveredKey{{Source: p}}
+	}
+	run := (*scanRun)(nil)
+	keys := scanParallel([]string{"fast", "slow", "fast2"}, 1, time.Time{}, run.timeLimited("test", scan, 20*time.Millisecond))
+	if len(keys) != 2 || keys[0].Source != "fast" || keys[1].Source != "fast2" {
+		t.Errorf("got %+v, want the two fast files", keys)
+	}
+}
+
+func TestScanGlobalTimeout(t *testing.T) {
+	home := t.TempDir()
+	result, err := Scan(ScanOptions{HomeDir: home, ScanArchives: true, GlobalTimeout: time.Nanosecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("got %v, want ErrTimeout", err)
+	}
+	// What was found until then comes back with the error
+	if result == nil || result.HomeDir != home || result.SchemaVersion != ScanResultSchemaVersion {
+		t.Errorf("partial result = %+v", result)
+	}
+
+	err = withDeadline("op", time.Now().Add(10*time.Millisecond), func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("withDeadline: got %v, want ErrTimeout", err)
+	}
+}
@@ -0,0 +1,100 @@
+package aicred
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// windowsAppDirs maps the home-relative application data directories of a
+// default Windows profile to the variables that hold their real location,
+// which differs when the profile is redirected by policy or roams
+var windowsAppDirs = []struct{ rel, env string }{
+	{"AppData/Roaming", "APPDATA"},
+	{"AppData/Local", "LOCALAPPDATA"},
+}
+
+// oneDriveVars hold the OneDrive folders of the current user. Known-folder
+// redirection moves Documents and Desktop there, often outside the profile.
+var oneDriveVars = []string{"OneDrive", "OneDriveCommercial", "OneDriveConsumer"}
+
+// isCurrentProfile reports whether home is the current user's profile
+// directory, so the profile's environment describes it
+func isCurrentProfile(home string) bool {
+	profile := getenv("USERPROFILE")
+	return profile != "" && strings.EqualFold(filepath.Clean(profile), filepath.Clean(home))
+}
+
+// homePath returns the directory rel, a slash-separated path relative to
+// home, names. When home is the current user's profile, paths under
+// AppData/Roaming and AppData/Local follow %APPDATA% and %LOCALAPPDATA%.
+func homePath(home, rel string) string {
+	if isCurrentProfile(home) {
+		for _, dir := range windowsAppDirs {
+			rest, ok := strings.CutPrefix(rel, dir.rel)
+			if !ok || (rest != "" && rest[0] != '/') {
+				continue
+			}
+			if base := getenv(dir.env); base != "" {
+				return filepath.Join(base, filepath.FromSlash(rest))
+			}
+		}
+	}
+	return filepath.Join(home, filepath.FromSlash(rel))
+}
+
+// scanRoots returns the directories the whole-home passes walk: home, and
+// when home is the current user's profile, the OneDrive folders and
+// relocated AppData directories that lie outside it
+func scanRoots(home string) []string {
+	roots := []string{home}
+	if !isCurrentProfile(home) {
+		return roots
+	}
+	var extra []string
+	for _, env := range oneDriveVars {
+		extra = append(extra, getenv(env))
+	}
+	for _, dir := range windowsAppDirs {
+		extra = append(extra, getenv(dir.env))
+	}
+	for _, dir := range extra {
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if !containsPath(roots, dir) {
+			roots = append(roots, dir)
+		}
+	}
+	return roots
+}
+
+// containsPath reports whether p is one of roots or lies inside one
+func containsPath(roots []string, p string) bool {
+	for _, root := range roots {
+		if within(root, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether p is base or lies inside it. Windows paths are
+// compared without regard to case.
+func within(base, p string) bool {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// absHome makes home absolute. The os package only applies the \\?\ prefix
+// that lifts the 260-character MAX_PATH limit on Windows to absolute
+// paths, so deep node_modules or OneDrive trees stay readable.
+func absHome(home string) string {
+	if abs, err := filepath.Abs(home); err == nil {
+		return abs
+	}
+	return home
+}
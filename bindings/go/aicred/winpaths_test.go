@@ -0,0 +1,85 @@
+package aicred
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// fakeProfile makes home look like the current user's Windows profile, with
+// the given variables set, until the test ends
+func fakeProfile(t *testing.T, home string, env map[string]string) {
+	t.Helper()
+	saved := getenv
+	t.Cleanup(func() { getenv = saved })
+	getenv = func(name string) string {
+		if name == "USERPROFILE" {
+			return home
+		}
+		return env[name]
+	}
+}
+
+func TestHomePath(t *testing.T) {
+	home := t.TempDir()
+	roaming := filepath.Join(t.TempDir(), "Roaming")
+	if got, want := homePath(home, "AppData/Roaming/Claude"), filepath.Join(home, "AppData", "Roaming", "Claude"); got != want {
+		t.Errorf("without a profile: %s, want %s", got, want)
+	}
+
+	fakeProfile(t, home, map[string]string{"APPDATA": roaming})
+	for rel, want := range map[string]string{
+		"AppData/Roaming/Claude":      filepath.Join(roaming, "Claude"),
+		"AppData/Roaming":             roaming,
+		"AppData/RoamingExtra":        filepath.Join(home, "AppData", "RoamingExtra"),
+		"AppData/Local/Google/Chrome": filepath.Join(home, "AppData", "Local", "Google", "Chrome"),
+		".config":                     filepath.Join(home, ".config"),
+	} {
+		if got := homePath(home, rel); got != want {
+			t.Errorf("homePath(%s) = %s, want %s", rel, got, want)
+		}
+	}
+	if got := homePath(t.TempDir(), "AppData/Roaming/Claude"); got == filepath.Join(roaming, "Claude") {
+		t.Errorf("another home followed %%APPDATA%%")
+	}
+}
+
+func TestScanRoots(t *testing.T) {
+	home := t.TempDir()
+	oneDrive := t.TempDir()
+	fakeProfile(t, home, map[string]string{
+		"OneDrive":           oneDrive,
+		"OneDriveCommercial": oneDrive,
+		"OneDriveConsumer":   filepath.Join(home, "OneDrive"),
+		"LOCALAPPDATA":       filepath.Join(home, "AppData", "Local"),
+		"APPDATA":            "relative",
+	})
+	if got := scanRoots(home); !slices.Equal(got, []string{home, oneDrive}) {
+		t.Errorf("scanRoots = %q, want home and the OneDrive outside it", got)
+	}
+	if got := scanRoots(t.TempDir()); len(got) != 1 {
+		t.Errorf("scanRoots of another home = %q", got)
+	}
+}
+
+func TestScanRedirectedProfile(t *testing.T) {
+	home := t.TempDir()
+	oneDrive := t.TempDir()
+	roaming := t.TempDir()
+	fakeProfile(t, home, map[string]string{"OneDrive": oneDrive, "APPDATA": roaming})
+
+	writeFile(t, filepath.Join(oneDrive, "Documents", "app", ".npmrc"), []byte("//ai-proxy.example.com/:_authToken=sk-proj-abcdefghijklmnopqrstuvwx\n"))
+	if keys := scanPackageConfigs(home, ScanOptions{MaxDepth: 3}, nil); len(keys) != 1 {
+		t.Errorf("found %d keys in the redirected OneDrive, want 1", len(keys))
+	}
+
+	data, err := os.ReadFile("testdata/settings.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(roaming, "Example", "store.db"), data)
+	if got := keyProviders(scanBinaryConfigs(home, ScanOptions{}, nil)); !slices.Equal(got, []string{"groq", "groq"}) {
+		t.Errorf("providers under %%APPDATA%% = %v", got)
+	}
+}
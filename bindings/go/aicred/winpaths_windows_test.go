@@ -0,0 +1,30 @@
+//go:build windows
+
+package aicred
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanLongPaths(t *testing.T) {
+	home := t.TempDir()
+	dir := home
+	for len(dir) < 300 {
+		dir = filepath.Join(dir, strings.Repeat("nested", 8))
+	}
+	writeFile(t, filepath.Join(dir, ".npmrc"), []byte("//ai-proxy.example.com/:_authToken=sk-proj-abcdefghijklmnopqrstuvwx\n"))
+
+	if keys := scanPackageConfigs(home, ScanOptions{}, nil); len(keys) != 1 {
+		t.Errorf("found %d keys below MAX_PATH, want 1", len(keys))
+	}
+}
+
+func TestHomePathFollowsCase(t *testing.T) {
+	home := t.TempDir()
+	fakeProfile(t, strings.ToUpper(home), map[string]string{"LOCALAPPDATA": `D:\Local`})
+	if got := homePath(home, "AppData/Local/Microsoft/Edge"); got != `D:\Local\Microsoft\Edge` {
+		t.Errorf("homePath = %s", got)
+	}
+}
@@ -0,0 +1,520 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+// openStore opens the aicred configuration of home. Tests replace it.
+var openStore = func(home string) (aicred.ConfigStore, error) {
+	return aicred.OpenSession(home)
+}
+
+// closeStore closes store if it holds resources, as a Session does
+func closeStore(store aicred.ConfigStore) {
+	if c, ok := store.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// configFlags adds the flags every configuration command takes and returns
+// a function opening the store they select
+func configFlags(fs *flag.FlagSet) func() (aicred.ConfigStore, error) {
+	home := fs.String("home", "", "home directory whose aicred configuration to use (default: current user's home)")
+	return func() (aicred.ConfigStore, error) { return openStore(*home) }
+}
+
+// fail prints err and returns exit code 1, or 2 for invalid input
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintln(stderr, err)
+	if errors.Is(err, aicred.ErrInvalidOption) || errors.Is(err, aicred.ErrInvalidInstance) {
+		return 2
+	}
+	return 1
+}
+
+func runInstances(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: aicred-go instances list|add|rm [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "list":
+		return runInstancesList(args[1:], stdout, stderr)
+	case "add":
+		return runInstancesAdd(args[1:], stdout, stderr)
+	case "rm":
+		return runInstancesRemove(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown instances command %q\n", args[0])
+		return 2
+	}
+}
+
+func runInstancesList(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("instances list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	s, err := open()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer closeStore(s)
+
+	instances, err := s.LoadInstances()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if len(instances) == 0 {
+		fmt.Fprintln(stdout, "No instances configured.")
+		return 0
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPROVIDER\tBASE URL\tKEY\tACTIVE\tMODELS")
+	for _, instance := range instances {
+		key := "-"
+		if !instance.APIKey.IsZero() {
+			key = "set"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\n", instance.ID, instance.ProviderType, instance.BaseURL, key, instance.Active, strings.Join(instance.Models, ","))
+	}
+	if err := tw.Flush(); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+func runInstancesAdd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("instances add", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	id := fs.String("id", "", "instance ID (default: generated from the provider and base URL)")
+	provider := fs.String("provider", "", "provider type, such as openai")
+	baseURL := fs.String("base-url", "", "API base URL (default: the provider type's first endpoint)")
+	keyEnv := fs.String("api-key-env", "", "environment variable holding the API key")
+	models := fs.String("models", "", "comma-separated model IDs")
+	inactive := fs.Bool("inactive", false, "add the instance deactivated")
+	strict := fs.Bool("strict", false, "validate the instance against its provider type")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *provider == "" {
+		fmt.Fprintln(stderr, "instances add: --provider is required")
+		return 2
+	}
+	if *baseURL == "" {
+		if t, ok := aicred.LookupProviderType(*provider); ok && len(t.BaseURLs) > 0 {
+			*baseURL = t.BaseURLs[0]
+		}
+	}
+
+	instance := aicred.ProviderInstance{
+		ID:           *id,
+		ProviderType: *provider,
+		BaseURL:      *baseURL,
+		Models:       splitList(*models),
+		Active:       !*inactive,
+	}
+	if *keyEnv != "" {
+		key := os.Getenv(*keyEnv)
+		if key == "" {
+			fmt.Fprintf(stderr, "instances add: $%s is not set\n", *keyEnv)
+			return 2
+		}
+		instance.APIKey = aicred.NewSecretString(key)
+		instance.Metadata = map[string]string{aicred.MetadataKeyCreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	}
+
+	s, err := open()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer closeStore(s)
+
+	existing, err := s.LoadInstances()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if instance.ID == "" {
+		if instance.ID, err = aicred.NextInstanceID(s, instance.ProviderType, instance.BaseURL); err != nil {
+			return fail(stderr, err)
+		}
+	} else if slices.ContainsFunc(existing, func(p aicred.ProviderInstance) bool { return p.ID == instance.ID }) {
+		fmt.Fprintf(stderr, "instances add: instance %q already exists\n", instance.ID)
+		return 1
+	}
+	if err := aicred.ValidateProviderInstance(instance, aicred.ValidationOptions{Strict: *strict}); err != nil {
+		return fail(stderr, err)
+	}
+	if err := s.SaveInstance(instance); err != nil {
+		return fail(stderr, err)
+	}
+	fmt.Fprintln(stdout, instance.ID)
+	return 0
+}
+
+func runInstancesRemove(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("instances rm", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: aicred-go instances rm [flags] ID...")
+		return 2
+	}
+	s, err := open()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer closeStore(s)
+
+	for _, id := range fs.Args() {
+		if err := s.DeleteInstance(id); err != nil {
+			return fail(stderr, err)
+		}
+		// Labels on a removed instance would dangle
+		labels, err := s.LoadLabels()
+		if err != nil {
+			return fail(stderr, err)
+		}
+		kept := slices.DeleteFunc(slices.Clone(labels), func(a aicred.LabelAssignment) bool { return a.Target.InstanceID == id })
+		if len(kept) < len(labels) {
+			if err := s.SaveLabels(kept); err != nil {
+				return fail(stderr, err)
+			}
+		}
+		fmt.Fprintf(stdout, "removed %s\n", id)
+	}
+	return 0
+}
+
+func runLabels(args []string, stdout, stderr io.Writer) int {
+	command := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("labels "+command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var target aicred.LabelTarget
+	switch command {
+	case "list":
+		if fs.NArg() != 0 {
+			fmt.Fprintln(stderr, "usage: aicred-go labels list [flags]")
+			return 2
+		}
+	case "assign", "unassign":
+		if fs.NArg() != 2 {
+			fmt.Fprintf(stderr, "usage: aicred-go labels %s [flags] LABEL INSTANCE[/MODEL]\n", command)
+			return 2
+		}
+		target = parseLabelTarget(fs.Arg(1))
+	default:
+		fmt.Fprintf(stderr, "unknown labels command %q\n", command)
+		return 2
+	}
+
+	s, err := open()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer closeStore(s)
+	assignments, err := s.LoadLabels()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	assigned := slices.IndexFunc(assignments, func(a aicred.LabelAssignment) bool {
+		return a.LabelName == fs.Arg(0) && a.Target == target
+	})
+
+	switch command {
+	case "assign":
+		if _, err := s.GetInstance(target.InstanceID); err != nil {
+			return fail(stderr, err)
+		}
+		if assigned < 0 {
+			err = s.SaveLabels(append(assignments, aicred.LabelAssignment{LabelName: fs.Arg(0), Target: target, AssignedAt: time.Now().UTC(), AssignedBy: "aicred-go"}))
+		}
+	case "unassign":
+		if assigned < 0 {
+			err = fmt.Errorf("label %q is not assigned to %s: %w", fs.Arg(0), fs.Arg(1), aicred.ErrNotFound)
+			break
+		}
+		err = s.SaveLabels(slices.Delete(assignments, assigned, assigned+1))
+	default:
+		slices.SortStableFunc(assignments, func(a, b aicred.LabelAssignment) int { return strings.Compare(a.LabelName, b.LabelName) })
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "LABEL\tTARGET\tASSIGNED")
+		for _, a := range assignments {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", a.LabelName, formatLabelTarget(a.Target), a.AssignedAt.Format(time.DateOnly))
+		}
+		err = tw.Flush()
+	}
+	if err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+// parseLabelTarget parses INSTANCE or INSTANCE/MODEL. Model IDs may hold
+// slashes of their own, instance IDs may not.
+func parseLabelTarget(s string) aicred.LabelTarget {
+	if instance, model, ok := strings.Cut(s, "/"); ok {
+		return aicred.LabelTarget{Type: aicred.LabelTargetModel, InstanceID: instance, ModelID: model}
+	}
+	return aicred.LabelTarget{Type: aicred.LabelTargetInstance, InstanceID: s}
+}
+
+func formatLabelTarget(t aicred.LabelTarget) string {
+	if t.Type == aicred.LabelTargetModel {
+		return t.InstanceID + "/" + t.ModelID
+	}
+	return t.InstanceID
+}
+
+func runTags(args []string, stdout, stderr io.Writer) int {
+	command := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("tags "+command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	var description, parent, color *string
+	if command == "add" {
+		description = fs.String("description", "", "what the tag means")
+		parent = fs.String("parent", "", "tag this one sits under")
+		color = fs.String("color", "", "display color, such as #ff0000")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	switch {
+	case command == "list" && fs.NArg() == 0:
+	case (command == "add" || command == "rm") && fs.NArg() == 1:
+	case command == "list" || command == "add" || command == "rm":
+		fmt.Fprintf(stderr, "usage: aicred-go tags list|add NAME|rm NAME [flags]\n")
+		return 2
+	default:
+		fmt.Fprintf(stderr, "unknown tags command %q\n", command)
+		return 2
+	}
+
+	s, err := open()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer closeStore(s)
+	tags, err := s.LoadTags()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	name := fs.Arg(0)
+	i := slices.IndexFunc(tags, func(t aicred.Tag) bool { return t.Name == name })
+
+	switch command {
+	case "add":
+		if i >= 0 {
+			fmt.Fprintf(stderr, "tags add: tag %q already exists\n", name)
+			return 1
+		}
+		if *parent != "" && !slices.ContainsFunc(tags, func(t aicred.Tag) bool { return t.Name == *parent }) {
+			fmt.Fprintf(stderr, "tags add: parent tag %q does not exist\n", *parent)
+			return 1
+		}
+		tag := aicred.Tag{Name: name, Description: *description, Parent: *parent, CreatedAt: time.Now().UTC()}
+		if *color != "" {
+			tag.Metadata = map[string]string{"color": *color}
+		}
+		err = s.SaveTags(append(tags, tag))
+	case "rm":
+		if i < 0 {
+			fmt.Fprintf(stderr, "tags rm: tag %q: %v\n", name, aicred.ErrNotFound)
+			return 1
+		}
+		err = s.SaveTags(slices.Delete(tags, i, i+1))
+	default:
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TAG\tPARENT\tDESCRIPTION")
+		for _, t := range tags {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, t.Parent, t.Description)
+		}
+		err = tw.Flush()
+	}
+	if err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+func runModels(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "search" {
+		fmt.Fprintln(stderr, "usage: aicred-go models search [flags] QUERY")
+		return 2
+	}
+	fs := flag.NewFlagSet("models search", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: aicred-go models search [flags] QUERY")
+		return 2
+	}
+	query := modelQuery(strings.Join(fs.Args(), " "))
+
+	s, err := open()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer closeStore(s)
+	config, err := aicred.CurrentConfig(s)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	selections, err := config.Select(query)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if len(selections) == 0 {
+		fmt.Fprintln(stdout, "No models match.")
+		return 0
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tINSTANCE\tPROVIDER")
+	for _, sel := range selections {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", sel.Model, sel.Instance.ID, sel.Instance.ProviderType)
+	}
+	if err := tw.Flush(); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+// modelQuery turns a search into a selector query: a full selector such as
+// "provider=openai AND capability:streaming" is used as given, a bare word
+// matches model IDs containing it
+func modelQuery(search string) string {
+	if strings.ContainsAny(search, "=:()") {
+		return search
+	}
+	return fmt.Sprintf("model=%q", "*"+search+"*")
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	strict := fs.Bool("strict", false, "check instances against their provider types")
+	allowHosts := fs.String("allow-hosts", "", "comma-separated hosts strict mode accepts for any provider, such as a gateway")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(stderr, "usage: aicred-go validate [flags] [CONFIG.yaml]")
+		return 2
+	}
+
+	// A file is checked on its own; without one, the stored configuration is
+	var config *aicred.Config
+	if fs.NArg() == 1 {
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return fail(stderr, err)
+		}
+		if config, err = aicred.ParseConfig(data); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	} else {
+		s, err := open()
+		if err != nil {
+			return fail(stderr, err)
+		}
+		defer closeStore(s)
+		if config, err = aicred.CurrentConfig(s); err != nil {
+			return fail(stderr, err)
+		}
+	}
+
+	problems := 0
+	options := aicred.ValidationOptions{Strict: *strict, AllowedHosts: splitList(*allowHosts)}
+	for _, instance := range config.Instances {
+		if err := aicred.ValidateProviderInstance(instance, options); err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Fprintln(stdout, line)
+				problems++
+			}
+		}
+	}
+	for _, issue := range aicred.CheckIntegrity(config.Instances, config.Labels) {
+		fmt.Fprintln(stdout, issue.Message)
+		problems++
+	}
+	if problems > 0 {
+		fmt.Fprintf(stdout, "%d problems found.\n", problems)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%d instances OK.\n", len(config.Instances))
+	return 0
+}
+
+func runExport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	open := configFlags(fs)
+	templateFile := fs.String("template", "", "render this Go text/template instead of writing YAML (it may reveal keys)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var tmpl string
+	if *templateFile != "" {
+		data, err := os.ReadFile(*templateFile)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		tmpl = string(data)
+	}
+
+	s, err := open()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer closeStore(s)
+	config, err := aicred.CurrentConfig(s)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if tmpl != "" {
+		err = aicred.ExportTemplate(config, tmpl, stdout)
+	} else {
+		var data []byte
+		if data, err = aicred.MarshalConfig(config); err == nil {
+			_, err = stdout.Write(data)
+		}
+	}
+	if err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
@@ -0,0 +1,210 @@
+// Command aicred-go is a command-line front end for the aicred Go bindings.
+//
+// Usage:
+//
+//	aicred-go scan [--home DIR] [--format table|json|sarif|github|gitlab] [--only P,...] [--exclude P,...]
+//	aicred-go review [--home DIR] [--only P,...] [--exclude P,...]
+//	aicred-go instances list|add|rm [--home DIR] [flags]
+//	aicred-go labels [list|assign LABEL TARGET|unassign LABEL TARGET] [--home DIR]
+//	aicred-go tags [list|add NAME|rm NAME] [--home DIR]
+//	aicred-go models search [--home DIR] QUERY
+//	aicred-go validate [--home DIR] [--strict] [CONFIG.yaml]
+//	aicred-go export [--home DIR] [--template FILE]
+//	aicred-go providers
+//	aicred-go scanners
+//	aicred-go version
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+	"github.com/robottwo/aicred/bindings/go/aicred/tui"
+)
+
+const usage = `Usage: aicred-go <command> [flags]
+
+Commands:
+  scan        Scan for GenAI credentials and application configs
+  review      Scan and review findings interactively
+  instances   List, add or remove provider instances
+  labels      List, assign or unassign labels
+  tags        List, add or remove tags
+  models      Search the models of the configured instances
+  validate    Check the configuration, or a configuration file
+  export      Write the configuration as YAML or through a template
+  providers   List available provider plugins
+  scanners    List available application scanners
+  version     Print the library version
+
+Run "aicred-go <command> -h" for command flags.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes a command and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "scan":
+		return runScan(args[1:], stdout, stderr)
+	case "review":
+		return runReview(args[1:], stdout, stderr)
+	case "instances":
+		return runInstances(args[1:], stdout, stderr)
+	case "labels":
+		return runLabels(args[1:], stdout, stderr)
+	case "tags":
+		return runTags(args[1:], stdout, stderr)
+	case "models":
+		return runModels(args[1:], stdout, stderr)
+	case "validate":
+		return runValidate(args[1:], stdout, stderr)
+	case "export":
+		return runExport(args[1:], stdout, stderr)
+	case "providers":
+		printList(stdout, aicred.ListProviders())
+		return 0
+	case "scanners":
+		printList(stdout, aicred.ListScanners())
+		return 0
+	case "version":
+		fmt.Fprintln(stdout, aicred.Version())
+		return 0
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+func printList(w io.Writer, items []string) {
+	for _, item := range items {
+		fmt.Fprintln(w, item)
+	}
+}
+
+func runScan(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	home := fs.String("home", "", "home directory to scan (default: current user's home)")
+	format := fs.String("format", "table", "output format: table, json, sarif, github (Actions annotations), or gitlab (Code Quality)")
+	only := fs.String("only", "", "comma-separated providers to scan")
+	exclude := fs.String("exclude", "", "comma-separated providers to skip")
+	maxSize := fs.Int("max-file-size", 0, "maximum file size in bytes (0 for the library default)")
+	redaction := fs.String("redaction", "partial", "redaction level: none, partial, hash-only, or full")
+	fullValues := fs.Bool("include-full-values", false, "same as --redaction none (DANGEROUS)")
+	failOnFindings := fs.Bool("fail-on-findings", false, "exit with status 1 when any key is found, in a config or not")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	level, err := aicred.ParseRedactionLevel(*redaction)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if *fullValues {
+		level = aicred.RedactionNone
+	}
+
+	result, err := aicred.Scan(aicred.ScanOptions{
+		HomeDir:          *home,
+		Redaction:        level,
+		MaxFileSize:      *maxSize,
+		OnlyProviders:    splitList(*only),
+		ExcludeProviders: splitList(*exclude),
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "scan failed: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "table":
+		err = writeTable(stdout, result)
+	case "json":
+		err = writeJSON(stdout, result, level == aicred.RedactionNone)
+	case "sarif":
+		err = writeSARIF(stdout, result)
+	case "github":
+		err = aicred.WriteGitHubAnnotations(stdout, result)
+	case "gitlab":
+		err = aicred.WriteGitLabCodeQuality(stdout, result)
+	default:
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to write output: %v\n", err)
+		return 1
+	}
+
+	if *failOnFindings && findings(result) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// findings counts the keys of result, loose and in application configs
+func findings(result *aicred.ScanResult) int {
+	n := len(result.Keys)
+	for _, instance := range result.ConfigInstances {
+		n += len(instance.Keys)
+	}
+	return n
+}
+
+func runReview(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("review", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	home := fs.String("home", "", "home directory to scan (default: current user's home)")
+	only := fs.String("only", "", "comma-separated providers to scan")
+	exclude := fs.String("exclude", "", "comma-separated providers to skip")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	result, err := aicred.Scan(aicred.ScanOptions{
+		HomeDir:          *home,
+		OnlyProviders:    splitList(*only),
+		ExcludeProviders: splitList(*exclude),
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "scan failed: %v\n", err)
+		return 1
+	}
+
+	decisions, err := tui.Run(result, tui.Options{Output: stdout})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for _, d := range decisions {
+		fmt.Fprintf(stdout, "%s\t%s\t%s\t%s\n", d.Action, d.Key.Provider, d.Key.Redacted, d.Key.Source)
+	}
+	return 0
+}
+
+// splitList parses a comma-separated flag value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
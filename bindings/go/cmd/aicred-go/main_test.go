@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func TestRunCommands(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := run([]string{"providers"}, &out, &errOut); code != 0 {
+		t.Fatalf("providers exited %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "openai") {
+		t.Errorf("expected openai in provider list:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"version"}, &out, &errOut); code != 0 || strings.TrimSpace(out.String()) == "" {
+		t.Errorf("version failed: code=%d out=%q", code, out.String())
+	}
+
+	if code := run([]string{"bogus"}, &out, &errOut); code != 2 {
+		t.Errorf("expected exit 2 for unknown command, got %d", code)
+	}
+	if code := run(nil, &out, &errOut); code != 2 {
+		t.Errorf("expected exit 2 with no command, got %d", code)
+	}
+}
+
+func TestRunScanFormats(t *testing.T) {
+	home := t.TempDir()
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"scan", "--home", home, "--format", "json"}, &out, &errOut); code != 0 {
+		t.Fatalf("json scan exited %d: %s", code, errOut.String())
+	}
+	var result aicred.ScanResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+
+	out.Reset()
+	if code := run([]string{"scan", "--home", home, "--format", "sarif"}, &out, &errOut); code != 0 {
+		t.Fatalf("sarif scan exited %d: %s", code, errOut.String())
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF output: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Errorf("unexpected SARIF log: %+v", log)
+	}
+
+	out.Reset()
+	if code := run([]string{"scan", "--home", home, "--format", "gitlab"}, &out, &errOut); code != 0 {
+		t.Fatalf("gitlab scan exited %d: %s", code, errOut.String())
+	}
+	var issues []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &issues); err != nil {
+		t.Fatalf("invalid Code Quality output: %v", err)
+	}
+
+	out.Reset()
+	if code := run([]string{"scan", "--home", home}, &out, &errOut); code != 0 {
+		t.Fatalf("table scan exited %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "Scanned "+home) {
+		t.Errorf("unexpected table output:\n%s", out.String())
+	}
+
+	if code := run([]string{"scan", "--home", home, "--format", "xml"}, &out, &errOut); code != 2 {
+		t.Errorf("expected exit 2 for unknown format, got %d", code)
+	}
+	if code := run([]string{"scan", "--home", "/nonexistent/aicred"}, &out, &errOut); code != 1 {
+		t.Errorf("expected exit 1 for invalid home, got %d", code)
+	}
+}
+
+func TestSARIFFindings(t *testing.T) {
+	result := &aicred.ScanResult{Keys: []aicred.DiscoveredKey{
+		{Provider: "openai", Redacted: "sk-****abcd", Confidence: "VeryHigh", Source: "/home/u/.env", Hash: "h1"},
+		{Provider: "groq", Redacted: "gsk_****", Confidence: "Low", Hash: "h2"},
+	}}
+	var out bytes.Buffer
+	if err := writeSARIF(&out, result); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 2 {
+		t.Fatalf("unexpected SARIF run: %+v", run)
+	}
+	if run.Results[0].Level != "error" || run.Results[1].Level != "note" {
+		t.Errorf("unexpected levels: %s, %s", run.Results[0].Level, run.Results[1].Level)
+	}
+	if run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI != "/home/u/.env" {
+		t.Errorf("unexpected location: %+v", run.Results[0].Locations)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" openai, ,anthropic ")
+	if len(got) != 2 || got[0] != "openai" || got[1] != "anthropic" {
+		t.Errorf("unexpected split: %v", got)
+	}
+	if splitList("") != nil {
+		t.Error("expected nil for empty input")
+	}
+}
+
+func TestRunConfigCommands(t *testing.T) {
+	home := t.TempDir()
+	store := aicred.NewMemoryStore(nil, nil)
+	defer func(open func(string) (aicred.ConfigStore, error)) { openStore = open }(openStore)
+	openStore = func(dir string) (aicred.ConfigStore, error) {
+		if dir != home {
+			t.Errorf("opened %q, want %q", dir, home)
+		}
+		return store, nil
+	}
+	t.Setenv("TEST_OPENAI_KEY", "sk-proj-0123456789abcdef")
+
+	cmd := func(args ...string) string {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		if code := run(args, &out, &errOut); code != 0 {
+			t.Fatalf("%v exited %d: %s", args, code, errOut.String())
+		}
+		return out.String()
+	}
+	cmd("instances", "add", "--home", home, "--id", "openai-main", "--provider", "openai", "--api-key-env", "TEST_OPENAI_KEY", "--models", "gpt-4o,gpt-4o-mini")
+	cmd("instances", "add", "--home", home, "--provider", "ollama", "--base-url", "http://localhost:11434", "--models", "llama3")
+	if got := cmd("instances", "list", "--home", home); !strings.Contains(got, "openai-main") || !strings.Contains(got, "ollama") || strings.Contains(got, "sk-proj") {
+		t.Errorf("instances list:\n%s", got)
+	}
+
+	cmd("tags", "add", "--home", home, "--description", "Production", "prod")
+	if got := cmd("tags", "--home", home); !strings.Contains(got, "prod") || !strings.Contains(got, "Production") {
+		t.Errorf("tags:\n%s", got)
+	}
+	cmd("labels", "assign", "--home", home, "fast", "openai-main/gpt-4o-mini")
+	if got := cmd("labels", "--home", home); !strings.Contains(got, "openai-main/gpt-4o-mini") {
+		t.Errorf("labels:\n%s", got)
+	}
+
+	if got := cmd("models", "search", "--home", home, "mini"); !strings.Contains(got, "gpt-4o-mini") || strings.Contains(got, "llama3") {
+		t.Errorf("models search mini:\n%s", got)
+	}
+	if got := cmd("models", "search", "--home", home, "label=fast"); !strings.Contains(got, "gpt-4o-mini") || strings.Contains(got, "gpt-4o ") {
+		t.Errorf("models search label=fast:\n%s", got)
+	}
+
+	if got := cmd("validate", "--home", home); !strings.Contains(got, "2 instances OK") {
+		t.Errorf("validate:\n%s", got)
+	}
+	export := cmd("export", "--home", home)
+	if !strings.Contains(export, "id: openai-main") || strings.Contains(export, "sk-proj") {
+		t.Errorf("export:\n%s", export)
+	}
+	config, err := aicred.ParseConfig([]byte(export))
+	if err != nil || len(config.Instances) != 2 || len(config.Tags) != 1 || len(config.Labels) != 1 {
+		t.Errorf("export does not parse back: %+v, %v", config, err)
+	}
+
+	cmd("instances", "rm", "--home", home, "openai-main")
+	if got := cmd("labels", "--home", home); strings.Contains(got, "openai-main") {
+		t.Errorf("labels of a removed instance remain:\n%s", got)
+	}
+	cmd("tags", "rm", "--home", home, "prod")
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"instances", "rm", "--home", home, "missing"}, &out, &errOut); code != 1 {
+		t.Errorf("removing a missing instance exited %d", code)
+	}
+	if code := run([]string{"instances", "add", "--home", home, "--provider", "openai", "--base-url", "ftp://example.com"}, &out, &errOut); code != 2 {
+		t.Errorf("adding an invalid instance exited %d", code)
+	}
+	if code := run([]string{"labels", "assign", "--home", home, "fast", "missing"}, &out, &errOut); code != 1 {
+		t.Errorf("labelling a missing instance exited %d", code)
+	}
+}
+
+func TestRunValidateFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "aicred.yaml")
+	config := "instances:\n  - id: a\n    provider_type: openai\n    base_url: http://api.example.com\n" +
+		"labels:\n  - label_name: prod\n    target: {type: provider_instance, instance_id: b}\n"
+	if err := os.WriteFile(file, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	if code := run([]string{"validate", "--strict", file}, &out, &errOut); code != 1 {
+		t.Fatalf("validate exited %d: %s%s", code, out.String(), errOut.String())
+	}
+	for _, want := range []string{"unencrypted", `"b"`, "problems found"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("validate output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestFindingsCountsConfigKeys(t *testing.T) {
+	result := &aicred.ScanResult{ConfigInstances: []aicred.ConfigInstance{{AppName: "roo-code", Keys: []aicred.DiscoveredKey{{Provider: "openai"}}}}}
+	if n := findings(result); n != 1 {
+		t.Errorf("findings = %d, want 1", n)
+	}
+	result.Keys = []aicred.DiscoveredKey{{Provider: "groq"}}
+	if n := findings(result); n != 2 {
+		t.Errorf("findings = %d, want 2", n)
+	}
+}
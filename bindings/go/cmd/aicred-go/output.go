@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func writeTable(w io.Writer, result *aicred.ScanResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Scanned %s at %s\n\n", result.HomeDir, result.ScannedAt)
+
+	if len(result.Keys) == 0 {
+		fmt.Fprintln(tw, "No keys found.")
+	} else {
+		fmt.Fprintln(tw, "PROVIDER\tKEY\tCONFIDENCE\tSOURCE")
+		for _, key := range result.Keys {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", key.Provider, key.Redacted, key.Confidence, key.Source)
+		}
+	}
+
+	if len(result.ConfigInstances) > 0 {
+		fmt.Fprintln(tw, "\nAPP\tKEYS\tCONFIG PATH")
+		for _, instance := range result.ConfigInstances {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", instance.AppName, len(instance.Keys), instance.ConfigPath)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, result *aicred.ScanResult, withSecrets bool) error {
+	if withSecrets {
+		body, err := result.MarshalWithSecrets()
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, body, "", "  "); err != nil {
+			return err
+		}
+		out.WriteByte('\n')
+		_, err = out.WriteTo(w)
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// SARIF 2.1.0 types, limited to the fields aicred fills in
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string            `json:"ruleId"`
+	Level     string            `json:"level"`
+	Message   sarifMessage      `json:"message"`
+	Locations []sarifLocation   `json:"locations,omitempty"`
+	Partial   map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func writeSARIF(w io.Writer, result *aicred.ScanResult) error {
+	providers := make(map[string]bool)
+	var results []sarifResult
+	for _, key := range result.Keys {
+		ruleID := "aicred/" + key.Provider
+		providers[key.Provider] = true
+		r := sarifResult{
+			RuleID:  ruleID,
+			Level:   sarifLevel(key.Confidence),
+			Message: sarifMessage{Text: fmt.Sprintf("%s credential %s found", key.Provider, key.Redacted)},
+			Partial: map[string]string{"keyHash/v1": key.Hash},
+		}
+		if key.Source != "" {
+			r.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(key.Source)},
+			}}}
+		}
+		results = append(results, r)
+	}
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rules := make([]sarifRule, 0, len(names))
+	for _, name := range names {
+		rules = append(rules, sarifRule{
+			ID:               "aicred/" + name,
+			ShortDescription: sarifMessage{Text: fmt.Sprintf("Exposed %s credential", name)},
+		})
+	}
+	if results == nil {
+		results = []sarifResult{}
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "aicred",
+				Version:        aicred.Version(),
+				InformationURI: "https://github.com/robottwo/aicred",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// sarifLevel maps key confidence to a SARIF level
+func sarifLevel(confidence string) string {
+	switch confidence {
+	case "Low":
+		return "note"
+	case "Medium":
+		return "warning"
+	default:
+		return "error"
+	}
+}
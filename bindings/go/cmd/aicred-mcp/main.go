@@ -0,0 +1,46 @@
+// Command aicred-mcp runs the aicred MCP server on stdin/stdout.
+//
+// Register it with an MCP client such as Claude Desktop:
+//
+//	{"mcpServers": {"aicred": {"command": "aicred-mcp"}}}
+//
+// --prices names a JSON file of model prices for the estimate_cost tool:
+//
+//	{"gpt-4o-mini": {"input_per_million": 0.15, "output_per_million": 0.6}}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/mcp"
+)
+
+func main() {
+	homeDir := flag.String("home", "", "home directory to scan (default: current user's home)")
+	pricesFile := flag.String("prices", "", "JSON file of model prices in US dollars per million tokens")
+	flag.Parse()
+
+	var prices map[string]mcp.Price
+	if *pricesFile != "" {
+		data, err := os.ReadFile(*pricesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &prices); err != nil {
+			log.Fatalf("%s: %v", *pricesFile, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := mcp.New(mcp.Options{HomeDir: *homeDir, Prices: prices})
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		log.Fatalf("mcp server failed: %v", err)
+	}
+}
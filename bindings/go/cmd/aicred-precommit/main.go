@@ -0,0 +1,56 @@
+// Command aicred-precommit fails a commit that adds a provider API key.
+//
+// It scans only the lines staged for commit and exits with status 1 when it
+// finds a key. Use it from the pre-commit framework:
+//
+//	repos:
+//	  - repo: https://github.com/robottwo/aicred
+//	    rev: v0.2.0
+//	    hooks:
+//	      - id: aicred
+//
+// or directly as .git/hooks/pre-commit:
+//
+//	#!/bin/sh
+//	exec aicred-precommit
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/precommit"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run checks the staged changes and returns the process exit code
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("aicred-precommit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	repo := fs.String("repo", ".", "repository to check")
+	// The pre-commit framework passes the staged file names; the staged
+	// diff already covers them
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	keys, err := precommit.CheckStagedFiles(*repo)
+	if err != nil {
+		fmt.Fprintf(stderr, "aicred-precommit: %v\n", err)
+		return 2
+	}
+	if len(keys) == 0 {
+		return 0
+	}
+	fmt.Fprintf(stderr, "aicred: %d API key(s) staged for commit:\n", len(keys))
+	for _, key := range keys {
+		fmt.Fprintf(stderr, "  %s:%d:%d  %s %s\n", key.Source, key.Location.Line, key.Location.Column, key.Provider, key.Redacted)
+	}
+	fmt.Fprintln(stderr, "Remove them, or move them to a secret store, and commit again.")
+	return 1
+}
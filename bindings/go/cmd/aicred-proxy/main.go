@@ -0,0 +1,50 @@
+// Command aicred-proxy runs the aicred credential-injecting proxy, so local
+// applications can call providers without holding their keys.
+//
+// Set the token clients present as their API key in $AICRED_PROXY_TOKEN,
+// then point an OpenAI SDK at the proxy and name a label as the model:
+//
+//	AICRED_PROXY_TOKEN=local-secret aicred-proxy --listen 127.0.0.1:8787
+//	OPENAI_BASE_URL=http://127.0.0.1:8787/v1 OPENAI_API_KEY=local-secret app
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/proxy"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8787", "address to listen on")
+	homeDir := flag.String("home", "", "home directory whose aicred configuration is used (default: current user's home)")
+	flag.Parse()
+
+	token := os.Getenv("AICRED_PROXY_TOKEN")
+	if token == "" {
+		log.Fatal("set AICRED_PROXY_TOKEN to the token clients must present")
+	}
+	p, err := proxy.New(proxy.Options{Token: token, HomeDir: *homeDir})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := &http.Server{Addr: *listen, Handler: p}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	log.Printf("aicred proxy listening on %s", *listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("proxy failed: %v", err)
+	}
+}
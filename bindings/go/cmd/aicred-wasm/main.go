@@ -0,0 +1,62 @@
+//go:build js && wasm
+
+// Command aicred-wasm exposes the pure-Go key detector to JavaScript, so a
+// browser extension or edge worker can check pasted content for provider
+// keys without sending it anywhere.
+//
+// Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o aicred.wasm ./cmd/aicred-wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+// Once running it defines a global aicred object:
+//
+//	aicred.scan(text)   // [{provider, confidence, line, column, hash, redacted}]
+//	aicred.providers()  // ["anthropic", "groq", ...]
+//
+// Full key values never cross into JavaScript.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/robottwo/aicred/bindings/go/aicred/detect"
+)
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("scan", js.FuncOf(scan))
+	api.Set("providers", js.FuncOf(providers))
+	js.Global().Set("aicred", api)
+
+	// Keep the Go runtime alive to serve calls
+	select {}
+}
+
+func scan(_ js.Value, args []js.Value) any {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return js.Global().Get("Error").New("aicred.scan expects a string")
+	}
+	matches := detect.Find([]byte(args[0].String()))
+	out := make([]any, len(matches))
+	for i, m := range matches {
+		out[i] = map[string]any{
+			"provider":   m.Provider,
+			"confidence": m.Confidence,
+			"line":       m.Line,
+			"column":     m.Column,
+			"hash":       m.Hash,
+			"redacted":   m.Redacted,
+		}
+	}
+	return out
+}
+
+func providers(js.Value, []js.Value) any {
+	names := detect.Providers()
+	out := make([]any, len(names))
+	for i, name := range names {
+		out[i] = name
+	}
+	return out
+}
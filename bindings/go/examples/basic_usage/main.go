@@ -56,7 +56,7 @@ func main() {
 		}
 	}
 
-	// Save to JSON
+	// Save to JSON; key values are never included, even with IncludeFullValues
 	jsonData, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		log.Fatalf("Failed to marshal JSON: %v", err)
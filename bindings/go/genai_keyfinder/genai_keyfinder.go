@@ -0,0 +1,77 @@
+// Package genai_keyfinder is the former name of the aicred Go bindings.
+//
+// Every identifier here is an alias for, or forwards to, the identically
+// named one in package aicred, so values pass between the two packages
+// without conversion.
+//
+// Deprecated: import github.com/robottwo/aicred/bindings/go/aicred instead.
+package genai_keyfinder
+
+import "github.com/robottwo/aicred/bindings/go/aicred"
+
+// Deprecated: use aicred.ScanOptions.
+type ScanOptions = aicred.ScanOptions
+
+// Deprecated: use aicred.ScanResult.
+type ScanResult = aicred.ScanResult
+
+// Deprecated: use aicred.DiscoveredKey.
+type DiscoveredKey = aicred.DiscoveredKey
+
+// Deprecated: use aicred.ConfigInstance.
+type ConfigInstance = aicred.ConfigInstance
+
+// Deprecated: use aicred.Secret.
+type Secret = aicred.Secret
+
+// Deprecated: use aicred.RedactionLevel.
+type RedactionLevel = aicred.RedactionLevel
+
+// Deprecated: use aicred.Error.
+type Error = aicred.Error
+
+// Deprecated: use the aicred redaction levels.
+const (
+	RedactionPartial  = aicred.RedactionPartial
+	RedactionNone     = aicred.RedactionNone
+	RedactionHashOnly = aicred.RedactionHashOnly
+	RedactionFull     = aicred.RedactionFull
+)
+
+// Deprecated: use the aicred sentinel errors.
+var (
+	ErrNotFound         = aicred.ErrNotFound
+	ErrParse            = aicred.ErrParse
+	ErrIO               = aicred.ErrIO
+	ErrPermissionDenied = aicred.ErrPermissionDenied
+	ErrTimeout          = aicred.ErrTimeout
+	ErrBadSignature     = aicred.ErrBadSignature
+)
+
+// Scan forwards to aicred.Scan.
+//
+// Deprecated: use aicred.Scan.
+func Scan(options ScanOptions) (*ScanResult, error) {
+	return aicred.Scan(options)
+}
+
+// Version forwards to aicred.Version.
+//
+// Deprecated: use aicred.Version.
+func Version() string {
+	return aicred.Version()
+}
+
+// ListProviders forwards to aicred.ListProviders.
+//
+// Deprecated: use aicred.ListProviders.
+func ListProviders() []string {
+	return aicred.ListProviders()
+}
+
+// ListScanners forwards to aicred.ListScanners.
+//
+// Deprecated: use aicred.ListScanners.
+func ListScanners() []string {
+	return aicred.ListScanners()
+}
@@ -0,0 +1,28 @@
+package genai_keyfinder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/robottwo/aicred/bindings/go/aicred"
+)
+
+func TestForwardsToAicred(t *testing.T) {
+	if Version() != aicred.Version() {
+		t.Errorf("Version() = %q, want %q", Version(), aicred.Version())
+	}
+
+	// Aliased types are interchangeable with the aicred ones
+	var result *aicred.ScanResult
+	result, err := Scan(ScanOptions{HomeDir: t.TempDir(), Redaction: RedactionFull})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil {
+		t.Fatal("nil result")
+	}
+
+	if _, err := Scan(ScanOptions{HomeDir: "/nonexistent/aicred"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
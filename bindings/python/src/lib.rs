@@ -1300,6 +1300,7 @@ fn scan_py(
         exclude_providers,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let result = core_scan(&options)
@@ -1338,7 +1339,22 @@ fn list_providers() -> Vec<&'static str> {
 /// List available application scanners
 #[pyfunction]
 fn list_scanners() -> Vec<&'static str> {
-    vec!["roo-code", "claude-desktop", "ragit", "langchain", "gsh"]
+    vec![
+        "roo-code",
+        "claude-desktop",
+        "ragit",
+        "langchain",
+        "gsh",
+        "raycast",
+        "alfred",
+        "obsidian",
+        "sillytavern",
+        "lm-studio",
+        "gpt4all",
+        "ollama",
+        "llama-cpp",
+        "vllm",
+    ]
 }
 
 /// AICred - Python bindings
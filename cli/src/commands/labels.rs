@@ -1,8 +1,11 @@
 //! Label management commands for the aicred CLI.
 
+use crate::utils::paths::config_dir;
 use crate::utils::provider_loader::load_provider_instances;
 use aicred_core::env_resolver::LabelWithTarget;
-use aicred_core::models::{Label, LabelAssignment, LabelTarget, ProviderCollection};
+use aicred_core::models::{
+    Label, LabelAssignment, LabelHierarchy, LabelTarget, ProviderCollection, UniquenessScope,
+};
 use aicred_core::utils::ProviderModelTuple;
 use anyhow::Result;
 use colored::*;
@@ -55,20 +58,7 @@ pub fn load_labels_with_targets(home: Option<&Path>) -> Result<Vec<LabelWithTarg
 
 /// Load all label assignments from the configuration directory
 pub fn load_label_assignments_with_home(home: Option<&Path>) -> Result<Vec<LabelAssignment>> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     let labels_file = config_dir.join("labels.yaml");
 
@@ -91,20 +81,7 @@ pub fn save_label_assignments_with_home(
     labels: &[LabelAssignment],
     home: Option<&Path>,
 ) -> Result<()> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     std::fs::create_dir_all(&config_dir)?;
 
@@ -156,20 +133,7 @@ pub fn save_label_assignments(labels: &[LabelAssignment]) -> Result<()> {
 
 /// Load labels (metadata) from a separate file
 fn load_labels_with_home(home: Option<&Path>) -> Result<std::collections::HashMap<String, Label>> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     let labels_metadata_file = config_dir.join("labels_metadata.yaml");
 
@@ -187,20 +151,7 @@ fn save_labels_with_home(
     labels: &std::collections::HashMap<String, Label>,
     home: Option<&Path>,
 ) -> Result<()> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     std::fs::create_dir_all(&config_dir)?;
 
@@ -242,6 +193,12 @@ pub fn handle_list_labels() -> Result<()> {
             if let Some(ref description) = label.description {
                 println!("    Description: {}", description);
             }
+            if let Some(ref parent) = label.parent {
+                println!("    Parent: {}", parent);
+            }
+            if let Some(color) = label.color() {
+                println!("    Color: {}", color);
+            }
         }
 
         println!(
@@ -274,21 +231,7 @@ fn assignment_target_to_string(target: &LabelTarget) -> String {
 /// First checks user config directory, then falls back to distributed application files
 fn find_labels_directory(home: Option<&Path>) -> Result<std::path::PathBuf> {
     // First try user config directory: ~/.config/aicred/patterns/
-    let user_config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred")
-    .join("patterns");
+    let user_config_dir = config_dir(home)?.join("patterns");
 
     if user_config_dir.exists() {
         return Ok(user_config_dir);
@@ -620,6 +563,8 @@ pub fn handle_label_scan(dry_run: bool, verbose: bool, home: Option<&Path>) -> R
                         description: None,
                         created_at: chrono::Utc::now(),
                         metadata: std::collections::HashMap::new(),
+                        parent: None,
+                        uniqueness: UniquenessScope::Global,
                     },
                 );
             }
@@ -737,21 +682,7 @@ pub fn handle_label_scan(dry_run: bool, verbose: bool, home: Option<&Path>) -> R
 
 /// Save provider instances to configuration directory
 fn save_provider_instances(instances: &ProviderCollection, home: Option<&Path>) -> Result<()> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred")
-    .join("inference_services");
+    let config_dir = config_dir(home)?.join("inference_services");
 
     std::fs::create_dir_all(&config_dir)?;
 
@@ -777,19 +708,67 @@ fn save_provider_instances(instances: &ProviderCollection, home: Option<&Path>)
 pub fn handle_set_label(
     label_name: String,
     tuple_str: String,
-    _color: Option<String>, // Color not supported in new Label
+    color: Option<String>,
     description: Option<String>,
     home: Option<&Path>,
+) -> Result<()> {
+    handle_set_label_with(
+        label_name,
+        tuple_str,
+        color,
+        description,
+        LabelSetOptions::default(),
+        home,
+    )
+}
+
+/// Optional settings for `labels set`
+#[derive(Debug, Clone, Default)]
+pub struct LabelSetOptions {
+    /// Parent label, so assignments resolve through the hierarchy
+    pub parent: Option<String>,
+    /// Uniqueness scope; an existing label keeps its scope when `None`
+    pub scope: Option<UniquenessScope>,
+}
+
+/// Handle the labels set command with a parent label and uniqueness scope.
+/// A globally unique label moves to the new target; other scopes add the
+/// assignment if it does not conflict with one the label already has.
+pub fn handle_set_label_with(
+    label_name: String,
+    tuple_str: String,
+    color: Option<String>,
+    description: Option<String>,
+    options: LabelSetOptions,
+    home: Option<&Path>,
 ) -> Result<()> {
     // Trim and validate label name
     let label_name = label_name.trim().to_string();
     if label_name.is_empty() {
         return Err(anyhow::anyhow!("Label name cannot be empty"));
     }
+    let color = crate::commands::tags::parse_color(color.as_deref())?;
+    let parent = options
+        .parent
+        .map(|p| p.trim().to_string())
+        .filter(|p| !p.is_empty());
 
     let mut assignments = load_label_assignments_with_home(home)?;
     let mut labels_metadata = load_labels_with_home(home)?;
 
+    if let Some(parent) = &parent {
+        let hierarchy = LabelHierarchy::new(labels_metadata.values());
+        if hierarchy.would_cycle(&label_name, parent) {
+            return Err(anyhow::anyhow!(
+                "Label '{}' cannot be placed under '{}': '{}' is already within '{}'",
+                label_name,
+                parent,
+                parent,
+                label_name
+            ));
+        }
+    }
+
     // Parse the provider:model tuple
     let tuple = ProviderModelTuple::parse(&tuple_str)
         .map_err(|e| anyhow::anyhow!("Invalid provider:model tuple '{}': {}", tuple_str, e))?;
@@ -855,10 +834,46 @@ pub fn handle_set_label(
         }
     };
 
-    // Check if this label already exists and update it, or create new one
-    let existing_assignment_index = assignments
-        .iter()
-        .position(|assignment| assignment.label_name == label_name);
+    let scope = options.scope.unwrap_or_else(|| {
+        labels_metadata
+            .get(&label_name)
+            .map(|label| label.uniqueness)
+            .unwrap_or_default()
+    });
+
+    // A globally unique label replaces its assignment; narrower scopes keep
+    // the others as long as the new target does not conflict with them
+    let existing_assignment_index = if scope == UniquenessScope::Global {
+        let first = assignments
+            .iter()
+            .position(|assignment| assignment.label_name == label_name);
+        if let Some(first) = first {
+            // Drop any others left from a narrower scope; they all follow
+            // the first, so its index stays valid
+            let mut index = 0;
+            assignments.retain(|a| {
+                let keep = a.label_name != label_name || index == first;
+                index += 1;
+                keep
+            });
+        }
+        first
+    } else {
+        let label = Label {
+            name: label_name.clone(),
+            description: None,
+            created_at: chrono::Utc::now(),
+            metadata: std::collections::HashMap::new(),
+            parent: None,
+            uniqueness: scope,
+        };
+        label
+            .check_assignment(&target, &assignments)
+            .map_err(|e| anyhow::anyhow!("{}", e))?;
+        assignments
+            .iter()
+            .position(|a| a.label_name == label_name && a.target == target)
+    };
 
     if let Some(index) = existing_assignment_index {
         // Update existing assignment
@@ -886,7 +901,12 @@ pub fn handle_set_label(
     }
 
     // Update label metadata
-    if description.is_some() || !labels_metadata.contains_key(&label_name) {
+    if description.is_some()
+        || color.is_some()
+        || parent.is_some()
+        || options.scope.is_some()
+        || !labels_metadata.contains_key(&label_name)
+    {
         let label = labels_metadata
             .entry(label_name.clone())
             .or_insert_with(|| Label {
@@ -894,9 +914,20 @@ pub fn handle_set_label(
                 description: None,
                 created_at: chrono::Utc::now(),
                 metadata: std::collections::HashMap::new(),
+                parent: None,
+                uniqueness: UniquenessScope::Global,
             });
 
-        label.description = description;
+        if description.is_some() {
+            label.description = description;
+        }
+        if let Some(color) = color {
+            label.set_color(color);
+        }
+        if parent.is_some() {
+            label.parent = parent;
+        }
+        label.uniqueness = scope;
     }
 
     // Save to disk
@@ -1042,6 +1073,8 @@ mod tests {
             description: None,
             created_at: chrono::Utc::now(),
             metadata: std::collections::HashMap::new(),
+            parent: None,
+            uniqueness: UniquenessScope::Global,
         };
 
         // Save them
@@ -1072,6 +1105,8 @@ mod tests {
             description: None,
             created_at: chrono::Utc::now(),
             metadata: std::collections::HashMap::new(),
+            parent: None,
+            uniqueness: UniquenessScope::Global,
         };
 
         save_label_assignments_with_home(&[assignment], Some(temp_dir.path())).unwrap();
@@ -1101,6 +1136,8 @@ mod tests {
             description: None,
             created_at: chrono::Utc::now(),
             metadata: std::collections::HashMap::new(),
+            parent: None,
+            uniqueness: UniquenessScope::Global,
         };
 
         save_label_assignments_with_home(&[assignment], Some(temp_dir.path())).unwrap();
@@ -1130,6 +1167,8 @@ mod tests {
             description: None,
             created_at: chrono::Utc::now(),
             metadata: std::collections::HashMap::new(),
+            parent: None,
+            uniqueness: UniquenessScope::Global,
         };
 
         save_label_assignments_with_home(&[assignment], Some(temp_dir.path())).unwrap();
@@ -1,3 +1,4 @@
+use crate::utils::paths::config_dir;
 use crate::utils::provider_loader::load_provider_instances;
 use aicred_core::models::{ProviderCollection, ProviderInstance};
 use anyhow::Result;
@@ -15,11 +16,7 @@ fn truncate_string(s: &str, max_len: usize) -> String {
 
 /// Save provider instances to configuration directory
 fn save_provider_instances(instances: &ProviderCollection) -> Result<()> {
-    let config_dir = dirs_next::home_dir()
-        .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-        .join(".config")
-        .join("aicred")
-        .join("inference_services");
+    let config_dir = config_dir(None)?.join("inference_services");
 
     std::fs::create_dir_all(&config_dir)?;
 
@@ -227,6 +224,8 @@ pub fn handle_add_instance(
     let instances_copy = instances.clone();
     save_provider_instances(&instances_copy)?;
 
+    let tagged = crate::commands::tags::apply_tag_rules(&[&instance], None)?;
+
     println!(
         "{} Provider instance '{}' added successfully.",
         "✓".green(),
@@ -242,6 +241,10 @@ pub fn handle_add_instance(
             "Inactive"
         }
     );
+    if !tagged.is_empty() {
+        let names: Vec<&str> = tagged.iter().map(|a| a.label_name.as_str()).collect();
+        println!("  Tags: {}", names.join(", "));
+    }
 
     Ok(())
 }
@@ -539,6 +542,15 @@ pub fn handle_providers(verbose: bool) -> Result<()> {
         ("ragit", "Ragit configurations"),
         ("langchain", "LangChain application configs"),
         ("gsh", "GSH configurations"),
+        ("raycast", "Raycast AI providers and extension settings"),
+        ("alfred", "Alfred workflow variables and preferences"),
+        ("obsidian", "Obsidian community plugin settings"),
+        ("sillytavern", "SillyTavern secrets"),
+        ("lm-studio", "LM Studio settings and MCP servers"),
+        ("gpt4all", "GPT4All remote models and settings"),
+        ("ollama", "Ollama pulled models and OLLAMA_HOST endpoint"),
+        ("llama-cpp", "llama.cpp cached models and llama-swap config"),
+        ("vllm", "vLLM serve configs"),
     ];
 
     for (name, desc) in scanners {
@@ -91,6 +91,7 @@ pub fn handle_scan(
         exclude_providers,
         probe_models,
         probe_timeout_secs: probe_timeout.unwrap_or(30),
+        concurrency: 0,
     };
 
     if dry_run {
@@ -101,7 +102,8 @@ pub fn handle_scan(
     }
 
     // Perform scan
-    println!("{}", "Scanning for GenAI credentials...".cyan().bold());
+    // Progress goes to stderr so --format json output stays parseable
+    eprintln!("{}", "Scanning for GenAI credentials...".cyan().bold());
     let result = scan(&options)?;
 
     // Output results based on format
@@ -209,10 +211,7 @@ fn hash_value(value: &str) -> String {
 /// Updates or creates the YAML configuration files with discovered providers and keys
 /// NEW APPROACH: One instance per API key, using API key as instance ID
 fn update_yaml_config(result: &aicred_core::ScanResult, home_dir: &std::path::Path) -> Result<()> {
-    let config_dir = home_dir
-        .join(".config")
-        .join("aicred")
-        .join("inference_services");
+    let config_dir = aicred_core::utils::paths::config_dir(home_dir).join("inference_services");
 
     // Create directory if it doesn't exist
     std::fs::create_dir_all(&config_dir)?;
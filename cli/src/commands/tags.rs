@@ -1,26 +1,18 @@
 //! Label management commands for the aicred CLI.
 
-use aicred_core::models::{Label, LabelAssignment, LabelTarget};
+use crate::utils::paths::config_dir;
+use aicred_core::models::{
+    evaluate_tag_rules, Label, LabelAssignment, LabelTarget, ProviderInstance, TagRule,
+    UniquenessScope,
+};
+use aicred_core::utils::{next_color, Color};
 use anyhow::Result;
 use colored::*;
 use std::path::Path;
 
 /// Load all labels from the configuration directory
 pub fn load_tags(home: Option<&Path>) -> Result<Vec<Label>> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     let tags_file = config_dir.join("tags.yaml");
 
@@ -35,20 +27,7 @@ pub fn load_tags(home: Option<&Path>) -> Result<Vec<Label>> {
 
 /// Save tags to the configuration directory
 pub fn save_tags(tags: &[Label], home: Option<&Path>) -> Result<()> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     std::fs::create_dir_all(&config_dir)?;
 
@@ -61,20 +40,7 @@ pub fn save_tags(tags: &[Label], home: Option<&Path>) -> Result<()> {
 
 /// Load all label assignments from the configuration directory
 pub fn load_tag_assignments(home: Option<&Path>) -> Result<Vec<LabelAssignment>> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     let assignments_file = config_dir.join("tag_assignments.yaml");
 
@@ -89,20 +55,7 @@ pub fn load_tag_assignments(home: Option<&Path>) -> Result<Vec<LabelAssignment>>
 
 /// Save tag assignments to the configuration directory
 pub fn save_tag_assignments(assignments: &[LabelAssignment], home: Option<&Path>) -> Result<()> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => {
-            // Check HOME environment variable first (for test compatibility)
-            if let Ok(home_env) = std::env::var("HOME") {
-                std::path::PathBuf::from(home_env)
-            } else {
-                dirs_next::home_dir()
-                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
-            }
-        }
-    }
-    .join(".config")
-    .join("aicred");
+    let config_dir = config_dir(home)?;
 
     std::fs::create_dir_all(&config_dir)?;
 
@@ -113,6 +66,41 @@ pub fn save_tag_assignments(assignments: &[LabelAssignment], home: Option<&Path>
     Ok(())
 }
 
+/// Load tag rules from the configuration directory
+pub fn load_tag_rules(home: Option<&Path>) -> Result<Vec<TagRule>> {
+    let config_dir = config_dir(home)?;
+
+    let rules_file = config_dir.join("tag_rules.yaml");
+
+    if !rules_file.exists() {
+        return Ok(Vec::new());
+    }
+
+    let content = std::fs::read_to_string(&rules_file)?;
+    let rules: Vec<TagRule> = serde_yaml::from_str(&content)?;
+    Ok(rules)
+}
+
+/// Save tag rules to the configuration directory
+pub fn save_tag_rules(rules: &[TagRule], home: Option<&Path>) -> Result<()> {
+    let config_dir = config_dir(home)?;
+
+    std::fs::create_dir_all(&config_dir)?;
+
+    let rules_file = config_dir.join("tag_rules.yaml");
+    let content = serde_yaml::to_string(rules)?;
+    std::fs::write(&rules_file, content)?;
+
+    Ok(())
+}
+
+/// Parse and validate a --color argument
+pub fn parse_color(color: Option<&str>) -> Result<Option<Color>> {
+    color
+        .map(|c| c.parse::<Color>().map_err(|e| anyhow::anyhow!("{}", e)))
+        .transpose()
+}
+
 /// Handle the tags list command
 pub fn handle_list_tags(home: Option<&Path>) -> Result<()> {
     let tags = load_tags(home)?;
@@ -131,6 +119,9 @@ pub fn handle_list_tags(home: Option<&Path>) -> Result<()> {
         if let Some(ref description) = tag.description {
             println!("    Description: {}", description);
         }
+        if let Some(color) = tag.color() {
+            println!("    Color: {}", color);
+        }
 
         println!(
             "    Created: {}",
@@ -147,7 +138,7 @@ pub fn handle_list_tags(home: Option<&Path>) -> Result<()> {
 /// Handle the tags add command
 pub fn handle_add_tag(
     name: String,
-    _color: Option<String>, // Color not supported in Label
+    color: Option<String>,
     description: Option<String>,
     home: Option<&Path>,
 ) -> Result<()> {
@@ -156,6 +147,7 @@ pub fn handle_add_tag(
     if trimmed_name.is_empty() {
         return Err(anyhow::anyhow!("Tag name cannot be empty"));
     }
+    let color = parse_color(color.as_deref())?;
 
     let mut tags = load_tags(home)?;
 
@@ -168,12 +160,20 @@ pub fn handle_add_tag(
     }
 
     let now = chrono::Utc::now();
-    let tag = Label {
+    let mut tag = Label {
         name: trimmed_name.to_string(),
         description,
         created_at: now,
         metadata: std::collections::HashMap::new(),
+        parent: None,
+        uniqueness: UniquenessScope::Global,
     };
+    // Give the tag a color of its own unless one was chosen
+    let color = color.unwrap_or_else(|| {
+        let used: Vec<Color> = tags.iter().filter_map(Label::color).collect();
+        next_color(&used)
+    });
+    tag.set_color(color);
 
     tags.push(tag);
 
@@ -252,10 +252,11 @@ pub fn handle_remove_tag(name: String, force: bool, home: Option<&Path>) -> Resu
 /// Handle the tags update command
 pub fn handle_update_tag(
     name: String,
-    _color: Option<String>, // Color not supported in Label
+    color: Option<String>,
     description: Option<String>,
     home: Option<&Path>,
 ) -> Result<()> {
+    let color = parse_color(color.as_deref())?;
     let mut tags = load_tags(home)?;
 
     // Find the tag
@@ -270,6 +271,9 @@ pub fn handle_update_tag(
     if description.is_some() {
         tag.description = description;
     }
+    if let Some(color) = color {
+        tag.set_color(color);
+    }
 
     // Save to disk
     save_tags(&tags, home)?;
@@ -505,3 +509,138 @@ pub fn get_tags_for_target(
 
     Ok(result)
 }
+
+/// Handle the tags rules command (list tag rules)
+pub fn handle_list_tag_rules(home: Option<&Path>) -> Result<()> {
+    let rules = load_tag_rules(home)?;
+
+    if rules.is_empty() {
+        println!("{}", "No tag rules configured.".yellow());
+        println!(
+            "{}",
+            "Use 'aicred tags add-rule \"provider_type=openai -> openai\"' to create one.".dimmed()
+        );
+        return Ok(());
+    }
+
+    println!("\n{}", "Tag Rules:".green().bold());
+    for (index, rule) in rules.iter().enumerate() {
+        println!("  {}. {}", index + 1, rule.to_string().cyan());
+    }
+
+    Ok(())
+}
+
+/// Handle the tags add-rule command
+pub fn handle_add_tag_rule(rule: String, home: Option<&Path>) -> Result<()> {
+    let rule: TagRule = rule.parse().map_err(|e| anyhow::anyhow!("{}", e))?;
+    let mut rules = load_tag_rules(home)?;
+
+    if rules.contains(&rule) {
+        return Err(anyhow::anyhow!("Tag rule '{}' already exists", rule));
+    }
+
+    println!(
+        "{} Tag rule '{}' added.",
+        "✓".green(),
+        rule.to_string().cyan()
+    );
+    rules.push(rule);
+    save_tag_rules(&rules, home)?;
+
+    Ok(())
+}
+
+/// Handle the tags remove-rule command; `number` is as shown by `tags rules`
+pub fn handle_remove_tag_rule(number: usize, home: Option<&Path>) -> Result<()> {
+    let mut rules = load_tag_rules(home)?;
+
+    if number == 0 || number > rules.len() {
+        return Err(anyhow::anyhow!(
+            "No tag rule numbered {} ({} configured)",
+            number,
+            rules.len()
+        ));
+    }
+
+    let rule = rules.remove(number - 1);
+    save_tag_rules(&rules, home)?;
+
+    println!(
+        "{} Tag rule '{}' removed.",
+        "✓".green(),
+        rule.to_string().cyan()
+    );
+
+    Ok(())
+}
+
+/// Apply the tag rules to the given instances, creating any tag a rule names
+/// that does not exist yet. Returns the assignments added.
+pub fn apply_tag_rules(
+    instances: &[&ProviderInstance],
+    home: Option<&Path>,
+) -> Result<Vec<LabelAssignment>> {
+    let rules = load_tag_rules(home)?;
+    if rules.is_empty() {
+        return Ok(Vec::new());
+    }
+
+    let mut tags = load_tags(home)?;
+    let mut assignments = load_tag_assignments(home)?;
+    let mut added = Vec::new();
+    for instance in instances {
+        let new_assignments = evaluate_tag_rules(&rules, instance, &assignments);
+        assignments.extend(new_assignments.iter().cloned());
+        added.extend(new_assignments);
+    }
+    if added.is_empty() {
+        return Ok(added);
+    }
+
+    for assignment in &added {
+        if !tags.iter().any(|tag| tag.name == assignment.label_name) {
+            let used: Vec<Color> = tags.iter().filter_map(Label::color).collect();
+            let mut tag = Label {
+                name: assignment.label_name.clone(),
+                description: Some("Created by a tag rule".to_string()),
+                created_at: chrono::Utc::now(),
+                metadata: std::collections::HashMap::new(),
+                parent: None,
+                uniqueness: UniquenessScope::Global,
+            };
+            tag.set_color(next_color(&used));
+            tags.push(tag);
+        }
+    }
+    save_tags(&tags, home)?;
+    save_tag_assignments(&assignments, home)?;
+
+    Ok(added)
+}
+
+/// Handle the tags apply command (evaluate rules against every instance)
+pub fn handle_apply_tag_rules(home: Option<&Path>) -> Result<()> {
+    let instances = crate::utils::provider_loader::load_provider_instances(home)?;
+    let added = apply_tag_rules(&instances.all_instances(), home)?;
+
+    if added.is_empty() {
+        println!("{}", "No new tag assignments.".yellow());
+        return Ok(());
+    }
+
+    for assignment in &added {
+        println!(
+            "{} Tagged '{}' with '{}'",
+            "✓".green(),
+            assignment.target.instance_id(),
+            assignment.label_name.cyan()
+        );
+    }
+    println!(
+        "{}",
+        format!("Added {} tag assignment(s).", added.len()).cyan()
+    );
+
+    Ok(())
+}
@@ -13,6 +13,7 @@
 #![allow(unused_imports)]
 #![allow(unused_comparisons)]
 
+use aicred_core::models::UniquenessScope;
 use anyhow::{anyhow, Result};
 use clap::{Parser, Subcommand};
 use colored::*;
@@ -24,7 +25,10 @@ mod output;
 mod utils;
 
 use commands::{
-    labels::{handle_label_scan, handle_list_labels, handle_set_label, handle_unset_label},
+    labels::{
+        handle_label_scan, handle_list_labels, handle_set_label_with, handle_unset_label,
+        LabelSetOptions,
+    },
     providers::{
         handle_add_instance, handle_get_instance, handle_list_instances, handle_list_models,
         handle_providers, handle_remove_instance, handle_update_instance,
@@ -32,7 +36,8 @@ use commands::{
     },
     scan::handle_scan,
     tags::{
-        handle_add_tag, handle_assign_tag, handle_list_tags, handle_remove_tag,
+        handle_add_tag, handle_add_tag_rule, handle_apply_tag_rules, handle_assign_tag,
+        handle_list_tag_rules, handle_list_tags, handle_remove_tag, handle_remove_tag_rule,
         handle_unassign_tag, handle_update_tag,
     },
     wrap::handle_wrap,
@@ -148,7 +153,7 @@ enum Commands {
 
     /// Wrap a command with LLM environment variables
     Wrap {
-        /// Scanner names to use (e.g., gsh, roo-code, claude-desktop)
+        /// Scanner names to use (e.g., gsh, roo-code, claude-desktop, obsidian)
         #[arg(long, short = 's')]
         scanner_names: Option<Vec<String>>,
 
@@ -361,6 +366,27 @@ enum TagCommands {
         #[arg(short = 'm', long)]
         model: Option<String>,
     },
+
+    /// List the rules that tag instances automatically
+    Rules,
+
+    /// Add a tag rule, e.g. "provider_type=openai -> openai" or
+    /// "base_url contains azure -> azure"
+    AddRule {
+        /// Rule in the form 'field=value -> tag' or 'field contains value -> tag'
+        #[arg(index = 1, required = true)]
+        rule: String,
+    },
+
+    /// Remove a tag rule by its number in 'tags rules'
+    RemoveRule {
+        /// Rule number
+        #[arg(index = 1, required = true)]
+        number: usize,
+    },
+
+    /// Apply the tag rules to every existing instance
+    Apply,
 }
 
 #[derive(Subcommand)]
@@ -381,6 +407,15 @@ enum LabelCommands {
         /// Label description
         #[arg(short = 'd', long)]
         description: Option<String>,
+
+        /// Parent label, so routing to the parent also reaches this label
+        #[arg(short = 'p', long)]
+        parent: Option<String>,
+
+        /// Uniqueness scope: global (one target), per-target-type (one
+        /// instance and one model) or per-instance (one target per instance)
+        #[arg(short = 's', long)]
+        scope: Option<String>,
     },
 
     /// Unset (remove) a label assignment
@@ -582,6 +617,18 @@ fn main() -> Result<()> {
                 model,
                 cli.home.map(PathBuf::from).as_deref(),
             ),
+            Some(TagCommands::Rules) => {
+                handle_list_tag_rules(cli.home.map(PathBuf::from).as_deref())
+            }
+            Some(TagCommands::AddRule { rule }) => {
+                handle_add_tag_rule(rule, cli.home.map(PathBuf::from).as_deref())
+            }
+            Some(TagCommands::RemoveRule { number }) => {
+                handle_remove_tag_rule(number, cli.home.map(PathBuf::from).as_deref())
+            }
+            Some(TagCommands::Apply) => {
+                handle_apply_tag_rules(cli.home.map(PathBuf::from).as_deref())
+            }
             None => handle_list_tags(cli.home.map(PathBuf::from).as_deref()),
         },
         Commands::Labels { command } => match command {
@@ -590,6 +637,8 @@ fn main() -> Result<()> {
                 assignment,
                 color,
                 description,
+                parent,
+                scope,
             }) => {
                 // Parse assignment format: label=provider:model
                 let parts: Vec<&str> = assignment.split('=').collect();
@@ -600,11 +649,16 @@ fn main() -> Result<()> {
                 }
                 let label_name = parts[0].trim().to_string();
                 let tuple_str = parts[1].trim().to_string();
-                handle_set_label(
+                let scope = scope
+                    .map(|s| s.parse::<UniquenessScope>())
+                    .transpose()
+                    .map_err(|e| anyhow::anyhow!("{}", e))?;
+                handle_set_label_with(
                     label_name,
                     tuple_str,
                     color,
                     description,
+                    LabelSetOptions { parent, scope },
                     cli.home.map(PathBuf::from).as_deref(),
                 )
             }
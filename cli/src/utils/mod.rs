@@ -1,3 +1,4 @@
 //! Utility modules for the aicred CLI.
 
+pub mod paths;
 pub mod provider_loader;
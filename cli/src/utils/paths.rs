@@ -0,0 +1,23 @@
+//! Location of the aicred configuration for CLI commands.
+
+use anyhow::Result;
+use std::path::{Path, PathBuf};
+
+/// Returns the configuration directory for `home`, or for the current user
+/// when no home is given. `AICRED_CONFIG_DIR` and `XDG_CONFIG_HOME` apply
+/// as they do for the FFI and the Go bindings.
+pub fn config_dir(home: Option<&Path>) -> Result<PathBuf> {
+    let home = match home {
+        Some(h) => h.to_path_buf(),
+        None => {
+            // Check HOME environment variable first (for test compatibility)
+            if let Ok(home_env) = std::env::var("HOME") {
+                PathBuf::from(home_env)
+            } else {
+                dirs_next::home_dir()
+                    .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?
+            }
+        }
+    };
+    Ok(aicred_core::utils::paths::config_dir(&home))
+}
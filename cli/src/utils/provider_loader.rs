@@ -1,5 +1,6 @@
 //! Provider instance loading utilities.
 
+use crate::utils::paths::config_dir;
 use aicred_core::models::{ProviderCollection, ProviderInstance};
 use anyhow::Result;
 use colored::Colorize;
@@ -7,15 +8,7 @@ use std::path::Path;
 
 /// Load provider instances from configuration directory
 pub fn load_provider_instances(home: Option<&Path>) -> Result<ProviderCollection> {
-    let config_dir = match home {
-        Some(h) => h.to_path_buf(),
-        None => dirs_next::home_dir()
-            .ok_or_else(|| anyhow::anyhow!("Could not determine home directory"))?,
-    }
-    .join(".config")
-    .join("aicred");
-
-    let instances_dir = config_dir.join("inference_services");
+    let instances_dir = config_dir(home)?.join("inference_services");
 
     // Create instances directory if it doesn't exist
     if !instances_dir.exists() {
@@ -11,10 +11,14 @@ use std::fs;
 use tempfile::TempDir;
 
 /// Helper function to set both HOME and USERPROFILE environment variables
-/// for cross-platform test compatibility (Unix uses HOME, Windows uses USERPROFILE)
+/// for cross-platform test compatibility (Unix uses HOME, Windows uses USERPROFILE).
+/// The configuration directory overrides are cleared so it is always
+/// `<home>/.config/aicred`.
 fn set_test_home_envs(cmd: &mut Command, home: &std::path::Path) {
     cmd.env("HOME", home);
     cmd.env("USERPROFILE", home);
+    cmd.env_remove("AICRED_CONFIG_DIR");
+    cmd.env_remove("XDG_CONFIG_HOME");
 }
 
 /// Helper function to get home path as string for CLI arguments
@@ -1813,3 +1817,30 @@ fn test_labels_help_commands() {
         "Unset (remove) a label assignment",
     ));
 }
+
+#[test]
+fn test_config_dir_overrides() {
+    let temp_home = TempDir::new().unwrap();
+    let config_dir = temp_home.path().join("elsewhere");
+
+    let mut cmd = Command::cargo_bin("aicred").unwrap();
+    set_test_home_envs(&mut cmd, temp_home.path());
+    cmd.env("AICRED_CONFIG_DIR", &config_dir);
+    cmd.args(&["tags", "add", "--name", "production"]);
+    cmd.assert().success();
+
+    assert!(config_dir.join("tags.yaml").exists());
+    assert!(!temp_home.path().join(".config").exists());
+
+    // XDG_CONFIG_HOME applies to the user's own home
+    if cfg!(all(unix, not(target_os = "macos"))) {
+        let xdg = temp_home.path().join("xdg");
+        let mut cmd = Command::cargo_bin("aicred").unwrap();
+        set_test_home_envs(&mut cmd, temp_home.path());
+        cmd.env("XDG_CONFIG_HOME", &xdg);
+        cmd.args(&["tags", "add", "--name", "staging"]);
+        cmd.assert().success();
+
+        assert!(xdg.join("aicred").join("tags.yaml").exists());
+    }
+}
@@ -18,6 +18,8 @@ mod cli_integration_tests {
         // Set HOME to temp directory for isolated testing
         let mut cmd = Command::cargo_bin("aicred").expect("Failed to find aicred binary");
         cmd.env("HOME", temp_dir.path());
+        cmd.env_remove("AICRED_CONFIG_DIR");
+        cmd.env_remove("XDG_CONFIG_HOME");
 
         (cmd, temp_dir)
     }
@@ -0,0 +1,285 @@
+//! Alfred scanner for discovering API keys in Alfred workflow preferences.
+
+use super::{extract_json_api_keys, ScanResult, ScannerPlugin, ScannerPluginExt};
+use crate::error::Result;
+use crate::models::ConfigInstance;
+use std::path::{Path, PathBuf};
+
+/// Preference directories of Alfred 5 and of earlier versions, relative to
+/// the home directory.
+const PREFERENCES_DIRS: &[&str] = &[
+    "Library/Application Support/Alfred/Alfred.alfredpreferences",
+    "Library/Application Support/Alfred 4/Alfred.alfredpreferences",
+    "Library/Application Support/Alfred 3/Alfred.alfredpreferences",
+];
+
+/// Scanner for Alfred workflow configuration.
+///
+/// Workflows such as ChatGPT / DALL-E keep their API keys as workflow
+/// variables in `info.plist`, or as user configuration in `prefs.plist`,
+/// which Alfred 5 keeps out of exported workflows.
+pub struct AlfredScanner;
+
+impl ScannerPlugin for AlfredScanner {
+    fn name(&self) -> &'static str {
+        "alfred"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "Alfred"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        let mut paths = Vec::new();
+        for preferences_dir in PREFERENCES_DIRS {
+            let Ok(entries) = std::fs::read_dir(home_dir.join(preferences_dir).join("workflows"))
+            else {
+                continue;
+            };
+            for entry in entries.flatten() {
+                paths.push(entry.path().join("info.plist"));
+                paths.push(entry.path().join("prefs.plist"));
+            }
+        }
+        paths
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        let file_name = path.file_name().unwrap_or_default().to_string_lossy();
+        (file_name == "info.plist" || file_name == "prefs.plist")
+            && path.to_string_lossy().contains(".alfredpreferences")
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let entries = Self::plist_strings(content);
+        // Each entry stands alone so that a workflow's "name" is not taken
+        // for the provider of every key in it
+        let json_value = serde_json::Value::Array(
+            entries
+                .iter()
+                .map(|(key, value)| {
+                    serde_json::Value::Object(serde_json::Map::from_iter([(
+                        key.clone(),
+                        serde_json::Value::String(value.clone()),
+                    )]))
+                })
+                .collect(),
+        );
+        let keys = extract_json_api_keys(&json_value, path, "alfred");
+        if keys.is_empty() {
+            return Ok(result);
+        }
+
+        let mut instance = Self::create_config_instance(path, &entries);
+        match self.build_instances_from_keys(&keys, &path.display().to_string(), None) {
+            Ok(provider_instances) => {
+                for provider_instance in provider_instances {
+                    if let Err(e) = instance.add_provider_instance(provider_instance) {
+                        tracing::warn!("Failed to add provider instance to config: {}", e);
+                    }
+                }
+            }
+            Err(e) => tracing::warn!("Failed to build provider instances from keys: {}", e),
+        }
+
+        result.add_keys(keys);
+        result.add_instance(instance);
+        Ok(result)
+    }
+}
+
+impl AlfredScanner {
+    /// Extract the `<key>` / `<string>` pairs of an XML property list, in
+    /// document order. Nesting is not kept.
+    fn plist_strings(content: &str) -> Vec<(String, String)> {
+        let pattern = regex::Regex::new(r"<key>([^<]*)</key>\s*<string>([^<]*)</string>").unwrap();
+        pattern
+            .captures_iter(content)
+            .map(|cap| (Self::unescape(&cap[1]), Self::unescape(&cap[2])))
+            .collect()
+    }
+
+    /// Decode the XML entities a property list writer produces.
+    fn unescape(text: &str) -> String {
+        text.replace("&lt;", "<")
+            .replace("&gt;", ">")
+            .replace("&quot;", "\"")
+            .replace("&apos;", "'")
+            .replace("&amp;", "&")
+    }
+
+    /// Create a config instance for a workflow.
+    fn create_config_instance(path: &Path, entries: &[(String, String)]) -> ConfigInstance {
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "alfred".to_string(),
+            path.to_path_buf(),
+        );
+
+        // The workflow's own bundle ID and name come before those of its objects
+        for field in ["bundleid", "name", "version"] {
+            if let Some((_, value)) = entries.iter().find(|(key, _)| key == field) {
+                let name = if field == "bundleid" {
+                    "bundle_id"
+                } else {
+                    field
+                };
+                instance.metadata.insert(name.to_string(), value.clone());
+            }
+        }
+        if let Some(workflow) = path.parent().and_then(Path::file_name) {
+            instance.metadata.insert(
+                "workflow".to_string(),
+                workflow.to_string_lossy().to_string(),
+            );
+        }
+
+        instance
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("alfred_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::models::credentials::{Confidence, ValueType};
+
+    const INFO_PLIST: &str = r#"<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>bundleid</key>
+	<string>com.alfredapp.vitor.openai</string>
+	<key>name</key>
+	<string>ChatGPT &amp; DALL-E</string>
+	<key>objects</key>
+	<array>
+		<dict>
+			<key>name</key>
+			<string>Ask Gemini</string>
+		</dict>
+	</array>
+	<key>variables</key>
+	<dict>
+		<key>openai_api_key</key>
+		<string>sk-proj-alfredtest1234567890</string>
+		<key>ANTHROPIC_API_KEY</key>
+		<string>sk-ant-REDACTED</string>
+		<key>gpt_model</key>
+		<string>gpt-4o</string>
+	</dict>
+	<key>version</key>
+	<string>2024.1</string>
+</dict>
+</plist>
+"#;
+
+    fn workflow_path(file_name: &str) -> PathBuf {
+        PathBuf::from("/Users/me")
+            .join(PREFERENCES_DIRS[0])
+            .join("workflows")
+            .join("user.workflow.1234")
+            .join(file_name)
+    }
+
+    #[test]
+    fn test_alfred_scanner_name() {
+        let scanner = AlfredScanner;
+        assert_eq!(scanner.name(), "alfred");
+        assert_eq!(scanner.app_name(), "Alfred");
+    }
+
+    #[test]
+    fn test_scan_paths() {
+        let scanner = AlfredScanner;
+        let temp_dir = tempfile::tempdir().unwrap();
+        let workflow = temp_dir
+            .path()
+            .join(PREFERENCES_DIRS[0])
+            .join("workflows")
+            .join("user.workflow.1234");
+        std::fs::create_dir_all(&workflow).unwrap();
+
+        let paths = scanner.scan_paths(temp_dir.path());
+        assert_eq!(
+            paths,
+            vec![workflow.join("info.plist"), workflow.join("prefs.plist")]
+        );
+    }
+
+    #[test]
+    fn test_can_handle_file() {
+        let scanner = AlfredScanner;
+        assert!(scanner.can_handle_file(&workflow_path("info.plist")));
+        assert!(scanner.can_handle_file(&workflow_path("prefs.plist")));
+        assert!(!scanner.can_handle_file(Path::new("/Applications/Foo.app/Contents/Info.plist")));
+    }
+
+    #[test]
+    fn test_parse_workflow_variables() {
+        let scanner = AlfredScanner;
+        let result = scanner
+            .parse_config(&workflow_path("info.plist"), INFO_PLIST)
+            .unwrap();
+
+        assert_eq!(result.keys.len(), 2);
+        assert_eq!(result.keys[0].provider, "openai");
+        assert_eq!(result.keys[1].provider, "anthropic");
+        assert!(result
+            .keys
+            .iter()
+            .all(|k| k.value_type == ValueType::ApiKey && k.confidence == Confidence::High));
+
+        let instance = &result.instances[0];
+        assert_eq!(instance.app_name, "alfred");
+        assert_eq!(
+            instance.metadata.get("bundle_id").unwrap(),
+            "com.alfredapp.vitor.openai"
+        );
+        assert_eq!(instance.metadata.get("name").unwrap(), "ChatGPT & DALL-E");
+        assert_eq!(
+            instance.metadata.get("workflow").unwrap(),
+            "user.workflow.1234"
+        );
+    }
+
+    #[test]
+    fn test_parse_prefs_plist() {
+        let scanner = AlfredScanner;
+        let prefs = r#"<plist version="1.0">
+<dict>
+	<key>api_key</key>
+	<string>gsk_alfredtest1234567890abcdef</string>
+</dict>
+</plist>"#;
+
+        let result = scanner
+            .parse_config(&workflow_path("prefs.plist"), prefs)
+            .unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "groq");
+    }
+
+    #[test]
+    fn test_parse_workflow_without_keys() {
+        let scanner = AlfredScanner;
+        let plist = r#"<plist version="1.0"><dict><key>name</key><string>Calculator</string></dict></plist>"#;
+        let result = scanner
+            .parse_config(&workflow_path("info.plist"), plist)
+            .unwrap();
+        assert!(result.keys.is_empty());
+        assert!(result.instances.is_empty());
+    }
+}
@@ -0,0 +1,250 @@
+//! `GPT4All` scanner for discovering API keys in `GPT4All` model and settings files.
+
+use super::{extract_json_api_keys, ScanResult, ScannerPlugin, ScannerPluginExt};
+use crate::error::Result;
+use crate::models::ConfigInstance;
+use std::path::{Path, PathBuf};
+
+/// `GPT4All` data directories on Linux, macOS and Windows, relative to the
+/// home directory. Remote model definitions are kept here.
+const DATA_DIRS: &[&str] = &[
+    ".local/share/nomic.ai/GPT4All",
+    "Library/Application Support/nomic.ai/GPT4All",
+    "AppData/Local/nomic.ai/GPT4All",
+];
+
+/// `GPT4All` settings, written by Qt as INI on Linux. macOS keeps them in a
+/// binary property list and Windows in the registry, neither of which is read.
+const SETTINGS_FILE: &str = ".config/nomic.ai/GPT4All.ini";
+
+/// Scanner for `GPT4All` configuration.
+///
+/// Models served by `OpenAI`, Mistral, Groq or another compatible API are added
+/// to `GPT4All` as `.rmodel` files, JSON documents holding the model name, base
+/// URL and API key.
+pub struct Gpt4AllScanner;
+
+impl ScannerPlugin for Gpt4AllScanner {
+    fn name(&self) -> &'static str {
+        "gpt4all"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "GPT4All"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        let mut paths = vec![home_dir.join(SETTINGS_FILE)];
+        for data_dir in DATA_DIRS {
+            let Ok(entries) = std::fs::read_dir(home_dir.join(data_dir)) else {
+                continue;
+            };
+            let mut models: Vec<PathBuf> = entries
+                .flatten()
+                .map(|entry| entry.path())
+                .filter(|path| path.extension().is_some_and(|ext| ext == "rmodel"))
+                .collect();
+            models.sort();
+            paths.extend(models);
+        }
+        paths
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        let file_name = path.file_name().unwrap_or_default().to_string_lossy();
+        file_name.ends_with(".rmodel")
+            || (file_name == "GPT4All.ini" && path.to_string_lossy().contains("nomic.ai"))
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let is_ini = path.extension().is_some_and(|ext| ext == "ini");
+        let json_value = if is_ini {
+            Self::ini_to_json(content)
+        } else {
+            let Ok(json_value) = serde_json::from_str::<serde_json::Value>(content) else {
+                return Ok(result);
+            };
+            json_value
+        };
+
+        let keys = extract_json_api_keys(&json_value, path, "gpt4all");
+        if keys.is_empty() {
+            return Ok(result);
+        }
+
+        let mut instance = Self::create_config_instance(path, &json_value);
+        match self.build_instances_from_keys(&keys, &path.display().to_string(), None) {
+            Ok(provider_instances) => {
+                for provider_instance in provider_instances {
+                    if let Err(e) = instance.add_provider_instance(provider_instance) {
+                        tracing::warn!("Failed to add provider instance to config: {}", e);
+                    }
+                }
+            }
+            Err(e) => tracing::warn!("Failed to build provider instances from keys: {}", e),
+        }
+
+        result.add_keys(keys);
+        result.add_instance(instance);
+        Ok(result)
+    }
+}
+
+impl Gpt4AllScanner {
+    /// Convert Qt INI settings to a JSON object of sections, so that a
+    /// section named after a model or provider gives its keys their provider.
+    fn ini_to_json(content: &str) -> serde_json::Value {
+        let mut sections = serde_json::Map::new();
+        let mut section = "General".to_string();
+        for line in content.lines() {
+            let line = line.trim();
+            if line.is_empty() || line.starts_with(';') || line.starts_with('#') {
+                continue;
+            }
+            if let Some(name) = line.strip_prefix('[').and_then(|l| l.strip_suffix(']')) {
+                section = name.to_string();
+                continue;
+            }
+            let Some((name, value)) = line.split_once('=') else {
+                continue;
+            };
+            // Qt nests groups within a section as group/key
+            let name = name.trim().rsplit('/').next().unwrap_or_default();
+            let value = value.trim().trim_matches('"');
+            if let serde_json::Value::Object(entries) = sections
+                .entry(section.clone())
+                .or_insert_with(|| serde_json::Value::Object(serde_json::Map::new()))
+            {
+                entries.insert(
+                    name.to_string(),
+                    serde_json::Value::String(value.to_string()),
+                );
+            }
+        }
+        serde_json::Value::Object(sections)
+    }
+
+    /// Create a config instance from `GPT4All` settings or a remote model.
+    fn create_config_instance(path: &Path, json_value: &serde_json::Value) -> ConfigInstance {
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "gpt4all".to_string(),
+            path.to_path_buf(),
+        );
+
+        if let Some(model) = json_value.get("modelName").and_then(|v| v.as_str()) {
+            instance
+                .metadata
+                .insert("model".to_string(), model.to_string());
+        }
+        if let Some(base_url) = json_value.get("baseUrl").and_then(|v| v.as_str()) {
+            instance
+                .metadata
+                .insert("base_url".to_string(), base_url.to_string());
+        }
+
+        instance
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("gpt4all_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::models::credentials::{Confidence, ValueType};
+
+    #[test]
+    fn test_gpt4all_scanner_name() {
+        let scanner = Gpt4AllScanner;
+        assert_eq!(scanner.name(), "gpt4all");
+        assert_eq!(scanner.app_name(), "GPT4All");
+    }
+
+    #[test]
+    fn test_scan_paths_include_remote_models() {
+        let scanner = Gpt4AllScanner;
+        let temp_dir = tempfile::tempdir().unwrap();
+        let data_dir = temp_dir.path().join(DATA_DIRS[0]);
+        std::fs::create_dir_all(&data_dir).unwrap();
+        std::fs::write(data_dir.join("gpt4all-gpt-4o.rmodel"), "{}").unwrap();
+        std::fs::write(data_dir.join("Llama-3.2-3B-Instruct-Q4_0.gguf"), "").unwrap();
+
+        let paths = scanner.scan_paths(temp_dir.path());
+        assert_eq!(
+            paths,
+            vec![
+                temp_dir.path().join(SETTINGS_FILE),
+                data_dir.join("gpt4all-gpt-4o.rmodel"),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_can_handle_file() {
+        let scanner = Gpt4AllScanner;
+        assert!(scanner.can_handle_file(Path::new(
+            "/home/user/.local/share/nomic.ai/GPT4All/gpt4all-gpt-4o.rmodel"
+        )));
+        assert!(scanner.can_handle_file(Path::new("/home/user/.config/nomic.ai/GPT4All.ini")));
+        assert!(!scanner.can_handle_file(Path::new("/home/user/.config/other/GPT4All.ini")));
+    }
+
+    #[test]
+    fn test_parse_remote_model() {
+        let scanner = Gpt4AllScanner;
+        let model = r#"{
+            "apiKey": "gsk_gpt4alltest1234567890abcdef",
+            "modelName": "llama-3.1-70b-versatile",
+            "baseUrl": "https://api.groq.com/openai/v1/chat/completions"
+        }"#;
+        let path = Path::new("/home/user/.local/share/nomic.ai/GPT4All/gpt4all-groq.rmodel");
+
+        let result = scanner.parse_config(path, model).unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "groq");
+        assert_eq!(result.keys[0].value_type, ValueType::ApiKey);
+        assert_eq!(result.keys[0].confidence, Confidence::High);
+
+        let instance = &result.instances[0];
+        assert_eq!(instance.app_name, "gpt4all");
+        assert_eq!(
+            instance.metadata.get("model").unwrap(),
+            "llama-3.1-70b-versatile"
+        );
+    }
+
+    #[test]
+    fn test_parse_mistral_model_without_prefix() {
+        let scanner = Gpt4AllScanner;
+        let model = r#"{"apiKey": "gpt4allmistraltest1234567890ab", "modelName": "mistral-large-latest", "baseUrl": "https://api.mistral.ai/v1/chat/completions"}"#;
+        let path = Path::new("/home/user/.local/share/nomic.ai/GPT4All/gpt4all-mistral.rmodel");
+
+        let result = scanner.parse_config(path, model).unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "gpt4all");
+        assert_eq!(result.keys[0].confidence, Confidence::Medium);
+    }
+
+    #[test]
+    fn test_parse_ini_settings() {
+        let scanner = Gpt4AllScanner;
+        let settings = "[General]\nuserDefaultModel=Application default\n\n[openai]\napiKey=sk-proj-gpt4alltest1234567890\n";
+        let path = Path::new("/home/user/.config/nomic.ai/GPT4All.ini");
+
+        let result = scanner.parse_config(path, settings).unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "openai");
+    }
+}
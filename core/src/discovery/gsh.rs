@@ -315,7 +315,8 @@ impl GshScanner {
                         value_type,
                         Self::get_confidence(value),
                         value.clone(),
-                    );
+                    )
+                    .with_env_var(key_name);
 
                     if is_model_id {
                         tracing::debug!("Found GSH ModelId: {} = {}", key_name, value);
@@ -349,7 +350,8 @@ impl GshScanner {
                         value_type,
                         Self::get_confidence(value),
                         value.clone(),
-                    );
+                    )
+                    .with_env_var(key_name);
 
                     if is_model_id {
                         tracing::debug!("Found GSH ModelId: {} = {}", key_name, value);
@@ -474,7 +476,8 @@ impl GshScanner {
                         ValueType::ApiKey,
                         Self::get_confidence(key_value),
                         key_value.to_string(),
-                    );
+                    )
+                    .with_offset_in(content, key_match.start());
 
                     keys.push(discovered_key);
                 }
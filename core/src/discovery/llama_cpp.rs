@@ -0,0 +1,267 @@
+//! llama.cpp scanner for discovering local llama.cpp servers and their models.
+
+use super::{ScanResult, ScannerPlugin};
+use crate::error::Result;
+use crate::models::{ConfigInstance, ProviderInstance};
+use std::path::{Path, PathBuf};
+
+/// Where `llama-server --hf-repo` caches downloaded models on Linux, macOS
+/// and Windows, relative to the home directory.
+const CACHE_DIRS: &[&str] = &[
+    ".cache/llama.cpp",
+    "Library/Caches/llama.cpp",
+    "AppData/Local/llama.cpp",
+];
+
+/// The endpoint `llama-server` and `llama-swap` listen on by default.
+const DEFAULT_HOST: &str = "127.0.0.1";
+const DEFAULT_PORT: &str = "8080";
+
+/// Scanner for llama.cpp servers.
+///
+/// `llama-server` takes its settings on the command line, so the models it
+/// can serve are listed from its download cache (`$LLAMA_CACHE` or the
+/// platform cache directory) and its endpoint from `LLAMA_ARG_HOST` and
+/// `LLAMA_ARG_PORT`. `llama-swap`, which runs a server per model on demand,
+/// lists its models in `~/.config/llama-swap/config.yaml`.
+pub struct LlamaCppScanner;
+
+impl ScannerPlugin for LlamaCppScanner {
+    fn name(&self) -> &'static str {
+        "llama-cpp"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "llama.cpp"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        vec![home_dir
+            .join(".config")
+            .join("llama-swap")
+            .join("config.yaml")]
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        let file_name = path.file_name().unwrap_or_default().to_string_lossy();
+        (file_name == "config.yaml" || file_name == "config.yml")
+            && path.to_string_lossy().contains("llama-swap")
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let Ok(config) = serde_yaml::from_str::<serde_yaml::Value>(content) else {
+            return Ok(result);
+        };
+        let Some(models) = config.get("models").and_then(|v| v.as_mapping()) else {
+            return Ok(result);
+        };
+
+        let mut names: Vec<String> = models
+            .keys()
+            .filter_map(|name| name.as_str())
+            .map(str::to_string)
+            .collect();
+        names.sort();
+        if names.is_empty() {
+            return Ok(result);
+        }
+
+        result.add_instance(Self::create_config_instance(
+            path,
+            &Self::base_url(DEFAULT_HOST, DEFAULT_PORT),
+            names,
+        ));
+        Ok(result)
+    }
+
+    fn scan_instances(&self, home_dir: &Path) -> Result<Vec<ConfigInstance>> {
+        let cache_dir = std::env::var_os("LLAMA_CACHE")
+            .map(PathBuf::from)
+            .or_else(|| {
+                CACHE_DIRS
+                    .iter()
+                    .map(|dir| home_dir.join(dir))
+                    .find(|dir| dir.is_dir())
+            });
+        let Some(cache_dir) = cache_dir else {
+            return Ok(Vec::new());
+        };
+
+        let host = std::env::var("LLAMA_ARG_HOST").unwrap_or_else(|_| DEFAULT_HOST.to_string());
+        let port = std::env::var("LLAMA_ARG_PORT").unwrap_or_else(|_| DEFAULT_PORT.to_string());
+        // llama-swap's config is read through scan_paths
+        Ok(Self::discover(&cache_dir, &host, &port)
+            .into_iter()
+            .collect())
+    }
+}
+
+impl LlamaCppScanner {
+    /// Build the instance for a download cache, or nothing if it holds no
+    /// models.
+    fn discover(cache_dir: &Path, host: &str, port: &str) -> Option<ConfigInstance> {
+        let models = Self::cached_models(cache_dir);
+        if models.is_empty() {
+            return None;
+        }
+        Some(Self::create_config_instance(
+            cache_dir,
+            &Self::base_url(host, port),
+            models,
+        ))
+    }
+
+    /// List the GGUF models in a download cache. `llama-server` names them
+    /// `<user>_<repo>_<file>.gguf`; split multi-part models are listed once.
+    fn cached_models(cache_dir: &Path) -> Vec<String> {
+        let Ok(entries) = std::fs::read_dir(cache_dir) else {
+            return Vec::new();
+        };
+        let mut models: Vec<String> = entries
+            .flatten()
+            .map(|entry| entry.path())
+            .filter(|path| path.extension().is_some_and(|ext| ext == "gguf"))
+            .filter_map(|path| {
+                let stem = path.file_stem()?.to_string_lossy().to_string();
+                // model-00002-of-00003 is a continuation of model-00001-of-00003
+                let continuation = stem.rsplit_once("-of-").is_some_and(|(part, total)| {
+                    total.chars().all(|c| c.is_ascii_digit())
+                        && part
+                            .rsplit_once('-')
+                            .is_some_and(|(_, n)| n.chars().all(|c| c.is_ascii_digit()))
+                        && !part.ends_with("-00001")
+                });
+                (!continuation).then_some(stem)
+            })
+            .collect();
+        models.sort();
+        models
+    }
+
+    /// The OpenAI-compatible API root of a server.
+    fn base_url(host: &str, port: &str) -> String {
+        format!("http://{host}:{port}/v1")
+    }
+
+    /// Create a config instance for a cache or `llama-swap` config.
+    fn create_config_instance(path: &Path, base_url: &str, models: Vec<String>) -> ConfigInstance {
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "llama-cpp".to_string(),
+            path.to_path_buf(),
+        );
+        instance
+            .metadata
+            .insert("base_url".to_string(), base_url.to_string());
+        instance
+            .metadata
+            .insert("model_count".to_string(), models.len().to_string());
+        instance
+            .metadata
+            .insert("models".to_string(), models.join(", "));
+
+        let provider_instance = ProviderInstance::new(
+            Self::generate_instance_id(Path::new(base_url)),
+            "llamacpp".to_string(),
+            base_url.to_string(),
+            String::new(),
+            models,
+        );
+        if let Err(e) = instance.add_provider_instance(provider_instance) {
+            tracing::warn!("Failed to add provider instance to config: {}", e);
+        }
+
+        instance
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("llamacpp_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_llama_cpp_scanner_name() {
+        let scanner = LlamaCppScanner;
+        assert_eq!(scanner.name(), "llama-cpp");
+        assert_eq!(scanner.app_name(), "llama.cpp");
+    }
+
+    #[test]
+    fn test_can_handle_file() {
+        let scanner = LlamaCppScanner;
+        assert!(scanner.can_handle_file(Path::new("/home/user/.config/llama-swap/config.yaml")));
+        assert!(!scanner.can_handle_file(Path::new("/home/user/.config/app/config.yaml")));
+    }
+
+    #[test]
+    fn test_discover_cached_models() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        for file in [
+            "bartowski_Llama-3.2-3B-Instruct-GGUF_Llama-3.2-3B-Instruct-Q4_K_M.gguf",
+            "unsloth_Qwen3-235B-GGUF_Qwen3-235B-Q4_K_M-00001-of-00002.gguf",
+            "unsloth_Qwen3-235B-GGUF_Qwen3-235B-Q4_K_M-00002-of-00002.gguf",
+            "mradermacher_Mixture-of-Experts-GGUF_Mixture-of-Experts.Q8_0.gguf",
+            "manifest=bartowski=Llama-3.2-3B-Instruct-GGUF=latest.json",
+        ] {
+            std::fs::write(temp_dir.path().join(file), "").unwrap();
+        }
+
+        let instance = LlamaCppScanner::discover(temp_dir.path(), "0.0.0.0", "8081").unwrap();
+        assert_eq!(instance.app_name, "llama-cpp");
+        assert_eq!(instance.metadata.get("model_count").unwrap(), "3");
+
+        let providers = instance.provider_instances();
+        assert_eq!(providers.len(), 1);
+        assert_eq!(providers[0].provider_type, "llamacpp");
+        assert_eq!(providers[0].base_url, "http://0.0.0.0:8081/v1");
+        assert_eq!(
+            providers[0].models,
+            vec![
+                "bartowski_Llama-3.2-3B-Instruct-GGUF_Llama-3.2-3B-Instruct-Q4_K_M",
+                "mradermacher_Mixture-of-Experts-GGUF_Mixture-of-Experts.Q8_0",
+                "unsloth_Qwen3-235B-GGUF_Qwen3-235B-Q4_K_M-00001-of-00002",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_discover_empty_cache() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        assert!(LlamaCppScanner::discover(temp_dir.path(), DEFAULT_HOST, DEFAULT_PORT).is_none());
+    }
+
+    #[test]
+    fn test_parse_llama_swap_config() {
+        let scanner = LlamaCppScanner;
+        let config = r#"
+healthCheckTimeout: 60
+models:
+  "qwen2.5-coder":
+    cmd: llama-server --port ${PORT} -m /models/qwen2.5-coder-7b-q8_0.gguf
+  "llama-3.2":
+    cmd: llama-server --port ${PORT} -hf bartowski/Llama-3.2-3B-Instruct-GGUF
+    ttl: 300
+"#;
+        let path = Path::new("/home/user/.config/llama-swap/config.yaml");
+
+        let result = scanner.parse_config(path, config).unwrap();
+        assert!(result.keys.is_empty());
+        assert_eq!(result.instances.len(), 1);
+        let providers = result.instances[0].provider_instances();
+        assert_eq!(providers[0].base_url, "http://127.0.0.1:8080/v1");
+        assert_eq!(providers[0].models, vec!["llama-3.2", "qwen2.5-coder"]);
+    }
+}
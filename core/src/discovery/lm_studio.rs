@@ -0,0 +1,180 @@
+//! LM Studio scanner for discovering API keys in LM Studio configuration files.
+
+use super::{extract_json_api_keys, ScanResult, ScannerPlugin, ScannerPluginExt};
+use crate::error::Result;
+use crate::models::ConfigInstance;
+use std::path::{Path, PathBuf};
+
+/// LM Studio configuration files, relative to the home directory: the
+/// settings and MCP server list of 0.3 and later, and the settings of
+/// earlier releases.
+const CONFIG_FILES: &[&str] = &[
+    ".lmstudio/settings.json",
+    ".lmstudio/mcp.json",
+    ".cache/lm-studio/settings.json",
+];
+
+/// Scanner for LM Studio configuration.
+///
+/// LM Studio runs models locally, but the MCP servers it connects to are
+/// often given cloud provider keys through their `env` blocks in `mcp.json`,
+/// next to any keys kept in its settings.
+pub struct LmStudioScanner;
+
+impl ScannerPlugin for LmStudioScanner {
+    fn name(&self) -> &'static str {
+        "lm-studio"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "LM Studio"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        CONFIG_FILES
+            .iter()
+            .map(|file| home_dir.join(file))
+            .collect()
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        let file_name = path.file_name().unwrap_or_default().to_string_lossy();
+        let path_str = path.to_string_lossy();
+
+        (file_name == "settings.json" || file_name == "mcp.json")
+            && (path_str.contains(".lmstudio") || path_str.contains("lm-studio"))
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let Ok(json_value) = serde_json::from_str::<serde_json::Value>(content) else {
+            return Ok(result);
+        };
+
+        let keys = extract_json_api_keys(&json_value, path, "lm-studio");
+        if keys.is_empty() {
+            return Ok(result);
+        }
+
+        let mut instance = Self::create_config_instance(path, &json_value);
+        match self.build_instances_from_keys(&keys, &path.display().to_string(), None) {
+            Ok(provider_instances) => {
+                for provider_instance in provider_instances {
+                    if let Err(e) = instance.add_provider_instance(provider_instance) {
+                        tracing::warn!("Failed to add provider instance to config: {}", e);
+                    }
+                }
+            }
+            Err(e) => tracing::warn!("Failed to build provider instances from keys: {}", e),
+        }
+
+        result.add_keys(keys);
+        result.add_instance(instance);
+        Ok(result)
+    }
+}
+
+impl LmStudioScanner {
+    /// Create a config instance from LM Studio configuration.
+    fn create_config_instance(path: &Path, json_value: &serde_json::Value) -> ConfigInstance {
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "lm-studio".to_string(),
+            path.to_path_buf(),
+        );
+
+        if let Some(servers) = json_value.get("mcpServers").and_then(|v| v.as_object()) {
+            let mut names: Vec<&str> = servers.keys().map(String::as_str).collect();
+            names.sort_unstable();
+            instance
+                .metadata
+                .insert("mcp_servers".to_string(), names.join(", "));
+        }
+
+        instance
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("lmstudio_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::models::credentials::{Confidence, ValueType};
+
+    #[test]
+    fn test_lm_studio_scanner_name() {
+        let scanner = LmStudioScanner;
+        assert_eq!(scanner.name(), "lm-studio");
+        assert_eq!(scanner.app_name(), "LM Studio");
+    }
+
+    #[test]
+    fn test_scan_paths() {
+        let scanner = LmStudioScanner;
+        let paths = scanner.scan_paths(Path::new("/home/user"));
+        assert_eq!(paths.len(), 3);
+        assert!(paths.contains(&PathBuf::from("/home/user/.lmstudio/mcp.json")));
+    }
+
+    #[test]
+    fn test_can_handle_file() {
+        let scanner = LmStudioScanner;
+        assert!(scanner.can_handle_file(Path::new("/home/user/.lmstudio/mcp.json")));
+        assert!(scanner.can_handle_file(Path::new("/home/user/.cache/lm-studio/settings.json")));
+        assert!(!scanner.can_handle_file(Path::new("/home/user/.vscode/settings.json")));
+    }
+
+    #[test]
+    fn test_parse_mcp_servers() {
+        let scanner = LmStudioScanner;
+        let config = r#"{
+            "mcpServers": {
+                "web-search": {
+                    "command": "npx",
+                    "args": ["-y", "web-search-mcp"],
+                    "env": {"OPENAI_API_KEY": "sk-proj-lmstudio1234567890abcdef"}
+                },
+                "notes": {"url": "http://localhost:3000/mcp"}
+            }
+        }"#;
+
+        let result = scanner
+            .parse_config(Path::new("/home/user/.lmstudio/mcp.json"), config)
+            .unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "openai");
+        assert_eq!(result.keys[0].value_type, ValueType::ApiKey);
+        assert_eq!(result.keys[0].confidence, Confidence::High);
+
+        let instance = &result.instances[0];
+        assert_eq!(instance.app_name, "lm-studio");
+        assert_eq!(
+            instance.metadata.get("mcp_servers").unwrap(),
+            "notes, web-search"
+        );
+    }
+
+    #[test]
+    fn test_parse_settings_without_keys() {
+        let scanner = LmStudioScanner;
+        let result = scanner
+            .parse_config(
+                Path::new("/home/user/.lmstudio/settings.json"),
+                r#"{"downloadsFolder": "/home/user/.lmstudio/models"}"#,
+            )
+            .unwrap();
+        assert!(result.keys.is_empty());
+        assert!(result.instances.is_empty());
+    }
+}
@@ -45,17 +45,35 @@ impl Default for ScannerConfig {
     }
 }
 
+mod alfred;
 mod claude_desktop;
+mod gpt4all;
 mod gsh;
 mod langchain;
+mod llama_cpp;
+mod lm_studio;
+mod obsidian;
+mod ollama;
 mod ragit;
+mod raycast;
 mod roo_code;
+mod sillytavern;
+mod vllm;
 
+pub use alfred::AlfredScanner;
 pub use claude_desktop::ClaudeDesktopScanner;
+pub use gpt4all::Gpt4AllScanner;
 pub use gsh::GshScanner;
 pub use langchain::LangChainScanner;
+pub use llama_cpp::LlamaCppScanner;
+pub use lm_studio::LmStudioScanner;
+pub use obsidian::ObsidianScanner;
+pub use ollama::OllamaScanner;
 pub use ragit::RagitScanner;
+pub use raycast::RaycastScanner;
 pub use roo_code::RooCodeScanner;
+pub use sillytavern::SillyTavernScanner;
+pub use vllm::VllmScanner;
 
 use crate::error::{Error, Result};
 use crate::models::credentials::{Confidence, DiscoveredCredential, ValueType};
@@ -498,6 +516,158 @@ pub fn extract_env_keys_with_metadata(
     keys
 }
 
+/// Infers the provider of an API key from the name of the setting that holds
+/// it (e.g., `anthropicApiKey`), falling back to the key's prefix.
+#[must_use]
+pub fn infer_provider(setting: &str, value: &str) -> Option<&'static str> {
+    const SETTING_NAMES: &[(&str, &str)] = &[
+        ("openrouter", "openrouter"),
+        ("anthropic", "anthropic"),
+        ("claude", "anthropic"),
+        // Before openai, for Groq's OpenAI-compatible base URL
+        ("groq", "groq"),
+        ("openai", "openai"),
+        ("gpt", "openai"),
+        ("google", "google"),
+        ("gemini", "google"),
+        ("huggingface", "huggingface"),
+        ("ollama", "ollama"),
+        ("litellm", "litellm"),
+    ];
+    // Longer prefixes first so sk-ant- is not taken for an OpenAI key
+    const KEY_PREFIXES: &[(&str, &str)] = &[
+        ("sk-ant-", "anthropic"),
+        ("sk-or-", "openrouter"),
+        ("sk-", "openai"),
+        ("gsk_", "groq"),
+        ("hf_", "huggingface"),
+        ("AIza", "google"),
+    ];
+
+    let setting = setting.to_lowercase();
+    SETTING_NAMES
+        .iter()
+        .find(|(name, _)| setting.contains(name))
+        .or_else(|| {
+            KEY_PREFIXES
+                .iter()
+                .find(|(prefix, _)| value.starts_with(prefix))
+        })
+        .map(|(_, provider)| *provider)
+}
+
+/// Confidence that a value found under an API key setting is a real key:
+/// high for a known provider prefix, otherwise by length.
+#[must_use]
+pub fn key_confidence(key: &str) -> Confidence {
+    if infer_provider("", key).is_some() {
+        Confidence::High
+    } else if key.len() >= 30 {
+        Confidence::Medium
+    } else {
+        Confidence::Low
+    }
+}
+
+/// Reports whether a setting name such as `api_key`, `openAIApiKey` or
+/// `anthropic-api-key` names an API key.
+#[must_use]
+pub fn is_api_key_setting(setting: &str) -> bool {
+    let normalized: String = setting
+        .chars()
+        .filter(|c| *c != '_' && *c != '-' && *c != ' ')
+        .collect::<String>()
+        .to_lowercase();
+    normalized.ends_with("apikey") || normalized.ends_with("apitoken")
+}
+
+/// Helper function to extract API keys from the settings of an application
+/// or plugin, at any depth of a JSON document.
+///
+/// A string is taken as a key when its setting name passes
+/// [`is_api_key_setting`], or when it sits in an `api_keys` object whose
+/// entries are named by provider. The provider comes from [`infer_provider`],
+/// then from the nearest enclosing object whose name, provider, type, ID or
+/// base URL names one, then `default_provider`.
+#[must_use]
+pub fn extract_json_api_keys(
+    json_value: &serde_json::Value,
+    path: &Path,
+    default_provider: &str,
+) -> Vec<DiscoveredCredential> {
+    // Fields of an object that name the provider its keys belong to
+    const CONTEXT_FIELDS: &[&str] = &[
+        "provider", "type", "name", "id", "base_url", "baseUrl", "url",
+    ];
+
+    fn walk(
+        value: &serde_json::Value,
+        setting: &str,
+        context: Option<&'static str>,
+        in_key_map: bool,
+        path: &Path,
+        default_provider: &str,
+        keys: &mut Vec<DiscoveredCredential>,
+    ) {
+        match value {
+            serde_json::Value::Object(map) => {
+                let context = infer_provider(setting, "")
+                    .or_else(|| {
+                        CONTEXT_FIELDS.iter().find_map(|field| {
+                            map.get(*field)
+                                .and_then(|v| v.as_str())
+                                .and_then(|v| infer_provider(v, ""))
+                        })
+                    })
+                    .or(context);
+                let key_map = is_api_key_setting(setting.trim_end_matches(['s', 'S']));
+                for (name, child) in map {
+                    walk(child, name, context, key_map, path, default_provider, keys);
+                }
+            }
+            serde_json::Value::Array(items) => {
+                for item in items {
+                    walk(item, setting, context, false, path, default_provider, keys);
+                }
+            }
+            serde_json::Value::String(key) => {
+                let key = key.trim();
+                if !(in_key_map || is_api_key_setting(setting))
+                    || key.len() < 15
+                    || key.chars().any(char::is_whitespace)
+                    // References such as ${OPENAI_API_KEY} are not keys
+                    || key.starts_with('$')
+                {
+                    return;
+                }
+                let provider = infer_provider(setting, key)
+                    .or(context)
+                    .unwrap_or(default_provider);
+                keys.push(DiscoveredCredential::new(
+                    provider.to_string(),
+                    path.display().to_string(),
+                    ValueType::ApiKey,
+                    key_confidence(key),
+                    key.to_string(),
+                ));
+            }
+            _ => {}
+        }
+    }
+
+    let mut keys = Vec::new();
+    walk(
+        json_value,
+        "",
+        None,
+        false,
+        path,
+        default_provider,
+        &mut keys,
+    );
+    keys
+}
+
 /// Extension trait for `ScannerPlugin` providing helper functions to build `ProviderInstance` objects.
 pub trait ScannerPluginExt: ScannerPlugin {
     /// Groups discovered keys by provider.
@@ -830,6 +1000,15 @@ pub fn register_builtin_scanners(registry: &ScannerRegistry) -> Result<()> {
     registry.register(std::sync::Arc::new(RooCodeScanner))?;
     registry.register(std::sync::Arc::new(LangChainScanner))?;
     registry.register(std::sync::Arc::new(GshScanner))?;
+    registry.register(std::sync::Arc::new(RaycastScanner))?;
+    registry.register(std::sync::Arc::new(AlfredScanner))?;
+    registry.register(std::sync::Arc::new(ObsidianScanner))?;
+    registry.register(std::sync::Arc::new(SillyTavernScanner))?;
+    registry.register(std::sync::Arc::new(LmStudioScanner))?;
+    registry.register(std::sync::Arc::new(Gpt4AllScanner))?;
+    registry.register(std::sync::Arc::new(OllamaScanner))?;
+    registry.register(std::sync::Arc::new(LlamaCppScanner))?;
+    registry.register(std::sync::Arc::new(VllmScanner))?;
 
     Ok(())
 }
@@ -0,0 +1,278 @@
+//! Obsidian scanner for discovering API keys in Obsidian community plugin settings.
+
+use super::{extract_json_api_keys, ScanResult, ScannerPlugin, ScannerPluginExt};
+use crate::error::Result;
+use crate::models::ConfigInstance;
+use std::path::{Path, PathBuf};
+
+/// Locations of Obsidian's vault list on macOS, Linux (native and Flatpak)
+/// and Windows, relative to the home directory.
+const VAULT_LISTS: &[&str] = &[
+    "Library/Application Support/obsidian/obsidian.json",
+    ".config/obsidian/obsidian.json",
+    ".var/app/md.obsidian.Obsidian/config/obsidian/obsidian.json",
+    "AppData/Roaming/obsidian/obsidian.json",
+];
+
+/// Scanner for Obsidian community plugin settings.
+///
+/// Vaults are found through Obsidian's vault list, and every community
+/// plugin's `data.json` in each vault is searched. AI plugins such as
+/// Smart Connections and Copilot keep their OpenAI and Anthropic keys there
+/// in plain text, inside the vault, where they are easily synced or committed.
+pub struct ObsidianScanner;
+
+impl ScannerPlugin for ObsidianScanner {
+    fn name(&self) -> &'static str {
+        "obsidian"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "Obsidian"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        let mut paths = Vec::new();
+        for vault in Self::vaults(home_dir) {
+            let Ok(plugins) = std::fs::read_dir(vault.join(".obsidian").join("plugins")) else {
+                continue;
+            };
+            for plugin in plugins.flatten() {
+                let data = plugin.path().join("data.json");
+                if !paths.contains(&data) {
+                    paths.push(data);
+                }
+            }
+        }
+        paths
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        path.file_name().is_some_and(|name| name == "data.json")
+            && path
+                .parent()
+                .and_then(Path::parent)
+                .is_some_and(|plugins| plugins.ends_with(".obsidian/plugins"))
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let Ok(json_value) = serde_json::from_str::<serde_json::Value>(content) else {
+            return Ok(result);
+        };
+
+        let keys = extract_json_api_keys(&json_value, path, "obsidian");
+        if keys.is_empty() {
+            return Ok(result);
+        }
+
+        let mut instance = Self::create_config_instance(path);
+        match self.build_instances_from_keys(&keys, &path.display().to_string(), None) {
+            Ok(provider_instances) => {
+                for provider_instance in provider_instances {
+                    if let Err(e) = instance.add_provider_instance(provider_instance) {
+                        tracing::warn!("Failed to add provider instance to config: {}", e);
+                    }
+                }
+            }
+            Err(e) => tracing::warn!("Failed to build provider instances from keys: {}", e),
+        }
+
+        result.add_keys(keys);
+        result.add_instance(instance);
+        Ok(result)
+    }
+}
+
+impl ObsidianScanner {
+    /// Read the vault directories listed in Obsidian's `obsidian.json`,
+    /// which maps vault IDs to `{"path": ..., "ts": ..., "open": ...}`.
+    fn vaults(home_dir: &Path) -> Vec<PathBuf> {
+        let mut vaults = Vec::new();
+        for vault_list in VAULT_LISTS {
+            let Ok(content) = std::fs::read_to_string(home_dir.join(vault_list)) else {
+                continue;
+            };
+            let Ok(json_value) = serde_json::from_str::<serde_json::Value>(&content) else {
+                tracing::debug!("Skipping unparseable Obsidian vault list {}", vault_list);
+                continue;
+            };
+            let Some(entries) = json_value.get("vaults").and_then(|v| v.as_object()) else {
+                continue;
+            };
+            for entry in entries.values() {
+                if let Some(vault) = entry.get("path").and_then(|v| v.as_str()) {
+                    let vault = PathBuf::from(vault);
+                    if !vaults.contains(&vault) {
+                        vaults.push(vault);
+                    }
+                }
+            }
+        }
+        vaults
+    }
+
+    /// Create a config instance for a plugin's settings.
+    fn create_config_instance(path: &Path) -> ConfigInstance {
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "obsidian".to_string(),
+            path.to_path_buf(),
+        );
+
+        let plugin_dir = path.parent();
+        if let Some(plugin) = plugin_dir.and_then(Path::file_name) {
+            instance
+                .metadata
+                .insert("plugin".to_string(), plugin.to_string_lossy().to_string());
+        }
+        // data.json sits in <vault>/.obsidian/plugins/<plugin>/
+        if let Some(vault) = plugin_dir.and_then(|p| p.ancestors().nth(3)) {
+            instance
+                .metadata
+                .insert("vault".to_string(), vault.display().to_string());
+        }
+
+        instance
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("obsidian_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::models::credentials::{Confidence, ValueType};
+
+    #[test]
+    fn test_obsidian_scanner_name() {
+        let scanner = ObsidianScanner;
+        assert_eq!(scanner.name(), "obsidian");
+        assert_eq!(scanner.app_name(), "Obsidian");
+    }
+
+    #[test]
+    fn test_scan_paths_follow_vault_list() {
+        let scanner = ObsidianScanner;
+        let temp_dir = tempfile::tempdir().unwrap();
+        let home_dir = temp_dir.path();
+        let vault = home_dir.join("Documents").join("Notes");
+        let plugins = vault.join(".obsidian").join("plugins");
+        std::fs::create_dir_all(plugins.join("copilot")).unwrap();
+        std::fs::create_dir_all(plugins.join("smart-connections")).unwrap();
+
+        let vault_list = home_dir.join(VAULT_LISTS[1]);
+        std::fs::create_dir_all(vault_list.parent().unwrap()).unwrap();
+        std::fs::write(
+            &vault_list,
+            serde_json::json!({
+                "vaults": {
+                    "a1b2c3": {"path": vault, "ts": 1_700_000_000_000_u64, "open": true},
+                    "d4e5f6": {"path": home_dir.join("missing")}
+                }
+            })
+            .to_string(),
+        )
+        .unwrap();
+
+        let mut paths = scanner.scan_paths(home_dir);
+        paths.sort();
+        assert_eq!(
+            paths,
+            vec![
+                plugins.join("copilot").join("data.json"),
+                plugins.join("smart-connections").join("data.json"),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_can_handle_file() {
+        let scanner = ObsidianScanner;
+        assert!(scanner.can_handle_file(Path::new(
+            "/home/user/Notes/.obsidian/plugins/copilot/data.json"
+        )));
+        assert!(!scanner.can_handle_file(Path::new(
+            "/home/user/Notes/.obsidian/plugins/copilot/manifest.json"
+        )));
+        assert!(!scanner.can_handle_file(Path::new("/home/user/data.json")));
+    }
+
+    #[test]
+    fn test_parse_copilot_settings() {
+        let scanner = ObsidianScanner;
+        let config = r#"{
+            "openAIApiKey": "sk-proj-obsidiantest1234567890",
+            "anthropicApiKey": "sk-ant-REDACTED",
+            "googleApiKey": "",
+            "activeModels": [
+                {"name": "llama-3.1-70b", "provider": "groq", "apiKey": "obsidiantestgroq1234567890"}
+            ],
+            "temperature": 0.1
+        }"#;
+        let path = Path::new("/home/user/Notes/.obsidian/plugins/copilot/data.json");
+
+        let result = scanner.parse_config(path, config).unwrap();
+        let confidence = |provider: &str| {
+            result
+                .keys
+                .iter()
+                .find(|k| k.provider == provider)
+                .map(|k| k.confidence)
+        };
+        assert_eq!(result.keys.len(), 3);
+        assert!(result
+            .keys
+            .iter()
+            .all(|k| k.value_type == ValueType::ApiKey));
+        assert_eq!(confidence("openai"), Some(Confidence::High));
+        assert_eq!(confidence("anthropic"), Some(Confidence::High));
+        // Named by the model entry it belongs to, not by a key prefix
+        assert_eq!(confidence("groq"), Some(Confidence::Low));
+
+        let instance = &result.instances[0];
+        assert_eq!(instance.app_name, "obsidian");
+        assert_eq!(instance.metadata.get("plugin").unwrap(), "copilot");
+        assert_eq!(instance.metadata.get("vault").unwrap(), "/home/user/Notes");
+    }
+
+    #[test]
+    fn test_parse_smart_connections_settings() {
+        let scanner = ObsidianScanner;
+        let config = r#"{
+            "smart_sources": {
+                "embed_model": {
+                    "adapter": "openai",
+                    "openai": {"api_key": "sk-proj-obsidiantest1234567890"}
+                }
+            },
+            "chat_model": {"anthropic": {"api_key": "${ANTHROPIC_API_KEY}"}}
+        }"#;
+        let path = Path::new("/home/user/Notes/.obsidian/plugins/smart-connections/data.json");
+
+        let result = scanner.parse_config(path, config).unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "openai");
+    }
+
+    #[test]
+    fn test_parse_settings_without_keys() {
+        let scanner = ObsidianScanner;
+        let path = Path::new("/home/user/Notes/.obsidian/plugins/calendar/data.json");
+        let result = scanner
+            .parse_config(path, r#"{"weekStart": "monday"}"#)
+            .unwrap();
+        assert!(result.keys.is_empty());
+        assert!(result.instances.is_empty());
+    }
+}
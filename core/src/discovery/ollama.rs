@@ -0,0 +1,330 @@
+//! Ollama scanner for discovering local Ollama servers and their pulled models.
+
+use super::{ScanResult, ScannerPlugin};
+use crate::error::Result;
+use crate::models::{ConfigInstance, ProviderInstance};
+use crate::providers::ollama::OllamaPlugin;
+use std::path::{Path, PathBuf};
+
+/// The registry `ollama pull` uses when a model name has none.
+const DEFAULT_REGISTRY: &str = "registry.ollama.ai";
+
+/// The namespace of official models, which their names leave out.
+const DEFAULT_NAMESPACE: &str = "library";
+
+/// Scanner for the models an Ollama install has pulled.
+///
+/// Ollama has no configuration file. Models are listed from the manifests
+/// under `~/.ollama/models` (or `$OLLAMA_MODELS`), and the server's endpoint
+/// comes from `OLLAMA_HOST` in the environment or a systemd user unit,
+/// defaulting to `http://127.0.0.1:11434`.
+pub struct OllamaScanner;
+
+impl ScannerPlugin for OllamaScanner {
+    fn name(&self) -> &'static str {
+        "ollama"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "Ollama"
+    }
+
+    fn scan_paths(&self, _home_dir: &Path) -> Vec<PathBuf> {
+        // Instances come from the model store rather than a config file
+        Vec::new()
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        path.to_string_lossy().contains(".ollama/models/manifests/")
+    }
+
+    fn parse_config(&self, _path: &Path, _content: &str) -> Result<ScanResult> {
+        Ok(ScanResult::new())
+    }
+
+    fn scan_instances(&self, home_dir: &Path) -> Result<Vec<ConfigInstance>> {
+        let models_dir = std::env::var_os("OLLAMA_MODELS")
+            .map_or_else(|| home_dir.join(".ollama").join("models"), PathBuf::from);
+        let host = std::env::var("OLLAMA_HOST")
+            .ok()
+            .filter(|host| !host.trim().is_empty())
+            .or_else(|| Self::systemd_host(home_dir));
+
+        Ok(Self::discover(&models_dir, host.as_deref())
+            .into_iter()
+            .collect())
+    }
+}
+
+impl OllamaScanner {
+    /// Build the instance for a model store and `OLLAMA_HOST` value. Nothing
+    /// is returned when there are no models and no custom endpoint.
+    fn discover(models_dir: &Path, host: Option<&str>) -> Option<ConfigInstance> {
+        let models = Self::pulled_models(&models_dir.join("manifests"));
+        if models.is_empty() && host.is_none() {
+            return None;
+        }
+
+        let base_url = OllamaPlugin::base_url_from_host(host.unwrap_or(""));
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(models_dir),
+            "ollama".to_string(),
+            models_dir.to_path_buf(),
+        );
+        instance
+            .metadata
+            .insert("base_url".to_string(), base_url.clone());
+        instance
+            .metadata
+            .insert("model_count".to_string(), models.len().to_string());
+        if !models.is_empty() {
+            instance
+                .metadata
+                .insert("models".to_string(), models.join(", "));
+        }
+
+        let provider_instance = ProviderInstance::new(
+            Self::generate_instance_id(Path::new(&base_url)),
+            "ollama".to_string(),
+            base_url,
+            String::new(),
+            models,
+        );
+        if let Err(e) = instance.add_provider_instance(provider_instance) {
+            tracing::warn!("Failed to add provider instance to config: {}", e);
+        }
+
+        Some(instance)
+    }
+
+    /// List pulled models from a manifests directory laid out as
+    /// `<registry>/<namespace>/<model>/<tag>`, named as `ollama list` names
+    /// them, e.g. `llama3.2:3b` or `hf.co/bartowski/Llama-3.2-1B-GGUF:Q4_K_M`.
+    fn pulled_models(manifests_dir: &Path) -> Vec<String> {
+        let mut models = Vec::new();
+        for registry in Self::subdirectories(manifests_dir) {
+            for namespace in Self::subdirectories(&registry) {
+                for model in Self::subdirectories(&namespace) {
+                    let Ok(tags) = std::fs::read_dir(&model) else {
+                        continue;
+                    };
+                    for tag in tags.flatten() {
+                        if !tag.path().is_file() {
+                            continue;
+                        }
+                        models.push(Self::model_name(
+                            &Self::file_name(&registry),
+                            &Self::file_name(&namespace),
+                            &Self::file_name(&model),
+                            &tag.file_name().to_string_lossy(),
+                        ));
+                    }
+                }
+            }
+        }
+        models.sort();
+        models
+    }
+
+    /// Name a model as Ollama does, leaving out the default registry and
+    /// namespace.
+    fn model_name(registry: &str, namespace: &str, model: &str, tag: &str) -> String {
+        match (registry, namespace) {
+            (DEFAULT_REGISTRY, DEFAULT_NAMESPACE) => format!("{model}:{tag}"),
+            (DEFAULT_REGISTRY, _) => format!("{namespace}/{model}:{tag}"),
+            _ => format!("{registry}/{namespace}/{model}:{tag}"),
+        }
+    }
+
+    /// Find `OLLAMA_HOST` in the user's systemd unit for Ollama or its
+    /// drop-ins, set as `Environment="OLLAMA_HOST=..."`.
+    fn systemd_host(home_dir: &Path) -> Option<String> {
+        let unit_dir = home_dir.join(".config").join("systemd").join("user");
+        let mut units = vec![unit_dir.join("ollama.service")];
+        if let Ok(drop_ins) = std::fs::read_dir(unit_dir.join("ollama.service.d")) {
+            let mut drop_ins: Vec<PathBuf> = drop_ins
+                .flatten()
+                .map(|entry| entry.path())
+                .filter(|path| path.extension().is_some_and(|ext| ext == "conf"))
+                .collect();
+            drop_ins.sort();
+            units.extend(drop_ins);
+        }
+
+        // Later drop-ins override earlier settings, as systemd applies them
+        units
+            .iter()
+            .filter_map(|unit| std::fs::read_to_string(unit).ok())
+            .filter_map(|content| Self::unit_host(&content))
+            .last()
+    }
+
+    /// Read `OLLAMA_HOST` from the `Environment=` lines of a systemd unit.
+    fn unit_host(content: &str) -> Option<String> {
+        content
+            .lines()
+            .filter_map(|line| line.trim().strip_prefix("Environment="))
+            .flat_map(|assignments| assignments.split_whitespace())
+            .filter_map(|assignment| {
+                assignment
+                    .trim_matches('"')
+                    .strip_prefix("OLLAMA_HOST=")
+                    .map(|host| host.trim_matches('"').to_string())
+            })
+            .last()
+    }
+
+    fn subdirectories(dir: &Path) -> Vec<PathBuf> {
+        std::fs::read_dir(dir)
+            .map(|entries| {
+                entries
+                    .flatten()
+                    .map(|entry| entry.path())
+                    .filter(|path| path.is_dir())
+                    .collect()
+            })
+            .unwrap_or_default()
+    }
+
+    fn file_name(path: &Path) -> String {
+        path.file_name()
+            .unwrap_or_default()
+            .to_string_lossy()
+            .to_string()
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("ollama_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn pull(models_dir: &Path, registry: &str, namespace: &str, model: &str, tag: &str) {
+        let dir = models_dir
+            .join("manifests")
+            .join(registry)
+            .join(namespace)
+            .join(model);
+        std::fs::create_dir_all(&dir).unwrap();
+        std::fs::write(dir.join(tag), r#"{"schemaVersion": 2, "layers": []}"#).unwrap();
+    }
+
+    #[test]
+    fn test_ollama_scanner_name() {
+        let scanner = OllamaScanner;
+        assert_eq!(scanner.name(), "ollama");
+        assert_eq!(scanner.app_name(), "Ollama");
+    }
+
+    #[test]
+    fn test_discover_pulled_models() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let models_dir = temp_dir.path().join(".ollama").join("models");
+        pull(
+            &models_dir,
+            DEFAULT_REGISTRY,
+            DEFAULT_NAMESPACE,
+            "llama3.2",
+            "3b",
+        );
+        pull(
+            &models_dir,
+            DEFAULT_REGISTRY,
+            DEFAULT_NAMESPACE,
+            "llama3.2",
+            "latest",
+        );
+        pull(&models_dir, DEFAULT_REGISTRY, "sam860", "qwen3", "4b");
+        pull(
+            &models_dir,
+            "hf.co",
+            "bartowski",
+            "Llama-3.2-1B-GGUF",
+            "Q4_K_M",
+        );
+
+        let instance = OllamaScanner::discover(&models_dir, None).unwrap();
+        assert_eq!(instance.app_name, "ollama");
+        assert_eq!(
+            instance.metadata.get("base_url").unwrap(),
+            "http://127.0.0.1:11434"
+        );
+        assert_eq!(instance.metadata.get("model_count").unwrap(), "4");
+
+        let providers = instance.provider_instances();
+        assert_eq!(providers.len(), 1);
+        assert_eq!(providers[0].provider_type, "ollama");
+        assert_eq!(
+            providers[0].models,
+            vec![
+                "hf.co/bartowski/Llama-3.2-1B-GGUF:Q4_K_M",
+                "llama3.2:3b",
+                "llama3.2:latest",
+                "sam860/qwen3:4b",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_discover_custom_host() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let models_dir = temp_dir.path().join("models");
+        pull(
+            &models_dir,
+            DEFAULT_REGISTRY,
+            DEFAULT_NAMESPACE,
+            "gemma3",
+            "1b",
+        );
+
+        let instance = OllamaScanner::discover(&models_dir, Some("0.0.0.0:11500")).unwrap();
+        let providers = instance.provider_instances();
+        assert_eq!(providers[0].base_url, "http://0.0.0.0:11500");
+        assert!(
+            OllamaPlugin::validate_instance_for_host(providers[0], Some("0.0.0.0:11500")).is_ok()
+        );
+    }
+
+    #[test]
+    fn test_discover_nothing_installed() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        assert!(OllamaScanner::discover(&temp_dir.path().join("models"), None).is_none());
+    }
+
+    #[test]
+    fn test_systemd_host() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let drop_ins = temp_dir
+            .path()
+            .join(".config")
+            .join("systemd")
+            .join("user")
+            .join("ollama.service.d");
+        std::fs::create_dir_all(&drop_ins).unwrap();
+        std::fs::write(
+            drop_ins.join("10-host.conf"),
+            "[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\"\n",
+        )
+        .unwrap();
+        std::fs::write(
+            drop_ins.join("20-override.conf"),
+            "[Service]\nEnvironment=\"OLLAMA_KEEP_ALIVE=1h\" \"OLLAMA_HOST=gpu-box.lan:8080\"\n",
+        )
+        .unwrap();
+
+        assert_eq!(
+            OllamaScanner::systemd_host(temp_dir.path()).as_deref(),
+            Some("gpu-box.lan:8080")
+        );
+    }
+}
@@ -0,0 +1,257 @@
+//! Raycast scanner for discovering API keys in Raycast AI configuration files.
+
+use super::{extract_json_api_keys, ScanResult, ScannerPlugin, ScannerPluginExt};
+use crate::error::Result;
+use crate::models::ConfigInstance;
+use std::path::{Path, PathBuf};
+
+/// Scanner for Raycast AI custom provider and extension configuration.
+///
+/// Raycast keeps "bring your own key" providers in `~/.config/raycast/ai/providers.yaml`
+/// and lets extensions keep JSON or YAML settings alongside it. Preferences
+/// entered in Raycast's own UI live in its encrypted database and are not read.
+pub struct RaycastScanner;
+
+impl ScannerPlugin for RaycastScanner {
+    fn name(&self) -> &'static str {
+        "raycast"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "Raycast"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        let config_dir = home_dir.join(".config").join("raycast");
+        let mut paths = vec![
+            config_dir.join("ai").join("providers.yaml"),
+            config_dir.join("ai").join("providers.yml"),
+            config_dir.join("config.json"),
+        ];
+
+        // Settings kept by installed and locally developed extensions
+        if let Ok(entries) = std::fs::read_dir(config_dir.join("extensions")) {
+            for entry in entries.flatten() {
+                for file_name in ["config.json", "preferences.json", "settings.json"] {
+                    paths.push(entry.path().join(file_name));
+                }
+            }
+        }
+
+        paths
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        let file_name = path.file_name().unwrap_or_default().to_string_lossy();
+        let path_str = path.to_string_lossy();
+
+        (path_str.contains("/raycast/") || path_str.contains("com.raycast.macos"))
+            && (file_name.ends_with(".json")
+                || file_name.ends_with(".yaml")
+                || file_name.ends_with(".yml"))
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let is_yaml = path
+            .extension()
+            .is_some_and(|ext| ext == "yaml" || ext == "yml");
+        let json_value = if is_yaml {
+            let Ok(yaml_value) = serde_yaml::from_str::<serde_yaml::Value>(content) else {
+                return Ok(result);
+            };
+            let Ok(json_value) = serde_json::to_value(yaml_value) else {
+                return Ok(result);
+            };
+            json_value
+        } else {
+            let Ok(json_value) = serde_json::from_str::<serde_json::Value>(content) else {
+                return Ok(result);
+            };
+            json_value
+        };
+
+        let keys = extract_json_api_keys(&json_value, path, "raycast");
+        if keys.is_empty() {
+            return Ok(result);
+        }
+
+        let mut instance = Self::create_config_instance(path, &json_value);
+        match self.build_instances_from_keys(&keys, &path.display().to_string(), None) {
+            Ok(provider_instances) => {
+                for provider_instance in provider_instances {
+                    if let Err(e) = instance.add_provider_instance(provider_instance) {
+                        tracing::warn!("Failed to add provider instance to config: {}", e);
+                    }
+                }
+            }
+            Err(e) => tracing::warn!("Failed to build provider instances from keys: {}", e),
+        }
+
+        result.add_keys(keys);
+        result.add_instance(instance);
+        Ok(result)
+    }
+}
+
+impl RaycastScanner {
+    /// Create a config instance from Raycast configuration.
+    fn create_config_instance(path: &Path, json_value: &serde_json::Value) -> ConfigInstance {
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "raycast".to_string(),
+            path.to_path_buf(),
+        );
+
+        // The extension a settings file belongs to is its directory name
+        if let Some(extension) = path
+            .parent()
+            .filter(|dir| dir.parent().is_some_and(|p| p.ends_with("extensions")))
+            .and_then(Path::file_name)
+        {
+            instance.metadata.insert(
+                "extension".to_string(),
+                extension.to_string_lossy().to_string(),
+            );
+        }
+
+        if let Some(providers) = json_value.get("providers").and_then(|v| v.as_array()) {
+            let names: Vec<&str> = providers
+                .iter()
+                .filter_map(|p| p.get("id").and_then(|v| v.as_str()))
+                .collect();
+            if !names.is_empty() {
+                instance
+                    .metadata
+                    .insert("providers".to_string(), names.join(", "));
+            }
+        }
+
+        instance
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("raycast_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::models::credentials::{Confidence, ValueType};
+
+    #[test]
+    fn test_raycast_scanner_name() {
+        let scanner = RaycastScanner;
+        assert_eq!(scanner.name(), "raycast");
+        assert_eq!(scanner.app_name(), "Raycast");
+    }
+
+    #[test]
+    fn test_scan_paths_include_extensions() {
+        let scanner = RaycastScanner;
+        let temp_dir = tempfile::tempdir().unwrap();
+        let extension = temp_dir
+            .path()
+            .join(".config")
+            .join("raycast")
+            .join("extensions")
+            .join("chatgpt");
+        std::fs::create_dir_all(&extension).unwrap();
+
+        let paths = scanner.scan_paths(temp_dir.path());
+        assert!(paths.iter().any(|p| p.ends_with("ai/providers.yaml")));
+        assert!(paths.contains(&extension.join("preferences.json")));
+    }
+
+    #[test]
+    fn test_can_handle_file() {
+        let scanner = RaycastScanner;
+        assert!(scanner.can_handle_file(Path::new("/home/user/.config/raycast/ai/providers.yaml")));
+        assert!(scanner.can_handle_file(Path::new(
+            "/home/user/.config/raycast/extensions/chatgpt/preferences.json"
+        )));
+        assert!(!scanner.can_handle_file(Path::new("/random/providers.yaml")));
+    }
+
+    #[test]
+    fn test_parse_providers_yaml() {
+        let scanner = RaycastScanner;
+        let config = r"
+providers:
+  - id: anthropic-direct
+    name: Anthropic
+    base_url: https://api.anthropic.com/v1
+    api_keys:
+      anthropic: sk-ant-REDACTED
+    models:
+      - id: claude-sonnet
+  - id: openai
+    name: OpenAI
+    api_keys:
+      openai: sk-proj-raycasttest1234567890
+";
+
+        let result = scanner
+            .parse_config(
+                Path::new("/home/user/.config/raycast/ai/providers.yaml"),
+                config,
+            )
+            .unwrap();
+        assert_eq!(result.keys.len(), 2);
+        assert_eq!(result.keys[0].provider, "anthropic");
+        assert_eq!(result.keys[1].provider, "openai");
+        assert!(result
+            .keys
+            .iter()
+            .all(|k| k.value_type == ValueType::ApiKey && k.confidence == Confidence::High));
+
+        assert_eq!(result.instances.len(), 1);
+        assert_eq!(result.instances[0].app_name, "raycast");
+        assert_eq!(
+            result.instances[0].metadata.get("providers").unwrap(),
+            "anthropic-direct, openai"
+        );
+        assert_eq!(result.instances[0].provider_instances.len(), 2);
+    }
+
+    #[test]
+    fn test_parse_extension_preferences() {
+        let scanner = RaycastScanner;
+        let config = r#"{"apiKey": "sk-proj-raycasttest1234567890", "model": "gpt-4o"}"#;
+
+        let result = scanner
+            .parse_config(
+                Path::new("/home/user/.config/raycast/extensions/chatgpt/preferences.json"),
+                config,
+            )
+            .unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "openai");
+        assert_eq!(
+            result.instances[0].metadata.get("extension").unwrap(),
+            "chatgpt"
+        );
+    }
+
+    #[test]
+    fn test_parse_config_without_keys() {
+        let scanner = RaycastScanner;
+        let result = scanner
+            .parse_config(
+                Path::new("/home/user/.config/raycast/config.json"),
+                r#"{"theme": "dark"}"#,
+            )
+            .unwrap();
+        assert!(result.keys.is_empty());
+        assert!(result.instances.is_empty());
+    }
+}
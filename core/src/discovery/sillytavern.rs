@@ -0,0 +1,267 @@
+//! `SillyTavern` scanner for discovering API keys in `SillyTavern` secrets files.
+
+use super::{infer_provider, key_confidence, ScanResult, ScannerPlugin, ScannerPluginExt};
+use crate::error::Result;
+use crate::models::credentials::{DiscoveredCredential, ValueType};
+use crate::models::ConfigInstance;
+use std::path::{Path, PathBuf};
+
+/// Directories `SillyTavern` is commonly cloned or installed to, relative to
+/// the home directory.
+const INSTALL_DIRS: &[&str] = &[
+    "SillyTavern",
+    "sillytavern",
+    "SillyTavern-Launcher/SillyTavern",
+    "Documents/SillyTavern",
+    ".local/share/SillyTavern",
+];
+
+/// `SillyTavern` secret names whose suffix does not name the provider.
+const SECRET_PROVIDERS: &[(&str, &str)] = &[
+    ("makersuite", "google"),
+    ("vertexai", "google"),
+    ("mistralai", "mistral"),
+];
+
+/// Scanner for `SillyTavern` chat frontend secrets.
+///
+/// `SillyTavern` keeps the keys of every connected API in `secrets.json`,
+/// under names such as `api_key_openai`. Since 1.12.6 each user has one in
+/// `data/<user>/`; older installs keep a single file at the install root.
+pub struct SillyTavernScanner;
+
+impl ScannerPlugin for SillyTavernScanner {
+    fn name(&self) -> &'static str {
+        "sillytavern"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "SillyTavern"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        let mut paths = Vec::new();
+        let mut seen = Vec::new();
+        for install_dir in INSTALL_DIRS {
+            let install_dir = home_dir.join(install_dir);
+            // SillyTavern and sillytavern are one directory on macOS
+            if let Ok(canonical) = install_dir.canonicalize() {
+                if seen.contains(&canonical) {
+                    continue;
+                }
+                seen.push(canonical);
+            }
+            paths.push(install_dir.join("secrets.json"));
+
+            // One data directory per user, default-user for single-user installs
+            if let Ok(users) = std::fs::read_dir(install_dir.join("data")) {
+                for user in users.flatten() {
+                    paths.push(user.path().join("secrets.json"));
+                }
+            }
+        }
+        paths
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        path.file_name().is_some_and(|name| name == "secrets.json")
+            && path
+                .to_string_lossy()
+                .to_lowercase()
+                .contains("sillytavern")
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let Ok(json_value) = serde_json::from_str::<serde_json::Value>(content) else {
+            return Ok(result);
+        };
+        let Some(secrets) = json_value.as_object() else {
+            return Ok(result);
+        };
+
+        let mut keys = Vec::new();
+        for (name, secret) in secrets {
+            let Some(source) = name.strip_prefix("api_key_") else {
+                continue;
+            };
+            for value in Self::secret_values(secret) {
+                if value.len() < 15 || value.chars().any(char::is_whitespace) {
+                    continue;
+                }
+                keys.push(DiscoveredCredential::new(
+                    Self::provider_for(source, value),
+                    path.display().to_string(),
+                    ValueType::ApiKey,
+                    key_confidence(value),
+                    value.to_string(),
+                ));
+            }
+        }
+        if keys.is_empty() {
+            return Ok(result);
+        }
+
+        let mut instance = Self::create_config_instance(path);
+        match self.build_instances_from_keys(&keys, &path.display().to_string(), None) {
+            Ok(provider_instances) => {
+                for provider_instance in provider_instances {
+                    if let Err(e) = instance.add_provider_instance(provider_instance) {
+                        tracing::warn!("Failed to add provider instance to config: {}", e);
+                    }
+                }
+            }
+            Err(e) => tracing::warn!("Failed to build provider instances from keys: {}", e),
+        }
+
+        result.add_keys(keys);
+        result.add_instance(instance);
+        Ok(result)
+    }
+}
+
+impl SillyTavernScanner {
+    /// The values of one secret: a plain string in older releases, or a list
+    /// of `{"id", "value", "label", "active"}` entries since several keys
+    /// per API are allowed.
+    fn secret_values(secret: &serde_json::Value) -> Vec<&str> {
+        match secret {
+            serde_json::Value::String(value) => vec![value.trim()],
+            serde_json::Value::Array(entries) => entries
+                .iter()
+                .filter_map(|entry| entry.get("value").and_then(|v| v.as_str()))
+                .map(str::trim)
+                .collect(),
+            _ => Vec::new(),
+        }
+    }
+
+    /// Map the API a secret is named after (e.g., `claude` in `api_key_claude`)
+    /// to a provider.
+    fn provider_for(source: &str, value: &str) -> String {
+        SECRET_PROVIDERS
+            .iter()
+            .find(|(name, _)| *name == source)
+            .map(|(_, provider)| *provider)
+            .or_else(|| infer_provider(source, value))
+            .unwrap_or(source)
+            .to_string()
+    }
+
+    /// Create a config instance for one user's secrets.
+    fn create_config_instance(path: &Path) -> ConfigInstance {
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "sillytavern".to_string(),
+            path.to_path_buf(),
+        );
+
+        // data/<user>/secrets.json belongs to <user>
+        if let Some(user) = path
+            .parent()
+            .filter(|dir| dir.parent().is_some_and(|p| p.ends_with("data")))
+            .and_then(Path::file_name)
+        {
+            instance
+                .metadata
+                .insert("user".to_string(), user.to_string_lossy().to_string());
+        }
+
+        instance
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("sillytavern_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::models::credentials::Confidence;
+
+    #[test]
+    fn test_sillytavern_scanner_name() {
+        let scanner = SillyTavernScanner;
+        assert_eq!(scanner.name(), "sillytavern");
+        assert_eq!(scanner.app_name(), "SillyTavern");
+    }
+
+    #[test]
+    fn test_scan_paths_include_user_data() {
+        let scanner = SillyTavernScanner;
+        let temp_dir = tempfile::tempdir().unwrap();
+        let user_dir = temp_dir
+            .path()
+            .join("SillyTavern")
+            .join("data")
+            .join("default-user");
+        std::fs::create_dir_all(&user_dir).unwrap();
+
+        let paths = scanner.scan_paths(temp_dir.path());
+        assert!(paths.contains(&temp_dir.path().join("SillyTavern").join("secrets.json")));
+        assert!(paths.contains(&user_dir.join("secrets.json")));
+    }
+
+    #[test]
+    fn test_can_handle_file() {
+        let scanner = SillyTavernScanner;
+        assert!(scanner.can_handle_file(Path::new(
+            "/home/user/SillyTavern/data/default-user/secrets.json"
+        )));
+        assert!(!scanner.can_handle_file(Path::new("/home/user/app/secrets.json")));
+    }
+
+    #[test]
+    fn test_parse_secrets() {
+        let scanner = SillyTavernScanner;
+        let secrets = r#"{
+            "api_key_openai": "sk-proj-tavern1234567890abcdef",
+            "api_key_claude": [
+                {"id": "1", "value": "sk-ant-REDACTED", "label": "main", "active": true},
+                {"id": "2", "value": "sk-ant-REDACTED", "label": "backup", "active": false}
+            ],
+            "api_key_makersuite": "AIzaSyTavern1234567890abcdef",
+            "api_key_mistralai": "tavernmistral1234567890",
+            "api_url_custom": "http://localhost:5000/v1"
+        }"#;
+        let path = Path::new("/home/user/SillyTavern/data/default-user/secrets.json");
+
+        let result = scanner.parse_config(path, secrets).unwrap();
+        let mut providers: Vec<&str> = result.keys.iter().map(|k| k.provider.as_str()).collect();
+        providers.sort_unstable();
+        assert_eq!(
+            providers,
+            vec!["anthropic", "anthropic", "google", "mistral", "openai"]
+        );
+        let mistral = result
+            .keys
+            .iter()
+            .find(|k| k.provider == "mistral")
+            .unwrap();
+        assert_eq!(mistral.confidence, Confidence::Low);
+
+        let instance = &result.instances[0];
+        assert_eq!(instance.app_name, "sillytavern");
+        assert_eq!(instance.metadata.get("user").unwrap(), "default-user");
+    }
+
+    #[test]
+    fn test_parse_secrets_without_keys() {
+        let scanner = SillyTavernScanner;
+        let path = Path::new("/home/user/SillyTavern/secrets.json");
+        let result = scanner
+            .parse_config(path, r#"{"api_key_openai": ""}"#)
+            .unwrap();
+        assert!(result.keys.is_empty());
+        assert!(result.instances.is_empty());
+    }
+}
@@ -0,0 +1,234 @@
+//! vLLM scanner for discovering local vLLM servers from their serve configs.
+
+use super::{key_confidence, ScanResult, ScannerPlugin};
+use crate::error::Result;
+use crate::models::credentials::{DiscoveredCredential, ValueType};
+use crate::models::{ConfigInstance, ProviderInstance};
+use std::path::{Path, PathBuf};
+
+/// The endpoint `vllm serve` listens on by default.
+const DEFAULT_HOST: &str = "localhost";
+const DEFAULT_PORT: u64 = 8000;
+
+/// Scanner for vLLM servers.
+///
+/// `vllm serve --config <file>` reads its arguments from YAML, which is
+/// conventionally kept in `~/.config/vllm/`. Each file describes one server:
+/// the model it serves, the names clients request it by, where it listens
+/// and the API key clients must send.
+pub struct VllmScanner;
+
+impl ScannerPlugin for VllmScanner {
+    fn name(&self) -> &'static str {
+        "vllm"
+    }
+
+    fn app_name(&self) -> &'static str {
+        "vLLM"
+    }
+
+    fn scan_paths(&self, home_dir: &Path) -> Vec<PathBuf> {
+        let Ok(entries) = std::fs::read_dir(home_dir.join(".config").join("vllm")) else {
+            return Vec::new();
+        };
+        let mut paths: Vec<PathBuf> = entries
+            .flatten()
+            .map(|entry| entry.path())
+            .filter(|path| {
+                path.extension()
+                    .is_some_and(|ext| ext == "yaml" || ext == "yml")
+            })
+            .collect();
+        paths.sort();
+        paths
+    }
+
+    fn can_handle_file(&self, path: &Path) -> bool {
+        path.to_string_lossy().contains("/vllm/")
+            && path
+                .extension()
+                .is_some_and(|ext| ext == "yaml" || ext == "yml")
+    }
+
+    fn parse_config(&self, path: &Path, content: &str) -> Result<ScanResult> {
+        let mut result = ScanResult::new();
+
+        let Ok(config) = serde_yaml::from_str::<serde_yaml::Value>(content) else {
+            return Ok(result);
+        };
+        let Some(model) = Self::setting(&config, "model").and_then(|v| v.as_str()) else {
+            return Ok(result);
+        };
+
+        // Clients request the model by its served names, or by its path
+        let served_names: Vec<String> = match Self::setting(&config, "served-model-name") {
+            Some(serde_yaml::Value::String(name)) => vec![name.clone()],
+            Some(serde_yaml::Value::Sequence(names)) => names
+                .iter()
+                .filter_map(|name| name.as_str())
+                .map(str::to_string)
+                .collect(),
+            _ => Vec::new(),
+        };
+        let models = if served_names.is_empty() {
+            vec![model.to_string()]
+        } else {
+            served_names
+        };
+
+        let host = Self::setting(&config, "host")
+            .and_then(|v| v.as_str())
+            .unwrap_or(DEFAULT_HOST);
+        let port = Self::setting(&config, "port")
+            .and_then(serde_yaml::Value::as_u64)
+            .unwrap_or(DEFAULT_PORT);
+        let base_url = format!("http://{host}:{port}/v1");
+        let api_key = Self::setting(&config, "api-key")
+            .and_then(|v| v.as_str())
+            .map(str::trim)
+            .filter(|key| !key.is_empty() && !key.starts_with('$'))
+            .unwrap_or_default();
+
+        let mut instance = ConfigInstance::new(
+            Self::generate_instance_id(path),
+            "vllm".to_string(),
+            path.to_path_buf(),
+        );
+        instance
+            .metadata
+            .insert("model".to_string(), model.to_string());
+        instance
+            .metadata
+            .insert("base_url".to_string(), base_url.clone());
+
+        let provider_instance = ProviderInstance::new(
+            Self::generate_instance_id(Path::new(&base_url)),
+            "vllm".to_string(),
+            base_url,
+            api_key.to_string(),
+            models,
+        );
+        if let Err(e) = instance.add_provider_instance(provider_instance) {
+            tracing::warn!("Failed to add provider instance to config: {}", e);
+        }
+
+        if !api_key.is_empty() {
+            result.add_key(DiscoveredCredential::new(
+                "vllm".to_string(),
+                path.display().to_string(),
+                ValueType::ApiKey,
+                key_confidence(api_key),
+                api_key.to_string(),
+            ));
+        }
+        result.add_instance(instance);
+        Ok(result)
+    }
+}
+
+impl VllmScanner {
+    /// Look up a serve argument, which vLLM accepts with dashes or
+    /// underscores.
+    fn setting<'a>(config: &'a serde_yaml::Value, name: &str) -> Option<&'a serde_yaml::Value> {
+        config
+            .get(name)
+            .or_else(|| config.get(name.replace('-', "_")))
+    }
+
+    /// Generate a unique instance ID.
+    fn generate_instance_id(path: &Path) -> String {
+        use sha2::{Digest, Sha256};
+        let mut hasher = Sha256::new();
+        hasher.update(path.to_string_lossy().as_bytes());
+        format!("vllm_{:x}", hasher.finalize())
+            .chars()
+            .take(16)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::models::credentials::Confidence;
+
+    #[test]
+    fn test_vllm_scanner_name() {
+        let scanner = VllmScanner;
+        assert_eq!(scanner.name(), "vllm");
+        assert_eq!(scanner.app_name(), "vLLM");
+    }
+
+    #[test]
+    fn test_scan_paths() {
+        let scanner = VllmScanner;
+        let temp_dir = tempfile::tempdir().unwrap();
+        let config_dir = temp_dir.path().join(".config").join("vllm");
+        std::fs::create_dir_all(&config_dir).unwrap();
+        std::fs::write(config_dir.join("qwen.yaml"), "").unwrap();
+        std::fs::write(config_dir.join("notes.txt"), "").unwrap();
+
+        assert_eq!(
+            scanner.scan_paths(temp_dir.path()),
+            vec![config_dir.join("qwen.yaml")]
+        );
+    }
+
+    #[test]
+    fn test_parse_serve_config() {
+        let scanner = VllmScanner;
+        let config = r"
+model: Qwen/Qwen2.5-7B-Instruct
+served-model-name:
+  - qwen
+  - qwen2.5
+host: 0.0.0.0
+port: 8001
+api-key: vllmtoken1234567890abcdef
+tensor-parallel-size: 2
+";
+        let path = Path::new("/home/user/.config/vllm/qwen.yaml");
+
+        let result = scanner.parse_config(path, config).unwrap();
+        assert_eq!(result.keys.len(), 1);
+        assert_eq!(result.keys[0].provider, "vllm");
+        assert_eq!(result.keys[0].value_type, ValueType::ApiKey);
+        assert_eq!(result.keys[0].confidence, Confidence::Low);
+
+        let instance = &result.instances[0];
+        assert_eq!(instance.app_name, "vllm");
+        assert_eq!(
+            instance.metadata.get("model").unwrap(),
+            "Qwen/Qwen2.5-7B-Instruct"
+        );
+        let providers = instance.provider_instances();
+        assert_eq!(providers[0].provider_type, "vllm");
+        assert_eq!(providers[0].base_url, "http://0.0.0.0:8001/v1");
+        assert_eq!(providers[0].models, vec!["qwen", "qwen2.5"]);
+    }
+
+    #[test]
+    fn test_parse_config_defaults() {
+        let scanner = VllmScanner;
+        let path = Path::new("/home/user/.config/vllm/llama.yml");
+
+        let result = scanner
+            .parse_config(path, "model: meta-llama/Llama-3.1-8B-Instruct\n")
+            .unwrap();
+        assert!(result.keys.is_empty());
+        let providers = result.instances[0].provider_instances();
+        assert_eq!(providers[0].base_url, "http://localhost:8000/v1");
+        assert_eq!(
+            providers[0].models,
+            vec!["meta-llama/Llama-3.1-8B-Instruct"]
+        );
+    }
+
+    #[test]
+    fn test_parse_config_without_model() {
+        let scanner = VllmScanner;
+        let path = Path::new("/home/user/.config/vllm/empty.yaml");
+        let result = scanner.parse_config(path, "port: 8000\n").unwrap();
+        assert!(result.instances.is_empty());
+    }
+}
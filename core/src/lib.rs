@@ -117,6 +117,7 @@ pub use models::{
     Confidence,
     // Config
     ConfigInstance,
+    CredentialLocation,
     CredentialValue,
     // Credentials & Discovery
     DiscoveredCredential,
@@ -124,6 +125,7 @@ pub use models::{
     // Labels
     Label,
     LabelAssignment,
+    LabelHierarchy,
     LabelTarget,
     LabelWithAssignments,
     // Models
@@ -136,10 +138,17 @@ pub use models::{
     ProviderCollection,
     ProviderInstance,
     RateLimit,
+    RuleField,
+    RuleOp,
     // Scan
     ScanResult,
+    ScanStats,
     ScanSummary,
+    ScanWarning,
+    ScanWarningKind,
+    TagRule,
     TokenCost,
+    UniquenessScope,
     ValidationStatus,
     ValueType,
 };
@@ -190,6 +199,8 @@ pub struct ScanOptions {
     pub probe_models: bool,
     /// Timeout for model probing in seconds (default: 30).
     pub probe_timeout_secs: u64,
+    /// Number of scanners run in parallel; 0 uses one per available CPU (default: 0).
+    pub concurrency: usize,
 }
 
 impl Default for ScanOptions {
@@ -202,6 +213,7 @@ impl Default for ScanOptions {
             exclude_providers: None,
             probe_models: false,
             probe_timeout_secs: 30,
+            concurrency: 0,
         }
     }
 }
@@ -248,6 +260,13 @@ impl ScanOptions {
         self
     }
 
+    /// Sets how many scanners run in parallel (0 for one per available CPU).
+    #[must_use]
+    pub const fn with_concurrency(mut self, concurrency: usize) -> Self {
+        self.concurrency = concurrency;
+        self
+    }
+
     /// Gets the effective home directory (either provided or user's home).
     ///
     /// # Errors
@@ -274,6 +293,8 @@ impl ScanOptions {
 /// 4. Parses found files and extracts API keys using both providers and scanners
 /// 5. Returns the results
 ///
+/// It collects what [`scan_incremental`] hands over into one result.
+///
 /// # Arguments
 ///
 /// * `options` - Configuration options for the scan operation
@@ -285,8 +306,38 @@ impl ScanOptions {
 /// # Errors
 ///
 /// Returns an error if the scan fails due to IO errors, invalid configuration, etc.
-#[allow(clippy::too_many_lines)]
 pub fn scan(options: &ScanOptions) -> Result<ScanResult> {
+    let mut keys = Vec::new();
+    let mut config_instances = Vec::new();
+    let mut result = scan_incremental(options, |_, batch_keys, batch_instances| {
+        keys.extend(batch_keys);
+        config_instances.extend(batch_instances);
+        true
+    })?;
+    result.keys = keys;
+    result.config_instances = config_instances;
+    Ok(result)
+}
+
+/// Scans like [`scan`], handing each scanner's findings to `on_batch` as soon
+/// as they are ready instead of collecting them, so a caller can pass them on
+/// without holding the whole result.
+///
+/// Batches arrive in the registry's scanner order, already deduplicated,
+/// probed and redacted as [`scan`] would leave them. `on_batch` also receives
+/// the result so far, with the home directory, providers and start time but
+/// no findings, stats or warnings; returning `false` stops the scan after the
+/// scanners already running. The returned result holds the stats, warnings
+/// and metadata but no keys or config instances.
+///
+/// # Errors
+///
+/// Returns an error if the scan fails due to IO errors, invalid configuration, etc.
+#[allow(clippy::too_many_lines)]
+pub fn scan_incremental<F>(options: &ScanOptions, mut on_batch: F) -> Result<ScanResult>
+where
+    F: FnMut(&ScanResult, Vec<DiscoveredCredential>, Vec<ConfigInstance>) -> bool,
+{
     // Get the home directory to scan
     let home_dir = options.get_home_dir()?;
 
@@ -313,81 +364,110 @@ pub fn scan(options: &ScanOptions) -> Result<ScanResult> {
         scan_started_at,
     );
 
-    // Run targeted scanner-specific scanning only
-    let scanner_results = scan_with_scanners(
+    // Keys are unique by hash and config instances by instance_id across
+    // all batches
+    let mut seen_keys = std::collections::HashSet::new();
+    let mut seen_instances = std::collections::HashSet::new();
+    let mut probe_stats = ProbeStatistics {
+        total_instances: 0,
+        probed_successfully: 0,
+        probe_failures: 0,
+        total_models_discovered: 0,
+    };
+
+    // Run targeted scanner-specific scanning only, validating and passing
+    // on each scanner's keys as it finishes
+    let header = &result;
+    let mut report = scan_with_scanners(
         &filtered_scanner_registry,
         &filtered_provider_registry,
         &home_dir,
-    );
-
-    // Process scanner results and validate keys with provider plugins
-    // Use a HashSet to track unique config instances by instance_id
-    let mut seen_instances = std::collections::HashSet::new();
+        options.max_file_size,
+        options.concurrency,
+        &mut |scanner_name: &str, mut scan_result: scanners::ScanResult| {
+            debug!(
+                "Processing {} keys from scanner: {}",
+                scan_result.keys.len(),
+                scanner_name
+            );
 
-    for (scanner_name, mut scan_result) in scanner_results {
-        debug!(
-            "Processing {} keys from scanner: {}",
-            scan_result.keys.len(),
-            scanner_name
-        );
+            // Validate discovered keys using provider plugins for confidence scoring
+            for key in &mut scan_result.keys {
+                if let Some(plugin) = filtered_provider_registry.get(&key.provider) {
+                    // Use provider plugin to validate and score the key
+                    if let Some(full_value) = key.full_value() {
+                        let confidence_score = plugin.confidence_score(full_value);
+                        // For now, we validate but don't modify the key structure
+                        // The scanner has already determined the confidence
+                        debug!(
+                            "Validated key from {} with confidence {} (hash: {})",
+                            key.provider,
+                            confidence_score,
+                            &key.hash[..8]
+                        );
+                    }
+                }
+            }
 
-        // Validate discovered keys using provider plugins for confidence scoring
-        for key in &mut scan_result.keys {
-            if let Some(plugin) = filtered_provider_registry.get(&key.provider) {
-                // Use provider plugin to validate and score the key
-                if let Some(full_value) = key.full_value() {
-                    let confidence_score = plugin.confidence_score(full_value);
-                    // For now, we validate but don't modify the key structure
-                    // The scanner has already determined the confidence
+            let mut keys = Vec::new();
+            for key in scan_result.keys {
+                if seen_keys.insert(key.hash.clone()) {
+                    keys.push(key);
+                } else {
                     debug!(
-                        "Validated key from {} with confidence {} (hash: {})",
+                        "Skipping duplicate key for provider: {} (hash: {})",
                         key.provider,
-                        confidence_score,
                         &key.hash[..8]
                     );
                 }
             }
-        }
+            debug!(
+                "Passing on {} keys from scanner {}",
+                keys.len(),
+                scanner_name
+            );
 
-        debug!(
-            "Adding {} keys from scanner {} to result",
-            scan_result.keys.len(),
-            scanner_name
-        );
-        let keys_before = result.keys.len();
-        result.add_keys(scan_result.keys);
-        debug!(
-            "Result now has {} keys (added {})",
-            result.keys.len(),
-            result.keys.len() - keys_before
-        );
+            // Add config instances with deduplication
+            let mut instances = Vec::new();
+            for instance in scan_result.instances {
+                if seen_instances.insert(instance.instance_id.clone()) {
+                    debug!(
+                        "Adding config instance: {} ({})",
+                        instance.app_name, instance.instance_id
+                    );
+                    instances.push(instance);
+                } else {
+                    debug!(
+                        "Skipping duplicate config instance: {} ({})",
+                        instance.app_name, instance.instance_id
+                    );
+                }
+            }
 
-        // Add config instances with deduplication
-        for instance in scan_result.instances {
-            if seen_instances.insert(instance.instance_id.clone()) {
-                debug!(
-                    "Adding config instance: {} ({})",
-                    instance.app_name, instance.instance_id
-                );
-                result.add_config_instance(instance);
-            } else {
-                debug!(
-                    "Skipping duplicate config instance: {} ({})",
-                    instance.app_name, instance.instance_id
+            // Probe provider instances for available models if requested
+            if options.probe_models && !instances.is_empty() {
+                debug!("Probing provider instances for available models...");
+                let stats = probe_provider_instances_async(
+                    &mut instances,
+                    &filtered_provider_registry,
+                    options.probe_timeout_secs,
                 );
+                probe_stats.total_instances += stats.total_instances;
+                probe_stats.probed_successfully += stats.probed_successfully;
+                probe_stats.probe_failures += stats.probe_failures;
+                probe_stats.total_models_discovered += stats.total_models_discovered;
             }
-        }
-    }
 
-    // Probe provider instances for available models if requested
-    if options.probe_models {
-        debug!("Probing provider instances for available models...");
-        let probe_stats = probe_provider_instances_async(
-            &mut result.config_instances,
-            &filtered_provider_registry,
-            options.probe_timeout_secs,
-        );
+            // Apply selective redaction if needed
+            if !options.include_full_values {
+                keys = redact_keys(keys);
+            }
 
+            on_batch(header, keys, instances)
+        },
+    );
+
+    if options.probe_models {
         debug!(
             "Probe complete: {}/{} instances probed successfully, {} models discovered",
             probe_stats.probed_successfully,
@@ -417,59 +497,69 @@ pub fn scan(options: &ScanOptions) -> Result<ScanResult> {
         );
     }
 
-    // Apply selective redaction if needed
-    // Always keep full values for non-sensitive fields like ModelId, but redact API keys
-    if !options.include_full_values {
-        let keys_before_redaction = result.keys.len();
-        result.keys = result
-            .keys
-            .into_iter()
-            .map(|key| {
-                // Keep full values for non-sensitive value types
-                use crate::models::credentials::ValueType as OldValueType;
-                let should_preserve = match &key.value_type {
-                    OldValueType::ModelId => {
-                        tracing::debug!("Preserving ModelId key: {}", key.redacted_value());
-                        true
-                    }
-                    OldValueType::Custom(name) if name == "ModelId" || name.contains("Model") => {
-                        tracing::debug!("Preserving custom Model key: {}", name);
-                        true
-                    }
-                    OldValueType::Custom(name) if name == "Temperature" || name == "BaseUrl" => {
-                        true
-                    }
-                    // Redact sensitive values like API keys
-                    _ => false,
-                };
-
-                if should_preserve {
-                    key
-                } else {
-                    tracing::trace!("Redacting key of type: {:?}", key.value_type);
-                    key.with_full_value(false)
-                }
-            })
-            .collect();
-
-        tracing::info!(
-            "Redaction complete: {} keys before, {} keys after ({} ModelId keys preserved)",
-            keys_before_redaction,
-            result.keys.len(),
-            result
-                .keys
-                .iter()
-                .filter(|k| matches!(k.value_type, ValueType::ModelId))
-                .count()
-        );
-    }
-
     // Set completion timestamp before returning
     result.set_completed();
+    report.stats.duration_ms = u64::try_from(
+        (result.scan_completed_at - result.scan_started_at)
+            .num_milliseconds()
+            .max(0),
+    )
+    .unwrap_or(0);
+    result.set_stats(
+        u32::try_from(report.stats.files_examined).unwrap_or(u32::MAX),
+        0,
+    );
+    result.stats = report.stats;
+    result.warnings = report.warnings;
 
     Ok(result)
 }
 
+/// Redacts the full values of sensitive keys.
+///
+/// Always keeps full values for non-sensitive fields like `ModelId`, but
+/// redacts API keys.
+fn redact_keys(keys: Vec<DiscoveredCredential>) -> Vec<DiscoveredCredential> {
+    let keys_before_redaction = keys.len();
+    let keys: Vec<DiscoveredCredential> = keys
+        .into_iter()
+        .map(|key| {
+            // Keep full values for non-sensitive value types
+            use crate::models::credentials::ValueType as OldValueType;
+            let should_preserve = match &key.value_type {
+                OldValueType::ModelId => {
+                    tracing::debug!("Preserving ModelId key: {}", key.redacted_value());
+                    true
+                }
+                OldValueType::Custom(name) if name == "ModelId" || name.contains("Model") => {
+                    tracing::debug!("Preserving custom Model key: {}", name);
+                    true
+                }
+                OldValueType::Custom(name) if name == "Temperature" || name == "BaseUrl" => true,
+                // Redact sensitive values like API keys
+                _ => false,
+            };
+
+            if should_preserve {
+                key
+            } else {
+                tracing::trace!("Redacting key of type: {:?}", key.value_type);
+                key.with_full_value(false)
+            }
+        })
+        .collect();
+
+    tracing::debug!(
+        "Redaction complete: {} keys before, {} keys after ({} ModelId keys preserved)",
+        keys_before_redaction,
+        keys.len(),
+        keys.iter()
+            .filter(|k| matches!(k.value_type, ValueType::ModelId))
+            .count()
+    );
+    keys
+}
+
 /// Creates a default plugin registry with built-in plugins.
 fn create_default_registry() -> ProviderRegistry {
     register_builtin_providers()
@@ -486,140 +576,329 @@ fn create_default_scanner_registry() -> Result<ScannerRegistry> {
 }
 
 /// Scans using application scanners to find config instances.
-#[allow(clippy::too_many_lines, clippy::cognitive_complexity)]
+///
+/// Scanners run on up to `concurrency` threads, or one per available CPU when
+/// it is 0. Each scanner's result is handed to `on_result` in the registry's
+/// scanner order either way; results that finish early wait for those before
+/// them. Once `on_result` returns `false`, no further scanners are started.
 fn scan_with_scanners(
     scanner_registry: &ScannerRegistry,
     plugin_registry: &ProviderRegistry,
     home_dir: &std::path::Path,
-) -> Vec<(String, scanners::ScanResult)> {
-    let mut results = Vec::new();
-
-    for scanner_name in scanner_registry.list() {
-        debug!("Running scanner: {}", scanner_name);
+    max_file_size: usize,
+    concurrency: usize,
+    on_result: &mut dyn FnMut(&str, scanners::ScanResult) -> bool,
+) -> ScannerReport {
+    let names = scanner_registry.list();
+    let workers = match concurrency {
+        0 => std::thread::available_parallelism().map_or(1, std::num::NonZeroUsize::get),
+        n => n,
+    }
+    .min(names.len());
+
+    let run = |name: &str| {
+        let started = std::time::Instant::now();
+        let (outcome, mut report) = run_scanner(
+            scanner_registry,
+            plugin_registry,
+            home_dir,
+            max_file_size,
+            name,
+        );
+        report.stats.duration_ms_by_scanner.insert(
+            name.to_string(),
+            u64::try_from(started.elapsed().as_millis()).unwrap_or(u64::MAX),
+        );
+        (outcome, report)
+    };
 
-        // Create scanner-specific instances to call _with_registry methods
-        let mut scan_result = scanners::ScanResult::new();
+    let mut report = ScannerReport::default();
+    let mut wanted = true;
+    let mut deliver =
+        |name: &str, (outcome, scanner_report): (Option<scanners::ScanResult>, ScannerReport)| {
+            report.stats.merge(scanner_report.stats);
+            report.warnings.extend(scanner_report.warnings);
+            if let Some(result) = outcome {
+                wanted = wanted && on_result(name, result);
+            }
+            wanted
+        };
 
-        match scanner_name.as_str() {
-            "claude-desktop" => {
-                let scanner = scanners::ClaudeDesktopScanner;
-                if let Ok(instances) =
-                    scanner.scan_instances_with_registry(home_dir, Some(plugin_registry))
-                {
-                    debug!(
-                        "Scanner {} found {} instances",
-                        scanner_name,
-                        instances.len()
-                    );
-                    for instance in instances {
-                        scan_result.add_instance(instance);
+    if workers <= 1 {
+        for name in &names {
+            if !deliver(name, run(name)) {
+                break;
+            }
+        }
+    } else {
+        // Workers take the next scanner index until none are left or the
+        // caller has stopped
+        let next = std::sync::atomic::AtomicUsize::new(0);
+        let stop = std::sync::atomic::AtomicBool::new(false);
+        let (tx, rx) = std::sync::mpsc::channel();
+        let (run, names_ref, next_ref, stop_ref) = (&run, &names, &next, &stop);
+        std::thread::scope(|scope| {
+            for _ in 0..workers {
+                let tx = tx.clone();
+                scope.spawn(move || loop {
+                    if stop_ref.load(std::sync::atomic::Ordering::Relaxed) {
+                        break;
+                    }
+                    let i = next_ref.fetch_add(1, std::sync::atomic::Ordering::Relaxed);
+                    let Some(name) = names_ref.get(i) else { break };
+                    if tx.send((i, run(name))).is_err() {
+                        break;
+                    }
+                });
+            }
+            drop(tx);
+
+            let mut pending = std::collections::BTreeMap::new();
+            let mut due = 0;
+            for (i, outcome) in rx {
+                pending.insert(i, outcome);
+                while let Some(outcome) = pending.remove(&due) {
+                    if !deliver(&names[due], outcome) {
+                        stop.store(true, std::sync::atomic::Ordering::Relaxed);
                     }
+                    due += 1;
                 }
+            }
+        });
+    }
+    report
+}
 
-                let app_paths = scanner.scan_paths(home_dir);
+/// Runs one application scanner, returning `None` if it found nothing,
+/// along with what it read and the problems it met.
+#[allow(clippy::too_many_lines, clippy::cognitive_complexity)]
+fn run_scanner(
+    scanner_registry: &ScannerRegistry,
+    plugin_registry: &ProviderRegistry,
+    home_dir: &std::path::Path,
+    max_file_size: usize,
+    scanner_name: &str,
+) -> (Option<scanners::ScanResult>, ScannerReport) {
+    debug!("Running scanner: {}", scanner_name);
+
+    // Create scanner-specific instances to call _with_registry methods
+    let mut scan_result = scanners::ScanResult::new();
+    let mut report = ScannerReport::default();
+
+    match scanner_name {
+        "claude-desktop" => {
+            let scanner = scanners::ClaudeDesktopScanner;
+            if let Some(instances) = parsed(
+                scanner.scan_instances_with_registry(home_dir, Some(plugin_registry)),
+                home_dir,
+                scanner_name,
+                &mut report,
+            ) {
                 debug!(
-                    "Scanner {} found {} app paths",
+                    "Scanner {} found {} instances",
                     scanner_name,
-                    app_paths.len()
+                    instances.len()
                 );
+                for instance in instances {
+                    scan_result.add_instance(instance);
+                }
+            }
 
-                let mut scanned_paths = std::collections::HashSet::new();
-                for path in app_paths {
-                    if path.exists() && scanned_paths.insert(path.clone()) {
-                        debug!("Scanner {} scanning path: {}", scanner_name, path.display());
-                        if let Ok(content) = std::fs::read_to_string(&path) {
-                            if let Ok(result) = scanner.parse_config_with_registry(
+            let app_paths = scanner.scan_paths(home_dir);
+            debug!(
+                "Scanner {} found {} app paths",
+                scanner_name,
+                app_paths.len()
+            );
+
+            let mut scanned_paths = std::collections::HashSet::new();
+            for path in app_paths {
+                if path.exists() && scanned_paths.insert(path.clone()) {
+                    debug!("Scanner {} scanning path: {}", scanner_name, path.display());
+                    if let Some(content) =
+                        read_candidate(&path, max_file_size, scanner_name, &mut report)
+                    {
+                        if let Some(result) = parsed(
+                            scanner.parse_config_with_registry(
                                 &path,
                                 &content,
                                 Some(plugin_registry),
-                            ) {
+                            ),
+                            &path,
+                            scanner_name,
+                            &mut report,
+                        ) {
+                            debug!(
+                                "Scanner {} found {} keys and {} instances in {}",
+                                scanner_name,
+                                result.keys.len(),
+                                result.instances.len(),
+                                path.display()
+                            );
+
+                            for key in result.keys {
                                 debug!(
-                                    "Scanner {} found {} keys and {} instances in {}",
+                                    "Scanner {} adding key for provider: {} (hash: {})",
                                     scanner_name,
-                                    result.keys.len(),
-                                    result.instances.len(),
-                                    path.display()
+                                    key.provider,
+                                    &key.hash[..8]
                                 );
+                                scan_result.add_key(key);
+                            }
 
-                                for key in result.keys {
-                                    debug!(
-                                        "Scanner {} adding key for provider: {} (hash: {})",
-                                        scanner_name,
-                                        key.provider,
-                                        &key.hash[..8]
-                                    );
-                                    scan_result.add_key(key);
-                                }
-
-                                for instance in result.instances {
-                                    scan_result.add_instance(instance);
-                                }
+                            for instance in result.instances {
+                                scan_result.add_instance(instance);
                             }
                         }
                     }
                 }
             }
-            "gsh" => {
-                let scanner = scanners::GshScanner;
-                if let Ok(instances) =
-                    scanner.scan_instances_with_registry(home_dir, Some(plugin_registry))
-                {
-                    debug!(
-                        "Scanner {} found {} instances",
-                        scanner_name,
-                        instances.len()
-                    );
-                    for instance in instances {
-                        scan_result.add_instance(instance);
-                    }
+        }
+        "gsh" => {
+            let scanner = scanners::GshScanner;
+            if let Some(instances) = parsed(
+                scanner.scan_instances_with_registry(home_dir, Some(plugin_registry)),
+                home_dir,
+                scanner_name,
+                &mut report,
+            ) {
+                debug!(
+                    "Scanner {} found {} instances",
+                    scanner_name,
+                    instances.len()
+                );
+                for instance in instances {
+                    scan_result.add_instance(instance);
                 }
+            }
 
-                let app_paths = scanner.scan_paths(home_dir);
+            let app_paths = scanner.scan_paths(home_dir);
+            debug!(
+                "Scanner {} found {} app paths",
+                scanner_name,
+                app_paths.len()
+            );
+
+            let mut scanned_paths = std::collections::HashSet::new();
+            for path in app_paths {
+                if path.exists() && scanned_paths.insert(path.clone()) {
+                    debug!("Scanner {} scanning path: {}", scanner_name, path.display());
+                    if let Some(content) =
+                        read_candidate(&path, max_file_size, scanner_name, &mut report)
+                    {
+                        if let Some(result) = parsed(
+                            scanner.parse_config_with_registry(
+                                &path,
+                                &content,
+                                Some(plugin_registry),
+                            ),
+                            &path,
+                            scanner_name,
+                            &mut report,
+                        ) {
+                            debug!(
+                                "Scanner {} found {} keys and {} instances in {}",
+                                scanner_name,
+                                result.keys.len(),
+                                result.instances.len(),
+                                path.display()
+                            );
+
+                            for key in result.keys {
+                                debug!(
+                                    "Scanner {} adding key for provider: {} (hash: {})",
+                                    scanner_name,
+                                    key.provider,
+                                    &key.hash[..8]
+                                );
+                                scan_result.add_key(key);
+                            }
+
+                            for instance in result.instances {
+                                scan_result.add_instance(instance);
+                            }
+                        }
+                    }
+                }
+            }
+        }
+        "roo-code" => {
+            let scanner = scanners::RooCodeScanner;
+            if let Some(instances) = parsed(
+                scanner.scan_instances(home_dir),
+                home_dir,
+                scanner_name,
+                &mut report,
+            ) {
                 debug!(
-                    "Scanner {} found {} app paths",
+                    "Scanner {} found {} instances",
                     scanner_name,
-                    app_paths.len()
+                    instances.len()
                 );
+                for instance in instances {
+                    scan_result.add_instance(instance);
+                }
+            }
 
-                let mut scanned_paths = std::collections::HashSet::new();
-                for path in app_paths {
-                    if path.exists() && scanned_paths.insert(path.clone()) {
-                        debug!("Scanner {} scanning path: {}", scanner_name, path.display());
-                        if let Ok(content) = std::fs::read_to_string(&path) {
-                            if let Ok(result) = scanner.parse_config_with_registry(
+            let app_paths = scanner.scan_paths(home_dir);
+            debug!(
+                "Scanner {} found {} app paths",
+                scanner_name,
+                app_paths.len()
+            );
+
+            let mut scanned_paths = std::collections::HashSet::new();
+            for path in app_paths {
+                if path.exists() && scanned_paths.insert(path.clone()) {
+                    debug!("Scanner {} scanning path: {}", scanner_name, path.display());
+                    if let Some(content) =
+                        read_candidate(&path, max_file_size, scanner_name, &mut report)
+                    {
+                        if let Some(result) = parsed(
+                            scanner.parse_config_with_registry(
                                 &path,
                                 &content,
                                 Some(plugin_registry),
-                            ) {
+                            ),
+                            &path,
+                            scanner_name,
+                            &mut report,
+                        ) {
+                            debug!(
+                                "Scanner {} found {} keys and {} instances in {}",
+                                scanner_name,
+                                result.keys.len(),
+                                result.instances.len(),
+                                path.display()
+                            );
+
+                            for key in result.keys {
                                 debug!(
-                                    "Scanner {} found {} keys and {} instances in {}",
+                                    "Scanner {} adding key for provider: {} (hash: {})",
                                     scanner_name,
-                                    result.keys.len(),
-                                    result.instances.len(),
-                                    path.display()
+                                    key.provider,
+                                    &key.hash[..8]
                                 );
+                                scan_result.add_key(key);
+                            }
 
-                                for key in result.keys {
-                                    debug!(
-                                        "Scanner {} adding key for provider: {} (hash: {})",
-                                        scanner_name,
-                                        key.provider,
-                                        &key.hash[..8]
-                                    );
-                                    scan_result.add_key(key);
-                                }
-
-                                for instance in result.instances {
-                                    scan_result.add_instance(instance);
-                                }
+                            for instance in result.instances {
+                                scan_result.add_instance(instance);
                             }
                         }
                     }
                 }
             }
-            "roo-code" => {
-                let scanner = scanners::RooCodeScanner;
-                if let Ok(instances) = scanner.scan_instances(home_dir) {
+        }
+        _ => {
+            // For other scanners, use the default trait methods
+            if let Some(scanner) = scanner_registry.get(scanner_name) {
+                if let Some(instances) = parsed(
+                    scanner.scan_instances(home_dir),
+                    home_dir,
+                    scanner_name,
+                    &mut report,
+                ) {
                     debug!(
                         "Scanner {} found {} instances",
                         scanner_name,
@@ -641,11 +920,14 @@ fn scan_with_scanners(
                 for path in app_paths {
                     if path.exists() && scanned_paths.insert(path.clone()) {
                         debug!("Scanner {} scanning path: {}", scanner_name, path.display());
-                        if let Ok(content) = std::fs::read_to_string(&path) {
-                            if let Ok(result) = scanner.parse_config_with_registry(
+                        if let Some(content) =
+                            read_candidate(&path, max_file_size, scanner_name, &mut report)
+                        {
+                            if let Some(result) = parsed(
+                                scanner.parse_config(&path, &content),
                                 &path,
-                                &content,
-                                Some(plugin_registry),
+                                scanner_name,
+                                &mut report,
                             ) {
                                 debug!(
                                     "Scanner {} found {} keys and {} instances in {}",
@@ -673,76 +955,136 @@ fn scan_with_scanners(
                     }
                 }
             }
-            _ => {
-                // For other scanners, use the default trait methods
-                if let Some(scanner) = scanner_registry.get(&scanner_name) {
-                    if let Ok(instances) = scanner.scan_instances(home_dir) {
-                        debug!(
-                            "Scanner {} found {} instances",
-                            scanner_name,
-                            instances.len()
-                        );
-                        for instance in instances {
-                            scan_result.add_instance(instance);
-                        }
-                    }
+        }
+    }
 
-                    let app_paths = scanner.scan_paths(home_dir);
-                    debug!(
-                        "Scanner {} found {} app paths",
-                        scanner_name,
-                        app_paths.len()
-                    );
+    // Only include results if we found something
+    if scan_result.keys.is_empty() && scan_result.instances.is_empty() {
+        return (None, report);
+    }
+    debug!(
+        "Scanner {} found {} keys and {} instances total",
+        scanner_name,
+        scan_result.keys.len(),
+        scan_result.instances.len()
+    );
+    (Some(scan_result), report)
+}
 
-                    let mut scanned_paths = std::collections::HashSet::new();
-                    for path in app_paths {
-                        if path.exists() && scanned_paths.insert(path.clone()) {
-                            debug!("Scanner {} scanning path: {}", scanner_name, path.display());
-                            if let Ok(content) = std::fs::read_to_string(&path) {
-                                if let Ok(result) = scanner.parse_config(&path, &content) {
-                                    debug!(
-                                        "Scanner {} found {} keys and {} instances in {}",
-                                        scanner_name,
-                                        result.keys.len(),
-                                        result.instances.len(),
-                                        path.display()
-                                    );
+/// What one scanner read and the problems it met.
+#[derive(Debug, Default)]
+struct ScannerReport {
+    stats: ScanStats,
+    warnings: Vec<ScanWarning>,
+}
 
-                                    for key in result.keys {
-                                        debug!(
-                                            "Scanner {} adding key for provider: {} (hash: {})",
-                                            scanner_name,
-                                            key.provider,
-                                            &key.hash[..8]
-                                        );
-                                        scan_result.add_key(key);
-                                    }
-
-                                    for instance in result.instances {
-                                        scan_result.add_instance(instance);
-                                    }
-                                }
-                            }
-                        }
-                    }
-                }
-            }
+impl ScannerReport {
+    /// Records a problem with `path` as a warning; errors also count
+    /// against the scanner in the stats.
+    fn warn(
+        &mut self,
+        scanner_name: &str,
+        path: &std::path::Path,
+        kind: ScanWarningKind,
+        message: String,
+    ) {
+        debug!(
+            "Scanner {} skipping {}: {}",
+            scanner_name,
+            path.display(),
+            message
+        );
+        if kind == ScanWarningKind::TooLarge {
+            self.stats.skipped_by_size += 1;
+        } else {
+            self.stats.record_error(scanner_name);
         }
+        self.warnings.push(ScanWarning {
+            scanner: scanner_name.to_string(),
+            path: path.display().to_string(),
+            kind,
+            message,
+        });
+    }
 
-        // Only include results if we found something
-        if !scan_result.keys.is_empty() || !scan_result.instances.is_empty() {
-            debug!(
-                "Scanner {} found {} keys and {} instances total",
+    fn warn_io(&mut self, scanner_name: &str, path: &std::path::Path, err: &std::io::Error) {
+        let kind = if err.kind() == std::io::ErrorKind::PermissionDenied {
+            ScanWarningKind::PermissionDenied
+        } else {
+            ScanWarningKind::Unreadable
+        };
+        self.warn(scanner_name, path, kind, err.to_string());
+    }
+}
+
+/// Reads a scanner's candidate file, recording it in `report`. Files over
+/// `max_file_size` (0 means [`DEFAULT_MAX_FILE_SIZE`]) are skipped, and
+/// unreadable files are reported as warnings.
+fn read_candidate(
+    path: &std::path::Path,
+    max_file_size: usize,
+    scanner_name: &str,
+    report: &mut ScannerReport,
+) -> Option<String> {
+    let limit = if max_file_size == 0 {
+        DEFAULT_MAX_FILE_SIZE
+    } else {
+        max_file_size
+    };
+    match std::fs::metadata(path) {
+        Ok(meta) if meta.len() > limit as u64 => {
+            report.warn(
                 scanner_name,
-                scan_result.keys.len(),
-                scan_result.instances.len()
+                path,
+                ScanWarningKind::TooLarge,
+                format!("{} bytes exceeds max_file_size of {limit}", meta.len()),
             );
-            results.push((scanner_name, scan_result));
+            return None;
+        }
+        Ok(_) => {}
+        Err(e) => {
+            report.warn_io(scanner_name, path, &e);
+            return None;
+        }
+    }
+    match std::fs::read_to_string(path) {
+        Ok(content) => {
+            report.stats.files_examined += 1;
+            report.stats.bytes_read += content.len() as u64;
+            Some(content)
+        }
+        Err(e) => {
+            report.warn_io(scanner_name, path, &e);
+            None
         }
     }
+}
 
-    results
+/// Unwraps a scanner's result for `path`, reporting a failure in `report`.
+fn parsed<T>(
+    result: Result<T>,
+    path: &std::path::Path,
+    scanner_name: &str,
+    report: &mut ScannerReport,
+) -> Option<T> {
+    match result {
+        Ok(value) => Some(value),
+        Err(Error::IoError(e)) => {
+            report.warn_io(scanner_name, path, &e);
+            None
+        }
+        Err(e) => {
+            report.warn(
+                scanner_name,
+                path,
+                ScanWarningKind::ParseError,
+                e.to_string(),
+            );
+            None
+        }
+    }
 }
+
 /// Statistics from probing provider instances.
 #[derive(Debug, Clone)]
 pub struct ProbeStatistics {
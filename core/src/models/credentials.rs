@@ -27,6 +27,10 @@ pub struct DiscoveredCredential {
     pub source_line: Option<usize>,
     /// Column number in the source file (if applicable)
     pub column_number: Option<u32>,
+    /// Structured location, including the byte offset, variable name or
+    /// document pointer when the scanner knows them
+    #[serde(default)]
+    pub location: CredentialLocation,
     /// Environment where credential was discovered
     pub environment: Environment,
     /// When this credential was discovered
@@ -37,6 +41,40 @@ pub struct DiscoveredCredential {
     pub metadata: Option<serde_json::Value>,
 }
 
+/// Where exactly a credential was found, so remediation tools and editor
+/// integrations can jump to the spot.
+#[derive(Debug, Clone, Default, Serialize, Deserialize, PartialEq, Eq)]
+pub struct CredentialLocation {
+    /// File the credential was found in
+    pub path: String,
+    /// 1-based line number
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub line: Option<usize>,
+    /// 1-based column, counted in bytes
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub column: Option<u32>,
+    /// Byte offset from the start of the file
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub offset: Option<usize>,
+    /// Environment variable or config key the credential was assigned to
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub env_var: Option<String>,
+    /// JSON pointer (RFC 6901) within a JSON or YAML document
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub pointer: Option<String>,
+}
+
+impl CredentialLocation {
+    /// A location naming only the file
+    #[must_use]
+    pub fn file(path: impl Into<String>) -> Self {
+        Self {
+            path: path.into(),
+            ..Self::default()
+        }
+    }
+}
+
 impl DiscoveredCredential {
     /// Creates a new discovered credential with a full value
     #[must_use]
@@ -55,6 +93,7 @@ impl DiscoveredCredential {
             value: CredentialValue::full(full_value),
             confidence,
             hash,
+            location: CredentialLocation::file(&source_file),
             source_file,
             source_line: None,
             column_number: None,
@@ -82,6 +121,7 @@ impl DiscoveredCredential {
             value: CredentialValue::redact(full_value),
             confidence,
             hash,
+            location: CredentialLocation::file(&source_file),
             source_file,
             source_line: None,
             column_number: None,
@@ -162,6 +202,39 @@ impl DiscoveredCredential {
     pub const fn with_position(mut self, line: usize, column: u32) -> Self {
         self.source_line = Some(line);
         self.column_number = Some(column);
+        self.location.line = Some(line);
+        self.location.column = Some(column);
+        self
+    }
+
+    /// Sets the byte offset of the credential within `content`, the text it
+    /// was found in, along with the line and column it falls on
+    #[must_use]
+    #[allow(clippy::cast_possible_truncation)]
+    pub fn with_offset_in(mut self, content: &str, offset: usize) -> Self {
+        let before = &content.as_bytes()[..offset.min(content.len())];
+        let line = before.iter().filter(|&&b| b == b'\n').count() + 1;
+        let line_start = before
+            .iter()
+            .rposition(|&b| b == b'\n')
+            .map_or(0, |i| i + 1);
+        self.location.offset = Some(before.len());
+        self.with_position(line, (before.len() - line_start + 1) as u32)
+    }
+
+    /// Sets the environment variable or config key the credential was
+    /// assigned to
+    #[must_use]
+    pub fn with_env_var(mut self, name: impl Into<String>) -> Self {
+        self.location.env_var = Some(name.into());
+        self
+    }
+
+    /// Sets the JSON pointer (RFC 6901) of the credential within a JSON or
+    /// YAML document, e.g. `/mcpServers/openai/env/OPENAI_API_KEY`
+    #[must_use]
+    pub fn with_pointer(mut self, pointer: impl Into<String>) -> Self {
+        self.location.pointer = Some(pointer.into());
         self
     }
 
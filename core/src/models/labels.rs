@@ -2,7 +2,7 @@
 
 use chrono::{DateTime, Utc};
 use serde::{Deserialize, Serialize};
-use std::collections::HashMap;
+use std::collections::{HashMap, HashSet, VecDeque};
 
 /// A semantic label (e.g., "fast", "smart", "cheap").
 #[derive(Debug, Clone, Serialize, Deserialize, PartialEq, Eq)]
@@ -16,6 +16,100 @@ pub struct Label {
     /// Additional metadata
     #[serde(default)]
     pub metadata: HashMap<String, String>,
+    /// Name of the parent label, placing this label in a hierarchy
+    /// (e.g., "prod-us" under "prod")
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub parent: Option<String>,
+    /// How widely the label may be assigned
+    #[serde(default)]
+    pub uniqueness: UniquenessScope,
+}
+
+impl Label {
+    /// Metadata key the label's color is stored under
+    pub const COLOR_KEY: &'static str = "color";
+
+    /// Returns the label's color, if it has a valid one.
+    #[must_use]
+    pub fn color(&self) -> Option<crate::utils::Color> {
+        self.metadata.get(Self::COLOR_KEY)?.parse().ok()
+    }
+
+    /// Sets the label's color, stored as a `#rrggbb` hex code.
+    pub fn set_color(&mut self, color: crate::utils::Color) {
+        self.metadata
+            .insert(Self::COLOR_KEY.to_string(), color.to_hex());
+    }
+
+    /// Checks that assigning this label to `target` keeps it within its
+    /// uniqueness scope, given the assignments that already exist.
+    /// Reassigning the label to a target it already has is always allowed.
+    ///
+    /// # Errors
+    ///
+    /// Returns `Error::ValidationError` naming the assignment the new one
+    /// would conflict with.
+    pub fn check_assignment(
+        &self,
+        target: &LabelTarget,
+        existing: &[LabelAssignment],
+    ) -> crate::error::Result<()> {
+        let conflict = existing
+            .iter()
+            .filter(|a| a.label_name == self.name && &a.target != target)
+            .find(|a| match self.uniqueness {
+                UniquenessScope::Global => true,
+                UniquenessScope::PerTargetType => {
+                    a.target.model_id().is_some() == target.model_id().is_some()
+                }
+                UniquenessScope::PerInstance => a.target.instance_id() == target.instance_id(),
+            });
+        match conflict {
+            Some(a) => Err(crate::error::Error::ValidationError(format!(
+                "label '{}' is unique {} and is already assigned to {}; cannot also assign it to {}",
+                self.name, self.uniqueness, a.target, target
+            ))),
+            None => Ok(()),
+        }
+    }
+}
+
+/// How widely a label may be assigned.
+#[derive(Debug, Clone, Copy, Default, Serialize, Deserialize, PartialEq, Eq, Hash)]
+#[serde(rename_all = "snake_case")]
+pub enum UniquenessScope {
+    /// One target in the whole configuration (e.g., "primary")
+    #[default]
+    Global,
+    /// One provider instance and one model
+    PerTargetType,
+    /// One target within each provider instance
+    PerInstance,
+}
+
+impl std::fmt::Display for UniquenessScope {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.write_str(match self {
+            Self::Global => "globally",
+            Self::PerTargetType => "per target type",
+            Self::PerInstance => "per instance",
+        })
+    }
+}
+
+impl std::str::FromStr for UniquenessScope {
+    type Err = crate::error::Error;
+
+    fn from_str(s: &str) -> crate::error::Result<Self> {
+        match s.trim().to_lowercase().replace('_', "-").as_str() {
+            "global" => Ok(Self::Global),
+            "per-target-type" => Ok(Self::PerTargetType),
+            "per-instance" => Ok(Self::PerInstance),
+            other => Err(crate::error::Error::ValidationError(format!(
+                "unknown uniqueness scope '{other}': expected global, per-target-type or per-instance"
+            ))),
+        }
+    }
 }
 
 /// Assignment linking a label to a provider:model.
@@ -49,6 +143,18 @@ pub enum LabelTarget {
     },
 }
 
+impl std::fmt::Display for LabelTarget {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Self::ProviderInstance { instance_id } => write!(f, "instance '{instance_id}'"),
+            Self::ProviderModel {
+                instance_id,
+                model_id,
+            } => write!(f, "model '{model_id}' of instance '{instance_id}'"),
+        }
+    }
+}
+
 impl LabelTarget {
     /// Gets the instance ID from any target variant
     #[must_use]
@@ -99,6 +205,128 @@ impl LabelWithAssignments {
     }
 }
 
+/// Parent/child view over a set of labels.
+///
+/// Queries resolve assignments transitively, so routing to "prod" also
+/// reaches targets labeled "prod-us" or "prod-eu" when those labels name
+/// "prod" as their parent. Cycles in the parent links are tolerated: each
+/// label is visited at most once.
+#[derive(Debug, Clone, Default)]
+pub struct LabelHierarchy {
+    parents: HashMap<String, String>,
+    children: HashMap<String, Vec<String>>,
+}
+
+impl LabelHierarchy {
+    /// Builds the hierarchy from the labels' parent links
+    #[must_use]
+    pub fn new<'a>(labels: impl IntoIterator<Item = &'a Label>) -> Self {
+        let mut hierarchy = Self::default();
+        for label in labels {
+            if let Some(parent) = &label.parent {
+                hierarchy.parents.insert(label.name.clone(), parent.clone());
+                hierarchy
+                    .children
+                    .entry(parent.clone())
+                    .or_default()
+                    .push(label.name.clone());
+            }
+        }
+        for children in hierarchy.children.values_mut() {
+            children.sort();
+        }
+        hierarchy
+    }
+
+    /// Gets the direct parent of a label
+    #[must_use]
+    pub fn parent(&self, name: &str) -> Option<&str> {
+        self.parents.get(name).map(String::as_str)
+    }
+
+    /// Gets the direct children of a label, sorted by name
+    #[must_use]
+    pub fn children(&self, name: &str) -> &[String] {
+        self.children.get(name).map_or(&[], Vec::as_slice)
+    }
+
+    /// Gets every label below `name`, nearest first
+    #[must_use]
+    pub fn descendants<'a>(&'a self, name: &'a str) -> Vec<&'a str> {
+        let mut seen = HashSet::from([name]);
+        let mut queue = VecDeque::from([name]);
+        let mut descendants = Vec::new();
+        while let Some(current) = queue.pop_front() {
+            for child in self.children(current) {
+                if seen.insert(child.as_str()) {
+                    descendants.push(child.as_str());
+                    queue.push_back(child.as_str());
+                }
+            }
+        }
+        descendants
+    }
+
+    /// Gets every label above `name`, nearest first
+    #[must_use]
+    pub fn ancestors<'a>(&'a self, name: &'a str) -> Vec<&'a str> {
+        let mut seen = HashSet::from([name]);
+        let mut ancestors = Vec::new();
+        let mut current = name;
+        while let Some(parent) = self.parent(current) {
+            if !seen.insert(parent) {
+                break;
+            }
+            ancestors.push(parent);
+            current = parent;
+        }
+        ancestors
+    }
+
+    /// Checks whether `name` is `ancestor` or lies below it
+    #[must_use]
+    pub fn is_within(&self, name: &str, ancestor: &str) -> bool {
+        name == ancestor || self.ancestors(name).contains(&ancestor)
+    }
+
+    /// Checks whether making `parent` the parent of `name` would create a cycle
+    #[must_use]
+    pub fn would_cycle(&self, name: &str, parent: &str) -> bool {
+        self.is_within(parent, name)
+    }
+
+    /// Gets the assignments of `name` and of every label below it
+    #[must_use]
+    pub fn resolve_assignments<'b>(
+        &self,
+        name: &str,
+        assignments: &'b [LabelAssignment],
+    ) -> Vec<&'b LabelAssignment> {
+        assignments
+            .iter()
+            .filter(|assignment| self.is_within(&assignment.label_name, name))
+            .collect()
+    }
+
+    /// Gets the labels that apply to `target`: those assigned to it and
+    /// every label above them, sorted by name
+    #[must_use]
+    pub fn labels_for_target(
+        &self,
+        target: &LabelTarget,
+        assignments: &[LabelAssignment],
+    ) -> Vec<String> {
+        let mut labels = HashSet::new();
+        for assignment in assignments.iter().filter(|a| &a.target == target) {
+            labels.insert(assignment.label_name.as_str());
+            labels.extend(self.ancestors(&assignment.label_name));
+        }
+        let mut labels: Vec<String> = labels.into_iter().map(str::to_string).collect();
+        labels.sort();
+        labels
+    }
+}
+
 // =============================================================================
 // Backward Compatibility Type Aliases (Feature-Gated)
 // =============================================================================
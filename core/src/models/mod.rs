@@ -13,6 +13,7 @@ pub mod labels;
 pub mod models;
 pub mod providers;
 pub mod scan;
+pub mod tag_rules;
 
 // ==== SPECIALIZED MODELS ====
 pub mod config_instance;
@@ -22,11 +23,17 @@ pub mod config_validator;
 
 // Credentials & Discovery
 pub use credentials::{
-    Confidence, CredentialValue, DiscoveredCredential, Environment, ValidationStatus, ValueType,
+    Confidence, CredentialLocation, CredentialValue, DiscoveredCredential, Environment,
+    ValidationStatus, ValueType,
 };
 
 // Labels (semantic tagging)
-pub use labels::{Label, LabelAssignment, LabelTarget, LabelWithAssignments};
+pub use labels::{
+    Label, LabelAssignment, LabelHierarchy, LabelTarget, LabelWithAssignments, UniquenessScope,
+};
+
+// Tag rules
+pub use tag_rules::{evaluate_tag_rules, RuleField, RuleOp, TagRule};
 
 // Models & Metadata
 pub use models::{Model, ModelCapabilities, ModelMetadata, ModelPricing, TokenCost};
@@ -37,7 +44,7 @@ pub use providers::{
 };
 
 // Scan Results
-pub use scan::{ScanResult, ScanSummary};
+pub use scan::{ScanResult, ScanStats, ScanSummary, ScanWarning, ScanWarningKind};
 
 // Config Instance
 pub use config_instance::ConfigInstance;
@@ -10,7 +10,7 @@ use crate::models::config_instance::ConfigInstance;
 use crate::models::credentials::{Confidence, DiscoveredCredential, ValueType};
 use chrono::{DateTime, Utc};
 use serde::{Deserialize, Serialize};
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap};
 
 /// Results from scanning for API keys.
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -33,6 +33,13 @@ pub struct ScanResult {
     pub directories_scanned: u32,
     /// Scan metadata.
     pub metadata: Option<HashMap<String, serde_json::Value>>,
+    /// What the scan examined, for judging coverage.
+    #[serde(default)]
+    pub stats: ScanStats,
+    /// Non-fatal problems met during the scan, such as unreadable or
+    /// unparseable files, so an incomplete scan is visible.
+    #[serde(default)]
+    pub warnings: Vec<ScanWarning>,
 }
 
 impl ScanResult {
@@ -53,6 +60,8 @@ impl ScanResult {
             files_scanned: 0,
             directories_scanned: 0,
             metadata: None,
+            stats: ScanStats::default(),
+            warnings: Vec::new(),
         }
     }
 
@@ -275,6 +284,74 @@ impl ScanSummary {
     }
 }
 
+/// Coverage statistics for a scan.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ScanStats {
+    /// Candidate files read by the scanners.
+    pub files_examined: u64,
+    /// Bytes read from those files.
+    pub bytes_read: u64,
+    /// Wall-clock duration of the scan in milliseconds.
+    pub duration_ms: u64,
+    /// Candidate files skipped for exceeding `max_file_size`.
+    pub skipped_by_size: u64,
+    /// Files each scanner could not read or parse, by scanner name.
+    pub errors_by_scanner: BTreeMap<String, u64>,
+    /// Time each scanner took in milliseconds, by scanner name.
+    #[serde(default)]
+    pub duration_ms_by_scanner: BTreeMap<String, u64>,
+}
+
+impl ScanStats {
+    /// Adds the counts from `other` to these.
+    pub fn merge(&mut self, other: Self) {
+        self.files_examined += other.files_examined;
+        self.bytes_read += other.bytes_read;
+        self.skipped_by_size += other.skipped_by_size;
+        for (scanner, errors) in other.errors_by_scanner {
+            *self.errors_by_scanner.entry(scanner).or_insert(0) += errors;
+        }
+        for (scanner, ms) in other.duration_ms_by_scanner {
+            *self.duration_ms_by_scanner.entry(scanner).or_insert(0) += ms;
+        }
+    }
+
+    /// Records a file `scanner` could not read or parse.
+    pub fn record_error(&mut self, scanner: &str) {
+        *self
+            .errors_by_scanner
+            .entry(scanner.to_string())
+            .or_insert(0) += 1;
+    }
+}
+
+/// A non-fatal problem met during a scan.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ScanWarning {
+    /// Scanner that met the problem.
+    pub scanner: String,
+    /// File or directory concerned.
+    pub path: String,
+    /// What went wrong.
+    pub kind: ScanWarningKind,
+    /// Details from the underlying error.
+    pub message: String,
+}
+
+/// Kinds of [`ScanWarning`].
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ScanWarningKind {
+    /// The file could not be read.
+    Unreadable,
+    /// Reading the file was not permitted.
+    PermissionDenied,
+    /// The file was read but could not be parsed.
+    ParseError,
+    /// The file exceeded `max_file_size` and was skipped.
+    TooLarge,
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
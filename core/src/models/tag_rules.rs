@@ -0,0 +1,222 @@
+//! Rules that tag provider instances automatically.
+
+use super::labels::{LabelAssignment, LabelTarget};
+use super::providers::ProviderInstance;
+use crate::error::{Error, Result};
+use serde::{Deserialize, Serialize};
+use std::fmt;
+use std::str::FromStr;
+
+/// The instance field a rule looks at.
+#[derive(Debug, Clone, Copy, Serialize, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "snake_case")]
+pub enum RuleField {
+    /// The instance ID
+    Id,
+    /// The provider type, e.g. "openai"
+    ProviderType,
+    /// The API base URL
+    BaseUrl,
+    /// Any of the instance's models
+    Model,
+}
+
+impl RuleField {
+    const fn as_str(self) -> &'static str {
+        match self {
+            Self::Id => "id",
+            Self::ProviderType => "provider_type",
+            Self::BaseUrl => "base_url",
+            Self::Model => "model",
+        }
+    }
+}
+
+impl FromStr for RuleField {
+    type Err = Error;
+
+    fn from_str(s: &str) -> Result<Self> {
+        match s.trim().to_lowercase().replace('-', "_").as_str() {
+            "id" => Ok(Self::Id),
+            "provider_type" | "provider" => Ok(Self::ProviderType),
+            "base_url" | "url" => Ok(Self::BaseUrl),
+            "model" => Ok(Self::Model),
+            other => Err(Error::ValidationError(format!(
+                "unknown rule field '{other}': expected id, provider_type, base_url or model"
+            ))),
+        }
+    }
+}
+
+/// How a rule compares the field with its value.
+#[derive(Debug, Clone, Copy, Serialize, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "snake_case")]
+pub enum RuleOp {
+    /// The field equals the value, ignoring case
+    Equals,
+    /// The field contains the value, ignoring case
+    Contains,
+}
+
+/// A rule that tags every instance whose field matches a value, written as
+/// `provider_type=openai -> openai` or `base_url contains azure -> azure`.
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq, Eq)]
+pub struct TagRule {
+    /// Field to compare
+    pub field: RuleField,
+    /// Comparison to make
+    pub op: RuleOp,
+    /// Value to compare against
+    pub value: String,
+    /// Tag assigned to matching instances
+    pub tag: String,
+}
+
+impl TagRule {
+    /// Returns true if the instance matches the rule.
+    #[must_use]
+    pub fn matches(&self, instance: &ProviderInstance) -> bool {
+        let value = self.value.to_lowercase();
+        let compare = |field: &str| {
+            let field = field.to_lowercase();
+            match self.op {
+                RuleOp::Equals => field == value,
+                RuleOp::Contains => field.contains(&value),
+            }
+        };
+        match self.field {
+            RuleField::Id => compare(&instance.id),
+            RuleField::ProviderType => compare(&instance.provider_type),
+            RuleField::BaseUrl => compare(&instance.base_url),
+            RuleField::Model => instance.models.iter().any(|m| compare(m)),
+        }
+    }
+}
+
+impl fmt::Display for TagRule {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self.op {
+            RuleOp::Equals => write!(f, "{}={}", self.field.as_str(), self.value)?,
+            RuleOp::Contains => write!(f, "{} contains {}", self.field.as_str(), self.value)?,
+        }
+        write!(f, " -> {}", self.tag)
+    }
+}
+
+impl FromStr for TagRule {
+    type Err = Error;
+
+    fn from_str(s: &str) -> Result<Self> {
+        let invalid = || {
+            Error::ValidationError(format!(
+                "invalid tag rule '{s}': expected 'field=value -> tag' or 'field contains value -> tag'"
+            ))
+        };
+        let (condition, tag) = s
+            .split_once("->")
+            .or_else(|| s.split_once('→'))
+            .ok_or_else(invalid)?;
+        let tag = tag.trim();
+        let tag = tag.strip_prefix("tag:").unwrap_or(tag).trim();
+
+        let (field, op, value) = if let Some((field, value)) = condition.split_once(" contains ") {
+            (field, RuleOp::Contains, value)
+        } else if let Some((field, value)) = condition.split_once('=') {
+            (field, RuleOp::Equals, value)
+        } else {
+            return Err(invalid());
+        };
+        let value = value.trim();
+        if tag.is_empty() || value.is_empty() {
+            return Err(invalid());
+        }
+        Ok(Self {
+            field: field.parse()?,
+            op,
+            value: value.to_string(),
+            tag: tag.to_string(),
+        })
+    }
+}
+
+/// Returns the instance assignments the rules call for that `existing` does
+/// not already hold, one per tag.
+#[must_use]
+pub fn evaluate_tag_rules(
+    rules: &[TagRule],
+    instance: &ProviderInstance,
+    existing: &[LabelAssignment],
+) -> Vec<LabelAssignment> {
+    let target = LabelTarget::ProviderInstance {
+        instance_id: instance.id.clone(),
+    };
+    let mut assignments: Vec<LabelAssignment> = Vec::new();
+    for rule in rules.iter().filter(|rule| rule.matches(instance)) {
+        let held = existing
+            .iter()
+            .chain(&assignments)
+            .any(|a| a.label_name == rule.tag && a.target == target);
+        if !held {
+            assignments.push(LabelAssignment {
+                label_name: rule.tag.clone(),
+                target: target.clone(),
+                assigned_at: chrono::Utc::now(),
+                assigned_by: Some(format!("rule: {rule}")),
+            });
+        }
+    }
+    assignments
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn azure_instance() -> ProviderInstance {
+        ProviderInstance::new(
+            "work-azure".to_string(),
+            "openai".to_string(),
+            "https://acme.openai.azure.com".to_string(),
+            "key".to_string(),
+            vec!["gpt-4o".to_string()],
+        )
+    }
+
+    #[test]
+    fn test_parse_and_display_round_trip() {
+        let rule: TagRule = "provider_type=openai → tag:openai".parse().unwrap();
+        assert_eq!(rule.field, RuleField::ProviderType);
+        assert_eq!(rule.op, RuleOp::Equals);
+        assert_eq!(rule.tag, "openai");
+        assert_eq!(rule.to_string(), "provider_type=openai -> openai");
+
+        let rule: TagRule = "base_url contains azure -> azure".parse().unwrap();
+        assert_eq!(rule.op, RuleOp::Contains);
+        assert_eq!(rule.to_string().parse::<TagRule>().unwrap(), rule);
+
+        assert!("provider_type=openai".parse::<TagRule>().is_err());
+        assert!("colour=red -> red".parse::<TagRule>().is_err());
+        assert!("model= -> empty".parse::<TagRule>().is_err());
+    }
+
+    #[test]
+    fn test_evaluate_skips_held_tags() {
+        let rules: Vec<TagRule> = [
+            "provider_type=OpenAI -> openai",
+            "base_url contains azure -> azure",
+            "model contains gpt-4 -> openai",
+            "provider_type=anthropic -> anthropic",
+        ]
+        .iter()
+        .map(|r| r.parse().unwrap())
+        .collect();
+        let instance = azure_instance();
+
+        let added = evaluate_tag_rules(&rules, &instance, &[]);
+        let tags: Vec<&str> = added.iter().map(|a| a.label_name.as_str()).collect();
+        assert_eq!(tags, vec!["openai", "azure"]);
+        assert_eq!(added[0].target.instance_id(), "work-azure");
+
+        assert!(evaluate_tag_rules(&rules, &instance, &added).is_empty());
+    }
+}
@@ -79,7 +79,7 @@ pub trait ProviderPlugin: Send + Sync {
         use crate::models::Model;
 
         // Try to load the base model from the models directory
-        let config_dir = home_dir.join(".config").join("aicred").join("models");
+        let config_dir = crate::utils::paths::config_dir(home_dir).join("models");
 
         let model_file_name = format!("{}.yaml", model_id.replace(['/', ':'], "-"));
         let model_file_path = config_dir.join(&model_file_name);
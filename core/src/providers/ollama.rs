@@ -24,41 +24,7 @@ impl ProviderPlugin for OllamaPlugin {
     }
 
     fn validate_instance(&self, instance: &ProviderInstance) -> Result<()> {
-        // First perform base validation
-        Self::validate_base_instance(instance)?;
-
-        // Parse and enforce loopback/local + default port 11434
-        let url = url::Url::parse(&instance.base_url)
-            .map_err(|e| Error::PluginError(format!("Invalid Ollama base URL: {e}")))?;
-        let host_ok = match url.host_str() {
-            Some("localhost") => true,
-            Some(h) if h == "127.0.0.1" || h == "0.0.0.0" => true,
-            Some(h) => h
-                .parse::<std::net::IpAddr>()
-                .map(|ip| ip.is_loopback())
-                .unwrap_or(false),
-            None => false,
-        } || matches!(url.host(), Some(url::Host::Ipv6(addr)) if addr.is_loopback());
-        let port_ok = url.port() == Some(11434);
-        if !(host_ok && port_ok) {
-            return Err(Error::PluginError(
-                "Invalid Ollama base URL. Expected local loopback host and port 11434 (e.g., http://localhost:11434)".to_string()
-            ));
-        }
-
-        // Ollama doesn't require API keys, so we don't check for them
-        // But if models are configured, we should validate them
-        if !instance.models.is_empty() {
-            for model in &instance.models {
-                if model.is_empty() {
-                    return Err(Error::PluginError(
-                        "Ollama instance has empty model ID".to_string(),
-                    ));
-                }
-            }
-        }
-
-        Ok(())
+        Self::validate_instance_for_host(instance, std::env::var("OLLAMA_HOST").ok().as_deref())
     }
 
     fn get_instance_models(&self, instance: &ProviderInstance) -> Result<Vec<String>> {
@@ -105,6 +71,86 @@ impl ProviderPlugin for OllamaPlugin {
 }
 
 impl OllamaPlugin {
+    /// Validates an instance against the endpoint set by an `OLLAMA_HOST`
+    /// value, if any, as well as the default local endpoint.
+    ///
+    /// # Errors
+    /// Returns an error if the base URL is neither a local loopback URL on
+    /// port 11434 nor the `OLLAMA_HOST` endpoint, or if a model ID is empty.
+    pub fn validate_instance_for_host(
+        instance: &ProviderInstance,
+        ollama_host: Option<&str>,
+    ) -> Result<()> {
+        // First perform base validation
+        Self::validate_base_instance(instance)?;
+
+        // Parse and enforce loopback/local + default port 11434
+        let url = url::Url::parse(&instance.base_url)
+            .map_err(|e| Error::PluginError(format!("Invalid Ollama base URL: {e}")))?;
+        let host_ok = match url.host_str() {
+            Some("localhost") => true,
+            Some(h) if h == "127.0.0.1" || h == "0.0.0.0" => true,
+            Some(h) => h
+                .parse::<std::net::IpAddr>()
+                .map(|ip| ip.is_loopback())
+                .unwrap_or(false),
+            None => false,
+        } || matches!(url.host(), Some(url::Host::Ipv6(addr)) if addr.is_loopback());
+        let port_ok = url.port() == Some(11434);
+        // A server moved with OLLAMA_HOST is where the client will connect
+        let custom_ok = ollama_host.is_some_and(|host| {
+            Self::base_url_from_host(host).trim_end_matches('/')
+                == instance.base_url.trim_end_matches('/')
+        });
+        if !(host_ok && port_ok || custom_ok) {
+            return Err(Error::PluginError(
+                "Invalid Ollama base URL. Expected local loopback host and port 11434 (e.g., http://localhost:11434) or the OLLAMA_HOST endpoint".to_string()
+            ));
+        }
+
+        // Ollama doesn't require API keys, so we don't check for them
+        // But if models are configured, we should validate them
+        if !instance.models.is_empty() {
+            for model in &instance.models {
+                if model.is_empty() {
+                    return Err(Error::PluginError(
+                        "Ollama instance has empty model ID".to_string(),
+                    ));
+                }
+            }
+        }
+
+        Ok(())
+    }
+
+    /// Converts an `OLLAMA_HOST` value to the base URL a client connects to,
+    /// filling in what Ollama does: scheme `http`, host 127.0.0.1, and port
+    /// 11434, or the scheme's own port when one is given. For example
+    /// `0.0.0.0` becomes `http://0.0.0.0:11434` and `:8080` becomes
+    /// `http://127.0.0.1:8080`.
+    #[must_use]
+    pub fn base_url_from_host(host: &str) -> String {
+        let host = host.trim().trim_matches('"').trim_end_matches('/');
+        let (scheme, rest, default_port) = match host.split_once("://") {
+            None => ("http", host, "11434"),
+            Some(("https", rest)) => ("https", rest, "443"),
+            Some((scheme, rest)) => (scheme, rest, "80"),
+        };
+        let (authority, path) = rest.split_once('/').unwrap_or((rest, ""));
+        // Bracketed IPv6 hosts keep their colons
+        let (name, port) = match authority.rsplit_once(':') {
+            Some((name, port)) if !port.contains(']') => (name, port),
+            _ => (authority, default_port),
+        };
+        let name = if name.is_empty() { "127.0.0.1" } else { name };
+        let port = if port.is_empty() { default_port } else { port };
+        if path.is_empty() {
+            format!("{scheme}://{name}:{port}")
+        } else {
+            format!("{scheme}://{name}:{port}/{path}")
+        }
+    }
+
     /// Helper method to perform base instance validation
     fn validate_base_instance(instance: &ProviderInstance) -> Result<()> {
         if instance.base_url.is_empty() {
@@ -269,4 +315,51 @@ mod tests {
         let result = plugin.initialize_instance(&instance);
         assert!(result.is_ok());
     }
+
+    #[test]
+    fn test_base_url_from_host() {
+        assert_eq!(
+            OllamaPlugin::base_url_from_host("0.0.0.0"),
+            "http://0.0.0.0:11434"
+        );
+        assert_eq!(
+            OllamaPlugin::base_url_from_host(":8080"),
+            "http://127.0.0.1:8080"
+        );
+        assert_eq!(
+            OllamaPlugin::base_url_from_host("gpu-box.lan:11500"),
+            "http://gpu-box.lan:11500"
+        );
+        assert_eq!(
+            OllamaPlugin::base_url_from_host("https://ollama.example.com"),
+            "https://ollama.example.com:443"
+        );
+        assert_eq!(
+            OllamaPlugin::base_url_from_host("http://[::1]"),
+            "http://[::1]:80"
+        );
+        assert_eq!(
+            OllamaPlugin::base_url_from_host("http://example.com/ollama/"),
+            "http://example.com:80/ollama"
+        );
+    }
+
+    #[test]
+    fn test_validate_custom_ollama_host() {
+        let instance = ProviderInstance::new_without_models(
+            "test-ollama".to_string(),
+            "ollama".to_string(),
+            "http://gpu-box.lan:11500".to_string(),
+            String::new(),
+        );
+
+        assert!(OllamaPlugin::validate_instance_for_host(&instance, None).is_err());
+        assert!(
+            OllamaPlugin::validate_instance_for_host(&instance, Some("gpu-box.lan:11500")).is_ok()
+        );
+        assert!(
+            OllamaPlugin::validate_instance_for_host(&instance, Some("other-box.lan:11500"))
+                .is_err()
+        );
+    }
 }
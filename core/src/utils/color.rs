@@ -0,0 +1,241 @@
+//! Colors for tags and labels: parsing, an automatic palette, and contrast
+//! helpers for UIs that render them.
+
+use crate::error::{Error, Result};
+use std::fmt;
+use std::str::FromStr;
+
+/// Named colors accepted in place of a hex code.
+const NAMED_COLORS: &[(&str, Color)] = &[
+    ("black", Color::new(0x00, 0x00, 0x00)),
+    ("white", Color::new(0xff, 0xff, 0xff)),
+    ("gray", Color::new(0x80, 0x80, 0x80)),
+    ("grey", Color::new(0x80, 0x80, 0x80)),
+    ("silver", Color::new(0xc0, 0xc0, 0xc0)),
+    ("red", Color::new(0xff, 0x00, 0x00)),
+    ("maroon", Color::new(0x80, 0x00, 0x00)),
+    ("orange", Color::new(0xff, 0xa5, 0x00)),
+    ("yellow", Color::new(0xff, 0xff, 0x00)),
+    ("olive", Color::new(0x80, 0x80, 0x00)),
+    ("lime", Color::new(0x00, 0xff, 0x00)),
+    ("green", Color::new(0x00, 0x80, 0x00)),
+    ("teal", Color::new(0x00, 0x80, 0x80)),
+    ("cyan", Color::new(0x00, 0xff, 0xff)),
+    ("aqua", Color::new(0x00, 0xff, 0xff)),
+    ("blue", Color::new(0x00, 0x00, 0xff)),
+    ("navy", Color::new(0x00, 0x00, 0x80)),
+    ("purple", Color::new(0x80, 0x00, 0x80)),
+    ("magenta", Color::new(0xff, 0x00, 0xff)),
+    ("fuchsia", Color::new(0xff, 0x00, 0xff)),
+    ("pink", Color::new(0xff, 0xc0, 0xcb)),
+    ("brown", Color::new(0xa5, 0x2a, 0x2a)),
+];
+
+/// The WCAG AA contrast ratio for normal text.
+pub const MIN_READABLE_CONTRAST: f64 = 4.5;
+
+/// An sRGB color, written as `#rrggbb`.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub struct Color {
+    pub r: u8,
+    pub g: u8,
+    pub b: u8,
+}
+
+impl Color {
+    /// Black, for text on light colors
+    pub const BLACK: Self = Self::new(0x00, 0x00, 0x00);
+    /// White, for text on dark colors
+    pub const WHITE: Self = Self::new(0xff, 0xff, 0xff);
+
+    /// Creates a color from its red, green and blue components.
+    #[must_use]
+    pub const fn new(r: u8, g: u8, b: u8) -> Self {
+        Self { r, g, b }
+    }
+
+    /// Returns the color as a lowercase `#rrggbb` hex code.
+    #[must_use]
+    pub fn to_hex(self) -> String {
+        format!("#{:02x}{:02x}{:02x}", self.r, self.g, self.b)
+    }
+
+    /// Returns the WCAG relative luminance, from 0 (black) to 1 (white).
+    #[must_use]
+    pub fn relative_luminance(self) -> f64 {
+        let channel = |c: u8| {
+            let c = f64::from(c) / 255.0;
+            if c <= 0.039_28 {
+                c / 12.92
+            } else {
+                ((c + 0.055) / 1.055).powf(2.4)
+            }
+        };
+        0.0722f64.mul_add(
+            channel(self.b),
+            0.2126f64.mul_add(channel(self.r), 0.7152 * channel(self.g)),
+        )
+    }
+
+    /// Returns the WCAG contrast ratio between two colors, from 1 to 21.
+    #[must_use]
+    pub fn contrast_ratio(self, other: Self) -> f64 {
+        let (a, b) = (self.relative_luminance(), other.relative_luminance());
+        (a.max(b) + 0.05) / (a.min(b) + 0.05)
+    }
+
+    /// Returns black or white, whichever reads better on this color.
+    #[must_use]
+    pub fn text_color(self) -> Self {
+        if self.contrast_ratio(Self::BLACK) >= self.contrast_ratio(Self::WHITE) {
+            Self::BLACK
+        } else {
+            Self::WHITE
+        }
+    }
+
+    /// Returns true if text in this color is readable on `background` at the
+    /// WCAG AA level.
+    #[must_use]
+    pub fn is_readable_on(self, background: Self) -> bool {
+        self.contrast_ratio(background) >= MIN_READABLE_CONTRAST
+    }
+
+    /// Creates a color from a hue in degrees and saturation and lightness
+    /// between 0 and 1.
+    #[must_use]
+    #[allow(clippy::cast_possible_truncation, clippy::cast_sign_loss)]
+    pub fn from_hsl(hue: f64, saturation: f64, lightness: f64) -> Self {
+        let chroma = (1.0 - 2.0f64.mul_add(lightness, -1.0).abs()) * saturation;
+        let h = hue.rem_euclid(360.0) / 60.0;
+        let x = chroma * (1.0 - (h % 2.0 - 1.0).abs());
+        let (r, g, b) = match h as u8 {
+            0 => (chroma, x, 0.0),
+            1 => (x, chroma, 0.0),
+            2 => (0.0, chroma, x),
+            3 => (0.0, x, chroma),
+            4 => (x, 0.0, chroma),
+            _ => (chroma, 0.0, x),
+        };
+        let m = lightness - chroma / 2.0;
+        let scale = |c: f64| ((c + m) * 255.0).round().clamp(0.0, 255.0) as u8;
+        Self::new(scale(r), scale(g), scale(b))
+    }
+}
+
+impl fmt::Display for Color {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(&self.to_hex())
+    }
+}
+
+impl FromStr for Color {
+    type Err = Error;
+
+    /// Parses `#rgb`, `#rrggbb` (the `#` is optional) or a named color such
+    /// as "red" or "navy", ignoring case.
+    fn from_str(s: &str) -> Result<Self> {
+        let s = s.trim().to_lowercase();
+        if let Some((_, color)) = NAMED_COLORS.iter().find(|(name, _)| *name == s) {
+            return Ok(*color);
+        }
+        let hex = s.strip_prefix('#').unwrap_or(&s);
+        let invalid = || {
+            Error::ValidationError(format!(
+                "invalid color '{s}': expected #rgb, #rrggbb or a color name such as 'red'"
+            ))
+        };
+        if !hex.chars().all(|c| c.is_ascii_hexdigit()) {
+            return Err(invalid());
+        }
+        let digit = |i: usize, len: usize| u8::from_str_radix(&hex[i..i + len], 16);
+        match hex.len() {
+            3 => Ok(Self::new(
+                digit(0, 1).map_err(|_| invalid())? * 0x11,
+                digit(1, 1).map_err(|_| invalid())? * 0x11,
+                digit(2, 1).map_err(|_| invalid())? * 0x11,
+            )),
+            6 => Ok(Self::new(
+                digit(0, 2).map_err(|_| invalid())?,
+                digit(2, 2).map_err(|_| invalid())?,
+                digit(4, 2).map_err(|_| invalid())?,
+            )),
+            _ => Err(invalid()),
+        }
+    }
+}
+
+/// Returns `n` distinct colors, spreading hues by the golden angle so that
+/// neighbours differ clearly however many are needed. The first `k` colors
+/// are the same for any `n >= k`.
+#[must_use]
+#[allow(clippy::cast_precision_loss)]
+pub fn palette(n: usize) -> Vec<Color> {
+    const GOLDEN_ANGLE: f64 = 137.507_764;
+    (0..n)
+        .map(|i| {
+            // Alternate lightness so hues that land close together still differ
+            let lightness = if i % 2 == 0 { 0.45 } else { 0.6 };
+            Color::from_hsl(i as f64 * GOLDEN_ANGLE + 210.0, 0.65, lightness)
+        })
+        .collect()
+}
+
+/// Returns the first palette color not in `used`, for giving a new tag or
+/// label a color of its own.
+#[must_use]
+pub fn next_color(used: &[Color]) -> Color {
+    palette(used.len() + 1)
+        .into_iter()
+        .find(|color| !used.contains(color))
+        .unwrap_or(Color::BLACK)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_parse_colors() {
+        assert_eq!("#FF8800".parse::<Color>().unwrap(), Color::new(255, 136, 0));
+        assert_eq!("f80".parse::<Color>().unwrap(), Color::new(255, 136, 0));
+        assert_eq!("Navy".parse::<Color>().unwrap(), Color::new(0, 0, 128));
+        assert_eq!(Color::new(255, 136, 0).to_string(), "#ff8800");
+
+        for bad in [
+            "",
+            "#12345",
+            "#gggggg",
+            "#ff88001",
+            "chartreuse-ish",
+            "#+12",
+        ] {
+            assert!(bad.parse::<Color>().is_err(), "{bad} parsed");
+        }
+    }
+
+    #[test]
+    fn test_palette_is_distinct_and_stable() {
+        let colors = palette(24);
+        for (i, a) in colors.iter().enumerate() {
+            assert!(!colors[i + 1..].contains(a), "{a} repeats");
+        }
+        assert_eq!(palette(5), colors[..5]);
+
+        let used = vec![colors[0], colors[2]];
+        assert_eq!(next_color(&used), colors[1]);
+    }
+
+    #[test]
+    fn test_contrast() {
+        assert!((Color::BLACK.contrast_ratio(Color::WHITE) - 21.0).abs() < 1e-9);
+        assert_eq!(
+            "yellow".parse::<Color>().unwrap().text_color(),
+            Color::BLACK
+        );
+        assert_eq!("navy".parse::<Color>().unwrap().text_color(), Color::WHITE);
+        for color in palette(12) {
+            assert!(color.text_color().is_readable_on(color), "{color}");
+        }
+    }
+}
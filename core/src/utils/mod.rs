@@ -1,5 +1,8 @@
 //! Utility modules for the aicred core library.
 
+pub mod color;
+pub mod paths;
 pub mod provider_model_tuple;
 
+pub use color::{next_color, palette, Color};
 pub use provider_model_tuple::ProviderModelTuple;
@@ -0,0 +1,128 @@
+//! Location of the aicred configuration directory
+//!
+//! The CLI, the FFI and the Go bindings' `ConfigDir` all follow these rules,
+//! so a process that sets the overrides sees one layout whichever side reads
+//! the files:
+//!
+//! - `AICRED_CONFIG_DIR` replaces the configuration directory outright
+//! - `XDG_CONFIG_HOME` moves it to `$XDG_CONFIG_HOME/aicred` on Linux and
+//!   other Unix systems, but only for the current user's own home
+//! - an `active_profile` file in that directory naming a profile other than
+//!   `default` moves it to `profiles/<name>` under it
+
+use std::path::{Path, PathBuf};
+
+/// Overrides the configuration directory for every home
+pub const ENV_CONFIG_DIR: &str = "AICRED_CONFIG_DIR";
+
+/// Reads a variable, treating an empty value as unset
+fn env(name: &str) -> Option<String> {
+    std::env::var(name).ok().filter(|v| !v.is_empty())
+}
+
+/// Names the active profile, in the directory holding the default one
+pub const ACTIVE_PROFILE_FILE: &str = "active_profile";
+
+/// The profile kept in the configuration directory itself
+pub const DEFAULT_PROFILE: &str = "default";
+
+/// Returns the aicred configuration directory for `home`, in its active
+/// profile
+#[must_use]
+pub fn config_dir(home: &Path) -> PathBuf {
+    profile_dir(config_dir_with(home, env))
+}
+
+/// Returns the directory of the profile named in `base`'s active-profile
+/// file, or `base` itself for the default profile. A name that is not a
+/// plain directory name is ignored, as the Go bindings ignore it.
+fn profile_dir(base: PathBuf) -> PathBuf {
+    let Ok(name) = std::fs::read_to_string(base.join(ACTIVE_PROFILE_FILE)) else {
+        return base;
+    };
+    let name = name.trim();
+    let valid = name
+        .chars()
+        .next()
+        .is_some_and(|c| c.is_ascii_alphanumeric())
+        && name
+            .chars()
+            .all(|c| c.is_ascii_alphanumeric() || matches!(c, '.' | '_' | '-'));
+    if !valid || name == DEFAULT_PROFILE {
+        return base;
+    }
+    base.join("profiles").join(name)
+}
+
+fn config_dir_with(home: &Path, env: impl Fn(&str) -> Option<String>) -> PathBuf {
+    if let Some(dir) = env(ENV_CONFIG_DIR) {
+        return PathBuf::from(dir);
+    }
+    if cfg!(all(unix, not(target_os = "macos"))) {
+        let own_home = env("HOME").is_some_and(|h| Path::new(&h) == home);
+        if let Some(xdg) = env("XDG_CONFIG_HOME").filter(|_| own_home) {
+            // The specification says relative values are to be ignored
+            if Path::new(&xdg).is_absolute() {
+                return Path::new(&xdg).join("aicred");
+            }
+        }
+    }
+    home.join(".config").join("aicred")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::collections::HashMap;
+
+    fn dir(home: &str, vars: &[(&str, &str)]) -> PathBuf {
+        let vars: HashMap<String, String> = vars
+            .iter()
+            .map(|(k, v)| (k.to_string(), v.to_string()))
+            .collect();
+        config_dir_with(Path::new(home), |name| vars.get(name).cloned())
+    }
+
+    #[test]
+    fn test_config_dir_overrides() {
+        assert_eq!(dir("/home/a", &[]), PathBuf::from("/home/a/.config/aicred"));
+        assert_eq!(
+            dir("/home/a", &[(ENV_CONFIG_DIR, "/etc/aicred")]),
+            PathBuf::from("/etc/aicred")
+        );
+        // XDG_CONFIG_HOME describes the current user's home only
+        let xdg = [("HOME", "/home/a"), ("XDG_CONFIG_HOME", "/srv/config")];
+        assert_eq!(
+            dir("/home/b", &xdg),
+            PathBuf::from("/home/b/.config/aicred")
+        );
+        if cfg!(all(unix, not(target_os = "macos"))) {
+            assert_eq!(dir("/home/a", &xdg), PathBuf::from("/srv/config/aicred"));
+            assert_eq!(
+                dir(
+                    "/home/a",
+                    &[("HOME", "/home/a"), ("XDG_CONFIG_HOME", "config")]
+                ),
+                PathBuf::from("/home/a/.config/aicred")
+            );
+        }
+    }
+
+    #[test]
+    fn test_active_profile() {
+        let tmp = tempfile::tempdir().unwrap();
+        let base = tmp.path().to_path_buf();
+        assert_eq!(profile_dir(base.clone()), base);
+
+        std::fs::write(base.join(ACTIVE_PROFILE_FILE), "work\n").unwrap();
+        assert_eq!(
+            profile_dir(base.clone()),
+            base.join("profiles").join("work")
+        );
+
+        for name in [DEFAULT_PROFILE, "../escape", ""] {
+            std::fs::write(base.join(ACTIVE_PROFILE_FILE), name).unwrap();
+            assert_eq!(profile_dir(base.clone()), base, "profile {name:?}");
+        }
+    }
+}
@@ -241,12 +241,48 @@ fn test_register_builtin_scanners() {
         scanner_names.contains(&"gsh".to_string()),
         "Should have gsh scanner"
     );
+    assert!(
+        scanner_names.contains(&"raycast".to_string()),
+        "Should have raycast scanner"
+    );
+    assert!(
+        scanner_names.contains(&"alfred".to_string()),
+        "Should have alfred scanner"
+    );
+    assert!(
+        scanner_names.contains(&"obsidian".to_string()),
+        "Should have obsidian scanner"
+    );
+    assert!(
+        scanner_names.contains(&"sillytavern".to_string()),
+        "Should have sillytavern scanner"
+    );
+    assert!(
+        scanner_names.contains(&"lm-studio".to_string()),
+        "Should have lm-studio scanner"
+    );
+    assert!(
+        scanner_names.contains(&"gpt4all".to_string()),
+        "Should have gpt4all scanner"
+    );
+    assert!(
+        scanner_names.contains(&"ollama".to_string()),
+        "Should have ollama scanner"
+    );
+    assert!(
+        scanner_names.contains(&"llama-cpp".to_string()),
+        "Should have llama-cpp scanner"
+    );
+    assert!(
+        scanner_names.contains(&"vllm".to_string()),
+        "Should have vllm scanner"
+    );
 
-    // Should have exactly 5 scanners (including GSH)
+    // Should have exactly 14 scanners (including GSH, the desktop app and local server scanners)
     assert_eq!(
         scanner_names.len(),
-        5,
-        "Should have exactly 5 built-in scanners"
+        14,
+        "Should have exactly 14 built-in scanners"
     );
 }
 
@@ -577,6 +613,6 @@ fn test_register_builtin_scanners_includes_gsh() {
     assert!(scanner_names.contains(&"roo-code".to_string()));
     assert!(scanner_names.contains(&"gsh".to_string()));
 
-    // Should have exactly 5 scanners now (including GSH)
-    assert_eq!(scanner_names.len(), 5);
+    // Should have exactly 14 scanners now (including GSH)
+    assert_eq!(scanner_names.len(), 14);
 }
@@ -42,6 +42,7 @@ export GSH_SLOW_MODEL_ID="deepseek/deepseek-v3.2-exp"#;
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let scan_result = aicred_core::scan(&scan_options).unwrap();
@@ -207,6 +208,7 @@ fn test_complete_scan_update_workflow_with_id_validation() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let scan_result = aicred_core::scan(&scan_options).unwrap();
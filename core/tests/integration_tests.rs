@@ -5,7 +5,7 @@
 #![allow(clippy::absurd_extreme_comparisons)]
 #![allow(unused_comparisons)]
 
-use aicred_core::{scan, ScanOptions};
+use aicred_core::{scan, ScanOptions, ScanWarningKind};
 use std::fs;
 use tempfile::TempDir;
 
@@ -49,6 +49,7 @@ ANTHROPIC_API_KEY=sk-ant-REDACTED
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     })
     .expect("scan should succeed");
 
@@ -95,6 +96,7 @@ OPENAI_API_KEY=sk-ABCDEFGHIJKLMNOPQRSTUVWXYZ012345
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     })
     .expect("scan should succeed");
 
@@ -141,6 +143,81 @@ OPENAI_API_KEY=sk-ABCDEFGHIJKLMNOPQRSTUVWXYZ012345
     // 4. The Ragit scanner successfully extracted provider keys from its config file
 }
 
+#[test]
+fn test_scan_stats() {
+    let temp_home = TempDir::new().unwrap();
+    let ragit_dir = temp_home.path().join(".ragit");
+    fs::create_dir_all(&ragit_dir).unwrap();
+    let config = r#"{"providers": {"openai": {"api_key": "sk-ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"}}}"#;
+    fs::write(ragit_dir.join("config.json"), config).unwrap();
+
+    let result = scan(&ScanOptions {
+        home_dir: Some(temp_home.path().to_path_buf()),
+        ..ScanOptions::default()
+    })
+    .expect("scan should succeed");
+    assert!(result.stats.files_examined >= 1);
+    assert!(result.stats.bytes_read >= config.len() as u64);
+    assert_eq!(result.stats.skipped_by_size, 0);
+    assert_eq!(u64::from(result.files_scanned), result.stats.files_examined);
+
+    // A limit below the config's size skips it rather than reading it for keys
+    let result = scan(&ScanOptions {
+        home_dir: Some(temp_home.path().to_path_buf()),
+        max_file_size: 16,
+        ..ScanOptions::default()
+    })
+    .expect("scan should succeed");
+    assert!(result.stats.skipped_by_size >= 1);
+    assert!(result
+        .warnings
+        .iter()
+        .any(|w| w.kind == ScanWarningKind::TooLarge && w.path.ends_with("config.json")));
+}
+
+#[test]
+fn test_concurrency_does_not_change_results() {
+    let temp_home = TempDir::new().unwrap();
+    let ragit_dir = temp_home.path().join(".ragit");
+    fs::create_dir_all(&ragit_dir).unwrap();
+    fs::write(
+        ragit_dir.join("config.json"),
+        r#"{"providers": {"anthropic": {"api_key": "sk-ant-REDACTED"}}}"#,
+    )
+    .unwrap();
+    fs::write(
+        temp_home.path().join(".env"),
+        "OPENAI_API_KEY=sk-ABCDEFGHIJKLMNOPQRSTUVWXYZ012345\n",
+    )
+    .unwrap();
+
+    let run = |concurrency| {
+        let result = scan(&ScanOptions {
+            home_dir: Some(temp_home.path().to_path_buf()),
+            concurrency,
+            ..ScanOptions::default()
+        })
+        .expect("scan should succeed");
+        let keys: Vec<_> = result
+            .keys
+            .iter()
+            .map(|k| (k.provider.clone(), k.source_file.clone(), k.hash.clone()))
+            .collect();
+        let instances: Vec<_> = result
+            .config_instances
+            .iter()
+            .map(|i| i.config_path.clone())
+            .collect();
+        (keys, instances)
+    };
+
+    // Results keep scanner registration order however many run at once
+    let serial = run(1);
+    assert!(!serial.0.is_empty());
+    assert_eq!(serial, run(4));
+    assert_eq!(serial, run(0));
+}
+
 #[test]
 fn test_application_scanner_integration() {
     let temp_home = TempDir::new().unwrap();
@@ -167,6 +244,7 @@ fn test_application_scanner_integration() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     })
     .expect("scan should succeed");
 
@@ -195,6 +273,7 @@ fn test_anthropic_auto_model_detection() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     })
     .expect("scan should succeed");
 
@@ -296,6 +375,7 @@ fn test_anthropic_model_detection_without_api_key() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     })
     .expect("scan should succeed");
 
@@ -18,6 +18,7 @@ fn test_scan_with_probe_models_disabled() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     // Run scan
@@ -49,6 +50,7 @@ fn test_scan_with_probe_models_enabled() {
         exclude_providers: None,
         probe_models: true,
         probe_timeout_secs: 5,
+        concurrency: 0,
     };
 
     // Run scan
@@ -91,6 +93,7 @@ fn test_scan_graceful_error_handling_with_probing() {
         exclude_providers: None,
         probe_models: true,
         probe_timeout_secs: 5,
+        concurrency: 0,
     };
 
     // Run scan - should succeed even if no instances are found
@@ -116,6 +119,7 @@ fn test_probe_statistics_in_metadata() {
         exclude_providers: None,
         probe_models: true,
         probe_timeout_secs: 5,
+        concurrency: 0,
     };
 
     // Run scan
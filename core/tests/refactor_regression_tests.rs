@@ -19,6 +19,7 @@ fn test_basic_scan_flow() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let result = scan(&options);
@@ -118,6 +119,7 @@ fn test_scan_options_defaults() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     assert!(!options.include_full_values, "Should default to redacted");
@@ -170,6 +172,7 @@ fn test_scan_with_filters() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let result = scan(&options);
@@ -184,6 +187,7 @@ fn test_scan_with_filters() {
         exclude_providers: Some(vec!["groq".to_string()]),
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let result_exclude = scan(&options_exclude);
@@ -331,6 +331,7 @@ fn test_scanner_filtering_ignores_provider_filters() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let result = aicred_core::scan(&scan_options);
@@ -352,6 +353,7 @@ fn test_scanner_filtering_ignores_provider_filters() {
         exclude_providers: Some(vec!["mock".to_string(), "another_mock".to_string()]),
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let result = aicred_core::scan(&scan_options_exclude);
@@ -373,6 +375,7 @@ fn test_scanner_filtering_ignores_provider_filters() {
         exclude_providers: None,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     let result = aicred_core::scan(&scan_options_no_providers);
@@ -1949,3 +1952,29 @@ fn test_multiple_models_same_provider() {
     assert!(model_ids.contains(&"gpt-3.5-turbo"));
     assert!(model_ids.contains(&"gpt-4-turbo"));
 }
+
+#[test]
+fn test_credential_location() {
+    let content = "# keys\nexport OPENAI_API_KEY=sk-abc123\n";
+    let offset = content.find("sk-").unwrap();
+    let key = DiscoveredCredential::new(
+        "openai".to_string(),
+        "/home/u/.zshrc".to_string(),
+        ValueType::ApiKey,
+        Confidence::High,
+        "sk-abc123".to_string(),
+    )
+    .with_offset_in(content, offset)
+    .with_env_var("OPENAI_API_KEY");
+
+    assert_eq!(key.location.path, "/home/u/.zshrc");
+    assert_eq!(key.location.offset, Some(offset));
+    assert_eq!(key.location.line, Some(2));
+    assert_eq!(key.location.column, Some(23));
+    assert_eq!(key.source_line, Some(2));
+    assert_eq!(key.location.env_var.as_deref(), Some("OPENAI_API_KEY"));
+
+    let json = serde_json::to_value(&key).unwrap();
+    assert_eq!(json["location"]["line"], 2);
+    assert!(json["location"].get("pointer").is_none());
+}
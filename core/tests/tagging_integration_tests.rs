@@ -5,7 +5,7 @@
 //! These tests validate the integration between core data structures,
 //! serialization, validation, and business logic.
 
-use aicred_core::models::{Label, LabelAssignment, LabelTarget};
+use aicred_core::models::{Label, LabelAssignment, LabelTarget, UniquenessScope};
 use chrono::Utc;
 use std::collections::HashMap;
 
@@ -19,6 +19,8 @@ mod integration_tests {
             description: Some(format!("Test label: {}", name)),
             created_at: Utc::now(),
             metadata: HashMap::new(),
+            parent: None,
+            uniqueness: UniquenessScope::Global,
         }
     }
 
@@ -109,6 +111,8 @@ mod integration_tests {
                 m.insert("tier".to_string(), "1".to_string());
                 m
             },
+            parent: None,
+            uniqueness: UniquenessScope::Global,
         };
 
         // Test serialization to JSON
@@ -179,4 +183,188 @@ mod integration_tests {
         assert!(label_with_assignments.has_assignments());
         assert_eq!(label_with_assignments.assignment_count(), 2);
     }
+
+    fn child_label(name: &str, parent: &str) -> Label {
+        Label {
+            parent: Some(parent.to_string()),
+            ..create_test_label(name)
+        }
+    }
+
+    fn instance_assignment(label: &str, instance_id: &str) -> LabelAssignment {
+        LabelAssignment {
+            label_name: label.to_string(),
+            target: LabelTarget::ProviderInstance {
+                instance_id: instance_id.to_string(),
+            },
+            assigned_at: Utc::now(),
+            assigned_by: None,
+        }
+    }
+
+    #[test]
+    fn test_label_hierarchy_resolves_transitively() {
+        use aicred_core::models::LabelHierarchy;
+
+        let labels = vec![
+            create_test_label("prod"),
+            child_label("prod-us", "prod"),
+            child_label("prod-eu", "prod"),
+            child_label("prod-us-east", "prod-us"),
+            create_test_label("staging"),
+        ];
+        let hierarchy = LabelHierarchy::new(&labels);
+
+        assert_eq!(hierarchy.parent("prod-us"), Some("prod"));
+        assert_eq!(hierarchy.children("prod"), ["prod-eu", "prod-us"]);
+        assert_eq!(
+            hierarchy.descendants("prod"),
+            vec!["prod-eu", "prod-us", "prod-us-east"]
+        );
+        assert_eq!(hierarchy.ancestors("prod-us-east"), vec!["prod-us", "prod"]);
+        assert!(hierarchy.is_within("prod-us-east", "prod"));
+        assert!(!hierarchy.is_within("staging", "prod"));
+        assert!(hierarchy.would_cycle("prod", "prod-us-east"));
+        assert!(!hierarchy.would_cycle("staging", "prod"));
+
+        let assignments = vec![
+            instance_assignment("prod-us-east", "openai-east"),
+            instance_assignment("prod-eu", "openai-eu"),
+            instance_assignment("staging", "openai-staging"),
+        ];
+        let routed: Vec<&str> = hierarchy
+            .resolve_assignments("prod", &assignments)
+            .iter()
+            .map(|a| a.target.instance_id())
+            .collect();
+        assert_eq!(routed, vec!["openai-east", "openai-eu"]);
+
+        let target = LabelTarget::ProviderInstance {
+            instance_id: "openai-east".to_string(),
+        };
+        assert_eq!(
+            hierarchy.labels_for_target(&target, &assignments),
+            vec!["prod", "prod-us", "prod-us-east"]
+        );
+    }
+
+    #[test]
+    fn test_label_hierarchy_tolerates_cycles() {
+        use aicred_core::models::LabelHierarchy;
+
+        let labels = vec![child_label("a", "b"), child_label("b", "a")];
+        let hierarchy = LabelHierarchy::new(&labels);
+
+        assert_eq!(hierarchy.ancestors("a"), vec!["b"]);
+        assert_eq!(hierarchy.descendants("a"), vec!["b"]);
+    }
+
+    #[test]
+    fn test_label_parent_serialization() {
+        let json = serde_json::to_string(&create_test_label("prod")).unwrap();
+        assert!(!json.contains("parent"));
+
+        let label: Label =
+            serde_json::from_str(&serde_json::to_string(&child_label("prod-us", "prod")).unwrap())
+                .unwrap();
+        assert_eq!(label.parent.as_deref(), Some("prod"));
+    }
+
+    fn model_assignment(label: &str, instance_id: &str, model_id: &str) -> LabelAssignment {
+        LabelAssignment {
+            target: LabelTarget::ProviderModel {
+                instance_id: instance_id.to_string(),
+                model_id: model_id.to_string(),
+            },
+            ..instance_assignment(label, instance_id)
+        }
+    }
+
+    #[test]
+    fn test_label_uniqueness_scopes() {
+        let scoped = |scope| Label {
+            uniqueness: scope,
+            ..create_test_label("fast")
+        };
+        let existing = vec![
+            model_assignment("fast", "openai", "gpt-4o-mini"),
+            instance_assignment("other", "groq"),
+        ];
+        let same_instance_model = LabelTarget::ProviderModel {
+            instance_id: "openai".to_string(),
+            model_id: "gpt-4o".to_string(),
+        };
+        let other_instance_model = LabelTarget::ProviderModel {
+            instance_id: "groq".to_string(),
+            model_id: "llama3".to_string(),
+        };
+        let instance = LabelTarget::ProviderInstance {
+            instance_id: "groq".to_string(),
+        };
+
+        let global = scoped(UniquenessScope::Global);
+        let err = global
+            .check_assignment(&other_instance_model, &existing)
+            .unwrap_err()
+            .to_string();
+        assert!(
+            err.contains("model 'gpt-4o-mini' of instance 'openai'"),
+            "{err}"
+        );
+        // Reassigning the same target is not a conflict
+        assert!(global
+            .check_assignment(&existing[0].target, &existing)
+            .is_ok());
+
+        let per_type = scoped(UniquenessScope::PerTargetType);
+        assert!(per_type.check_assignment(&instance, &existing).is_ok());
+        assert!(per_type
+            .check_assignment(&other_instance_model, &existing)
+            .is_err());
+
+        let per_instance = scoped(UniquenessScope::PerInstance);
+        assert!(per_instance
+            .check_assignment(&other_instance_model, &existing)
+            .is_ok());
+        assert!(per_instance
+            .check_assignment(&same_instance_model, &existing)
+            .is_err());
+    }
+
+    #[test]
+    fn test_uniqueness_scope_parsing() {
+        assert_eq!(
+            "per-instance".parse::<UniquenessScope>().unwrap(),
+            UniquenessScope::PerInstance
+        );
+        assert_eq!(
+            "per_target_type".parse::<UniquenessScope>().unwrap(),
+            UniquenessScope::PerTargetType
+        );
+        assert!("everywhere".parse::<UniquenessScope>().is_err());
+
+        // Labels saved before scopes existed are globally unique
+        let label: Label = serde_json::from_str(
+            r#"{"name":"fast","created_at":"2024-01-01T00:00:00Z","metadata":{}}"#,
+        )
+        .unwrap();
+        assert_eq!(label.uniqueness, UniquenessScope::Global);
+    }
+
+    #[test]
+    fn test_label_color_in_metadata() {
+        use aicred_core::utils::Color;
+
+        let mut label = create_test_label("prod");
+        assert_eq!(label.color(), None);
+
+        label.set_color("Red".parse().unwrap());
+        assert_eq!(label.metadata[Label::COLOR_KEY], "#ff0000");
+        assert_eq!(label.color(), Some(Color::new(255, 0, 0)));
+
+        label
+            .metadata
+            .insert(Label::COLOR_KEY.to_string(), "not a color".to_string());
+        assert_eq!(label.color(), None);
+    }
 }
@@ -6,6 +6,53 @@
 #include <stdint.h>
 #include <stdlib.h>
 
+/**
+ * No error occurred
+ */
+#define AICRED_OK 0
+
+/**
+ * An error that does not fit any other code
+ */
+#define AICRED_ERROR_UNKNOWN 1
+
+/**
+ * A directory, file or named item does not exist
+ */
+#define AICRED_ERROR_NOT_FOUND 2
+
+/**
+ * Input or configuration could not be parsed
+ */
+#define AICRED_ERROR_PARSE 3
+
+/**
+ * An I/O operation failed
+ */
+#define AICRED_ERROR_IO 4
+
+/**
+ * Access to a file or directory was denied
+ */
+#define AICRED_ERROR_PERMISSION_DENIED 5
+
+/**
+ * JSON encoding for session results
+ */
+#define AICRED_ENCODING_JSON 0
+
+/**
+ * CBOR (RFC 8949) encoding for session results
+ */
+#define AICRED_ENCODING_CBOR 1
+
+/**
+ * Opaque session handle returned by [`aicred_session_open`]
+ */
+typedef struct AicredScanCursor AicredScanCursor;
+
+typedef struct AicredSession AicredSession;
+
 /**
  * Scan for GenAI credentials and configurations
  *
@@ -57,6 +104,14 @@ const char *aicred_version(void);
  */
 const char *aicred_last_error(void);
 
+/**
+ * Get the code of the last error (thread-local)
+ *
+ * Returns one of the `AICRED_ERROR_*` constants, or `AICRED_OK` if the last
+ * call on this thread succeeded.
+ */
+int32_t aicred_last_error_code(void);
+
 /**
  * Get list of available provider plugins
  *
@@ -93,4 +148,188 @@ char *aicred_list_providers(void);
  */
 char *aicred_list_scanners(void);
 
+/**
+ * Scan for GenAI credentials, reporting errors in the returned value
+ *
+ * Takes the same parameters as [`aicred_scan`] but always returns a JSON
+ * result envelope instead of relying on [`aicred_last_error`]. Callers whose
+ * threads may interleave (such as Go goroutines) should prefer this form.
+ *
+ * # Returns
+ * ```json
+ * {"ok": true, "result": { ...scan result... }}
+ * {"ok": false, "error": {"code": 2, "message": "Scan failed: Not found: ..."}}
+ * ```
+ * `code` is one of the `AICRED_ERROR_*` constants. Caller must free with
+ * [`aicred_free`]. Returns NULL only if the envelope cannot be allocated.
+ *
+ * # Safety
+ *
+ * Both pointers must be either null or point to valid null-terminated C strings.
+ */
+char *aicred_scan_envelope(const char *home_path, const char *options_json);
+
+/**
+ * Get list of available provider plugins as a result envelope
+ *
+ * Returns `{"ok": true, "result": ["openai", ...]}` or an error envelope as
+ * described for [`aicred_scan_envelope`]. Caller must free with [`aicred_free`].
+ */
+char *aicred_list_providers_envelope(void);
+
+/**
+ * Get list of available scanner plugins as a result envelope
+ *
+ * Returns `{"ok": true, "result": ["roo-code", ...]}` or an error envelope as
+ * described for [`aicred_scan_envelope`]. Caller must free with [`aicred_free`].
+ */
+char *aicred_list_scanners_envelope(void);
+
+/**
+ * Open a session over `<home_path>/.config/aicred`
+ *
+ * Nothing is read until the first call that needs it. Returns NULL if
+ * `home_path` is null or not valid UTF-8. Close with [`aicred_session_close`].
+ *
+ * # Safety
+ *
+ * `home_path` must be either null or point to a valid null-terminated C string.
+ */
+AicredSession *aicred_session_open(const char *home_path);
+
+/**
+ * Open a session whose [`aicred_session_scan`] results use `encoding`
+ *
+ * `encoding` is `AICRED_ENCODING_JSON` or `AICRED_ENCODING_CBOR`. Returns
+ * NULL if `home_path` is invalid or the encoding is not supported.
+ *
+ * # Safety
+ *
+ * `home_path` must be either null or point to a valid null-terminated C string.
+ */
+AicredSession *aicred_session_open_with_encoding(const char *home_path, int32_t encoding);
+
+/**
+ * Close a session and free its handle
+ *
+ * # Safety
+ *
+ * The handle must be either null or a handle returned by
+ * [`aicred_session_open`] that has not already been closed.
+ */
+void aicred_session_close(AicredSession *handle);
+
+/**
+ * Drop the session's cached state so the next call re-reads the files
+ */
+void aicred_session_reload(const AicredSession *handle);
+
+/**
+ * List provider instances as a result envelope
+ *
+ * Returns `{"ok": true, "result": [ProviderInstance, ...]}` or an error
+ * envelope. Caller must free with [`crate::aicred_free`].
+ */
+char *aicred_session_instances(const AicredSession *handle);
+
+/**
+ * Get one provider instance by ID as a result envelope
+ *
+ * Fails with `AICRED_ERROR_NOT_FOUND` if no instance has that ID.
+ * Caller must free with [`crate::aicred_free`].
+ *
+ * # Safety
+ *
+ * `instance_id` must be either null or point to a valid null-terminated C string.
+ */
+char *aicred_session_get_instance(const AicredSession *handle, const char *instance_id);
+
+/**
+ * List label assignments as a result envelope
+ *
+ * Caller must free with [`crate::aicred_free`].
+ */
+char *aicred_session_labels(const AicredSession *handle);
+
+/**
+ * Replace all label assignments and write them to `labels.yaml`
+ *
+ * `labels_json` is a JSON array of label assignments. Returns
+ * `{"ok": true, "result": null}` or an error envelope. Caller must free with
+ * [`crate::aicred_free`].
+ *
+ * # Safety
+ *
+ * `labels_json` must be either null or point to a valid null-terminated C string.
+ */
+char *aicred_session_save_labels(const AicredSession *handle, const char *labels_json);
+
+/**
+ * Scan the session's home directory, returning an envelope in the session's
+ * encoding
+ *
+ * Takes the same `options_json` as [`crate::aicred_scan`]. The envelope bytes
+ * are not NUL-terminated; their length is written to `out_len`. Free them with
+ * [`crate::aicred_bytes_free`]. Returns NULL only if `out_len` is null.
+ *
+ * # Safety
+ *
+ * `options_json` must be either null or point to a valid null-terminated C
+ * string, and `out_len` must point to writable memory.
+ */
+uint8_t *aicred_session_scan(const AicredSession *handle, const char *options_json, uintptr_t *out_len);
+
+/**
+ * Free a byte buffer returned with an explicit length, such as by
+ * [`aicred_session_scan`]
+ *
+ * # Safety
+ *
+ * `ptr` and `len` must be exactly as returned by this library, or `ptr` null.
+ */
+void aicred_bytes_free(uint8_t *ptr, uintptr_t len);
+
+/**
+ * Start a paged scan
+ *
+ * Takes the same parameters as [`crate::aicred_scan`] plus the maximum number
+ * of keys or config instances per page (0 for the default of 500). The scan
+ * starts on the first [`aicred_scan_next`] call and runs on its own thread,
+ * one scanner ahead of the pages read. Returns NULL if either string is null
+ * or not valid UTF-8. Close with [`aicred_scan_close`].
+ *
+ * # Safety
+ *
+ * Both pointers must be either null or point to valid null-terminated C strings.
+ */
+AicredScanCursor *aicred_scan_begin(const char *home_path, const char *options_json, uintptr_t page_size);
+
+/**
+ * Fetch the next page of a paged scan as a result envelope
+ *
+ * Each page has the shape of a scan result holding a slice of one scanner's
+ * keys or config instances, plus `"done": true` on the last page, which also
+ * carries the finished scan's stats and warnings. A scan error is reported in
+ * the envelope of the call that meets it, and ends the scan. Caller must free
+ * with [`crate::aicred_free`].
+ *
+ * # Safety
+ *
+ * The cursor must be a live handle from [`aicred_scan_begin`], used by one
+ * thread at a time.
+ */
+char *aicred_scan_next(AicredScanCursor *cursor);
+
+/**
+ * Close a paged scan and free its cursor
+ *
+ * A scan still running stops once the scanners it has started finish.
+ *
+ * # Safety
+ *
+ * The cursor must be either null or a handle from [`aicred_scan_begin`] that
+ * has not already been closed.
+ */
+void aicred_scan_close(AicredScanCursor *cursor);
+
 #endif /* GENAI_KEYFINDER_H */
@@ -21,6 +21,13 @@
 #![allow(clippy::redundant_closure)]
 #![allow(clippy::not_unsafe_ptr_arg_deref)]
 
+mod paging;
+mod paths;
+mod session;
+
+pub use paging::*;
+pub use session::*;
+
 use aicred_core::{scan, ScanOptions};
 use std::cell::RefCell;
 use std::ffi::{CStr, CString};
@@ -36,17 +43,80 @@ thread_local! {
     static ERROR_BUFFER: RefCell<Option<CString>> = RefCell::new(None);
 }
 
+/// Thread-local storage for the last error code
+thread_local! {
+    static LAST_ERROR_CODE: RefCell<i32> = RefCell::new(AICRED_OK);
+}
+
+/// No error occurred
+pub const AICRED_OK: i32 = 0;
+/// An error that does not fit any other code
+pub const AICRED_ERROR_UNKNOWN: i32 = 1;
+/// A directory, file or named item does not exist
+pub const AICRED_ERROR_NOT_FOUND: i32 = 2;
+/// Input or configuration could not be parsed
+pub const AICRED_ERROR_PARSE: i32 = 3;
+/// An I/O operation failed
+pub const AICRED_ERROR_IO: i32 = 4;
+/// Access to a file or directory was denied
+pub const AICRED_ERROR_PERMISSION_DENIED: i32 = 5;
+
+/// JSON encoding for session results
+pub const AICRED_ENCODING_JSON: i32 = 0;
+/// CBOR (RFC 8949) encoding for session results
+pub const AICRED_ENCODING_CBOR: i32 = 1;
+
+/// An error with a stable code for callers to branch on
+struct FfiError {
+    code: i32,
+    message: String,
+}
+
+impl FfiError {
+    fn new(code: i32, message: impl Into<String>) -> Self {
+        Self {
+            code,
+            message: message.into(),
+        }
+    }
+}
+
+impl From<String> for FfiError {
+    fn from(message: String) -> Self {
+        Self::new(AICRED_ERROR_UNKNOWN, message)
+    }
+}
+
+impl From<aicred_core::Error> for FfiError {
+    fn from(err: aicred_core::Error) -> Self {
+        use aicred_core::Error;
+        let code = match &err {
+            Error::IoError(io) => match io.kind() {
+                std::io::ErrorKind::NotFound => AICRED_ERROR_NOT_FOUND,
+                std::io::ErrorKind::PermissionDenied => AICRED_ERROR_PERMISSION_DENIED,
+                _ => AICRED_ERROR_IO,
+            },
+            Error::NotFound(_) => AICRED_ERROR_NOT_FOUND,
+            Error::ParseError { .. } | Error::SerializationError(_) => AICRED_ERROR_PARSE,
+            _ => AICRED_ERROR_UNKNOWN,
+        };
+        Self::new(code, format!("Scan failed: {}", err))
+    }
+}
+
 /// Version string for the library
 const VERSION: &str = env!("CARGO_PKG_VERSION");
 
-/// Sets the last error message
-fn set_last_error(err: String) {
-    LAST_ERROR.with(|e| *e.borrow_mut() = Some(err));
+/// Sets the last error message and code
+fn set_last_error(err: FfiError) {
+    LAST_ERROR.with(|e| *e.borrow_mut() = Some(err.message));
+    LAST_ERROR_CODE.with(|c| *c.borrow_mut() = err.code);
 }
 
-/// Clears the last error message
+/// Clears the last error message and code
 fn clear_last_error() {
     LAST_ERROR.with(|e| *e.borrow_mut() = None);
+    LAST_ERROR_CODE.with(|c| *c.borrow_mut() = AICRED_OK);
 }
 
 /// Gets the last error message
@@ -76,16 +146,181 @@ fn string_to_c_str(s: String) -> *mut libc::c_char {
     }
 }
 
-/// Safely executes a closure, catching any panics and converting them to error strings
-fn safe_execute<T, F>(f: F) -> Result<T, String>
+/// Safely executes a closure, catching any panics and converting them to errors
+fn safe_execute<T, F>(f: F) -> Result<T, FfiError>
 where
-    F: FnOnce() -> Result<T, String>,
+    F: FnOnce() -> Result<T, FfiError>,
 {
     std::panic::catch_unwind(std::panic::AssertUnwindSafe(|| f()))
-        .map_err(|_| "Panic occurred during execution".to_string())
+        .map_err(|_| FfiError::from("Panic occurred during execution".to_string()))
         .and_then(|result| result)
 }
 
+/// Parses scan arguments and runs the scan, shared by [`aicred_scan`] and
+/// [`aicred_scan_envelope`]
+fn run_scan(
+    home_path: *const libc::c_char,
+    options_json: *const libc::c_char,
+) -> Result<aicred_core::ScanResult, FfiError> {
+    // Parse home path
+    let home_path_str =
+        unsafe { c_str_to_string(home_path) }.ok_or_else(|| "Invalid home path".to_string())?;
+
+    scan_home(PathBuf::from(home_path_str), options_json)
+}
+
+/// Runs a scan of `home` with the given JSON options
+fn scan_home(
+    home: PathBuf,
+    options_json: *const libc::c_char,
+) -> Result<aicred_core::ScanResult, FfiError> {
+    Ok(scan(&scan_options(home, options_json)?)?)
+}
+
+/// Builds the options for a scan of `home` from the given JSON options
+fn scan_options(home: PathBuf, options_json: *const libc::c_char) -> Result<ScanOptions, FfiError> {
+    // Parse options JSON
+    let options_str = unsafe { c_str_to_string(options_json) }
+        .ok_or_else(|| "Invalid options JSON".to_string())?;
+
+    // Parse JSON options
+    let json_options: serde_json::Value = serde_json::from_str(&options_str).map_err(|e| {
+        FfiError::new(
+            AICRED_ERROR_PARSE,
+            format!("Failed to parse options JSON: {}", e),
+        )
+    })?;
+
+    // Build ScanOptions
+    let mut options = ScanOptions::new();
+
+    // Set home directory; an empty one falls back to AICRED_HOME
+    options.home_dir = paths::home_dir(home);
+
+    // Parse other options
+    if let Some(include_full_values) = json_options
+        .get("include_full_values")
+        .and_then(|v| v.as_bool())
+    {
+        options.include_full_values = include_full_values;
+    }
+
+    if let Some(max_file_size) = json_options.get("max_file_size").and_then(|v| v.as_u64()) {
+        options.max_file_size = max_file_size as usize;
+    }
+
+    if let Some(concurrency) = json_options.get("concurrency").and_then(|v| v.as_u64()) {
+        options.concurrency = concurrency as usize;
+    }
+
+    if let Some(only_providers) = json_options
+        .get("only_providers")
+        .and_then(|v| v.as_array())
+    {
+        options.only_providers = Some(
+            only_providers
+                .iter()
+                .filter_map(|v| v.as_str().map(String::from))
+                .collect(),
+        );
+    }
+
+    if let Some(exclude_providers) = json_options
+        .get("exclude_providers")
+        .and_then(|v| v.as_array())
+    {
+        options.exclude_providers = Some(
+            exclude_providers
+                .iter()
+                .filter_map(|v| v.as_str().map(String::from))
+                .collect(),
+        );
+    }
+
+    Ok(options)
+}
+
+/// Names of the built-in provider plugins
+fn provider_names() -> Result<Vec<String>, FfiError> {
+    let registry = aicred_core::plugins::register_builtin_providers();
+    Ok(aicred_core::plugins::list_providers(&registry))
+}
+
+/// Names of the built-in scanner plugins
+fn scanner_names() -> Result<Vec<String>, FfiError> {
+    let registry = aicred_core::scanners::ScannerRegistry::new();
+    aicred_core::scanners::register_builtin_scanners(&registry)
+        .map_err(|e| format!("Failed to register scanners: {}", e))?;
+    Ok(registry.list())
+}
+
+/// Per-call result wrapper returned by the envelope functions:
+/// `{"ok": true, "result": ...}` or
+/// `{"ok": false, "error": {"code": N, "message": "..."}}`
+#[derive(serde::Serialize)]
+struct Envelope<T> {
+    ok: bool,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    result: Option<T>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    error: Option<EnvelopeError>,
+}
+
+#[derive(serde::Serialize)]
+struct EnvelopeError {
+    code: i32,
+    message: String,
+}
+
+impl<T> From<Result<T, FfiError>> for Envelope<T> {
+    fn from(outcome: Result<T, FfiError>) -> Self {
+        match outcome {
+            Ok(result) => Self {
+                ok: true,
+                result: Some(result),
+                error: None,
+            },
+            Err(err) => Self {
+                ok: false,
+                result: None,
+                error: Some(EnvelopeError {
+                    code: err.code,
+                    message: err.message,
+                }),
+            },
+        }
+    }
+}
+
+/// Encodes a call outcome as an envelope in the given `AICRED_ENCODING_*` format
+fn encode_envelope<T: serde::Serialize>(outcome: Result<T, FfiError>, encoding: i32) -> Vec<u8> {
+    let env = Envelope::from(outcome);
+    let encoded = match encoding {
+        AICRED_ENCODING_CBOR => {
+            let mut buf = Vec::new();
+            ciborium::into_writer(&env, &mut buf)
+                .map(|()| buf)
+                .map_err(|e| e.to_string())
+        }
+        _ => serde_json::to_vec(&env).map_err(|e| e.to_string()),
+    };
+    encoded.unwrap_or_else(|e| {
+        let failed: Envelope<()> = Envelope::from(Err(FfiError::from(format!(
+            "Failed to serialize result: {}",
+            e
+        ))));
+        serde_json::to_vec(&failed).unwrap_or_default()
+    })
+}
+
+/// Serializes a call outcome as a JSON result envelope C string
+fn envelope<T: serde::Serialize>(outcome: Result<T, FfiError>) -> *mut libc::c_char {
+    match String::from_utf8(encode_envelope(outcome, AICRED_ENCODING_JSON)) {
+        Ok(json) => string_to_c_str(json),
+        Err(_) => std::ptr::null_mut(),
+    }
+}
+
 /// Scan for GenAI credentials and configurations
 ///
 /// # Parameters
@@ -101,6 +336,7 @@ where
 /// {
 ///   "include_full_values": false,
 ///   "max_file_size": 1048576,
+///   "concurrency": 4,
 ///   "only_providers": ["openai", "anthropic"],
 ///   "exclude_providers": []
 /// }
@@ -117,62 +353,7 @@ pub extern "C" fn aicred_scan(
     clear_last_error();
 
     let result = safe_execute(|| {
-        // Parse home path
-        let home_path_str =
-            unsafe { c_str_to_string(home_path) }.ok_or_else(|| "Invalid home path".to_string())?;
-
-        // Parse options JSON
-        let options_str = unsafe { c_str_to_string(options_json) }
-            .ok_or_else(|| "Invalid options JSON".to_string())?;
-
-        // Parse JSON options
-        let json_options: serde_json::Value = serde_json::from_str(&options_str)
-            .map_err(|e| format!("Failed to parse options JSON: {}", e))?;
-
-        // Build ScanOptions
-        let mut options = ScanOptions::new();
-
-        // Set home directory
-        options.home_dir = Some(PathBuf::from(home_path_str));
-
-        // Parse other options
-        if let Some(include_full_values) = json_options
-            .get("include_full_values")
-            .and_then(|v| v.as_bool())
-        {
-            options.include_full_values = include_full_values;
-        }
-
-        if let Some(max_file_size) = json_options.get("max_file_size").and_then(|v| v.as_u64()) {
-            options.max_file_size = max_file_size as usize;
-        }
-
-        if let Some(only_providers) = json_options
-            .get("only_providers")
-            .and_then(|v| v.as_array())
-        {
-            options.only_providers = Some(
-                only_providers
-                    .iter()
-                    .filter_map(|v| v.as_str().map(String::from))
-                    .collect(),
-            );
-        }
-
-        if let Some(exclude_providers) = json_options
-            .get("exclude_providers")
-            .and_then(|v| v.as_array())
-        {
-            options.exclude_providers = Some(
-                exclude_providers
-                    .iter()
-                    .filter_map(|v| v.as_str().map(String::from))
-                    .collect(),
-            );
-        }
-
-        // Run the scan
-        let scan_result = scan(&options).map_err(|e| format!("Scan failed: {}", e))?;
+        let scan_result = run_scan(home_path, options_json)?;
 
         // Serialize result to JSON
         let json_result = serde_json::to_string(&scan_result)
@@ -260,6 +441,15 @@ pub extern "C" fn aicred_last_error() -> *const libc::c_char {
     }
 }
 
+/// Get the code of the last error (thread-local)
+///
+/// Returns one of the `AICRED_ERROR_*` constants, or `AICRED_OK` if the last
+/// call on this thread succeeded.
+#[no_mangle]
+pub extern "C" fn aicred_last_error_code() -> i32 {
+    LAST_ERROR_CODE.with(|c| *c.borrow())
+}
+
 /// Get list of available provider plugins
 ///
 /// Returns a JSON array of provider names as a UTF-8 encoded string.
@@ -279,11 +469,7 @@ pub extern "C" fn aicred_list_providers() -> *mut libc::c_char {
     clear_last_error();
 
     let result = safe_execute(|| {
-        // Create a provider registry with built-in providers
-        let registry = aicred_core::plugins::register_builtin_providers();
-
-        // Get the list of provider names
-        let providers = aicred_core::plugins::list_providers(&registry);
+        let providers = provider_names()?;
 
         // Serialize to JSON
         let json_result = serde_json::to_string(&providers)
@@ -309,7 +495,8 @@ pub extern "C" fn aicred_list_providers() -> *mut libc::c_char {
 ///
 /// # Example return value:
 /// ```json
-/// ["ragit", "claude-desktop", "roo-code", "langchain", "gsh"]
+/// ["ragit", "claude-desktop", "roo-code", "langchain", "gsh", "raycast", "alfred",
+///  "obsidian", "sillytavern", "lm-studio", "gpt4all", "ollama", "llama-cpp", "vllm"]
 /// ```
 ///
 /// # Safety
@@ -320,13 +507,7 @@ pub extern "C" fn aicred_list_scanners() -> *mut libc::c_char {
     clear_last_error();
 
     let result = safe_execute(|| {
-        // Create a scanner registry and register built-in scanners
-        let registry = aicred_core::scanners::ScannerRegistry::new();
-        aicred_core::scanners::register_builtin_scanners(&registry)
-            .map_err(|e| format!("Failed to register scanners: {}", e))?;
-
-        // Get the list of scanner names
-        let scanners = registry.list();
+        let scanners = scanner_names()?;
 
         // Serialize to JSON
         let json_result = serde_json::to_string(&scanners)
@@ -411,3 +592,61 @@ mod tests {
         }
     }
 }
+
+/// Scan for GenAI credentials, reporting errors in the returned value
+///
+/// Takes the same parameters as [`aicred_scan`] but always returns a JSON
+/// result envelope instead of relying on [`aicred_last_error`]. Callers whose
+/// threads may interleave (such as Go goroutines) should prefer this form.
+///
+/// # Returns
+/// ```json
+/// {"ok": true, "result": { ...scan result... }}
+/// {"ok": false, "error": {"code": 2, "message": "Scan failed: Not found: ..."}}
+/// ```
+/// `code` is one of the `AICRED_ERROR_*` constants. Caller must free with
+/// [`aicred_free`]. Returns NULL only if the envelope cannot be allocated.
+///
+/// # Safety
+///
+/// Both pointers must be either null or point to valid null-terminated C strings.
+#[no_mangle]
+pub extern "C" fn aicred_scan_envelope(
+    home_path: *const libc::c_char,
+    options_json: *const libc::c_char,
+) -> *mut libc::c_char {
+    envelope(safe_execute(|| run_scan(home_path, options_json)))
+}
+
+/// Get list of available provider plugins as a result envelope
+///
+/// Returns `{"ok": true, "result": ["openai", ...]}` or an error envelope as
+/// described for [`aicred_scan_envelope`]. Caller must free with [`aicred_free`].
+#[no_mangle]
+pub extern "C" fn aicred_list_providers_envelope() -> *mut libc::c_char {
+    envelope(safe_execute(provider_names))
+}
+
+/// Get list of available scanner plugins as a result envelope
+///
+/// Returns `{"ok": true, "result": ["roo-code", ...]}` or an error envelope as
+/// described for [`aicred_scan_envelope`]. Caller must free with [`aicred_free`].
+#[no_mangle]
+pub extern "C" fn aicred_list_scanners_envelope() -> *mut libc::c_char {
+    envelope(safe_execute(scanner_names))
+}
+
+/// Free a byte buffer returned with an explicit length, such as by
+/// [`aicred_session_scan`]
+///
+/// # Safety
+///
+/// `ptr` and `len` must be exactly as returned by this library, or `ptr` null.
+#[no_mangle]
+pub extern "C" fn aicred_bytes_free(ptr: *mut u8, len: usize) {
+    if !ptr.is_null() {
+        unsafe {
+            drop(Box::from_raw(std::ptr::slice_from_raw_parts_mut(ptr, len)));
+        }
+    }
+}
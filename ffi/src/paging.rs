@@ -0,0 +1,253 @@
+//! Paged transfer of scan results
+//!
+//! [`aicred_scan_begin`] returns a cursor and [`aicred_scan_next`] hands the
+//! result back in bounded pages, so callers never hold one C string with every
+//! finding in it. The scan runs on its own thread and hands over one scanner's
+//! findings at a time through a channel of one batch, pausing until the pages
+//! before are read, so the cursor holds at most a few scanners' findings
+//! rather than the whole result.
+
+use crate::{envelope, safe_execute, scan_options, FfiError};
+use aicred_core::models::{ConfigInstance, DiscoveredCredential};
+use std::path::PathBuf;
+use std::sync::mpsc::{sync_channel, Receiver};
+
+/// Page size used when [`aicred_scan_begin`] is given 0
+const DEFAULT_PAGE_SIZE: usize = 500;
+
+/// Opaque cursor returned by [`aicred_scan_begin`]
+pub struct AicredScanCursor {
+    home: PathBuf,
+    options_json: std::ffi::CString,
+    page_size: usize,
+    state: CursorState,
+}
+
+enum CursorState {
+    Pending,
+    Running(ScanPages),
+    Finished,
+}
+
+/// What the scan thread hands the cursor
+enum Batch {
+    /// One scanner's findings, with the scalar fields of the result so far
+    Findings {
+        header: serde_json::Value,
+        keys: Vec<DiscoveredCredential>,
+        config_instances: Vec<ConfigInstance>,
+    },
+    /// The scalar fields of the finished scan, or why it failed
+    Done(Result<serde_json::Value, FfiError>),
+}
+
+/// A running scan and the batch being paged over
+struct ScanPages {
+    batches: Receiver<Batch>,
+    header: serde_json::Value,
+    keys: std::vec::IntoIter<DiscoveredCredential>,
+    config_instances: std::vec::IntoIter<ConfigInstance>,
+    finished: bool,
+}
+
+impl ScanPages {
+    /// Starts the scan thread
+    fn start(options: aicred_core::ScanOptions) -> Self {
+        let (tx, batches) = sync_channel(1);
+        std::thread::spawn(move || {
+            let outcome =
+                aicred_core::scan_incremental(&options, |result, keys, config_instances| {
+                    let Ok(header) = header(result) else {
+                        return false;
+                    };
+                    // A closed cursor drops the receiver, which stops the scan
+                    tx.send(Batch::Findings {
+                        header,
+                        keys,
+                        config_instances,
+                    })
+                    .is_ok()
+                });
+            let done = outcome
+                .map_err(FfiError::from)
+                .and_then(|result| header(&result));
+            let _ = tx.send(Batch::Done(done));
+        });
+        Self {
+            batches,
+            header: serde_json::Value::Null,
+            keys: Vec::new().into_iter(),
+            config_instances: Vec::new().into_iter(),
+            finished: false,
+        }
+    }
+
+    fn is_empty(&self) -> bool {
+        self.keys.len() == 0 && self.config_instances.len() == 0
+    }
+
+    /// Waits for the next batch with findings, or the end of the scan,
+    /// once the current batch is paged out
+    fn fill(&mut self) -> Result<(), FfiError> {
+        while self.is_empty() && !self.finished {
+            match self.batches.recv() {
+                Ok(Batch::Findings {
+                    header,
+                    keys,
+                    config_instances,
+                }) => {
+                    self.header = header;
+                    self.keys = keys.into_iter();
+                    self.config_instances = config_instances.into_iter();
+                }
+                Ok(Batch::Done(header)) => {
+                    self.header = header?;
+                    self.finished = true;
+                }
+                Err(_) => return Err(FfiError::from("Scan thread stopped".to_string())),
+            }
+        }
+        Ok(())
+    }
+}
+
+/// The scalar fields of a scan result, repeated on every page
+fn header(result: &aicred_core::ScanResult) -> Result<serde_json::Value, FfiError> {
+    let mut header =
+        serde_json::to_value(result).map_err(|e| format!("Failed to serialize result: {}", e))?;
+    if let Some(fields) = header.as_object_mut() {
+        fields.remove("keys");
+        fields.remove("config_instances");
+    }
+    Ok(header)
+}
+
+/// One page of a scan result; the scalar fields repeat on every page
+#[derive(serde::Serialize)]
+struct ScanPage {
+    #[serde(flatten)]
+    header: serde_json::Value,
+    keys: Vec<DiscoveredCredential>,
+    config_instances: Vec<ConfigInstance>,
+    done: bool,
+}
+
+impl AicredScanCursor {
+    fn next_page(&mut self) -> Result<ScanPage, FfiError> {
+        if matches!(self.state, CursorState::Pending) {
+            let options = scan_options(self.home.clone(), self.options_json.as_ptr())?;
+            self.state = CursorState::Running(ScanPages::start(options));
+        }
+
+        let CursorState::Running(pages) = &mut self.state else {
+            return Err(FfiError::from("Scan cursor is exhausted".to_string()));
+        };
+
+        // Each scanner's keys, then its config instances, up to page_size
+        // per page
+        pages.fill()?;
+        let keys: Vec<_> = pages.keys.by_ref().take(self.page_size).collect();
+        let config_instances: Vec<_> = if keys.is_empty() {
+            pages
+                .config_instances
+                .by_ref()
+                .take(self.page_size)
+                .collect()
+        } else {
+            Vec::new()
+        };
+        // Looking ahead marks the last page with findings as done, with the
+        // finished scan's stats and warnings
+        pages.fill()?;
+        let done = pages.finished && pages.is_empty();
+
+        let page = ScanPage {
+            header: pages.header.clone(),
+            keys,
+            config_instances,
+            done,
+        };
+        if done {
+            self.state = CursorState::Finished;
+        }
+        Ok(page)
+    }
+}
+
+/// Start a paged scan
+///
+/// Takes the same parameters as [`crate::aicred_scan`] plus the maximum number
+/// of keys or config instances per page (0 for the default of 500). The scan
+/// starts on the first [`aicred_scan_next`] call and runs on its own thread,
+/// one scanner ahead of the pages read. Returns NULL if either string is null
+/// or not valid UTF-8. Close with [`aicred_scan_close`].
+///
+/// # Safety
+///
+/// Both pointers must be either null or point to valid null-terminated C strings.
+#[no_mangle]
+pub extern "C" fn aicred_scan_begin(
+    home_path: *const libc::c_char,
+    options_json: *const libc::c_char,
+    page_size: usize,
+) -> *mut AicredScanCursor {
+    let home = unsafe { crate::c_str_to_string(home_path) };
+    let options = unsafe { crate::c_str_to_string(options_json) };
+    match (home, options.and_then(|o| std::ffi::CString::new(o).ok())) {
+        (Some(home), Some(options_json)) => Box::into_raw(Box::new(AicredScanCursor {
+            home: PathBuf::from(home),
+            options_json,
+            page_size: if page_size == 0 {
+                DEFAULT_PAGE_SIZE
+            } else {
+                page_size
+            },
+            state: CursorState::Pending,
+        })),
+        _ => std::ptr::null_mut(),
+    }
+}
+
+/// Fetch the next page of a paged scan as a result envelope
+///
+/// Each page has the shape of a scan result holding a slice of one scanner's
+/// keys or config instances, plus `"done": true` on the last page, which also
+/// carries the finished scan's stats and warnings. A scan error is reported in
+/// the envelope of the call that meets it, and ends the scan. Caller must free with
+/// [`crate::aicred_free`].
+///
+/// # Safety
+///
+/// The cursor must be a live handle from [`aicred_scan_begin`], used by one
+/// thread at a time.
+#[no_mangle]
+pub extern "C" fn aicred_scan_next(cursor: *mut AicredScanCursor) -> *mut libc::c_char {
+    envelope(safe_execute(|| {
+        if cursor.is_null() {
+            return Err(FfiError::from("Invalid scan cursor".to_string()));
+        }
+        let cursor = unsafe { &mut *cursor };
+        let page = cursor.next_page();
+        if page.is_err() {
+            cursor.state = CursorState::Finished;
+        }
+        page
+    }))
+}
+
+/// Close a paged scan and free its cursor
+///
+/// A scan still running stops once the scanners it has started finish.
+///
+/// # Safety
+///
+/// The cursor must be either null or a handle from [`aicred_scan_begin`] that
+/// has not already been closed.
+#[no_mangle]
+pub extern "C" fn aicred_scan_close(cursor: *mut AicredScanCursor) {
+    if !cursor.is_null() {
+        unsafe {
+            drop(Box::from_raw(cursor));
+        }
+    }
+}
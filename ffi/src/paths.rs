@@ -0,0 +1,31 @@
+//! Locations of the aicred configuration
+//!
+//! These follow the same rules as the Go bindings' `ConfigDir` and
+//! `DefaultHomeDir`, so a process that sets the overrides sees one layout
+//! whichever side reads the files:
+//!
+//! - `AICRED_HOME` replaces the home directory when none is given
+//! - `AICRED_CONFIG_DIR` and `XDG_CONFIG_HOME` move the configuration
+//!   directory, as described in `aicred_core::utils::paths`
+
+use std::path::{Path, PathBuf};
+
+/// Overrides the home directory used when the caller passes an empty one
+pub(crate) const ENV_HOME: &str = "AICRED_HOME";
+
+/// Returns `AICRED_HOME` for an empty `home`, or `home` itself. `None`
+/// leaves the choice to the core library, which uses the user's home.
+pub(crate) fn home_dir(home: PathBuf) -> Option<PathBuf> {
+    if home.as_os_str().is_empty() {
+        return std::env::var(ENV_HOME)
+            .ok()
+            .filter(|v| !v.is_empty())
+            .map(PathBuf::from);
+    }
+    Some(home)
+}
+
+/// Returns the aicred configuration directory for `home`
+pub(crate) fn config_dir(home: &Path) -> PathBuf {
+    aicred_core::utils::paths::config_dir(home)
+}
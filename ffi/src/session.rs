@@ -0,0 +1,333 @@
+//! Handle-based sessions over the aicred configuration directory
+//!
+//! A session parses the configuration directory, `~/.config/aicred` unless
+//! overridden as described in [`crate::paths`], once and serves later calls
+//! from the parsed state, instead of re-reading YAML on every call. Call
+//! [`aicred_session_reload`] to drop the cache after the files change.
+
+use crate::{
+    c_str_to_string, encode_envelope, envelope, paths, safe_execute, scan_home, FfiError,
+    AICRED_ENCODING_CBOR, AICRED_ENCODING_JSON, AICRED_ERROR_IO, AICRED_ERROR_NOT_FOUND,
+    AICRED_ERROR_PARSE,
+};
+use aicred_core::models::{LabelAssignment, ProviderInstance};
+use std::path::{Path, PathBuf};
+use std::sync::Mutex;
+
+/// Opaque session handle returned by [`aicred_session_open`]
+pub struct AicredSession {
+    home_dir: PathBuf,
+    encoding: i32,
+    state: Mutex<SessionState>,
+}
+
+#[derive(Default)]
+struct SessionState {
+    instances: Option<Vec<ProviderInstance>>,
+    labels: Option<Vec<LabelAssignment>>,
+}
+
+impl AicredSession {
+    /// The configuration directory, resolved on each use so a profile
+    /// switch applies after [`aicred_session_reload`]
+    fn config_dir(&self) -> PathBuf {
+        paths::config_dir(&self.home_dir)
+    }
+
+    fn instances(&self) -> Result<Vec<ProviderInstance>, FfiError> {
+        let mut state = self.lock()?;
+        if state.instances.is_none() {
+            state.instances = Some(load_instances(&self.config_dir())?);
+        }
+        Ok(state.instances.clone().unwrap_or_default())
+    }
+
+    fn labels(&self) -> Result<Vec<LabelAssignment>, FfiError> {
+        let mut state = self.lock()?;
+        if state.labels.is_none() {
+            state.labels = Some(load_labels(&self.config_dir())?);
+        }
+        Ok(state.labels.clone().unwrap_or_default())
+    }
+
+    fn save_labels(&self, labels: Vec<LabelAssignment>) -> Result<(), FfiError> {
+        let mut state = self.lock()?;
+        write_labels(&self.config_dir(), &labels)?;
+        state.labels = Some(labels);
+        Ok(())
+    }
+
+    fn lock(&self) -> Result<std::sync::MutexGuard<'_, SessionState>, FfiError> {
+        self.state
+            .lock()
+            .map_err(|_| FfiError::from("Session state is poisoned".to_string()))
+    }
+}
+
+fn io_error(context: &str, path: &Path, err: &std::io::Error) -> FfiError {
+    let code = match err.kind() {
+        std::io::ErrorKind::NotFound => AICRED_ERROR_NOT_FOUND,
+        std::io::ErrorKind::PermissionDenied => crate::AICRED_ERROR_PERMISSION_DENIED,
+        _ => AICRED_ERROR_IO,
+    };
+    FfiError::new(code, format!("{} {}: {}", context, path.display(), err))
+}
+
+/// Loads every provider instance from `inference_services/*.yaml`, skipping
+/// files that do not parse, as the CLI does
+fn load_instances(config_dir: &Path) -> Result<Vec<ProviderInstance>, FfiError> {
+    let dir = config_dir.join("inference_services");
+    if !dir.exists() {
+        return Ok(Vec::new());
+    }
+    let entries = std::fs::read_dir(&dir).map_err(|e| io_error("Failed to read", &dir, &e))?;
+
+    let mut instances = Vec::new();
+    for entry in entries {
+        let path = entry
+            .map_err(|e| io_error("Failed to read", &dir, &e))?
+            .path();
+        if path.extension().is_some_and(|ext| ext == "yaml") {
+            if let Ok(content) = std::fs::read_to_string(&path) {
+                if let Ok(instance) = serde_yaml::from_str::<ProviderInstance>(&content) {
+                    instances.push(instance);
+                }
+            }
+        }
+    }
+    instances.sort_by(|a, b| a.id.cmp(&b.id));
+    Ok(instances)
+}
+
+/// Loads label assignments from `labels.yaml`
+fn load_labels(config_dir: &Path) -> Result<Vec<LabelAssignment>, FfiError> {
+    let path = config_dir.join("labels.yaml");
+    if !path.exists() {
+        return Ok(Vec::new());
+    }
+    let content =
+        std::fs::read_to_string(&path).map_err(|e| io_error("Failed to read", &path, &e))?;
+    serde_yaml::from_str(&content).map_err(|e| {
+        FfiError::new(
+            AICRED_ERROR_PARSE,
+            format!("Failed to parse {}: {}", path.display(), e),
+        )
+    })
+}
+
+/// Writes `labels.yaml` through a temporary file and rename so readers never
+/// see a partial file. The directory is restricted to its owner and the
+/// file is created readable by the owner only.
+fn write_labels(config_dir: &Path, labels: &[LabelAssignment]) -> Result<(), FfiError> {
+    std::fs::create_dir_all(config_dir)
+        .map_err(|e| io_error("Failed to create", config_dir, &e))?;
+    restrict_dir(config_dir).map_err(|e| io_error("Failed to restrict", config_dir, &e))?;
+    let content = serde_yaml::to_string(labels)
+        .map_err(|e| format!("Failed to serialize labels: {}", e))?;
+
+    let path = config_dir.join("labels.yaml");
+    let tmp = config_dir.join(format!(".labels.yaml.{}.tmp", std::process::id()));
+    write_private(&tmp, content.as_bytes()).map_err(|e| io_error("Failed to write", &tmp, &e))?;
+    std::fs::rename(&tmp, &path).map_err(|e| {
+        let _ = std::fs::remove_file(&tmp);
+        io_error("Failed to replace", &path, &e)
+    })
+}
+
+/// Sets `dir` to mode 0700 on Unix, where other users could otherwise list
+/// the credential files in it
+fn restrict_dir(dir: &Path) -> std::io::Result<()> {
+    #[cfg(unix)]
+    {
+        use std::os::unix::fs::PermissionsExt;
+        std::fs::set_permissions(dir, std::fs::Permissions::from_mode(0o700))?;
+    }
+    #[cfg(not(unix))]
+    let _ = dir;
+    Ok(())
+}
+
+/// Writes `content` to a new file created with mode 0600 on Unix
+fn write_private(path: &Path, content: &[u8]) -> std::io::Result<()> {
+    use std::io::Write;
+    let mut options = std::fs::OpenOptions::new();
+    options.write(true).create(true).truncate(true);
+    #[cfg(unix)]
+    std::os::unix::fs::OpenOptionsExt::mode(&mut options, 0o600);
+    options.open(path)?.write_all(content)
+}
+
+/// Borrows the session behind a handle
+fn session<'a>(handle: *const AicredSession) -> Result<&'a AicredSession, FfiError> {
+    if handle.is_null() {
+        return Err(FfiError::from("Invalid session handle".to_string()));
+    }
+    Ok(unsafe { &*handle })
+}
+
+/// Open a session over `<home_path>/.config/aicred`, or the directory that
+/// `AICRED_CONFIG_DIR` or `XDG_CONFIG_HOME` names
+///
+/// Nothing is read until the first call that needs it. Returns NULL if
+/// `home_path` is null or not valid UTF-8. Close with [`aicred_session_close`].
+///
+/// # Safety
+///
+/// `home_path` must be either null or point to a valid null-terminated C string.
+#[no_mangle]
+pub extern "C" fn aicred_session_open(home_path: *const libc::c_char) -> *mut AicredSession {
+    aicred_session_open_with_encoding(home_path, AICRED_ENCODING_JSON)
+}
+
+/// Open a session whose [`aicred_session_scan`] results use `encoding`
+///
+/// `encoding` is `AICRED_ENCODING_JSON` or `AICRED_ENCODING_CBOR`. Returns
+/// NULL if `home_path` is invalid or the encoding is not supported.
+///
+/// # Safety
+///
+/// `home_path` must be either null or point to a valid null-terminated C string.
+#[no_mangle]
+pub extern "C" fn aicred_session_open_with_encoding(
+    home_path: *const libc::c_char,
+    encoding: i32,
+) -> *mut AicredSession {
+    if encoding != AICRED_ENCODING_JSON && encoding != AICRED_ENCODING_CBOR {
+        return std::ptr::null_mut();
+    }
+    match unsafe { c_str_to_string(home_path) } {
+        Some(home) => {
+            let home_dir = paths::home_dir(PathBuf::from(home)).unwrap_or_default();
+            Box::into_raw(Box::new(AicredSession {
+                home_dir,
+                encoding,
+                state: Mutex::new(SessionState::default()),
+            }))
+        }
+        None => std::ptr::null_mut(),
+    }
+}
+
+/// Close a session and free its handle
+///
+/// # Safety
+///
+/// The handle must be either null or a handle returned by
+/// [`aicred_session_open`] that has not already been closed.
+#[no_mangle]
+pub extern "C" fn aicred_session_close(handle: *mut AicredSession) {
+    if !handle.is_null() {
+        unsafe {
+            drop(Box::from_raw(handle));
+        }
+    }
+}
+
+/// Drop the session's cached state so the next call re-reads the files
+#[no_mangle]
+pub extern "C" fn aicred_session_reload(handle: *const AicredSession) {
+    if let Ok(session) = session(handle) {
+        if let Ok(mut state) = session.lock() {
+            *state = SessionState::default();
+        }
+    }
+}
+
+/// List provider instances as a result envelope
+///
+/// Returns `{"ok": true, "result": [ProviderInstance, ...]}` or an error
+/// envelope. Caller must free with [`crate::aicred_free`].
+#[no_mangle]
+pub extern "C" fn aicred_session_instances(handle: *const AicredSession) -> *mut libc::c_char {
+    envelope(safe_execute(|| session(handle)?.instances()))
+}
+
+/// Get one provider instance by ID as a result envelope
+///
+/// Fails with `AICRED_ERROR_NOT_FOUND` if no instance has that ID.
+/// Caller must free with [`crate::aicred_free`].
+///
+/// # Safety
+///
+/// `instance_id` must be either null or point to a valid null-terminated C string.
+#[no_mangle]
+pub extern "C" fn aicred_session_get_instance(
+    handle: *const AicredSession,
+    instance_id: *const libc::c_char,
+) -> *mut libc::c_char {
+    envelope(safe_execute(|| {
+        let id = unsafe { c_str_to_string(instance_id) }
+            .ok_or_else(|| "Invalid instance ID".to_string())?;
+        session(handle)?
+            .instances()?
+            .into_iter()
+            .find(|instance| instance.id == id)
+            .ok_or_else(|| {
+                FfiError::new(AICRED_ERROR_NOT_FOUND, format!("Instance not found: {}", id))
+            })
+    }))
+}
+
+/// List label assignments as a result envelope
+///
+/// Caller must free with [`crate::aicred_free`].
+#[no_mangle]
+pub extern "C" fn aicred_session_labels(handle: *const AicredSession) -> *mut libc::c_char {
+    envelope(safe_execute(|| session(handle)?.labels()))
+}
+
+/// Replace all label assignments and write them to `labels.yaml`
+///
+/// `labels_json` is a JSON array of label assignments. Returns
+/// `{"ok": true, "result": null}` or an error envelope. Caller must free with
+/// [`crate::aicred_free`].
+///
+/// # Safety
+///
+/// `labels_json` must be either null or point to a valid null-terminated C string.
+#[no_mangle]
+pub extern "C" fn aicred_session_save_labels(
+    handle: *const AicredSession,
+    labels_json: *const libc::c_char,
+) -> *mut libc::c_char {
+    envelope(safe_execute(|| {
+        let json = unsafe { c_str_to_string(labels_json) }
+            .ok_or_else(|| "Invalid labels JSON".to_string())?;
+        let labels: Vec<LabelAssignment> = serde_json::from_str(&json).map_err(|e| {
+            FfiError::new(
+                AICRED_ERROR_PARSE,
+                format!("Failed to parse labels JSON: {}", e),
+            )
+        })?;
+        session(handle)?.save_labels(labels)
+    }))
+}
+
+/// Scan the session's home directory, returning an envelope in the session's
+/// encoding
+///
+/// Takes the same `options_json` as [`crate::aicred_scan`]. The envelope bytes
+/// are not NUL-terminated; their length is written to `out_len`. Free them with
+/// [`crate::aicred_bytes_free`]. Returns NULL only if `out_len` is null.
+///
+/// # Safety
+///
+/// `options_json` must be either null or point to a valid null-terminated C
+/// string, and `out_len` must point to writable memory.
+#[no_mangle]
+pub extern "C" fn aicred_session_scan(
+    handle: *const AicredSession,
+    options_json: *const libc::c_char,
+    out_len: *mut usize,
+) -> *mut u8 {
+    if out_len.is_null() {
+        return std::ptr::null_mut();
+    }
+    let encoding = session(handle).map_or(AICRED_ENCODING_JSON, |s| s.encoding);
+    let outcome = safe_execute(|| scan_home(session(handle)?.home_dir.clone(), options_json));
+    let bytes = encode_envelope(outcome, encoding).into_boxed_slice();
+    unsafe {
+        *out_len = bytes.len();
+    }
+    Box::into_raw(bytes).cast::<u8>()
+}
@@ -118,6 +118,20 @@ fn test_invalid_json() {
         assert!(!error.is_null());
         let error_str = CStr::from_ptr(error).to_str().unwrap();
         assert!(error_str.contains("Failed to parse options JSON"));
+        assert_eq!(aicred_last_error_code(), AICRED_ERROR_PARSE);
+    }
+}
+
+#[test]
+fn test_error_code_cleared_on_success() {
+    unsafe {
+        let version = aicred_version();
+        assert!(!version.is_null());
+
+        let list = aicred_list_providers();
+        assert!(!list.is_null());
+        assert_eq!(aicred_last_error_code(), AICRED_OK);
+        aicred_free(list);
     }
 }
 
@@ -324,3 +338,152 @@ fn test_concurrent_scans_10() {
         h.join().unwrap();
     }
 }
+
+#[test]
+fn test_scan_envelope_reports_error_per_call() {
+    unsafe {
+        let home = CString::new("/tmp/test").unwrap();
+        let options = CString::new("invalid json").unwrap();
+
+        let result = aicred_scan_envelope(home.as_ptr(), options.as_ptr());
+        assert!(!result.is_null());
+        let envelope: serde_json::Value =
+            serde_json::from_str(CStr::from_ptr(result).to_str().unwrap()).unwrap();
+        assert_eq!(envelope["ok"], false);
+        assert_eq!(envelope["error"]["code"], AICRED_ERROR_PARSE);
+        assert!(envelope["error"]["message"]
+            .as_str()
+            .unwrap()
+            .contains("Failed to parse options JSON"));
+        aicred_free(result);
+    }
+}
+
+#[test]
+fn test_list_providers_envelope() {
+    unsafe {
+        let result = aicred_list_providers_envelope();
+        assert!(!result.is_null());
+        let envelope: serde_json::Value =
+            serde_json::from_str(CStr::from_ptr(result).to_str().unwrap()).unwrap();
+        assert_eq!(envelope["ok"], true);
+        assert!(envelope["result"].as_array().is_some());
+        aicred_free(result);
+    }
+}
+
+#[test]
+fn test_session_instances_and_labels() {
+    let home = std::env::temp_dir().join(format!("aicred-ffi-session-{}", std::process::id()));
+    let services = home.join(".config/aicred/inference_services");
+    std::fs::create_dir_all(&services).unwrap();
+    std::fs::write(
+        services.join("openai-main.yaml"),
+        "id: openai-main\nprovider_type: openai\nbase_url: https://api.openai.com/v1\napi_key: sk-test\nmodels: [gpt-4o]\ncapabilities:\n  chat: true\n  completion: false\n  embedding: false\n  image_generation: false\n  function_calling: false\n  streaming: true\n",
+    )
+    .unwrap();
+
+    let read = |ptr: *mut libc::c_char| -> serde_json::Value {
+        assert!(!ptr.is_null());
+        let value = unsafe { serde_json::from_str(CStr::from_ptr(ptr).to_str().unwrap()).unwrap() };
+        aicred_free(ptr);
+        value
+    };
+
+    unsafe {
+        let home_c = CString::new(home.to_str().unwrap()).unwrap();
+        let session = aicred_session_open(home_c.as_ptr());
+        assert!(!session.is_null());
+
+        let instances = read(aicred_session_instances(session));
+        assert_eq!(instances["ok"], true);
+        assert_eq!(instances["result"][0]["id"], "openai-main");
+
+        let missing = CString::new("nope").unwrap();
+        let err = read(aicred_session_get_instance(session, missing.as_ptr()));
+        assert_eq!(err["error"]["code"], AICRED_ERROR_NOT_FOUND);
+
+        let labels = CString::new(
+            r#"[{"label_name":"fast","target":{"type":"provider_instance","instance_id":"openai-main"},"assigned_at":"2025-01-01T00:00:00Z","assigned_by":null}]"#,
+        )
+        .unwrap();
+        assert_eq!(read(aicred_session_save_labels(session, labels.as_ptr()))["ok"], true);
+
+        aicred_session_reload(session);
+        let loaded = read(aicred_session_labels(session));
+        assert_eq!(loaded["result"][0]["label_name"], "fast");
+
+        aicred_session_close(session);
+    }
+    let _ = std::fs::remove_dir_all(&home);
+}
+
+#[test]
+fn test_scan_pages() {
+    let home = std::env::temp_dir().join(format!("aicred-ffi-pages-{}", std::process::id()));
+    std::fs::create_dir_all(&home).unwrap();
+
+    unsafe {
+        let home_c = CString::new(home.to_str().unwrap()).unwrap();
+        let options = CString::new("{}").unwrap();
+        let cursor = aicred_scan_begin(home_c.as_ptr(), options.as_ptr(), 2);
+        assert!(!cursor.is_null());
+
+        let mut pages = 0;
+        loop {
+            let result = aicred_scan_next(cursor);
+            assert!(!result.is_null());
+            let envelope: serde_json::Value =
+                serde_json::from_str(CStr::from_ptr(result).to_str().unwrap()).unwrap();
+            aicred_free(result);
+
+            assert_eq!(envelope["ok"], true);
+            let page = &envelope["result"];
+            assert!(page["keys"].as_array().unwrap().len() <= 2);
+            assert!(page["home_directory"].is_string());
+            pages += 1;
+            if page["done"] == true {
+                // The last page carries the finished scan's stats
+                assert!(page["stats"].is_object());
+                assert!(page["warnings"].is_array());
+                break;
+            }
+        }
+        assert!(pages >= 1);
+
+        // An exhausted cursor reports an error instead of repeating the last page
+        let result = aicred_scan_next(cursor);
+        let envelope: serde_json::Value =
+            serde_json::from_str(CStr::from_ptr(result).to_str().unwrap()).unwrap();
+        assert_eq!(envelope["ok"], false);
+        aicred_free(result);
+
+        aicred_scan_close(cursor);
+    }
+    let _ = std::fs::remove_dir_all(&home);
+}
+
+#[test]
+fn test_scan_pages_close_early() {
+    let home = std::env::temp_dir().join(format!("aicred-ffi-pages-close-{}", std::process::id()));
+    std::fs::create_dir_all(&home).unwrap();
+
+    unsafe {
+        let home_c = CString::new(home.to_str().unwrap()).unwrap();
+        let options = CString::new(r#"{"concurrency":2}"#).unwrap();
+
+        // A cursor closed before or after its first page stops its scan
+        let unread = aicred_scan_begin(home_c.as_ptr(), options.as_ptr(), 1);
+        assert!(!unread.is_null());
+        aicred_scan_close(unread);
+
+        let cursor = aicred_scan_begin(home_c.as_ptr(), options.as_ptr(), 1);
+        let result = aicred_scan_next(cursor);
+        let envelope: serde_json::Value =
+            serde_json::from_str(CStr::from_ptr(result).to_str().unwrap()).unwrap();
+        aicred_free(result);
+        assert_eq!(envelope["ok"], true);
+        aicred_scan_close(cursor);
+    }
+    let _ = std::fs::remove_dir_all(&home);
+}
@@ -49,6 +49,7 @@ fn perform_scan(options: ScanOptions) -> Result<String, String> {
         exclude_providers: options.exclude_providers,
         probe_models: false,
         probe_timeout_secs: 30,
+        concurrency: 0,
     };
 
     match scan(&core_options) {